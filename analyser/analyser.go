@@ -3,23 +3,48 @@ package analyser
 import (
 	"log"
 	"math"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/ftfmtavares/anomalies-detector/collector"
 	"github.com/ftfmtavares/anomalies-detector/config"
+	"github.com/ftfmtavares/anomalies-detector/utils"
 )
 
+//Clock is the time source of the check dates of the reports, replaced by tests to make reports deterministic
+var Clock utils.Clock = utils.SystemClock
+
 //OutlierReport provides the structure to store all detected outliers of a given site
+//OutliersDetectionMethod field joins the names of all methods run with "+" when several were used, Consensus field being the number of them required for an alarm
+//Shadow field holds the events of the optional shadow detection method, which are recorded and visualized but never notified
+//Objectives field holds the compliance of the configured service level objectives
+//BaselineReset field is the latest baseline reset of the analysed period, the data before it having been discarded by the detection
+//DataGaps field lists the time steps missing from the metric totals, found when the dataset fills gaps, which are no outliers
+//KnownEvents field lists the occurrences of the known events of the site within the analysed period
+//Incidents field groups the events overlapping in time, set by the "group" post-processor
+//Expectations field holds the expected value and tolerated deviations of every time step, set when the dataset exports its expectations
 type OutlierReport struct {
-	SiteId                  string         `json:"siteId"`
-	OutliersDetectionMethod string         `json:"outliersDetectionMethod"`
-	CheckDateStart          time.Time      `json:"checkTimeStart"`
-	CheckDateEnd            time.Time      `json:"checkTimeEnd"`
-	TimeAgo                 string         `json:"timeAgo"`
-	TimeStep                string         `json:"timeStep"`
-	DateStart               time.Time      `json:"dateStart"`
-	DateEnd                 time.Time      `json:"dateEnd"`
-	Result                  OutlierResults `json:"result"`
+	SiteId                  string                  `json:"siteId"`
+	OutliersDetectionMethod string                  `json:"outliersDetectionMethod"`
+	Consensus               int                     `json:"consensus,omitempty"`
+	CheckDateStart          time.Time               `json:"checkTimeStart"`
+	CheckDateEnd            time.Time               `json:"checkTimeEnd"`
+	TimeAgo                 string                  `json:"timeAgo"`
+	TimeStep                string                  `json:"timeStep"`
+	DateStart               time.Time               `json:"dateStart"`
+	DateEnd                 time.Time               `json:"dateEnd"`
+	Result                  OutlierResults          `json:"result"`
+	ShadowDetectionMethod   string                  `json:"shadowDetectionMethod,omitempty"`
+	Shadow                  *OutlierResults         `json:"shadow,omitempty"`
+	Objectives              []ObjectiveReport       `json:"objectives,omitempty"`
+	BaselineReset           *time.Time              `json:"baselineReset,omitempty"`
+	DataGaps                []DataGap               `json:"dataGaps,omitempty"`
+	KnownEvents             []KnownEventWindow      `json:"knownEvents,omitempty"`
+	Incidents               []Incident              `json:"incidents,omitempty"`
+	Expectations            []AttributeExpectations `json:"expectations,omitempty"`
 }
 
 //OutlierResults holds the list of detected warnings and alarms
@@ -29,11 +54,28 @@ type OutlierResults struct {
 }
 
 //OutlierEvent provides the structure to store the warning or alarm details
+//Score field ranks events of any method, being the largest distance of the period values from the baseline mean in baseline standard deviations,
+//Observed field that value, Expected field the baseline mean and DeviationPercent field their relative difference
+//Direction field is "spike" when the observed value is above the expected one and "drop" otherwise
+//Explanation field is the plain language description of the event added by the "explain" post-processor
+//KnownEvent field names the known event the event overlaps, telling it was expected
+//ProbableCauses field ranks, on alarms of "Total", the child attribute paths that contributed most to its deviation
 type OutlierEvent struct {
-	OutlierPeriodStart time.Time `json:"outlierPeriodStart"`
-	OutlierPeriodEnd   time.Time `json:"outlierPeriodEnd"`
-	Metric             string    `json:"metric"`
-	Attribute          string    `json:"attribute"`
+	OutlierPeriodStart time.Time       `json:"outlierPeriodStart"`
+	OutlierPeriodEnd   time.Time       `json:"outlierPeriodEnd"`
+	Metric             string          `json:"metric"`
+	Attribute          string          `json:"attribute"`
+	Score              float64         `json:"score"`
+	Observed           float64         `json:"observed"`
+	Expected           float64         `json:"expected"`
+	DeviationPercent   float64         `json:"deviationPercent"`
+	Direction          string          `json:"direction"`
+	Resolution         string          `json:"resolution,omitempty"`
+	Methods            []string        `json:"methods,omitempty"`
+	Routes             []string        `json:"routes,omitempty"`
+	Explanation        string          `json:"explanation,omitempty"`
+	KnownEvent         string          `json:"knownEvent,omitempty"`
+	ProbableCauses     []ProbableCause `json:"probableCauses,omitempty"`
 }
 
 //eventPeriod provides the structure to store a period of time
@@ -43,106 +85,327 @@ type eventPeriod struct {
 }
 
 //GetResults takes the entire data from a site and the respective configurations in order to look for outliers
-//An OutlierReport is generated and returned
+//An OutlierReport is generated and returned, including the shadow method results and the objectives compliance if configured
 func GetResults(siteData collector.SiteData, dataConf config.Dataset, methodParams config.DetectionMethodsParams) OutlierReport {
 
+	//Applying the detection profile of the site, if any, before its method is reported
+	dataConf, methodParams, cooldown, err := ResolveDetectionProfile(dataConf, methodParams)
+	if err != nil {
+		log.Printf("Detection profile ignored - %s - %s\n", siteData.SiteId, err.Error())
+		cooldown = 0
+	}
+
 	//Initalizing the resulting OutlierReport logging the check date start at the same time
 	res := OutlierReport{
 		SiteId:                  siteData.SiteId,
-		OutliersDetectionMethod: dataConf.OutliersDetectionMethod,
-		CheckDateStart:          time.Now(),
+		OutliersDetectionMethod: strings.Join(dataConf.Methods(), "+"),
+		CheckDateStart:          Clock.Now(),
 		TimeAgo:                 dataConf.TimeAgo,
 		TimeStep:                dataConf.TimeStep,
 		DateStart:               siteData.DateStart,
 		DateEnd:                 siteData.DateEnd,
-		Result: OutlierResults{
-			Warnings: []OutlierEvent{},
-			Alarms:   []OutlierEvent{},
-		},
 	}
 
-	//Looping all attribute/sub-values combinations of each metric
+	//Discarding the data before the latest baseline reset, so tracking changes aren't flagged as step changes forever
+	detectionData := siteData
+	var burnInEnd time.Time
+	resets, burnIn, err := ParseBaselineResets(dataConf)
+	if err != nil {
+		log.Printf("Baseline resets ignored - %s - %s\n", siteData.SiteId, err.Error())
+	} else if reset, found := latestBaselineReset(resets, siteData.DateEnd); found {
+		if reset.After(siteData.DateStart) {
+			detectionData = trimBaseline(siteData, reset)
+		}
+		burnInEnd = reset.Add(burnIn)
+		res.BaselineReset = &reset
+	}
+
+	//Leaving the time steps of excluded known events out of the detection, the events overlapping tagged ones being reported as expected
+	knownEvents, err := ParseKnownEvents(dataConf, siteData.DateStart, siteData.DateEnd)
+	if err != nil {
+		log.Printf("Known events ignored - %s - %s\n", siteData.SiteId, err.Error())
+		knownEvents = nil
+	}
+	if len(knownEvents) > 0 {
+		detectionData = excludeKnownEvents(detectionData, knownEvents)
+		res.KnownEvents = knownEvents
+	}
+
+	//Running the main detection methods, requiring a consensus for alarms if configured
+	if len(dataConf.Methods()) > 1 && dataConf.Consensus > 1 {
+		res.Consensus = dataConf.Consensus
+	}
+	//Short events are debounced and repeated ones cooled down, as configured directly or through the profile
+	timeStep, _ := utils.StrToDuration(dataConf.TimeStep)
+	filterEvents := func(results OutlierResults) OutlierResults {
+		return tagKnownEvents(cooldownEvents(debounceEvents(dropBurnIn(results, burnInEnd), dataConf.Debounce, timeStep), cooldown), knownEvents)
+	}
+	res.Result = filterEvents(detectSiteOutliers(detectionData, dataConf.Methods(), dataConf.Consensus, methodParams, dataConf.MetricDetection))
+	tagResolution(res.Result, res.TimeStep)
+	attachProbableCauses(res.Result, detectionData)
+
+	//Exporting what the main methods expected on every time step, so charts can show the data against the bands it was checked with
+	if dataConf.Expectations {
+		res.Expectations = siteExpectations(detectionData, dataConf.Methods(), methodParams, dataConf.MetricDetection)
+	}
+
+	//Running the shadow method over the same data, its results being kept apart from the main ones
+	if dataConf.ShadowDetectionMethod != "" {
+		shadow := filterEvents(detectSiteOutliers(detectionData, []string{dataConf.ShadowDetectionMethod}, 0, methodParams, config.MetricParamsOnly(dataConf.MetricDetection)))
+		tagResolution(shadow, res.TimeStep)
+		res.ShadowDetectionMethod = dataConf.ShadowDetectionMethod
+		res.Shadow = &shadow
+	}
+
+	//Reporting the data gaps apart from the outliers, so outages aren't taken for drops
+	if dataConf.Gaps != nil {
+		res.DataGaps = findDataGaps(siteData, dataConf.Gaps.MinSteps, timeStep)
+	}
+
+	//Tracking the service level objectives compliance over the same data
+	if len(dataConf.Objectives) > 0 {
+		res.Objectives = evaluateObjectives(siteData, dataConf.Objectives, res.TimeStep)
+	}
+
+	//Closing the log time just before returning the report
+	res.CheckDateEnd = Clock.Now()
+	return res
+}
+
+//tagResolution sets the time step the events were detected at, so events of a site analysed at several resolutions can be told apart
+func tagResolution(results OutlierResults, timeStep string) {
+	for i := range results.Warnings {
+		results.Warnings[i].Resolution = timeStep
+	}
+	for i := range results.Alarms {
+		results.Alarms[i].Resolution = timeStep
+	}
+}
+
+//detectSiteOutliers runs the given detection methods over all attribute/sub-values combinations of each metric of a site
+//The results of several methods are merged per time step according to the consensus (see mergeMethodsLevels)
+//Metrics found on metricDetection are analysed with their own methods, consensus and parameters, as far as they are given
+//The detected event periods are returned as the respective warnings and alarms, each one listing the methods that flagged it
+func detectSiteOutliers(siteData collector.SiteData, methods []string, consensus int, methodParams config.DetectionMethodsParams, metricDetection map[string]config.MetricDetection) OutlierResults {
+	res := OutlierResults{
+		Warnings: []OutlierEvent{},
+		Alarms:   []OutlierEvent{},
+	}
+
+	//Listing all attribute/sub-values combinations of each metric, analysed in parallel by a bounded number of goroutines
+	type attributeJob struct {
+		metricData   collector.MetricData
+		attribute    string
+		methods      []string
+		consensus    int
+		methodParams config.DetectionMethodsParams
+	}
+	jobs := []attributeJob{}
 	for _, metricData := range siteData.Metrics {
+		metricMethods, metricConsensus, metricParams := methods, consensus, methodParams
+		if detection, found := metricDetection[metricData.Metric]; found {
+			metricMethods, metricConsensus, metricParams = detection.Apply(methods, consensus, methodParams)
+		}
 		for _, attribute := range metricData.Attributes {
-			var warnings []eventPeriod
-			var alarms []eventPeriod
-
-			//Checking which detection method should be used and call the respective function
-			switch res.OutliersDetectionMethod {
-			case "3-sigmas":
-				warnings, alarms = detectOutliers3Sigmas(metricData.AttributeData[attribute], siteData.DateEnd, methodParams.ThreeSigmas.OutliersMultiplier, methodParams.ThreeSigmas.StrongOutliersMultiplier)
-			default:
-				log.Printf("Detection Method %s not implemented\n", res.OutliersDetectionMethod)
-				warnings = []eventPeriod{}
-				alarms = []eventPeriod{}
-			}
+			jobs = append(jobs, attributeJob{metricData: metricData, attribute: attribute, methods: metricMethods, consensus: metricConsensus, methodParams: metricParams})
+		}
+	}
+	parallelism := methodParams.AttributeParallelism
+	if parallelism <= 0 {
+		parallelism = runtime.NumCPU()
+	}
 
-			//Taking the returned event periods and creating the respective warnings and alarms on the report
-			for _, warning := range warnings {
-				newOutlierEvent := OutlierEvent{
-					OutlierPeriodStart: warning.outlierPeriodStart,
-					OutlierPeriodEnd:   warning.outlierPeriodEnd,
-					Metric:             metricData.Metric,
-					Attribute:          attribute,
-				}
-				res.Result.Warnings = append(res.Result.Warnings, newOutlierEvent)
-			}
-			for _, alarm := range alarms {
-				newOutlierEvent := OutlierEvent{
-					OutlierPeriodStart: alarm.outlierPeriodStart,
-					OutlierPeriodEnd:   alarm.outlierPeriodEnd,
-					Metric:             metricData.Metric,
-					Attribute:          attribute,
-				}
-				res.Result.Alarms = append(res.Result.Alarms, newOutlierEvent)
-			}
+	//Every job writes its own results slot, so they are merged in metric and attribute order whatever goroutine finished first
+	jobResults := make([]OutlierResults, len(jobs))
+	semaphore := make(chan struct{}, parallelism)
+	var wg sync.WaitGroup
+	for ind := range jobs {
+		wg.Add(1)
+		semaphore <- struct{}{}
+		go func(ind int) {
+			defer wg.Done()
+			defer func() { <-semaphore }()
+			job := jobs[ind]
+			jobResults[ind] = detectAttributeEvents(job.metricData, job.attribute, siteData.DateEnd, job.methods, job.consensus, job.methodParams)
+		}(ind)
+	}
+	wg.Wait()
+
+	for _, jobResult := range jobResults {
+		res.Warnings = append(res.Warnings, jobResult.Warnings...)
+		res.Alarms = append(res.Alarms, jobResult.Alarms...)
+	}
+
+	return res
+}
+
+//detectAttributeEvents runs the given detection methods over a single attribute/sub-values combination of a metric, merging their results
+func detectAttributeEvents(metricData collector.MetricData, attribute string, periodEnd time.Time, methods []string, consensus int, methodParams config.DetectionMethodsParams) OutlierResults {
+	res := OutlierResults{
+		Warnings: []OutlierEvent{},
+		Alarms:   []OutlierEvent{},
+	}
+
+	//Leaving out the time steps missing a value and the series too short or flat for any method to tell deviations apart
+	data, missing := presentSteps(metricData.AttributeData[attribute])
+	if missing > 0 {
+		log.Printf("Missing values skipped - %s - %s - %d time steps\n", metricData.Metric, attribute, missing)
+	}
+	if err := checkSeries(data, methodParams); err != nil {
+		log.Printf("Detection skipped - %s - %s - %s\n", metricData.Metric, attribute, err.Error())
+		return res
+	}
+
+	//Running every method and merging their results on each time step
+	methodsLevels := make([][]int, len(methods))
+	for i, method := range methods {
+		warnings, alarms := detectAttributeOutliers(data, periodEnd, method, methodParams)
+		methodsLevels[i] = levelsFromEventPeriods(data, warnings, alarms)
+	}
+	levels := mergeMethodsLevels(methodsLevels, consensus)
+	warnings, alarms := eventPeriodsFromLevels(data, levels, periodEnd)
+
+	//Taking the returned event periods and creating the respective scored warnings and alarms on the report
+	for _, warning := range warnings {
+		newOutlierEvent := OutlierEvent{
+			OutlierPeriodStart: warning.outlierPeriodStart,
+			OutlierPeriodEnd:   warning.outlierPeriodEnd,
+			Metric:             metricData.Metric,
+			Attribute:          attribute,
+			Methods:            flaggingMethods(data, warning, methods, methodsLevels),
 		}
+		newOutlierEvent.Score, newOutlierEvent.Observed, newOutlierEvent.Expected, newOutlierEvent.DeviationPercent = scoreEvent(data, levels, warning)
+		newOutlierEvent.Direction = eventDirection(newOutlierEvent)
+		res.Warnings = append(res.Warnings, newOutlierEvent)
 	}
+	for _, alarm := range alarms {
+		newOutlierEvent := OutlierEvent{
+			OutlierPeriodStart: alarm.outlierPeriodStart,
+			OutlierPeriodEnd:   alarm.outlierPeriodEnd,
+			Metric:             metricData.Metric,
+			Attribute:          attribute,
+			Methods:            flaggingMethods(data, alarm, methods, methodsLevels),
+		}
+		newOutlierEvent.Score, newOutlierEvent.Observed, newOutlierEvent.Expected, newOutlierEvent.DeviationPercent = scoreEvent(data, levels, alarm)
+		newOutlierEvent.Direction = eventDirection(newOutlierEvent)
+
+		//Alarms in the direction the metric doesn't alert on are only informational
+		if metricData.AlertDirection != "" && newOutlierEvent.Direction != metricData.AlertDirection {
+			res.Warnings = append(res.Warnings, newOutlierEvent)
+			continue
+		}
+		res.Alarms = append(res.Alarms, newOutlierEvent)
+	}
+	sort.SliceStable(res.Warnings, func(i, j int) bool {
+		return res.Warnings[i].OutlierPeriodStart.Before(res.Warnings[j].OutlierPeriodStart)
+	})
 
-	//Closing the log time just before returning the report
-	res.CheckDateEnd = time.Now()
 	return res
 }
 
+//detectAttributeOutliers runs a given detection method over the data of a single attribute/sub-values combination
+func detectAttributeOutliers(data []collector.TimeStepData, PeriodEnd time.Time, method string, methodParams config.DetectionMethodsParams) ([]eventPeriod, []eventPeriod) {
+
+	//Checking which detection method should be used and call the respective function
+	switch method {
+	case "3-sigmas":
+		return detectOutliers3Sigmas(data, PeriodEnd, methodParams.ThreeSigmas.OutliersMultiplier, methodParams.ThreeSigmas.StrongOutliersMultiplier)
+	case "rolling-3-sigmas":
+		return detectOutliersRolling3Sigmas(data, PeriodEnd, methodParams.RollingThreeSigmas.WindowSize, methodParams.RollingThreeSigmas.OutliersMultiplier, methodParams.RollingThreeSigmas.StrongOutliersMultiplier)
+	case "stl":
+		return detectOutliersStl(data, PeriodEnd, methodParams.Stl.SeasonLength, methodParams.Stl.OutliersMultiplier, methodParams.Stl.StrongOutliersMultiplier)
+	case "ewma":
+		return detectOutliersEwma(data, PeriodEnd, methodParams.Ewma.Alpha, methodParams.Ewma.OutliersMultiplier, methodParams.Ewma.StrongOutliersMultiplier)
+	case "cusum":
+		return detectOutliersCusum(data, PeriodEnd, methodParams.Cusum.BaselineSize, methodParams.Cusum.Drift, methodParams.Cusum.Threshold)
+	case "esd":
+		return detectOutliersEsd(data, PeriodEnd, methodParams.Esd.MaxOutliers, methodParams.Esd.Alpha, methodParams.Esd.WarningAlpha)
+	case "holt-winters":
+		return detectOutliersHoltWinters(data, PeriodEnd, methodParams.HoltWinters)
+	case "seasonal-buckets":
+		return detectOutliersSeasonalBuckets(data, PeriodEnd, methodParams.SeasonalBuckets)
+	case "period-over-period":
+		return detectOutliersPeriodOverPeriod(data, PeriodEnd, methodParams.PeriodOverPeriod)
+	default:
+		log.Printf("Detection Method %s not implemented\n", method)
+		return []eventPeriod{}, []eventPeriod{}
+	}
+}
+
 //detectOutliers3Sigmas implements the 3-sigmas method
 //It takes the time step data and the method parameters as inputs and returns 2 event periods list containg the detected warnings and alarms
 func detectOutliers3Sigmas(data []collector.TimeStepData, PeriodEnd time.Time, outliersMultiplier, strongOutliersMultiplier float64) ([]eventPeriod, []eventPeriod) {
-	count := len(data)
-	sum := 0.0
-	mean := 0.0
-	sd := 0.0
+	if len(data) == 0 {
+		return []eventPeriod{}, []eventPeriod{}
+	}
+	mean, sd := meanAndDeviation(data)
+
+	//Constant series have no deviation to measure, rounding errors on their mean being flagged otherwise
+	if sd == 0 {
+		return eventPeriodsFromLevels(data, make([]int, len(data)), PeriodEnd)
+	}
+
+	//Calculating the Z-Score limits for warnings and alarms
+	strongLimit := strongOutliersMultiplier * sd
+	weakLimit := outliersMultiplier * sd
+
+	//Classifying each metric value according to the warning and alarm Z-score limits
+	levels := make([]int, len(data))
+	for ind := range data {
+		if math.Abs(data[ind].Value-mean) > strongLimit {
+			levels[ind] = levelAlarm
+		} else if math.Abs(data[ind].Value-mean) > weakLimit {
+			levels[ind] = levelWarning
+		}
+	}
+
+	return eventPeriodsFromLevels(data, levels, PeriodEnd)
+}
+
+//meanAndDeviation returns the mean and the population standard deviation of the values of the given time steps, which mustn't be empty
+func meanAndDeviation(data []collector.TimeStepData) (float64, float64) {
 
 	//1st loop to calculate Sum and Mean
+	sum := 0.0
 	for _, stepData := range data {
 		sum += stepData.Value
 	}
-	mean = sum / float64(count)
+	mean := sum / float64(len(data))
 
 	//2nd loop to calculate Standard Deviation
+	sd := 0.0
 	for _, stepData := range data {
 		sd += math.Pow(stepData.Value-mean, 2)
 	}
-	sd = math.Sqrt(sd / float64(count))
+	return mean, math.Sqrt(sd / float64(len(data)))
+}
 
-	//Calculating the Z-Score limits for warnings and alarms
-	strongLimit := strongOutliersMultiplier * sd
-	weakLimit := outliersMultiplier * sd
+//Const block defines the outlier level of each time step used to build event periods
+const (
+	levelNormal = iota
+	levelWarning
+	levelAlarm
+)
+
+//eventPeriodsFromLevels takes the outlier level of each time step and groups consecutive steps into warning and alarm event periods
+//Each event period ends at the start of the following step or at the given period end for the last step
+func eventPeriodsFromLevels(data []collector.TimeStepData, levels []int, PeriodEnd time.Time) ([]eventPeriod, []eventPeriod) {
 
 	//Initializing the resulting event periods
 	warnings := []eventPeriod{}
 	alarms := []eventPeriod{}
 
-	//3rd loop to identify metric values that fall above the warning or alarm Z-score limits
+	//Loop to identify metric values classified as warnings or alarms
 	//A state machine keeps track if the beginning of an event period has been detected already and if it's an alarm or warning
 	beginStep := -1
 	strongEvent := false
 	for ind := 0; ind < len(data); ind++ {
 
-		//Z-Score above alarm limit
+		//Alarm level
 		//If no event was previously detected, it registers the start of a new alarm period
 		//If a warning start was previously detected, it closes the warning and registers the start of a new alarm period
 		//If an alarm start was previously detected, it does nothing and proceeds within the loop
-		if math.Abs(data[ind].Value-mean) > strongLimit {
+		if levels[ind] == levelAlarm {
 			if beginStep == -1 {
 				beginStep = ind
 				strongEvent = true
@@ -156,11 +419,11 @@ func detectOutliers3Sigmas(data []collector.TimeStepData, PeriodEnd time.Time, o
 				strongEvent = true
 			}
 
-			//Z-Score above warning limit
+			//Warning level
 			//If no event was previously detected, it registers the start of a new warning period
 			//If a warning start was previously detected, it does nothing and proceeds within the loop
 			//If an alarm start was previously detected, it closes the alarm and registers the start of a new warning period
-		} else if math.Abs(data[ind].Value-mean) > weakLimit {
+		} else if levels[ind] == levelWarning {
 			if beginStep == -1 {
 				beginStep = ind
 				strongEvent = false
@@ -174,7 +437,7 @@ func detectOutliers3Sigmas(data []collector.TimeStepData, PeriodEnd time.Time, o
 				strongEvent = false
 			}
 
-			//Z-Score normal
+			//Normal level
 			//If no event was previously detected, it does nothing and proceeds within the loop
 			//If a warning start was previously detected, it closes it
 			//If an alarm start was previously detected, it closes it
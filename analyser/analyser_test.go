@@ -1,11 +1,14 @@
 package analyser
 
 import (
+	"fmt"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/ftfmtavares/anomalies-detector/collector"
+	"github.com/ftfmtavares/anomalies-detector/config"
 )
 
 func TestDetectOutliers3Sigmas(t *testing.T) {
@@ -60,3 +63,169 @@ func TestDetectOutliers3Sigmas(t *testing.T) {
 		})
 	}
 }
+
+func TestGetResultsResolution(t *testing.T) {
+	timeRef := time.Now()
+
+	values := []float64{221, 254, 270, 264, 244, 241, 238, 243, 277, 237, 254, 289, 278, 264, 265, 243, 284, 244, 212, 242, 271, 243, 252, 230, 238, 214, 234, 1027, 1057, 911}
+	data := make([]collector.TimeStepData, len(values))
+	for i, val := range values {
+		data[i] = collector.TimeStepData{DateStart: timeRef.Add(time.Duration(i-len(values)) * time.Hour), Value: val, Samples: 100}
+	}
+	siteData := collector.SiteData{
+		SiteId:   "site",
+		TimeStep: "1h",
+		DateEnd:  timeRef,
+		Metrics:  []collector.MetricData{{Metric: "Revenue", Attributes: []string{"Total"}, AttributeData: map[string][]collector.TimeStepData{"Total": data}}},
+	}
+	dataConf := config.Dataset{SiteId: "site", TimeStep: "1h", OutliersDetectionMethod: "3-sigmas"}
+	methodParams := config.DetectionMethodsParams{ThreeSigmas: config.ThreeSigmasParams{OutliersMultiplier: 2, StrongOutliersMultiplier: 3}}
+
+	report := GetResults(siteData, dataConf, methodParams)
+	if len(report.Result.Alarms) == 0 {
+		t.Fatalf("GetResults() got no alarms")
+	}
+	for _, event := range append(report.Result.Alarms, report.Result.Warnings...) {
+		if event.Resolution != "1h" {
+			t.Errorf("GetResults() event resolution = %s, want 1h", event.Resolution)
+		}
+	}
+}
+
+func TestGetResultsShadow(t *testing.T) {
+	timeRef := time.Date(2022, 9, 1, 0, 0, 0, 0, time.UTC)
+
+	//A steady series with a mild bump on the 20th day and a spike on the 26th, the shadow method being the more sensitive one
+	data := make([]collector.TimeStepData, 30)
+	for day := range data {
+		data[day] = collector.TimeStepData{DateStart: timeRef.AddDate(0, 0, day), Value: 100 + float64(day%3), Samples: 100}
+	}
+	data[19].Value = 115
+	data[25].Value = 400
+	siteData := collector.SiteData{
+		SiteId:   "site",
+		TimeStep: "1d",
+		DateEnd:  timeRef.AddDate(0, 0, 30),
+		Metrics:  []collector.MetricData{{Metric: "Revenue", Attributes: []string{"Total"}, AttributeData: map[string][]collector.TimeStepData{"Total": data}}},
+	}
+	methodParams := config.DetectionMethodsParams{RollingThreeSigmas: config.RollingThreeSigmasParams{WindowSize: 7}}.WithMultipliers(2, 3)
+	dataConf := config.Dataset{SiteId: "site", TimeStep: "1d", OutliersDetectionMethod: "3-sigmas"}
+	shadowConf := dataConf
+	shadowConf.OutliersDetectionMethod = "rolling-3-sigmas"
+
+	plain := GetResults(siteData, dataConf, methodParams)
+	alone := GetResults(siteData, shadowConf, methodParams)
+	dataConf.ShadowDetectionMethod = "rolling-3-sigmas"
+	report := GetResults(siteData, dataConf, methodParams)
+
+	//The main results are the same with or without the shadow method, whose events are those it detects alone
+	if report.Shadow == nil || report.ShadowDetectionMethod != "rolling-3-sigmas" {
+		t.Fatalf("GetResults() shadow = %v, method %s, want the rolling-3-sigmas events", report.Shadow, report.ShadowDetectionMethod)
+	}
+	if !reflect.DeepEqual(report.Result, plain.Result) {
+		t.Errorf("GetResults() result = %v, want %v as without the shadow method", report.Result, plain.Result)
+	}
+	periods := func(events []OutlierEvent) []string {
+		res := []string{}
+		for _, event := range events {
+			res = append(res, fmt.Sprintf("%s/%s %s-%s %s", event.Metric, event.Attribute, event.OutlierPeriodStart.Format("01-02"), event.OutlierPeriodEnd.Format("01-02"), event.Resolution))
+		}
+		return res
+	}
+	if got, want := periods(report.Shadow.Alarms), periods(alone.Result.Alarms); !reflect.DeepEqual(got, want) {
+		t.Errorf("GetResults() shadow alarms = %v, want %v", got, want)
+	}
+	if got, want := periods(report.Shadow.Warnings), periods(alone.Result.Warnings); !reflect.DeepEqual(got, want) {
+		t.Errorf("GetResults() shadow warnings = %v, want %v", got, want)
+	}
+	if len(report.Shadow.Alarms) <= len(report.Result.Alarms) {
+		t.Errorf("GetResults() shadow alarms = %v, want the bump flagged apart from the main alarms %v", periods(report.Shadow.Alarms), periods(report.Result.Alarms))
+	}
+}
+
+func TestDetectSiteOutliersParallelism(t *testing.T) {
+	timeRef := time.Date(2022, 9, 1, 0, 0, 0, 0, time.UTC)
+
+	//Every attribute gets a spike on a different day so the events order tells their attribute order
+	siteData := collector.SiteData{SiteId: "site", TimeStep: "1d", DateEnd: timeRef.AddDate(0, 0, 30)}
+	wantAttributes := []string{}
+	for _, metric := range []string{"Revenue", "Visits"} {
+		metricData := collector.MetricData{Metric: metric, AttributeData: map[string][]collector.TimeStepData{}}
+		for ind := 0; ind < 12; ind++ {
+			attribute := fmt.Sprintf("Browser>B%02d", ind)
+			data := make([]collector.TimeStepData, 30)
+			for day := range data {
+				data[day] = collector.TimeStepData{DateStart: timeRef.AddDate(0, 0, day), Value: 100 + float64(day%2), Samples: 100}
+			}
+			data[ind+5].Value = 1000
+			metricData.Attributes = append(metricData.Attributes, attribute)
+			metricData.AttributeData[attribute] = data
+			wantAttributes = append(wantAttributes, metric+"/"+attribute)
+		}
+		siteData.Metrics = append(siteData.Metrics, metricData)
+	}
+
+	sequential := detectSiteOutliers(siteData, []string{"3-sigmas"}, 0, config.DetectionMethodsParams{AttributeParallelism: 1, ThreeSigmas: config.ThreeSigmasParams{OutliersMultiplier: 2, StrongOutliersMultiplier: 3}}, nil)
+	parallel := detectSiteOutliers(siteData, []string{"3-sigmas"}, 0, config.DetectionMethodsParams{AttributeParallelism: 8, ThreeSigmas: config.ThreeSigmasParams{OutliersMultiplier: 2, StrongOutliersMultiplier: 3}}, nil)
+	if !reflect.DeepEqual(sequential, parallel) {
+		t.Errorf("detectSiteOutliers() parallel = %v, want %v", parallel, sequential)
+	}
+
+	gotAttributes := []string{}
+	for _, alarm := range parallel.Alarms {
+		gotAttributes = append(gotAttributes, alarm.Metric+"/"+alarm.Attribute)
+	}
+	if !reflect.DeepEqual(gotAttributes, wantAttributes) {
+		t.Errorf("detectSiteOutliers() alarms order = %v, want %v", gotAttributes, wantAttributes)
+	}
+}
+
+func TestDetectSiteOutliersMetricDetection(t *testing.T) {
+	timeRef := time.Date(2022, 9, 1, 0, 0, 0, 0, time.UTC)
+
+	//Both metrics get the same spike, flagged on Visits with the site settings and left out on Revenue by its own multipliers
+	siteData := collector.SiteData{SiteId: "site", TimeStep: "1d", DateEnd: timeRef.AddDate(0, 0, 30)}
+	for _, metric := range []string{"Revenue", "Visits"} {
+		data := make([]collector.TimeStepData, 30)
+		for day := range data {
+			data[day] = collector.TimeStepData{DateStart: timeRef.AddDate(0, 0, day), Value: 100 + float64(day%2), Samples: 100}
+		}
+		data[20].Value = 1000
+		siteData.Metrics = append(siteData.Metrics, collector.MetricData{Metric: metric, Attributes: []string{"Total"}, AttributeData: map[string][]collector.TimeStepData{"Total": data}})
+	}
+	methodParams := config.DetectionMethodsParams{ThreeSigmas: config.ThreeSigmasParams{OutliersMultiplier: 2, StrongOutliersMultiplier: 3}}
+
+	tests := []struct {
+		name            string
+		metricDetection map[string]config.MetricDetection
+		want            []string
+	}{
+		{name: "Site settings", want: []string{"Revenue/3-sigmas", "Visits/3-sigmas"}},
+		{
+			name: "Metric parameters",
+			metricDetection: map[string]config.MetricDetection{
+				"Revenue": {DetectionMethods: &config.DetectionMethodsParams{ThreeSigmas: config.ThreeSigmasParams{OutliersMultiplier: 50, StrongOutliersMultiplier: 60}}},
+			},
+			want: []string{"Visits/3-sigmas"},
+		},
+		{
+			name: "Metric method",
+			metricDetection: map[string]config.MetricDetection{
+				"Visits": {OutliersDetectionMethod: "esd", DetectionMethods: &config.DetectionMethodsParams{Esd: config.EsdParams{MaxOutliers: 3, Alpha: 0.05}}},
+			},
+			want: []string{"Revenue/3-sigmas", "Visits/esd"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results := detectSiteOutliers(siteData, []string{"3-sigmas"}, 0, methodParams, tt.metricDetection)
+			got := []string{}
+			for _, event := range append(results.Alarms, results.Warnings...) {
+				got = append(got, event.Metric+"/"+strings.Join(event.Methods, "+"))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("detectSiteOutliers() events = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
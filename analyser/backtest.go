@@ -0,0 +1,151 @@
+package analyser
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/ftfmtavares/anomalies-detector/collector"
+	"github.com/ftfmtavares/anomalies-detector/config"
+)
+
+//BacktestSweep provides the structure for the values a detection method parameter is swept through, from From to To by Step
+//Parameter field is the name of the parameter on the config file, e.g. "outliersMultiplier" or "windowSize"
+type BacktestSweep struct {
+	Method    string  `json:"method"`
+	Parameter string  `json:"parameter"`
+	From      float64 `json:"from"`
+	To        float64 `json:"to"`
+	Step      float64 `json:"step"`
+}
+
+//BacktestRun provides the structure for the detection accuracy of a method over all sites with one of the swept values of its parameter
+type BacktestRun struct {
+	Value float64     `json:"value"`
+	Score MethodScore `json:"score"`
+}
+
+//Values returns the swept values in increasing order, the last one being To if the steps reach it
+//It returns an error if the method, parameter or range are invalid, or if any value doesn't suit the parameter
+func (sweep BacktestSweep) Values() ([]float64, error) {
+	if _, err := SetMethodParam(config.DetectionMethodsParams{}, sweep.Method, sweep.Parameter, sweep.From); err != nil {
+		return nil, err
+	}
+	if sweep.Step <= 0 || sweep.To < sweep.From {
+		return nil, fmt.Errorf("invalid range from %g to %g by %g, a positive step up to a higher or equal value expected", sweep.From, sweep.To, sweep.Step)
+	}
+
+	//Rounding every value so repeated float steps don't drift from the expected ones
+	values := []float64{}
+	for i := 0; ; i++ {
+		value := math.Round((sweep.From+float64(i)*sweep.Step)*1e9) / 1e9
+		if value > sweep.To {
+			break
+		}
+		if _, err := SetMethodParam(config.DetectionMethodsParams{}, sweep.Method, sweep.Parameter, value); err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+	}
+	return values, nil
+}
+
+//SetMethodParam returns the detection methods parameters with a parameter of a method set to the given value, parameters being named as on the config file
+//It returns an error if the method or parameter is unknown or if the value doesn't suit the parameter (e.g. a fraction for a window size)
+func SetMethodParam(methodParams config.DetectionMethodsParams, method, parameter string, value float64) (config.DetectionMethodsParams, error) {
+	encoded, _ := json.Marshal(methodParams)
+	params := map[string]json.RawMessage{}
+	json.Unmarshal(encoded, &params)
+	methodValues := map[string]interface{}{}
+	if err := json.Unmarshal(params[method], &methodValues); err != nil || !isDetectionMethod(method) {
+		return methodParams, fmt.Errorf("unknown detection method \"%s\", it must be one of %s", method, strings.Join(config.DetectionMethods, ", "))
+	}
+	if _, found := methodValues[parameter]; !found {
+		return methodParams, fmt.Errorf("unknown parameter \"%s\" of %s", parameter, method)
+	}
+	methodValues[parameter] = value
+
+	params[method], _ = json.Marshal(methodValues)
+	encoded, _ = json.Marshal(params)
+	res := config.DetectionMethodsParams{}
+	if err := json.Unmarshal(encoded, &res); err != nil {
+		return methodParams, fmt.Errorf("invalid value %g for parameter \"%s\" of %s", value, parameter, method)
+	}
+	return res, nil
+}
+
+//isDetectionMethod checks if a method is one of the supported detection methods
+func isDetectionMethod(method string) bool {
+	for _, supported := range config.DetectionMethods {
+		if method == supported {
+			return true
+		}
+	}
+	return false
+}
+
+//methodParam returns the value of a parameter of a method, named as on the config file, and whether it was found
+func methodParam(methodParams config.DetectionMethodsParams, method, parameter string) (float64, bool) {
+	encoded, _ := json.Marshal(methodParams)
+	params := map[string]map[string]float64{}
+	json.Unmarshal(encoded, &params)
+	value, found := params[method][parameter]
+	return value, found
+}
+
+//BacktestSite scores the swept method over the site data with each value of its parameter, the other parameters and the site settings being those of the site
+//The detection profile of the site, if any, is applied before the parameter is set, so it doesn't override the swept values
+//A swept outliers multiplier moves the strong outliers multiplier of the method along with it, keeping the configured gap between them
+func BacktestSite(siteData collector.SiteData, dataConf config.Dataset, methodParams config.DetectionMethodsParams, labels []Label, sweep BacktestSweep, values []float64) ([]MethodScore, error) {
+	dataConf, methodParams, err := dataConf.ApplyDetectionProfile(methodParams)
+	if err != nil {
+		return nil, err
+	}
+	dataConf.DetectionProfile = ""
+
+	//Moving the strong multiplier along with a swept outliers multiplier, keeping their gap, so values above it still tell apart from each other
+	gap, moveStrong := 0.0, false
+	if sweep.Parameter == "outliersMultiplier" {
+		outliers, _ := methodParam(methodParams, sweep.Method, "outliersMultiplier")
+		strong, found := methodParam(methodParams, sweep.Method, "strongOutliersMultiplier")
+		gap, moveStrong = strong-outliers, found
+	}
+
+	scores := []MethodScore{}
+	for _, value := range values {
+		params, err := SetMethodParam(methodParams, sweep.Method, sweep.Parameter, value)
+		if err == nil && moveStrong {
+			params, err = SetMethodParam(params, sweep.Method, "strongOutliersMultiplier", value+gap)
+		}
+		if err != nil {
+			return nil, err
+		}
+		evaluation := EvaluateMethods(siteData, dataConf, params, labels, []string{sweep.Method})
+		scores = append(scores, evaluation.Scores[0])
+	}
+	return scores, nil
+}
+
+//FormatBacktest returns the backtest runs as an aligned text table, one swept value per line, marking the one with the highest F1
+func FormatBacktest(sweep BacktestSweep, runs []BacktestRun) string {
+	best := -1
+	for i, run := range runs {
+		if run.Score.F1 > 0 && (best < 0 || run.Score.F1 > runs[best].Score.F1) {
+			best = i
+		}
+	}
+
+	header := sweep.Method + " " + sweep.Parameter
+	var table strings.Builder
+	table.WriteString(fmt.Sprintf("%-*s %9s %7s %6s %6s %6s %9s %7s %6s\n", len(header), header, "incidents", "events", "TP", "FP", "FN", "precision", "recall", "F1"))
+	for i, run := range runs {
+		mark := ""
+		if i == best {
+			mark = " *"
+		}
+		score := run.Score
+		table.WriteString(fmt.Sprintf("%-*g %9d %7d %6d %6d %6d %9.3f %7.3f %6.3f%s\n", len(header), run.Value, score.Incidents, score.Events, score.TruePositives, score.FalsePositives, score.FalseNegatives, score.Precision, score.Recall, score.F1, mark))
+	}
+	return table.String()
+}
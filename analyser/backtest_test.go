@@ -0,0 +1,125 @@
+package analyser
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ftfmtavares/anomalies-detector/collector"
+	"github.com/ftfmtavares/anomalies-detector/collector/synthetic"
+	"github.com/ftfmtavares/anomalies-detector/config"
+)
+
+func TestSetMethodParam(t *testing.T) {
+	methodParams := config.DetectionMethodsParams{
+		ThreeSigmas:          config.ThreeSigmasParams{OutliersMultiplier: 2, StrongOutliersMultiplier: 3},
+		RollingThreeSigmas:   config.RollingThreeSigmasParams{WindowSize: 7, OutliersMultiplier: 2, StrongOutliersMultiplier: 3},
+		AttributeParallelism: 4,
+	}
+
+	tests := []struct {
+		name      string
+		method    string
+		parameter string
+		value     float64
+		want      config.DetectionMethodsParams
+		wantErr   bool
+	}{
+		{
+			name:      "Multiplier",
+			method:    "3-sigmas",
+			parameter: "outliersMultiplier",
+			value:     2.5,
+			want: config.DetectionMethodsParams{
+				ThreeSigmas:          config.ThreeSigmasParams{OutliersMultiplier: 2.5, StrongOutliersMultiplier: 3},
+				RollingThreeSigmas:   config.RollingThreeSigmasParams{WindowSize: 7, OutliersMultiplier: 2, StrongOutliersMultiplier: 3},
+				AttributeParallelism: 4,
+			},
+		},
+		{
+			name:      "Window size",
+			method:    "rolling-3-sigmas",
+			parameter: "windowSize",
+			value:     14,
+			want: config.DetectionMethodsParams{
+				ThreeSigmas:          config.ThreeSigmasParams{OutliersMultiplier: 2, StrongOutliersMultiplier: 3},
+				RollingThreeSigmas:   config.RollingThreeSigmasParams{WindowSize: 14, OutliersMultiplier: 2, StrongOutliersMultiplier: 3},
+				AttributeParallelism: 4,
+			},
+		},
+		{name: "Fractional window size", method: "rolling-3-sigmas", parameter: "windowSize", value: 7.5, wantErr: true},
+		{name: "Not a method", method: "attributeParallelism", parameter: "outliersMultiplier", value: 2, wantErr: true},
+		{name: "Unknown method", method: "median", parameter: "outliersMultiplier", value: 2, wantErr: true},
+		{name: "Unknown parameter", method: "3-sigmas", parameter: "windowSize", value: 2, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := SetMethodParam(methodParams, tt.method, tt.parameter, tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("SetMethodParam() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("SetMethodParam() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBacktestSweepValues(t *testing.T) {
+	tests := []struct {
+		name    string
+		sweep   BacktestSweep
+		want    []float64
+		wantErr bool
+	}{
+		{name: "Multipliers", sweep: BacktestSweep{Method: "3-sigmas", Parameter: "outliersMultiplier", From: 2, To: 5, Step: 0.5}, want: []float64{2, 2.5, 3, 3.5, 4, 4.5, 5}},
+		{name: "Inexact steps", sweep: BacktestSweep{Method: "ewma", Parameter: "alpha", From: 0.1, To: 0.3, Step: 0.1}, want: []float64{0.1, 0.2, 0.3}},
+		{name: "Single value", sweep: BacktestSweep{Method: "3-sigmas", Parameter: "outliersMultiplier", From: 3, To: 3, Step: 1}, want: []float64{3}},
+		{name: "No step", sweep: BacktestSweep{Method: "3-sigmas", Parameter: "outliersMultiplier", From: 2, To: 5}, wantErr: true},
+		{name: "Reversed range", sweep: BacktestSweep{Method: "3-sigmas", Parameter: "outliersMultiplier", From: 5, To: 2, Step: 1}, wantErr: true},
+		{name: "Fractional window sizes", sweep: BacktestSweep{Method: "rolling-3-sigmas", Parameter: "windowSize", From: 4, To: 8, Step: 1.5}, wantErr: true},
+		{name: "Unknown parameter", sweep: BacktestSweep{Method: "esd", Parameter: "outliersMultiplier", From: 2, To: 5, Step: 1}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.sweep.Values()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Values() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Values() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBacktestSite(t *testing.T) {
+	//A spike injected on a flat series is found with a low multiplier and missed with a high one, even if the site profile sets other multipliers
+	timeRef := time.Date(2022, 9, 1, 0, 0, 0, 0, time.UTC)
+	data := []collector.TimeStepData{}
+	for i := 0; i < 48; i++ {
+		data = append(data, collector.TimeStepData{DateStart: timeRef.Add(time.Duration(i) * time.Hour), Value: 100 + float64(i%3), Samples: 10})
+	}
+	data[30].Value = 400
+	siteData := collector.SiteData{SiteId: "shop", TimeStep: "1h", DateStart: timeRef, DateEnd: timeRef.Add(48 * time.Hour), Metrics: []collector.MetricData{{
+		Metric:        "Revenue",
+		Attributes:    []string{"Total"},
+		AttributeData: map[string][]collector.TimeStepData{"Total": data},
+		Injected:      []synthetic.Outlier{{Attribute: "Total", PeriodStart: data[30].DateStart, PeriodEnd: data[30].DateStart.Add(time.Hour), Diff: 300}},
+	}}}
+	dataConf := config.Dataset{SiteId: "shop", TimeAgo: "2d", TimeStep: "1h", OutliersDetectionMethod: "esd", DetectionProfile: "sensitive"}
+	methodParams := config.DetectionMethodsParams{ThreeSigmas: config.ThreeSigmasParams{OutliersMultiplier: 2, StrongOutliersMultiplier: 100}}
+	sweep := BacktestSweep{Method: "3-sigmas", Parameter: "outliersMultiplier"}
+
+	got, err := BacktestSite(siteData, dataConf, methodParams, GroundTruth(siteData), sweep, []float64{2, 50})
+	if err != nil {
+		t.Fatalf("BacktestSite() error = %v", err)
+	}
+	want := []MethodScore{
+		{Method: "3-sigmas", Incidents: 1, Events: 1, TruePositives: 1, Precision: 1, Recall: 1, F1: 1},
+		{Method: "3-sigmas", Incidents: 1, FalseNegatives: 1},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("BacktestSite() = %+v, want %+v", got, want)
+	}
+}
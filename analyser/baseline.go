@@ -0,0 +1,77 @@
+package analyser
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/ftfmtavares/anomalies-detector/collector"
+	"github.com/ftfmtavares/anomalies-detector/config"
+	"github.com/ftfmtavares/anomalies-detector/utils"
+)
+
+//ParseBaselineResets parses the baseline reset dates of a dataset, in date order, along with its burn-in period
+//It returns an error if any date or the burn-in period is invalid
+func ParseBaselineResets(dataConf config.Dataset) ([]time.Time, time.Duration, error) {
+	resets := []time.Time{}
+	for _, value := range dataConf.BaselineResets {
+		reset, err := time.Parse("2006-01-02", value)
+		if err != nil {
+			if reset, err = time.Parse(time.RFC3339, value); err != nil {
+				return nil, 0, fmt.Errorf("invalid baseline reset \"%s\", it must be a date or an RFC 3339 time", value)
+			}
+		}
+		resets = append(resets, reset)
+	}
+	sort.Slice(resets, func(i, j int) bool { return resets[i].Before(resets[j]) })
+
+	var burnIn time.Duration
+	if dataConf.BurnIn != "" {
+		var err error
+		if burnIn, err = utils.StrToDuration(dataConf.BurnIn); err != nil || burnIn < 0 {
+			return nil, 0, fmt.Errorf("invalid burn-in \"%s\"", dataConf.BurnIn)
+		}
+	}
+	return resets, burnIn, nil
+}
+
+//latestBaselineReset returns the latest reset before the end of the analysed period, if any
+func latestBaselineReset(resets []time.Time, dateEnd time.Time) (time.Time, bool) {
+	for i := len(resets) - 1; i >= 0; i-- {
+		if resets[i].Before(dateEnd) {
+			return resets[i], true
+		}
+	}
+	return time.Time{}, false
+}
+
+//trimBaseline returns a copy of the site data without the time steps starting before the reset
+func trimBaseline(siteData collector.SiteData, reset time.Time) collector.SiteData {
+	metrics := make([]collector.MetricData, len(siteData.Metrics))
+	for i, metricData := range siteData.Metrics {
+		trimmed := metricData
+		trimmed.AttributeData = map[string][]collector.TimeStepData{}
+		for attribute, data := range metricData.AttributeData {
+			start := sort.Search(len(data), func(ind int) bool { return !data[ind].DateStart.Before(reset) })
+			trimmed.AttributeData[attribute] = data[start:]
+		}
+		metrics[i] = trimmed
+	}
+	siteData.Metrics = metrics
+	siteData.DateStart = reset
+	return siteData
+}
+
+//dropBurnIn removes the events starting before the end of the burn-in period
+func dropBurnIn(results OutlierResults, burnInEnd time.Time) OutlierResults {
+	keep := func(events []OutlierEvent) []OutlierEvent {
+		res := []OutlierEvent{}
+		for _, event := range events {
+			if !event.OutlierPeriodStart.Before(burnInEnd) {
+				res = append(res, event)
+			}
+		}
+		return res
+	}
+	return OutlierResults{Warnings: keep(results.Warnings), Alarms: keep(results.Alarms)}
+}
@@ -0,0 +1,103 @@
+package analyser
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ftfmtavares/anomalies-detector/collector"
+	"github.com/ftfmtavares/anomalies-detector/config"
+)
+
+func TestGetResultsBaselineReset(t *testing.T) {
+	timeRef := time.Date(2022, 9, 1, 0, 0, 0, 0, time.UTC)
+
+	//A retagging on day 25 multiplies the tracked revenue by 10
+	values := []float64{}
+	for day := 0; day < 30; day++ {
+		value := 100.0 + float64(day%2)
+		if day >= 25 {
+			value = 1000 + float64(day%2)
+		}
+		values = append(values, value)
+	}
+	data := make([]collector.TimeStepData, len(values))
+	for i, val := range values {
+		data[i] = collector.TimeStepData{DateStart: timeRef.AddDate(0, 0, i), Value: val, Samples: 100}
+	}
+	siteData := collector.SiteData{
+		SiteId:    "site",
+		TimeStep:  "1d",
+		DateStart: timeRef,
+		DateEnd:   timeRef.AddDate(0, 0, len(values)),
+		Metrics:   []collector.MetricData{{Metric: "Revenue", Attributes: []string{"Total"}, AttributeData: map[string][]collector.TimeStepData{"Total": data}}},
+	}
+	methodParams := config.DetectionMethodsParams{ThreeSigmas: config.ThreeSigmasParams{OutliersMultiplier: 2, StrongOutliersMultiplier: 3}}
+
+	tests := []struct {
+		name       string
+		resets     []string
+		burnIn     string
+		wantStarts []time.Time
+		wantReset  bool
+	}{
+		{
+			name:       "Step change flagged without reset",
+			wantStarts: []time.Time{timeRef.AddDate(0, 0, 25)},
+		},
+		{
+			name:      "Data before the reset discarded",
+			resets:    []string{"2022-08-01", "2022-09-26T00:00:00Z"},
+			wantReset: true,
+		},
+		{
+			name:       "Events after the burn-in kept",
+			resets:     []string{"2022-09-01"},
+			burnIn:     "20d",
+			wantStarts: []time.Time{timeRef.AddDate(0, 0, 25)},
+			wantReset:  true,
+		},
+		{
+			name:      "Events within the burn-in dropped",
+			resets:    []string{"2022-09-01"},
+			burnIn:    "26d",
+			wantReset: true,
+		},
+		{
+			name:       "Resets after the period ignored",
+			resets:     []string{"2022-12-01"},
+			wantStarts: []time.Time{timeRef.AddDate(0, 0, 25)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dataConf := config.Dataset{SiteId: "site", TimeStep: "1d", OutliersDetectionMethod: "3-sigmas", BaselineResets: tt.resets, BurnIn: tt.burnIn}
+			report := GetResults(siteData, dataConf, methodParams)
+
+			starts := []time.Time{}
+			for _, event := range append(report.Result.Alarms, report.Result.Warnings...) {
+				starts = append(starts, event.OutlierPeriodStart)
+			}
+			if len(starts) != len(tt.wantStarts) {
+				t.Fatalf("GetResults() event starts = %v, want %v", starts, tt.wantStarts)
+			}
+			for _, want := range tt.wantStarts {
+				found := false
+				for _, start := range starts {
+					found = found || start.Equal(want)
+				}
+				if !found {
+					t.Errorf("GetResults() event starts = %v, want %v", starts, tt.wantStarts)
+				}
+			}
+			if (report.BaselineReset != nil) != tt.wantReset {
+				t.Errorf("GetResults() baseline reset = %v, want %v", report.BaselineReset, tt.wantReset)
+			}
+		})
+	}
+
+	for _, dataConf := range []config.Dataset{{BaselineResets: []string{"21/09/2022"}}, {BurnIn: "5x"}} {
+		if _, _, err := ParseBaselineResets(dataConf); err == nil {
+			t.Errorf("ParseBaselineResets() expected an error for %v", dataConf)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package analyser
+
+import (
+	"math"
+	"sort"
+
+	"github.com/ftfmtavares/anomalies-detector/collector"
+)
+
+//maxProbableCauses is the number of child attribute paths listed as probable causes of an alarm
+const maxProbableCauses = 3
+
+//ProbableCause provides the structure for a child attribute path ranked among the probable causes of an alarm of "Total"
+//Contribution field is how far the mean of the path strayed from its baseline mean during the alarm, weighted by its share of the samples for Average metrics,
+//while Share field is that contribution in percent of the deviation of "Total"
+type ProbableCause struct {
+	Attribute    string  `json:"attribute"`
+	Contribution float64 `json:"contribution"`
+	Share        float64 `json:"share"`
+}
+
+//attachProbableCauses ranks, for every alarm of "Total", the direct child attribute paths of its metric by their contribution to its deviation
+func attachProbableCauses(results OutlierResults, siteData collector.SiteData) {
+	for i, alarm := range results.Alarms {
+		if alarm.Attribute != "Total" {
+			continue
+		}
+		for _, metricData := range siteData.Metrics {
+			if metricData.Metric == alarm.Metric {
+				results.Alarms[i].ProbableCauses = probableCauses(metricData, alarm)
+				break
+			}
+		}
+	}
+}
+
+//probableCauses returns the direct child attribute paths of "Total" that moved in the direction of its deviation during an event, the largest contribution first
+//Children are the paths without a parent path on the metric, so every top attribute level is ranked alongside the others, and at most maxProbableCauses are returned
+func probableCauses(metricData collector.MetricData, event OutlierEvent) []ProbableCause {
+	total, totalSamples, found := periodDeviation(metricData.AttributeData["Total"], event)
+	if !found || total == 0 {
+		return nil
+	}
+	weighted := metricData.Aggregation() == "mean"
+
+	causes := []ProbableCause{}
+	for _, attribute := range metricData.Attributes {
+		if attribute == "Total" || hasParentPath(metricData.Attributes, attribute) {
+			continue
+		}
+		contribution, samples, found := periodDeviation(metricData.AttributeData[attribute], event)
+		if !found {
+			continue
+		}
+		if weighted {
+			if totalSamples == 0 {
+				continue
+			}
+			contribution *= float64(samples) / float64(totalSamples)
+		}
+		if contribution*total <= 0 {
+			continue
+		}
+		causes = append(causes, ProbableCause{Attribute: attribute, Contribution: contribution, Share: contribution / total * 100})
+	}
+	sort.SliceStable(causes, func(i, j int) bool { return math.Abs(causes[i].Contribution) > math.Abs(causes[j].Contribution) })
+	if len(causes) > maxProbableCauses {
+		causes = causes[:maxProbableCauses]
+	}
+	return causes
+}
+
+//periodDeviation returns the difference between the mean of a series during an event and its mean outside of it, along with the samples during the event
+//Missing time steps are left out, the deviation not being found if the event or the rest of the series has none left
+func periodDeviation(data []collector.TimeStepData, event OutlierEvent) (deviation float64, samples int, found bool) {
+	var inside, outside float64
+	insideCount, outsideCount := 0, 0
+	for _, stepData := range data {
+		if stepData.Missing {
+			continue
+		}
+		if !stepData.DateStart.Before(event.OutlierPeriodStart) && stepData.DateStart.Before(event.OutlierPeriodEnd) {
+			inside += stepData.Value
+			samples += stepData.Samples
+			insideCount++
+		} else {
+			outside += stepData.Value
+			outsideCount++
+		}
+	}
+	if insideCount == 0 || outsideCount == 0 {
+		return 0, 0, false
+	}
+	return inside/float64(insideCount) - outside/float64(outsideCount), samples, true
+}
+
+//hasParentPath checks if any other attribute path than "Total" is a parent of the given one
+func hasParentPath(attributes []string, attribute string) bool {
+	for _, parent := range attributes {
+		if parent != "Total" && isParentAttribute(parent, attribute) {
+			return true
+		}
+	}
+	return false
+}
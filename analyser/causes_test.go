@@ -0,0 +1,58 @@
+package analyser
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ftfmtavares/anomalies-detector/collector"
+	"github.com/ftfmtavares/anomalies-detector/config"
+)
+
+func TestProbableCauses(t *testing.T) {
+	timeRef := time.Date(2022, 9, 1, 0, 0, 0, 0, time.UTC)
+
+	//Edge drives the spike of the last 2 days, Chrome moving a bit along with it and Firefox going the other way, the Edge versions being left to their parent
+	series := func(base, last float64, samples int) []collector.TimeStepData {
+		data := make([]collector.TimeStepData, 10)
+		for day := range data {
+			data[day] = collector.TimeStepData{DateStart: timeRef.AddDate(0, 0, day), Value: base, Samples: samples}
+			if day >= 8 {
+				data[day].Value = last
+			}
+		}
+		return data
+	}
+	metricData := collector.MetricData{
+		Metric:     "Revenue",
+		Type:       config.MetricTypeSum,
+		Attributes: []string{"Total", "Browser>Chrome", "Browser>Edge", "Browser>Edge>v1", "Browser>Firefox"},
+		AttributeData: map[string][]collector.TimeStepData{
+			"Total":           series(300, 400, 40),
+			"Browser>Chrome":  series(100, 120, 10),
+			"Browser>Edge":    series(100, 190, 10),
+			"Browser>Edge>v1": series(50, 140, 5),
+			"Browser>Firefox": series(100, 90, 10),
+		},
+	}
+	event := OutlierEvent{Metric: "Revenue", Attribute: "Total", OutlierPeriodStart: timeRef.AddDate(0, 0, 8), OutlierPeriodEnd: timeRef.AddDate(0, 0, 10)}
+
+	want := []ProbableCause{{Attribute: "Browser>Edge", Contribution: 90, Share: 90}, {Attribute: "Browser>Chrome", Contribution: 20, Share: 20}}
+	if got := probableCauses(metricData, event); !reflect.DeepEqual(got, want) {
+		t.Errorf("probableCauses() = %+v, want %+v", got, want)
+	}
+
+	//Average metrics weight the deviation of every path by its share of the samples
+	metricData.Type = config.MetricTypeAverage
+	want = []ProbableCause{{Attribute: "Browser>Edge", Contribution: 22.5, Share: 22.5}, {Attribute: "Browser>Chrome", Contribution: 5, Share: 5}}
+	if got := probableCauses(metricData, event); !reflect.DeepEqual(got, want) {
+		t.Errorf("probableCauses() = %+v, want %+v", got, want)
+	}
+
+	//Only alarms of "Total" get probable causes
+	results := OutlierResults{Alarms: []OutlierEvent{event, {Metric: "Revenue", Attribute: "Browser>Edge", OutlierPeriodStart: event.OutlierPeriodStart, OutlierPeriodEnd: event.OutlierPeriodEnd}}}
+	attachProbableCauses(results, collector.SiteData{Metrics: []collector.MetricData{metricData}})
+	if len(results.Alarms[0].ProbableCauses) != 2 || results.Alarms[1].ProbableCauses != nil {
+		t.Errorf("attachProbableCauses() = %+v, want causes on the Total alarm only", results.Alarms)
+	}
+}
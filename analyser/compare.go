@@ -0,0 +1,151 @@
+package analyser
+
+import (
+	"encoding/json"
+	"log"
+	"os"
+	"time"
+)
+
+//ReportsComparison provides the structure to store the differences between the events of 2 runs
+type ReportsComparison struct {
+	OnlyInA         []ComparedEvent `json:"onlyInA"`
+	OnlyInB         []ComparedEvent `json:"onlyInB"`
+	ChangedSeverity []ComparedEvent `json:"changedSeverity"`
+	Unchanged       int             `json:"unchanged"`
+}
+
+//ComparedEvent provides the structure to store an event present in at least one of the compared runs
+//Fields related to the run where the event is missing are left empty
+type ComparedEvent struct {
+	SiteId       string     `json:"siteId"`
+	Metric       string     `json:"metric"`
+	Attribute    string     `json:"attribute"`
+	Resolution   string     `json:"resolution,omitempty"`
+	SeverityA    string     `json:"severityA,omitempty"`
+	PeriodStartA *time.Time `json:"periodStartA,omitempty"`
+	PeriodEndA   *time.Time `json:"periodEndA,omitempty"`
+	SeverityB    string     `json:"severityB,omitempty"`
+	PeriodStartB *time.Time `json:"periodStartB,omitempty"`
+	PeriodEndB   *time.Time `json:"periodEndB,omitempty"`
+}
+
+//severityEvent is an OutlierEvent tagged with its site and severity, used while comparing runs
+type severityEvent struct {
+	siteId   string
+	severity string
+	event    OutlierEvent
+}
+
+//ReadReportsFile reads a report file previously written by the application
+//Encrypted records are skipped since they can't be compared without their keys
+func ReadReportsFile(reportFile string) ([]OutlierReport, error) {
+	byteValue, err := os.ReadFile(reportFile)
+	if err != nil {
+		return nil, err
+	}
+
+	var records []json.RawMessage
+	if err := json.Unmarshal(byteValue, &records); err != nil {
+		return nil, err
+	}
+
+	reports := []OutlierReport{}
+	for _, record := range records {
+		var probe struct {
+			SiteId     string `json:"siteId"`
+			Ciphertext string `json:"ciphertext"`
+		}
+		if err := json.Unmarshal(record, &probe); err != nil {
+			return nil, err
+		}
+		if probe.Ciphertext != "" {
+			log.Printf("Skipping encrypted report of site %s\n", probe.SiteId)
+			continue
+		}
+
+		var report OutlierReport
+		if err := json.Unmarshal(record, &report); err != nil {
+			return nil, err
+		}
+		reports = append(reports, report)
+	}
+
+	return reports, nil
+}
+
+//CompareReports compares the events of 2 runs and returns their differences
+//Events of the same site, resolution, metric and attribute are considered the same when their periods overlap, so small shifts
+//caused by configuration changes don't show up as unrelated events, while a different severity is reported as changed
+func CompareReports(reportsA, reportsB []OutlierReport) ReportsComparison {
+	res := ReportsComparison{
+		OnlyInA:         []ComparedEvent{},
+		OnlyInB:         []ComparedEvent{},
+		ChangedSeverity: []ComparedEvent{},
+	}
+
+	eventsA := flattenEvents(reportsA)
+	eventsB := flattenEvents(reportsB)
+	matchedB := make([]bool, len(eventsB))
+
+	for _, a := range eventsA {
+		match := -1
+		for i, b := range eventsB {
+			if !matchedB[i] && a.siteId == b.siteId && a.event.Metric == b.event.Metric && a.event.Attribute == b.event.Attribute && a.event.Resolution == b.event.Resolution &&
+				a.event.OutlierPeriodStart.Before(b.event.OutlierPeriodEnd) && b.event.OutlierPeriodStart.Before(a.event.OutlierPeriodEnd) {
+				match = i
+				break
+			}
+		}
+
+		if match == -1 {
+			res.OnlyInA = append(res.OnlyInA, comparedEvent(&a, nil))
+			continue
+		}
+		matchedB[match] = true
+		if a.severity != eventsB[match].severity {
+			res.ChangedSeverity = append(res.ChangedSeverity, comparedEvent(&a, &eventsB[match]))
+		} else {
+			res.Unchanged++
+		}
+	}
+
+	for i := range eventsB {
+		if !matchedB[i] {
+			res.OnlyInB = append(res.OnlyInB, comparedEvent(nil, &eventsB[i]))
+		}
+	}
+
+	return res
+}
+
+//flattenEvents lists all alarms and warnings of the given reports tagged with their site and severity
+func flattenEvents(reports []OutlierReport) []severityEvent {
+	events := []severityEvent{}
+	for _, report := range reports {
+		for _, alarm := range report.Result.Alarms {
+			events = append(events, severityEvent{siteId: report.SiteId, severity: "alarm", event: alarm})
+		}
+		for _, warning := range report.Result.Warnings {
+			events = append(events, severityEvent{siteId: report.SiteId, severity: "warning", event: warning})
+		}
+	}
+	return events
+}
+
+//comparedEvent builds a ComparedEvent from the events found in each run, any of them being optional
+func comparedEvent(a, b *severityEvent) ComparedEvent {
+	//Periods are copied so the result doesn't point to the callers variables
+	res := ComparedEvent{}
+	if a != nil {
+		start, end := a.event.OutlierPeriodStart, a.event.OutlierPeriodEnd
+		res.SiteId, res.Metric, res.Attribute, res.Resolution = a.siteId, a.event.Metric, a.event.Attribute, a.event.Resolution
+		res.SeverityA, res.PeriodStartA, res.PeriodEndA = a.severity, &start, &end
+	}
+	if b != nil {
+		start, end := b.event.OutlierPeriodStart, b.event.OutlierPeriodEnd
+		res.SiteId, res.Metric, res.Attribute, res.Resolution = b.siteId, b.event.Metric, b.event.Attribute, b.event.Resolution
+		res.SeverityB, res.PeriodStartB, res.PeriodEndB = b.severity, &start, &end
+	}
+	return res
+}
@@ -0,0 +1,54 @@
+package analyser
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCompareReports(t *testing.T) {
+	timeRef := time.Date(2022, 9, 1, 0, 0, 0, 0, time.UTC)
+
+	reportsA := []OutlierReport{
+		{
+			SiteId: "site",
+			Result: OutlierResults{
+				Alarms: []OutlierEvent{
+					{OutlierPeriodStart: timeRef, OutlierPeriodEnd: timeRef.AddDate(0, 0, 2), Metric: "Revenue", Attribute: "Total"},
+				},
+				Warnings: []OutlierEvent{
+					{OutlierPeriodStart: timeRef, OutlierPeriodEnd: timeRef.AddDate(0, 0, 1), Metric: "Basket", Attribute: "Total"},
+					{OutlierPeriodStart: timeRef.AddDate(0, 0, 5), OutlierPeriodEnd: timeRef.AddDate(0, 0, 6), Metric: "Visits", Attribute: "Total"},
+				},
+			},
+		},
+	}
+	reportsB := []OutlierReport{
+		{
+			SiteId: "site",
+			Result: OutlierResults{
+				Alarms: []OutlierEvent{
+					{OutlierPeriodStart: timeRef.AddDate(0, 0, 1), OutlierPeriodEnd: timeRef.AddDate(0, 0, 2), Metric: "Revenue", Attribute: "Total"},
+					{OutlierPeriodStart: timeRef, OutlierPeriodEnd: timeRef.AddDate(0, 0, 1), Metric: "Basket", Attribute: "Total"},
+				},
+				Warnings: []OutlierEvent{
+					{OutlierPeriodStart: timeRef.AddDate(0, 0, 8), OutlierPeriodEnd: timeRef.AddDate(0, 0, 9), Metric: "Visits", Attribute: "Total"},
+				},
+			},
+		},
+	}
+
+	got := CompareReports(reportsA, reportsB)
+
+	if got.Unchanged != 1 {
+		t.Errorf("CompareReports().Unchanged = %d, want 1", got.Unchanged)
+	}
+	if len(got.ChangedSeverity) != 1 || got.ChangedSeverity[0].Metric != "Basket" || got.ChangedSeverity[0].SeverityA != "warning" || got.ChangedSeverity[0].SeverityB != "alarm" {
+		t.Errorf("CompareReports().ChangedSeverity = %v, want Basket warning -> alarm", got.ChangedSeverity)
+	}
+	if len(got.OnlyInA) != 1 || !got.OnlyInA[0].PeriodStartA.Equal(timeRef.AddDate(0, 0, 5)) || got.OnlyInA[0].PeriodStartB != nil {
+		t.Errorf("CompareReports().OnlyInA = %v, want Visits starting at %v", got.OnlyInA, timeRef.AddDate(0, 0, 5))
+	}
+	if len(got.OnlyInB) != 1 || !got.OnlyInB[0].PeriodStartB.Equal(timeRef.AddDate(0, 0, 8)) || got.OnlyInB[0].PeriodStartA != nil {
+		t.Errorf("CompareReports().OnlyInB = %v, want Visits starting at %v", got.OnlyInB, timeRef.AddDate(0, 0, 8))
+	}
+}
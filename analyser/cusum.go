@@ -0,0 +1,78 @@
+package analyser
+
+import (
+	"log"
+	"math"
+	"time"
+
+	"github.com/ftfmtavares/anomalies-detector/collector"
+)
+
+//detectOutliersCusum implements the cusum (cumulative sum) changepoint method
+//Deviations from the baseline mean are accumulated separately upwards and downwards, discounting the drift on every time step,
+//and an alarm is raised when any sum exceeds the threshold, so persistent level shifts are detected even if no single point is an outlier
+//Alarms start at the time step where the shift began (the last time the sum left zero) and last while the sum remains positive
+//This method only produces alarms
+func detectOutliersCusum(data []collector.TimeStepData, PeriodEnd time.Time, baselineSize int, drift, threshold float64) ([]eventPeriod, []eventPeriod) {
+	levels := make([]int, len(data))
+	if baselineSize < 2 || baselineSize > len(data) {
+		log.Printf("Invalid cusum baseline size %d for %d time steps\n", baselineSize, len(data))
+		return eventPeriodsFromLevels(data, levels, PeriodEnd)
+	}
+
+	//Calculating the baseline Mean and Standard Deviation
+	mean := 0.0
+	for _, stepData := range data[:baselineSize] {
+		mean += stepData.Value
+	}
+	mean /= float64(baselineSize)
+	sd := 0.0
+	for _, stepData := range data[:baselineSize] {
+		sd += math.Pow(stepData.Value-mean, 2)
+	}
+	sd = math.Sqrt(sd / float64(baselineSize))
+	if sd == 0 {
+		log.Printf("Constant cusum baseline, no shift can be measured\n")
+		return eventPeriodsFromLevels(data, levels, PeriodEnd)
+	}
+
+	//Upwards and downwards sums are tracked independently with the step where each one left zero and whether it's alarming
+	upSum, downSum := 0.0, 0.0
+	upStart, downStart := 0, 0
+	upAlarm, downAlarm := false, false
+	for ind := range data {
+		z := (data[ind].Value - mean) / sd
+
+		if upSum == 0 {
+			upStart = ind
+		}
+		upSum = math.Max(0, upSum+z-drift)
+		if upSum == 0 {
+			upAlarm = false
+		} else if upSum > threshold && !upAlarm {
+			upAlarm = true
+			for shiftStep := upStart; shiftStep < ind; shiftStep++ {
+				levels[shiftStep] = levelAlarm
+			}
+		}
+
+		if downSum == 0 {
+			downStart = ind
+		}
+		downSum = math.Max(0, downSum-z-drift)
+		if downSum == 0 {
+			downAlarm = false
+		} else if downSum > threshold && !downAlarm {
+			downAlarm = true
+			for shiftStep := downStart; shiftStep < ind; shiftStep++ {
+				levels[shiftStep] = levelAlarm
+			}
+		}
+
+		if upAlarm || downAlarm {
+			levels[ind] = levelAlarm
+		}
+	}
+
+	return eventPeriodsFromLevels(data, levels, PeriodEnd)
+}
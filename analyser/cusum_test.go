@@ -0,0 +1,63 @@
+package analyser
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ftfmtavares/anomalies-detector/collector"
+)
+
+func TestDetectOutliersCusum(t *testing.T) {
+	timeRef := time.Now()
+
+	stable := []float64{}
+	shifted := []float64{}
+	for i := 0; i < 30; i++ {
+		value := 100 + float64(i%2)*2 - 1
+		stable = append(stable, value)
+		if i >= 20 {
+			value -= 3
+		}
+		shifted = append(shifted, value)
+	}
+
+	tests := []struct {
+		name           string
+		values         []float64
+		wantedWarnings []eventPeriod
+		wantedAlarms   []eventPeriod
+	}{
+		{
+			name:           "Level shift of -3 sigmas from sample #21 is an alarm until the end",
+			values:         shifted,
+			wantedWarnings: []eventPeriod{},
+			wantedAlarms:   []eventPeriod{{outlierPeriodStart: timeRef.AddDate(0, 0, -10), outlierPeriodEnd: timeRef}},
+		},
+		{
+			name:           "Stable values",
+			values:         stable,
+			wantedWarnings: []eventPeriod{},
+			wantedAlarms:   []eventPeriod{},
+		},
+	}
+
+	for _, tt := range tests {
+		data := make([]collector.TimeStepData, len(tt.values))
+		for i, val := range tt.values {
+			data[i].Samples = 100
+			data[i].DateStart = timeRef.AddDate(0, 0, -len(tt.values)+i)
+			data[i].Value = val
+		}
+
+		t.Run(tt.name, func(t *testing.T) {
+			warnings, alarms := detectOutliersCusum(data, timeRef, 10, 0.5, 5)
+			if !reflect.DeepEqual(warnings, tt.wantedWarnings) {
+				t.Errorf("detectOutliersCusum() got = %v, want %v", warnings, tt.wantedWarnings)
+			}
+			if !reflect.DeepEqual(alarms, tt.wantedAlarms) {
+				t.Errorf("detectOutliersCusum() got1 = %v, want %v", alarms, tt.wantedAlarms)
+			}
+		})
+	}
+}
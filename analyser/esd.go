@@ -0,0 +1,190 @@
+package analyser
+
+import (
+	"log"
+	"math"
+	"time"
+
+	"github.com/ftfmtavares/anomalies-detector/collector"
+)
+
+//detectOutliersEsd implements the esd (generalized extreme studentized deviate) method
+//The most extreme value is repeatedly removed up to maxOutliers times, and the number of outliers is the largest number of removals
+//whose test statistic exceeds the respective critical value for the given significance level
+//Outliers at the alpha level are alarms, while those only found at the looser warningAlpha level are warnings
+func detectOutliersEsd(data []collector.TimeStepData, PeriodEnd time.Time, maxOutliers int, alpha, warningAlpha float64) ([]eventPeriod, []eventPeriod) {
+	levels := make([]int, len(data))
+	if maxOutliers < 1 || len(data)-maxOutliers < 3 || alpha <= 0 || alpha >= 1 {
+		log.Printf("Invalid esd parameters, max outliers %d and alpha %.3f for %d time steps\n", maxOutliers, alpha, len(data))
+		return eventPeriodsFromLevels(data, levels, PeriodEnd)
+	}
+
+	//Ordering the candidates by removal and keeping their test statistics
+	candidates, statistics := esdCandidates(data, maxOutliers)
+
+	if warningAlpha > 0 && warningAlpha < 1 {
+		for _, ind := range candidates[:esdOutliersCount(statistics, len(data), warningAlpha)] {
+			levels[ind] = levelWarning
+		}
+	}
+	for _, ind := range candidates[:esdOutliersCount(statistics, len(data), alpha)] {
+		levels[ind] = levelAlarm
+	}
+
+	return eventPeriodsFromLevels(data, levels, PeriodEnd)
+}
+
+//esdCandidates removes the value farthest from the mean of the remaining values maxOutliers times
+//It returns the removed indexes in order along with the respective test statistics |x - mean| / sd
+func esdCandidates(data []collector.TimeStepData, maxOutliers int) ([]int, []float64) {
+	removed := make([]bool, len(data))
+	candidates := []int{}
+	statistics := []float64{}
+
+	for i := 0; i < maxOutliers; i++ {
+		count := 0
+		mean := 0.0
+		for ind, stepData := range data {
+			if !removed[ind] {
+				mean += stepData.Value
+				count++
+			}
+		}
+		mean /= float64(count)
+		sd := 0.0
+		for ind, stepData := range data {
+			if !removed[ind] {
+				sd += math.Pow(stepData.Value-mean, 2)
+			}
+		}
+		sd = math.Sqrt(sd / float64(count-1))
+
+		farthest := -1
+		for ind, stepData := range data {
+			if !removed[ind] && (farthest == -1 || math.Abs(stepData.Value-mean) > math.Abs(data[farthest].Value-mean)) {
+				farthest = ind
+			}
+		}
+		statistic := 0.0
+		if sd > 0 {
+			statistic = math.Abs(data[farthest].Value-mean) / sd
+		}
+
+		removed[farthest] = true
+		candidates = append(candidates, farthest)
+		statistics = append(statistics, statistic)
+	}
+
+	return candidates, statistics
+}
+
+//esdOutliersCount returns the largest number of removals whose statistic exceeds the critical value at the given significance level
+func esdOutliersCount(statistics []float64, n int, alpha float64) int {
+	count := 0
+	for i, statistic := range statistics {
+		if statistic > esdCriticalValue(n, i+1, alpha) {
+			count = i + 1
+		}
+	}
+	return count
+}
+
+//esdCriticalValue returns the critical value of the i-th removal (1 based) out of n values at the given significance level
+func esdCriticalValue(n, i int, alpha float64) float64 {
+	p := 1 - alpha/(2*float64(n-i+1))
+	df := float64(n - i - 1)
+	t := studentTQuantile(p, df)
+	return float64(n-i) * t / math.Sqrt((df+t*t)*float64(n-i+1))
+}
+
+//studentTQuantile returns the value whose Student t cumulative probability with df degrees of freedom is p (p >= 0.5)
+//The inverse is found by bisection over the cumulative distribution function
+func studentTQuantile(p, df float64) float64 {
+	low, high := 0.0, 1.0
+	for studentTCdf(high, df) < p {
+		high *= 2
+	}
+	for i := 0; i < 100; i++ {
+		middle := (low + high) / 2
+		if studentTCdf(middle, df) < p {
+			low = middle
+		} else {
+			high = middle
+		}
+	}
+	return (low + high) / 2
+}
+
+//studentTCdf returns the Student t cumulative probability of a non-negative t with df degrees of freedom
+func studentTCdf(t, df float64) float64 {
+	return 1 - 0.5*regularizedIncompleteBeta(df/2, 0.5, df/(df+t*t))
+}
+
+//regularizedIncompleteBeta returns I_x(a, b) evaluated with its continued fraction expansion
+func regularizedIncompleteBeta(a, b, x float64) float64 {
+	if x <= 0 {
+		return 0
+	}
+	if x >= 1 {
+		return 1
+	}
+	lgammaA, _ := math.Lgamma(a)
+	lgammaB, _ := math.Lgamma(b)
+	lgammaAB, _ := math.Lgamma(a + b)
+	front := math.Exp(lgammaAB - lgammaA - lgammaB + a*math.Log(x) + b*math.Log(1-x))
+
+	//The continued fraction converges quickly only for x below (a + 1) / (a + b + 2), the symmetry relation is used otherwise
+	if x < (a+1)/(a+b+2) {
+		return front * betaContinuedFraction(a, b, x) / a
+	}
+	return 1 - front*betaContinuedFraction(b, a, 1-x)/b
+}
+
+//betaContinuedFraction evaluates the incomplete beta continued fraction with the modified Lentz method
+func betaContinuedFraction(a, b, x float64) float64 {
+	const (
+		epsilon = 1e-14
+		tiny    = 1e-300
+	)
+	c := 1.0
+	d := 1 - (a+b)*x/(a+1)
+	if math.Abs(d) < tiny {
+		d = tiny
+	}
+	d = 1 / d
+	res := d
+	for m := 1; m <= 300; m++ {
+		fm := float64(m)
+
+		//Even step
+		numerator := fm * (b - fm) * x / ((a + 2*fm - 1) * (a + 2*fm))
+		d = 1 + numerator*d
+		if math.Abs(d) < tiny {
+			d = tiny
+		}
+		c = 1 + numerator/c
+		if math.Abs(c) < tiny {
+			c = tiny
+		}
+		d = 1 / d
+		res *= d * c
+
+		//Odd step
+		numerator = -(a + fm) * (a + b + fm) * x / ((a + 2*fm) * (a + 2*fm + 1))
+		d = 1 + numerator*d
+		if math.Abs(d) < tiny {
+			d = tiny
+		}
+		c = 1 + numerator/c
+		if math.Abs(c) < tiny {
+			c = tiny
+		}
+		d = 1 / d
+		delta := d * c
+		res *= delta
+		if math.Abs(delta-1) < epsilon {
+			break
+		}
+	}
+	return res
+}
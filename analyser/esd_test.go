@@ -0,0 +1,70 @@
+package analyser
+
+import (
+	"math"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ftfmtavares/anomalies-detector/collector"
+)
+
+func TestEsdCriticalValue(t *testing.T) {
+	//Critical values of the NIST/SEMATECH e-Handbook generalized ESD example, with 54 values and alpha 0.05
+	want := []float64{3.158, 3.151, 3.143, 3.136, 3.128, 3.120, 3.111, 3.103, 3.094, 3.085}
+	for i, lambda := range want {
+		if got := esdCriticalValue(54, i+1, 0.05); math.Abs(got-lambda) > 0.001 {
+			t.Errorf("esdCriticalValue(54, %d, 0.05) = %.4f, want %.3f", i+1, got, lambda)
+		}
+	}
+}
+
+func TestDetectOutliersEsd(t *testing.T) {
+	timeRef := time.Now()
+
+	values := []float64{221, 254, 270, 264, 244, 241, 238, 243, 277, 237, 254, 289, 278, 264, 265, 243, 284, 244, 212, 242, 271, 243, 252, 230, 238, 214, 234, 1027, 1057, 911}
+	data := make([]collector.TimeStepData, len(values))
+	for i, val := range values {
+		data[i].Samples = 100
+		data[i].DateStart = timeRef.AddDate(0, 0, -len(values)+i)
+		data[i].Value = val
+	}
+
+	tests := []struct {
+		name           string
+		maxOutliers    int
+		wantedWarnings []eventPeriod
+		wantedAlarms   []eventPeriod
+	}{
+		{
+			name:           "Samples #28-#30 are outliers",
+			maxOutliers:    5,
+			wantedWarnings: []eventPeriod{},
+			wantedAlarms:   []eventPeriod{{outlierPeriodStart: timeRef.AddDate(0, 0, -3), outlierPeriodEnd: timeRef}},
+		},
+		{
+			name:           "Samples #28-#29 are the 2 most extreme values tested",
+			maxOutliers:    2,
+			wantedWarnings: []eventPeriod{},
+			wantedAlarms:   []eventPeriod{{outlierPeriodStart: timeRef.AddDate(0, 0, -3), outlierPeriodEnd: timeRef.AddDate(0, 0, -1)}},
+		},
+		{
+			name:           "Invalid max outliers",
+			maxOutliers:    0,
+			wantedWarnings: []eventPeriod{},
+			wantedAlarms:   []eventPeriod{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			warnings, alarms := detectOutliersEsd(data, timeRef, tt.maxOutliers, 0.01, 0.05)
+			if !reflect.DeepEqual(warnings, tt.wantedWarnings) {
+				t.Errorf("detectOutliersEsd() got = %v, want %v", warnings, tt.wantedWarnings)
+			}
+			if !reflect.DeepEqual(alarms, tt.wantedAlarms) {
+				t.Errorf("detectOutliersEsd() got1 = %v, want %v", alarms, tt.wantedAlarms)
+			}
+		})
+	}
+}
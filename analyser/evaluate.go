@@ -0,0 +1,167 @@
+package analyser
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ftfmtavares/anomalies-detector/collector"
+	"github.com/ftfmtavares/anomalies-detector/config"
+)
+
+//MethodScore provides the structure for the detection accuracy of a method against the incidents labelled on the analysed data
+//An event is a true positive if it overlaps an incident of its metric on its attribute path or below it, since deviations are carried up to the parent paths, and a false positive otherwise
+//An incident is found if an event of its metric and attribute path overlaps it, missed incidents being the false negatives
+//Precision, Recall and F1 fields are 0 when they can't be computed (no events, no incidents or neither found)
+type MethodScore struct {
+	Method         string  `json:"method"`
+	Incidents      int     `json:"incidents"`
+	Events         int     `json:"events"`
+	TruePositives  int     `json:"truePositives"`
+	FalsePositives int     `json:"falsePositives"`
+	FalseNegatives int     `json:"falseNegatives"`
+	Precision      float64 `json:"precision"`
+	Recall         float64 `json:"recall"`
+	F1             float64 `json:"f1"`
+}
+
+//Evaluation provides the structure for the detection accuracy of every evaluated method on a site at one of its time steps
+type Evaluation struct {
+	SiteId   string        `json:"siteId"`
+	TimeStep string        `json:"timeStep"`
+	Scores   []MethodScore `json:"scores"`
+}
+
+//GroundTruth returns the deviations injected into generated site data as incident labels
+//Deviations on attribute paths left out of the data by the collection filters are dropped, as nothing can detect them
+func GroundTruth(siteData collector.SiteData) []Label {
+	labels := []Label{}
+	for _, metricData := range siteData.Metrics {
+		for _, injected := range metricData.Injected {
+			if _, found := metricData.AttributeData[injected.Attribute]; !found {
+				continue
+			}
+			labels = append(labels, Label{SiteId: siteData.SiteId, Metric: metricData.Metric, Attribute: injected.Attribute, PeriodStart: injected.PeriodStart, PeriodEnd: injected.PeriodEnd, Verdict: VerdictIncident})
+		}
+	}
+	return labels
+}
+
+//EvaluateMethods runs each given detection method alone over the site data, with the site settings otherwise, and scores its alarms and warnings against the incidents of the site
+//Metric detection settings keep their parameters only, so every metric is analysed with the evaluated method
+//Only the incident labels of the site overlapping the analysed period are used, false alarm labels adding nothing since unlabelled events already count as false positives
+func EvaluateMethods(siteData collector.SiteData, dataConf config.Dataset, methodParams config.DetectionMethodsParams, labels []Label, methods []string) Evaluation {
+	incidents := siteIncidents(siteData, labels)
+	evaluation := Evaluation{SiteId: siteData.SiteId, TimeStep: siteData.TimeStep, Scores: []MethodScore{}}
+	for _, method := range methods {
+		methodConf := dataConf
+		methodConf.OutliersDetectionMethod, methodConf.OutliersDetectionMethods, methodConf.Consensus = method, nil, 0
+		methodConf.ShadowDetectionMethod, methodConf.Objectives = "", nil
+		methodConf.MetricDetection = config.MetricParamsOnly(dataConf.MetricDetection)
+		report := GetResults(siteData, methodConf, methodParams)
+		events := append(append([]OutlierEvent{}, report.Result.Alarms...), report.Result.Warnings...)
+		evaluation.Scores = append(evaluation.Scores, ScoreEvents(method, events, incidents))
+	}
+	return evaluation
+}
+
+//siteIncidents returns the incident labels of a site overlapping the period of its data
+func siteIncidents(siteData collector.SiteData, labels []Label) []Label {
+	incidents := []Label{}
+	for _, label := range labels {
+		if label.SiteId == siteData.SiteId && label.Verdict == VerdictIncident && overlaps(label.PeriodStart, label.PeriodEnd, siteData.DateStart, siteData.DateEnd) {
+			incidents = append(incidents, label)
+		}
+	}
+	return incidents
+}
+
+//ScoreEvents scores the detected events of a method against the labelled incidents
+func ScoreEvents(method string, events []OutlierEvent, incidents []Label) MethodScore {
+	score := MethodScore{Method: method, Incidents: len(incidents), Events: len(events)}
+	for _, event := range events {
+		matched := false
+		for _, incident := range incidents {
+			if incident.Metric == event.Metric && (incident.Attribute == event.Attribute || isParentAttribute(event.Attribute, incident.Attribute)) &&
+				overlaps(event.OutlierPeriodStart, event.OutlierPeriodEnd, incident.PeriodStart, incident.PeriodEnd) {
+				matched = true
+				break
+			}
+		}
+		if matched {
+			score.TruePositives++
+		} else {
+			score.FalsePositives++
+		}
+	}
+	for _, incident := range incidents {
+		found := false
+		for _, event := range events {
+			if event.Metric == incident.Metric && event.Attribute == incident.Attribute && overlaps(event.OutlierPeriodStart, event.OutlierPeriodEnd, incident.PeriodStart, incident.PeriodEnd) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			score.FalseNegatives++
+		}
+	}
+	return score.withRates()
+}
+
+//SumScores adds up the scores of every method over several evaluations, in the order methods are first evaluated
+func SumScores(evaluations []Evaluation) []MethodScore {
+	totals := []MethodScore{}
+	positions := map[string]int{}
+	for _, evaluation := range evaluations {
+		for _, score := range evaluation.Scores {
+			position, found := positions[score.Method]
+			if !found {
+				position = len(totals)
+				positions[score.Method] = position
+				totals = append(totals, MethodScore{Method: score.Method})
+			}
+			total := &totals[position]
+			total.Incidents += score.Incidents
+			total.Events += score.Events
+			total.TruePositives += score.TruePositives
+			total.FalsePositives += score.FalsePositives
+			total.FalseNegatives += score.FalseNegatives
+		}
+	}
+	for i := range totals {
+		totals[i] = totals[i].withRates()
+	}
+	return totals
+}
+
+//withRates returns the score with its precision, recall and F1 computed from its counts
+//Recall is computed over the incidents, since an incident found by several events still counts once
+func (score MethodScore) withRates() MethodScore {
+	score.Precision, score.Recall, score.F1 = 0, 0, 0
+	if score.Events > 0 {
+		score.Precision = float64(score.TruePositives) / float64(score.Events)
+	}
+	if score.Incidents > 0 {
+		score.Recall = float64(score.Incidents-score.FalseNegatives) / float64(score.Incidents)
+	}
+	if score.Precision+score.Recall > 0 {
+		score.F1 = 2 * score.Precision * score.Recall / (score.Precision + score.Recall)
+	}
+	return score
+}
+
+//overlaps checks if two periods share any time
+func overlaps(startA, endA, startB, endB time.Time) bool {
+	return startA.Before(endB) && startB.Before(endA)
+}
+
+//FormatScores returns the scores as an aligned text table, one method per line
+func FormatScores(scores []MethodScore) string {
+	var table strings.Builder
+	table.WriteString(fmt.Sprintf("%-18s %9s %7s %6s %6s %6s %9s %7s %6s\n", "method", "incidents", "events", "TP", "FP", "FN", "precision", "recall", "F1"))
+	for _, score := range scores {
+		table.WriteString(fmt.Sprintf("%-18s %9d %7d %6d %6d %6d %9.3f %7.3f %6.3f\n", score.Method, score.Incidents, score.Events, score.TruePositives, score.FalsePositives, score.FalseNegatives, score.Precision, score.Recall, score.F1))
+	}
+	return table.String()
+}
@@ -0,0 +1,116 @@
+package analyser
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ftfmtavares/anomalies-detector/collector"
+	"github.com/ftfmtavares/anomalies-detector/collector/synthetic"
+	"github.com/ftfmtavares/anomalies-detector/config"
+)
+
+func TestGroundTruth(t *testing.T) {
+	timeRef := time.Date(2022, 9, 1, 0, 0, 0, 0, time.UTC)
+	siteData := collector.SiteData{SiteId: "shop", Metrics: []collector.MetricData{{
+		Metric:        "Revenue",
+		Attributes:    []string{"Total", "Browser>Edge"},
+		AttributeData: map[string][]collector.TimeStepData{"Total": {}, "Browser>Edge": {}},
+		Injected: []synthetic.Outlier{
+			{Attribute: "Total", PeriodStart: timeRef, PeriodEnd: timeRef.Add(2 * time.Hour), Diff: 100},
+			{Attribute: "Browser>Edge", PeriodStart: timeRef.Add(5 * time.Hour), PeriodEnd: timeRef.Add(6 * time.Hour), Diff: -50},
+			{Attribute: "Browser>Chrome>v1", PeriodStart: timeRef, PeriodEnd: timeRef.Add(time.Hour), Diff: 10},
+		},
+	}}}
+
+	want := []Label{
+		{SiteId: "shop", Metric: "Revenue", Attribute: "Total", PeriodStart: timeRef, PeriodEnd: timeRef.Add(2 * time.Hour), Verdict: VerdictIncident},
+		{SiteId: "shop", Metric: "Revenue", Attribute: "Browser>Edge", PeriodStart: timeRef.Add(5 * time.Hour), PeriodEnd: timeRef.Add(6 * time.Hour), Verdict: VerdictIncident},
+	}
+	if got := GroundTruth(siteData); !reflect.DeepEqual(got, want) {
+		t.Errorf("GroundTruth() = %v, want %v", got, want)
+	}
+}
+
+func TestScoreEvents(t *testing.T) {
+	timeRef := time.Date(2022, 9, 1, 0, 0, 0, 0, time.UTC)
+	incidents := []Label{
+		{SiteId: "shop", Metric: "Revenue", Attribute: "Total", PeriodStart: timeRef, PeriodEnd: timeRef.Add(2 * time.Hour), Verdict: VerdictIncident},
+		{SiteId: "shop", Metric: "Revenue", Attribute: "Browser>Edge", PeriodStart: timeRef.Add(5 * time.Hour), PeriodEnd: timeRef.Add(6 * time.Hour), Verdict: VerdictIncident},
+		{SiteId: "shop", Metric: "Basket", Attribute: "Total", PeriodStart: timeRef.Add(10 * time.Hour), PeriodEnd: timeRef.Add(11 * time.Hour), Verdict: VerdictIncident},
+	}
+
+	tests := []struct {
+		name   string
+		events []OutlierEvent
+		want   MethodScore
+	}{
+		{
+			name: "Found, carried up and false events",
+			events: []OutlierEvent{
+				{Metric: "Revenue", Attribute: "Total", OutlierPeriodStart: timeRef.Add(time.Hour), OutlierPeriodEnd: timeRef.Add(3 * time.Hour)},
+				{Metric: "Revenue", Attribute: "Browser>Edge", OutlierPeriodStart: timeRef.Add(5 * time.Hour), OutlierPeriodEnd: timeRef.Add(6 * time.Hour)},
+				{Metric: "Revenue", Attribute: "Browser", OutlierPeriodStart: timeRef.Add(5 * time.Hour), OutlierPeriodEnd: timeRef.Add(6 * time.Hour)},
+				{Metric: "Revenue", Attribute: "Browser>Edge", OutlierPeriodStart: timeRef.Add(2 * time.Hour), OutlierPeriodEnd: timeRef.Add(3 * time.Hour)},
+			},
+			want: MethodScore{Method: "3-sigmas", Incidents: 3, Events: 4, TruePositives: 3, FalsePositives: 1, FalseNegatives: 1, Precision: 0.75, Recall: 2.0 / 3, F1: 2 * 0.75 * (2.0 / 3) / (0.75 + 2.0/3)},
+		},
+		{
+			name: "Carried up event doesn't find the incident below",
+			events: []OutlierEvent{
+				{Metric: "Revenue", Attribute: "Total", OutlierPeriodStart: timeRef.Add(5 * time.Hour), OutlierPeriodEnd: timeRef.Add(6 * time.Hour)},
+			},
+			want: MethodScore{Method: "3-sigmas", Incidents: 3, Events: 1, TruePositives: 1, FalseNegatives: 3, Precision: 1},
+		},
+		{
+			name: "No events",
+			want: MethodScore{Method: "3-sigmas", Incidents: 3, FalseNegatives: 3},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ScoreEvents("3-sigmas", tt.events, incidents); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ScoreEvents() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSumScores(t *testing.T) {
+	evaluations := []Evaluation{
+		{SiteId: "shop", Scores: []MethodScore{{Method: "3-sigmas", Incidents: 2, Events: 2, TruePositives: 2}, {Method: "esd", Incidents: 2, Events: 1, TruePositives: 1, FalseNegatives: 1}}},
+		{SiteId: "blog", Scores: []MethodScore{{Method: "esd", Incidents: 2, Events: 1, FalsePositives: 1, FalseNegatives: 2}, {Method: "3-sigmas", Incidents: 2, Events: 2, TruePositives: 2}}},
+	}
+	got := SumScores(evaluations)
+	want := []MethodScore{
+		{Method: "3-sigmas", Incidents: 4, Events: 4, TruePositives: 4, Precision: 1, Recall: 1, F1: 1},
+		{Method: "esd", Incidents: 4, Events: 2, TruePositives: 1, FalsePositives: 1, FalseNegatives: 3, Precision: 0.5, Recall: 0.25, F1: 2 * 0.5 * 0.25 / 0.75},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("SumScores() = %+v, want %+v", got, want)
+	}
+}
+
+func TestEvaluateMethods(t *testing.T) {
+	//A spike injected on a flat series is found by the 3-sigmas method without any other event
+	timeRef := time.Date(2022, 9, 1, 0, 0, 0, 0, time.UTC)
+	data := []collector.TimeStepData{}
+	for i := 0; i < 48; i++ {
+		data = append(data, collector.TimeStepData{DateStart: timeRef.Add(time.Duration(i) * time.Hour), Value: 100 + float64(i%3), Samples: 10})
+	}
+	data[30].Value = 400
+	siteData := collector.SiteData{SiteId: "shop", TimeStep: "1h", DateStart: timeRef, DateEnd: timeRef.Add(48 * time.Hour), Metrics: []collector.MetricData{{
+		Metric:        "Revenue",
+		Attributes:    []string{"Total"},
+		AttributeData: map[string][]collector.TimeStepData{"Total": data},
+		Injected:      []synthetic.Outlier{{Attribute: "Total", PeriodStart: data[30].DateStart, PeriodEnd: data[30].DateStart.Add(time.Hour), Diff: 300}},
+	}}}
+	dataConf := config.Dataset{SiteId: "shop", TimeAgo: "2d", TimeStep: "1h", OutliersDetectionMethod: "esd", ShadowDetectionMethod: "ewma"}
+	methodParams := config.DetectionMethodsParams{ThreeSigmas: config.ThreeSigmasParams{OutliersMultiplier: 2, StrongOutliersMultiplier: 3}}
+
+	got := EvaluateMethods(siteData, dataConf, methodParams, GroundTruth(siteData), []string{"3-sigmas"})
+	want := Evaluation{SiteId: "shop", TimeStep: "1h", Scores: []MethodScore{{Method: "3-sigmas", Incidents: 1, Events: 1, TruePositives: 1, Precision: 1, Recall: 1, F1: 1}}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("EvaluateMethods() = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,79 @@
+package analyser
+
+import (
+	"log"
+	"math"
+	"time"
+
+	"github.com/ftfmtavares/anomalies-detector/collector"
+)
+
+//detectOutliersEwma implements the ewma (exponentially weighted moving average) method
+//Each metric value is compared against the exponentially weighted mean and standard deviation of the values before it,
+//so the baseline follows slow drifts while sudden deviations are still flagged
+//The first 1/alpha time steps are used to warm up the baseline and are never classified as outliers, as neither are the time steps with a flat baseline
+//An alpha of 1 keeps no variance at all, so it never flags anything
+func detectOutliersEwma(data []collector.TimeStepData, PeriodEnd time.Time, alpha, outliersMultiplier, strongOutliersMultiplier float64) ([]eventPeriod, []eventPeriod) {
+	levels := make([]int, len(data))
+	if alpha <= 0 || alpha > 1 {
+		log.Printf("Invalid ewma alpha %.2f, it must be greater than 0 and at most 1\n", alpha)
+		return eventPeriodsFromLevels(data, levels, PeriodEnd)
+	}
+
+	means, sds, warmUp := ewmaBaseline(data, alpha)
+	for ind := warmUp; ind < len(data); ind++ {
+
+		//Flat baselines have no deviation to measure, any change being flagged otherwise
+		if sds[ind] == 0 {
+			continue
+		}
+
+		//Classifying the metric value according to the current baseline limits
+		diff := data[ind].Value - means[ind]
+		if math.Abs(diff) > strongOutliersMultiplier*sds[ind] {
+			levels[ind] = levelAlarm
+		} else if math.Abs(diff) > outliersMultiplier*sds[ind] {
+			levels[ind] = levelWarning
+		}
+	}
+
+	return eventPeriodsFromLevels(data, levels, PeriodEnd)
+}
+
+//ewmaBaseline returns the exponentially weighted mean and standard deviation each time step is compared against, along with the number of warm up time steps
+//The baseline of the warm up time steps, all of them on series shorter than 1/alpha, is left as zero
+func ewmaBaseline(data []collector.TimeStepData, alpha float64) ([]float64, []float64, int) {
+	means := make([]float64, len(data))
+	sds := make([]float64, len(data))
+	warmUp := int(math.Ceil(1 / alpha))
+	if warmUp > len(data) {
+		warmUp = len(data)
+	}
+	if warmUp == 0 {
+		return means, sds, warmUp
+	}
+
+	//Initializing the baseline with the plain Mean and Variance of the warm up time steps
+	mean := 0.0
+	for _, stepData := range data[:warmUp] {
+		mean += stepData.Value
+	}
+	mean /= float64(warmUp)
+	variance := 0.0
+	for _, stepData := range data[:warmUp] {
+		variance += math.Pow(stepData.Value-mean, 2)
+	}
+	variance /= float64(warmUp)
+
+	for ind := warmUp; ind < len(data); ind++ {
+		means[ind], sds[ind] = mean, math.Sqrt(variance)
+
+		//Updating the exponentially weighted Mean and Variance with the metric value
+		diff := data[ind].Value - mean
+		increment := alpha * diff
+		mean += increment
+		variance = (1 - alpha) * (variance + diff*increment)
+	}
+
+	return means, sds, warmUp
+}
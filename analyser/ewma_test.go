@@ -0,0 +1,85 @@
+package analyser
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ftfmtavares/anomalies-detector/collector"
+)
+
+func TestDetectOutliersEwma(t *testing.T) {
+	timeRef := time.Now()
+
+	//Slowly drifting values with a single spike on sample #21
+	values := []float64{}
+	for i := 0; i < 30; i++ {
+		value := 100 + float64(i)/2 + float64(i%2)*6
+		if i == 20 {
+			value += 40
+		}
+		values = append(values, value)
+	}
+	data := make([]collector.TimeStepData, len(values))
+	for i, val := range values {
+		data[i].Samples = 100
+		data[i].DateStart = timeRef.AddDate(0, 0, -len(values)+i)
+		data[i].Value = val
+	}
+
+	tests := []struct {
+		name           string
+		alpha          float64
+		wantedWarnings []eventPeriod
+		wantedAlarms   []eventPeriod
+	}{
+		{
+			name:           "Drift is followed and the spike at sample #21 is an alarm",
+			alpha:          0.3,
+			wantedWarnings: []eventPeriod{},
+			wantedAlarms:   []eventPeriod{{outlierPeriodStart: timeRef.AddDate(0, 0, -10), outlierPeriodEnd: timeRef.AddDate(0, 0, -9)}},
+		},
+		{
+			name:           "Alpha of 1 keeps no variance to flag changes against",
+			alpha:          1,
+			wantedWarnings: []eventPeriod{},
+			wantedAlarms:   []eventPeriod{},
+		},
+		{
+			name:           "Invalid alpha",
+			alpha:          1.5,
+			wantedWarnings: []eventPeriod{},
+			wantedAlarms:   []eventPeriod{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			warnings, alarms := detectOutliersEwma(data, timeRef, tt.alpha, 2, 3)
+			if !reflect.DeepEqual(warnings, tt.wantedWarnings) {
+				t.Errorf("detectOutliersEwma() got = %v, want %v", warnings, tt.wantedWarnings)
+			}
+			if !reflect.DeepEqual(alarms, tt.wantedAlarms) {
+				t.Errorf("detectOutliersEwma() got1 = %v, want %v", alarms, tt.wantedAlarms)
+			}
+		})
+	}
+}
+
+func TestDetectOutliersEwma_flatWarmUp(t *testing.T) {
+	timeRef := time.Now()
+
+	//The first change after the flat warm up has no deviation to be measured against, the following ones being in line with it
+	values := []float64{100, 100, 102, 101, 101, 102, 101}
+	data := make([]collector.TimeStepData, len(values))
+	for i, val := range values {
+		data[i].Samples = 100
+		data[i].DateStart = timeRef.AddDate(0, 0, -len(values)+i)
+		data[i].Value = val
+	}
+
+	warnings, alarms := detectOutliersEwma(data, timeRef, 0.5, 2, 3)
+	if len(warnings) != 0 || len(alarms) != 0 {
+		t.Errorf("detectOutliersEwma() = %v, %v, want no events", warnings, alarms)
+	}
+}
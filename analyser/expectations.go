@@ -0,0 +1,164 @@
+package analyser
+
+import (
+	"math"
+	"time"
+
+	"github.com/ftfmtavares/anomalies-detector/collector"
+	"github.com/ftfmtavares/anomalies-detector/config"
+)
+
+//StepExpectation provides the structure for the value a detection method expected on a time step and the deviations from it it tolerated
+//Deviations beyond WarningDeviation are warnings and those beyond AlarmDeviation alarms, either one being zero when the method has no such limit
+type StepExpectation struct {
+	DateStart        time.Time `json:"dateStart"`
+	Expected         float64   `json:"expected"`
+	WarningDeviation float64   `json:"warningDeviation"`
+	AlarmDeviation   float64   `json:"alarmDeviation"`
+}
+
+//AttributeExpectations provides the structure for the expectations of an attribute/sub-values combination of a metric, as modelled by a detection method
+//Steps field only holds the time steps the method classified against a baseline, leaving out those warming it up or without any spread
+type AttributeExpectations struct {
+	Metric    string            `json:"metric"`
+	Attribute string            `json:"attribute"`
+	Method    string            `json:"method"`
+	Steps     []StepExpectation `json:"steps"`
+}
+
+//siteExpectations returns the expectations of all attribute/sub-values combinations of each metric of a site
+//Each one is modelled by the first of its detection methods with a baseline, metrics found on metricDetection using their own methods and parameters
+//Series that can't be analysed and those whose methods have no baseline (cusum and esd) are left out
+func siteExpectations(siteData collector.SiteData, methods []string, methodParams config.DetectionMethodsParams, metricDetection map[string]config.MetricDetection) []AttributeExpectations {
+	res := []AttributeExpectations{}
+	for _, metricData := range siteData.Metrics {
+		metricMethods, metricParams := methods, methodParams
+		if detection, found := metricDetection[metricData.Metric]; found {
+			metricMethods, _, metricParams = detection.Apply(methods, 0, methodParams)
+		}
+
+		for _, attribute := range metricData.Attributes {
+			data, _ := presentSteps(metricData.AttributeData[attribute])
+			if checkSeries(data, metricParams) != nil {
+				continue
+			}
+			for _, method := range metricMethods {
+				if steps, modelled := methodExpectations(data, method, metricParams); modelled {
+					res = append(res, AttributeExpectations{Metric: metricData.Metric, Attribute: attribute, Method: method, Steps: steps})
+					break
+				}
+			}
+		}
+	}
+	return res
+}
+
+//methodExpectations returns the expected value and tolerated deviations of each time step a detection method classifies against a baseline
+//It returns false for the methods without a baseline and for invalid method parameters
+func methodExpectations(data []collector.TimeStepData, method string, methodParams config.DetectionMethodsParams) ([]StepExpectation, bool) {
+	res := []StepExpectation{}
+	add := func(ind int, expected, sd, outliersMultiplier, strongOutliersMultiplier float64) {
+		if sd > 0 {
+			res = append(res, StepExpectation{DateStart: data[ind].DateStart, Expected: expected, WarningDeviation: outliersMultiplier * sd, AlarmDeviation: strongOutliersMultiplier * sd})
+		}
+	}
+
+	switch method {
+	case "3-sigmas":
+		params := methodParams.ThreeSigmas
+		mean, sd := meanAndDeviation(data)
+		for ind := range data {
+			add(ind, mean, sd, params.OutliersMultiplier, params.StrongOutliersMultiplier)
+		}
+
+	case "rolling-3-sigmas":
+		params := methodParams.RollingThreeSigmas
+		if params.WindowSize < 2 {
+			return nil, false
+		}
+		for ind := params.WindowSize; ind < len(data); ind++ {
+			mean, sd := meanAndDeviation(data[ind-params.WindowSize : ind])
+			add(ind, mean, sd, params.OutliersMultiplier, params.StrongOutliersMultiplier)
+		}
+
+	case "ewma":
+		params := methodParams.Ewma
+		if params.Alpha <= 0 || params.Alpha > 1 {
+			return nil, false
+		}
+		means, sds, warmUp := ewmaBaseline(data, params.Alpha)
+		for ind := warmUp; ind < len(data); ind++ {
+			add(ind, means[ind], sds[ind], params.OutliersMultiplier, params.StrongOutliersMultiplier)
+		}
+
+	case "stl":
+		params := methodParams.Stl
+		if params.SeasonLength < 2 || len(data) < 2*params.SeasonLength {
+			return nil, false
+		}
+
+		//The 3-sigmas baseline of the residuals is added back to the trend and seasonal components
+		trend, seasonal := stlComponents(data, params.SeasonLength)
+		residuals := make([]collector.TimeStepData, len(data))
+		for ind, stepData := range data {
+			residuals[ind] = stepData
+			residuals[ind].Value = stepData.Value - trend[ind] - seasonal[ind]
+		}
+		mean, sd := meanAndDeviation(residuals)
+		for ind := range data {
+			add(ind, trend[ind]+seasonal[ind]+mean, sd, params.OutliersMultiplier, params.StrongOutliersMultiplier)
+		}
+
+	case "holt-winters":
+		params := methodParams.HoltWinters
+		if params.SeasonLength < 2 || len(data) < 2*params.SeasonLength {
+			return nil, false
+		}
+		for _, factor := range []float64{params.Alpha, params.Beta, params.Gamma} {
+			if factor < 0 || factor > 1 {
+				return nil, false
+			}
+		}
+
+		//The forecast of each time step is its value minus its forecast error
+		errors, sd := holtWintersModel(data, params)
+		for ind := params.SeasonLength; ind < len(data); ind++ {
+			add(ind, data[ind].Value-errors[ind], sd, params.OutliersMultiplier, params.StrongOutliersMultiplier)
+		}
+
+	case "seasonal-buckets":
+		params := methodParams.SeasonalBuckets
+		bucketKey := params.BucketKey
+		if bucketKey == "" {
+			bucketKey = config.BucketDayOfWeek
+		}
+		if bucketKey != config.BucketDayOfWeek && bucketKey != config.BucketHour && bucketKey != config.BucketDayOfWeekHour {
+			return nil, false
+		}
+		centers, sds := make([]float64, len(data)), make([]float64, len(data))
+		for _, indexes := range seasonalBuckets(data, bucketKey) {
+			center, sd := bucketSpread(data, indexes)
+			for _, ind := range indexes {
+				centers[ind], sds[ind] = center, sd
+			}
+		}
+		for ind := range data {
+			add(ind, centers[ind], sds[ind], params.OutliersMultiplier, params.StrongOutliersMultiplier)
+		}
+
+	case "period-over-period":
+		params := methodParams.PeriodOverPeriod
+		references, found := periodOverPeriodReferences(data, params)
+		if !found {
+			return nil, false
+		}
+		for ind, reference := range references {
+			add(ind, reference, math.Abs(reference)/100, math.Max(params.WarningPercent, 0), math.Max(params.AlarmPercent, 0))
+		}
+
+	default:
+		return nil, false
+	}
+
+	return res, true
+}
@@ -0,0 +1,130 @@
+package analyser
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/ftfmtavares/anomalies-detector/collector"
+	"github.com/ftfmtavares/anomalies-detector/config"
+)
+
+func TestMethodExpectations(t *testing.T) {
+	//Eight weeks of daily values with a weekly pattern, quiet Sundays, a spike on the 38th day and a dip on the 45th
+	timeRef := time.Date(2022, 8, 1, 0, 0, 0, 0, time.UTC)
+	data := make([]collector.TimeStepData, 56)
+	for day := range data {
+		value := 100 + float64(day%7)*5 + float64(day%3)
+		if day%7 == 6 {
+			value = 40 + float64(day%2)*3
+		}
+		data[day] = collector.TimeStepData{DateStart: timeRef.AddDate(0, 0, day), Value: value, Samples: 100}
+	}
+	data[37].Value = 220
+	data[44].Value = 70
+	periodEnd := timeRef.AddDate(0, 0, 56)
+
+	methodParams := config.DetectionMethodsParams{
+		RollingThreeSigmas: config.RollingThreeSigmasParams{WindowSize: 7},
+		Stl:                config.StlParams{SeasonLength: 7},
+		Ewma:               config.EwmaParams{Alpha: 0.2},
+		HoltWinters:        config.HoltWintersParams{Alpha: 0.3, Beta: 0.1, Gamma: 0.3, SeasonLength: 7},
+		SeasonalBuckets:    config.SeasonalBucketsParams{BucketKey: config.BucketDayOfWeek},
+		PeriodOverPeriod:   config.PeriodOverPeriodParams{Offsets: []string{"1w"}, WarningPercent: 20, AlarmPercent: 50},
+		Cusum:              config.CusumParams{BaselineSize: 14, Drift: 0.5, Threshold: 5},
+	}.WithMultipliers(2, 3)
+
+	//Every exported time step must be classified by its expectation as the method classifies it
+	tests := []struct {
+		method       string
+		wantModelled bool
+		wantSteps    int
+	}{
+		{method: "3-sigmas", wantModelled: true, wantSteps: 56},
+		{method: "rolling-3-sigmas", wantModelled: true, wantSteps: 49},
+		{method: "ewma", wantModelled: true, wantSteps: 51},
+		{method: "stl", wantModelled: true, wantSteps: 56},
+		{method: "holt-winters", wantModelled: true, wantSteps: 49},
+		{method: "seasonal-buckets", wantModelled: true, wantSteps: 56},
+		{method: "period-over-period", wantModelled: true, wantSteps: 49},
+		{method: "cusum", wantModelled: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method, func(t *testing.T) {
+			steps, modelled := methodExpectations(data, tt.method, methodParams)
+			if modelled != tt.wantModelled || len(steps) != tt.wantSteps {
+				t.Fatalf("methodExpectations() = %d steps, %v, want %d steps, %v", len(steps), modelled, tt.wantSteps, tt.wantModelled)
+			}
+			if !modelled {
+				return
+			}
+
+			warnings, alarms := detectAttributeOutliers(data, periodEnd, tt.method, methodParams)
+			levels := levelsFromEventPeriods(data, warnings, alarms)
+			flagged := 0
+			for _, step := range steps {
+				ind := int(step.DateStart.Sub(timeRef).Hours() / 24)
+				deviation := math.Abs(data[ind].Value - step.Expected)
+				level := levelNormal
+				if step.AlarmDeviation > 0 && deviation > step.AlarmDeviation {
+					level = levelAlarm
+				} else if step.WarningDeviation > 0 && deviation > step.WarningDeviation {
+					level = levelWarning
+				}
+				if level != levels[ind] {
+					t.Errorf("time step %d level = %d from its expectation %+v, want %d as detected", ind, level, step, levels[ind])
+				}
+				if level != levelNormal {
+					flagged++
+				}
+			}
+			if flagged == 0 {
+				t.Errorf("no time step flagged, want the spike and dip compared")
+			}
+		})
+	}
+}
+
+func TestSiteExpectations(t *testing.T) {
+	timeRef := time.Date(2022, 8, 1, 0, 0, 0, 0, time.UTC)
+	series := func(values ...float64) []collector.TimeStepData {
+		data := make([]collector.TimeStepData, len(values))
+		for i, value := range values {
+			data[i] = collector.TimeStepData{DateStart: timeRef.AddDate(0, 0, i), Value: value}
+		}
+		return data
+	}
+	siteData := collector.SiteData{
+		SiteId: "site",
+		Metrics: []collector.MetricData{
+			{Metric: "Visits", Attributes: []string{"Total", "Flat"}, AttributeData: map[string][]collector.TimeStepData{
+				"Total": series(10, 12, 11, 13, 10, 30, 12, 11),
+				"Flat":  series(5, 5, 5, 5, 5, 5, 5, 5),
+			}},
+			{Metric: "Revenue", Attributes: []string{"Total"}, AttributeData: map[string][]collector.TimeStepData{
+				"Total": series(100, 120, 110, 130, 100, 300, 120, 110),
+			}},
+		},
+	}
+	methodParams := config.DetectionMethodsParams{Cusum: config.CusumParams{BaselineSize: 4, Drift: 0.5, Threshold: 5}}.WithMultipliers(2, 3)
+	metricDetection := map[string]config.MetricDetection{"Revenue": {OutliersDetectionMethod: "cusum"}}
+
+	//Flat series can't be analysed and Revenue is only run by cusum, without a baseline
+	got := siteExpectations(siteData, []string{"cusum", "3-sigmas"}, methodParams, metricDetection)
+	if len(got) != 1 || got[0].Metric != "Visits" || got[0].Attribute != "Total" || got[0].Method != "3-sigmas" || len(got[0].Steps) != 8 {
+		t.Fatalf("siteExpectations() = %+v, want the 3-sigmas expectations of the Visits total only", got)
+	}
+	if step := got[0].Steps[0]; step.Expected != 13.625 || step.AlarmDeviation != 1.5*step.WarningDeviation {
+		t.Errorf("siteExpectations() first step = %+v, want the series mean with 2 and 3 standard deviations", step)
+	}
+
+	//Reports only carry the expectations of the datasets exporting them
+	dataConf := config.Dataset{SiteId: "site", OutliersDetectionMethod: "3-sigmas"}
+	if report := GetResults(siteData, dataConf, methodParams); report.Expectations != nil {
+		t.Errorf("GetResults() expectations = %+v, want none when not exported", report.Expectations)
+	}
+	dataConf.Expectations = true
+	if report := GetResults(siteData, dataConf, methodParams); len(report.Expectations) != 2 {
+		t.Errorf("GetResults() expectations = %+v, want those of both totals", report.Expectations)
+	}
+}
@@ -0,0 +1,47 @@
+package analyser
+
+import (
+	"time"
+
+	"github.com/ftfmtavares/anomalies-detector/collector"
+)
+
+//DataGap provides the structure for a period whose time steps are missing from the collected total of a metric, reported apart from the outliers as a likely data outage
+//Steps field is the number of consecutive missing time steps, whether their values were filled or only marked missing
+type DataGap struct {
+	Metric      string    `json:"metric"`
+	PeriodStart time.Time `json:"periodStart"`
+	PeriodEnd   time.Time `json:"periodEnd"`
+	Steps       int       `json:"steps"`
+}
+
+//findDataGaps returns the runs of at least minSteps consecutive time steps missing from the "Total" attribute of every metric, in metric and time order
+//Missing time steps of the other attributes are left out, since a sub-value without samples on a time step is no outage
+func findDataGaps(siteData collector.SiteData, minSteps int, timeStep time.Duration) []DataGap {
+	if minSteps <= 0 {
+		minSteps = 1
+	}
+	gaps := []DataGap{}
+	for _, metricData := range siteData.Metrics {
+		data := metricData.AttributeData["Total"]
+		for ind := 0; ind < len(data); ind++ {
+			if !data[ind].Filled && !data[ind].Missing {
+				continue
+			}
+			last := ind
+			for last+1 < len(data) && (data[last+1].Filled || data[last+1].Missing) {
+				last++
+			}
+			if steps := last - ind + 1; steps >= minSteps {
+				//The gap ends where the next time step starts, calendar time steps varying in length
+				periodEnd := data[last].DateStart.Add(timeStep)
+				if last+1 < len(data) {
+					periodEnd = data[last+1].DateStart
+				}
+				gaps = append(gaps, DataGap{Metric: metricData.Metric, PeriodStart: data[ind].DateStart, PeriodEnd: periodEnd, Steps: steps})
+			}
+			ind = last
+		}
+	}
+	return gaps
+}
@@ -0,0 +1,59 @@
+package analyser
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ftfmtavares/anomalies-detector/collector"
+)
+
+func TestFindDataGaps(t *testing.T) {
+	timeRef := time.Date(2022, 9, 1, 0, 0, 0, 0, time.UTC)
+	series := func(gaps ...int) []collector.TimeStepData {
+		data := make([]collector.TimeStepData, 8)
+		for ind := range data {
+			data[ind] = collector.TimeStepData{DateStart: timeRef.Add(time.Duration(ind) * time.Hour), Value: 100}
+		}
+		for _, ind := range gaps {
+			data[ind].Filled = true
+		}
+		return data
+	}
+	siteData := collector.SiteData{Metrics: []collector.MetricData{
+		{Metric: "Revenue", Attributes: []string{"Total", "Browser>Edge"}, AttributeData: map[string][]collector.TimeStepData{"Total": series(1, 4, 5, 7), "Browser>Edge": series(2, 3)}},
+		{Metric: "Visits", Attributes: []string{"Total"}, AttributeData: map[string][]collector.TimeStepData{"Total": series(0, 1, 2)}},
+	}}
+	siteData.Metrics[1].AttributeData["Total"][1].Filled, siteData.Metrics[1].AttributeData["Total"][1].Missing = false, true
+
+	tests := []struct {
+		name     string
+		minSteps int
+		want     []DataGap
+	}{
+		{
+			name: "Every gap",
+			want: []DataGap{
+				{Metric: "Revenue", PeriodStart: timeRef.Add(time.Hour), PeriodEnd: timeRef.Add(2 * time.Hour), Steps: 1},
+				{Metric: "Revenue", PeriodStart: timeRef.Add(4 * time.Hour), PeriodEnd: timeRef.Add(6 * time.Hour), Steps: 2},
+				{Metric: "Revenue", PeriodStart: timeRef.Add(7 * time.Hour), PeriodEnd: timeRef.Add(8 * time.Hour), Steps: 1},
+				{Metric: "Visits", PeriodStart: timeRef, PeriodEnd: timeRef.Add(3 * time.Hour), Steps: 3},
+			},
+		},
+		{
+			name:     "Gaps of 2 time steps or more",
+			minSteps: 2,
+			want: []DataGap{
+				{Metric: "Revenue", PeriodStart: timeRef.Add(4 * time.Hour), PeriodEnd: timeRef.Add(6 * time.Hour), Steps: 2},
+				{Metric: "Visits", PeriodStart: timeRef, PeriodEnd: timeRef.Add(3 * time.Hour), Steps: 3},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := findDataGaps(siteData, tt.minSteps, time.Hour); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("findDataGaps() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
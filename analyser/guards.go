@@ -0,0 +1,59 @@
+package analyser
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/ftfmtavares/anomalies-detector/collector"
+	"github.com/ftfmtavares/anomalies-detector/config"
+)
+
+//defaultMinPoints is the minimum number of time steps analysed when none is configured, fewer points giving no meaningful deviation
+const defaultMinPoints = 3
+
+//presentSteps returns the time steps holding a value, leaving out those missing a value (NaN, infinite or marked missing by the gap strategy) along with their count
+//The given data is returned as is if no time step is missing
+func presentSteps(data []collector.TimeStepData) ([]collector.TimeStepData, int) {
+	missing := 0
+	for _, stepData := range data {
+		if stepData.Missing || math.IsNaN(stepData.Value) || math.IsInf(stepData.Value, 0) {
+			missing++
+		}
+	}
+	if missing == 0 {
+		return data, 0
+	}
+
+	present := make([]collector.TimeStepData, 0, len(data)-missing)
+	for _, stepData := range data {
+		if !stepData.Missing && !math.IsNaN(stepData.Value) && !math.IsInf(stepData.Value, 0) {
+			present = append(present, stepData)
+		}
+	}
+	return present, missing
+}
+
+//checkSeries checks if a series has enough points and variance to be analysed, returning why it can't be otherwise
+func checkSeries(data []collector.TimeStepData, methodParams config.DetectionMethodsParams) error {
+	minPoints := methodParams.MinPoints
+	if minPoints <= 0 {
+		minPoints = defaultMinPoints
+	}
+	if len(data) < minPoints {
+		return fmt.Errorf("%d time steps with a value, %d required", len(data), minPoints)
+	}
+
+	mean, variance := 0.0, 0.0
+	for _, stepData := range data {
+		mean += stepData.Value
+	}
+	mean /= float64(len(data))
+	for _, stepData := range data {
+		variance += math.Pow(stepData.Value-mean, 2)
+	}
+	variance /= float64(len(data))
+	if variance <= methodParams.MinVariance {
+		return fmt.Errorf("variance %g not above %g", variance, math.Max(methodParams.MinVariance, 0))
+	}
+	return nil
+}
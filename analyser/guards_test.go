@@ -0,0 +1,84 @@
+package analyser
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/ftfmtavares/anomalies-detector/collector"
+	"github.com/ftfmtavares/anomalies-detector/config"
+)
+
+func TestPresentSteps(t *testing.T) {
+	timeRef := time.Date(2022, 9, 1, 0, 0, 0, 0, time.UTC)
+	data := []collector.TimeStepData{
+		{DateStart: timeRef, Value: 1},
+		{DateStart: timeRef.AddDate(0, 0, 1), Value: math.NaN()},
+		{DateStart: timeRef.AddDate(0, 0, 2), Value: 3},
+		{DateStart: timeRef.AddDate(0, 0, 3), Value: math.Inf(1)},
+	}
+
+	got, missing := presentSteps(data)
+	if missing != 2 || len(got) != 2 || got[0].Value != 1 || got[1].Value != 3 {
+		t.Errorf("presentSteps() = %v, %d, want the 1st and 3rd time steps and 2 missing", got, missing)
+	}
+	if got, missing := presentSteps(data[:1]); missing != 0 || len(got) != 1 {
+		t.Errorf("presentSteps() = %v, %d, want the given time steps", got, missing)
+	}
+}
+
+func TestCheckSeries(t *testing.T) {
+	series := func(values ...float64) []collector.TimeStepData {
+		data := []collector.TimeStepData{}
+		for _, value := range values {
+			data = append(data, collector.TimeStepData{Value: value})
+		}
+		return data
+	}
+
+	tests := []struct {
+		name         string
+		data         []collector.TimeStepData
+		methodParams config.DetectionMethodsParams
+		wantErr      bool
+	}{
+		{name: "Enough points", data: series(1, 2, 3)},
+		{name: "No points", data: series(), wantErr: true},
+		{name: "Fewer points than the default", data: series(1, 5), wantErr: true},
+		{name: "Fewer points than configured", data: series(1, 2, 3), methodParams: config.DetectionMethodsParams{MinPoints: 4}, wantErr: true},
+		{name: "Constant series", data: series(5, 5, 5, 5), wantErr: true},
+		{name: "Variance not above the minimum", data: series(1, 2, 3), methodParams: config.DetectionMethodsParams{MinVariance: 1}, wantErr: true},
+		{name: "Variance above the minimum", data: series(1, 2, 3), methodParams: config.DetectionMethodsParams{MinVariance: 0.5}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := checkSeries(tt.data, tt.methodParams); (err != nil) != tt.wantErr {
+				t.Errorf("checkSeries() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDetectAttributeEventsGuards(t *testing.T) {
+	timeRef := time.Date(2022, 9, 1, 0, 0, 0, 0, time.UTC)
+	methodParams := config.DetectionMethodsParams{ThreeSigmas: config.ThreeSigmasParams{OutliersMultiplier: 2, StrongOutliersMultiplier: 3}}
+	data := make([]collector.TimeStepData, 20)
+	for day := range data {
+		data[day] = collector.TimeStepData{DateStart: timeRef.AddDate(0, 0, day), Value: 100 + float64(day%2), Samples: 100}
+	}
+	data[5].Value = math.NaN()
+	data[12].Value = 1000
+
+	//A missing value is left out instead of turning every limit into NaN
+	metricData := collector.MetricData{Metric: "Revenue", Attributes: []string{"Total"}, AttributeData: map[string][]collector.TimeStepData{"Total": data}}
+	got := detectAttributeEvents(metricData, "Total", timeRef.AddDate(0, 0, 20), []string{"3-sigmas"}, 0, methodParams)
+	if len(got.Alarms) != 1 || !got.Alarms[0].OutlierPeriodStart.Equal(data[12].DateStart) || math.IsNaN(got.Alarms[0].Expected) {
+		t.Errorf("detectAttributeEvents() alarms = %+v, want the spike alone", got.Alarms)
+	}
+
+	//Series too short to be analysed give no events
+	metricData.AttributeData["Total"] = data[11:13]
+	if got := detectAttributeEvents(metricData, "Total", timeRef.AddDate(0, 0, 13), []string{"3-sigmas"}, 0, methodParams); len(got.Alarms)+len(got.Warnings) != 0 {
+		t.Errorf("detectAttributeEvents() = %+v, want no events on 2 time steps", got)
+	}
+}
@@ -0,0 +1,416 @@
+package analyser
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ftfmtavares/anomalies-detector/collector"
+	"github.com/ftfmtavares/anomalies-detector/config"
+	"github.com/ftfmtavares/anomalies-detector/utils"
+)
+
+//Const block defines the layout of the run file names, in UTC, and the suffix of the files holding the collected data of a run
+const (
+	runFileLayout     = "2006-01-02T150405Z"
+	runDataFileSuffix = ".data.json"
+)
+
+//RunHistory keeps the reports of every run as <dir>/runs/<run time>.json, so a run can be compared with earlier ones
+//With snapshots, the collected data of every run is kept as well as <dir>/runs/<run time>.data.json
+//The records of the sites given an encryption key are stored encrypted and decrypted when read, being skipped when read without the key
+type RunHistory struct {
+	dir       string
+	retention time.Duration
+	snapshots bool
+	keys      map[string][]byte
+}
+
+//OverlayEvent provides the structure for an alarm of a run overlaid with the runs of an earlier one
+//PreviousPeriodStart and PreviousPeriodEnd fields are the period of the matching alarm of the earlier run, if any
+type OverlayEvent struct {
+	SiteId              string     `json:"siteId"`
+	Resolution          string     `json:"resolution,omitempty"`
+	Metric              string     `json:"metric"`
+	Attribute           string     `json:"attribute"`
+	PeriodStart         time.Time  `json:"periodStart"`
+	PeriodEnd           time.Time  `json:"periodEnd"`
+	PreviousPeriodStart *time.Time `json:"previousPeriodStart,omitempty"`
+	PreviousPeriodEnd   *time.Time `json:"previousPeriodEnd,omitempty"`
+}
+
+//RunOverlay provides the structure for the comparison of the alarms of a run with those of an earlier run
+//Recurring field lists the alarms also raised by the earlier run at the same time once shifted (e.g. every Sunday night),
+//New field those it didn't raise and Resolved field the alarms of the earlier run not raised again, with their earlier periods
+type RunOverlay struct {
+	Recurring []OverlayEvent `json:"recurring"`
+	New       []OverlayEvent `json:"new"`
+	Resolved  []OverlayEvent `json:"resolved"`
+}
+
+//NewRunHistory creates a RunHistory under the storage directory, runs older than the RunRetention (90 days by default) being pruned when a new one is saved
+//It returns an error if the directory is missing or the retention is invalid
+func NewRunHistory(params config.StorageParams) (*RunHistory, error) {
+	if params.Dir == "" {
+		return nil, fmt.Errorf("storage - dir is required")
+	}
+	runRetention := params.RunRetention
+	if runRetention == "" {
+		runRetention = "90d"
+	}
+	retention, err := utils.StrToDuration(runRetention)
+	if err != nil || retention <= 0 {
+		return nil, fmt.Errorf("storage - invalid run retention \"%s\"", params.RunRetention)
+	}
+	return &RunHistory{dir: filepath.Join(params.Dir, "runs"), retention: retention, snapshots: params.RunSnapshots, keys: map[string][]byte{}}, nil
+}
+
+//EncryptSite sets the AES-256 key the reports and data of a site are encrypted with on the stored runs
+func (history *RunHistory) EncryptSite(siteId string, key []byte) {
+	history.keys[siteId] = key
+}
+
+//RunTime returns the time a run is named by, which is the latest check period start of its reports, or the current time if it has none
+func RunTime(reports []OutlierReport) time.Time {
+	runTime := time.Time{}
+	for _, report := range reports {
+		if report.CheckDateStart.After(runTime) {
+			runTime = report.CheckDateStart
+		}
+	}
+	if runTime.IsZero() {
+		return Clock.Now()
+	}
+	return runTime.UTC()
+}
+
+//SaveRun stores the reports of a run, named by the given run time, and prunes the runs older than the retention from the current time
+//It returns an error if the run can't be written, pruning failures being ignored until the next run
+func (history *RunHistory) SaveRun(runTime time.Time, reports []OutlierReport) error {
+	if err := utils.Files.MkdirAll(history.dir); err != nil {
+		return fmt.Errorf("run history - %s", err.Error())
+	}
+	records := make([]interface{}, len(reports))
+	for i, report := range reports {
+		var err error
+		if records[i], err = history.record(report.SiteId, report); err != nil {
+			return err
+		}
+	}
+	byteValue, err := json.Marshal(records)
+	if err != nil {
+		return fmt.Errorf("run history - %s", err.Error())
+	}
+	fileName := filepath.Join(history.dir, runTime.UTC().Format(runFileLayout)+".json")
+	if err := utils.Files.WriteFile(fileName, byteValue, 0o644); err != nil {
+		return fmt.Errorf("run history - %s", err.Error())
+	}
+
+	runTimes, _ := history.runTimes()
+	for _, stored := range runTimes {
+		if stored.Before(Clock.Now().Add(-history.retention)) {
+			os.Remove(filepath.Join(history.dir, stored.Format(runFileLayout)+".json"))
+			os.Remove(filepath.Join(history.dir, stored.Format(runFileLayout)+runDataFileSuffix))
+		}
+	}
+	return nil
+}
+
+//SaveRunData stores the collected data of a run, named by the given run time, if snapshots are enabled, doing nothing otherwise
+//It's pruned along with the reports of the run
+func (history *RunHistory) SaveRunData(runTime time.Time, sitesData []collector.SiteData) error {
+	if !history.snapshots {
+		return nil
+	}
+	if err := utils.Files.MkdirAll(history.dir); err != nil {
+		return fmt.Errorf("run history - %s", err.Error())
+	}
+	records := make([]interface{}, len(sitesData))
+	for i, siteData := range sitesData {
+		var err error
+		if records[i], err = history.record(siteData.SiteId, siteData); err != nil {
+			return err
+		}
+	}
+	byteValue, err := json.Marshal(records)
+	if err != nil {
+		return fmt.Errorf("run history - %s", err.Error())
+	}
+	fileName := filepath.Join(history.dir, runTime.UTC().Format(runFileLayout)+runDataFileSuffix)
+	if err := utils.Files.WriteFile(fileName, byteValue, 0o644); err != nil {
+		return fmt.Errorf("run history - %s", err.Error())
+	}
+	return nil
+}
+
+//Runs lists the times of the stored runs, from the latest
+func (history *RunHistory) Runs() ([]time.Time, error) {
+	runTimes, err := history.runTimes()
+	if err != nil {
+		return nil, err
+	}
+	for i, j := 0, len(runTimes)-1; i < j; i, j = i+1, j-1 {
+		runTimes[i], runTimes[j] = runTimes[j], runTimes[i]
+	}
+	return runTimes, nil
+}
+
+//Run returns the reports of the run stored at the given time, along with its collected data if it was stored as well (nil otherwise)
+//It returns false if no run is stored at that time
+func (history *RunHistory) Run(runTime time.Time) ([]OutlierReport, []collector.SiteData, bool, error) {
+	baseName := filepath.Join(history.dir, runTime.UTC().Format(runFileLayout))
+	if _, err := os.Stat(baseName + ".json"); os.IsNotExist(err) {
+		return nil, nil, false, nil
+	}
+	reports, err := history.readReports(baseName + ".json")
+	if err != nil {
+		return nil, nil, false, err
+	}
+
+	records, err := readRecords(baseName + runDataFileSuffix)
+	if os.IsNotExist(err) {
+		return reports, nil, true, nil
+	}
+	if err != nil {
+		return nil, nil, false, fmt.Errorf("run history - %s - %s", baseName+runDataFileSuffix, err.Error())
+	}
+	sitesData := []collector.SiteData{}
+	for _, record := range records {
+		var siteData collector.SiteData
+		decoded, err := history.decode(record, &siteData)
+		if err != nil {
+			return nil, nil, false, fmt.Errorf("run history - %s - %s", baseName+runDataFileSuffix, err.Error())
+		}
+		if decoded {
+			sitesData = append(sitesData, siteData)
+		}
+	}
+	return reports, sitesData, true, nil
+}
+
+//RunNear returns the reports of the stored run closest to the given time, along with its run time
+//It returns false if no run is stored within the tolerance of that time
+func (history *RunHistory) RunNear(runTime time.Time, tolerance time.Duration) ([]OutlierReport, time.Time, bool, error) {
+	runTimes, err := history.runTimes()
+	if err != nil {
+		return nil, time.Time{}, false, err
+	}
+	var closest time.Time
+	found := false
+	distance := func(t time.Time) time.Duration {
+		if t.Before(runTime) {
+			return runTime.Sub(t)
+		}
+		return t.Sub(runTime)
+	}
+	for _, stored := range runTimes {
+		if distance(stored) <= tolerance && (!found || distance(stored) < distance(closest)) {
+			closest, found = stored, true
+		}
+	}
+	if !found {
+		return nil, time.Time{}, false, nil
+	}
+	reports, err := history.readReports(filepath.Join(history.dir, closest.Format(runFileLayout)+".json"))
+	if err != nil {
+		return nil, closest, false, err
+	}
+	return reports, closest, true, nil
+}
+
+//ReadRunReports reads the reports of a report file or, if no file exists at the given reference, of the stored run it identifies
+//Run ids are the run times, either in RFC 3339 or as the runs are named on the history (e.g. 2024-03-03T220000Z)
+//It returns an error if the reference is neither a file nor a stored run, the history being nil if none is configured
+func ReadRunReports(ref string, history *RunHistory) ([]OutlierReport, error) {
+	if fileInfo, err := os.Stat(ref); err == nil && !fileInfo.IsDir() {
+		return ReadReportsFile(ref)
+	}
+	runTime, err := time.Parse(time.RFC3339, ref)
+	if err != nil {
+		if runTime, err = time.Parse(runFileLayout, ref); err != nil {
+			return nil, errors.New("neither a report file nor a run id")
+		}
+	}
+	if history == nil {
+		return nil, fmt.Errorf("no report file found and no run history configured to read run %s from", ref)
+	}
+	reports, _, found, err := history.Run(runTime)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, fmt.Errorf("no run stored at %s", runTime.UTC().Format(time.RFC3339))
+	}
+	return reports, nil
+}
+
+//LatestSiteReports returns the reports of a site from the latest stored run including it, none if no stored run does
+func (history *RunHistory) LatestSiteReports(siteId string) ([]OutlierReport, error) {
+	runTimes, err := history.runTimes()
+	if err != nil {
+		return nil, err
+	}
+	for i := len(runTimes) - 1; i >= 0; i-- {
+		reports, err := history.readReports(filepath.Join(history.dir, runTimes[i].Format(runFileLayout)+".json"))
+		if err != nil {
+			return nil, err
+		}
+		siteReports := []OutlierReport{}
+		for _, report := range reports {
+			if report.SiteId == siteId {
+				siteReports = append(siteReports, report)
+			}
+		}
+		if len(siteReports) > 0 {
+			return siteReports, nil
+		}
+	}
+	return []OutlierReport{}, nil
+}
+
+//record returns the record a report or data of a site is stored as, encrypted if the site has a key
+func (history *RunHistory) record(siteId string, v interface{}) (interface{}, error) {
+	key := history.keys[siteId]
+	if key == nil {
+		return v, nil
+	}
+	encrypted, err := utils.EncryptRecord(siteId, v, key)
+	if err != nil {
+		return nil, fmt.Errorf("run history - site %s - %s", siteId, err.Error())
+	}
+	return encrypted, nil
+}
+
+//readReports reads the reports of a stored run, decrypting those of the sites with a key
+func (history *RunHistory) readReports(fileName string) ([]OutlierReport, error) {
+	records, err := readRecords(fileName)
+	if err != nil {
+		return nil, fmt.Errorf("run history - %s - %s", fileName, err.Error())
+	}
+	reports := []OutlierReport{}
+	for _, record := range records {
+		var report OutlierReport
+		decoded, err := history.decode(record, &report)
+		if err != nil {
+			return nil, fmt.Errorf("run history - %s - %s", fileName, err.Error())
+		}
+		if decoded {
+			reports = append(reports, report)
+		}
+	}
+	return reports, nil
+}
+
+//decode unmarshals a plain or encrypted record into v, decrypting it with the key of its site
+//It returns false if the record is encrypted for a site without key, which is skipped
+func (history *RunHistory) decode(record json.RawMessage, v interface{}) (bool, error) {
+	var encrypted utils.EncryptedRecord
+	if err := json.Unmarshal(record, &encrypted); err != nil {
+		return false, err
+	}
+	if encrypted.Ciphertext == "" {
+		return true, json.Unmarshal(record, v)
+	}
+	key := history.keys[encrypted.SiteId]
+	if key == nil {
+		log.Printf("Skipping encrypted run record of site %s\n", encrypted.SiteId)
+		return false, nil
+	}
+	if err := utils.DecryptRecord(encrypted, key, v); err != nil {
+		return false, fmt.Errorf("site %s - %s", encrypted.SiteId, err.Error())
+	}
+	return true, nil
+}
+
+//readRecords reads the JSON records of a run file
+func readRecords(fileName string) ([]json.RawMessage, error) {
+	byteValue, err := os.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+	var records []json.RawMessage
+	if err := json.Unmarshal(byteValue, &records); err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+//runTimes lists the times of the stored runs, from the oldest, none if the history is still empty
+func (history *RunHistory) runTimes() ([]time.Time, error) {
+	entries, err := os.ReadDir(history.dir)
+	if os.IsNotExist(err) {
+		return []time.Time{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("run history - %s", err.Error())
+	}
+	runTimes := []time.Time{}
+	for _, entry := range entries {
+		runTime, err := time.Parse(runFileLayout, strings.TrimSuffix(entry.Name(), ".json"))
+		if err != nil || entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+		runTimes = append(runTimes, runTime)
+	}
+	sort.Slice(runTimes, func(i, j int) bool { return runTimes[i].Before(runTimes[j]) })
+	return runTimes, nil
+}
+
+//OverlayRuns compares the alarms of a run with those of an earlier run, the earlier alarms being shifted forward by the given shift (e.g. a week)
+//Alarms of the same site, resolution, metric and attribute whose shifted periods overlap are recurring, as with CompareReports
+func OverlayRuns(current, previous []OutlierReport, shift time.Duration) RunOverlay {
+	res := RunOverlay{Recurring: []OverlayEvent{}, New: []OverlayEvent{}, Resolved: []OverlayEvent{}}
+
+	alarms := func(reports []OutlierReport) []severityEvent {
+		events := []severityEvent{}
+		for _, event := range flattenEvents(reports) {
+			if event.severity == "alarm" {
+				events = append(events, event)
+			}
+		}
+		return events
+	}
+	overlayEvent := func(event severityEvent) OverlayEvent {
+		return OverlayEvent{SiteId: event.siteId, Resolution: event.event.Resolution, Metric: event.event.Metric, Attribute: event.event.Attribute,
+			PeriodStart: event.event.OutlierPeriodStart, PeriodEnd: event.event.OutlierPeriodEnd}
+	}
+
+	currentAlarms, previousAlarms := alarms(current), alarms(previous)
+	matched := make([]bool, len(previousAlarms))
+	for _, alarm := range currentAlarms {
+		match := -1
+		for i, earlier := range previousAlarms {
+			if !matched[i] && alarm.siteId == earlier.siteId && alarm.event.Resolution == earlier.event.Resolution && alarm.event.Metric == earlier.event.Metric && alarm.event.Attribute == earlier.event.Attribute &&
+				alarm.event.OutlierPeriodStart.Before(earlier.event.OutlierPeriodEnd.Add(shift)) && earlier.event.OutlierPeriodStart.Add(shift).Before(alarm.event.OutlierPeriodEnd) {
+				match = i
+				break
+			}
+		}
+		event := overlayEvent(alarm)
+		if match == -1 {
+			res.New = append(res.New, event)
+			continue
+		}
+		matched[match] = true
+		previousStart, previousEnd := previousAlarms[match].event.OutlierPeriodStart, previousAlarms[match].event.OutlierPeriodEnd
+		event.PreviousPeriodStart, event.PreviousPeriodEnd = &previousStart, &previousEnd
+		res.Recurring = append(res.Recurring, event)
+	}
+	for i, earlier := range previousAlarms {
+		if matched[i] {
+			continue
+		}
+		event := overlayEvent(earlier)
+		previousStart, previousEnd := event.PeriodStart, event.PeriodEnd
+		event.PreviousPeriodStart, event.PreviousPeriodEnd = &previousStart, &previousEnd
+		event.PeriodStart, event.PeriodEnd = time.Time{}, time.Time{}
+		res.Resolved = append(res.Resolved, event)
+	}
+	return res
+}
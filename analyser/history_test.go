@@ -0,0 +1,252 @@
+package analyser
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ftfmtavares/anomalies-detector/collector"
+	"github.com/ftfmtavares/anomalies-detector/config"
+	"github.com/ftfmtavares/anomalies-detector/utils"
+)
+
+func TestRunHistory(t *testing.T) {
+	defer func(clock utils.Clock) { Clock = clock }(Clock)
+	timeRef := time.Date(2024, 3, 3, 22, 0, 0, 0, time.UTC)
+
+	history, err := NewRunHistory(config.StorageParams{Dir: t.TempDir(), RunRetention: "10d"})
+	if err != nil {
+		t.Fatalf("NewRunHistory() error = %v", err)
+	}
+	for _, days := range []int{-14, -7, 0} {
+		Clock = utils.FixedClock(timeRef.AddDate(0, 0, days))
+		if err := history.SaveRun(timeRef.AddDate(0, 0, days), []OutlierReport{{SiteId: "shop", CheckDateStart: timeRef.AddDate(0, 0, days)}}); err != nil {
+			t.Fatalf("SaveRun() error = %v", err)
+		}
+	}
+
+	tests := []struct {
+		name      string
+		runTime   time.Time
+		wantFound bool
+		wantTime  time.Time
+	}{
+		{name: "Run a week earlier", runTime: timeRef.AddDate(0, 0, -7).Add(3 * time.Hour), wantFound: true, wantTime: timeRef.AddDate(0, 0, -7)},
+		{name: "Run pruned past the retention", runTime: timeRef.AddDate(0, 0, -14), wantFound: false},
+		{name: "No run within the tolerance", runTime: timeRef.AddDate(0, 0, -3), wantFound: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reports, runTime, found, err := history.RunNear(tt.runTime, 12*time.Hour)
+			if err != nil {
+				t.Fatalf("RunNear() error = %v", err)
+			}
+			if found != tt.wantFound {
+				t.Fatalf("RunNear() found = %v, want %v", found, tt.wantFound)
+			}
+			if found && (!runTime.Equal(tt.wantTime) || len(reports) != 1 || !reports[0].CheckDateStart.Equal(tt.wantTime)) {
+				t.Errorf("RunNear() = %v, %v, want the run of %v", reports, runTime, tt.wantTime)
+			}
+		})
+	}
+
+	runTimes, err := history.Runs()
+	if err != nil || len(runTimes) != 2 || !runTimes[0].Equal(timeRef) || !runTimes[1].Equal(timeRef.AddDate(0, 0, -7)) {
+		t.Errorf("Runs() = %v, %v, want the kept runs from the latest", runTimes, err)
+	}
+
+	if _, err := NewRunHistory(config.StorageParams{Dir: t.TempDir(), RunRetention: "forever"}); err == nil {
+		t.Errorf("NewRunHistory() expected an error on an invalid run retention")
+	}
+}
+
+func TestOverlayRuns(t *testing.T) {
+	sunday := time.Date(2024, 3, 9, 22, 0, 0, 0, time.UTC)
+
+	current := []OutlierReport{{
+		SiteId: "shop",
+		Result: OutlierResults{
+			Alarms: []OutlierEvent{
+				{OutlierPeriodStart: sunday, OutlierPeriodEnd: sunday.Add(2 * time.Hour), Metric: "Revenue", Attribute: "Total"},
+				{OutlierPeriodStart: sunday, OutlierPeriodEnd: sunday.Add(time.Hour), Metric: "Visits", Attribute: "Total"},
+			},
+			Warnings: []OutlierEvent{
+				{OutlierPeriodStart: sunday, OutlierPeriodEnd: sunday.Add(time.Hour), Metric: "Basket", Attribute: "Total"},
+			},
+		},
+	}}
+	previous := []OutlierReport{{
+		SiteId: "shop",
+		Result: OutlierResults{
+			Alarms: []OutlierEvent{
+				{OutlierPeriodStart: sunday.AddDate(0, 0, -7).Add(time.Hour), OutlierPeriodEnd: sunday.AddDate(0, 0, -7).Add(3 * time.Hour), Metric: "Revenue", Attribute: "Total"},
+				{OutlierPeriodStart: sunday.AddDate(0, 0, -7), OutlierPeriodEnd: sunday.AddDate(0, 0, -7).Add(time.Hour), Metric: "Basket", Attribute: "Total"},
+				{OutlierPeriodStart: sunday.AddDate(0, 0, -10), OutlierPeriodEnd: sunday.AddDate(0, 0, -10).Add(time.Hour), Metric: "Visits", Attribute: "Total"},
+			},
+		},
+	}}
+
+	got := OverlayRuns(current, previous, 7*24*time.Hour)
+	if len(got.Recurring) != 1 || got.Recurring[0].Metric != "Revenue" || !got.Recurring[0].PreviousPeriodStart.Equal(sunday.AddDate(0, 0, -7).Add(time.Hour)) {
+		t.Errorf("OverlayRuns() recurring = %+v, want the Revenue alarm", got.Recurring)
+	}
+	if len(got.New) != 1 || got.New[0].Metric != "Visits" {
+		t.Errorf("OverlayRuns() new = %+v, want the Visits alarm", got.New)
+	}
+	if len(got.Resolved) != 2 || got.Resolved[0].Metric != "Basket" || got.Resolved[1].Metric != "Visits" || !got.Resolved[0].PeriodStart.IsZero() || !got.Resolved[0].PreviousPeriodStart.Equal(sunday.AddDate(0, 0, -7)) {
+		t.Errorf("OverlayRuns() resolved = %+v, want the Basket and earlier Visits alarms", got.Resolved)
+	}
+}
+
+func TestRunHistorySnapshots(t *testing.T) {
+	defer func(clock utils.Clock) { Clock = clock }(Clock)
+	timeRef := time.Date(2024, 3, 3, 22, 0, 0, 0, time.UTC)
+	Clock = utils.FixedClock(timeRef)
+	sitesData := []collector.SiteData{{SiteId: "shop", TimeStep: "1d", Metrics: []collector.MetricData{{Metric: "Revenue", Attributes: []string{"Total"}, AttributeData: map[string][]collector.TimeStepData{"Total": {{DateStart: timeRef, Value: 10, Samples: 2}}}}}}}
+
+	tests := []struct {
+		name      string
+		snapshots bool
+		wantData  bool
+	}{
+		{name: "Reports and data", snapshots: true, wantData: true},
+		{name: "Reports only", snapshots: false, wantData: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			history, err := NewRunHistory(config.StorageParams{Dir: t.TempDir(), RunSnapshots: tt.snapshots})
+			if err != nil {
+				t.Fatalf("NewRunHistory() error = %v", err)
+			}
+			if err := history.SaveRun(timeRef, []OutlierReport{{SiteId: "shop"}}); err != nil {
+				t.Fatalf("SaveRun() error = %v", err)
+			}
+			if err := history.SaveRunData(timeRef, sitesData); err != nil {
+				t.Fatalf("SaveRunData() error = %v", err)
+			}
+
+			reports, gotData, found, err := history.Run(timeRef)
+			if err != nil || !found || len(reports) != 1 {
+				t.Fatalf("Run() = %v, %v, %v, want the stored reports", reports, found, err)
+			}
+			if tt.wantData && !reflect.DeepEqual(gotData, sitesData) {
+				t.Errorf("Run() data = %v, want %v", gotData, sitesData)
+			}
+			if !tt.wantData && gotData != nil {
+				t.Errorf("Run() data = %v, want none", gotData)
+			}
+			if runTimes, _ := history.Runs(); len(runTimes) != 1 {
+				t.Errorf("Runs() = %v, want the data file left out", runTimes)
+			}
+			if _, _, found, _ := history.Run(timeRef.Add(time.Hour)); found {
+				t.Errorf("Run() found a run not stored")
+			}
+		})
+	}
+}
+
+func TestRunHistoryEncrypted(t *testing.T) {
+	defer func(clock utils.Clock) { Clock = clock }(Clock)
+	timeRef := time.Date(2024, 3, 3, 22, 0, 0, 0, time.UTC)
+	Clock = utils.FixedClock(timeRef)
+	dir := t.TempDir()
+	sitesData := []collector.SiteData{
+		{SiteId: "shop", TimeStep: "1d", Metrics: []collector.MetricData{{Metric: "Revenue", Attributes: []string{"Total"}, AttributeData: map[string][]collector.TimeStepData{"Total": {{DateStart: timeRef, Value: 10, Samples: 2}}}}}},
+		{SiteId: "vault", TimeStep: "1d", Metrics: []collector.MetricData{{Metric: "Visits", Attributes: []string{"Total"}, AttributeData: map[string][]collector.TimeStepData{"Total": {{DateStart: timeRef, Value: 4, Samples: 1}}}}}},
+	}
+	reports := []OutlierReport{{SiteId: "shop", CheckDateStart: timeRef}, {SiteId: "vault", CheckDateStart: timeRef}}
+
+	history, err := NewRunHistory(config.StorageParams{Dir: dir, RunSnapshots: true})
+	if err != nil {
+		t.Fatalf("NewRunHistory() error = %v", err)
+	}
+	history.EncryptSite("vault", make([]byte, 32))
+	if err := history.SaveRun(timeRef, reports); err != nil {
+		t.Fatalf("SaveRun() error = %v", err)
+	}
+	if err := history.SaveRunData(timeRef, sitesData); err != nil {
+		t.Fatalf("SaveRunData() error = %v", err)
+	}
+	for _, suffix := range []string{".json", runDataFileSuffix} {
+		content, _ := os.ReadFile(filepath.Join(dir, "runs", timeRef.Format(runFileLayout)+suffix))
+		if !strings.Contains(string(content), `"ciphertext"`) || strings.Contains(string(content), "Visits") {
+			t.Errorf("SaveRun() stored %s, want the site with key encrypted", content)
+		}
+	}
+
+	//The history with the key reads every site back, the one without it skips the encrypted site
+	gotReports, gotData, found, err := history.Run(timeRef)
+	if err != nil || !found || !reflect.DeepEqual(gotReports, reports) || !reflect.DeepEqual(gotData, sitesData) {
+		t.Errorf("Run() = %v, %v, %v, %v, want every site decrypted", gotReports, gotData, found, err)
+	}
+	plain, _ := NewRunHistory(config.StorageParams{Dir: dir, RunSnapshots: true})
+	gotReports, gotData, found, err = plain.Run(timeRef)
+	if err != nil || !found || len(gotReports) != 1 || gotReports[0].SiteId != "shop" || len(gotData) != 1 || gotData[0].SiteId != "shop" {
+		t.Errorf("Run() without key = %v, %v, %v, %v, want only the site without key", gotReports, gotData, found, err)
+	}
+}
+
+func TestReadRunReports(t *testing.T) {
+	defer func(clock utils.Clock) { Clock = clock }(Clock)
+	timeRef := time.Date(2024, 3, 3, 22, 0, 0, 0, time.UTC)
+	Clock = utils.FixedClock(timeRef)
+
+	dir := t.TempDir()
+	history, err := NewRunHistory(config.StorageParams{Dir: dir})
+	if err != nil {
+		t.Fatalf("NewRunHistory() error = %v", err)
+	}
+	if err := history.SaveRun(timeRef, []OutlierReport{{SiteId: "stored"}}); err != nil {
+		t.Fatalf("SaveRun() error = %v", err)
+	}
+	reportFile := filepath.Join(dir, "reports.json")
+	if err := os.WriteFile(reportFile, []byte(`[{"siteId": "file"}]`), 0o644); err != nil {
+		t.Fatalf("os.WriteFile() error = %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		ref      string
+		history  *RunHistory
+		wantSite string
+		wantErr  bool
+	}{
+		{name: "Report file", ref: reportFile, history: history, wantSite: "file"},
+		{name: "Report file without history", ref: reportFile, wantSite: "file"},
+		{name: "RFC 3339 run id", ref: "2024-03-03T22:00:00Z", history: history, wantSite: "stored"},
+		{name: "Run id in another time zone", ref: "2024-03-03T23:00:00+01:00", history: history, wantSite: "stored"},
+		{name: "Run id as named on the history", ref: "2024-03-03T220000Z", history: history, wantSite: "stored"},
+		{name: "Run not stored", ref: "2024-03-02T22:00:00Z", history: history, wantErr: true},
+		{name: "Run id without history", ref: "2024-03-03T22:00:00Z", wantErr: true},
+		{name: "Missing file", ref: filepath.Join(dir, "missing.json"), history: history, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reports, err := ReadRunReports(tt.ref, tt.history)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ReadRunReports() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && (len(reports) != 1 || reports[0].SiteId != tt.wantSite) {
+				t.Errorf("ReadRunReports() = %v, want the reports of %s", reports, tt.wantSite)
+			}
+		})
+	}
+
+	//A report file compared with a stored run, as compare-runs does with mixed arguments
+	alarm := OutlierEvent{OutlierPeriodStart: timeRef, OutlierPeriodEnd: timeRef.Add(time.Hour), Metric: "Revenue", Attribute: "Total"}
+	if err := history.SaveRun(timeRef.Add(time.Hour), []OutlierReport{{SiteId: "shop", Result: OutlierResults{Alarms: []OutlierEvent{alarm}}}}); err != nil {
+		t.Fatalf("SaveRun() error = %v", err)
+	}
+	utils.WriteJsonStruct([]OutlierReport{{SiteId: "shop", Result: OutlierResults{Alarms: []OutlierEvent{alarm}}}}, reportFile)
+	reportsA, errA := ReadRunReports(reportFile, history)
+	reportsB, errB := ReadRunReports("2024-03-03T230000Z", history)
+	if errA != nil || errB != nil {
+		t.Fatalf("ReadRunReports() errors = %v, %v", errA, errB)
+	}
+	if comparison := CompareReports(reportsA, reportsB); comparison.Unchanged != 1 || len(comparison.OnlyInA) != 0 || len(comparison.OnlyInB) != 0 {
+		t.Errorf("CompareReports() = %v, want the alarm unchanged", comparison)
+	}
+}
@@ -0,0 +1,129 @@
+package analyser
+
+import (
+	"log"
+	"math"
+	"time"
+
+	"github.com/ftfmtavares/anomalies-detector/collector"
+	"github.com/ftfmtavares/anomalies-detector/config"
+)
+
+//madScale converts a median absolute deviation into a standard deviation estimate for normally distributed values
+const madScale = 1.4826
+
+//detectOutliersHoltWinters implements the holt-winters (additive triple exponential smoothing) method
+//Each metric value is compared against the one step ahead forecast of level, trend and seasonality, and flagged when its deviation
+//exceeds the confidence bands, given in robust standard deviations (median absolute deviation) of all forecast errors
+//The smoothing runs twice, the 2nd time replacing strong outliers by their forecast so that they don't distort the following forecasts
+//The first season only initializes the model and is never classified as outlier
+func detectOutliersHoltWinters(data []collector.TimeStepData, PeriodEnd time.Time, params config.HoltWintersParams) ([]eventPeriod, []eventPeriod) {
+	levels := make([]int, len(data))
+	if params.SeasonLength < 2 || len(data) < 2*params.SeasonLength {
+		log.Printf("Not enough data for holt-winters with season length %d, at least 2 seasons are required and %d time steps were given\n", params.SeasonLength, len(data))
+		return eventPeriodsFromLevels(data, levels, PeriodEnd)
+	}
+	for _, factor := range []float64{params.Alpha, params.Beta, params.Gamma} {
+		if factor < 0 || factor > 1 {
+			log.Printf("Invalid holt-winters smoothing factors %.2f, %.2f and %.2f, they must be between 0 and 1\n", params.Alpha, params.Beta, params.Gamma)
+			return eventPeriodsFromLevels(data, levels, PeriodEnd)
+		}
+	}
+
+	//Series forecasted exactly have no spread of errors to measure, any rounding error being flagged otherwise
+	errors, sd := holtWintersModel(data, params)
+	if sd == 0 {
+		return eventPeriodsFromLevels(data, levels, PeriodEnd)
+	}
+	for ind := params.SeasonLength; ind < len(data); ind++ {
+		if math.Abs(errors[ind]) > params.StrongOutliersMultiplier*sd {
+			levels[ind] = levelAlarm
+		} else if math.Abs(errors[ind]) > params.OutliersMultiplier*sd {
+			levels[ind] = levelWarning
+		}
+	}
+
+	return eventPeriodsFromLevels(data, levels, PeriodEnd)
+}
+
+//holtWintersModel returns the one step ahead forecast errors of a series and their robust standard deviation
+//1st pass estimating the forecast errors spread, 2nd pass keeping strong outliers out of the smoothing
+//Mostly exact forecasts have no median absolute deviation, the standard deviation of the errors being taken instead (zero if all forecasts are exact)
+func holtWintersModel(data []collector.TimeStepData, params config.HoltWintersParams) ([]float64, float64) {
+	values := make([]float64, len(data))
+	for ind, stepData := range data {
+		values[ind] = stepData.Value
+	}
+
+	errors := holtWintersErrors(values, params, math.Inf(1))
+	sd := madScale * medianAbsoluteDeviation(errors[params.SeasonLength:])
+	if sd == 0 {
+		sd = standardDeviation(errors[params.SeasonLength:])
+	}
+	return holtWintersErrors(values, params, params.StrongOutliersMultiplier*sd), sd
+}
+
+//holtWintersErrors runs the additive triple exponential smoothing over the values and returns the one step ahead forecast errors
+//The model is initialized with the first 2 seasons and errors of the first season are left as zero
+//Values whose error exceeds the given limit are replaced by their forecast when updating the model
+func holtWintersErrors(values []float64, params config.HoltWintersParams, limit float64) []float64 {
+	seasonLength := params.SeasonLength
+
+	//Initializing level and trend from the means of the first 2 seasons, the level being placed at the end of the 1st season
+	firstMean, secondMean := 0.0, 0.0
+	for ind := 0; ind < seasonLength; ind++ {
+		firstMean += values[ind] / float64(seasonLength)
+		secondMean += values[seasonLength+ind] / float64(seasonLength)
+	}
+	trend := (secondMean - firstMean) / float64(seasonLength)
+	level := firstMean + trend*float64(seasonLength-1)/2
+
+	//Initializing the seasonal components as the deviations of the 1st season from the initial trend line
+	seasonal := make([]float64, seasonLength)
+	for ind := 0; ind < seasonLength; ind++ {
+		seasonal[ind] = values[ind] - (firstMean + trend*(float64(ind)-float64(seasonLength-1)/2))
+	}
+
+	errors := make([]float64, len(values))
+	for ind := seasonLength; ind < len(values); ind++ {
+		forecast := level + trend + seasonal[ind%seasonLength]
+		errors[ind] = values[ind] - forecast
+
+		observed := values[ind]
+		if math.Abs(errors[ind]) > limit {
+			observed = forecast
+		}
+
+		previousLevel := level
+		level = params.Alpha*(observed-seasonal[ind%seasonLength]) + (1-params.Alpha)*(level+trend)
+		trend = params.Beta*(level-previousLevel) + (1-params.Beta)*trend
+		seasonal[ind%seasonLength] = params.Gamma*(observed-level) + (1-params.Gamma)*seasonal[ind%seasonLength]
+	}
+
+	return errors
+}
+
+//medianAbsoluteDeviation returns the median of the absolute deviations from the median of the given values
+func medianAbsoluteDeviation(values []float64) float64 {
+	center := median(values)
+	deviations := make([]float64, len(values))
+	for ind, value := range values {
+		deviations[ind] = math.Abs(value - center)
+	}
+	return median(deviations)
+}
+
+//standardDeviation returns the population standard deviation of the given values, which mustn't be empty
+func standardDeviation(values []float64) float64 {
+	sum := 0.0
+	for _, value := range values {
+		sum += value
+	}
+	mean := sum / float64(len(values))
+
+	sd := 0.0
+	for _, value := range values {
+		sd += math.Pow(value-mean, 2)
+	}
+	return math.Sqrt(sd / float64(len(values)))
+}
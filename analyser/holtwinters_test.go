@@ -0,0 +1,88 @@
+package analyser
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ftfmtavares/anomalies-detector/collector"
+	"github.com/ftfmtavares/anomalies-detector/config"
+)
+
+func TestDetectOutliersHoltWinters(t *testing.T) {
+	timeRef := time.Now()
+
+	//8 weeks of daily values with a rising trend, a weekly peak on the last day of each week and a single spike on sample #46
+	values := []float64{}
+	for i := 0; i < 56; i++ {
+		value := 100 + float64(i)/2 + float64((i*3)%5) - 2
+		if i%7 == 6 {
+			value += 100
+		}
+		if i == 45 {
+			value += 50
+		}
+		values = append(values, value)
+	}
+	data := make([]collector.TimeStepData, len(values))
+	for i, val := range values {
+		data[i].Samples = 100
+		data[i].DateStart = timeRef.AddDate(0, 0, -len(values)+i)
+		data[i].Value = val
+	}
+
+	//8 weeks of the same weekly pattern, forecasted exactly but for a slight bump on sample #41 and a spike on sample #46
+	repeated := make([]collector.TimeStepData, 56)
+	for i := range repeated {
+		repeated[i] = collector.TimeStepData{DateStart: timeRef.AddDate(0, 0, -len(repeated)+i), Value: 100 + float64(i%7)*10, Samples: 100}
+	}
+	repeated[40].Value++
+	repeated[45].Value += 100
+
+	params := config.HoltWintersParams{Alpha: 0.3, Beta: 0.05, Gamma: 0.2, SeasonLength: 7, OutliersMultiplier: 3, StrongOutliersMultiplier: 5}
+	tooLong := params
+	tooLong.SeasonLength = 30
+
+	tests := []struct {
+		name           string
+		data           []collector.TimeStepData
+		params         config.HoltWintersParams
+		wantedWarnings []eventPeriod
+		wantedAlarms   []eventPeriod
+	}{
+		{
+			name:           "Trend and weekly peaks are forecasted and the spike at sample #46 is an alarm",
+			params:         params,
+			wantedWarnings: []eventPeriod{},
+			wantedAlarms:   []eventPeriod{{outlierPeriodStart: timeRef.AddDate(0, 0, -11), outlierPeriodEnd: timeRef.AddDate(0, 0, -10)}},
+		},
+		{
+			name:           "Mostly exact forecasts judged on the standard deviation of their errors",
+			data:           repeated,
+			params:         params,
+			wantedWarnings: []eventPeriod{},
+			wantedAlarms:   []eventPeriod{{outlierPeriodStart: timeRef.AddDate(0, 0, -11), outlierPeriodEnd: timeRef.AddDate(0, 0, -10)}},
+		},
+		{
+			name:           "Less than 2 seasons of data",
+			params:         tooLong,
+			wantedWarnings: []eventPeriod{},
+			wantedAlarms:   []eventPeriod{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.data == nil {
+				tt.data = data
+			}
+			warnings, alarms := detectOutliersHoltWinters(tt.data, timeRef, tt.params)
+			if !reflect.DeepEqual(warnings, tt.wantedWarnings) {
+				t.Errorf("detectOutliersHoltWinters() got = %v, want %v", warnings, tt.wantedWarnings)
+			}
+			if !reflect.DeepEqual(alarms, tt.wantedAlarms) {
+				t.Errorf("detectOutliersHoltWinters() got1 = %v, want %v", alarms, tt.wantedAlarms)
+			}
+		})
+	}
+}
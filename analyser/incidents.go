@@ -0,0 +1,89 @@
+package analyser
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/ftfmtavares/anomalies-detector/config"
+	"github.com/ftfmtavares/anomalies-detector/utils"
+)
+
+//Incident provides the structure for a group of events overlapping in time, across attributes and metrics, most likely having a single cause
+//Severity field is "alarm" when any of its events is an alarm and "warning" otherwise, Score field being the highest score of its events
+//Metrics and Attributes fields list the distinct metrics and attribute paths of its events, in order of appearance, alarms first
+type Incident struct {
+	PeriodStart time.Time      `json:"periodStart"`
+	PeriodEnd   time.Time      `json:"periodEnd"`
+	Severity    string         `json:"severity"`
+	Score       float64        `json:"score"`
+	Metrics     []string       `json:"metrics"`
+	Attributes  []string       `json:"attributes"`
+	Events      OutlierResults `json:"events"`
+}
+
+//newGroupProcessor creates the "group" post-processor, grouping the events overlapping in time, or less than the step gap apart, into incidents
+//Events are kept as they are, so it's meant to be the last step, after those dropping or joining events
+func newGroupProcessor(step config.PostProcessor) (PostProcessor, error) {
+	gap := time.Duration(0)
+	if step.Gap != "" {
+		var err error
+		if gap, err = utils.StrToDuration(step.Gap); err != nil || gap < 0 {
+			return nil, fmt.Errorf("group - invalid gap \"%s\"", step.Gap)
+		}
+	}
+	return PostProcessorFunc(func(report OutlierReport) OutlierReport {
+		report.Incidents = GroupIncidents(report.Result, gap)
+		return report
+	}), nil
+}
+
+//GroupIncidents groups the alarms and warnings whose periods overlap, touch or are less than gap apart into incidents, ordered by start
+//Events chain into a single incident, so one overlapping two incidents joins them
+func GroupIncidents(results OutlierResults, gap time.Duration) []Incident {
+	type member struct {
+		event OutlierEvent
+		alarm bool
+	}
+	members := []member{}
+	for _, alarm := range results.Alarms {
+		members = append(members, member{event: alarm, alarm: true})
+	}
+	for _, warning := range results.Warnings {
+		members = append(members, member{event: warning})
+	}
+	sort.SliceStable(members, func(i, j int) bool {
+		return members[i].event.OutlierPeriodStart.Before(members[j].event.OutlierPeriodStart)
+	})
+
+	incidents := []Incident{}
+	for _, member := range members {
+		last := len(incidents) - 1
+		if last < 0 || member.event.OutlierPeriodStart.After(incidents[last].PeriodEnd.Add(gap)) {
+			incidents = append(incidents, Incident{PeriodStart: member.event.OutlierPeriodStart, PeriodEnd: member.event.OutlierPeriodEnd, Severity: "warning", Events: OutlierResults{Warnings: []OutlierEvent{}, Alarms: []OutlierEvent{}}})
+			last++
+		}
+		incident := &incidents[last]
+		if member.event.OutlierPeriodEnd.After(incident.PeriodEnd) {
+			incident.PeriodEnd = member.event.OutlierPeriodEnd
+		}
+		if member.alarm {
+			incident.Severity = "alarm"
+			incident.Events.Alarms = append(incident.Events.Alarms, member.event)
+		} else {
+			incident.Events.Warnings = append(incident.Events.Warnings, member.event)
+		}
+		if member.event.Score > incident.Score {
+			incident.Score = member.event.Score
+		}
+	}
+
+	for i := range incidents {
+		incidents[i].Metrics, incidents[i].Attributes = []string{}, []string{}
+		for _, event := range append(append([]OutlierEvent{}, incidents[i].Events.Alarms...), incidents[i].Events.Warnings...) {
+			incidents[i].Metrics = unionStrings(incidents[i].Metrics, []string{event.Metric})
+			incidents[i].Attributes = unionStrings(incidents[i].Attributes, []string{event.Attribute})
+		}
+	}
+	return incidents
+}
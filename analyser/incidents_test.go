@@ -0,0 +1,79 @@
+package analyser
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ftfmtavares/anomalies-detector/config"
+)
+
+func TestGroupIncidents(t *testing.T) {
+	timeRef := time.Date(2022, 9, 1, 0, 0, 0, 0, time.UTC)
+	event := func(metric, attribute string, start, end int, score float64) OutlierEvent {
+		return OutlierEvent{Metric: metric, Attribute: attribute, OutlierPeriodStart: timeRef.Add(time.Duration(start) * time.Hour), OutlierPeriodEnd: timeRef.Add(time.Duration(end) * time.Hour), Score: score}
+	}
+
+	//A site wide drop fires on several metrics and attributes at once, an unrelated warning coming a few hours later
+	results := OutlierResults{
+		Alarms:   []OutlierEvent{event("Revenue", "Total", 10, 12, 5), event("Visits", "Total", 11, 13, 4)},
+		Warnings: []OutlierEvent{event("Revenue", "DeviceType>Desktop", 10, 11, 2.5), event("Basket", "Browser>Edge", 15, 16, 2.2)},
+	}
+
+	tests := []struct {
+		name string
+		gap  time.Duration
+		want []Incident
+	}{
+		{
+			name: "Overlapping events",
+			want: []Incident{
+				{
+					PeriodStart: timeRef.Add(10 * time.Hour), PeriodEnd: timeRef.Add(13 * time.Hour), Severity: "alarm", Score: 5,
+					Metrics: []string{"Revenue", "Visits"}, Attributes: []string{"Total", "DeviceType>Desktop"},
+					Events: OutlierResults{Alarms: results.Alarms, Warnings: results.Warnings[:1]},
+				},
+				{
+					PeriodStart: timeRef.Add(15 * time.Hour), PeriodEnd: timeRef.Add(16 * time.Hour), Severity: "warning", Score: 2.2,
+					Metrics: []string{"Basket"}, Attributes: []string{"Browser>Edge"},
+					Events: OutlierResults{Alarms: []OutlierEvent{}, Warnings: results.Warnings[1:]},
+				},
+			},
+		},
+		{
+			name: "Events less than the gap apart",
+			gap:  2 * time.Hour,
+			want: []Incident{
+				{
+					PeriodStart: timeRef.Add(10 * time.Hour), PeriodEnd: timeRef.Add(16 * time.Hour), Severity: "alarm", Score: 5,
+					Metrics: []string{"Revenue", "Visits", "Basket"}, Attributes: []string{"Total", "DeviceType>Desktop", "Browser>Edge"},
+					Events: OutlierResults{Alarms: results.Alarms, Warnings: results.Warnings},
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := GroupIncidents(results, tt.gap); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GroupIncidents() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGroupPostProcessor(t *testing.T) {
+	if _, err := CompilePostProcessors([]config.PostProcessor{{Type: "group", Gap: "soon"}}); err == nil {
+		t.Errorf("CompilePostProcessors() expected an error for an invalid gap")
+	}
+	postProcessors, err := CompilePostProcessors([]config.PostProcessor{{Type: "group", Gap: "1h"}})
+	if err != nil {
+		t.Fatalf("CompilePostProcessors() error = %v", err)
+	}
+
+	timeRef := time.Date(2022, 9, 1, 0, 0, 0, 0, time.UTC)
+	report := OutlierReport{Result: OutlierResults{Alarms: []OutlierEvent{{Metric: "Revenue", Attribute: "Total", OutlierPeriodStart: timeRef, OutlierPeriodEnd: timeRef.Add(time.Hour)}}}}
+	got := ApplyPostProcessors(report, postProcessors)
+	if len(got.Incidents) != 1 || !reflect.DeepEqual(got.Result, report.Result) {
+		t.Errorf("group = %+v, want a single incident and the events kept", got)
+	}
+}
@@ -0,0 +1,143 @@
+package analyser
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/ftfmtavares/anomalies-detector/collector"
+	"github.com/ftfmtavares/anomalies-detector/config"
+)
+
+//KnownEventWindow provides the structure for an occurrence of a known event of a site within the analysed period
+//Action field is "exclude" when its time steps were left out of the detection and "tag" when the events overlapping it were reported as expected
+type KnownEventWindow struct {
+	Name        string    `json:"name"`
+	PeriodStart time.Time `json:"periodStart"`
+	PeriodEnd   time.Time `json:"periodEnd"`
+	Action      string    `json:"action"`
+	Metrics     []string  `json:"metrics,omitempty"`
+}
+
+//ParseKnownEvents returns the occurrences of the known events of a dataset overlapping the given period, in start order
+//Recurring events repeat after their first occurrence only, dates being taken in the dataset time zone
+//It returns an error if any event is invalid
+func ParseKnownEvents(dataConf config.Dataset, dateStart, dateEnd time.Time) ([]KnownEventWindow, error) {
+	location, err := dataConf.Location()
+	if err != nil {
+		return nil, err
+	}
+
+	windows := []KnownEventWindow{}
+	for _, event := range dataConf.KnownEvents {
+		start, end, err := event.Period(location)
+		if err != nil {
+			return nil, fmt.Errorf("known event \"%s\" - %s", event.Name, err.Error())
+		}
+		action := event.Action
+		if action == "" {
+			action = config.KnownEventTag
+		}
+		if action != config.KnownEventExclude && action != config.KnownEventTag {
+			return nil, fmt.Errorf("known event \"%s\" - invalid action \"%s\"", event.Name, event.Action)
+		}
+
+		var years, months, days int
+		switch event.Recurrence {
+		case "":
+		case config.RecurrenceWeekly:
+			days = 7
+		case config.RecurrenceMonthly:
+			months = 1
+		case config.RecurrenceYearly:
+			years = 1
+		default:
+			return nil, fmt.Errorf("known event \"%s\" - invalid recurrence \"%s\"", event.Name, event.Recurrence)
+		}
+
+		//Moving the occurrence forward until it starts after the period, keeping those ending within it
+		for n := 0; start.Before(dateEnd); n++ {
+			if end.After(dateStart) {
+				windows = append(windows, KnownEventWindow{Name: event.Name, PeriodStart: start, PeriodEnd: end, Action: action, Metrics: event.Metrics})
+			}
+			if event.Recurrence == "" {
+				break
+			}
+			start, end = start.AddDate(years, months, days), end.AddDate(years, months, days)
+		}
+	}
+	sort.SliceStable(windows, func(i, j int) bool { return windows[i].PeriodStart.Before(windows[j].PeriodStart) })
+	return windows, nil
+}
+
+//appliesTo checks if a known event window affects the given metric
+func (window KnownEventWindow) appliesTo(metric string) bool {
+	if len(window.Metrics) == 0 {
+		return true
+	}
+	for _, name := range window.Metrics {
+		if name == metric {
+			return true
+		}
+	}
+	return false
+}
+
+//excludeKnownEvents returns a copy of the site data with the time steps starting within the "exclude" windows marked as missing, so the detection leaves them out
+func excludeKnownEvents(siteData collector.SiteData, windows []KnownEventWindow) collector.SiteData {
+	metrics := make([]collector.MetricData, len(siteData.Metrics))
+	for i, metricData := range siteData.Metrics {
+		metrics[i] = metricData
+		excluded := []KnownEventWindow{}
+		for _, window := range windows {
+			if window.Action == config.KnownEventExclude && window.appliesTo(metricData.Metric) {
+				excluded = append(excluded, window)
+			}
+		}
+		if len(excluded) == 0 {
+			continue
+		}
+
+		metrics[i].AttributeData = map[string][]collector.TimeStepData{}
+		for attribute, data := range metricData.AttributeData {
+			marked := append([]collector.TimeStepData{}, data...)
+			for ind := range marked {
+				for _, window := range excluded {
+					if !marked[ind].DateStart.Before(window.PeriodStart) && marked[ind].DateStart.Before(window.PeriodEnd) {
+						marked[ind].Missing = true
+					}
+				}
+			}
+			metrics[i].AttributeData[attribute] = marked
+		}
+	}
+	siteData.Metrics = metrics
+	return siteData
+}
+
+//tagKnownEvents marks the events overlapping a "tag" window of their metric as expected, naming the known event
+//Expected alarms are reported as warnings, after the detected ones
+func tagKnownEvents(results OutlierResults, windows []KnownEventWindow) OutlierResults {
+	knownEvent := func(event OutlierEvent) string {
+		for _, window := range windows {
+			if window.Action == config.KnownEventTag && window.appliesTo(event.Metric) && event.OutlierPeriodStart.Before(window.PeriodEnd) && window.PeriodStart.Before(event.OutlierPeriodEnd) {
+				return window.Name
+			}
+		}
+		return ""
+	}
+
+	res := OutlierResults{Warnings: []OutlierEvent{}, Alarms: []OutlierEvent{}}
+	for _, event := range results.Warnings {
+		event.KnownEvent = knownEvent(event)
+		res.Warnings = append(res.Warnings, event)
+	}
+	for _, event := range results.Alarms {
+		if event.KnownEvent = knownEvent(event); event.KnownEvent != "" {
+			res.Warnings = append(res.Warnings, event)
+			continue
+		}
+		res.Alarms = append(res.Alarms, event)
+	}
+	return res
+}
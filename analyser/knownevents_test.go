@@ -0,0 +1,83 @@
+package analyser
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ftfmtavares/anomalies-detector/collector"
+	"github.com/ftfmtavares/anomalies-detector/config"
+)
+
+func TestParseKnownEvents(t *testing.T) {
+	dataConf := config.Dataset{SiteId: "shop", Timezone: "UTC", KnownEvents: []config.KnownEvent{
+		{Name: "Black Friday", Start: "2019-11-29", End: "2019-12-03", Recurrence: "yearly", Action: "exclude"},
+		{Name: "Launch", Start: "2022-11-10T09:00:00Z", End: "2022-11-10T18:00:00Z", Metrics: []string{"Visits"}},
+		{Name: "Sunday sale", Start: "2022-10-02", End: "2022-10-03", Recurrence: "weekly"},
+	}}
+	day := func(month time.Month, day int) time.Time { return time.Date(2022, month, day, 0, 0, 0, 0, time.UTC) }
+
+	got, err := ParseKnownEvents(dataConf, day(11, 1), day(12, 1))
+	if err != nil {
+		t.Fatalf("ParseKnownEvents() error = %v", err)
+	}
+	want := []KnownEventWindow{
+		{Name: "Sunday sale", PeriodStart: day(11, 6), PeriodEnd: day(11, 7), Action: "tag"},
+		{Name: "Launch", PeriodStart: time.Date(2022, 11, 10, 9, 0, 0, 0, time.UTC), PeriodEnd: time.Date(2022, 11, 10, 18, 0, 0, 0, time.UTC), Action: "tag", Metrics: []string{"Visits"}},
+		{Name: "Sunday sale", PeriodStart: day(11, 13), PeriodEnd: day(11, 14), Action: "tag"},
+		{Name: "Sunday sale", PeriodStart: day(11, 20), PeriodEnd: day(11, 21), Action: "tag"},
+		{Name: "Sunday sale", PeriodStart: day(11, 27), PeriodEnd: day(11, 28), Action: "tag"},
+		{Name: "Black Friday", PeriodStart: time.Date(2022, 11, 29, 0, 0, 0, 0, time.UTC), PeriodEnd: time.Date(2022, 12, 3, 0, 0, 0, 0, time.UTC), Action: "exclude"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseKnownEvents() = %+v, want %+v", got, want)
+	}
+
+	dataConf.KnownEvents = []config.KnownEvent{{Name: "Broken", Start: "2022-11-10", End: "2022-11-09"}}
+	if _, err := ParseKnownEvents(dataConf, day(11, 1), day(12, 1)); err == nil {
+		t.Errorf("ParseKnownEvents() error = nil, want an error for an event ending before it starts")
+	}
+}
+
+func TestGetResultsKnownEvents(t *testing.T) {
+	//A spike on the 21st day of a flat series, flagged as an alarm unless a known event covers it
+	timeRef := time.Date(2022, 9, 1, 0, 0, 0, 0, time.UTC)
+	data := make([]collector.TimeStepData, 30)
+	for day := range data {
+		data[day] = collector.TimeStepData{DateStart: timeRef.AddDate(0, 0, day), Value: 100 + float64(day%2), Samples: 100}
+	}
+	data[20].Value = 1000
+	siteData := collector.SiteData{SiteId: "shop", TimeStep: "1d", DateStart: timeRef, DateEnd: timeRef.AddDate(0, 0, 30), Metrics: []collector.MetricData{
+		{Metric: "Revenue", Attributes: []string{"Total"}, AttributeData: map[string][]collector.TimeStepData{"Total": data}},
+	}}
+	methodParams := config.DetectionMethodsParams{ThreeSigmas: config.ThreeSigmasParams{OutliersMultiplier: 2, StrongOutliersMultiplier: 3}}
+	sale := config.KnownEvent{Name: "Sale", Start: "2022-09-21", End: "2022-09-22"}
+
+	tests := []struct {
+		name         string
+		knownEvents  []config.KnownEvent
+		wantAlarms   int
+		wantWarnings []string
+	}{
+		{name: "No known events", wantAlarms: 1, wantWarnings: []string{}},
+		{name: "Tagged event", knownEvents: []config.KnownEvent{sale}, wantWarnings: []string{"Sale"}},
+		{name: "Event of another metric", knownEvents: []config.KnownEvent{{Name: "Sale", Start: "2022-09-21", End: "2022-09-22", Metrics: []string{"Visits"}}}, wantAlarms: 1, wantWarnings: []string{}},
+		{name: "Excluded event", knownEvents: []config.KnownEvent{{Name: "Sale", Start: "2022-09-21", End: "2022-09-22", Action: "exclude"}}, wantWarnings: []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dataConf := config.Dataset{SiteId: "shop", TimeAgo: "30d", TimeStep: "1d", OutliersDetectionMethod: "3-sigmas", Timezone: "UTC", KnownEvents: tt.knownEvents}
+			report := GetResults(siteData, dataConf, methodParams)
+			if len(report.Result.Alarms) != tt.wantAlarms {
+				t.Errorf("GetResults() alarms = %+v, want %d", report.Result.Alarms, tt.wantAlarms)
+			}
+			warnings := []string{}
+			for _, warning := range report.Result.Warnings {
+				warnings = append(warnings, warning.KnownEvent)
+			}
+			if !reflect.DeepEqual(warnings, tt.wantWarnings) {
+				t.Errorf("GetResults() warnings known events = %v, want %v", warnings, tt.wantWarnings)
+			}
+		})
+	}
+}
@@ -0,0 +1,209 @@
+package analyser
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+//Const block defines the supported label verdicts
+//An incident is a real anomaly the detection should flag, while a false alarm is a flagged period that was actually normal
+const (
+	VerdictIncident   = "incident"
+	VerdictFalseAlarm = "false-alarm"
+)
+
+//Label provides the structure for a known verdict on a period of a site metric, used to evaluate and tune the detection against real history
+//Attribute field defaults to "Total" while Note field is an optional free text (e.g. the postmortem reference)
+type Label struct {
+	SiteId      string    `json:"siteId"`
+	Metric      string    `json:"metric"`
+	Attribute   string    `json:"attribute"`
+	PeriodStart time.Time `json:"periodStart"`
+	PeriodEnd   time.Time `json:"periodEnd"`
+	Verdict     string    `json:"verdict"`
+	Note        string    `json:"note,omitempty"`
+}
+
+//key identifies the labelled period, a later verdict on the same period replacing the former one
+func (label Label) key() string {
+	return strings.Join([]string{label.SiteId, label.Metric, label.Attribute, label.PeriodStart.UTC().String(), label.PeriodEnd.UTC().String()}, "|")
+}
+
+//validate completes the label defaults and checks its fields
+func (label *Label) validate() error {
+	if label.SiteId == "" || label.Metric == "" {
+		return errors.New("site and metric are required")
+	}
+	if label.Attribute == "" {
+		label.Attribute = "Total"
+	}
+	if label.PeriodStart.IsZero() || !label.PeriodEnd.After(label.PeriodStart) {
+		return errors.New("the period end must be after its start")
+	}
+	if label.Verdict != VerdictIncident && label.Verdict != VerdictFalseAlarm {
+		return fmt.Errorf("invalid verdict \"%s\", it must be \"%s\" or \"%s\"", label.Verdict, VerdictIncident, VerdictFalseAlarm)
+	}
+	return nil
+}
+
+//ReadLabelsFile reads the label store file, a missing file being an empty store
+func ReadLabelsFile(labelFile string) ([]Label, error) {
+	byteValue, err := os.ReadFile(labelFile)
+	if os.IsNotExist(err) {
+		return []Label{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	labels := []Label{}
+	if len(strings.TrimSpace(string(byteValue))) == 0 {
+		return labels, nil
+	}
+	if err := json.Unmarshal(byteValue, &labels); err != nil {
+		return nil, err
+	}
+	return labels, nil
+}
+
+//ImportIncidents reads known past incidents from a CSV file with a header line or from a JSON array of labels, according to the format ("csv" or "json")
+//CSV columns are "site", "metric", "attribute" (optional), "start", "end", "verdict" and "note" (optional), dates being "2006-01-02" or RFC 3339 times
+//It returns an error referencing the first invalid incident
+func ImportIncidents(reader io.Reader, format string) ([]Label, error) {
+	labels := []Label{}
+	switch format {
+	case "json":
+		var records []struct {
+			Label
+			Site  string `json:"site"`
+			Start string `json:"start"`
+			End   string `json:"end"`
+		}
+		if err := json.NewDecoder(reader).Decode(&records); err != nil {
+			return nil, fmt.Errorf("incidents - %s", err.Error())
+		}
+		for i, record := range records {
+			label := record.Label
+			if label.SiteId == "" {
+				label.SiteId = record.Site
+			}
+			var err error
+			if record.Start != "" {
+				if label.PeriodStart, err = parseLabelTime(record.Start); err != nil {
+					return nil, fmt.Errorf("incident #%d - %s", i+1, err.Error())
+				}
+			}
+			if record.End != "" {
+				if label.PeriodEnd, err = parseLabelTime(record.End); err != nil {
+					return nil, fmt.Errorf("incident #%d - %s", i+1, err.Error())
+				}
+			}
+			if err := label.validate(); err != nil {
+				return nil, fmt.Errorf("incident #%d - %s", i+1, err.Error())
+			}
+			labels = append(labels, label)
+		}
+	case "csv":
+		csvReader := csv.NewReader(reader)
+		csvReader.TrimLeadingSpace = true
+		header, err := csvReader.Read()
+		if err != nil {
+			return nil, fmt.Errorf("incidents - reading header - %s", err.Error())
+		}
+		columns := map[string]int{}
+		for i, column := range header {
+			columns[strings.ToLower(strings.TrimSpace(column))] = i
+		}
+		for _, column := range []string{"site", "metric", "start", "end", "verdict"} {
+			if _, found := columns[column]; !found {
+				return nil, fmt.Errorf("incidents - missing column \"%s\"", column)
+			}
+		}
+		cell := func(row []string, column string) string {
+			if ind, found := columns[column]; found && ind < len(row) {
+				return strings.TrimSpace(row[ind])
+			}
+			return ""
+		}
+
+		for line := 2; ; line++ {
+			row, err := csvReader.Read()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return nil, fmt.Errorf("incidents - %s", err.Error())
+			}
+			label := Label{SiteId: cell(row, "site"), Metric: cell(row, "metric"), Attribute: cell(row, "attribute"), Verdict: cell(row, "verdict"), Note: cell(row, "note")}
+			if label.PeriodStart, err = parseLabelTime(cell(row, "start")); err != nil {
+				return nil, fmt.Errorf("incidents line %d - %s", line, err.Error())
+			}
+			if label.PeriodEnd, err = parseLabelTime(cell(row, "end")); err != nil {
+				return nil, fmt.Errorf("incidents line %d - %s", line, err.Error())
+			}
+			if err := label.validate(); err != nil {
+				return nil, fmt.Errorf("incidents line %d - %s", line, err.Error())
+			}
+			labels = append(labels, label)
+		}
+	default:
+		return nil, fmt.Errorf("invalid incidents format \"%s\", it must be \"csv\" or \"json\"", format)
+	}
+	return labels, nil
+}
+
+//parseLabelTime parses a date ("2006-01-02") or an RFC 3339 time
+func parseLabelTime(value string) (time.Time, error) {
+	date, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		if date, err = time.Parse(time.RFC3339, value); err != nil {
+			return time.Time{}, fmt.Errorf("invalid time \"%s\", it must be a date or an RFC 3339 time", value)
+		}
+	}
+	return date, nil
+}
+
+//MergeLabels adds the imported labels to the stored ones, imported verdicts replacing the stored ones of the same period
+//The merged labels are returned by site, metric, attribute and period start, along with the number of added and updated labels
+func MergeLabels(stored, imported []Label) ([]Label, int, int) {
+	merged := map[string]Label{}
+	for _, label := range stored {
+		merged[label.key()] = label
+	}
+	added, updated := 0, 0
+	for _, label := range imported {
+		if previous, found := merged[label.key()]; !found {
+			added++
+		} else if previous.Verdict != label.Verdict || previous.Note != label.Note {
+			updated++
+		}
+		merged[label.key()] = label
+	}
+
+	res := []Label{}
+	for _, label := range merged {
+		res = append(res, label)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].SiteId != res[j].SiteId {
+			return res[i].SiteId < res[j].SiteId
+		}
+		if res[i].Metric != res[j].Metric {
+			return res[i].Metric < res[j].Metric
+		}
+		if res[i].Attribute != res[j].Attribute {
+			return res[i].Attribute < res[j].Attribute
+		}
+		if !res[i].PeriodStart.Equal(res[j].PeriodStart) {
+			return res[i].PeriodStart.Before(res[j].PeriodStart)
+		}
+		return res[i].PeriodEnd.Before(res[j].PeriodEnd)
+	})
+	return res, added, updated
+}
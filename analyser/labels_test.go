@@ -0,0 +1,101 @@
+package analyser
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestImportIncidents(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2022, 9, d, 0, 0, 0, 0, time.UTC) }
+
+	tests := []struct {
+		name    string
+		input   string
+		format  string
+		want    []Label
+		wantErr string
+	}{
+		{
+			name:   "CSV incidents",
+			format: "csv",
+			input:  "site,metric,attribute,start,end,verdict,note\nshop,Revenue,,2022-09-01,2022-09-03,incident,broken tag\nshop,Visits,Browser>Chrome,2022-09-05T00:00:00Z,2022-09-06T00:00:00Z,false-alarm,\n",
+			want: []Label{
+				{SiteId: "shop", Metric: "Revenue", Attribute: "Total", PeriodStart: day(1), PeriodEnd: day(3), Verdict: VerdictIncident, Note: "broken tag"},
+				{SiteId: "shop", Metric: "Visits", Attribute: "Browser>Chrome", PeriodStart: day(5), PeriodEnd: day(6), Verdict: VerdictFalseAlarm},
+			},
+		},
+		{
+			name:   "JSON incidents",
+			format: "json",
+			input:  `[{"site": "shop", "metric": "Revenue", "start": "2022-09-01", "end": "2022-09-03", "verdict": "incident"}]`,
+			want:   []Label{{SiteId: "shop", Metric: "Revenue", Attribute: "Total", PeriodStart: day(1), PeriodEnd: day(3), Verdict: VerdictIncident}},
+		},
+		{
+			name:    "Missing column",
+			format:  "csv",
+			input:   "site,metric,start,verdict\n",
+			wantErr: "missing column \"end\"",
+		},
+		{
+			name:    "Invalid verdict referencing its line",
+			format:  "csv",
+			input:   "site,metric,start,end,verdict\nshop,Revenue,2022-09-01,2022-09-02,incident\nshop,Revenue,2022-09-03,2022-09-04,maybe\n",
+			wantErr: "incidents line 3 - invalid verdict",
+		},
+		{
+			name:    "Period end before its start",
+			format:  "json",
+			input:   `[{"site": "shop", "metric": "Revenue", "start": "2022-09-03", "end": "2022-09-01", "verdict": "incident"}]`,
+			wantErr: "incident #1",
+		},
+		{
+			name:    "Unknown format",
+			format:  "xml",
+			wantErr: "invalid incidents format",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ImportIncidents(strings.NewReader(tt.input), tt.format)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("ImportIncidents() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ImportIncidents() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ImportIncidents() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMergeLabels(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2022, 9, d, 0, 0, 0, 0, time.UTC) }
+	revenue := Label{SiteId: "shop", Metric: "Revenue", Attribute: "Total", PeriodStart: day(1), PeriodEnd: day(3), Verdict: VerdictIncident}
+	visits := Label{SiteId: "shop", Metric: "Visits", Attribute: "Total", PeriodStart: day(5), PeriodEnd: day(6), Verdict: VerdictIncident}
+	relabelled := revenue
+	relabelled.Verdict = VerdictFalseAlarm
+
+	labels, added, updated := MergeLabels([]Label{visits, revenue}, []Label{relabelled, visits})
+	if !reflect.DeepEqual(labels, []Label{relabelled, visits}) || added != 0 || updated != 1 {
+		t.Errorf("MergeLabels() = %v, %d added, %d updated", labels, added, updated)
+	}
+
+	//The store file is empty until the first import
+	labelFile := filepath.Join(t.TempDir(), "labels.json")
+	if stored, err := ReadLabelsFile(labelFile); err != nil || len(stored) != 0 {
+		t.Errorf("ReadLabelsFile() = %v, %v, want an empty store", stored, err)
+	}
+	os.WriteFile(labelFile, []byte(`[{"siteId": "shop", "metric": "Revenue", "attribute": "Total", "periodStart": "2022-09-01T00:00:00Z", "periodEnd": "2022-09-03T00:00:00Z", "verdict": "incident"}]`), 0644)
+	if stored, err := ReadLabelsFile(labelFile); err != nil || !reflect.DeepEqual(stored, []Label{revenue}) {
+		t.Errorf("ReadLabelsFile() = %v, %v, want %v", stored, err, []Label{revenue})
+	}
+}
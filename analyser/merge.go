@@ -0,0 +1,69 @@
+package analyser
+
+import (
+	"github.com/ftfmtavares/anomalies-detector/collector"
+)
+
+//levelsFromEventPeriods takes the warning and alarm event periods of a method and returns the outlier level of each time step
+//It's the inverse of eventPeriodsFromLevels, a time step belonging to an event period if it starts within it
+func levelsFromEventPeriods(data []collector.TimeStepData, warnings, alarms []eventPeriod) []int {
+	levels := make([]int, len(data))
+	mark := func(periods []eventPeriod, level int) {
+		for _, period := range periods {
+			for ind, stepData := range data {
+				if !stepData.DateStart.Before(period.outlierPeriodStart) && stepData.DateStart.Before(period.outlierPeriodEnd) {
+					levels[ind] = level
+				}
+			}
+		}
+	}
+	mark(warnings, levelWarning)
+	mark(alarms, levelAlarm)
+	return levels
+}
+
+//mergeMethodsLevels merges the outlier levels of several methods on each time step
+//A time step is an alarm when at least consensus methods classify it as alarm (any method if consensus is 0 or 1),
+//otherwise it's a warning when any method flagged it, so alarms lacking consensus are downgraded instead of lost
+func mergeMethodsLevels(methodsLevels [][]int, consensus int) []int {
+	if len(methodsLevels) == 0 {
+		return []int{}
+	}
+	if consensus < 1 {
+		consensus = 1
+	}
+
+	levels := make([]int, len(methodsLevels[0]))
+	for ind := range levels {
+		alarms := 0
+		flagged := false
+		for _, methodLevels := range methodsLevels {
+			if methodLevels[ind] == levelAlarm {
+				alarms++
+			}
+			if methodLevels[ind] != levelNormal {
+				flagged = true
+			}
+		}
+		if alarms >= consensus {
+			levels[ind] = levelAlarm
+		} else if flagged {
+			levels[ind] = levelWarning
+		}
+	}
+	return levels
+}
+
+//flaggingMethods returns the methods that flagged any time step within a merged event period, in the configured order
+func flaggingMethods(data []collector.TimeStepData, period eventPeriod, methods []string, methodsLevels [][]int) []string {
+	res := []string{}
+	for i, method := range methods {
+		for ind, stepData := range data {
+			if !stepData.DateStart.Before(period.outlierPeriodStart) && stepData.DateStart.Before(period.outlierPeriodEnd) && methodsLevels[i][ind] != levelNormal {
+				res = append(res, method)
+				break
+			}
+		}
+	}
+	return res
+}
@@ -0,0 +1,97 @@
+package analyser
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ftfmtavares/anomalies-detector/collector"
+	"github.com/ftfmtavares/anomalies-detector/config"
+)
+
+func TestMergeMethodsLevels(t *testing.T) {
+	methodsLevels := [][]int{
+		{levelNormal, levelAlarm, levelAlarm, levelWarning, levelNormal},
+		{levelNormal, levelAlarm, levelWarning, levelNormal, levelNormal},
+		{levelWarning, levelAlarm, levelNormal, levelNormal, levelNormal},
+	}
+
+	tests := []struct {
+		name      string
+		consensus int
+		want      []int
+	}{
+		{name: "Any method", consensus: 0, want: []int{levelWarning, levelAlarm, levelAlarm, levelWarning, levelNormal}},
+		{name: "2 methods must agree", consensus: 2, want: []int{levelWarning, levelAlarm, levelWarning, levelWarning, levelNormal}},
+		{name: "All methods must agree", consensus: 3, want: []int{levelWarning, levelAlarm, levelWarning, levelWarning, levelNormal}},
+		{name: "Consensus above the number of methods", consensus: 4, want: []int{levelWarning, levelWarning, levelWarning, levelWarning, levelNormal}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := mergeMethodsLevels(methodsLevels, tt.consensus); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("mergeMethodsLevels() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDetectSiteOutliersMethods(t *testing.T) {
+	timeRef := time.Now()
+
+	values := []float64{221, 254, 270, 264, 244, 241, 238, 243, 277, 237, 254, 289, 278, 264, 265, 243, 284, 244, 212, 242, 271, 243, 252, 230, 238, 214, 234, 1027, 1057, 911}
+	data := make([]collector.TimeStepData, len(values))
+	for i, val := range values {
+		data[i] = collector.TimeStepData{DateStart: timeRef.AddDate(0, 0, -len(values)+i), Value: val, Samples: 100}
+	}
+	siteData := collector.SiteData{
+		SiteId:  "site",
+		DateEnd: timeRef,
+		Metrics: []collector.MetricData{{Metric: "Revenue", Attributes: []string{"Total"}, AttributeData: map[string][]collector.TimeStepData{"Total": data}}},
+	}
+	//3-sigmas alarms on samples #28-#29 and warns on #30 while esd finds none with a single removal as the other extreme samples mask it
+	tests := []struct {
+		name         string
+		maxOutliers  int
+		consensus    int
+		wantWarnings []OutlierEvent
+		wantAlarms   []OutlierEvent
+	}{
+		{
+			name:         "Any method",
+			maxOutliers:  2,
+			consensus:    0,
+			wantWarnings: []OutlierEvent{{OutlierPeriodStart: timeRef.AddDate(0, 0, -1), OutlierPeriodEnd: timeRef, Metric: "Revenue", Attribute: "Total", Direction: "spike", Methods: []string{"3-sigmas"}}},
+			wantAlarms:   []OutlierEvent{{OutlierPeriodStart: timeRef.AddDate(0, 0, -3), OutlierPeriodEnd: timeRef.AddDate(0, 0, -1), Metric: "Revenue", Attribute: "Total", Direction: "spike", Methods: []string{"3-sigmas", "esd"}}},
+		},
+		{
+			name:         "Alarms lacking consensus are downgraded",
+			maxOutliers:  1,
+			consensus:    2,
+			wantWarnings: []OutlierEvent{{OutlierPeriodStart: timeRef.AddDate(0, 0, -3), OutlierPeriodEnd: timeRef, Metric: "Revenue", Attribute: "Total", Direction: "spike", Methods: []string{"3-sigmas"}}},
+			wantAlarms:   []OutlierEvent{},
+		},
+	}
+	for _, tt := range tests {
+		methodParams := config.DetectionMethodsParams{
+			ThreeSigmas: config.ThreeSigmasParams{OutliersMultiplier: 2, StrongOutliersMultiplier: 3},
+			Esd:         config.EsdParams{MaxOutliers: tt.maxOutliers, Alpha: 0.01},
+		}
+
+		t.Run(tt.name, func(t *testing.T) {
+			got := detectSiteOutliers(siteData, []string{"3-sigmas", "esd"}, tt.consensus, methodParams, nil)
+
+			//Scores are covered by TestScoreEvent, only the merged periods and methods being compared here
+			for _, events := range [][]OutlierEvent{got.Warnings, got.Alarms} {
+				for i := range events {
+					events[i].Score, events[i].Observed, events[i].Expected, events[i].DeviationPercent = 0, 0, 0, 0
+				}
+			}
+			if !reflect.DeepEqual(got.Warnings, tt.wantWarnings) {
+				t.Errorf("detectSiteOutliers().Warnings = %v, want %v", got.Warnings, tt.wantWarnings)
+			}
+			if !reflect.DeepEqual(got.Alarms, tt.wantAlarms) {
+				t.Errorf("detectSiteOutliers().Alarms = %v, want %v", got.Alarms, tt.wantAlarms)
+			}
+		})
+	}
+}
@@ -0,0 +1,79 @@
+package analyser
+
+import (
+	"log"
+	"math"
+	"time"
+
+	"github.com/ftfmtavares/anomalies-detector/collector"
+	"github.com/ftfmtavares/anomalies-detector/config"
+	"github.com/ftfmtavares/anomalies-detector/utils"
+)
+
+//detectOutliersPeriodOverPeriod implements the period-over-period method
+//Each metric value is compared with the mean of the values of the same time step one or more reference periods ago (e.g. last week and last year),
+//and flagged when its deviation from it, in percentage, exceeds the warning or alarm thresholds
+//Offsets of whole days move by calendar days, so the same time of day is compared across daylight saving changes
+//Time steps without any reference value on the data, or with a reference of 0, are never classified as outliers
+func detectOutliersPeriodOverPeriod(data []collector.TimeStepData, PeriodEnd time.Time, params config.PeriodOverPeriodParams) ([]eventPeriod, []eventPeriod) {
+	levels := make([]int, len(data))
+	references, found := periodOverPeriodReferences(data, params)
+	if !found {
+		return eventPeriodsFromLevels(data, levels, PeriodEnd)
+	}
+
+	for ind, stepData := range data {
+		reference := references[ind]
+		if reference == 0 {
+			continue
+		}
+
+		deviation := math.Abs(stepData.Value-reference) / math.Abs(reference) * 100
+		if params.AlarmPercent > 0 && deviation > params.AlarmPercent {
+			levels[ind] = levelAlarm
+		} else if params.WarningPercent > 0 && deviation > params.WarningPercent {
+			levels[ind] = levelWarning
+		}
+	}
+
+	return eventPeriodsFromLevels(data, levels, PeriodEnd)
+}
+
+//periodOverPeriodReferences returns the reference value of each time step, the mean of the values found at its offsets, 0 if none is found
+//It returns false, logging why, if any offset is invalid
+func periodOverPeriodReferences(data []collector.TimeStepData, params config.PeriodOverPeriodParams) ([]float64, bool) {
+	offsetValues := params.Offsets
+	if len(offsetValues) == 0 {
+		offsetValues = []string{"1w"}
+	}
+	offsets := []utils.TimeStep{}
+	for _, value := range offsetValues {
+		offset, err := utils.ParseTimeStep(value)
+		if err != nil || offset.Duration <= 0 {
+			log.Printf("Invalid period-over-period offset \"%s\"\n", value)
+			return nil, false
+		}
+		offset.Calendar = true
+		offsets = append(offsets, offset)
+	}
+
+	values := map[int64]float64{}
+	for _, stepData := range data {
+		values[stepData.DateStart.UnixNano()] = stepData.Value
+	}
+
+	references := make([]float64, len(data))
+	for ind, stepData := range data {
+		reference, found := 0.0, 0
+		for _, offset := range offsets {
+			if value, present := values[offset.Next(stepData.DateStart, -1).UnixNano()]; present {
+				reference += value
+				found++
+			}
+		}
+		if found > 0 {
+			references[ind] = reference / float64(found)
+		}
+	}
+	return references, true
+}
@@ -0,0 +1,66 @@
+package analyser
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ftfmtavares/anomalies-detector/collector"
+	"github.com/ftfmtavares/anomalies-detector/config"
+)
+
+func TestDetectOutliersPeriodOverPeriod(t *testing.T) {
+	//Three weeks of daily values repeating a weekly pattern, with a 20% rise on day #16 and a halving on day #19
+	timeRef := time.Date(2022, 8, 1, 0, 0, 0, 0, time.UTC)
+	pattern := []float64{100, 110, 120, 130, 140, 60, 40}
+	data := make([]collector.TimeStepData, 21)
+	for day := range data {
+		data[day] = collector.TimeStepData{DateStart: timeRef.AddDate(0, 0, day), Value: pattern[day%7], Samples: 100}
+	}
+	data[15].Value *= 1.2
+	data[18].Value /= 2
+	periodEnd := timeRef.AddDate(0, 0, 21)
+
+	tests := []struct {
+		name           string
+		offsets        []string
+		wantedWarnings []eventPeriod
+		wantedAlarms   []eventPeriod
+	}{
+		{
+			name:           "Same day last week",
+			wantedWarnings: []eventPeriod{{outlierPeriodStart: timeRef.AddDate(0, 0, 15), outlierPeriodEnd: timeRef.AddDate(0, 0, 16)}},
+			wantedAlarms:   []eventPeriod{{outlierPeriodStart: timeRef.AddDate(0, 0, 18), outlierPeriodEnd: timeRef.AddDate(0, 0, 19)}},
+		},
+		{
+			name:           "Mean of the last two weeks",
+			offsets:        []string{"1w", "2w"},
+			wantedWarnings: []eventPeriod{{outlierPeriodStart: timeRef.AddDate(0, 0, 15), outlierPeriodEnd: timeRef.AddDate(0, 0, 16)}},
+			wantedAlarms:   []eventPeriod{{outlierPeriodStart: timeRef.AddDate(0, 0, 18), outlierPeriodEnd: timeRef.AddDate(0, 0, 19)}},
+		},
+		{
+			name:           "No reference on the data",
+			offsets:        []string{"52w"},
+			wantedWarnings: []eventPeriod{},
+			wantedAlarms:   []eventPeriod{},
+		},
+		{
+			name:           "Invalid offset",
+			offsets:        []string{"last week"},
+			wantedWarnings: []eventPeriod{},
+			wantedAlarms:   []eventPeriod{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			params := config.PeriodOverPeriodParams{Offsets: tt.offsets, WarningPercent: 10, AlarmPercent: 30}
+			warnings, alarms := detectOutliersPeriodOverPeriod(data, periodEnd, params)
+			if !reflect.DeepEqual(warnings, tt.wantedWarnings) {
+				t.Errorf("detectOutliersPeriodOverPeriod() warnings = %v, want %v", warnings, tt.wantedWarnings)
+			}
+			if !reflect.DeepEqual(alarms, tt.wantedAlarms) {
+				t.Errorf("detectOutliersPeriodOverPeriod() alarms = %v, want %v", alarms, tt.wantedAlarms)
+			}
+		})
+	}
+}
@@ -0,0 +1,268 @@
+package analyser
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ftfmtavares/anomalies-detector/config"
+	"github.com/ftfmtavares/anomalies-detector/utils"
+)
+
+//PostProcessor is implemented by every post-processing step of the detected events, returning the report with its processed events
+//Implementations must not change the events of the given report, which may still be read by the caller
+type PostProcessor interface {
+	Process(report OutlierReport) OutlierReport
+}
+
+//PostProcessorFunc adapts a function into a PostProcessor
+type PostProcessorFunc func(report OutlierReport) OutlierReport
+
+//Process calls the function
+func (process PostProcessorFunc) Process(report OutlierReport) OutlierReport {
+	return process(report)
+}
+
+//PostProcessorFactory creates a post-processor from its configured step, returning an error if the step parameters are invalid
+type PostProcessorFactory func(step config.PostProcessor) (PostProcessor, error)
+
+//postProcessors holds the registered post-processor factories by type
+var (
+	postProcessorsMu sync.RWMutex
+	postProcessors   = map[string]PostProcessorFactory{}
+)
+
+//init registers the built-in post-processors
+func init() {
+	RegisterPostProcessor("merge", newMergeProcessor)
+	RegisterPostProcessor("rank", newRankProcessor)
+	RegisterPostProcessor("rollup", newRollupProcessor)
+	RegisterPostProcessor("suppress", newSuppressProcessor)
+	RegisterPostProcessor("explain", newExplainProcessor)
+	RegisterPostProcessor("group", newGroupProcessor)
+}
+
+//RegisterPostProcessor makes a post-processor type available to the configured datasets, replacing any registered with the same type
+//It is meant to be called by Go callers before creating their Detector
+func RegisterPostProcessor(postProcessorType string, factory PostProcessorFactory) {
+	postProcessorsMu.Lock()
+	defer postProcessorsMu.Unlock()
+	postProcessors[postProcessorType] = factory
+}
+
+//CompilePostProcessors creates the configured post-processing steps
+//It returns an error if an unknown type is given or if any step parameters are invalid
+func CompilePostProcessors(steps []config.PostProcessor) ([]PostProcessor, error) {
+	postProcessorsMu.RLock()
+	defer postProcessorsMu.RUnlock()
+
+	compiled := []PostProcessor{}
+	for i, step := range steps {
+		factory, found := postProcessors[step.Type]
+		if !found {
+			return nil, fmt.Errorf("post-processor #%d - unknown type \"%s\"", i+1, step.Type)
+		}
+		postProcessor, err := factory(step)
+		if err != nil {
+			return nil, fmt.Errorf("post-processor #%d - %s", i+1, err.Error())
+		}
+		compiled = append(compiled, postProcessor)
+	}
+	return compiled, nil
+}
+
+//ApplyPostProcessors runs the post-processing steps in order over the events of a report
+func ApplyPostProcessors(report OutlierReport, postProcessors []PostProcessor) OutlierReport {
+	for _, postProcessor := range postProcessors {
+		report = postProcessor.Process(report)
+	}
+	return report
+}
+
+//processEvents returns a report whose alarms and warnings are replaced by the given function output, applied to copies of each list
+func processEvents(report OutlierReport, process func(events []OutlierEvent) []OutlierEvent) OutlierReport {
+	report.Result = OutlierResults{
+		Warnings: process(append([]OutlierEvent{}, report.Result.Warnings...)),
+		Alarms:   process(append([]OutlierEvent{}, report.Result.Alarms...)),
+	}
+	return report
+}
+
+//newMergeProcessor creates the "merge" post-processor, joining the events of the same metric and attribute less than the step gap apart
+//The joined event spans all of them and keeps the measures of the highest scoring one, along with all their methods and routes
+func newMergeProcessor(step config.PostProcessor) (PostProcessor, error) {
+	gap := time.Duration(0)
+	if step.Gap != "" {
+		var err error
+		if gap, err = utils.StrToDuration(step.Gap); err != nil || gap < 0 {
+			return nil, fmt.Errorf("merge - invalid gap \"%s\"", step.Gap)
+		}
+	}
+	return PostProcessorFunc(func(report OutlierReport) OutlierReport {
+		return processEvents(report, func(events []OutlierEvent) []OutlierEvent {
+			sort.SliceStable(events, func(i, j int) bool {
+				if events[i].Metric != events[j].Metric {
+					return events[i].Metric < events[j].Metric
+				}
+				if events[i].Attribute != events[j].Attribute {
+					return events[i].Attribute < events[j].Attribute
+				}
+				return events[i].OutlierPeriodStart.Before(events[j].OutlierPeriodStart)
+			})
+			merged := []OutlierEvent{}
+			for _, event := range events {
+				last := len(merged) - 1
+				if last < 0 || merged[last].Metric != event.Metric || merged[last].Attribute != event.Attribute || event.OutlierPeriodStart.Sub(merged[last].OutlierPeriodEnd) > gap {
+					merged = append(merged, event)
+					continue
+				}
+				joined := merged[last]
+				if event.Score > joined.Score {
+					joined.Score, joined.Observed, joined.Expected, joined.DeviationPercent, joined.Direction = event.Score, event.Observed, event.Expected, event.DeviationPercent, event.Direction
+				}
+				if event.OutlierPeriodEnd.After(joined.OutlierPeriodEnd) {
+					joined.OutlierPeriodEnd = event.OutlierPeriodEnd
+				}
+				joined.Methods = unionStrings(joined.Methods, event.Methods)
+				joined.Routes = unionStrings(joined.Routes, event.Routes)
+				merged[last] = joined
+			}
+			return merged
+		})
+	}), nil
+}
+
+//newRankProcessor creates the "rank" post-processor, ordering the events by decreasing score and keeping the step maximum of each severity if set
+func newRankProcessor(step config.PostProcessor) (PostProcessor, error) {
+	if step.MaxEvents < 0 {
+		return nil, fmt.Errorf("rank - invalid maxEvents %d", step.MaxEvents)
+	}
+	return PostProcessorFunc(func(report OutlierReport) OutlierReport {
+		return processEvents(report, func(events []OutlierEvent) []OutlierEvent {
+			sort.SliceStable(events, func(i, j int) bool {
+				return events[i].Score > events[j].Score
+			})
+			if step.MaxEvents > 0 && len(events) > step.MaxEvents {
+				events = events[:step.MaxEvents]
+			}
+			return events
+		})
+	}), nil
+}
+
+//newRollupProcessor creates the "rollup" post-processor, dropping the events explained by an overlapping event of the same metric and severity on a parent attribute path
+//"Total" is the parent of every other path, so a site wide event is reported once instead of once per attribute
+func newRollupProcessor(step config.PostProcessor) (PostProcessor, error) {
+	return PostProcessorFunc(func(report OutlierReport) OutlierReport {
+		return processEvents(report, func(events []OutlierEvent) []OutlierEvent {
+			kept := []OutlierEvent{}
+			for _, event := range events {
+				rolledUp := false
+				for _, parent := range events {
+					if parent.Metric == event.Metric && isParentAttribute(parent.Attribute, event.Attribute) &&
+						parent.OutlierPeriodEnd.After(event.OutlierPeriodStart) && event.OutlierPeriodEnd.After(parent.OutlierPeriodStart) {
+						rolledUp = true
+						break
+					}
+				}
+				if !rolledUp {
+					kept = append(kept, event)
+				}
+			}
+			return kept
+		})
+	}), nil
+}
+
+//newSuppressProcessor creates the "suppress" post-processor, dropping the events of the step metric and attribute paths, or scoring below its minimum score
+func newSuppressProcessor(step config.PostProcessor) (PostProcessor, error) {
+	if step.Metric == "" && len(step.Attributes) == 0 && step.MinScore <= 0 {
+		return nil, fmt.Errorf("suppress requires metric, attributes or minScore")
+	}
+	selects := step.Metric != "" || len(step.Attributes) > 0
+	return PostProcessorFunc(func(report OutlierReport) OutlierReport {
+		return processEvents(report, func(events []OutlierEvent) []OutlierEvent {
+			kept := []OutlierEvent{}
+			for _, event := range events {
+				selected := selects && (step.Metric == "" || event.Metric == step.Metric)
+				if selected && len(step.Attributes) > 0 {
+					selected = false
+					for _, attribute := range step.Attributes {
+						if event.Attribute == attribute || strings.HasPrefix(event.Attribute, attribute+">") {
+							selected = true
+							break
+						}
+					}
+				}
+				if !selected && event.Score >= step.MinScore {
+					kept = append(kept, event)
+				}
+			}
+			return kept
+		})
+	}), nil
+}
+
+//newExplainProcessor creates the "explain" post-processor, describing every event in plain language from its direction and measures
+func newExplainProcessor(step config.PostProcessor) (PostProcessor, error) {
+	return PostProcessorFunc(func(report OutlierReport) OutlierReport {
+		return processEvents(report, func(events []OutlierEvent) []OutlierEvent {
+			for i := range events {
+				events[i].Explanation = explainEvent(events[i])
+			}
+			return events
+		})
+	}), nil
+}
+
+//explainEvent describes an event, e.g. "Revenue of Browser>Edge spiked to 690.00, 245.0% above the expected 200.00 (score 4.2)"
+//The main probable cause of an alarm of "Total" is added, e.g. ", mostly from Browser>Edge (82.5% of the deviation)"
+func explainEvent(event OutlierEvent) string {
+	moved, side := "spiked", "above"
+	if event.Direction == config.DirectionDrop {
+		moved, side = "dropped", "below"
+	}
+	explanation := fmt.Sprintf("%s of %s %s to %.2f", event.Metric, event.Attribute, moved, event.Observed)
+	if event.Expected != 0 {
+		explanation += fmt.Sprintf(", %.1f%% %s the expected %.2f", math.Abs(event.DeviationPercent), side, event.Expected)
+	} else {
+		explanation += fmt.Sprintf(", %s the expected %.2f", side, event.Expected)
+	}
+	explanation += fmt.Sprintf(" (score %.1f)", event.Score)
+	if len(event.ProbableCauses) > 0 {
+		explanation += fmt.Sprintf(", mostly from %s (%.1f%% of the deviation)", event.ProbableCauses[0].Attribute, event.ProbableCauses[0].Share)
+	}
+	return explanation
+}
+
+//isParentAttribute checks if an attribute path is a parent of another, "Total" being the parent of every other path
+func isParentAttribute(parent, attribute string) bool {
+	if parent == "Total" {
+		return attribute != "Total"
+	}
+	return strings.HasPrefix(attribute, parent+">")
+}
+
+//unionStrings returns the values of both lists without duplicates, in order of appearance
+func unionStrings(a, b []string) []string {
+	if len(b) == 0 {
+		return a
+	}
+	res := append([]string{}, a...)
+	for _, value := range b {
+		found := false
+		for _, existing := range res {
+			if existing == value {
+				found = true
+				break
+			}
+		}
+		if !found {
+			res = append(res, value)
+		}
+	}
+	return res
+}
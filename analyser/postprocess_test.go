@@ -0,0 +1,140 @@
+package analyser
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ftfmtavares/anomalies-detector/config"
+)
+
+func TestApplyPostProcessors(t *testing.T) {
+	timeRef := time.Date(2022, 9, 1, 0, 0, 0, 0, time.UTC)
+	event := func(metric, attribute string, start, end int, score float64) OutlierEvent {
+		return OutlierEvent{OutlierPeriodStart: timeRef.Add(time.Duration(start) * time.Hour), OutlierPeriodEnd: timeRef.Add(time.Duration(end) * time.Hour), Metric: metric, Attribute: attribute, Score: score}
+	}
+	report := OutlierReport{SiteId: "shop", TimeStep: "1h", Result: OutlierResults{
+		Alarms: []OutlierEvent{
+			event("Revenue", "Total", 10, 11, 4),
+			event("Revenue", "Browser>Edge", 10, 11, 6),
+			event("Revenue", "Browser>Edge", 12, 13, 5),
+			event("Visits", "Country>PT", 3, 4, 3),
+		},
+		Warnings: []OutlierEvent{
+			event("Revenue", "Browser>Chrome", 20, 21, 2),
+		},
+	}}
+
+	tests := []struct {
+		name  string
+		steps []config.PostProcessor
+		want  OutlierResults
+	}{
+		{
+			name:  "Events within the gap merged keeping the highest score",
+			steps: []config.PostProcessor{{Type: "merge", Gap: "1h"}},
+			want: OutlierResults{
+				Alarms:   []OutlierEvent{event("Revenue", "Browser>Edge", 10, 13, 6), event("Revenue", "Total", 10, 11, 4), event("Visits", "Country>PT", 3, 4, 3)},
+				Warnings: []OutlierEvent{event("Revenue", "Browser>Chrome", 20, 21, 2)},
+			},
+		},
+		{
+			name:  "Events ranked by score and capped per severity",
+			steps: []config.PostProcessor{{Type: "rank", MaxEvents: 2}},
+			want: OutlierResults{
+				Alarms:   []OutlierEvent{event("Revenue", "Browser>Edge", 10, 11, 6), event("Revenue", "Browser>Edge", 12, 13, 5)},
+				Warnings: []OutlierEvent{event("Revenue", "Browser>Chrome", 20, 21, 2)},
+			},
+		},
+		{
+			name:  "Events overlapping a parent path event rolled up",
+			steps: []config.PostProcessor{{Type: "rollup"}},
+			want: OutlierResults{
+				Alarms:   []OutlierEvent{event("Revenue", "Total", 10, 11, 4), event("Revenue", "Browser>Edge", 12, 13, 5), event("Visits", "Country>PT", 3, 4, 3)},
+				Warnings: []OutlierEvent{event("Revenue", "Browser>Chrome", 20, 21, 2)},
+			},
+		},
+		{
+			name:  "Events of a path or below the minimum score suppressed",
+			steps: []config.PostProcessor{{Type: "suppress", Attributes: []string{"Browser"}}, {Type: "suppress", MinScore: 3.5}},
+			want: OutlierResults{
+				Alarms:   []OutlierEvent{event("Revenue", "Total", 10, 11, 4)},
+				Warnings: []OutlierEvent{},
+			},
+		},
+		{
+			name:  "Steps run in order",
+			steps: []config.PostProcessor{{Type: "merge", Gap: "1h"}, {Type: "rollup"}, {Type: "rank"}},
+			want: OutlierResults{
+				Alarms:   []OutlierEvent{event("Revenue", "Total", 10, 11, 4), event("Visits", "Country>PT", 3, 4, 3)},
+				Warnings: []OutlierEvent{event("Revenue", "Browser>Chrome", 20, 21, 2)},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			postProcessors, err := CompilePostProcessors(tt.steps)
+			if err != nil {
+				t.Fatalf("CompilePostProcessors() error = %v", err)
+			}
+			got := ApplyPostProcessors(report, postProcessors)
+			if !reflect.DeepEqual(got.Result, tt.want) {
+				t.Errorf("ApplyPostProcessors() = %v, want %v", got.Result, tt.want)
+			}
+			if len(report.Result.Alarms) != 4 || report.Result.Alarms[0].Attribute != "Total" {
+				t.Errorf("ApplyPostProcessors() changed the given report")
+			}
+		})
+	}
+}
+
+func TestExplainPostProcessor(t *testing.T) {
+	postProcessors, err := CompilePostProcessors([]config.PostProcessor{{Type: "explain"}})
+	if err != nil {
+		t.Fatalf("CompilePostProcessors() error = %v", err)
+	}
+	report := OutlierReport{Result: OutlierResults{
+		Alarms: []OutlierEvent{
+			{Metric: "Revenue", Attribute: "Browser>Edge", Score: 4.23, Observed: 690, Expected: 200, DeviationPercent: 245, Direction: config.DirectionSpike},
+			{Metric: "Revenue", Attribute: "Total", Score: 3.5, Observed: 1200, Expected: 800, DeviationPercent: 50, Direction: config.DirectionSpike, ProbableCauses: []ProbableCause{{Attribute: "Browser>Edge", Contribution: 330, Share: 82.5}}},
+		},
+		Warnings: []OutlierEvent{{Metric: "Visits", Attribute: "Total", Score: 2.5, Observed: 300, Expected: 500, DeviationPercent: -40, Direction: config.DirectionDrop}},
+	}}
+	got := ApplyPostProcessors(report, postProcessors)
+	if want := "Revenue of Browser>Edge spiked to 690.00, 245.0% above the expected 200.00 (score 4.2)"; got.Result.Alarms[0].Explanation != want {
+		t.Errorf("explain = %q, want %q", got.Result.Alarms[0].Explanation, want)
+	}
+	if want := "Revenue of Total spiked to 1200.00, 50.0% above the expected 800.00 (score 3.5), mostly from Browser>Edge (82.5% of the deviation)"; got.Result.Alarms[1].Explanation != want {
+		t.Errorf("explain = %q, want %q", got.Result.Alarms[1].Explanation, want)
+	}
+	if want := "Visits of Total dropped to 300.00, 40.0% below the expected 500.00 (score 2.5)"; got.Result.Warnings[0].Explanation != want {
+		t.Errorf("explain = %q, want %q", got.Result.Warnings[0].Explanation, want)
+	}
+}
+
+func TestRegisterPostProcessor(t *testing.T) {
+	RegisterPostProcessor("tag", func(step config.PostProcessor) (PostProcessor, error) {
+		return PostProcessorFunc(func(report OutlierReport) OutlierReport {
+			return processEvents(report, func(events []OutlierEvent) []OutlierEvent {
+				for i := range events {
+					events[i].Routes = append(events[i].Routes, step.Params["route"])
+				}
+				return events
+			})
+		}), nil
+	})
+	postProcessors, err := CompilePostProcessors([]config.PostProcessor{{Type: "tag", Params: map[string]string{"route": "oncall"}}})
+	if err != nil {
+		t.Fatalf("CompilePostProcessors() error = %v", err)
+	}
+	got := ApplyPostProcessors(OutlierReport{Result: OutlierResults{Alarms: []OutlierEvent{{Metric: "Revenue"}}}}, postProcessors)
+	if !reflect.DeepEqual(got.Result.Alarms[0].Routes, []string{"oncall"}) {
+		t.Errorf("ApplyPostProcessors() routes = %v, want [oncall]", got.Result.Alarms[0].Routes)
+	}
+
+	for _, steps := range [][]config.PostProcessor{{{Type: "unknown"}}, {{Type: "merge", Gap: "soon"}}, {{Type: "suppress"}}} {
+		if _, err := CompilePostProcessors(steps); err == nil {
+			t.Errorf("CompilePostProcessors(%v) expected an error", steps)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package analyser
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/ftfmtavares/anomalies-detector/config"
+	"github.com/ftfmtavares/anomalies-detector/utils"
+)
+
+//ResolveDetectionProfile applies the detection profile of a dataset, returning the resulting dataset and detection methods parameters along with its cooldown period
+//It returns an error if the profile is unknown, the debounce is negative or the cooldown period is invalid
+func ResolveDetectionProfile(dataConf config.Dataset, methodParams config.DetectionMethodsParams) (config.Dataset, config.DetectionMethodsParams, time.Duration, error) {
+	dataConf, methodParams, err := dataConf.ApplyDetectionProfile(methodParams)
+	if err != nil {
+		return dataConf, methodParams, 0, err
+	}
+	if dataConf.Debounce < 0 {
+		return dataConf, methodParams, 0, fmt.Errorf("invalid debounce %d, it must not be negative", dataConf.Debounce)
+	}
+
+	var cooldown time.Duration
+	if dataConf.Cooldown != "" {
+		if cooldown, err = utils.StrToDuration(dataConf.Cooldown); err != nil || cooldown < 0 {
+			return dataConf, methodParams, 0, fmt.Errorf("invalid cooldown \"%s\"", dataConf.Cooldown)
+		}
+	}
+	return dataConf, methodParams, cooldown, nil
+}
+
+//debounceEvents removes the events lasting less than the given number of time steps
+func debounceEvents(results OutlierResults, debounce int, timeStep time.Duration) OutlierResults {
+	if debounce <= 1 {
+		return results
+	}
+	keep := func(events []OutlierEvent) []OutlierEvent {
+		res := []OutlierEvent{}
+		for _, event := range events {
+			if event.OutlierPeriodEnd.Sub(event.OutlierPeriodStart) >= time.Duration(debounce)*timeStep {
+				res = append(res, event)
+			}
+		}
+		return res
+	}
+	return OutlierResults{Warnings: keep(results.Warnings), Alarms: keep(results.Alarms)}
+}
+
+//cooldownEvents removes the events starting within the cooldown period after the end of the previous kept event of the same severity, metric and attribute
+func cooldownEvents(results OutlierResults, cooldown time.Duration) OutlierResults {
+	if cooldown <= 0 {
+		return results
+	}
+	keep := func(events []OutlierEvent) []OutlierEvent {
+		res := []OutlierEvent{}
+		lastEnd := map[string]time.Time{}
+		for _, event := range events {
+			key := event.Metric + "|" + event.Attribute
+			if end, found := lastEnd[key]; found && event.OutlierPeriodStart.Before(end.Add(cooldown)) {
+				continue
+			}
+			lastEnd[key] = event.OutlierPeriodEnd
+			res = append(res, event)
+		}
+		return res
+	}
+	return OutlierResults{Warnings: keep(results.Warnings), Alarms: keep(results.Alarms)}
+}
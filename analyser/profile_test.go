@@ -0,0 +1,118 @@
+package analyser
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ftfmtavares/anomalies-detector/config"
+)
+
+func TestResolveDetectionProfile(t *testing.T) {
+	methodParams := config.DetectionMethodsParams{ThreeSigmas: config.ThreeSigmasParams{OutliersMultiplier: 2, StrongOutliersMultiplier: 3}}
+
+	tests := []struct {
+		name           string
+		dataConf       config.Dataset
+		wantMethods    []string
+		wantMultiplier float64
+		wantDebounce   int
+		wantCooldown   time.Duration
+		wantErr        bool
+	}{
+		{
+			name:           "No profile",
+			dataConf:       config.Dataset{OutliersDetectionMethod: "esd", Debounce: 3},
+			wantMethods:    []string{"esd"},
+			wantMultiplier: 2,
+			wantDebounce:   3,
+		},
+		{
+			name:           "Quiet profile",
+			dataConf:       config.Dataset{DetectionProfile: "quiet"},
+			wantMethods:    []string{"3-sigmas"},
+			wantMultiplier: 3,
+			wantDebounce:   2,
+			wantCooldown:   7 * 24 * time.Hour,
+		},
+		{
+			name:           "Site settings overriding the profile",
+			dataConf:       config.Dataset{DetectionProfile: "balanced", OutliersDetectionMethods: []string{"3-sigmas", "esd"}, Debounce: 3, Cooldown: "12h"},
+			wantMethods:    []string{"3-sigmas", "esd"},
+			wantMultiplier: 2,
+			wantDebounce:   3,
+			wantCooldown:   12 * time.Hour,
+		},
+		{
+			name:     "Unknown profile",
+			dataConf: config.Dataset{DetectionProfile: "loud"},
+			wantErr:  true,
+		},
+		{
+			name:     "Invalid cooldown",
+			dataConf: config.Dataset{OutliersDetectionMethod: "3-sigmas", Cooldown: "soon"},
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dataConf, params, cooldown, err := ResolveDetectionProfile(tt.dataConf, methodParams)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ResolveDetectionProfile() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if !reflect.DeepEqual(dataConf.Methods(), tt.wantMethods) {
+				t.Errorf("ResolveDetectionProfile() methods = %v, want %v", dataConf.Methods(), tt.wantMethods)
+			}
+			if params.ThreeSigmas.OutliersMultiplier != tt.wantMultiplier {
+				t.Errorf("ResolveDetectionProfile() multiplier = %v, want %v", params.ThreeSigmas.OutliersMultiplier, tt.wantMultiplier)
+			}
+			if dataConf.Debounce != tt.wantDebounce || cooldown != tt.wantCooldown {
+				t.Errorf("ResolveDetectionProfile() debounce = %d cooldown = %v, want %d %v", dataConf.Debounce, cooldown, tt.wantDebounce, tt.wantCooldown)
+			}
+		})
+	}
+}
+
+func TestDebounceAndCooldownEvents(t *testing.T) {
+	timeRef := time.Date(2022, 9, 1, 0, 0, 0, 0, time.UTC)
+	event := func(metric string, startDay, endDay int) OutlierEvent {
+		return OutlierEvent{Metric: metric, Attribute: "Total", OutlierPeriodStart: timeRef.AddDate(0, 0, startDay), OutlierPeriodEnd: timeRef.AddDate(0, 0, endDay)}
+	}
+	results := OutlierResults{
+		Warnings: []OutlierEvent{event("Revenue", 1, 2), event("Revenue", 3, 6), event("Revenue", 7, 9), event("Visits", 7, 8)},
+		Alarms:   []OutlierEvent{event("Revenue", 4, 6)},
+	}
+
+	tests := []struct {
+		name     string
+		debounce int
+		cooldown time.Duration
+		want     OutlierResults
+	}{
+		{
+			name: "Nothing dropped",
+			want: results,
+		},
+		{
+			name:     "Short events debounced",
+			debounce: 2,
+			want:     OutlierResults{Warnings: []OutlierEvent{event("Revenue", 3, 6), event("Revenue", 7, 9)}, Alarms: []OutlierEvent{event("Revenue", 4, 6)}},
+		},
+		{
+			name:     "Repeated events cooled down per metric and attribute",
+			cooldown: 2 * 24 * time.Hour,
+			want:     OutlierResults{Warnings: []OutlierEvent{event("Revenue", 1, 2), event("Revenue", 7, 9), event("Visits", 7, 8)}, Alarms: []OutlierEvent{event("Revenue", 4, 6)}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := cooldownEvents(debounceEvents(results, tt.debounce, 24*time.Hour), tt.cooldown)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("debounceEvents() and cooldownEvents() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,41 @@
+package analyser
+
+import (
+	"log"
+	"math"
+	"time"
+
+	"github.com/ftfmtavares/anomalies-detector/collector"
+)
+
+//detectOutliersRolling3Sigmas implements the rolling-3-sigmas method
+//Each metric value is compared against the mean and standard deviation of the preceding window of time steps only,
+//so trends and level shifts along the range don't hide or trigger events by themselves
+//The first window of time steps has no preceding statistics and is never classified as outlier, as neither are the time steps after a flat window
+func detectOutliersRolling3Sigmas(data []collector.TimeStepData, PeriodEnd time.Time, windowSize int, outliersMultiplier, strongOutliersMultiplier float64) ([]eventPeriod, []eventPeriod) {
+	levels := make([]int, len(data))
+	if windowSize < 2 {
+		log.Printf("Invalid rolling-3-sigmas window size %d, at least 2 time steps are required\n", windowSize)
+		return eventPeriodsFromLevels(data, levels, PeriodEnd)
+	}
+
+	for ind := windowSize; ind < len(data); ind++ {
+		//Calculating the Mean and Standard Deviation of the preceding window
+		mean, sd := meanAndDeviation(data[ind-windowSize : ind])
+
+		//Flat windows have no deviation to measure, any change after them being flagged otherwise
+		if sd == 0 {
+			continue
+		}
+
+		//Classifying the metric value according to the window Z-score limits
+		deviation := math.Abs(data[ind].Value - mean)
+		if deviation > strongOutliersMultiplier*sd {
+			levels[ind] = levelAlarm
+		} else if deviation > outliersMultiplier*sd {
+			levels[ind] = levelWarning
+		}
+	}
+
+	return eventPeriodsFromLevels(data, levels, PeriodEnd)
+}
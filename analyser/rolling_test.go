@@ -0,0 +1,82 @@
+package analyser
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ftfmtavares/anomalies-detector/collector"
+)
+
+func TestDetectOutliersRolling3Sigmas(t *testing.T) {
+	timeRef := time.Now()
+
+	//Level shift from ~100 to ~200 at sample #17, which the global 3-sigmas method doesn't detect
+	levelShift := []float64{}
+	for i := 0; i < 30; i++ {
+		value := float64(100 + 2*(i%2))
+		if i >= 16 {
+			value += 100
+		}
+		levelShift = append(levelShift, value)
+	}
+
+	tests := []struct {
+		name           string
+		windowSize     int
+		wantedWarnings []eventPeriod
+		wantedAlarms   []eventPeriod
+		values         []float64
+	}{
+		{
+			name:           "Level shift with Z-Score >3 at sample #17 and Z-score >2 at sample #18",
+			windowSize:     5,
+			wantedWarnings: []eventPeriod{{outlierPeriodStart: timeRef.AddDate(0, 0, -13), outlierPeriodEnd: timeRef.AddDate(0, 0, -12)}},
+			wantedAlarms:   []eventPeriod{{outlierPeriodStart: timeRef.AddDate(0, 0, -14), outlierPeriodEnd: timeRef.AddDate(0, 0, -13)}},
+			values:         levelShift,
+		},
+		{
+			name:           "Changes after a flat window not classified",
+			windowSize:     5,
+			wantedWarnings: []eventPeriod{},
+			wantedAlarms:   []eventPeriod{},
+			values:         []float64{100, 100, 100, 100, 100, 101, 100, 100, 100, 100, 100, 99},
+		},
+		{
+			name:           "Invalid window size",
+			windowSize:     1,
+			wantedWarnings: []eventPeriod{},
+			wantedAlarms:   []eventPeriod{},
+			values:         levelShift,
+		},
+	}
+
+	for _, tt := range tests {
+		data := make([]collector.TimeStepData, len(tt.values))
+		for i, val := range tt.values {
+			data[i].Samples = 100
+			data[i].DateStart = timeRef.AddDate(0, 0, -len(tt.values)+i)
+			data[i].Value = val
+		}
+
+		t.Run(tt.name, func(t *testing.T) {
+			warnings, alarms := detectOutliersRolling3Sigmas(data, timeRef, tt.windowSize, 2, 3)
+			if !reflect.DeepEqual(warnings, tt.wantedWarnings) {
+				t.Errorf("detectOutliersRolling3Sigmas() got = %v, want %v", warnings, tt.wantedWarnings)
+			}
+			if !reflect.DeepEqual(alarms, tt.wantedAlarms) {
+				t.Errorf("detectOutliersRolling3Sigmas() got1 = %v, want %v", alarms, tt.wantedAlarms)
+			}
+		})
+	}
+
+	//The global method misses the same level shift
+	data := make([]collector.TimeStepData, len(levelShift))
+	for i, val := range levelShift {
+		data[i].DateStart = timeRef.AddDate(0, 0, -len(levelShift)+i)
+		data[i].Value = val
+	}
+	if warnings, alarms := detectOutliers3Sigmas(data, timeRef, 2, 3); len(warnings) != 0 || len(alarms) != 0 {
+		t.Errorf("detectOutliers3Sigmas() got = %v, %v, want no events", warnings, alarms)
+	}
+}
@@ -0,0 +1,153 @@
+package analyser
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/ftfmtavares/anomalies-detector/config"
+	"github.com/ftfmtavares/anomalies-detector/rules"
+)
+
+//Const block defines the numeric severity values exposed to the alert rules
+const (
+	severityWarning = 1.0
+	severityAlarm   = 2.0
+)
+
+//ruleVariables lists all identifiers that alert rule conditions can refer to, deviationPct being a shorter alias of deviationPercent
+var ruleVariables = []string{
+	"siteId",
+	"method",
+	"metric",
+	"attribute",
+	"level",
+	"severity",
+	"durationHours",
+	"score",
+	"deviationPercent",
+	"deviationPct",
+	"direction",
+	"resolution",
+	"warning",
+	"alarm",
+}
+
+//AlertRule is a compiled config.AlertRule ready to be applied to reports
+type AlertRule struct {
+	name      string
+	condition *rules.Expression
+	action    string
+	route     string
+}
+
+//CompileAlertRules validates and compiles the configured alert rules
+//It returns an error if any condition is invalid or if an unknown action is given
+func CompileAlertRules(confRules []config.AlertRule) ([]AlertRule, error) {
+	compiled := []AlertRule{}
+	for i, confRule := range confRules {
+		name := confRule.Name
+		if name == "" {
+			name = fmt.Sprintf("#%d", i+1)
+		}
+
+		condition, err := rules.Compile(confRule.Condition, ruleVariables)
+		if err != nil {
+			return nil, fmt.Errorf("alert rule %s - %s", name, err.Error())
+		}
+
+		switch confRule.Action {
+		case "suppress", "upgrade", "downgrade":
+		case "route":
+			if confRule.Route == "" {
+				return nil, fmt.Errorf("alert rule %s - missing route", name)
+			}
+		default:
+			return nil, fmt.Errorf("alert rule %s - unknown action \"%s\"", name, confRule.Action)
+		}
+
+		compiled = append(compiled, AlertRule{name: name, condition: condition, action: confRule.Action, route: confRule.Route})
+	}
+
+	return compiled, nil
+}
+
+//ApplyAlertRules evaluates the alert rules in order against every warning and alarm of a report
+//Matching rules can suppress events, upgrade warnings to alarms, downgrade alarms to warnings or add notification routes
+//Rules evaluation stops on a suppressed event while severity changes are visible to the following rules
+func ApplyAlertRules(report OutlierReport, alertRules []AlertRule) OutlierReport {
+	if len(alertRules) == 0 {
+		return report
+	}
+
+	warnings := []OutlierEvent{}
+	alarms := []OutlierEvent{}
+
+	process := func(event OutlierEvent, severity float64) {
+	RulesLoop:
+		for _, rule := range alertRules {
+			matched, err := rule.condition.Eval(ruleEnv(report, event, severity))
+			if err != nil {
+				log.Printf("Alert rule %s failed - %s\n", rule.name, err.Error())
+				continue
+			}
+			if !matched {
+				continue
+			}
+
+			switch rule.action {
+			case "suppress":
+				log.Printf("Alert rule %s suppressed %s - %s - %s\n", rule.name, report.SiteId, event.Metric, event.Attribute)
+				return
+			case "upgrade":
+				severity = severityAlarm
+			case "downgrade":
+				severity = severityWarning
+			case "route":
+				for _, route := range event.Routes {
+					if route == rule.route {
+						continue RulesLoop
+					}
+				}
+				event.Routes = append(event.Routes, rule.route)
+			}
+		}
+
+		if severity == severityAlarm {
+			alarms = append(alarms, event)
+		} else {
+			warnings = append(warnings, event)
+		}
+	}
+
+	for _, warning := range report.Result.Warnings {
+		process(warning, severityWarning)
+	}
+	for _, alarm := range report.Result.Alarms {
+		process(alarm, severityAlarm)
+	}
+
+	report.Result.Warnings = warnings
+	report.Result.Alarms = alarms
+	return report
+}
+
+//ruleEnv builds the alert rules evaluation environment of a given event
+func ruleEnv(report OutlierReport, event OutlierEvent, severity float64) rules.Env {
+	return rules.Env{
+		"siteId":           report.SiteId,
+		"method":           report.OutliersDetectionMethod,
+		"metric":           event.Metric,
+		"attribute":        event.Attribute,
+		"level":            float64(strings.Count(event.Attribute, ">")),
+		"severity":         severity,
+		"durationHours":    event.OutlierPeriodEnd.Sub(event.OutlierPeriodStart).Hours(),
+		"score":            event.Score,
+		"deviationPercent": event.DeviationPercent,
+		"deviationPct":     event.DeviationPercent,
+		"direction":        event.Direction,
+		"resolution":       event.Resolution,
+		"warning":          severityWarning,
+		"alarm":            severityAlarm,
+	}
+}
@@ -0,0 +1,101 @@
+package analyser
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ftfmtavares/anomalies-detector/config"
+)
+
+func TestApplyAlertRules(t *testing.T) {
+	timeRef := time.Now()
+
+	revenueTotal := OutlierEvent{OutlierPeriodStart: timeRef.AddDate(0, 0, -1), OutlierPeriodEnd: timeRef, Metric: "Revenue", Attribute: "Total"}
+	revenueTablet := OutlierEvent{OutlierPeriodStart: timeRef.AddDate(0, 0, -1), OutlierPeriodEnd: timeRef, Metric: "Revenue", Attribute: "DeviceType>Tablet"}
+	basketTotal := OutlierEvent{OutlierPeriodStart: timeRef.AddDate(0, 0, -1), OutlierPeriodEnd: timeRef, Metric: "Basket", Attribute: "Total", DeviationPercent: -35}
+
+	report := OutlierReport{
+		SiteId: "site",
+		Result: OutlierResults{
+			Warnings: []OutlierEvent{revenueTotal, revenueTablet},
+			Alarms:   []OutlierEvent{basketTotal},
+		},
+		Shadow: &OutlierResults{
+			Warnings: []OutlierEvent{revenueTablet},
+			Alarms:   []OutlierEvent{revenueTotal, basketTotal},
+		},
+	}
+	shadow := OutlierResults{Warnings: []OutlierEvent{revenueTablet}, Alarms: []OutlierEvent{revenueTotal, basketTotal}}
+
+	tests := []struct {
+		name  string
+		rules []config.AlertRule
+		want  OutlierResults
+	}{
+		{
+			name:  "Suppress by attribute",
+			rules: []config.AlertRule{{Condition: `endsWith(attribute, "Tablet")`, Action: "suppress"}},
+			want:  OutlierResults{Warnings: []OutlierEvent{revenueTotal}, Alarms: []OutlierEvent{basketTotal}},
+		},
+		{
+			name: "Upgrade and route upgraded alarms",
+			rules: []config.AlertRule{
+				{Condition: `metric == "Revenue" && level == 0`, Action: "upgrade"},
+				{Condition: `severity >= alarm`, Action: "route", Route: "oncall"},
+			},
+			want: OutlierResults{
+				Warnings: []OutlierEvent{revenueTablet},
+				Alarms: []OutlierEvent{
+					{OutlierPeriodStart: revenueTotal.OutlierPeriodStart, OutlierPeriodEnd: revenueTotal.OutlierPeriodEnd, Metric: "Revenue", Attribute: "Total", Routes: []string{"oncall"}},
+					{OutlierPeriodStart: basketTotal.OutlierPeriodStart, OutlierPeriodEnd: basketTotal.OutlierPeriodEnd, Metric: "Basket", Attribute: "Total", DeviationPercent: -35, Routes: []string{"oncall"}},
+				},
+			},
+		},
+		{
+			name:  "Route by deviation alias",
+			rules: []config.AlertRule{{Condition: `metric == "Basket" && severity >= alarm && deviationPct < -20`, Action: "route", Route: "oncall"}},
+			want: OutlierResults{
+				Warnings: []OutlierEvent{revenueTotal, revenueTablet},
+				Alarms:   []OutlierEvent{{OutlierPeriodStart: basketTotal.OutlierPeriodStart, OutlierPeriodEnd: basketTotal.OutlierPeriodEnd, Metric: "Basket", Attribute: "Total", DeviationPercent: -35, Routes: []string{"oncall"}}},
+			},
+		},
+		{
+			name:  "Downgrade by metric",
+			rules: []config.AlertRule{{Condition: `metric == "Basket" && durationHours <= 24`, Action: "downgrade"}},
+			want:  OutlierResults{Warnings: []OutlierEvent{revenueTotal, revenueTablet, basketTotal}, Alarms: []OutlierEvent{}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			alertRules, err := CompileAlertRules(tt.rules)
+			if err != nil {
+				t.Fatalf("CompileAlertRules() error = %v", err)
+			}
+			got := ApplyAlertRules(report, alertRules)
+			if !reflect.DeepEqual(got.Result, tt.want) {
+				t.Errorf("ApplyAlertRules() = %v, want %v", got.Result, tt.want)
+			}
+			//Shadow events are never notified, so rules don't suppress, regrade nor route them
+			if !reflect.DeepEqual(*got.Shadow, shadow) {
+				t.Errorf("ApplyAlertRules() shadow = %v, want %v", *got.Shadow, shadow)
+			}
+		})
+	}
+}
+
+func Test_ruleEnv(t *testing.T) {
+	//Every variable a condition can refer to is set on the evaluation environment, and nothing else
+	env := ruleEnv(OutlierReport{}, OutlierEvent{DeviationPercent: -35}, severityAlarm)
+	if len(env) != len(ruleVariables) {
+		t.Errorf("ruleEnv() = %d variables, want %d", len(env), len(ruleVariables))
+	}
+	for _, variable := range ruleVariables {
+		if _, found := env[variable]; !found {
+			t.Errorf("ruleEnv() missing %s", variable)
+		}
+	}
+	if env["deviationPct"] != env["deviationPercent"] {
+		t.Errorf("ruleEnv() deviationPct = %v, want %v", env["deviationPct"], env["deviationPercent"])
+	}
+}
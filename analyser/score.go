@@ -0,0 +1,61 @@
+package analyser
+
+import (
+	"math"
+
+	"github.com/ftfmtavares/anomalies-detector/collector"
+	"github.com/ftfmtavares/anomalies-detector/config"
+)
+
+//scoreEvent measures how far an event period strays from the baseline, whatever the methods that flagged it
+//The baseline is the mean and standard deviation of the time steps not flagged by the merged levels (all of them if every time step was flagged)
+//The observed value is the one of the period straying the most from the baseline mean, which is the expected value,
+//the score being their distance in baseline standard deviations (0 for flat baselines) and the deviation their relative difference in percent
+func scoreEvent(data []collector.TimeStepData, levels []int, period eventPeriod) (score, observed, expected, deviation float64) {
+	var sum, squares float64
+	count := 0
+	for ind, stepData := range data {
+		if levels[ind] == levelNormal {
+			sum += stepData.Value
+			squares += stepData.Value * stepData.Value
+			count++
+		}
+	}
+	if count == 0 {
+		for _, stepData := range data {
+			sum += stepData.Value
+			squares += stepData.Value * stepData.Value
+		}
+		count = len(data)
+	}
+	if count == 0 {
+		return 0, 0, 0, 0
+	}
+	expected = sum / float64(count)
+	sd := math.Sqrt(math.Max(squares/float64(count)-expected*expected, 0))
+
+	found := false
+	for _, stepData := range data {
+		if !stepData.DateStart.Before(period.outlierPeriodStart) && stepData.DateStart.Before(period.outlierPeriodEnd) {
+			if !found || math.Abs(stepData.Value-expected) > math.Abs(observed-expected) {
+				observed = stepData.Value
+				found = true
+			}
+		}
+	}
+	if sd > 0 {
+		score = math.Abs(observed-expected) / sd
+	}
+	if expected != 0 {
+		deviation = (observed - expected) / math.Abs(expected) * 100
+	}
+	return score, observed, expected, deviation
+}
+
+//eventDirection returns whether a scored event is a spike above its expected value or a drop below it
+func eventDirection(event OutlierEvent) string {
+	if event.Observed < event.Expected {
+		return config.DirectionDrop
+	}
+	return config.DirectionSpike
+}
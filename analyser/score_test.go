@@ -0,0 +1,140 @@
+package analyser
+
+import (
+	"math"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ftfmtavares/anomalies-detector/collector"
+	"github.com/ftfmtavares/anomalies-detector/config"
+)
+
+func TestScoreEvent(t *testing.T) {
+	timeRef := time.Date(2022, 9, 1, 0, 0, 0, 0, time.UTC)
+	series := func(values ...float64) []collector.TimeStepData {
+		data := []collector.TimeStepData{}
+		for i, value := range values {
+			data = append(data, collector.TimeStepData{DateStart: timeRef.AddDate(0, 0, i), Value: value})
+		}
+		return data
+	}
+	period := func(start, end int) eventPeriod {
+		return eventPeriod{outlierPeriodStart: timeRef.AddDate(0, 0, start), outlierPeriodEnd: timeRef.AddDate(0, 0, end)}
+	}
+
+	tests := []struct {
+		name          string
+		data          []collector.TimeStepData
+		levels        []int
+		period        eventPeriod
+		wantScore     float64
+		wantObserved  float64
+		wantExpected  float64
+		wantDeviation float64
+	}{
+		{
+			name:          "Spike scored by its largest value",
+			data:          series(90, 110, 90, 110, 130, 160),
+			levels:        []int{levelNormal, levelNormal, levelNormal, levelNormal, levelWarning, levelAlarm},
+			period:        period(4, 6),
+			wantScore:     6,
+			wantObserved:  160,
+			wantExpected:  100,
+			wantDeviation: 60,
+		},
+		{
+			name:          "Drop with a negative deviation",
+			data:          series(90, 110, 90, 110, 25),
+			levels:        []int{levelNormal, levelNormal, levelNormal, levelNormal, levelAlarm},
+			period:        period(4, 5),
+			wantScore:     7.5,
+			wantObserved:  25,
+			wantExpected:  100,
+			wantDeviation: -75,
+		},
+		{
+			name:          "Flat baseline without score",
+			data:          series(100, 100, 100, 150),
+			levels:        []int{levelNormal, levelNormal, levelNormal, levelAlarm},
+			period:        period(3, 4),
+			wantObserved:  150,
+			wantExpected:  100,
+			wantDeviation: 50,
+		},
+		{
+			name:          "Every time step flagged",
+			data:          series(100, 300),
+			levels:        []int{levelWarning, levelAlarm},
+			period:        period(1, 2),
+			wantScore:     1,
+			wantObserved:  300,
+			wantExpected:  200,
+			wantDeviation: 50,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			score, observed, expected, deviation := scoreEvent(tt.data, tt.levels, tt.period)
+			for _, check := range []struct {
+				field     string
+				got, want float64
+			}{
+				{"score", score, tt.wantScore},
+				{"observed", observed, tt.wantObserved},
+				{"expected", expected, tt.wantExpected},
+				{"deviation", deviation, tt.wantDeviation},
+			} {
+				if math.Abs(check.got-check.want) > 1e-9 {
+					t.Errorf("scoreEvent() %s = %v, want %v", check.field, check.got, check.want)
+				}
+			}
+		})
+	}
+}
+
+func TestDetectAttributeEventsDirection(t *testing.T) {
+	timeRef := time.Date(2022, 9, 1, 0, 0, 0, 0, time.UTC)
+	data := []collector.TimeStepData{}
+	for i := 0; i < 20; i++ {
+		value := 100.0 + float64(i%3)
+		switch i {
+		case 6:
+			value = 200
+		case 14:
+			value = 10
+		}
+		data = append(data, collector.TimeStepData{DateStart: timeRef.AddDate(0, 0, i), Value: value, Samples: 100})
+	}
+	methodParams := config.DetectionMethodsParams{ThreeSigmas: config.ThreeSigmasParams{OutliersMultiplier: 2, StrongOutliersMultiplier: 2.5}}
+
+	tests := []struct {
+		name           string
+		alertDirection string
+		wantAlarms     []string
+		wantWarnings   []string
+	}{
+		{name: "Both directions alert", wantAlarms: []string{"spike", "drop"}, wantWarnings: []string{}},
+		{name: "Only drops alert", alertDirection: config.DirectionDrop, wantAlarms: []string{"drop"}, wantWarnings: []string{"spike"}},
+		{name: "Only spikes alert", alertDirection: config.DirectionSpike, wantAlarms: []string{"spike"}, wantWarnings: []string{"drop"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			metricData := collector.MetricData{Metric: "Revenue", AlertDirection: tt.alertDirection, Attributes: []string{"Total"}, AttributeData: map[string][]collector.TimeStepData{"Total": data}}
+			got := detectAttributeEvents(metricData, "Total", timeRef.AddDate(0, 0, 20), []string{"3-sigmas"}, 0, methodParams)
+			directions := func(events []OutlierEvent) []string {
+				res := []string{}
+				for _, event := range events {
+					res = append(res, event.Direction)
+				}
+				return res
+			}
+			if !reflect.DeepEqual(directions(got.Alarms), tt.wantAlarms) {
+				t.Errorf("detectAttributeEvents() alarms = %v, want %v", directions(got.Alarms), tt.wantAlarms)
+			}
+			if !reflect.DeepEqual(directions(got.Warnings), tt.wantWarnings) {
+				t.Errorf("detectAttributeEvents() warnings = %v, want %v", directions(got.Warnings), tt.wantWarnings)
+			}
+		})
+	}
+}
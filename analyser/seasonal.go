@@ -0,0 +1,91 @@
+package analyser
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"time"
+
+	"github.com/ftfmtavares/anomalies-detector/collector"
+	"github.com/ftfmtavares/anomalies-detector/config"
+)
+
+//meanDeviationScale converts a mean absolute deviation into a standard deviation estimate for normally distributed values
+const meanDeviationScale = 1.2533
+
+//detectOutliersSeasonalBuckets implements the seasonal-buckets method
+//Time steps are grouped by day of the week, hour of the day or both, in the location of the time steps (the dataset time zone), and every group
+//gets its own baseline, its median and robust standard deviation (median absolute deviation), so a normally quiet Sunday or night isn't flagged
+//against busier days or hours
+//Groups whose values mostly repeat fall back to the mean absolute deviation around the median, and groups of a single time step are never classified as outliers
+func detectOutliersSeasonalBuckets(data []collector.TimeStepData, PeriodEnd time.Time, params config.SeasonalBucketsParams) ([]eventPeriod, []eventPeriod) {
+	levels := make([]int, len(data))
+	bucketKey := params.BucketKey
+	if bucketKey == "" {
+		bucketKey = config.BucketDayOfWeek
+	}
+	if bucketKey != config.BucketDayOfWeek && bucketKey != config.BucketHour && bucketKey != config.BucketDayOfWeekHour {
+		log.Printf("Invalid seasonal-buckets bucket key \"%s\", it must be \"%s\", \"%s\" or \"%s\"\n", bucketKey, config.BucketDayOfWeek, config.BucketHour, config.BucketDayOfWeekHour)
+		return eventPeriodsFromLevels(data, levels, PeriodEnd)
+	}
+
+	for _, indexes := range seasonalBuckets(data, bucketKey) {
+		center, sd := bucketSpread(data, indexes)
+		if sd == 0 {
+			continue
+		}
+
+		for _, ind := range indexes {
+			if diff := math.Abs(data[ind].Value - center); diff > params.StrongOutliersMultiplier*sd {
+				levels[ind] = levelAlarm
+			} else if diff > params.OutliersMultiplier*sd {
+				levels[ind] = levelWarning
+			}
+		}
+	}
+
+	return eventPeriodsFromLevels(data, levels, PeriodEnd)
+}
+
+//seasonalBuckets groups the indexes of the time steps by their seasonal bucket for the given bucket key
+func seasonalBuckets(data []collector.TimeStepData, bucketKey string) map[string][]int {
+	buckets := map[string][]int{}
+	for ind, stepData := range data {
+		key := seasonalBucket(stepData.DateStart, bucketKey)
+		buckets[key] = append(buckets[key], ind)
+	}
+	return buckets
+}
+
+//bucketSpread returns the median and the robust standard deviation of the time steps of a bucket, estimated robustly so the outliers of a few time steps don't hide themselves
+//The standard deviation is zero for buckets of a single time step, and falls back to the mean absolute deviation around the median when their values mostly repeat
+func bucketSpread(data []collector.TimeStepData, indexes []int) (float64, float64) {
+	if len(indexes) < 2 {
+		return 0, 0
+	}
+	values := make([]float64, len(indexes))
+	for i, ind := range indexes {
+		values[i] = data[ind].Value
+	}
+
+	center := median(values)
+	sd := madScale * medianAbsoluteDeviation(values)
+	if sd == 0 {
+		for _, value := range values {
+			sd += math.Abs(value - center)
+		}
+		sd = meanDeviationScale * sd / float64(len(values))
+	}
+	return center, sd
+}
+
+//seasonalBucket returns the seasonal bucket of a time step start for the given bucket key, e.g. "Sunday", "23h" or "Sunday 23h"
+func seasonalBucket(dateStart time.Time, bucketKey string) string {
+	switch bucketKey {
+	case config.BucketHour:
+		return fmt.Sprintf("%02dh", dateStart.Hour())
+	case config.BucketDayOfWeekHour:
+		return fmt.Sprintf("%s %02dh", dateStart.Weekday(), dateStart.Hour())
+	}
+	return dateStart.Weekday().String()
+}
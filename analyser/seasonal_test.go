@@ -0,0 +1,90 @@
+package analyser
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ftfmtavares/anomalies-detector/collector"
+	"github.com/ftfmtavares/anomalies-detector/config"
+)
+
+func TestDetectOutliersSeasonalBuckets(t *testing.T) {
+	//Eight weeks of daily values starting on a Monday, Sundays being normally quiet, with a spike on the Wednesday of the 6th week
+	timeRef := time.Date(2022, 8, 1, 0, 0, 0, 0, time.UTC)
+	daily := make([]collector.TimeStepData, 56)
+	for day := range daily {
+		value := 100 + float64(day%3)
+		if day%7 == 6 {
+			value = 20 + float64(day%2)
+		}
+		daily[day] = collector.TimeStepData{DateStart: timeRef.AddDate(0, 0, day), Value: value, Samples: 100}
+	}
+	daily[37].Value = 300
+
+	//Four days of hourly values, nights being normally quiet, with a drop at noon of the 3rd day
+	hourly := make([]collector.TimeStepData, 96)
+	for hour := range hourly {
+		value := 50 + float64(hour%2)
+		if hour%24 < 6 {
+			value = 5 + float64(hour%2)
+		}
+		hourly[hour] = collector.TimeStepData{DateStart: timeRef.Add(time.Duration(hour) * time.Hour), Value: value, Samples: 100}
+	}
+	hourly[60].Value = 5
+
+	tests := []struct {
+		name           string
+		data           []collector.TimeStepData
+		periodEnd      time.Time
+		bucketKey      string
+		wantedWarnings []eventPeriod
+		wantedAlarms   []eventPeriod
+	}{
+		{
+			name:           "Quiet Sundays aren't flagged while the Wednesday spike is",
+			data:           daily,
+			periodEnd:      timeRef.AddDate(0, 0, 56),
+			bucketKey:      "dow",
+			wantedWarnings: []eventPeriod{},
+			wantedAlarms:   []eventPeriod{{outlierPeriodStart: timeRef.AddDate(0, 0, 37), outlierPeriodEnd: timeRef.AddDate(0, 0, 38)}},
+		},
+		{
+			name:           "Quiet nights aren't flagged while the noon drop is",
+			data:           hourly,
+			periodEnd:      timeRef.Add(96 * time.Hour),
+			bucketKey:      "hour",
+			wantedWarnings: []eventPeriod{},
+			wantedAlarms:   []eventPeriod{{outlierPeriodStart: timeRef.Add(60 * time.Hour), outlierPeriodEnd: timeRef.Add(61 * time.Hour)}},
+		},
+		{
+			name:           "Invalid bucket key",
+			data:           daily,
+			periodEnd:      timeRef.AddDate(0, 0, 56),
+			bucketKey:      "month",
+			wantedWarnings: []eventPeriod{},
+			wantedAlarms:   []eventPeriod{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			params := config.SeasonalBucketsParams{BucketKey: tt.bucketKey, OutliersMultiplier: 2, StrongOutliersMultiplier: 3}
+			warnings, alarms := detectOutliersSeasonalBuckets(tt.data, tt.periodEnd, params)
+			if !reflect.DeepEqual(warnings, tt.wantedWarnings) {
+				t.Errorf("detectOutliersSeasonalBuckets() warnings = %v, want %v", warnings, tt.wantedWarnings)
+			}
+			if !reflect.DeepEqual(alarms, tt.wantedAlarms) {
+				t.Errorf("detectOutliersSeasonalBuckets() alarms = %v, want %v", alarms, tt.wantedAlarms)
+			}
+		})
+	}
+}
+
+func TestSeasonalBucket(t *testing.T) {
+	sunday := time.Date(2022, 8, 7, 23, 30, 0, 0, time.UTC)
+	for bucketKey, want := range map[string]string{"dow": "Sunday", "hour": "23h", "dow+hour": "Sunday 23h"} {
+		if got := seasonalBucket(sunday, bucketKey); got != want {
+			t.Errorf("seasonalBucket(%s) = %s, want %s", bucketKey, got, want)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package analyser
+
+import (
+	"log"
+
+	"github.com/ftfmtavares/anomalies-detector/collector"
+	"github.com/ftfmtavares/anomalies-detector/config"
+	"github.com/ftfmtavares/anomalies-detector/utils"
+)
+
+//ObjectiveReport provides the structure to store the compliance of a service level objective over its rolling window
+//ErrorBudget field is the number of violating time steps allowed by the objective compliance, and BudgetRemaining field
+//the percentage of that budget still available (negative when overspent, 0 when a zero budget was violated)
+type ObjectiveReport struct {
+	Name            string  `json:"name"`
+	Metric          string  `json:"metric"`
+	Attribute       string  `json:"attribute"`
+	Operator        string  `json:"operator"`
+	Target          float64 `json:"target"`
+	Compliance      float64 `json:"compliance"`
+	Window          string  `json:"window"`
+	TimeSteps       int     `json:"timeSteps"`
+	Violations      int     `json:"violations"`
+	Achieved        float64 `json:"achieved"`
+	ErrorBudget     float64 `json:"errorBudget"`
+	BudgetRemaining float64 `json:"budgetRemaining"`
+	Met             bool    `json:"met"`
+}
+
+//evaluateObjectives measures every objective of a site against its collected data at the given time step
+//Objectives restricted to other time steps are skipped, while invalid ones or those on unknown metrics and attributes are logged and skipped
+func evaluateObjectives(siteData collector.SiteData, objectives []config.Objective, timeStep string) []ObjectiveReport {
+	res := []ObjectiveReport{}
+	for _, objective := range objectives {
+		if objective.TimeStep != "" && objective.TimeStep != timeStep {
+			continue
+		}
+		if objective.Attribute == "" {
+			objective.Attribute = "Total"
+		}
+		if objective.Operator != ">=" && objective.Operator != "<=" {
+			log.Printf("Objective %s - invalid operator \"%s\", it must be \">=\" or \"<=\"\n", objective.Name, objective.Operator)
+			continue
+		}
+		if objective.Compliance <= 0 || objective.Compliance > 100 {
+			log.Printf("Objective %s - invalid compliance %.2f, it must be a percentage above 0\n", objective.Name, objective.Compliance)
+			continue
+		}
+
+		//Finding the objective data, limited to the time steps starting within the rolling window
+		var data []collector.TimeStepData
+		found := false
+		for _, metricData := range siteData.Metrics {
+			if metricData.Metric == objective.Metric {
+				data, found = metricData.AttributeData[objective.Attribute]
+			}
+		}
+		if !found {
+			log.Printf("Objective %s - metric %s and attribute %s not collected\n", objective.Name, objective.Metric, objective.Attribute)
+			continue
+		}
+		if objective.Window != "" {
+			window, err := utils.StrToDuration(objective.Window)
+			if err != nil {
+				log.Printf("Objective %s - %s\n", objective.Name, err.Error())
+				continue
+			}
+			windowStart := siteData.DateEnd.Add(-window)
+			for len(data) > 0 && data[0].DateStart.Before(windowStart) {
+				data = data[1:]
+			}
+		}
+
+		res = append(res, evaluateObjective(data, objective))
+	}
+	return res
+}
+
+//evaluateObjective counts the time steps violating a single objective and derives the achieved compliance and the remaining error budget
+func evaluateObjective(data []collector.TimeStepData, objective config.Objective) ObjectiveReport {
+	res := ObjectiveReport{
+		Name:       objective.Name,
+		Metric:     objective.Metric,
+		Attribute:  objective.Attribute,
+		Operator:   objective.Operator,
+		Target:     objective.Target,
+		Compliance: objective.Compliance,
+		Window:     objective.Window,
+		TimeSteps:  len(data),
+		Achieved:   100,
+	}
+
+	for _, stepData := range data {
+		if (objective.Operator == ">=" && stepData.Value < objective.Target) || (objective.Operator == "<=" && stepData.Value > objective.Target) {
+			res.Violations++
+		}
+	}
+
+	res.ErrorBudget = (100 - objective.Compliance) / 100 * float64(res.TimeSteps)
+	if res.TimeSteps > 0 {
+		res.Achieved = 100 * float64(res.TimeSteps-res.Violations) / float64(res.TimeSteps)
+	}
+	if res.ErrorBudget > 0 {
+		res.BudgetRemaining = 100 * (res.ErrorBudget - float64(res.Violations)) / res.ErrorBudget
+	} else if res.Violations == 0 {
+		res.BudgetRemaining = 100
+	}
+	res.Met = float64(res.Violations) <= res.ErrorBudget
+
+	return res
+}
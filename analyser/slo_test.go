@@ -0,0 +1,78 @@
+package analyser
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ftfmtavares/anomalies-detector/collector"
+	"github.com/ftfmtavares/anomalies-detector/config"
+)
+
+func TestEvaluateObjectives(t *testing.T) {
+	timeRef := time.Now()
+
+	values := []float64{120, 90, 130, 110, 80, 105, 95, 140, 100, 125}
+	data := make([]collector.TimeStepData, len(values))
+	for i, val := range values {
+		data[i] = collector.TimeStepData{DateStart: timeRef.AddDate(0, 0, -len(values)+i), Value: val, Samples: 100}
+	}
+	siteData := collector.SiteData{
+		SiteId:  "site",
+		DateEnd: timeRef,
+		Metrics: []collector.MetricData{{Metric: "Revenue", Attributes: []string{"Total"}, AttributeData: map[string][]collector.TimeStepData{"Total": data}}},
+	}
+
+	tests := []struct {
+		name      string
+		objective config.Objective
+		want      []ObjectiveReport
+	}{
+		{
+			name:      "Budget overspent on the whole range",
+			objective: config.Objective{Name: "Daily Revenue", Metric: "Revenue", Operator: ">=", Target: 100, Compliance: 80},
+			want: []ObjectiveReport{{
+				Name: "Daily Revenue", Metric: "Revenue", Attribute: "Total", Operator: ">=", Target: 100, Compliance: 80,
+				TimeSteps: 10, Violations: 3, Achieved: 70, ErrorBudget: 2, BudgetRemaining: -50, Met: false,
+			}},
+		},
+		{
+			name:      "Budget exhausted on the rolling window",
+			objective: config.Objective{Name: "Daily Revenue", Metric: "Revenue", Attribute: "Total", Operator: ">=", Target: 100, Compliance: 80, Window: "5d"},
+			want: []ObjectiveReport{{
+				Name: "Daily Revenue", Metric: "Revenue", Attribute: "Total", Operator: ">=", Target: 100, Compliance: 80, Window: "5d",
+				TimeSteps: 5, Violations: 1, Achieved: 80, ErrorBudget: 1, BudgetRemaining: 0, Met: true,
+			}},
+		},
+		{
+			name:      "Zero budget violated",
+			objective: config.Objective{Name: "Revenue cap", Metric: "Revenue", Operator: "<=", Target: 130, Compliance: 100},
+			want: []ObjectiveReport{{
+				Name: "Revenue cap", Metric: "Revenue", Attribute: "Total", Operator: "<=", Target: 130, Compliance: 100,
+				TimeSteps: 10, Violations: 1, Achieved: 90, ErrorBudget: 0, BudgetRemaining: 0, Met: false,
+			}},
+		},
+		{
+			name:      "Objective of another time step",
+			objective: config.Objective{Name: "Hourly Revenue", Metric: "Revenue", Operator: ">=", Target: 100, Compliance: 80, TimeStep: "1h"},
+			want:      []ObjectiveReport{},
+		},
+		{
+			name:      "Unknown metric",
+			objective: config.Objective{Name: "Daily Basket", Metric: "Basket", Operator: ">=", Target: 100, Compliance: 80},
+			want:      []ObjectiveReport{},
+		},
+		{
+			name:      "Invalid operator",
+			objective: config.Objective{Name: "Daily Revenue", Metric: "Revenue", Operator: ">", Target: 100, Compliance: 80},
+			want:      []ObjectiveReport{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := evaluateObjectives(siteData, []config.Objective{tt.objective}, "1d"); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("evaluateObjectives() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
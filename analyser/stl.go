@@ -0,0 +1,116 @@
+package analyser
+
+import (
+	"log"
+	"sort"
+	"time"
+
+	"github.com/ftfmtavares/anomalies-detector/collector"
+)
+
+//stlPasses is the number of times trend and seasonal components are re-estimated from each other
+const stlPasses = 2
+
+//detectOutliersStl implements the stl (seasonal decomposition) method
+//The series is decomposed into trend + seasonal + residual components and the 3-sigmas rule is applied to the residuals only,
+//so regular daily or weekly patterns aren't flagged as outliers
+//The seasonal component uses the median of each cycle subseries so that outliers don't leak into it
+func detectOutliersStl(data []collector.TimeStepData, PeriodEnd time.Time, seasonLength int, outliersMultiplier, strongOutliersMultiplier float64) ([]eventPeriod, []eventPeriod) {
+	if seasonLength < 2 || len(data) < 2*seasonLength {
+		log.Printf("Not enough data for stl with season length %d, at least 2 seasons are required and %d time steps were given\n", seasonLength, len(data))
+		return []eventPeriod{}, []eventPeriod{}
+	}
+
+	trend, seasonal := stlComponents(data, seasonLength)
+
+	//Building the residuals series keeping the original time steps
+	residuals := make([]collector.TimeStepData, len(data))
+	for ind, stepData := range data {
+		residuals[ind] = stepData
+		residuals[ind].Value = stepData.Value - trend[ind] - seasonal[ind]
+	}
+
+	return detectOutliers3Sigmas(residuals, PeriodEnd, outliersMultiplier, strongOutliersMultiplier)
+}
+
+//stlComponents returns the trend and seasonal components of a series of at least 2 seasons
+//Trend and seasonal estimations alternate, each one computed over the series without the other
+func stlComponents(data []collector.TimeStepData, seasonLength int) ([]float64, []float64) {
+	values := make([]float64, len(data))
+	for ind, stepData := range data {
+		values[ind] = stepData.Value
+	}
+
+	seasonal := make([]float64, len(values))
+	trend := []float64{}
+	for pass := 0; pass < stlPasses; pass++ {
+		deseasonalized := make([]float64, len(values))
+		for ind := range values {
+			deseasonalized[ind] = values[ind] - seasonal[ind]
+		}
+		trend = movingAverage(deseasonalized, seasonLength)
+
+		detrended := make([]float64, len(values))
+		for ind := range values {
+			detrended[ind] = values[ind] - trend[ind]
+		}
+		seasonal = seasonalComponent(detrended, seasonLength)
+	}
+	return trend, seasonal
+}
+
+//movingAverage returns the centered moving average of a series over a window of the given length
+//Even lengths use a 2xN average with half weights at both ends, and the window is shortened near the series bounds
+func movingAverage(values []float64, length int) []float64 {
+	half := length / 2
+	res := make([]float64, len(values))
+	for ind := range values {
+		sum := 0.0
+		weights := 0.0
+		for pos := ind - half; pos <= ind+half; pos++ {
+			if pos < 0 || pos >= len(values) {
+				continue
+			}
+			weight := 1.0
+			if length%2 == 0 && (pos == ind-half || pos == ind+half) {
+				weight = 0.5
+			}
+			sum += weight * values[pos]
+			weights += weight
+		}
+		res[ind] = sum / weights
+	}
+	return res
+}
+
+//seasonalComponent returns the seasonal component of a detrended series
+//Each position within the season takes the median of its cycle subseries, and the season is centered around zero
+func seasonalComponent(detrended []float64, seasonLength int) []float64 {
+	season := make([]float64, seasonLength)
+	seasonSum := 0.0
+	for position := 0; position < seasonLength; position++ {
+		subseries := []float64{}
+		for ind := position; ind < len(detrended); ind += seasonLength {
+			subseries = append(subseries, detrended[ind])
+		}
+		season[position] = median(subseries)
+		seasonSum += season[position]
+	}
+
+	res := make([]float64, len(detrended))
+	for ind := range detrended {
+		res[ind] = season[ind%seasonLength] - seasonSum/float64(seasonLength)
+	}
+	return res
+}
+
+//median returns the median of the given values without modifying them
+func median(values []float64) float64 {
+	sorted := append([]float64{}, values...)
+	sort.Float64s(sorted)
+	middle := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[middle-1] + sorted[middle]) / 2
+	}
+	return sorted[middle]
+}
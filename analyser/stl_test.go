@@ -0,0 +1,72 @@
+package analyser
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ftfmtavares/anomalies-detector/collector"
+)
+
+func TestDetectOutliersStl(t *testing.T) {
+	timeRef := time.Now()
+
+	//8 weeks of daily values with a weekly peak on the last day of each week and a single spike on sample #46
+	values := []float64{}
+	for i := 0; i < 56; i++ {
+		value := 100 + float64((i*3)%5) - 2
+		if i%7 == 6 {
+			value += 100
+		}
+		if i == 45 {
+			value += 50
+		}
+		values = append(values, value)
+	}
+	data := make([]collector.TimeStepData, len(values))
+	for i, val := range values {
+		data[i].Samples = 100
+		data[i].DateStart = timeRef.AddDate(0, 0, -len(values)+i)
+		data[i].Value = val
+	}
+
+	tests := []struct {
+		name           string
+		seasonLength   int
+		data           []collector.TimeStepData
+		wantedWarnings []eventPeriod
+		wantedAlarms   []eventPeriod
+	}{
+		{
+			name:           "Weekly peaks are ignored and the spike at sample #46 is an alarm",
+			seasonLength:   7,
+			data:           data,
+			wantedWarnings: []eventPeriod{},
+			wantedAlarms:   []eventPeriod{{outlierPeriodStart: timeRef.AddDate(0, 0, -11), outlierPeriodEnd: timeRef.AddDate(0, 0, -10)}},
+		},
+		{
+			name:           "Less than 2 seasons of data",
+			seasonLength:   30,
+			data:           data,
+			wantedWarnings: []eventPeriod{},
+			wantedAlarms:   []eventPeriod{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			warnings, alarms := detectOutliersStl(tt.data, timeRef, tt.seasonLength, 2, 3)
+			if !reflect.DeepEqual(warnings, tt.wantedWarnings) {
+				t.Errorf("detectOutliersStl() got = %v, want %v", warnings, tt.wantedWarnings)
+			}
+			if !reflect.DeepEqual(alarms, tt.wantedAlarms) {
+				t.Errorf("detectOutliersStl() got1 = %v, want %v", alarms, tt.wantedAlarms)
+			}
+		})
+	}
+
+	//The global method flags the weekly peaks instead
+	if warnings, _ := detectOutliers3Sigmas(data, timeRef, 2, 3); len(warnings) == 0 {
+		t.Errorf("detectOutliers3Sigmas() got no warnings, want the weekly peaks")
+	}
+}
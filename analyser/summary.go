@@ -0,0 +1,89 @@
+package analyser
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+//EventCount provides the structure for the number of alarms and warnings detected on a metric of a site at one of its time steps
+type EventCount struct {
+	SiteId   string `json:"siteId"`
+	TimeStep string `json:"timeStep"`
+	Metric   string `json:"metric"`
+	Alarms   int    `json:"alarms"`
+	Warnings int    `json:"warnings"`
+}
+
+//SummarizeReports counts the alarms and warnings of every report by metric, in report order and then by metric name
+//Metrics without events are left out, as are the shadow method events, which are never notified
+func SummarizeReports(reports []OutlierReport) []EventCount {
+	counts := []EventCount{}
+	for _, report := range reports {
+		byMetric := map[string]*EventCount{}
+		metrics := []string{}
+		count := func(events []OutlierEvent, alarms bool) {
+			for _, event := range events {
+				if byMetric[event.Metric] == nil {
+					byMetric[event.Metric] = &EventCount{SiteId: report.SiteId, TimeStep: report.TimeStep, Metric: event.Metric}
+					metrics = append(metrics, event.Metric)
+				}
+				if alarms {
+					byMetric[event.Metric].Alarms++
+				} else {
+					byMetric[event.Metric].Warnings++
+				}
+			}
+		}
+		count(report.Result.Alarms, true)
+		count(report.Result.Warnings, false)
+
+		sort.Strings(metrics)
+		for _, metric := range metrics {
+			counts = append(counts, *byMetric[metric])
+		}
+	}
+	return counts
+}
+
+//HasEvents checks if any of the counted events has at least the given severity, warnings including alarms
+func HasEvents(counts []EventCount, severity string) bool {
+	for _, count := range counts {
+		if count.Alarms > 0 || (severity == "warning" && count.Warnings > 0) {
+			return true
+		}
+	}
+	return false
+}
+
+//FormatSummary returns the event counts as an aligned text table, one site metric per line, followed by their totals
+func FormatSummary(counts []EventCount) string {
+	siteWidth, metricWidth := len("site"), len("metric")
+	for _, count := range counts {
+		if len(summarySite(count)) > siteWidth {
+			siteWidth = len(summarySite(count))
+		}
+		if len(count.Metric) > metricWidth {
+			metricWidth = len(count.Metric)
+		}
+	}
+
+	var table strings.Builder
+	table.WriteString(fmt.Sprintf("%-*s %-*s %6s %8s\n", siteWidth, "site", metricWidth, "metric", "alarms", "warnings"))
+	alarms, warnings := 0, 0
+	for _, count := range counts {
+		table.WriteString(fmt.Sprintf("%-*s %-*s %6d %8d\n", siteWidth, summarySite(count), metricWidth, count.Metric, count.Alarms, count.Warnings))
+		alarms += count.Alarms
+		warnings += count.Warnings
+	}
+	table.WriteString(fmt.Sprintf("%-*s %-*s %6d %8d\n", siteWidth, "total", metricWidth, "", alarms, warnings))
+	return table.String()
+}
+
+//summarySite returns the site of a count along with its time step, if any, e.g. "shop (1h)"
+func summarySite(count EventCount) string {
+	if count.TimeStep == "" {
+		return count.SiteId
+	}
+	return fmt.Sprintf("%s (%s)", count.SiteId, count.TimeStep)
+}
@@ -0,0 +1,47 @@
+package analyser
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSummarizeReports(t *testing.T) {
+	reports := []OutlierReport{
+		{
+			SiteId:   "shop",
+			TimeStep: "1h",
+			Result: OutlierResults{
+				Alarms:   []OutlierEvent{{Metric: "Visits"}, {Metric: "Revenue"}, {Metric: "Revenue"}},
+				Warnings: []OutlierEvent{{Metric: "Basket"}, {Metric: "Revenue"}},
+			},
+			Shadow: &OutlierResults{Alarms: []OutlierEvent{{Metric: "Orders"}}},
+		},
+		{SiteId: "blog", TimeStep: "1d"},
+		{SiteId: "news", Result: OutlierResults{Warnings: []OutlierEvent{{Metric: "Visits"}}}},
+	}
+
+	counts := SummarizeReports(reports)
+	want := []EventCount{
+		{SiteId: "shop", TimeStep: "1h", Metric: "Basket", Warnings: 1},
+		{SiteId: "shop", TimeStep: "1h", Metric: "Revenue", Alarms: 2, Warnings: 1},
+		{SiteId: "shop", TimeStep: "1h", Metric: "Visits", Alarms: 1},
+		{SiteId: "news", Metric: "Visits", Warnings: 1},
+	}
+	if !reflect.DeepEqual(counts, want) {
+		t.Fatalf("SummarizeReports() = %+v, want %+v", counts, want)
+	}
+
+	wantTable := "site      metric  alarms warnings\n" +
+		"shop (1h) Basket       0        1\n" +
+		"shop (1h) Revenue      2        1\n" +
+		"shop (1h) Visits       1        0\n" +
+		"news      Visits       0        1\n" +
+		"total                  3        3\n"
+	if got := FormatSummary(counts); got != wantTable {
+		t.Errorf("FormatSummary() = %q, want %q", got, wantTable)
+	}
+
+	if !HasEvents(counts, "alarm") || !HasEvents(counts[3:], "warning") || HasEvents(counts[3:], "alarm") {
+		t.Errorf("HasEvents() doesn't tell the severities apart")
+	}
+}
@@ -0,0 +1,172 @@
+package analyser
+
+import (
+	"errors"
+	"math"
+	"time"
+
+	"github.com/ftfmtavares/anomalies-detector/collector"
+	"github.com/ftfmtavares/anomalies-detector/config"
+)
+
+//Const block defines the verdicts of a verified period
+const (
+	VerdictAlarm   = "alarm"
+	VerdictWarning = "warning"
+	VerdictNormal  = "normal"
+)
+
+//VerificationRequest provides the structure for an on demand verification of a site metric period
+//Attribute field defaults to "Total", while Methods, the multipliers and Consensus fields optionally override the site detection settings
+type VerificationRequest struct {
+	Metric                   string
+	Attribute                string
+	PeriodStart              time.Time
+	PeriodEnd                time.Time
+	Methods                  []string
+	OutliersMultiplier       float64
+	StrongOutliersMultiplier float64
+	Consensus                int
+}
+
+//Verification provides the structure for the outcome of an on demand verification
+//Verdict field is the highest severity of the events overlapping the period, "normal" if none, Events field listing them
+type Verification struct {
+	SiteId      string                 `json:"siteId"`
+	Metric      string                 `json:"metric"`
+	Attribute   string                 `json:"attribute"`
+	TimeStep    string                 `json:"timeStep"`
+	PeriodStart time.Time              `json:"periodStart"`
+	PeriodEnd   time.Time              `json:"periodEnd"`
+	Methods     []string               `json:"methods"`
+	Consensus   int                    `json:"consensus,omitempty"`
+	Verdict     string                 `json:"verdict"`
+	Events      []OutlierEvent         `json:"events"`
+	Statistics  VerificationStatistics `json:"statistics"`
+}
+
+//VerificationStatistics provides the structure for the statistics of a verified period compared with the rest of the series
+//Deviation field is the distance between the period mean and the baseline mean, in baseline standard deviations
+type VerificationStatistics struct {
+	BaselinePoints int     `json:"baselinePoints"`
+	BaselineMean   float64 `json:"baselineMean"`
+	BaselineStdDev float64 `json:"baselineStdDev"`
+	PeriodPoints   int     `json:"periodPoints"`
+	PeriodMean     float64 `json:"periodMean"`
+	PeriodMin      float64 `json:"periodMin"`
+	PeriodMax      float64 `json:"periodMax"`
+	Deviation      float64 `json:"deviation"`
+}
+
+//VerifyPeriod recomputes the detection over a single metric attribute of a site, with the site settings and the optional overrides of the request,
+//and returns the verdict on the requested period along with its statistics
+//It returns an error if the metric, attribute or period isn't found on the site data
+func VerifyPeriod(siteData collector.SiteData, dataConf config.Dataset, methodParams config.DetectionMethodsParams, request VerificationRequest) (Verification, error) {
+	if request.Attribute == "" {
+		request.Attribute = "Total"
+	}
+	res := Verification{SiteId: siteData.SiteId, Metric: request.Metric, Attribute: request.Attribute, TimeStep: siteData.TimeStep, PeriodStart: request.PeriodStart, PeriodEnd: request.PeriodEnd, Events: []OutlierEvent{}}
+	if !request.PeriodStart.Before(request.PeriodEnd) {
+		return res, errors.New("the period end must be after its start")
+	}
+
+	//Keeping only the verified series so the detection runs over it alone
+	var data []collector.TimeStepData
+	verified := siteData
+	verified.Metrics = nil
+	for _, metricData := range siteData.Metrics {
+		if metricData.Metric != request.Metric {
+			continue
+		}
+		var found bool
+		if data, found = metricData.AttributeData[request.Attribute]; !found {
+			return res, errors.New("attribute not found")
+		}
+		single := metricData
+		single.Attributes = []string{request.Attribute}
+		single.AttributeData = map[string][]collector.TimeStepData{request.Attribute: data}
+		verified.Metrics = []collector.MetricData{single}
+	}
+	if verified.Metrics == nil {
+		return res, errors.New("metric not found")
+	}
+
+	//Applying the site profile first, so the request overrides win over it
+	dataConf, methodParams, _, err := ResolveDetectionProfile(dataConf, methodParams)
+	if err != nil {
+		return res, err
+	}
+	dataConf.DetectionProfile = ""
+	dataConf.TimeStep = siteData.TimeStep
+	if len(request.Methods) > 0 {
+		dataConf.OutliersDetectionMethod, dataConf.OutliersDetectionMethods = "", request.Methods
+	}
+	if request.Consensus > 0 {
+		dataConf.Consensus = request.Consensus
+	}
+	if request.OutliersMultiplier > 0 || request.StrongOutliersMultiplier > 0 {
+		outliersMultiplier, strongOutliersMultiplier := request.OutliersMultiplier, request.StrongOutliersMultiplier
+		if outliersMultiplier <= 0 {
+			outliersMultiplier = methodParams.ThreeSigmas.OutliersMultiplier
+		}
+		if strongOutliersMultiplier <= 0 {
+			strongOutliersMultiplier = methodParams.ThreeSigmas.StrongOutliersMultiplier
+		}
+		methodParams = methodParams.WithMultipliers(outliersMultiplier, strongOutliersMultiplier)
+	}
+	dataConf.ShadowDetectionMethod, dataConf.Objectives = "", nil
+	res.Methods = dataConf.Methods()
+	if len(res.Methods) > 1 && dataConf.Consensus > 1 {
+		res.Consensus = dataConf.Consensus
+	}
+
+	//Reporting the events overlapping the period, alarms first
+	report := GetResults(verified, dataConf, methodParams)
+	res.Verdict = VerdictNormal
+	for _, event := range report.Result.Alarms {
+		if event.OutlierPeriodStart.Before(request.PeriodEnd) && event.OutlierPeriodEnd.After(request.PeriodStart) {
+			res.Events = append(res.Events, event)
+			res.Verdict = VerdictAlarm
+		}
+	}
+	for _, event := range report.Result.Warnings {
+		if event.OutlierPeriodStart.Before(request.PeriodEnd) && event.OutlierPeriodEnd.After(request.PeriodStart) {
+			res.Events = append(res.Events, event)
+			if res.Verdict == VerdictNormal {
+				res.Verdict = VerdictWarning
+			}
+		}
+	}
+
+	//Comparing the period values with the rest of the series
+	var baselineSum, baselineSquares, periodSum float64
+	stats := &res.Statistics
+	for _, stepData := range data {
+		if !stepData.DateStart.Before(request.PeriodStart) && stepData.DateStart.Before(request.PeriodEnd) {
+			if stats.PeriodPoints == 0 || stepData.Value < stats.PeriodMin {
+				stats.PeriodMin = stepData.Value
+			}
+			if stats.PeriodPoints == 0 || stepData.Value > stats.PeriodMax {
+				stats.PeriodMax = stepData.Value
+			}
+			stats.PeriodPoints++
+			periodSum += stepData.Value
+			continue
+		}
+		stats.BaselinePoints++
+		baselineSum += stepData.Value
+		baselineSquares += stepData.Value * stepData.Value
+	}
+	if stats.PeriodPoints == 0 {
+		return res, errors.New("no data within the period")
+	}
+	stats.PeriodMean = periodSum / float64(stats.PeriodPoints)
+	if stats.BaselinePoints > 0 {
+		stats.BaselineMean = baselineSum / float64(stats.BaselinePoints)
+		stats.BaselineStdDev = math.Sqrt(math.Max(baselineSquares/float64(stats.BaselinePoints)-stats.BaselineMean*stats.BaselineMean, 0))
+		if stats.BaselineStdDev > 0 {
+			stats.Deviation = (stats.PeriodMean - stats.BaselineMean) / stats.BaselineStdDev
+		}
+	}
+	return res, nil
+}
@@ -0,0 +1,84 @@
+package analyser
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ftfmtavares/anomalies-detector/collector"
+	"github.com/ftfmtavares/anomalies-detector/config"
+)
+
+func TestVerifyPeriod(t *testing.T) {
+	timeRef := time.Date(2022, 9, 1, 0, 0, 0, 0, time.UTC)
+
+	//A steady daily revenue with a single spike on day 20
+	data := []collector.TimeStepData{}
+	for day := 0; day < 30; day++ {
+		value := 100.0 + float64(day%3)
+		if day == 20 {
+			value = 130
+		}
+		data = append(data, collector.TimeStepData{DateStart: timeRef.AddDate(0, 0, day), Value: value, Samples: 100})
+	}
+	siteData := collector.SiteData{
+		SiteId:    "shop",
+		TimeStep:  "1d",
+		DateStart: timeRef,
+		DateEnd:   timeRef.AddDate(0, 0, 30),
+		Metrics:   []collector.MetricData{{Metric: "Revenue", Attributes: []string{"Total"}, AttributeData: map[string][]collector.TimeStepData{"Total": data}}},
+	}
+	dataConf := config.Dataset{SiteId: "shop", TimeAgo: "30d", TimeStep: "1d", OutliersDetectionMethod: "3-sigmas"}
+	methodParams := config.DetectionMethodsParams{ThreeSigmas: config.ThreeSigmasParams{OutliersMultiplier: 2, StrongOutliersMultiplier: 3}}
+
+	tests := []struct {
+		name        string
+		request     VerificationRequest
+		wantVerdict string
+		wantEvents  int
+		wantErr     bool
+	}{
+		{
+			name:        "Spike confirmed",
+			request:     VerificationRequest{Metric: "Revenue", PeriodStart: timeRef.AddDate(0, 0, 20), PeriodEnd: timeRef.AddDate(0, 0, 21)},
+			wantVerdict: VerdictAlarm,
+			wantEvents:  1,
+		},
+		{
+			name:        "Normal period",
+			request:     VerificationRequest{Metric: "Revenue", Attribute: "Total", PeriodStart: timeRef.AddDate(0, 0, 5), PeriodEnd: timeRef.AddDate(0, 0, 8)},
+			wantVerdict: VerdictNormal,
+		},
+		{
+			name:        "Spike below overridden multipliers",
+			request:     VerificationRequest{Metric: "Revenue", PeriodStart: timeRef.AddDate(0, 0, 20), PeriodEnd: timeRef.AddDate(0, 0, 21), OutliersMultiplier: 10, StrongOutliersMultiplier: 12},
+			wantVerdict: VerdictNormal,
+		},
+		{
+			name:    "Unknown metric",
+			request: VerificationRequest{Metric: "Visits", PeriodStart: timeRef, PeriodEnd: timeRef.AddDate(0, 0, 1)},
+			wantErr: true,
+		},
+		{
+			name:    "Period without data",
+			request: VerificationRequest{Metric: "Revenue", PeriodStart: timeRef.AddDate(0, 1, 0), PeriodEnd: timeRef.AddDate(0, 2, 0)},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := VerifyPeriod(siteData, dataConf, methodParams, tt.request)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("VerifyPeriod() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if got.Verdict != tt.wantVerdict || len(got.Events) != tt.wantEvents {
+				t.Errorf("VerifyPeriod() verdict = %s with %d events, want %s with %d", got.Verdict, len(got.Events), tt.wantVerdict, tt.wantEvents)
+			}
+			if got.Statistics.PeriodPoints+got.Statistics.BaselinePoints != len(data) {
+				t.Errorf("VerifyPeriod() statistics cover %d points, want %d", got.Statistics.PeriodPoints+got.Statistics.BaselinePoints, len(data))
+			}
+		})
+	}
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+
+	anomaliesdetector "github.com/ftfmtavares/anomalies-detector"
+	"github.com/ftfmtavares/anomalies-detector/analyser"
+	"github.com/ftfmtavares/anomalies-detector/config"
+	"github.com/ftfmtavares/anomalies-detector/utils"
+)
+
+//backtestOutput provides the structure for the backtest command output, with the swept parameter and the scores over all sites of each of its values
+type backtestOutput struct {
+	Sweep analyser.BacktestSweep `json:"sweep"`
+	Runs  []analyser.BacktestRun `json:"runs"`
+}
+
+//backtestMethod implements the backtest command
+//It collects every dataset of the configuration once and scores a detection method with each value of one of its parameters, against the deviations injected into the generated data and the labelled incidents,
+//printing the true and false positives, precision, recall and F1 of every value and writing them to the output file if given
+func backtestMethod(args []string) {
+	flags := flag.NewFlagSet("backtest", flag.ExitOnError)
+	confFile := flags.String("conf-file", "config.json", "Configuration file name")
+	labelFile := flags.String("label-file", "", "Label store file name, whose incidents are scored along with the generated ones (none if empty)")
+	method := flags.String("method", "3-sigmas", "Detection method to backtest")
+	param := flags.String("param", "outliersMultiplier", "Parameter of the detection method to sweep, named as on the config file")
+	from := flags.Float64("from", 2, "First value of the swept parameter")
+	to := flags.Float64("to", 5, "Last value of the swept parameter")
+	step := flags.Float64("step", 0.5, "Step between the swept values")
+	outputFile := flags.String("output", "", "File name where the scores of every value are written (disabled if empty)")
+	overwrite := flags.Bool("overwrite", false, "Overwrite existing files")
+	seed := flags.Int64("seed", 0, "Seed of the generated data (the configured one or random if 0)")
+	flags.Usage = func() {
+		flags.Output().Write([]byte("Usage: anomalies-detector backtest [options]\n"))
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	//Validating the arguments values
+	if err := validateInputFile(*confFile); err != nil {
+		log.Fatalf("conf-file \"%s\" - %s\n\n", *confFile, err.Error())
+	}
+	sweep := analyser.BacktestSweep{Method: *method, Parameter: *param, From: *from, To: *to, Step: *step}
+	values, err := sweep.Values()
+	if err != nil {
+		log.Fatalf("backtest - %s\n\n", err.Error())
+	}
+	labels := []analyser.Label{}
+	if *labelFile != "" {
+		if err := validateInputFile(*labelFile); err != nil {
+			log.Fatalf("label-file \"%s\" - %s\n\n", *labelFile, err.Error())
+		}
+		if labels, err = analyser.ReadLabelsFile(*labelFile); err != nil {
+			log.Fatalf("label-file \"%s\" - %s\n\n", *labelFile, err.Error())
+		}
+	}
+
+	//Reading configurations from the config file
+	log.Printf("Built-in integrations: %s\n", builtIntegrations())
+	log.Printf("Using configuration file \"%s\"\n", *confFile)
+	appConfig, err := config.LoadConfFile(*confFile)
+	if err != nil {
+		log.Fatalf("conf-file \"%s\" - %s\n\n", *confFile, err.Error())
+	}
+	if *seed != 0 {
+		appConfig.Seed = *seed
+	}
+	configureOutbound(appConfig, *confFile)
+	configureFiles(appConfig, *confFile)
+	if *outputFile != "" {
+		if err := validateOutputFile(*outputFile, *overwrite); err != nil {
+			log.Fatalf("output \"%s\" - %s\n\n", *outputFile, err.Error())
+		}
+	}
+
+	//Collecting every dataset once and scoring every swept value over the same data
+	detector, err := anomaliesdetector.New(appConfig)
+	if err != nil {
+		log.Fatalf("conf-file \"%s\" - %s\n\n", *confFile, err.Error())
+	}
+	if _, err := detector.Collect(); err != nil {
+		log.Printf("Collection failed - %s\n", err.Error())
+	}
+	runs, err := detector.Backtest(labels, sweep, values)
+	if err != nil {
+		log.Fatalf("Backtest failed - %s\n\n", err.Error())
+	}
+
+	log.Printf("Backtested %s %s over %d values\n", sweep.Method, sweep.Parameter, len(runs))
+	os.Stdout.WriteString(analyser.FormatBacktest(sweep, runs))
+	if *outputFile != "" {
+		utils.WriteJsonStruct(backtestOutput{Sweep: sweep, Runs: runs}, *outputFile)
+	}
+}
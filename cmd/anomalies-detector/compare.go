@@ -0,0 +1,91 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"log"
+	"os"
+
+	"github.com/ftfmtavares/anomalies-detector/analyser"
+	"github.com/ftfmtavares/anomalies-detector/config"
+	"github.com/ftfmtavares/anomalies-detector/utils"
+)
+
+//compareRuns implements the compare-runs command
+//It takes 2 report files or ids of runs stored on the run history of the configuration and writes the differences between their events to the output file or to stdout
+func compareRuns(args []string) {
+	flags := flag.NewFlagSet("compare-runs", flag.ExitOnError)
+	confFile := flags.String("conf-file", "config.json", "Configuration file name, whose run history is read for run ids")
+	outputFile := flags.String("output", "", "Comparison output file name (stdout if empty)")
+	overwrite := flags.Bool("overwrite", false, "Overwrite existing files")
+	flags.Usage = func() {
+		flags.Output().Write([]byte("Usage: anomalies-detector compare-runs [options] <report-a|run-id> <report-b|run-id>\n"))
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	//The comparison is written to the standard output without an output file, so logs go to the standard error to keep it valid JSON
+	if *outputFile == "" {
+		log.SetOutput(os.Stderr)
+	}
+
+	//Validating the arguments values
+	if flags.NArg() != 2 {
+		flags.Usage()
+		os.Exit(2)
+	}
+	if *outputFile != "" {
+		if err := validateOutputFile(*outputFile, *overwrite); err != nil {
+			log.Fatalf("output \"%s\" - %s\n\n", *outputFile, err.Error())
+		}
+	}
+
+	//Opening the run history of the configuration only when an argument isn't a report file
+	var history *analyser.RunHistory
+	if validateInputFile(flags.Arg(0)) != nil || validateInputFile(flags.Arg(1)) != nil {
+		if err := validateInputFile(*confFile); err != nil {
+			log.Fatalf("conf-file \"%s\" - %s, required to read run ids\n\n", *confFile, err.Error())
+		}
+		appConfig, err := config.LoadConfFile(*confFile)
+		if err != nil {
+			log.Fatalf("conf-file \"%s\" - %s\n\n", *confFile, err.Error())
+		}
+		if appConfig.Storage != nil {
+			if history, err = analyser.NewRunHistory(*appConfig.Storage); err != nil {
+				log.Fatalf("conf-file \"%s\" - %s\n\n", *confFile, err.Error())
+			}
+			for _, dataSet := range appConfig.Datasets {
+				if dataSet.EncryptionKey == "" {
+					continue
+				}
+				key, err := utils.LoadEncryptionKey(dataSet.EncryptionKey)
+				if err != nil {
+					log.Fatalf("site %s - %s\n\n", dataSet.SiteId, err.Error())
+				}
+				history.EncryptSite(dataSet.SiteId, key)
+			}
+		}
+	}
+
+	//Reading both runs, from their report files or from the run history
+	reportsA, err := analyser.ReadRunReports(flags.Arg(0), history)
+	if err != nil {
+		log.Fatalf("report \"%s\" - %s\n\n", flags.Arg(0), err.Error())
+	}
+	reportsB, err := analyser.ReadRunReports(flags.Arg(1), history)
+	if err != nil {
+		log.Fatalf("report \"%s\" - %s\n\n", flags.Arg(1), err.Error())
+	}
+
+	//Comparing and exporting the differences
+	comparison := analyser.CompareReports(reportsA, reportsB)
+	log.Printf("Compared \"%s\" with \"%s\" - %d only in A, %d only in B, %d changed severity, %d unchanged\n", flags.Arg(0), flags.Arg(1), len(comparison.OnlyInA), len(comparison.OnlyInB), len(comparison.ChangedSeverity), comparison.Unchanged)
+
+	if *outputFile != "" {
+		utils.WriteJsonStruct(comparison, *outputFile)
+		return
+	}
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	encoder.Encode(comparison)
+}
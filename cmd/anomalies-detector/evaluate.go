@@ -0,0 +1,114 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	anomaliesdetector "github.com/ftfmtavares/anomalies-detector"
+	"github.com/ftfmtavares/anomalies-detector/analyser"
+	"github.com/ftfmtavares/anomalies-detector/config"
+	"github.com/ftfmtavares/anomalies-detector/utils"
+)
+
+//evaluationOutput provides the structure for the evaluate command output, with the scores of every method over all sites and on each site
+type evaluationOutput struct {
+	Totals []analyser.MethodScore `json:"totals"`
+	Sites  []analyser.Evaluation  `json:"sites"`
+}
+
+//evaluateMethods implements the evaluate command
+//It collects every dataset of the configuration and scores the detection methods against the deviations injected into the generated data and the labelled incidents,
+//printing the precision, recall and F1 of each method over all sites and writing the scores of every site to the output file if given
+func evaluateMethods(args []string) {
+	flags := flag.NewFlagSet("evaluate", flag.ExitOnError)
+	confFile := flags.String("conf-file", "config.json", "Configuration file name")
+	labelFile := flags.String("label-file", "", "Label store file name, whose incidents are scored along with the generated ones (none if empty)")
+	methods := flags.String("methods", "", "Comma separated detection methods to score (all if empty)")
+	outputFile := flags.String("output", "", "File name where the scores of every site are written (disabled if empty)")
+	overwrite := flags.Bool("overwrite", false, "Overwrite existing files")
+	seed := flags.Int64("seed", 0, "Seed of the generated data (the configured one or random if 0)")
+	flags.Usage = func() {
+		flags.Output().Write([]byte("Usage: anomalies-detector evaluate [options]\n"))
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	//Validating the arguments values
+	if err := validateInputFile(*confFile); err != nil {
+		log.Fatalf("conf-file \"%s\" - %s\n\n", *confFile, err.Error())
+	}
+	methodsList, err := parseMethods(*methods)
+	if err != nil {
+		log.Fatalf("methods \"%s\" - %s\n\n", *methods, err.Error())
+	}
+	labels := []analyser.Label{}
+	if *labelFile != "" {
+		if err := validateInputFile(*labelFile); err != nil {
+			log.Fatalf("label-file \"%s\" - %s\n\n", *labelFile, err.Error())
+		}
+		if labels, err = analyser.ReadLabelsFile(*labelFile); err != nil {
+			log.Fatalf("label-file \"%s\" - %s\n\n", *labelFile, err.Error())
+		}
+	}
+
+	//Reading configurations from the config file
+	log.Printf("Built-in integrations: %s\n", builtIntegrations())
+	log.Printf("Using configuration file \"%s\"\n", *confFile)
+	appConfig, err := config.LoadConfFile(*confFile)
+	if err != nil {
+		log.Fatalf("conf-file \"%s\" - %s\n\n", *confFile, err.Error())
+	}
+	if *seed != 0 {
+		appConfig.Seed = *seed
+	}
+	configureOutbound(appConfig, *confFile)
+	configureFiles(appConfig, *confFile)
+	if *outputFile != "" {
+		if err := validateOutputFile(*outputFile, *overwrite); err != nil {
+			log.Fatalf("output \"%s\" - %s\n\n", *outputFile, err.Error())
+		}
+	}
+
+	//Collecting every dataset once and scoring each method over the same data
+	detector, err := anomaliesdetector.New(appConfig)
+	if err != nil {
+		log.Fatalf("conf-file \"%s\" - %s\n\n", *confFile, err.Error())
+	}
+	if _, err := detector.Collect(); err != nil {
+		log.Printf("Collection failed - %s\n", err.Error())
+	}
+	evaluations, err := detector.Evaluate(labels, methodsList)
+	if err != nil {
+		log.Printf("Evaluation failed - %s\n", err.Error())
+	}
+
+	output := evaluationOutput{Totals: analyser.SumScores(evaluations), Sites: evaluations}
+	log.Printf("Evaluated %d sites\n", len(evaluations))
+	os.Stdout.WriteString(analyser.FormatScores(output.Totals))
+	if *outputFile != "" {
+		utils.WriteJsonStruct(output, *outputFile)
+	}
+}
+
+//parseMethods splits a comma separated list of detection methods, returning an error on the first unknown one
+func parseMethods(list string) ([]string, error) {
+	methods := []string{}
+	if list == "" {
+		return methods, nil
+	}
+	for _, method := range strings.Split(list, ",") {
+		method = strings.TrimSpace(method)
+		known := false
+		for _, supported := range config.DetectionMethods {
+			known = known || method == supported
+		}
+		if !known {
+			return nil, fmt.Errorf("unknown detection method \"%s\", it must be one of %s", method, strings.Join(config.DetectionMethods, ", "))
+		}
+		methods = append(methods, method)
+	}
+	return methods, nil
+}
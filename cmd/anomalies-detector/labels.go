@@ -0,0 +1,60 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ftfmtavares/anomalies-detector/analyser"
+	"github.com/ftfmtavares/anomalies-detector/utils"
+)
+
+//importIncidents implements the import-incidents command
+//It takes a CSV or JSON file of known past incidents and merges them into the label store file, which is created if missing
+func importIncidents(args []string) {
+	flags := flag.NewFlagSet("import-incidents", flag.ExitOnError)
+	labelFile := flags.String("label-file", "labels.json", "Label store file name")
+	format := flags.String("format", "", "Incidents file format, \"csv\" or \"json\" (from the file extension if empty)")
+	flags.Usage = func() {
+		flags.Output().Write([]byte("Usage: anomalies-detector import-incidents [options] <incidents-file>\n"))
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	//Validating the arguments values
+	if flags.NArg() != 1 {
+		flags.Usage()
+		os.Exit(2)
+	}
+	incidentsFile := flags.Arg(0)
+	if err := validateInputFile(incidentsFile); err != nil {
+		log.Fatalf("incidents file \"%s\" - %s\n\n", incidentsFile, err.Error())
+	}
+	if *format == "" {
+		*format = "json"
+		if strings.ToLower(filepath.Ext(incidentsFile)) == ".csv" {
+			*format = "csv"
+		}
+	}
+
+	//Reading the incidents and the current label store before writing them merged
+	file, err := os.Open(incidentsFile)
+	if err != nil {
+		log.Fatalf("incidents file \"%s\" - %s\n\n", incidentsFile, err.Error())
+	}
+	defer file.Close()
+	imported, err := analyser.ImportIncidents(file, *format)
+	if err != nil {
+		log.Fatalf("incidents file \"%s\" - %s\n\n", incidentsFile, err.Error())
+	}
+	stored, err := analyser.ReadLabelsFile(*labelFile)
+	if err != nil {
+		log.Fatalf("label-file \"%s\" - %s\n\n", *labelFile, err.Error())
+	}
+
+	labels, added, updated := analyser.MergeLabels(stored, imported)
+	utils.WriteJsonStruct(labels, *labelFile)
+	log.Printf("Imported \"%s\" into \"%s\" - %d added, %d updated, %d labels stored\n", incidentsFile, *labelFile, added, updated, len(labels))
+}
@@ -0,0 +1,597 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"strings"
+	"time"
+	_ "time/tzdata"
+
+	anomaliesdetector "github.com/ftfmtavares/anomalies-detector"
+	"github.com/ftfmtavares/anomalies-detector/analyser"
+	"github.com/ftfmtavares/anomalies-detector/collector"
+	"github.com/ftfmtavares/anomalies-detector/config"
+	"github.com/ftfmtavares/anomalies-detector/reporting"
+	"github.com/ftfmtavares/anomalies-detector/utils"
+)
+
+//failOnExitCode is the exit status of runs detecting events of the --fail-on severity, telling them apart from failures, which exit with 1
+const failOnExitCode = 3
+
+//eventPublisher is implemented by every channel publishing the detected events of a report to external automation
+type eventPublisher interface {
+	Publish(report analyser.OutlierReport) error
+}
+
+func main() {
+	log.SetOutput(os.Stdout)
+	log.SetFlags(log.Ldate + log.Ltime + log.Lmicroseconds)
+
+	//Dispatching to the supported commands, running the detection when none is given
+	if len(os.Args) > 1 && os.Args[1] == "compare-runs" {
+		compareRuns(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "stream" {
+		streamDetection(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "import-incidents" {
+		importIncidents(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "evaluate" {
+		evaluateMethods(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "backtest" {
+		backtestMethod(os.Args[2:])
+		return
+	}
+
+	//The analyse command analyses the data file given after its flags, "-" for the standard input, writing the report to the standard output by default
+	analyse := len(os.Args) > 1 && os.Args[1] == "analyse"
+	if analyse {
+		os.Args = append(os.Args[:1], os.Args[2:]...)
+	}
+
+	//Defining CLI arguments using the flag package
+	//Default values are local files with standard names and no overwrite option
+	confFile := flag.String("conf-file", "config.json", "Configuration file name")
+	dataFile := flag.String("data-file", "data.json", "Collected Data file name (\"-\" for the standard output)")
+	reportFile := flag.String("report-file", "report.json", "Outliers Report file name (\"-\" for the standard output)")
+	dataFormat := flag.String("data-format", reporting.FormatJson, "Collected Data file format (json, csv or parquet)")
+	reportFormat := flag.String("report-format", reporting.FormatJson, "Outliers Report file format (json, csv or parquet)")
+	overwrite := flag.Bool("overwrite", false, "Overwrite existing files")
+	chartArchiveDir := flag.String("chart-archive-dir", "", "Directory where the charts of each run are archived (disabled if empty)")
+	cloudEventsFile := flag.String("cloudevents-file", "", "File name where all detected events are exported as a CloudEvents batch (disabled if empty)")
+	seed := flag.Int64("seed", 0, "Seed of the generated data, runs with the same seed generating the same values (the configured one or random if 0)")
+	fromData := flag.String("from-data", "", "Data file previously written as JSON, analysed instead of collecting the datasets, which is neither written again nor stored (\"-\" for the standard input, disabled if empty)")
+	listen := flag.String("listen", "", "Address (host:port) the report server binds to, overriding the configured one (\":8080\" by default)")
+	tlsCert := flag.String("tls-cert", "", "PEM certificate file the report is served over HTTPS with, along with tls-key, overriding the configured one")
+	tlsKey := flag.String("tls-key", "", "PEM private key file of tls-cert, overriding the configured one")
+	failOn := flag.String("fail-on", "", "Severity (alarm or warning) of the detected events making the run exit with status 3, the run ending once exported instead of serving the report (disabled if empty)")
+	flag.Parse()
+	if analyse {
+		if flag.NArg() != 1 {
+			log.Fatalf("analyse - a single data file, or \"-\" for the standard input, expected after the flags\n\n")
+		}
+		*fromData = flag.Arg(0)
+		if !isFlagSet("report-file") {
+			*reportFile = utils.StdStream
+		}
+	}
+
+	//Runs writing to the standard output log to the standard error instead and end once exported, so their output can be piped
+	piped := *dataFile == utils.StdStream && *fromData == "" || *reportFile == utils.StdStream
+	summaryOutput := os.Stdout
+	if piped {
+		log.SetOutput(os.Stderr)
+		summaryOutput = os.Stderr
+	}
+
+	//Validating the arguments values
+	if err := validateInputFile(*confFile); err != nil {
+		log.Fatalf("conf-file \"%s\" - %s\n\n", *confFile, err.Error())
+	}
+	if err := reporting.ValidateFormat(*dataFormat); err != nil {
+		log.Fatalf("data-format \"%s\" - %s\n\n", *dataFormat, err.Error())
+	}
+	if err := reporting.ValidateFormat(*reportFormat); err != nil {
+		log.Fatalf("report-format \"%s\" - %s\n\n", *reportFormat, err.Error())
+	}
+	if *fromData != "" && *fromData != utils.StdStream {
+		if err := validateInputFile(*fromData); err != nil {
+			log.Fatalf("from-data \"%s\" - %s\n\n", *fromData, err.Error())
+		}
+	}
+	if *failOn != "" && *failOn != "alarm" && *failOn != "warning" {
+		log.Fatalf("fail-on \"%s\" - invalid severity, alarm or warning expected\n\n", *failOn)
+	}
+	if *dataFile == utils.StdStream && *fromData == "" && *reportFile == utils.StdStream {
+		log.Fatalf("data-file \"%s\" - only one of data-file and report-file can be written to the standard output\n\n", *dataFile)
+	}
+	if *dataFile == utils.StdStream && *dataFormat != reporting.FormatJson {
+		log.Fatalf("data-format \"%s\" - only the json format is written to the standard output\n\n", *dataFormat)
+	}
+	if *reportFile == utils.StdStream && *reportFormat != reporting.FormatJson {
+		log.Fatalf("report-format \"%s\" - only the json format is written to the standard output\n\n", *reportFormat)
+	}
+
+	//Reading configurations from the config file
+	log.Printf("Built-in integrations: %s\n", builtIntegrations())
+	log.Printf("Using configuration file \"%s\"\n", *confFile)
+	config, err := config.LoadConfFile(*confFile)
+	if err != nil {
+		log.Fatalf("conf-file \"%s\" - %s\n\n", *confFile, err.Error())
+	}
+	if *seed != 0 {
+		config.Seed = *seed
+	}
+	if *listen != "" {
+		config.ReportServer.Listen = *listen
+	}
+	if *tlsCert != "" || *tlsKey != "" {
+		config.ReportServer.CertFile, config.ReportServer.KeyFile = *tlsCert, *tlsKey
+	}
+	if err := validateReportServer(config.ReportServer); err != nil {
+		log.Fatalf("%s\n\n", err.Error())
+	}
+	log.Println("Configuration Read:")
+	utils.PrintJsonStruct(utils.RedactSecrets(config))
+
+	//Flat files hold plain rows only, so encrypted sites are kept on JSON files
+	for _, dataset := range config.Datasets {
+		if dataset.EncryptionKey == "" {
+			continue
+		}
+		if *dataFormat != reporting.FormatJson {
+			log.Fatalf("data-format \"%s\" - site %s is encrypted, only the json format holds encrypted records\n\n", *dataFormat, dataset.SiteId)
+		}
+		if *reportFormat != reporting.FormatJson {
+			log.Fatalf("report-format \"%s\" - site %s is encrypted, only the json format holds encrypted records\n\n", *reportFormat, dataset.SiteId)
+		}
+	}
+
+	//Routing every outbound integration through the configured proxy and TLS settings
+	configureOutbound(config, *confFile)
+
+	//Writing every file with the configured permissions, ownership and output root, starting with the output files validation
+	configureFiles(config, *confFile)
+	if *fromData == "" && *dataFile != utils.StdStream {
+		if err := validateOutputFile(*dataFile, *overwrite); err != nil {
+			log.Fatalf("data-file \"%s\" - %s\n\n", *dataFile, err.Error())
+			return
+		}
+	}
+	if *reportFile != utils.StdStream {
+		if err := validateOutputFile(*reportFile, *overwrite); err != nil {
+			log.Fatalf("report-file \"%s\" - %s\n\n", *reportFile, err.Error())
+			return
+		}
+	}
+	if *cloudEventsFile != "" {
+		if err := validateOutputFile(*cloudEventsFile, *overwrite); err != nil {
+			log.Fatalf("cloudevents-file \"%s\" - %s\n\n", *cloudEventsFile, err.Error())
+		}
+	}
+
+	//Creating the detection pipeline, which validates alert rules, metric definitions, transforms and encryption keys before any collection
+	detector, err := anomaliesdetector.New(config)
+	if err != nil {
+		log.Fatalf("conf-file \"%s\" - %s\n\n", *confFile, err.Error())
+	}
+
+	//Creating the notification channels, publishers and subscriptions the detected events are sent through
+	//The charts of the emailed digests are drawn from the collected data, which is set before any analysis
+	var sitesData []collector.SiteData
+	notify := newNotify(config, *confFile, func(siteId, timeStep string) (collector.SiteData, bool) {
+		for _, siteData := range sitesData {
+			if siteData.SiteId == siteId && siteData.TimeStep == timeStep {
+				return siteData, true
+			}
+		}
+		return collector.SiteData{}, false
+	})
+	detector.Notify = notify
+
+	//Tracking the events across runs if configured, so that only the events not reported by earlier runs are notified
+	var eventStates *reporting.EventStateStore
+	if config.EventState != nil {
+		if eventStates, err = reporting.NewEventStateStore(*config.EventState); err != nil {
+			log.Fatalf("conf-file \"%s\" - %s\n\n", *confFile, err.Error())
+		}
+		detector.Notify = func(report analyser.OutlierReport) {
+			fresh, resolved, err := eventStates.Track(report, time.Now())
+			if err != nil {
+				log.Printf("Event state not saved - %s\n", err.Error())
+			}
+			for _, state := range resolved {
+				log.Printf("Event resolved - %s - %s - %s\n", state.SiteId, state.Metric, state.Attribute)
+			}
+			notify(fresh)
+		}
+	}
+
+	//Creating the optional tiered storage the collected data of every run is appended to, along with the run history its reports are kept on
+	var store *collector.TierStore
+	if config.Storage != nil {
+		if store, err = collector.NewTierStore(*config.Storage); err != nil {
+			log.Fatalf("conf-file \"%s\" - %s\n\n", *confFile, err.Error())
+		}
+		if detector.History, err = analyser.NewRunHistory(*config.Storage); err != nil {
+			log.Fatalf("conf-file \"%s\" - %s\n\n", *confFile, err.Error())
+		}
+	}
+
+	//Opening the optional results store the collected data and detected events of every run are written to
+	var resultStore *reporting.ResultStore
+	if config.Database != nil {
+		if resultStore, err = reporting.NewResultStore(*config.Database); err != nil {
+			log.Fatalf("conf-file \"%s\" - %s\n\n", *confFile, err.Error())
+		}
+		defer resultStore.Close()
+	}
+
+	//Creating the optional self-monitoring watchdog, loading the collection state of the previous runs
+	var watchdog *reporting.Watchdog
+	if config.Watchdog != nil {
+		if watchdog, err = reporting.NewWatchdog(*config.Watchdog); err != nil {
+			log.Fatalf("conf-file \"%s\" - %s\n\n", *confFile, err.Error())
+		}
+	}
+
+	//Collecting and analysing all sites from the configuration file, once for each configured time step, notifying the detected events
+	//Failing datasets are logged without stopping the others, which are still exported and served
+	//An existing data file is analysed instead if given, so detection settings can be iterated over the same data without collecting it again
+	if *fromData != "" {
+		log.Printf("Analysing data file \"%s\"\n", *fromData)
+		if *fromData == utils.StdStream {
+			sitesData, err = detector.LoadReader(os.Stdin)
+		} else {
+			sitesData, err = detector.Load(*fromData)
+		}
+		if sitesData == nil {
+			log.Fatalf("from-data \"%s\" - %s\n\n", *fromData, err.Error())
+		}
+		if err != nil {
+			log.Printf("Loading failed - %s\n", err.Error())
+		}
+	} else if sitesData, err = detector.Collect(); err != nil {
+		log.Printf("Collection failed - %s\n", err.Error())
+	}
+	reports, err := detector.Analyse()
+	if err != nil {
+		log.Printf("Analysis failed - %s\n", err.Error())
+	}
+
+	//Persisted data and reports hold either plain or encrypted records, according to each site configuration
+	dataRecords, reportRecords, err := detector.Records()
+	if err != nil {
+		log.Fatalf("%s\n\n", err.Error())
+	}
+
+	//Exporting both data and reports on given files, as JSON records or as flat rows for spreadsheets and data warehouses
+	if *fromData != "" {
+		log.Println("Data file not written, the data was loaded")
+	} else if *dataFormat == reporting.FormatJson {
+		utils.WriteJsonStruct(dataRecords, *dataFile)
+	} else if err := reporting.WriteData(sitesData, *dataFormat, *dataFile); err != nil {
+		log.Fatalf("data-file \"%s\" - %s\n\n", *dataFile, err.Error())
+	}
+	if *reportFormat == reporting.FormatJson {
+		utils.WriteJsonStruct(reportRecords, *reportFile)
+	} else if err := reporting.WriteReports(reports, *reportFormat, *reportFile); err != nil {
+		log.Fatalf("report-file \"%s\" - %s\n\n", *reportFile, err.Error())
+	}
+
+	//Exporting all detected events as a CloudEvents batch if requested, leaving encrypted sites out since the batch is written in plain text
+	if *cloudEventsFile != "" {
+		encryptedSites := map[string]bool{}
+		for _, dataset := range config.Datasets {
+			if dataset.EncryptionKey != "" {
+				encryptedSites[dataset.SiteId] = true
+				log.Printf("CloudEvents file - site %s left out, its events are encrypted at rest\n", dataset.SiteId)
+			}
+		}
+		cloudEvents := []reporting.CloudEvent{}
+		for _, report := range reports {
+			if encryptedSites[report.SiteId] {
+				continue
+			}
+			cloudEvents = append(cloudEvents, reporting.CloudEvents(report, "", map[string]bool{"warning": true, "alarm": true}, nil)...)
+		}
+		utils.WriteJsonStruct(cloudEvents, *cloudEventsFile)
+	}
+
+	//Appending the collected data to the storage tiers, failures being logged since data and reports were already exported
+	if store != nil && *fromData == "" {
+		if err := detector.Store(store); err != nil {
+			log.Printf("Storage failed - %s\n", err.Error())
+		}
+	}
+
+	//Writing the run to the results store, failures being logged since data and reports were already exported
+	if resultStore != nil && *fromData == "" {
+		if err := detector.SaveResults(resultStore); err != nil {
+			log.Printf("Results store failed - %s\n", err.Error())
+		}
+	}
+
+	//Archiving the charts of this run if requested, failures being logged since data and reports were already exported
+	if *chartArchiveDir != "" {
+		if runDir, err := reporting.ArchiveCharts(sitesData, reports, config.Datasets, *chartArchiveDir, time.Now()); err != nil {
+			log.Printf("Chart archive \"%s\" failed - %s\n", runDir, err.Error())
+		} else {
+			log.Printf("Charts archived on \"%s\"\n", runDir)
+		}
+	}
+
+	//Alerting on sites whose collection keeps failing and then watching the age of the served data while the report server runs
+	if watchdog != nil && *fromData == "" {
+		alerts, err := watchdog.RecordRun(sitesData, time.Now())
+		if err != nil {
+			log.Printf("Watchdog state not saved - %s\n", err.Error())
+		}
+		for _, alert := range alerts {
+			log.Printf("Watchdog alert - %s - %s\n", alert.SiteId, alert.Result.Alarms[0].Attribute)
+			notify(alert)
+		}
+		go watchdog.Watch(sitesData, func(alert analyser.OutlierReport) {
+			log.Printf("Watchdog alert - %s - %s\n", alert.SiteId, alert.Result.Alarms[0].Attribute)
+			notify(alert)
+		})
+	}
+
+	//Printing the events detected by this run and, for CI checks, cron jobs and pipelines, ending it with the detection status instead of serving the report
+	summary := analyser.SummarizeReports(reports)
+	summaryOutput.WriteString(analyser.FormatSummary(summary))
+	if *failOn != "" || piped {
+		if *failOn == "" || !analyser.HasEvents(summary, *failOn) {
+			return
+		}
+		log.Printf("Events detected - failing on %s\n", *failOn)
+		if resultStore != nil {
+			resultStore.Close()
+		}
+		os.Exit(failOnExitCode)
+	}
+
+	//Serving the stored data and latest reports of the archived datasets alongside this run, without collecting nor alerting on them
+	if store != nil {
+		archivedData, archivedReports, err := detector.Archived(store)
+		if err != nil {
+			log.Printf("Archived datasets not loaded - %s\n", err.Error())
+		}
+		sitesData = append(sitesData, archivedData...)
+		reports = append(reports, archivedReports...)
+	}
+
+	//Starting the report server with the charts, dashboard and API of the collected data and detected events, notifications having been sent already
+	log.Printf("Generated Report on %s\n", reporting.ReportUrl(config.ReportServer))
+	runStats := detector.Stats()
+	if err := reporting.GenerateReport(sitesData, reports, config.ReportServer, reporting.DetectionSettings{Datasets: detector.Datasets(), Methods: config.DetectionMethods, History: detector.History, Run: &runStats, EventStates: eventStates, Metrics: detector.Metrics()}); err != nil {
+		log.Fatalf("report server \"%s\" - %s\n\n", reporting.ReportListen(config.ReportServer), err.Error())
+	}
+}
+
+//validateReportServer checks the report server address and TLS files given on the command line or the configuration file
+//It returns an error if the address isn't host:port or if only one of the certificate and key files is given, or either can't be read
+func validateReportServer(serverParams config.ReportServerParams) error {
+	if _, port, err := net.SplitHostPort(reporting.ReportListen(serverParams)); err != nil || port == "" {
+		return fmt.Errorf("listen \"%s\" - invalid address, host:port expected", serverParams.Listen)
+	}
+	if (serverParams.CertFile == "") != (serverParams.KeyFile == "") {
+		return errors.New("tls-cert and tls-key are both required for TLS")
+	}
+	if serverParams.CertFile == "" {
+		return nil
+	}
+	if err := validateInputFile(serverParams.CertFile); err != nil {
+		return fmt.Errorf("tls-cert \"%s\" - %s", serverParams.CertFile, err.Error())
+	}
+	if err := validateInputFile(serverParams.KeyFile); err != nil {
+		return fmt.Errorf("tls-key \"%s\" - %s", serverParams.KeyFile, err.Error())
+	}
+	return nil
+}
+
+//configureOutbound replaces the transport of the outbound HTTP clients and the TLS settings of the other connections with the configured ones, if any
+//It exits the application if the settings are invalid, so nothing is collected nor notified around the proxy or private CAs
+func configureOutbound(appConfig config.ApplicationConfig, confFile string) {
+	if appConfig.Outbound == nil {
+		return
+	}
+	outbound := appConfig.Outbound
+	transport, err := utils.NewOutboundTransport(outbound.Proxy, outbound.NoProxy, outbound.CaFile, outbound.ClientCertFile, outbound.ClientKeyFile)
+	if err != nil {
+		log.Fatalf("conf-file \"%s\" - %s\n\n", confFile, err.Error())
+	}
+	utils.HttpTransport = transport
+	utils.TlsConfig = transport.TLSClientConfig
+	utils.OutboundTlsFiles = utils.TlsFiles{CaFile: outbound.CaFile, CertFile: outbound.ClientCertFile, KeyFile: outbound.ClientKeyFile}
+}
+
+//configureFiles replaces the policy every file is written with by the configured one, if any
+//It exits the application if the settings are invalid, so no file is written with unexpected permissions or outside the output root
+func configureFiles(appConfig config.ApplicationConfig, confFile string) {
+	if appConfig.Files == nil {
+		return
+	}
+	files := appConfig.Files
+	policy, err := utils.NewFilePolicy(files.Umask, files.FileMode, files.DirMode, files.Owner, files.Group, files.CreateDirs, files.OutputRoot)
+	if err != nil {
+		log.Fatalf("conf-file \"%s\" - %s\n\n", confFile, err.Error())
+	}
+	utils.Files = policy
+}
+
+//builtIntegrations lists the optional integrations built into the binary, "none" for the default build
+func builtIntegrations() string {
+	if integrations := utils.Integrations(); len(integrations) > 0 {
+		return strings.Join(integrations, ", ")
+	}
+	return "none"
+}
+
+//newNotify creates the optional notification channels, event publishers and subscriptions of the configuration
+//It returns a function sending a report through all of them, exiting the application if any of them is invalid
+//The site data of a report is looked up with siteData, for the notification channels embedding charts
+func newNotify(appConfig config.ApplicationConfig, confFile string, siteData func(siteId, timeStep string) (collector.SiteData, bool)) func(report analyser.OutlierReport) {
+
+	//Creating the optional notification channels
+	var slackNotifier *reporting.SlackNotifier
+	if appConfig.Notifiers.Slack != nil {
+		var err error
+		if slackNotifier, err = reporting.NewSlackNotifier(*appConfig.Notifiers.Slack); err != nil {
+			log.Fatalf("conf-file \"%s\" - %s\n\n", confFile, err.Error())
+		}
+	}
+
+	var webhookNotifier *reporting.WebhookNotifier
+	if appConfig.Notifiers.Webhook != nil {
+		var err error
+		if webhookNotifier, err = reporting.NewWebhookNotifier(*appConfig.Notifiers.Webhook); err != nil {
+			log.Fatalf("conf-file \"%s\" - %s\n\n", confFile, err.Error())
+		}
+	}
+
+	var emailNotifier *reporting.EmailNotifier
+	if appConfig.Notifiers.Email != nil {
+		var err error
+		if emailNotifier, err = reporting.NewEmailNotifier(*appConfig.Notifiers.Email, appConfig.Datasets); err != nil {
+			log.Fatalf("conf-file \"%s\" - %s\n\n", confFile, err.Error())
+		}
+	}
+
+	//Creating the optional publishers of events to external automation
+	publishers := []eventPublisher{}
+	if appConfig.Notifiers.CloudEvents != nil {
+		publisher, err := reporting.NewCloudEventsPublisher(*appConfig.Notifiers.CloudEvents)
+		if err != nil {
+			log.Fatalf("conf-file \"%s\" - %s\n\n", confFile, err.Error())
+		}
+		publishers = append(publishers, publisher)
+	}
+	if appConfig.Notifiers.EventBridge != nil {
+		publisher, err := reporting.NewEventBridgePublisher(*appConfig.Notifiers.EventBridge)
+		if err != nil {
+			log.Fatalf("conf-file \"%s\" - %s\n\n", confFile, err.Error())
+		}
+		publishers = append(publishers, publisher)
+	}
+	if appConfig.Notifiers.PubSub != nil {
+		publisher, err := reporting.NewPubSubPublisher(*appConfig.Notifiers.PubSub)
+		if err != nil {
+			log.Fatalf("conf-file \"%s\" - %s\n\n", confFile, err.Error())
+		}
+		publishers = append(publishers, publisher)
+	}
+
+	//Creating a publisher for each subscription, those of the configuration file first and then those created through the API
+	for _, subscription := range appConfig.Subscriptions {
+		publisher, err := reporting.NewSubscriptionPublisher(subscription, appConfig.Notifiers)
+		if err != nil {
+			log.Fatalf("conf-file \"%s\" - %s\n\n", confFile, err.Error())
+		}
+		publishers = append(publishers, publisher)
+	}
+	if appConfig.ReportServer.SubscriptionsFile != "" {
+		store, err := reporting.LoadSubscriptionStore(appConfig.ReportServer.SubscriptionsFile)
+		if err != nil {
+			log.Fatalf("conf-file \"%s\" - %s\n\n", confFile, err.Error())
+		}
+		for _, subscription := range store.List() {
+			publisher, err := reporting.NewSubscriptionPublisher(subscription, appConfig.Notifiers)
+			if err != nil {
+				log.Printf("Skipping %s\n", err.Error())
+				continue
+			}
+			publishers = append(publishers, publisher)
+		}
+	}
+
+	//Sending a report through every notification channel and publisher, failures being logged without stopping the remaining ones
+	return func(report analyser.OutlierReport) {
+		if slackNotifier != nil {
+			if err := slackNotifier.Notify(report); err != nil {
+				log.Printf("Notification failed - %s - %s\n", report.SiteId, err.Error())
+			}
+		}
+		if webhookNotifier != nil {
+			if err := webhookNotifier.Notify(report); err != nil {
+				log.Printf("Notification failed - %s - %s\n", report.SiteId, err.Error())
+			}
+		}
+		if emailNotifier != nil {
+			data, found := siteData(report.SiteId, report.TimeStep)
+			if err := emailNotifier.Notify(report, data, found); err != nil {
+				log.Printf("Notification failed - %s - %s\n", report.SiteId, err.Error())
+			}
+		}
+		for _, publisher := range publishers {
+			if err := publisher.Publish(report); err != nil {
+				log.Printf("Publishing failed - %s - %s\n", report.SiteId, err.Error())
+			}
+		}
+	}
+}
+
+//validateInputFile checks if a given file name is valid to be read
+//It returns an error if file name is empty or invalid, if file does not exist or if it's a directory
+func validateInputFile(inputFile string) error {
+	if inputFile == "" {
+		return errors.New("missing parameter")
+	}
+	if fileInfo, err := os.Stat(inputFile); err != nil || fileInfo.IsDir() {
+		if err != nil && os.IsNotExist(err) {
+			return errors.New("file does not exist")
+		} else if fileInfo.IsDir() {
+			return errors.New("file is a directory")
+		} else {
+			return errors.New("invalid file name")
+		}
+	}
+
+	return nil
+}
+
+//isFlagSet checks if a flag was given on the command line, rather than taking its default value
+func isFlagSet(name string) bool {
+	set := false
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+	return set
+}
+
+//validateOutputFile checks if a given file name is valid to be writen with overwrite option or not
+//It returns an error if file name is empty or invalid, if it's a directory or it simply fails to create
+//An empty file is actually created at this stage, with the configured file policy, in order to test any possible creation errors (lack of permissions or outside the output root for instance)
+func validateOutputFile(outputFile string, overwrite bool) error {
+	if outputFile == "" {
+		return errors.New("missing parameter")
+	}
+	if fileInfo, err := os.Stat(outputFile); err == nil || !os.IsNotExist(err) {
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		} else if fileInfo.IsDir() {
+			return errors.New("file is a directory")
+		} else if !overwrite {
+			return errors.New("file already exists")
+		}
+	}
+	fData, err := utils.Files.Create(outputFile)
+	if err != nil {
+		return err
+	}
+	defer fData.Close()
+
+	return nil
+}
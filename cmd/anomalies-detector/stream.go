@@ -0,0 +1,311 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	anomaliesdetector "github.com/ftfmtavares/anomalies-detector"
+	"github.com/ftfmtavares/anomalies-detector/analyser"
+	"github.com/ftfmtavares/anomalies-detector/collector"
+	"github.com/ftfmtavares/anomalies-detector/config"
+	"github.com/ftfmtavares/anomalies-detector/utils"
+)
+
+//streamSettings holds everything the streams are analysed with, replaced as a whole when the configuration is reloaded
+//The detector runs the same collection stages and analysis over the buffered data as over collected data
+type streamSettings struct {
+	appConfig config.ApplicationConfig
+	detector  *anomaliesdetector.Detector
+	datasets  map[string]streamDataset
+}
+
+//streamDataset is a streamed dataset ready to be consumed at its first time step
+//Signature field identifies the settings its buffer and consumer depend on, the stream being restarted by reloads changing it
+type streamDataset struct {
+	kafka     config.KafkaParams
+	timeStep  time.Duration
+	timeAgo   time.Duration
+	interval  time.Duration
+	signature string
+}
+
+//newStreamSettings creates the detector of a configuration, validating it as a run does, and the buffer settings of its streamed datasets
+//It returns an error if any of them is invalid, so a faulty reload never replaces the running settings
+func newStreamSettings(appConfig config.ApplicationConfig) (*streamSettings, error) {
+	settings := &streamSettings{appConfig: appConfig, datasets: map[string]streamDataset{}}
+	var err error
+	if settings.detector, err = anomaliesdetector.New(appConfig); err != nil {
+		return nil, err
+	}
+
+	for _, dataSet := range appConfig.Datasets {
+		if dataSet.Kafka == nil || dataSet.Archived {
+			continue
+		}
+		if err := utils.CheckIntegration(utils.IntegrationKafka); err != nil {
+			return nil, fmt.Errorf("site %s - kafka - %s", dataSet.SiteId, err.Error())
+		}
+		stream := streamDataset{kafka: *dataSet.Kafka}
+		if stream.timeStep, err = utils.StrToDuration(dataSet.Resolutions()[0]); err != nil {
+			return nil, fmt.Errorf("site %s - %s", dataSet.SiteId, err.Error())
+		}
+		if stream.timeAgo, err = utils.StrToDuration(dataSet.TimeAgo); err != nil {
+			return nil, fmt.Errorf("site %s - %s", dataSet.SiteId, err.Error())
+		}
+		stream.interval = stream.timeStep
+		if dataSet.Kafka.AnalysisInterval != "" {
+			if stream.interval, err = utils.StrToDuration(dataSet.Kafka.AnalysisInterval); err != nil || stream.interval <= 0 {
+				return nil, fmt.Errorf("site %s - invalid analysisInterval \"%s\"", dataSet.SiteId, dataSet.Kafka.AnalysisInterval)
+			}
+		}
+		if _, err := collector.NewStreamBuffer(*dataSet.Kafka, stream.timeStep, stream.timeAgo); err != nil {
+			return nil, fmt.Errorf("site %s - %s", dataSet.SiteId, err.Error())
+		}
+		kafka, _ := json.Marshal(dataSet.Kafka)
+		stream.signature = fmt.Sprintf("%s|%s|%s|%s", kafka, stream.timeStep, stream.timeAgo, stream.interval)
+		settings.datasets[dataSet.SiteId] = stream
+	}
+	return settings, nil
+}
+
+//streamWorker is a running stream, stopped by cancelling its context
+type streamWorker struct {
+	signature string
+	cancel    context.CancelFunc
+	done      chan struct{}
+}
+
+//streamDetection implements the stream command
+//It consumes the raw events of every dataset with a kafka section, archived ones aside, and analyses their buffered time steps periodically, only notifying new events
+//The configuration is reloaded on SIGHUP, or when the file changes if a reload interval is given, streams whose buffer settings didn't change keeping their events
+func streamDetection(args []string) {
+	flags := flag.NewFlagSet("stream", flag.ExitOnError)
+	confFile := flags.String("conf-file", "config.json", "Configuration file name")
+	reportFile := flags.String("report-file", "", "File name where the latest report of every site is written after each analysis (disabled if empty)")
+	reloadInterval := flags.Duration("reload-interval", 0, "How often the configuration file is checked for changes to reload it (only on SIGHUP if 0)")
+	flags.Usage = func() {
+		flags.Output().Write([]byte("Usage: anomalies-detector stream [options]\n"))
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	//Validating the arguments values and reading the configuration file
+	if err := validateInputFile(*confFile); err != nil {
+		log.Fatalf("conf-file \"%s\" - %s\n\n", *confFile, err.Error())
+	}
+	log.Printf("Built-in integrations: %s\n", builtIntegrations())
+	log.Printf("Using configuration file \"%s\"\n", *confFile)
+	appConfig, err := config.LoadConfFile(*confFile)
+	if err != nil {
+		log.Fatalf("conf-file \"%s\" - %s\n\n", *confFile, err.Error())
+	}
+	settings, err := newStreamSettings(appConfig)
+	if err != nil {
+		log.Fatalf("conf-file \"%s\" - %s\n\n", *confFile, err.Error())
+	}
+	configureOutbound(appConfig, *confFile)
+	configureFiles(appConfig, *confFile)
+	//Notifications, the report file and the settings are shared by all streams, so they are serialized
+	//The notifications are sent under the lock, so the latest data of the streams is read by the digests without locking again
+	var mu sync.Mutex
+	latestReports := map[string]analyser.OutlierReport{}
+	latestData := map[string]collector.SiteData{}
+	notify := newNotify(appConfig, *confFile, func(siteId, timeStep string) (collector.SiteData, bool) {
+		siteData, found := latestData[siteId]
+		return siteData, found && siteData.TimeStep == timeStep
+	})
+	currentSettings := func() *streamSettings {
+		mu.Lock()
+		defer mu.Unlock()
+		return settings
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	//Starting a consumer and a periodic analysis for a streamed dataset, which reads the current settings on every analysis
+	workers := map[string]*streamWorker{}
+	startWorker := func(siteId string, stream streamDataset) {
+		streamCtx, cancel := context.WithCancel(ctx)
+		worker := &streamWorker{signature: stream.signature, cancel: cancel, done: make(chan struct{})}
+		workers[siteId] = worker
+		buffer, _ := collector.NewStreamBuffer(stream.kafka, stream.timeStep, stream.timeAgo)
+
+		var wg sync.WaitGroup
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			log.Printf("Streaming - %s - topic %s\n", siteId, stream.kafka.Topic)
+			if err := collector.ConsumeKafka(streamCtx, siteId, stream.kafka, buffer); err != nil {
+				log.Printf("Streaming stopped - %s - %s\n", siteId, err.Error())
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			notified := map[string]time.Time{}
+			ticker := time.NewTicker(stream.interval)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-streamCtx.Done():
+					return
+				case now := <-ticker.C:
+
+					//Analysing the buffered period as a collected one
+					siteData, report, _ := currentSettings().detector.Stream(siteId, func(dataSet config.Dataset) collector.SiteData {
+						return buffer.Snapshot(dataSet, now)
+					})
+					fresh := newStreamEvents(report, notified)
+					log.Printf("Stream analysed - %s - %d metrics - %d new alarms - %d new warnings\n", siteId, len(siteData.Metrics), len(fresh.Result.Alarms), len(fresh.Result.Warnings))
+
+					mu.Lock()
+					latestData[siteId] = siteData
+					notify(fresh)
+					latestReports[siteId] = report
+					if *reportFile != "" {
+						writeStreamReports(latestReports, *reportFile)
+					}
+					mu.Unlock()
+				}
+			}
+		}()
+		go func() {
+			wg.Wait()
+			close(worker.done)
+		}()
+	}
+	for siteId, stream := range settings.datasets {
+		startWorker(siteId, stream)
+	}
+
+	//Reloading the configuration on SIGHUP or when the file changes, keeping the running settings if the new ones are invalid
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
+	defer signal.Stop(hangup)
+	var poll <-chan time.Time
+	if *reloadInterval > 0 {
+		ticker := time.NewTicker(*reloadInterval)
+		defer ticker.Stop()
+		poll = ticker.C
+	}
+	modTime := fileModTime(*confFile)
+	reload := func() {
+		modTime = fileModTime(*confFile)
+		nextConfig, err := config.LoadConfFile(*confFile)
+		if err != nil {
+			log.Printf("Configuration reload rejected - %s\n", err.Error())
+			return
+		}
+		next, err := newStreamSettings(nextConfig)
+		if err != nil {
+			log.Printf("Configuration reload rejected - %s\n", err.Error())
+			return
+		}
+		previous := currentSettings().appConfig
+		changes := config.DiffConfigs(previous, nextConfig)
+		if len(changes) == 0 {
+			log.Println("Configuration reloaded - no changes")
+			return
+		}
+		for _, change := range changes {
+			log.Printf("Configuration reloaded - %s\n", change)
+		}
+		if !reflect.DeepEqual(previous.Notifiers, nextConfig.Notifiers) || !reflect.DeepEqual(previous.Subscriptions, nextConfig.Subscriptions) || !reflect.DeepEqual(previous.Outbound, nextConfig.Outbound) {
+			log.Println("Configuration reloaded - notifiers, subscriptions and outbound settings only change on restart")
+		}
+
+		//Stopping the streams removed or whose buffer settings changed before swapping the settings, the others picking them up on their next analysis
+		for siteId, worker := range workers {
+			if stream, found := next.datasets[siteId]; !found || stream.signature != worker.signature {
+				worker.cancel()
+				<-worker.done
+				delete(workers, siteId)
+				mu.Lock()
+				delete(latestReports, siteId)
+				mu.Unlock()
+			}
+		}
+		mu.Lock()
+		settings = next
+		mu.Unlock()
+		for siteId, stream := range next.datasets {
+			if _, found := workers[siteId]; !found {
+				startWorker(siteId, stream)
+			}
+		}
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			for _, worker := range workers {
+				<-worker.done
+			}
+			return
+		case <-hangup:
+			reload()
+		case <-poll:
+			if !fileModTime(*confFile).Equal(modTime) {
+				reload()
+			}
+		}
+	}
+}
+
+//fileModTime returns the modification time of a file, zero if it can't be read
+func fileModTime(fileName string) time.Time {
+	fileInfo, err := os.Stat(fileName)
+	if err != nil {
+		return time.Time{}
+	}
+	return fileInfo.ModTime()
+}
+
+//newStreamEvents returns a copy of the report keeping only the events not notified by previous analyses, recording them as notified
+//Events are identified by their severity, metric, attribute and start, those starting before the analysed period being forgotten
+func newStreamEvents(report analyser.OutlierReport, notified map[string]time.Time) analyser.OutlierReport {
+	for key, start := range notified {
+		if start.Before(report.DateStart) {
+			delete(notified, key)
+		}
+	}
+
+	filter := func(events []analyser.OutlierEvent, severity string) []analyser.OutlierEvent {
+		res := []analyser.OutlierEvent{}
+		for _, event := range events {
+			key := strings.Join([]string{severity, event.Metric, event.Attribute, event.OutlierPeriodStart.String()}, "|")
+			if _, found := notified[key]; found {
+				continue
+			}
+			notified[key] = event.OutlierPeriodStart
+			res = append(res, event)
+		}
+		return res
+	}
+	report.Result = analyser.OutlierResults{Alarms: filter(report.Result.Alarms, "alarm"), Warnings: filter(report.Result.Warnings, "warning")}
+	return report
+}
+
+//writeStreamReports writes the latest report of every streamed site to the given file, ordered by site
+func writeStreamReports(latestReports map[string]analyser.OutlierReport, reportFile string) {
+	siteIds := []string{}
+	for siteId := range latestReports {
+		siteIds = append(siteIds, siteId)
+	}
+	sort.Strings(siteIds)
+	reports := []analyser.OutlierReport{}
+	for _, siteId := range siteIds {
+		reports = append(reports, latestReports[siteId])
+	}
+	utils.WriteJsonStruct(reports, reportFile)
+}
@@ -0,0 +1,134 @@
+package collector
+
+import (
+	"sort"
+	"strings"
+	"time"
+)
+
+//stepAggregator combines the values of several time steps or attribute paths into a single time step
+//Aggregation field is "sum" (values added) or "mean" (values weighted by their samples), time steps without any samples being averaged evenly
+//so that series without samples counts aren't zeroed
+type stepAggregator struct {
+	aggregation string
+	weighted    float64
+	plain       float64
+	count       int
+	samples     int
+}
+
+//newStepAggregator creates an empty stepAggregator for the given aggregation
+func newStepAggregator(aggregation string) *stepAggregator {
+	return &stepAggregator{aggregation: aggregation}
+}
+
+//add accumulates the value and samples of a time step
+func (aggregator *stepAggregator) add(value float64, samples int) {
+	aggregator.weighted += value * float64(samples)
+	aggregator.plain += value
+	aggregator.count++
+	aggregator.samples += samples
+}
+
+//result returns the combined value and the total samples of the accumulated time steps
+func (aggregator *stepAggregator) result() (float64, int) {
+	if aggregator.aggregation != "mean" {
+		return aggregator.plain, aggregator.samples
+	}
+	if aggregator.samples > 0 {
+		return aggregator.weighted / float64(aggregator.samples), aggregator.samples
+	}
+	if aggregator.count > 0 {
+		return aggregator.plain / float64(aggregator.count), 0
+	}
+	return 0, 0
+}
+
+//CombineSteps combines several time steps into one starting at the given date, according to the aggregation
+//Average metrics ("mean") are weighted by samples so that steps with more samples count more, while other metrics ("sum") are added
+func CombineSteps(dateStart time.Time, steps []TimeStepData, aggregation string) TimeStepData {
+	aggregator := newStepAggregator(aggregation)
+	for _, stepData := range steps {
+		aggregator.add(stepData.Value, stepData.Samples)
+	}
+	value, samples := aggregator.result()
+	return TimeStepData{DateStart: dateStart, Value: value, Samples: samples}
+}
+
+//ResampleSeries combines the time steps of a series into longer ones of the given duration, aligned on the given start date
+//Time steps before the start date are ignored and only resampled time steps with data are returned, in date order
+func ResampleSeries(data []TimeStepData, dateStart time.Time, timeStep time.Duration, aggregation string) []TimeStepData {
+	groups := map[int][]TimeStepData{}
+	for _, stepData := range data {
+		if stepData.DateStart.Before(dateStart) {
+			continue
+		}
+		step := int(stepData.DateStart.Sub(dateStart) / timeStep)
+		groups[step] = append(groups[step], stepData)
+	}
+
+	res := []TimeStepData{}
+	for step, group := range groups {
+		res = append(res, CombineSteps(dateStart.Add(time.Duration(step)*timeStep), group, aggregation))
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].DateStart.Before(res[j].DateStart) })
+	return res
+}
+
+//RollupLevels adds the missing parent paths of a metric, e.g. Browser>Chrome from Browser>Chrome>v1 and Browser>Chrome>v2,
+//combining their direct children time step by time step according to the metric type, deepest levels first
+//Top attribute levels are never rolled up into "Total", since each attribute splits it on its own
+func RollupLevels(metricData MetricData) MetricData {
+	res := copyMetricData(metricData)
+
+	//Collecting the children of every missing parent, from the deepest level up so that new parents are rolled up as well
+	children := map[string][]string{}
+	attributes := append([]string{}, res.Attributes...)
+	sort.Slice(attributes, func(i, j int) bool { return res.GetLevel(attributes[i]) > res.GetLevel(attributes[j]) })
+	for i := 0; i < len(attributes); i++ {
+		attribute := attributes[i]
+		if res.GetLevel(attribute) < 2 {
+			continue
+		}
+		parent := attribute[:strings.LastIndex(attribute, ">")]
+		if _, found := res.AttributeData[parent]; found && children[parent] == nil {
+			continue
+		}
+		if children[parent] == nil {
+			attributes = append(attributes, parent)
+		}
+		children[parent] = append(children[parent], attribute)
+	}
+	sort.Slice(attributes, func(i, j int) bool { return res.GetLevel(attributes[i]) > res.GetLevel(attributes[j]) })
+
+	for _, attribute := range attributes {
+		if children[attribute] == nil {
+			continue
+		}
+		groups := map[int64][]TimeStepData{}
+		dates := map[int64]time.Time{}
+		for _, child := range children[attribute] {
+			for _, stepData := range res.AttributeData[child] {
+				key := stepData.DateStart.UnixNano()
+				groups[key] = append(groups[key], stepData)
+				dates[key] = stepData.DateStart
+			}
+		}
+		data := []TimeStepData{}
+		for key, group := range groups {
+			data = append(data, CombineSteps(dates[key], group, res.Aggregation()))
+		}
+		sort.Slice(data, func(i, j int) bool { return data[i].DateStart.Before(data[j].DateStart) })
+		res.AttributeData[attribute] = data
+		res.Attributes = append(res.Attributes, attribute)
+	}
+
+	//Keeping the attributes ordered as collected, "Total" first and then by path
+	sort.SliceStable(res.Attributes, func(i, j int) bool {
+		if res.Attributes[i] == "Total" || res.Attributes[j] == "Total" {
+			return res.Attributes[i] == "Total" && res.Attributes[j] != "Total"
+		}
+		return res.Attributes[i] < res.Attributes[j]
+	})
+	return res
+}
@@ -0,0 +1,116 @@
+package collector
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ftfmtavares/anomalies-detector/config"
+)
+
+func TestCombineSteps(t *testing.T) {
+	timeRef := time.Date(2022, 9, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name        string
+		steps       []TimeStepData
+		aggregation string
+		want        TimeStepData
+	}{
+		{
+			name:        "Sum",
+			steps:       []TimeStepData{{Value: 100, Samples: 3}, {Value: 50, Samples: 1}},
+			aggregation: "sum",
+			want:        TimeStepData{DateStart: timeRef, Value: 150, Samples: 4},
+		},
+		{
+			name:        "Mean weighted by samples",
+			steps:       []TimeStepData{{Value: 100, Samples: 3}, {Value: 500, Samples: 1}},
+			aggregation: "mean",
+			want:        TimeStepData{DateStart: timeRef, Value: 200, Samples: 4},
+		},
+		{
+			name:        "Mean without samples",
+			steps:       []TimeStepData{{Value: 100}, {Value: 500}},
+			aggregation: "mean",
+			want:        TimeStepData{DateStart: timeRef, Value: 300},
+		},
+		{
+			name:        "No steps",
+			aggregation: "mean",
+			want:        TimeStepData{DateStart: timeRef},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CombineSteps(timeRef, tt.steps, tt.aggregation); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("CombineSteps() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResampleSeries(t *testing.T) {
+	timeRef := time.Date(2022, 9, 1, 0, 0, 0, 0, time.UTC)
+	hourly := []TimeStepData{
+		{DateStart: timeRef.Add(-time.Hour), Value: 1000, Samples: 1},
+		{DateStart: timeRef, Value: 400, Samples: 10},
+		{DateStart: timeRef.Add(6 * time.Hour), Value: 100, Samples: 30},
+		{DateStart: timeRef.Add(48 * time.Hour), Value: 250, Samples: 5},
+	}
+
+	got := ResampleSeries(hourly, timeRef, 24*time.Hour, "mean")
+	want := []TimeStepData{
+		{DateStart: timeRef, Value: 175, Samples: 40},
+		{DateStart: timeRef.Add(48 * time.Hour), Value: 250, Samples: 5},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ResampleSeries() mean = %v, want %v", got, want)
+	}
+	if got := ResampleSeries(hourly, timeRef, 24*time.Hour, "sum"); got[0].Value != 500 || got[0].Samples != 40 {
+		t.Errorf("ResampleSeries() sum = %v, want 500 with 40 samples", got[0])
+	}
+}
+
+func TestRollupLevels(t *testing.T) {
+	timeRef := time.Date(2022, 9, 1, 0, 0, 0, 0, time.UTC)
+	step := func(value float64, samples int) []TimeStepData {
+		return []TimeStepData{{DateStart: timeRef, Value: value, Samples: samples}}
+	}
+
+	//Only the leaves of Chrome versions were collected, Edge and the top level being present already
+	metricData := MetricData{Metric: "Basket", Type: config.MetricTypeAverage,
+		Attributes: []string{"Total", "Browser>Chrome>v1>beta", "Browser>Chrome>v1>stable", "Browser>Chrome>v2", "Browser>Edge", "Browser>Edge>v9"},
+		AttributeData: map[string][]TimeStepData{
+			"Total":                    step(300, 40),
+			"Browser>Chrome>v1>beta":   step(100, 10),
+			"Browser>Chrome>v1>stable": step(400, 10),
+			"Browser>Chrome>v2":        step(200, 20),
+			"Browser>Edge":             step(500, 2),
+			"Browser>Edge>v9":          step(450, 2),
+		},
+	}
+
+	got := RollupLevels(metricData)
+	wantAttributes := []string{"Total", "Browser>Chrome", "Browser>Chrome>v1", "Browser>Chrome>v1>beta", "Browser>Chrome>v1>stable", "Browser>Chrome>v2", "Browser>Edge", "Browser>Edge>v9"}
+	if !reflect.DeepEqual(got.Attributes, wantAttributes) {
+		t.Errorf("RollupLevels() attributes = %v, want %v", got.Attributes, wantAttributes)
+	}
+	if want := step(250, 20); !reflect.DeepEqual(got.AttributeData["Browser>Chrome>v1"], want) {
+		t.Errorf("RollupLevels() Browser>Chrome>v1 = %v, want %v", got.AttributeData["Browser>Chrome>v1"], want)
+	}
+	if want := step(225, 40); !reflect.DeepEqual(got.AttributeData["Browser>Chrome"], want) {
+		t.Errorf("RollupLevels() Browser>Chrome = %v, want %v", got.AttributeData["Browser>Chrome"], want)
+	}
+	if want := step(500, 2); !reflect.DeepEqual(got.AttributeData["Browser>Edge"], want) {
+		t.Errorf("RollupLevels() changed the collected Browser>Edge to %v", got.AttributeData["Browser>Edge"])
+	}
+	if _, found := metricData.AttributeData["Browser>Chrome"]; found {
+		t.Errorf("RollupLevels() changed the given metric")
+	}
+
+	//Sum metrics add their children up instead
+	metricData.Type = config.MetricTypeSum
+	if want := step(700, 40); !reflect.DeepEqual(RollupLevels(metricData).AttributeData["Browser>Chrome"], want) {
+		t.Errorf("RollupLevels() sum Browser>Chrome = %v, want %v", RollupLevels(metricData).AttributeData["Browser>Chrome"], want)
+	}
+}
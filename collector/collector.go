@@ -2,29 +2,48 @@ package collector
 
 import (
 	"log"
+	"math"
+	"math/rand"
+	"sort"
 	"strings"
 	"time"
 
+	"github.com/ftfmtavares/anomalies-detector/collector/synthetic"
 	"github.com/ftfmtavares/anomalies-detector/config"
 	"github.com/ftfmtavares/anomalies-detector/utils"
 )
 
+//Clock and RandSource are the time and randomness sources of the collection, the period end being read from the former and the generator seeds of the datasets without their own drawn from the latter
+//Tests replace them with a fixed clock and a seeded source to make the collected data deterministic
+var (
+	Clock      utils.Clock = utils.SystemClock
+	RandSource rand.Source = utils.NewLockedSource(rand.NewSource(time.Now().UnixNano()))
+)
+
 //SiteData provides the structure to store all the collected data of a given site
+//Archived field marks the stored data of an archived site, served as it was last collected
 type SiteData struct {
 	SiteId    string       `json:"siteId"`
+	TimeStep  string       `json:"timeStep"`
 	DateStart time.Time    `json:"dateStart"`
 	DateEnd   time.Time    `json:"dateEnd"`
 	Metrics   []MetricData `json:"metrics"`
+	Archived  bool         `json:"archived,omitempty"`
 }
 
 //MetricData contains all collected data for each metric of a given site
 //Attributes field contains an ordered list of all attributes and sub-values combinations
 //AttributeData field is a map that points to a slice of TimeStepData of the respective attribute/sub-values combination
+//Type field is the metric type declared on the config metric registry, empty for undeclared metrics, as is AlertDirection field
+//Injected field lists the deviations injected into generated data, its ground truth, being empty for collected data
 type MetricData struct {
-	Metric        string                    `json:"metric"`
-	Unit          string                    `json:"unit"`
-	Attributes    []string                  `json:"attributes"`
-	AttributeData map[string][]TimeStepData `json:"attributeData"`
+	Metric         string                    `json:"metric"`
+	Unit           string                    `json:"unit"`
+	Type           string                    `json:"type,omitempty"`
+	AlertDirection string                    `json:"alertDirection,omitempty"`
+	Attributes     []string                  `json:"attributes"`
+	AttributeData  map[string][]TimeStepData `json:"attributeData"`
+	Injected       []synthetic.Outlier       `json:"injected,omitempty"`
 }
 
 //GetSamplesCount is a method of MetricData that returns the total samples count of a given attribute/sub-values combination
@@ -65,36 +84,98 @@ func (metricData MetricData) GetRank(attribute string) int {
 	return rank
 }
 
+//Aggregation is a method of MetricData that returns how its values combine according to its type, "mean" weighted by samples or "sum"
+func (metricData MetricData) Aggregation() string {
+	return config.MetricDefinition{Type: metricData.Type}.Aggregation()
+}
+
 //TimeStepData represents the data of a single time step
+//Filled field flags time steps missing from the collected data whose value was filled by the dataset gap strategy, and Missing field
+//those left without a value (0) by the "mark-missing" strategy, which the detection leaves out
 type TimeStepData struct {
 	DateStart time.Time `json:"dateStart"`
 	Value     float64   `json:"value"`
 	Samples   int       `json:"samples"`
+	Filled    bool      `json:"filled,omitempty"`
+	Missing   bool      `json:"missing,omitempty"`
 }
 
 //GetData takes a site configuration and returns the respective data
 func GetData(dataSet config.Dataset) SiteData {
 
-	//Converting time periods in string format to be used as time.Duration
-	timeAgoDuration, err := utils.StrToDuration(dataSet.TimeAgo)
+	//Converting time periods in string format to be used as time steps, those of datasets with a time zone moving by calendar days
+	timeAgo, err := utils.ParseTimeStep(dataSet.TimeAgo)
 	if err != nil {
 		log.Panic(err)
 	}
-	timeStepDuration, err := utils.StrToDuration(dataSet.TimeStep)
+	timeStep, err := utils.ParseTimeStep(dataSet.TimeStep)
 	if err != nil {
 		log.Panic(err)
 	}
+	location, err := dataSet.Location()
+	if err != nil {
+		log.Panic(err)
+	}
+	if dataSet.Timezone != "" {
+		timeAgo.Calendar, timeStep.Calendar = true, true
+	}
+	timeAgoDuration, timeStepDuration := timeAgo.Duration, timeStep.Duration
 
 	//Initializing the siteData object to be returned
-	siteData := SiteData{SiteId: dataSet.SiteId}
-	siteData.DateEnd = time.Now()
+	siteData := SiteData{SiteId: dataSet.SiteId, TimeStep: dataSet.TimeStep}
+	siteData.DateEnd = Clock.Now()
 	siteData.DateStart = siteData.DateEnd.Add(-1 * timeAgoDuration)
 	siteData.Metrics = []MetricData{}
 
+	//Collecting from a CSV file, a database, Prometheus, a JSON HTTP API or Google Analytics when configured, generating the data otherwise
+	var collection sourceCollection
+	if source := datasetSource(dataSet); source != nil {
+		if source.open == nil {
+			log.Printf("Skipping %s - %s\n", dataSet.SiteId, utils.CheckIntegration(source.name).Error())
+			return siteData
+		}
+		if collection, err = source.open(dataSet, &siteData, timeAgoDuration, timeStepDuration); err != nil {
+			log.Printf("Skipping %s - %s\n", dataSet.SiteId, err.Error())
+			return siteData
+		}
+		if collection.close != nil {
+			defer collection.close()
+		}
+	} else {
+
+		//Picking the run seed from which all generated series derive, unless fixed for the site, logged so the run can be reproduced
+		seed := dataSet.Seed
+		if seed == 0 {
+			seed = RandSource.Int63()
+		}
+		//The simulation profile of the site, if any, replaces the default metrics, attributes and outliers
+		log.Printf("Generator Seed - %s - %d\n", dataSet.SiteId, seed)
+		options := synthetic.DefaultOptions(seed)
+		if dataSet.Simulation != nil {
+			options = synthetic.ProfileOptions(seed, *dataSet.Simulation)
+		}
+		generator := synthetic.New(options)
+		collection.metrics = generator.MetricNames()
+		collection.getMetric = func(metric string) (MetricData, error) {
+			generated, err := generator.GenerateSteps(metric, siteData.DateStart, siteData.DateEnd, timeStep)
+			if err != nil {
+				return MetricData{}, err
+			}
+			return fromSynthetic(generated), nil
+		}
+	}
+
+	//Calendar time steps, as well as those of datasets with a time zone, have their period set in the dataset time zone, daily, weekly and monthly ones
+	//ending at the start of the ongoing calendar day, week or month and starting whole calendar periods before, so they don't drift across daylight saving changes
+	if timeStep.Calendar {
+		siteData.DateEnd = timeStep.Align(siteData.DateEnd.In(location))
+		siteData.DateStart = timeStep.Align(timeAgo.Next(siteData.DateEnd, -1))
+	}
+
 	//If the configured metric is "all", a list with all supported metrics will be used instead
 	var coveredMetrics []string
 	if len(dataSet.MetricesList) > 0 && strings.ToLower(dataSet.MetricesList[0]) == "all" {
-		coveredMetrics = allMetrices
+		coveredMetrics = collection.metrics
 	} else {
 		coveredMetrics = dataSet.MetricesList
 	}
@@ -103,24 +184,142 @@ func GetData(dataSet config.Dataset) SiteData {
 	for _, metric := range coveredMetrics {
 		log.Printf("Getting Data - %s - %s\n", dataSet.SiteId, metric)
 
-		//Since there is no access to the repository at this stage, data generation methods are used instead
 		//Attribute filters would be applied while accessing and reading the repository but for now, they are applied in a separate call
-		metricData := generateData(metric, siteData.DateStart, siteData.DateEnd, timeStepDuration)
+		metricData, err := collection.getMetric(metric)
+		if err != nil {
+			log.Printf("Skipping %s - %s - %s\n", dataSet.SiteId, metric, err.Error())
+			continue
+		}
 		metricData = filterData(metricData, *dataSet.SiteCollectFilters)
 
 		//Adds the read metric data to the result
 		siteData.Metrics = append(siteData.Metrics, metricData)
 	}
 
+	if dataSet.Timezone != "" {
+		inLocation(&siteData, location)
+	}
+	return siteData
+}
+
+//inLocation sets all the times of the collected data of a site in the given location, so they are reported in the time zone of the dataset
+func inLocation(siteData *SiteData, location *time.Location) {
+	siteData.DateStart = siteData.DateStart.In(location)
+	siteData.DateEnd = siteData.DateEnd.In(location)
+	for _, metricData := range siteData.Metrics {
+		for _, data := range metricData.AttributeData {
+			for ind := range data {
+				data[ind].DateStart = data[ind].DateStart.In(location)
+			}
+		}
+		for ind := range metricData.Injected {
+			metricData.Injected[ind].PeriodStart = metricData.Injected[ind].PeriodStart.In(location)
+			metricData.Injected[ind].PeriodEnd = metricData.Injected[ind].PeriodEnd.In(location)
+		}
+	}
+}
+
+//ApplyMetricDefinitions sets the declared type of every collected metric, as well as its declared unit if the backend didn't give one
+//Cumulative metrics are differenced into the value of each time step, on a copy so the collected data is left unchanged
+func ApplyMetricDefinitions(siteData SiteData, registry map[string]config.MetricDefinition) SiteData {
+	metrics := make([]MetricData, len(siteData.Metrics))
+	for i, metricData := range siteData.Metrics {
+		if definition, found := registry[metricData.Metric]; found {
+			metricData.Type = definition.Type
+			metricData.AlertDirection = definition.AlertDirection
+			if metricData.Unit == "" {
+				metricData.Unit = definition.Unit
+			}
+			if definition.Cumulative != "" {
+				metricData = copyMetricData(metricData)
+				for attribute, data := range metricData.AttributeData {
+					metricData.AttributeData[attribute] = differenceSeries(data, definition.Cumulative)
+				}
+			}
+		}
+		metrics[i] = metricData
+	}
+	siteData.Metrics = metrics
 	return siteData
 }
 
+//differenceSeries converts the running totals of a cumulative series into the value of each time step, the increase since the previous one
+//Time steps after a reset keep their running total, resets being the decreases of the total and, for daily totals, the change of day between steps
+//The first time step is dropped, as its increase is unknown, unless it's the first one of a day of daily totals
+func differenceSeries(data []TimeStepData, cumulative string) []TimeStepData {
+	res := make([]TimeStepData, 0, len(data))
+	for ind, stepData := range data {
+		reset := false
+		if cumulative == config.CumulativeDaily {
+			if ind == 0 {
+				reset = isMidnight(stepData.DateStart)
+			} else {
+				reset = !sameDay(data[ind-1].DateStart, stepData.DateStart)
+			}
+		}
+		switch {
+		case reset:
+		case ind == 0:
+			continue
+		case stepData.Value >= data[ind-1].Value:
+			stepData.Value -= data[ind-1].Value
+		}
+		res = append(res, stepData)
+	}
+	return res
+}
+
+//isMidnight checks if a time is the start of a day in its own location
+func isMidnight(value time.Time) bool {
+	return value.Hour() == 0 && value.Minute() == 0 && value.Second() == 0 && value.Nanosecond() == 0
+}
+
+//sameDay checks if two times are on the same day, in the location of the first one
+func sameDay(a, b time.Time) bool {
+	b = b.In(a.Location())
+	return a.Year() == b.Year() && a.YearDay() == b.YearDay()
+}
+
+//fromSynthetic converts a generated metric into the collector MetricData structure
+func fromSynthetic(generated synthetic.Metric) MetricData {
+	metricData := MetricData{Metric: generated.Metric, Unit: generated.Unit, Attributes: generated.Attributes, AttributeData: map[string][]TimeStepData{}, Injected: generated.Outliers}
+	for attribute, steps := range generated.AttributeData {
+		data := make([]TimeStepData, len(steps))
+		for i, step := range steps {
+			data[i] = TimeStepData{DateStart: step.DateStart, Value: step.Value, Samples: step.Samples}
+		}
+		metricData.AttributeData[attribute] = data
+	}
+	return metricData
+}
+
+//metricDataFromSeries builds a MetricData from the time steps of each attribute path, sorting them by date
+//Attributes are ordered with "Total" first, if present, followed by the other paths in alphabetical order
+func metricDataFromSeries(metric, unit string, series map[string][]TimeStepData) MetricData {
+	metricData := MetricData{Metric: metric, Unit: unit, Attributes: []string{}, AttributeData: map[string][]TimeStepData{}}
+	for attribute, data := range series {
+		sort.Slice(data, func(i, j int) bool { return data[i].DateStart.Before(data[j].DateStart) })
+		metricData.AttributeData[attribute] = data
+		if attribute != "Total" {
+			metricData.Attributes = append(metricData.Attributes, attribute)
+		}
+	}
+	sort.Strings(metricData.Attributes)
+	if _, found := series["Total"]; found {
+		metricData.Attributes = append([]string{"Total"}, metricData.Attributes...)
+	}
+	return metricData
+}
+
 //filterData checks data from all attribute/sub-values combinations and removes those that don't meet the configured filters
 func filterData(metricData MetricData, collectFilters config.CollectFilters) MetricData {
 
 	//Calculating total minimum samples for the given period
 	minSamples := collectFilters.MinVisitorsPerTimeStep * len(metricData.AttributeData["Total"])
 
+	//Calculating minimum samples relative to the total samples, which doesn't need retuning for each site size
+	minRelativeSamples := int(math.Ceil(collectFilters.MinSamplesPercentage / 100 * float64(metricData.GetSamplesCount("Total"))))
+
 	//Initializing a slice to hold the removal indication of each data set
 	toRemove := make([]bool, len(metricData.Attributes))
 
@@ -152,6 +351,12 @@ func filterData(metricData MetricData, collectFilters config.CollectFilters) Met
 			log.Printf("Filtering %s - Samples %d less than min %d\n", attribute, samples, minSamples)
 			toRemove[ind] = true
 		}
+
+		//Comparing the number of samples with the relative minimum
+		if samples < minRelativeSamples {
+			log.Printf("Filtering %s - Samples %d less than %.2f%% of total samples\n", attribute, samples, collectFilters.MinSamplesPercentage)
+			toRemove[ind] = true
+		}
 	}
 
 	//Removing all identified datasets from the list
@@ -0,0 +1,406 @@
+package collector
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+	"time"
+	_ "time/tzdata"
+
+	"github.com/ftfmtavares/anomalies-detector/config"
+	"github.com/ftfmtavares/anomalies-detector/utils"
+)
+
+func Test_filterData(t *testing.T) {
+	type args struct {
+		metricData     MetricData
+		collectFilters config.CollectFilters
+	}
+
+	timeRef := time.Now()
+
+	tests := []struct {
+		name string
+		args args
+		want MetricData
+	}{
+		{
+			name: "Filter by minimum samples",
+			args: args{
+				metricData: MetricData{
+					Metric:     "metric",
+					Unit:       "unit",
+					Attributes: []string{"Total", "Attribute1>Sub1", "Attribute1>Sub1>Sub1", "Attribute1>Sub1>Sub2", "Attribute1>Sub2", "Attribute2>Sub1", "Attribute2>Sub2"},
+					AttributeData: map[string][]TimeStepData{
+						"Total":                {{DateStart: timeRef, Value: 10, Samples: 100}},
+						"Attribute1>Sub1":      {{DateStart: timeRef, Value: 10, Samples: 80}},
+						"Attribute1>Sub1>Sub1": {{DateStart: timeRef, Value: 10, Samples: 50}},
+						"Attribute1>Sub1>Sub2": {{DateStart: timeRef, Value: 10, Samples: 30}},
+						"Attribute1>Sub2":      {{DateStart: timeRef, Value: 10, Samples: 20}},
+						"Attribute2>Sub1":      {{DateStart: timeRef, Value: 10, Samples: 60}},
+						"Attribute2>Sub2":      {{DateStart: timeRef, Value: 10, Samples: 40}},
+					},
+				},
+				collectFilters: config.CollectFilters{
+					MinVisitorsPerTimeStep: 90,
+					AttributesFilterParams: map[string]config.FilterParams{},
+				},
+			},
+			want: MetricData{
+				Metric:     "metric",
+				Unit:       "unit",
+				Attributes: []string{"Total"},
+				AttributeData: map[string][]TimeStepData{
+					"Total": {{DateStart: timeRef, Value: 10, Samples: 100}},
+				},
+			},
+		},
+		{
+			name: "Filter by minimum percentage of total samples",
+			args: args{
+				metricData: MetricData{
+					Metric:     "metric",
+					Unit:       "unit",
+					Attributes: []string{"Total", "Attribute1>Sub1", "Attribute1>Sub1>Sub1", "Attribute1>Sub1>Sub2", "Attribute1>Sub2", "Attribute2>Sub1", "Attribute2>Sub2"},
+					AttributeData: map[string][]TimeStepData{
+						"Total":                {{DateStart: timeRef, Value: 10, Samples: 100}},
+						"Attribute1>Sub1":      {{DateStart: timeRef, Value: 10, Samples: 80}},
+						"Attribute1>Sub1>Sub1": {{DateStart: timeRef, Value: 10, Samples: 50}},
+						"Attribute1>Sub1>Sub2": {{DateStart: timeRef, Value: 10, Samples: 30}},
+						"Attribute1>Sub2":      {{DateStart: timeRef, Value: 10, Samples: 20}},
+						"Attribute2>Sub1":      {{DateStart: timeRef, Value: 10, Samples: 60}},
+						"Attribute2>Sub2":      {{DateStart: timeRef, Value: 10, Samples: 40}},
+					},
+				},
+				collectFilters: config.CollectFilters{
+					MinSamplesPercentage:   50,
+					AttributesFilterParams: map[string]config.FilterParams{},
+				},
+			},
+			want: MetricData{
+				Metric:     "metric",
+				Unit:       "unit",
+				Attributes: []string{"Total", "Attribute1>Sub1", "Attribute1>Sub1>Sub1", "Attribute2>Sub1"},
+				AttributeData: map[string][]TimeStepData{
+					"Total":                {{DateStart: timeRef, Value: 10, Samples: 100}},
+					"Attribute1>Sub1":      {{DateStart: timeRef, Value: 10, Samples: 80}},
+					"Attribute1>Sub1>Sub1": {{DateStart: timeRef, Value: 10, Samples: 50}},
+					"Attribute2>Sub1":      {{DateStart: timeRef, Value: 10, Samples: 60}},
+				},
+			},
+		},
+		{
+			name: "Filter by limiting level of a given attribute",
+			args: args{
+				metricData: MetricData{
+					Metric:     "metric",
+					Unit:       "unit",
+					Attributes: []string{"Total", "Attribute1>Sub1", "Attribute1>Sub1>Sub1", "Attribute1>Sub1>Sub2", "Attribute1>Sub2", "Attribute2>Sub1", "Attribute2>Sub2"},
+					AttributeData: map[string][]TimeStepData{
+						"Total":                {{DateStart: timeRef, Value: 10, Samples: 100}},
+						"Attribute1>Sub1":      {{DateStart: timeRef, Value: 10, Samples: 80}},
+						"Attribute1>Sub1>Sub1": {{DateStart: timeRef, Value: 10, Samples: 50}},
+						"Attribute1>Sub1>Sub2": {{DateStart: timeRef, Value: 10, Samples: 30}},
+						"Attribute1>Sub2":      {{DateStart: timeRef, Value: 10, Samples: 20}},
+						"Attribute2>Sub1":      {{DateStart: timeRef, Value: 10, Samples: 60}},
+						"Attribute2>Sub2":      {{DateStart: timeRef, Value: 10, Samples: 40}},
+					},
+				},
+				collectFilters: config.CollectFilters{
+					MinVisitorsPerTimeStep: 10,
+					AttributesFilterParams: map[string]config.FilterParams{
+						"Attribute1": {Level: 1, Top: 0},
+					},
+				},
+			},
+			want: MetricData{
+				Metric:     "metric",
+				Unit:       "unit",
+				Attributes: []string{"Total", "Attribute1>Sub1", "Attribute1>Sub2", "Attribute2>Sub1", "Attribute2>Sub2"},
+				AttributeData: map[string][]TimeStepData{
+					"Total":           {{DateStart: timeRef, Value: 10, Samples: 100}},
+					"Attribute1>Sub1": {{DateStart: timeRef, Value: 10, Samples: 80}},
+					"Attribute1>Sub2": {{DateStart: timeRef, Value: 10, Samples: 20}},
+					"Attribute2>Sub1": {{DateStart: timeRef, Value: 10, Samples: 60}},
+					"Attribute2>Sub2": {{DateStart: timeRef, Value: 10, Samples: 40}},
+				},
+			},
+		},
+		{
+			name: "Filter by filtering top values of given attributes",
+			args: args{
+				metricData: MetricData{
+					Metric:     "metric",
+					Unit:       "unit",
+					Attributes: []string{"Total", "Attribute1>Sub1", "Attribute1>Sub1>Sub1", "Attribute1>Sub1>Sub2", "Attribute1>Sub2", "Attribute2>Sub1", "Attribute2>Sub2"},
+					AttributeData: map[string][]TimeStepData{
+						"Total":                {{DateStart: timeRef, Value: 10, Samples: 100}},
+						"Attribute1>Sub1":      {{DateStart: timeRef, Value: 10, Samples: 80}},
+						"Attribute1>Sub1>Sub1": {{DateStart: timeRef, Value: 10, Samples: 50}},
+						"Attribute1>Sub1>Sub2": {{DateStart: timeRef, Value: 10, Samples: 30}},
+						"Attribute1>Sub2":      {{DateStart: timeRef, Value: 10, Samples: 20}},
+						"Attribute2>Sub1":      {{DateStart: timeRef, Value: 10, Samples: 60}},
+						"Attribute2>Sub2":      {{DateStart: timeRef, Value: 10, Samples: 40}},
+					},
+				},
+				collectFilters: config.CollectFilters{
+					MinVisitorsPerTimeStep: 10,
+					AttributesFilterParams: map[string]config.FilterParams{
+						"Attribute1": {Level: 2, Top: 1},
+						"Attribute2": {Level: 1, Top: 1},
+					},
+				},
+			},
+			want: MetricData{
+				Metric:     "metric",
+				Unit:       "unit",
+				Attributes: []string{"Total", "Attribute1>Sub1", "Attribute1>Sub1>Sub1", "Attribute1>Sub2", "Attribute2>Sub1"},
+				AttributeData: map[string][]TimeStepData{
+					"Total":                {{DateStart: timeRef, Value: 10, Samples: 100}},
+					"Attribute1>Sub1":      {{DateStart: timeRef, Value: 10, Samples: 80}},
+					"Attribute1>Sub1>Sub1": {{DateStart: timeRef, Value: 10, Samples: 50}},
+					"Attribute1>Sub2":      {{DateStart: timeRef, Value: 10, Samples: 20}},
+					"Attribute2>Sub1":      {{DateStart: timeRef, Value: 10, Samples: 60}},
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := filterData(tt.args.metricData, tt.args.collectFilters); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("filterData() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyMetricDefinitions(t *testing.T) {
+	timeRef := time.Date(2022, 9, 1, 0, 0, 0, 0, time.UTC)
+	appConfig := config.ApplicationConfig{Metrics: []config.MetricDefinition{{Name: "Latency", Unit: "ms", Type: config.MetricTypeAverage}, {Name: "Orders", Type: config.MetricTypeCount, AlertDirection: config.DirectionDrop, Cumulative: config.CumulativeCounter}}}
+	registry, err := appConfig.MetricRegistry()
+	if err != nil {
+		t.Fatalf("MetricRegistry() error = %v", err)
+	}
+
+	siteData := SiteData{SiteId: "shop", Metrics: []MetricData{
+		{Metric: "Latency", AttributeData: map[string][]TimeStepData{"Total": {{DateStart: timeRef, Value: 100, Samples: 4}}}},
+		{Metric: "Revenue", Unit: "$"},
+		{Metric: "Errors"},
+		{Metric: "Orders", Attributes: []string{"Total"}, AttributeData: map[string][]TimeStepData{"Total": {{DateStart: timeRef, Value: 10, Samples: 1}, {DateStart: timeRef.Add(time.Hour), Value: 25, Samples: 1}}}},
+	}}
+	got := ApplyMetricDefinitions(siteData, registry)
+	want := []MetricData{
+		{Metric: "Latency", Unit: "ms", Type: config.MetricTypeAverage, AttributeData: map[string][]TimeStepData{"Total": {{DateStart: timeRef, Value: 100, Samples: 4}}}},
+		{Metric: "Revenue", Unit: "$", Type: config.MetricTypeSum},
+		{Metric: "Errors"},
+		{Metric: "Orders", Type: config.MetricTypeCount, AlertDirection: config.DirectionDrop, Attributes: []string{"Total"}, AttributeData: map[string][]TimeStepData{"Total": {{DateStart: timeRef.Add(time.Hour), Value: 15, Samples: 1}}}},
+	}
+	if !reflect.DeepEqual(got.Metrics, want) {
+		t.Errorf("ApplyMetricDefinitions() = %v, want %v", got.Metrics, want)
+	}
+	if siteData.Metrics[0].Type != "" || len(siteData.Metrics[3].AttributeData["Total"]) != 2 {
+		t.Errorf("ApplyMetricDefinitions() changed the collected data")
+	}
+
+	//Merges without aggregation follow the metric type, Average metrics being combined by their samples weighted mean
+	got.Metrics[0].Attributes = []string{"Total", "Region>North", "Region>South"}
+	got.Metrics[0].AttributeData["Region>North"] = []TimeStepData{{DateStart: timeRef, Value: 50, Samples: 3}}
+	got.Metrics[0].AttributeData["Region>South"] = []TimeStepData{{DateStart: timeRef, Value: 250, Samples: 1}}
+	transforms, err := CompileTransforms([]config.Transform{{Type: "merge", Attributes: []string{"Region>North", "Region>South"}, Into: "Region>All"}})
+	if err != nil {
+		t.Fatalf("CompileTransforms() error = %v", err)
+	}
+	merged := ApplyTransforms(got, transforms)
+	if wantMerged := []TimeStepData{{DateStart: timeRef, Value: 100, Samples: 4}}; !reflect.DeepEqual(merged.Metrics[0].AttributeData["Region>All"], wantMerged) {
+		t.Errorf("ApplyTransforms() merged = %v, want %v", merged.Metrics[0].AttributeData["Region>All"], wantMerged)
+	}
+
+	appConfig.Metrics = append(appConfig.Metrics, config.MetricDefinition{Name: "Orders", Type: "Median"})
+	if _, err := appConfig.MetricRegistry(); err == nil {
+		t.Errorf("MetricRegistry() expected an error for unknown types")
+	}
+}
+
+func Test_differenceSeries(t *testing.T) {
+	timeRef := time.Date(2022, 9, 1, 0, 0, 0, 0, time.UTC)
+	series := func(start time.Time, step time.Duration, values ...float64) []TimeStepData {
+		data := []TimeStepData{}
+		for i, value := range values {
+			data = append(data, TimeStepData{DateStart: start.Add(time.Duration(i) * step), Value: value, Samples: 1})
+		}
+		return data
+	}
+
+	tests := []struct {
+		name       string
+		data       []TimeStepData
+		cumulative string
+		want       []TimeStepData
+	}{
+		{
+			name:       "Daily totals reset at midnight",
+			data:       series(timeRef.Add(-16*time.Hour), 8*time.Hour, 10, 25, 5, 20, 35),
+			cumulative: config.CumulativeDaily,
+			want:       series(timeRef.Add(-8*time.Hour), 8*time.Hour, 15, 5, 15, 15),
+		},
+		{
+			name:       "Daily totals starting at midnight",
+			data:       series(timeRef, 12*time.Hour, 10, 30, 20),
+			cumulative: config.CumulativeDaily,
+			want:       series(timeRef, 12*time.Hour, 10, 20, 20),
+		},
+		{
+			name:       "Daily totals at daily steps",
+			data:       series(timeRef, 24*time.Hour, 100, 80, 120),
+			cumulative: config.CumulativeDaily,
+			want:       series(timeRef, 24*time.Hour, 100, 80, 120),
+		},
+		{
+			name:       "Counter restarted",
+			data:       series(timeRef, time.Hour, 100, 130, 160, 20, 50),
+			cumulative: config.CumulativeCounter,
+			want:       series(timeRef.Add(time.Hour), time.Hour, 30, 30, 20, 30),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := differenceSeries(tt.data, tt.cumulative); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("differenceSeries() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetDataDeterministic(t *testing.T) {
+	defer func(clock utils.Clock, source rand.Source) { Clock, RandSource = clock, source }(Clock, RandSource)
+
+	dataSet := config.Dataset{SiteId: "site1", TimeAgo: "3d", TimeStep: "1h", MetricesList: []string{"all"}, SiteCollectFilters: &config.CollectFilters{}}
+	now := time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC)
+	collect := func() SiteData {
+		Clock = utils.FixedClock(now)
+		RandSource = rand.NewSource(42)
+		return GetData(dataSet)
+	}
+
+	first := collect()
+	if !first.DateEnd.Equal(now) || !first.DateStart.Equal(now.Add(-72*time.Hour)) {
+		t.Errorf("GetData() period = %v - %v, want %v - %v", first.DateStart, first.DateEnd, now.Add(-72*time.Hour), now)
+	}
+	if len(first.Metrics) == 0 {
+		t.Fatalf("GetData() returned no metrics")
+	}
+	if second := collect(); !reflect.DeepEqual(first, second) {
+		t.Errorf("GetData() isn't deterministic with a fixed clock and a seeded source")
+	}
+}
+
+func TestGetDataTimezone(t *testing.T) {
+	defer func(clock utils.Clock) { Clock = clock }(Clock)
+	lisbon, err := time.LoadLocation("Europe/Lisbon")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	//Clocks went forward on the 27th of March 2022 in Lisbon, daily steps still starting at midnight after it
+	Clock = utils.FixedClock(time.Date(2022, 3, 29, 15, 30, 0, 0, time.UTC))
+	dataSet := config.Dataset{SiteId: "site1", TimeAgo: "7d", TimeStep: "1d", MetricesList: []string{"Revenue"}, SiteCollectFilters: &config.CollectFilters{}, Seed: 1, Timezone: "Europe/Lisbon"}
+	got := GetData(dataSet)
+
+	wantStart, wantEnd := time.Date(2022, 3, 22, 0, 0, 0, 0, lisbon), time.Date(2022, 3, 29, 0, 0, 0, 0, lisbon)
+	if !got.DateStart.Equal(wantStart) || !got.DateEnd.Equal(wantEnd) || got.DateEnd.Location().String() != "Europe/Lisbon" {
+		t.Errorf("GetData() period = %v - %v, want %v - %v", got.DateStart, got.DateEnd, wantStart, wantEnd)
+	}
+	if len(got.Metrics) != 1 {
+		t.Fatalf("GetData() returned %d metrics, want 1", len(got.Metrics))
+	}
+	data := got.Metrics[0].AttributeData["Total"]
+	if len(data) != 7 {
+		t.Fatalf("GetData() returned %d time steps, want 7", len(data))
+	}
+	for ind, stepData := range data {
+		if want := wantStart.AddDate(0, 0, ind); !stepData.DateStart.Equal(want) || stepData.DateStart.Hour() != 0 {
+			t.Errorf("GetData() time step #%d = %v, want %v", ind, stepData.DateStart, want)
+		}
+	}
+}
+
+func TestGetDataCalendar(t *testing.T) {
+	defer func(clock utils.Clock) { Clock = clock }(Clock)
+	lisbon, err := time.LoadLocation("Europe/Lisbon")
+	if err != nil {
+		t.Fatal(err)
+	}
+	Clock = utils.FixedClock(time.Date(2022, 3, 30, 15, 30, 0, 0, time.UTC))
+
+	tests := []struct {
+		name      string
+		timeAgo   string
+		timeStep  string
+		wantStart time.Time
+		wantSteps []time.Time
+	}{
+		{
+			name:      "Weekly buckets starting on Mondays",
+			timeAgo:   "3w",
+			timeStep:  "1w-calendar",
+			wantStart: time.Date(2022, 3, 7, 0, 0, 0, 0, lisbon),
+			wantSteps: []time.Time{time.Date(2022, 3, 7, 0, 0, 0, 0, lisbon), time.Date(2022, 3, 14, 0, 0, 0, 0, lisbon), time.Date(2022, 3, 21, 0, 0, 0, 0, lisbon)},
+		},
+		{
+			name:      "Monthly buckets starting on the first day of the month",
+			timeAgo:   "3mo",
+			timeStep:  "1mo",
+			wantStart: time.Date(2021, 12, 1, 0, 0, 0, 0, lisbon),
+			wantSteps: []time.Time{time.Date(2021, 12, 1, 0, 0, 0, 0, lisbon), time.Date(2022, 1, 1, 0, 0, 0, 0, lisbon), time.Date(2022, 2, 1, 0, 0, 0, 0, lisbon)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dataSet := config.Dataset{SiteId: "site1", TimeAgo: tt.timeAgo, TimeStep: tt.timeStep, MetricesList: []string{"Revenue"}, SiteCollectFilters: &config.CollectFilters{}, Seed: 1, Timezone: "Europe/Lisbon"}
+			got := GetData(dataSet)
+			if !got.DateStart.Equal(tt.wantStart) {
+				t.Errorf("GetData() period start = %v, want %v", got.DateStart, tt.wantStart)
+			}
+			if len(got.Metrics) != 1 {
+				t.Fatalf("GetData() returned %d metrics, want 1", len(got.Metrics))
+			}
+			steps := []time.Time{}
+			for _, stepData := range got.Metrics[0].AttributeData["Total"] {
+				steps = append(steps, stepData.DateStart)
+			}
+			if len(steps) != len(tt.wantSteps) {
+				t.Fatalf("GetData() time steps = %v, want %v", steps, tt.wantSteps)
+			}
+			for ind := range steps {
+				if !steps[ind].Equal(tt.wantSteps[ind]) {
+					t.Errorf("GetData() time step #%d = %v, want %v", ind, steps[ind], tt.wantSteps[ind])
+				}
+			}
+		})
+	}
+}
+
+func TestGetDataSimulation(t *testing.T) {
+	defer func(clock utils.Clock) { Clock = clock }(Clock)
+	Clock = utils.FixedClock(time.Date(2024, 3, 10, 0, 0, 0, 0, time.UTC))
+
+	simulation := &config.SimulationParams{
+		Metrics:    []config.SimulatedMetric{{Name: "Signups", Unit: "Accounts", Type: config.MetricTypeCount, Mean: 300, StdDev: 30}},
+		Attributes: []config.SimulatedAttribute{{Name: "Country", SubAttributes: []config.SimulatedAttribute{{Name: "PT", Weight: 70}, {Name: "ES", Weight: 30}}}},
+	}
+	appConfig := config.ApplicationConfig{Simulation: simulation}
+	registry, err := appConfig.MetricRegistry()
+	if err != nil {
+		t.Fatalf("MetricRegistry() error = %v", err)
+	}
+
+	//The simulated metrics and attributes replace the default ones, their types being registered as declared ones
+	dataSet := config.Dataset{SiteId: "site1", TimeAgo: "7d", TimeStep: "1d", MetricesList: []string{"all"}, SiteCollectFilters: &config.CollectFilters{}, Simulation: simulation, Seed: 1}
+	got := ApplyMetricDefinitions(GetData(dataSet), registry)
+	if len(got.Metrics) != 1 || got.Metrics[0].Metric != "Signups" || got.Metrics[0].Unit != "Accounts" || got.Metrics[0].Type != config.MetricTypeCount {
+		t.Fatalf("GetData() metrics = %+v, want the simulated Signups", got.Metrics)
+	}
+	if want := []string{"Total", "Country>PT", "Country>ES"}; !reflect.DeepEqual(got.Metrics[0].Attributes, want) {
+		t.Errorf("GetData() attributes = %v, want %v", got.Metrics[0].Attributes, want)
+	}
+}
@@ -0,0 +1,213 @@
+package collector
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ftfmtavares/anomalies-detector/config"
+	"github.com/ftfmtavares/anomalies-detector/utils"
+)
+
+//csvTable holds the rows loaded from a CSV file along with the latest timestamp found
+type csvTable struct {
+	rows        []csvRow
+	latest      time.Time
+	aggregation string
+	units       map[string]string
+}
+
+//csvRow represents a single row of a CSV file
+type csvRow struct {
+	metric    string
+	attribute string
+	timestamp time.Time
+	value     float64
+	samples   int
+}
+
+//loadCsv reads all rows of a CSV file according to the configured column mapping
+//It returns an error if the file can't be read, if a mapped column is missing or if any row holds an invalid timestamp, value or samples count
+func loadCsv(params config.CsvParams) (csvTable, error) {
+	table := csvTable{rows: []csvRow{}, aggregation: params.Aggregation, units: params.Units}
+	if table.aggregation == "" {
+		table.aggregation = "sum"
+	}
+	if table.aggregation != "sum" && table.aggregation != "mean" {
+		return table, fmt.Errorf("csv - invalid aggregation \"%s\", it must be \"sum\" or \"mean\"", params.Aggregation)
+	}
+	if params.TimestampColumn == "" || params.ValueColumn == "" || (params.MetricColumn == "" && params.Metric == "") {
+		return table, errors.New("csv - timestampColumn, valueColumn and either metricColumn or metric are required")
+	}
+
+	file, err := os.Open(params.File)
+	if err != nil {
+		return table, fmt.Errorf("csv - %s", err.Error())
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	if params.Delimiter != "" {
+		reader.Comma = []rune(params.Delimiter)[0]
+	}
+	reader.TrimLeadingSpace = true
+
+	//Mapping the configured column names to their indexes on the header line, -1 standing for the optional columns not configured
+	header, err := reader.Read()
+	if err != nil {
+		return table, fmt.Errorf("csv - reading header - %s", err.Error())
+	}
+	columns := map[string]int{}
+	for _, column := range []string{params.TimestampColumn, params.ValueColumn, params.MetricColumn, params.SamplesColumn, params.AttributeColumn} {
+		columns[column] = -1
+		if column == "" {
+			continue
+		}
+		for ind, name := range header {
+			if strings.TrimSpace(name) == column {
+				columns[column] = ind
+			}
+		}
+		if columns[column] == -1 {
+			return table, fmt.Errorf("csv - column \"%s\" not found", column)
+		}
+	}
+
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return table, fmt.Errorf("csv - %s", err.Error())
+		}
+
+		row := csvRow{metric: params.Metric, attribute: "Total", samples: 1}
+		if row.timestamp, err = parseCsvTimestamp(record[columns[params.TimestampColumn]], params.TimestampFormat); err != nil {
+			return table, fmt.Errorf("csv - line %d - %s", line, err.Error())
+		}
+		if row.value, err = strconv.ParseFloat(strings.TrimSpace(record[columns[params.ValueColumn]]), 64); err != nil {
+			return table, fmt.Errorf("csv - line %d - %s", line, err.Error())
+		}
+		if params.MetricColumn != "" {
+			row.metric = strings.TrimSpace(record[columns[params.MetricColumn]])
+		}
+		if params.AttributeColumn != "" && strings.TrimSpace(record[columns[params.AttributeColumn]]) != "" {
+			row.attribute = strings.TrimSpace(record[columns[params.AttributeColumn]])
+		}
+		if params.SamplesColumn != "" {
+			if row.samples, err = strconv.Atoi(strings.TrimSpace(record[columns[params.SamplesColumn]])); err != nil {
+				return table, fmt.Errorf("csv - line %d - %s", line, err.Error())
+			}
+		}
+
+		table.rows = append(table.rows, row)
+		if row.timestamp.After(table.latest) {
+			table.latest = row.timestamp
+		}
+	}
+
+	return table, nil
+}
+
+//openCsv loads the CSV file of a dataset, historical files being analysed up to their latest row instead of the current time
+func openCsv(dataSet config.Dataset, siteData *SiteData, timeAgo, timeStep time.Duration) (sourceCollection, error) {
+	table, err := loadCsv(*dataSet.Csv)
+	if err != nil {
+		return sourceCollection{}, err
+	}
+	step, err := utils.ParseTimeStep(dataSet.TimeStep)
+	if err != nil {
+		step = utils.TimeStep{Duration: timeStep}
+	}
+	if !table.latest.IsZero() {
+		siteData.DateEnd = step.Next(step.Align(table.latest), 1)
+		siteData.DateStart = siteData.DateEnd.Add(-1 * timeAgo)
+	}
+	return sourceCollection{
+		metrics: table.metricNames(),
+		getMetric: func(metric string) (MetricData, error) {
+			return table.getMetric(metric, siteData.DateStart, siteData.DateEnd, step)
+		},
+	}, nil
+}
+
+//parseCsvTimestamp parses a timestamp with a Go time layout, "unix" or "unix-ms", RFC 3339 being used if no format is given
+func parseCsvTimestamp(value, format string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	switch format {
+	case "":
+		return time.Parse(time.RFC3339, value)
+	case "unix", "unix-ms":
+		number, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return time.Time{}, err
+		}
+		if format == "unix" {
+			return time.Unix(number, 0), nil
+		}
+		return time.UnixMilli(number), nil
+	default:
+		return time.Parse(format, value)
+	}
+}
+
+//metricNames returns the names of all metrics present on the file in alphabetical order
+func (table csvTable) metricNames() []string {
+	names := []string{}
+	for _, row := range table.rows {
+		if !containsName(names, row.metric) {
+			names = append(names, row.metric)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+//getMetric aggregates the rows of a metric into the time steps of the given period, rows outside of it being ignored
+//Only time steps with rows are kept
+func (table csvTable) getMetric(metric string, dateStart, dateEnd time.Time, timeStep utils.TimeStep) (MetricData, error) {
+	buckets := map[string]map[int]*stepAggregator{}
+	for _, row := range table.rows {
+		if row.metric != metric || row.timestamp.Before(dateStart) || !row.timestamp.Before(dateEnd) {
+			continue
+		}
+		step := timeStep.Index(dateStart, row.timestamp)
+		if buckets[row.attribute] == nil {
+			buckets[row.attribute] = map[int]*stepAggregator{}
+		}
+		if buckets[row.attribute][step] == nil {
+			buckets[row.attribute][step] = newStepAggregator(table.aggregation)
+		}
+		buckets[row.attribute][step].add(row.value, row.samples)
+	}
+	if len(buckets) == 0 {
+		return MetricData{}, fmt.Errorf("no csv rows for metric %s within the period", metric)
+	}
+
+	series := map[string][]TimeStepData{}
+	for attribute, steps := range buckets {
+		for step, aggregator := range steps {
+			value, samples := aggregator.result()
+			series[attribute] = append(series[attribute], TimeStepData{DateStart: timeStep.Next(dateStart, step), Value: value, Samples: samples})
+		}
+	}
+
+	return metricDataFromSeries(metric, table.units[metric], series), nil
+}
+
+//containsName checks if a name is present on the given list
+func containsName(names []string, name string) bool {
+	for _, item := range names {
+		if item == name {
+			return true
+		}
+	}
+	return false
+}
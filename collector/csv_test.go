@@ -0,0 +1,145 @@
+package collector
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ftfmtavares/anomalies-detector/config"
+)
+
+func TestGetDataCsv(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "export.csv")
+	content := "date;metric;path;value;visitors\n" +
+		"2022-09-01;Revenue;;100;40\n" +
+		"2022-09-01;Revenue;Browser>Chrome;60;25\n" +
+		"2022-09-02;Revenue;;120;50\n" +
+		"2022-09-02;Revenue;;30;10\n" +
+		"2022-09-02;Revenue;Browser>Chrome;70;30\n" +
+		"2022-09-02;Basket;;25;50\n" +
+		"2022-09-02;Basket;;40;10\n" +
+		"2022-08-20;Revenue;;999;1\n"
+	if err := os.WriteFile(file, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	day := time.Date(2022, 9, 1, 0, 0, 0, 0, time.UTC)
+	params := config.CsvParams{
+		File:            file,
+		Delimiter:       ";",
+		TimestampColumn: "date",
+		TimestampFormat: "2006-01-02",
+		MetricColumn:    "metric",
+		ValueColumn:     "value",
+		SamplesColumn:   "visitors",
+		AttributeColumn: "path",
+		Units:           map[string]string{"Revenue": "€"},
+	}
+
+	tests := []struct {
+		name        string
+		aggregation string
+		want        []MetricData
+	}{
+		{
+			name:        "Rows summed per time step within the window ending on the latest row",
+			aggregation: "sum",
+			want: []MetricData{
+				{Metric: "Basket", Attributes: []string{"Total"}, AttributeData: map[string][]TimeStepData{
+					"Total": {{DateStart: day.AddDate(0, 0, 1), Value: 65, Samples: 60}},
+				}},
+				{Metric: "Revenue", Unit: "€", Attributes: []string{"Total", "Browser>Chrome"}, AttributeData: map[string][]TimeStepData{
+					"Total":          {{DateStart: day, Value: 100, Samples: 40}, {DateStart: day.AddDate(0, 0, 1), Value: 150, Samples: 60}},
+					"Browser>Chrome": {{DateStart: day, Value: 60, Samples: 25}, {DateStart: day.AddDate(0, 0, 1), Value: 70, Samples: 30}},
+				}},
+			},
+		},
+		{
+			name:        "Rows averaged per time step weighted by samples",
+			aggregation: "mean",
+			want: []MetricData{
+				{Metric: "Basket", Attributes: []string{"Total"}, AttributeData: map[string][]TimeStepData{
+					"Total": {{DateStart: day.AddDate(0, 0, 1), Value: 27.5, Samples: 60}},
+				}},
+				{Metric: "Revenue", Unit: "€", Attributes: []string{"Total", "Browser>Chrome"}, AttributeData: map[string][]TimeStepData{
+					"Total":          {{DateStart: day, Value: 100, Samples: 40}, {DateStart: day.AddDate(0, 0, 1), Value: 105, Samples: 60}},
+					"Browser>Chrome": {{DateStart: day, Value: 60, Samples: 25}, {DateStart: day.AddDate(0, 0, 1), Value: 70, Samples: 30}},
+				}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			csvParams := params
+			csvParams.Aggregation = tt.aggregation
+			got := GetData(config.Dataset{
+				SiteId:             "site",
+				TimeAgo:            "7d",
+				TimeStep:           "1d",
+				MetricesList:       []string{"all"},
+				SiteCollectFilters: &config.CollectFilters{},
+				Csv:                &csvParams,
+			})
+
+			if !got.DateEnd.Equal(day.AddDate(0, 0, 2)) || !got.DateStart.Equal(day.AddDate(0, 0, -5)) {
+				t.Errorf("GetData() period = %v - %v", got.DateStart, got.DateEnd)
+			}
+			if !reflect.DeepEqual(got.Metrics, tt.want) {
+				t.Errorf("GetData() = %v, want %v", got.Metrics, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetDataCsvMonthly(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "export.csv")
+	content := "date;metric;value;visitors\n" +
+		"2022-08-20;Revenue;90;10\n" +
+		"2022-08-31;Revenue;10;5\n" +
+		"2022-09-01;Revenue;100;40\n" +
+		"2022-09-12;Revenue;50;20\n"
+	if err := os.WriteFile(file, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	params := config.CsvParams{File: file, Delimiter: ";", TimestampColumn: "date", TimestampFormat: "2006-01-02", MetricColumn: "metric", ValueColumn: "value", SamplesColumn: "visitors", Aggregation: "sum"}
+
+	//Rows are summed by calendar month, the period ending at the start of the month after the latest row
+	got := GetData(config.Dataset{SiteId: "site", TimeAgo: "2mo", TimeStep: "1mo", MetricesList: []string{"all"}, SiteCollectFilters: &config.CollectFilters{}, Csv: &params, Timezone: "UTC"})
+	month := time.Date(2022, 8, 1, 0, 0, 0, 0, time.UTC)
+	if !got.DateStart.Equal(month) || !got.DateEnd.Equal(month.AddDate(0, 2, 0)) {
+		t.Errorf("GetData() period = %v - %v", got.DateStart, got.DateEnd)
+	}
+	want := []TimeStepData{{DateStart: month, Value: 100, Samples: 15}, {DateStart: month.AddDate(0, 1, 0), Value: 150, Samples: 60}}
+	if len(got.Metrics) != 1 || !reflect.DeepEqual(got.Metrics[0].AttributeData["Total"], want) {
+		t.Errorf("GetData() = %v, want %v", got.Metrics, want)
+	}
+}
+
+func TestLoadCsvErrors(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "export.csv")
+	if err := os.WriteFile(file, []byte("time,value\n1661990400,abc\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		params config.CsvParams
+	}{
+		{name: "Missing file", params: config.CsvParams{File: filepath.Join(dir, "missing.csv"), TimestampColumn: "time", ValueColumn: "value", Metric: "Revenue"}},
+		{name: "Unknown column", params: config.CsvParams{File: file, TimestampColumn: "date", ValueColumn: "value", Metric: "Revenue"}},
+		{name: "Invalid value", params: config.CsvParams{File: file, TimestampColumn: "time", TimestampFormat: "unix", ValueColumn: "value", Metric: "Revenue"}},
+		{name: "Missing metric", params: config.CsvParams{File: file, TimestampColumn: "time", ValueColumn: "value"}},
+		{name: "Invalid aggregation", params: config.CsvParams{File: file, TimestampColumn: "time", ValueColumn: "value", Metric: "Revenue", Aggregation: "max"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := loadCsv(tt.params); err == nil {
+				t.Errorf("loadCsv() expected an error")
+			}
+		})
+	}
+}
@@ -0,0 +1,337 @@
+package collector
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/ftfmtavares/anomalies-detector/config"
+)
+
+//DerivedMetric is a metric definition with a compiled expression, ready to be computed from the collected metrics
+//Operands field lists the collected metrics the expression refers to, in order of appearance
+type DerivedMetric struct {
+	config.MetricDefinition
+	Operands []string
+	expr     derivedExpr
+}
+
+//derivedExpr evaluates a compiled expression over the values of its operands, returning false on a division by zero
+type derivedExpr func(values map[string]float64) (float64, bool)
+
+//CompileDerivedMetrics compiles the expressions of the derived metrics of the registry, ordered by name
+//It returns an error if an expression is invalid, refers to no metric or refers to a derived metric, itself included
+func CompileDerivedMetrics(registry map[string]config.MetricDefinition) ([]DerivedMetric, error) {
+	names := []string{}
+	for name, definition := range registry {
+		if definition.Expression != "" {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	compiled := []DerivedMetric{}
+	for _, name := range names {
+		parser := derivedParser{}
+		if err := parser.tokenize(registry[name].Expression); err != nil {
+			return nil, fmt.Errorf("metric %s - %s", name, err.Error())
+		}
+		expr, err := parser.parseSum()
+		if err == nil && parser.pos < len(parser.tokens) {
+			err = fmt.Errorf("unexpected \"%s\"", parser.tokens[parser.pos])
+		}
+		if err != nil {
+			return nil, fmt.Errorf("metric %s - invalid expression \"%s\", %s", name, registry[name].Expression, err.Error())
+		}
+		if len(parser.operands) == 0 {
+			return nil, fmt.Errorf("metric %s - expression \"%s\" refers to no metric", name, registry[name].Expression)
+		}
+		for _, operand := range parser.operands {
+			if registry[operand].Expression != "" {
+				return nil, fmt.Errorf("metric %s - expression refers to derived metric %s, only collected metrics can be used", name, operand)
+			}
+		}
+		compiled = append(compiled, DerivedMetric{MetricDefinition: registry[name], Operands: parser.operands, expr: expr})
+	}
+
+	return compiled, nil
+}
+
+//SelectDerivedMetrics returns the derived metrics computed for a metrics list, all of them for "all"
+func SelectDerivedMetrics(metrics []string, derived []DerivedMetric) []DerivedMetric {
+	if len(metrics) > 0 && strings.ToLower(metrics[0]) == "all" {
+		return derived
+	}
+	selected := []DerivedMetric{}
+	for _, metric := range derived {
+		if containsName(metrics, metric.Name) {
+			selected = append(selected, metric)
+		}
+	}
+	return selected
+}
+
+//CollectedMetrics returns a metrics list with its derived metrics replaced by their operands, so that only collected metrics are requested from the backends
+//The "all" list is returned unchanged
+func CollectedMetrics(metrics []string, derived []DerivedMetric) []string {
+	if len(metrics) > 0 && strings.ToLower(metrics[0]) == "all" {
+		return metrics
+	}
+	res := []string{}
+	for _, metric := range metrics {
+		names := []string{metric}
+		for _, derivedMetric := range derived {
+			if derivedMetric.Name == metric {
+				names = derivedMetric.Operands
+			}
+		}
+		for _, name := range names {
+			if !containsName(res, name) {
+				res = append(res, name)
+			}
+		}
+	}
+	return res
+}
+
+//ApplyDerivedMetrics computes the derived metrics from the collected data of a site, adding them after the collected metrics
+//Derived metrics already collected under the same name are left as collected, while those missing any of their operands are skipped
+func ApplyDerivedMetrics(siteData SiteData, derived []DerivedMetric) SiteData {
+	if len(derived) == 0 {
+		return siteData
+	}
+
+	metrics := append([]MetricData{}, siteData.Metrics...)
+	for _, metric := range derived {
+		if findMetricData(metrics, metric.Name) >= 0 {
+			continue
+		}
+		operands := []MetricData{}
+		for _, operand := range metric.Operands {
+			if ind := findMetricData(metrics, operand); ind >= 0 {
+				operands = append(operands, metrics[ind])
+			}
+		}
+		if len(operands) < len(metric.Operands) {
+			log.Printf("Skipping %s - %s - operands %s not all collected\n", siteData.SiteId, metric.Name, strings.Join(metric.Operands, ", "))
+			continue
+		}
+		metrics = append(metrics, metric.derive(operands))
+	}
+
+	siteData.Metrics = metrics
+	return siteData
+}
+
+//derive computes a derived metric for every attribute path found on all its operands, matching their time steps by date
+//Time steps missing on an operand are left out, while those with a missing operand or a division by zero are kept as missing with a zero value
+//Every time step takes the lowest samples count of its operands
+func (metric DerivedMetric) derive(operands []MetricData) MetricData {
+	res := MetricData{Metric: metric.Name, Unit: metric.Unit, Type: metric.Type, AlertDirection: metric.AlertDirection, Attributes: []string{}, AttributeData: map[string][]TimeStepData{}}
+	for _, attribute := range operands[0].Attributes {
+		steps := make([]map[int64]TimeStepData, len(operands))
+		for i, operand := range operands {
+			data, found := operand.AttributeData[attribute]
+			if !found {
+				steps = nil
+				break
+			}
+			steps[i] = map[int64]TimeStepData{}
+			for _, stepData := range data {
+				steps[i][stepData.DateStart.UnixNano()] = stepData
+			}
+		}
+		if steps == nil {
+			continue
+		}
+
+		data := []TimeStepData{}
+		values := map[string]float64{}
+		for _, stepData := range operands[0].AttributeData[attribute] {
+			derivedStep := TimeStepData{DateStart: stepData.DateStart, Samples: stepData.Samples}
+			complete := true
+			for i, operand := range metric.Operands {
+				operandStep, found := steps[i][stepData.DateStart.UnixNano()]
+				if !found {
+					complete = false
+					break
+				}
+				values[operand] = operandStep.Value
+				if operandStep.Samples < derivedStep.Samples {
+					derivedStep.Samples = operandStep.Samples
+				}
+				derivedStep.Filled = derivedStep.Filled || operandStep.Filled
+				derivedStep.Missing = derivedStep.Missing || operandStep.Missing
+			}
+			if !complete {
+				continue
+			}
+			if !derivedStep.Missing {
+				value, valid := metric.expr(values)
+				if valid {
+					derivedStep.Value = value
+				} else {
+					derivedStep.Missing = true
+				}
+			}
+			data = append(data, derivedStep)
+		}
+		res.Attributes = append(res.Attributes, attribute)
+		res.AttributeData[attribute] = data
+	}
+	return res
+}
+
+//findMetricData returns the index of the named metric on a list of collected metrics, -1 if not found
+func findMetricData(metrics []MetricData, name string) int {
+	for ind, metricData := range metrics {
+		if metricData.Metric == name {
+			return ind
+		}
+	}
+	return -1
+}
+
+//derivedParser provides the structure for parsing an expression by recursive descent, sums being made of products of numbers, metric names, negations and parenthesised sums
+type derivedParser struct {
+	tokens   []string
+	pos      int
+	operands []string
+}
+
+//tokenize splits an expression into numbers, metric names, operators and parentheses
+//It returns an error on any other character
+func (parser *derivedParser) tokenize(expression string) error {
+	runes := []rune(expression)
+	for ind := 0; ind < len(runes); {
+		char := runes[ind]
+		switch {
+		case unicode.IsSpace(char):
+			ind++
+		case strings.ContainsRune("+-*/()", char):
+			parser.tokens = append(parser.tokens, string(char))
+			ind++
+		case unicode.IsDigit(char) || char == '.' || unicode.IsLetter(char) || char == '_':
+			end := ind + 1
+			for end < len(runes) && (unicode.IsDigit(runes[end]) || runes[end] == '.' || unicode.IsLetter(runes[end]) || runes[end] == '_') {
+				end++
+			}
+			parser.tokens = append(parser.tokens, string(runes[ind:end]))
+			ind = end
+		default:
+			return fmt.Errorf("invalid character '%c' in expression \"%s\"", char, expression)
+		}
+	}
+	return nil
+}
+
+//next returns the current token, if any, without consuming it
+func (parser *derivedParser) next() string {
+	if parser.pos < len(parser.tokens) {
+		return parser.tokens[parser.pos]
+	}
+	return ""
+}
+
+//parseSum parses products separated by + and -
+func (parser *derivedParser) parseSum() (derivedExpr, error) {
+	left, err := parser.parseProduct()
+	if err != nil {
+		return nil, err
+	}
+	for parser.next() == "+" || parser.next() == "-" {
+		operator := parser.next()
+		parser.pos++
+		right, err := parser.parseProduct()
+		if err != nil {
+			return nil, err
+		}
+		left = binaryExpr(operator, left, right)
+	}
+	return left, nil
+}
+
+//parseProduct parses factors separated by * and /
+func (parser *derivedParser) parseProduct() (derivedExpr, error) {
+	left, err := parser.parseFactor()
+	if err != nil {
+		return nil, err
+	}
+	for parser.next() == "*" || parser.next() == "/" {
+		operator := parser.next()
+		parser.pos++
+		right, err := parser.parseFactor()
+		if err != nil {
+			return nil, err
+		}
+		left = binaryExpr(operator, left, right)
+	}
+	return left, nil
+}
+
+//parseFactor parses a number, a metric name, a negated factor or a parenthesised sum
+func (parser *derivedParser) parseFactor() (derivedExpr, error) {
+	token := parser.next()
+	parser.pos++
+	switch {
+	case token == "":
+		return nil, fmt.Errorf("unexpected end")
+	case token == "-":
+		operand, err := parser.parseFactor()
+		if err != nil {
+			return nil, err
+		}
+		return func(values map[string]float64) (float64, bool) {
+			value, valid := operand(values)
+			return -value, valid
+		}, nil
+	case token == "(":
+		inner, err := parser.parseSum()
+		if err != nil {
+			return nil, err
+		}
+		if parser.next() != ")" {
+			return nil, fmt.Errorf("missing \")\"")
+		}
+		parser.pos++
+		return inner, nil
+	case unicode.IsDigit(rune(token[0])) || token[0] == '.':
+		number, err := strconv.ParseFloat(token, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number \"%s\"", token)
+		}
+		return func(map[string]float64) (float64, bool) { return number, true }, nil
+	case unicode.IsLetter([]rune(token)[0]) || token[0] == '_':
+		if !containsName(parser.operands, token) {
+			parser.operands = append(parser.operands, token)
+		}
+		return func(values map[string]float64) (float64, bool) { return values[token], true }, nil
+	}
+	return nil, fmt.Errorf("unexpected \"%s\"", token)
+}
+
+//binaryExpr combines two expressions with an arithmetic operator, divisions by zero being invalid
+func binaryExpr(operator string, left, right derivedExpr) derivedExpr {
+	return func(values map[string]float64) (float64, bool) {
+		leftValue, leftValid := left(values)
+		rightValue, rightValid := right(values)
+		if !leftValid || !rightValid {
+			return 0, false
+		}
+		switch operator {
+		case "+":
+			return leftValue + rightValue, true
+		case "-":
+			return leftValue - rightValue, true
+		case "*":
+			return leftValue * rightValue, true
+		}
+		if rightValue == 0 {
+			return 0, false
+		}
+		return leftValue / rightValue, true
+	}
+}
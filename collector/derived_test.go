@@ -0,0 +1,116 @@
+package collector
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ftfmtavares/anomalies-detector/config"
+)
+
+func TestCompileDerivedMetrics(t *testing.T) {
+	tests := []struct {
+		name         string
+		expression   string
+		wantOperands []string
+		wantValue    float64
+		wantValid    bool
+		wantErr      bool
+	}{
+		{name: "Ratio", expression: "Orders / Visits", wantOperands: []string{"Orders", "Visits"}, wantValue: 0.2, wantValid: true},
+		{name: "Precedence and parentheses", expression: "(Revenue - Orders * 2) / -(Orders - 10)", wantOperands: []string{"Revenue", "Orders"}, wantValue: -96, wantValid: true},
+		{name: "Percentage", expression: "100 * Orders / Visits", wantOperands: []string{"Orders", "Visits"}, wantValue: 20, wantValid: true},
+		{name: "Division by zero", expression: "Revenue / (Orders - 20)", wantOperands: []string{"Revenue", "Orders"}, wantValid: false},
+		{name: "Missing parenthesis", expression: "(Revenue / Orders", wantErr: true},
+		{name: "Dangling operator", expression: "Revenue /", wantErr: true},
+		{name: "Invalid character", expression: "Revenue % Orders", wantErr: true},
+		{name: "Constant", expression: "2 * 3", wantErr: true},
+		{name: "Self reference", expression: "Derived * 2", wantErr: true},
+	}
+	values := map[string]float64{"Revenue": 1000, "Orders": 20, "Visits": 100}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			registry := map[string]config.MetricDefinition{"Orders": {Name: "Orders", Type: config.MetricTypeCount}, "Derived": {Name: "Derived", Type: config.MetricTypeAverage, Expression: tt.expression}}
+			got, err := CompileDerivedMetrics(registry)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("CompileDerivedMetrics() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if len(got) != 1 || !reflect.DeepEqual(got[0].Operands, tt.wantOperands) {
+				t.Fatalf("CompileDerivedMetrics() = %v, want operands %v", got, tt.wantOperands)
+			}
+			value, valid := got[0].expr(values)
+			if valid != tt.wantValid || (valid && value != tt.wantValue) {
+				t.Errorf("CompileDerivedMetrics() evaluates to %g (valid %v), want %g (valid %v)", value, valid, tt.wantValue, tt.wantValid)
+			}
+		})
+	}
+}
+
+func TestCollectedMetrics(t *testing.T) {
+	derived, err := CompileDerivedMetrics(map[string]config.MetricDefinition{
+		"ConversionRate": {Name: "ConversionRate", Type: config.MetricTypeAverage, Expression: "Orders / Visits"},
+		"AOV":            {Name: "AOV", Type: config.MetricTypeAverage, Expression: "Revenue / Orders"},
+	})
+	if err != nil {
+		t.Fatalf("CompileDerivedMetrics() error = %v", err)
+	}
+
+	tests := []struct {
+		name          string
+		metrics       []string
+		wantCollected []string
+		wantDerived   []string
+	}{
+		{name: "All metrics", metrics: []string{"all"}, wantCollected: []string{"all"}, wantDerived: []string{"AOV", "ConversionRate"}},
+		{name: "Derived metrics replaced by their operands", metrics: []string{"Visits", "ConversionRate", "AOV"}, wantCollected: []string{"Visits", "Orders", "Revenue"}, wantDerived: []string{"AOV", "ConversionRate"}},
+		{name: "No derived metrics", metrics: []string{"Revenue"}, wantCollected: []string{"Revenue"}, wantDerived: []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CollectedMetrics(tt.metrics, derived); !reflect.DeepEqual(got, tt.wantCollected) {
+				t.Errorf("CollectedMetrics() = %v, want %v", got, tt.wantCollected)
+			}
+			got := []string{}
+			for _, metric := range SelectDerivedMetrics(tt.metrics, derived) {
+				got = append(got, metric.Name)
+			}
+			if !reflect.DeepEqual(got, tt.wantDerived) {
+				t.Errorf("SelectDerivedMetrics() = %v, want %v", got, tt.wantDerived)
+			}
+		})
+	}
+}
+
+func TestApplyDerivedMetrics(t *testing.T) {
+	timeRef := time.Date(2022, 9, 1, 0, 0, 0, 0, time.UTC)
+	siteData := SiteData{SiteId: "shop", Metrics: []MetricData{
+		{Metric: "Orders", Attributes: []string{"Total", "Browser>Edge"}, AttributeData: map[string][]TimeStepData{
+			"Total":        {{DateStart: timeRef, Value: 10, Samples: 10}, {DateStart: timeRef.Add(time.Hour), Value: 0, Samples: 0}, {DateStart: timeRef.Add(2 * time.Hour), Value: 30, Samples: 30, Filled: true}},
+			"Browser>Edge": {{DateStart: timeRef, Value: 1, Samples: 1}},
+		}},
+		{Metric: "Visits", Attributes: []string{"Total"}, AttributeData: map[string][]TimeStepData{
+			"Total": {{DateStart: timeRef, Value: 100, Samples: 100}, {DateStart: timeRef.Add(time.Hour), Value: 0, Samples: 0}, {DateStart: timeRef.Add(2 * time.Hour), Value: 200, Samples: 200}},
+		}},
+	}}
+	derived, err := CompileDerivedMetrics(map[string]config.MetricDefinition{
+		"ConversionRate": {Name: "ConversionRate", Unit: "%", Type: config.MetricTypeAverage, Expression: "100 * Orders / Visits"},
+		"AOV":            {Name: "AOV", Type: config.MetricTypeAverage, Expression: "Revenue / Orders"},
+	})
+	if err != nil {
+		t.Fatalf("CompileDerivedMetrics() error = %v", err)
+	}
+
+	got := ApplyDerivedMetrics(siteData, derived)
+	want := MetricData{Metric: "ConversionRate", Unit: "%", Type: config.MetricTypeAverage, Attributes: []string{"Total"}, AttributeData: map[string][]TimeStepData{
+		"Total": {{DateStart: timeRef, Value: 10, Samples: 10}, {DateStart: timeRef.Add(time.Hour), Samples: 0, Missing: true}, {DateStart: timeRef.Add(2 * time.Hour), Value: 15, Samples: 30, Filled: true}},
+	}}
+	if len(got.Metrics) != 3 || !reflect.DeepEqual(got.Metrics[2], want) {
+		t.Errorf("ApplyDerivedMetrics() = %+v, want AOV skipped and %+v", got.Metrics, want)
+	}
+	if len(siteData.Metrics) != 2 {
+		t.Errorf("ApplyDerivedMetrics() changed the collected data")
+	}
+}
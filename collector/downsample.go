@@ -0,0 +1,77 @@
+package collector
+
+import (
+	"math"
+	"time"
+)
+
+//MinDownsamplePoints is the lowest number of points a series can be downsampled to, its first and last points being always kept
+const MinDownsamplePoints = 3
+
+//TimeRange returns a copy of a metric holding only the time steps starting within the given range, a zero from or to time leaving the range open on that side
+func TimeRange(metricData MetricData, from, to time.Time) MetricData {
+	res := copyMetricData(metricData)
+	for attribute, data := range res.AttributeData {
+		kept := []TimeStepData{}
+		for _, stepData := range data {
+			if (from.IsZero() || !stepData.DateStart.Before(from)) && (to.IsZero() || stepData.DateStart.Before(to)) {
+				kept = append(kept, stepData)
+			}
+		}
+		res.AttributeData[attribute] = kept
+	}
+	return res
+}
+
+//Downsample returns a copy of a metric whose series are reduced to the given number of points, the series already short enough being kept whole
+func Downsample(metricData MetricData, points int) MetricData {
+	res := copyMetricData(metricData)
+	for attribute, data := range res.AttributeData {
+		res.AttributeData[attribute] = DownsampleSeries(data, points)
+	}
+	return res
+}
+
+//DownsampleSeries reduces a series to the given number of points with the largest triangle three buckets algorithm, which keeps its visual shape and so its peaks and dips
+//The first and last points are always kept and, for every bucket in between, the point forming the largest triangle with the point kept on the previous bucket and the average of the next one
+//The kept points are original time steps, with their values and samples
+func DownsampleSeries(data []TimeStepData, points int) []TimeStepData {
+	if points < MinDownsamplePoints || len(data) <= points {
+		return data
+	}
+
+	res := make([]TimeStepData, 0, points)
+	res = append(res, data[0])
+	bucketSize := float64(len(data)-2) / float64(points-2)
+	previous := 0
+	for bucket := 0; bucket < points-2; bucket++ {
+		start := int(float64(bucket)*bucketSize) + 1
+		end := int(float64(bucket+1)*bucketSize) + 1
+
+		//Averaging the next bucket, the last point being the next bucket of the last one
+		nextStart, nextEnd := end, int(float64(bucket+2)*bucketSize)+1
+		if nextEnd > len(data) {
+			nextEnd = len(data)
+		}
+		avgX, avgY := 0.0, 0.0
+		for _, stepData := range data[nextStart:nextEnd] {
+			avgX += float64(stepData.DateStart.Unix())
+			avgY += stepData.Value
+		}
+		avgX /= float64(nextEnd - nextStart)
+		avgY /= float64(nextEnd - nextStart)
+
+		//Keeping the point of the bucket forming the largest triangle
+		prevX, prevY := float64(data[previous].DateStart.Unix()), data[previous].Value
+		largest, chosen := -1.0, start
+		for ind := start; ind < end; ind++ {
+			area := math.Abs((prevX-avgX)*(data[ind].Value-prevY) - (prevX-float64(data[ind].DateStart.Unix()))*(avgY-prevY))
+			if area > largest {
+				largest, chosen = area, ind
+			}
+		}
+		res = append(res, data[chosen])
+		previous = chosen
+	}
+	return append(res, data[len(data)-1])
+}
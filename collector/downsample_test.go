@@ -0,0 +1,65 @@
+package collector
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestDownsampleSeries(t *testing.T) {
+	timeRef := time.Date(2022, 9, 1, 0, 0, 0, 0, time.UTC)
+	series := func(values ...float64) []TimeStepData {
+		data := []TimeStepData{}
+		for i, value := range values {
+			data = append(data, TimeStepData{DateStart: timeRef.Add(time.Duration(i) * time.Hour), Value: value, Samples: i})
+		}
+		return data
+	}
+	data := series(10, 11, 10, 12, 90, 11, 10, -30, 11, 10)
+
+	tests := []struct {
+		name   string
+		points int
+		want   []TimeStepData
+	}{
+		{
+			name:   "Peak and dip kept",
+			points: 4,
+			want:   []TimeStepData{data[0], data[4], data[7], data[9]},
+		},
+		{
+			name:   "Series shorter than the points kept whole",
+			points: 20,
+			want:   data,
+		},
+		{
+			name:   "Too few points ignored",
+			points: 2,
+			want:   data,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := DownsampleSeries(data, tt.points); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("DownsampleSeries() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTimeRange(t *testing.T) {
+	timeRef := time.Date(2022, 9, 1, 0, 0, 0, 0, time.UTC)
+	data := []TimeStepData{}
+	for i := 0; i < 5; i++ {
+		data = append(data, TimeStepData{DateStart: timeRef.AddDate(0, 0, i), Value: float64(i)})
+	}
+	metricData := MetricData{Metric: "Revenue", Attributes: []string{"Total"}, AttributeData: map[string][]TimeStepData{"Total": data}}
+
+	got := TimeRange(metricData, timeRef.AddDate(0, 0, 1), timeRef.AddDate(0, 0, 3))
+	if want := data[1:3]; !reflect.DeepEqual(got.AttributeData["Total"], want) {
+		t.Errorf("TimeRange() = %v, want %v", got.AttributeData["Total"], want)
+	}
+	if got = TimeRange(metricData, time.Time{}, time.Time{}); !reflect.DeepEqual(got.AttributeData["Total"], data) {
+		t.Errorf("TimeRange() = %v, want the whole series", got.AttributeData["Total"])
+	}
+}
@@ -0,0 +1,331 @@
+//go:build ga4 || full
+
+package collector
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ftfmtavares/anomalies-detector/config"
+	"github.com/ftfmtavares/anomalies-detector/utils"
+)
+
+//Const block defines the GA4 Data API defaults
+//Reports are read in pages of ga4PageSize rows, the API maximum
+const (
+	defaultGa4Endpoint      = "https://analyticsdata.googleapis.com"
+	defaultGa4SamplesMetric = "sessions"
+	ga4PageSize             = 100000
+)
+
+//init registers the Google Analytics source, built in with the ga4 build tag
+func init() {
+	utils.RegisterIntegration(utils.IntegrationGa4)
+	registerSource(utils.IntegrationGa4, openGa4)
+}
+
+//ga4Client collects metrics through the runReport method of the Google Analytics 4 Data API
+type ga4Client struct {
+	runReportUrl string
+	tokenUrl     string
+	accessToken  string
+	location     *time.Location
+	metrics      map[string]config.Ga4Metric
+	attributes   map[string][]string
+	filters      config.CollectFilters
+	client       *http.Client
+}
+
+//ga4Request provides the structure of a runReport request
+type ga4Request struct {
+	DateRanges []ga4DateRange `json:"dateRanges"`
+	Dimensions []ga4Name      `json:"dimensions"`
+	Metrics    []ga4Name      `json:"metrics"`
+	Limit      int            `json:"limit"`
+	Offset     int            `json:"offset"`
+}
+
+//ga4DateRange provides the structure of an inclusive range of days in the property time zone
+type ga4DateRange struct {
+	StartDate string `json:"startDate"`
+	EndDate   string `json:"endDate"`
+}
+
+//ga4Name provides the structure of a requested dimension or metric
+type ga4Name struct {
+	Name string `json:"name"`
+}
+
+//ga4Response provides the structure of a runReport response, values being given in the order of the requested dimensions and metrics
+type ga4Response struct {
+	Rows []struct {
+		DimensionValues []struct {
+			Value string `json:"value"`
+		} `json:"dimensionValues"`
+		MetricValues []struct {
+			Value string `json:"value"`
+		} `json:"metricValues"`
+	} `json:"rows"`
+	RowCount int `json:"rowCount"`
+	Error    struct {
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+//newGa4Client creates a ga4Client from the given parameters, honoring the attribute levels of the collection filters on its queries
+//It returns an error if the property is missing, if the time zone is unknown or if any metric is invalid
+func newGa4Client(params config.Ga4Params, filters config.CollectFilters) (*ga4Client, error) {
+	if params.PropertyId == "" {
+		return nil, errors.New("ga4 - missing propertyId")
+	}
+	location := time.UTC
+	if params.TimeZone != "" {
+		var err error
+		if location, err = time.LoadLocation(params.TimeZone); err != nil {
+			return nil, fmt.Errorf("ga4 - %s", err.Error())
+		}
+	}
+	for name, metric := range params.Metrics {
+		if metric.Metric == "" {
+			return nil, fmt.Errorf("ga4 - metric %s requires a GA4 metric", name)
+		}
+		if metric.Aggregation != "" && metric.Aggregation != "sum" && metric.Aggregation != "mean" {
+			return nil, fmt.Errorf("ga4 - metric %s - invalid aggregation \"%s\", it must be \"sum\" or \"mean\"", name, metric.Aggregation)
+		}
+	}
+	endpoint := params.Endpoint
+	if endpoint == "" {
+		endpoint = defaultGa4Endpoint
+	}
+
+	return &ga4Client{
+		runReportUrl: fmt.Sprintf("%s/v1beta/properties/%s:runReport", strings.TrimSuffix(endpoint, "/"), url.PathEscape(params.PropertyId)),
+		tokenUrl:     utils.GcpMetadataTokenUrl,
+		accessToken:  utils.ResolveSecret(params.AccessToken),
+		location:     location,
+		metrics:      params.Metrics,
+		attributes:   params.Attributes,
+		filters:      filters,
+		client:       utils.NewHttpClient(60 * time.Second),
+	}, nil
+}
+
+//openGa4 creates the Google Analytics client of a dataset, only whole days or hours being analysed, ending at the start of the ongoing one
+func openGa4(dataSet config.Dataset, siteData *SiteData, timeAgo, timeStep time.Duration) (sourceCollection, error) {
+	client, err := newGa4Client(*dataSet.Ga4, *dataSet.SiteCollectFilters)
+	if err != nil {
+		return sourceCollection{}, err
+	}
+	step, err := utils.ParseTimeStep(dataSet.TimeStep)
+	if err != nil {
+		step = utils.TimeStep{Duration: timeStep}
+	}
+	siteData.DateEnd = client.periodEnd(siteData.DateEnd, timeStep)
+	siteData.DateStart = siteData.DateEnd.Add(-1 * timeAgo)
+	return sourceCollection{
+		metrics: client.metricNames(),
+		getMetric: func(metric string) (MetricData, error) {
+			return client.getMetric(metric, siteData.DateStart, siteData.DateEnd, step)
+		},
+	}, nil
+}
+
+//metricNames returns the names of all configured metrics in alphabetical order
+func (client *ga4Client) metricNames() []string {
+	names := []string{}
+	for name := range client.metrics {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+//periodEnd returns the start of the current day, or hour for time steps shorter than a day, on the property time zone
+//GA4 only reports whole days and hours, so the period ends there instead of including the ongoing one
+func (client *ga4Client) periodEnd(now time.Time, timeStep time.Duration) time.Time {
+	now = now.In(client.location)
+	if timeStep < 24*time.Hour {
+		return time.Date(now.Year(), now.Month(), now.Day(), now.Hour(), 0, 0, 0, client.location)
+	}
+	return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, client.location)
+}
+
+//getMetric collects a metric for the given period, running one report for the total and one for each level of every attribute
+//Each level is requested on its own since GA4 metrics such as users aren't additive across dimension values
+//Levels deeper than the attribute filter level are never requested, the remaining filters being applied afterwards as for any other backend
+func (client *ga4Client) getMetric(metric string, dateStart, dateEnd time.Time, timeStep utils.TimeStep) (MetricData, error) {
+	gaMetric, found := client.metrics[metric]
+	if !found {
+		return MetricData{}, fmt.Errorf("no ga4 metric for metric %s", metric)
+	}
+	if gaMetric.SamplesMetric == "" {
+		gaMetric.SamplesMetric = defaultGa4SamplesMetric
+	}
+
+	token := client.accessToken
+	if token == "" {
+		var err error
+		if token, err = utils.GcpMetadataToken(client.client, client.tokenUrl); err != nil {
+			return MetricData{}, fmt.Errorf("ga4 - %s", err.Error())
+		}
+	}
+
+	//Listing the dimensions of every report, the total having none
+	names := []string{}
+	for name := range client.attributes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	reports := []ga4Query{{attribute: "Total"}}
+	for _, name := range names {
+		levels := len(client.attributes[name])
+		if maxLevel := client.filters.AttributesFilterParams[name].Level; maxLevel != 0 && maxLevel < levels {
+			levels = maxLevel
+		}
+		for level := 1; level <= levels; level++ {
+			reports = append(reports, ga4Query{attribute: name, dimensions: client.attributes[name][:level]})
+		}
+	}
+
+	buckets := map[string]map[int]*stepAggregator{}
+	for _, report := range reports {
+		rows, err := client.runReport(token, gaMetric, report.dimensions, dateStart, dateEnd, timeStep.Duration)
+		if err != nil {
+			return MetricData{}, err
+		}
+
+		for _, row := range rows {
+			if row.date.Before(dateStart) || !row.date.Before(dateEnd) {
+				continue
+			}
+			attribute := report.attribute
+			for _, value := range row.dimensions {
+				attribute += ">" + strings.ReplaceAll(value, ">", " ")
+			}
+			step := timeStep.Index(dateStart, row.date)
+			if buckets[attribute] == nil {
+				buckets[attribute] = map[int]*stepAggregator{}
+			}
+			if buckets[attribute][step] == nil {
+				buckets[attribute][step] = newStepAggregator(gaMetric.Aggregation)
+			}
+			buckets[attribute][step].add(row.value, row.samples)
+		}
+	}
+
+	series := map[string][]TimeStepData{}
+	for attribute, steps := range buckets {
+		for step, aggregator := range steps {
+			value, samples := aggregator.result()
+			series[attribute] = append(series[attribute], TimeStepData{DateStart: timeStep.Next(dateStart, step), Value: value, Samples: samples})
+		}
+	}
+
+	return metricDataFromSeries(metric, gaMetric.Unit, series), nil
+}
+
+//ga4Query holds the attribute a report is collected for along with the dimensions of its level
+type ga4Query struct {
+	attribute  string
+	dimensions []string
+}
+
+//ga4Row holds a single report row with its date, dimension values, metric value and samples
+type ga4Row struct {
+	date       time.Time
+	dimensions []string
+	value      float64
+	samples    int
+}
+
+//runReport requests the value and samples metrics by day, or by hour for time steps shorter than a day, along with the given dimensions
+//All pages of the report are read, returning an error if any request fails or if a row can't be parsed
+func (client *ga4Client) runReport(token string, gaMetric config.Ga4Metric, dimensions []string, dateStart, dateEnd time.Time, timeStep time.Duration) ([]ga4Row, error) {
+	dateDimension, dateLayout := "date", "20060102"
+	if timeStep < 24*time.Hour {
+		dateDimension, dateLayout = "dateHour", "2006010215"
+	}
+
+	request := ga4Request{
+		DateRanges: []ga4DateRange{{StartDate: dateStart.In(client.location).Format("2006-01-02"), EndDate: dateEnd.Add(-time.Nanosecond).In(client.location).Format("2006-01-02")}},
+		Dimensions: []ga4Name{{Name: dateDimension}},
+		Metrics:    []ga4Name{{Name: gaMetric.Metric}, {Name: gaMetric.SamplesMetric}},
+		Limit:      ga4PageSize,
+	}
+	for _, dimension := range dimensions {
+		request.Dimensions = append(request.Dimensions, ga4Name{Name: dimension})
+	}
+
+	rows := []ga4Row{}
+	for {
+		resp, err := client.post(token, request)
+		if err != nil {
+			return nil, err
+		}
+		for _, respRow := range resp.Rows {
+			if len(respRow.DimensionValues) != len(request.Dimensions) || len(respRow.MetricValues) != 2 {
+				return nil, errors.New("ga4 - malformed row")
+			}
+			date, err := time.ParseInLocation(dateLayout, respRow.DimensionValues[0].Value, client.location)
+			if err != nil {
+				return nil, fmt.Errorf("ga4 - %s", err.Error())
+			}
+			value, err := strconv.ParseFloat(respRow.MetricValues[0].Value, 64)
+			if err != nil {
+				return nil, fmt.Errorf("ga4 - %s", err.Error())
+			}
+			samples, err := strconv.ParseFloat(respRow.MetricValues[1].Value, 64)
+			if err != nil {
+				return nil, fmt.Errorf("ga4 - %s", err.Error())
+			}
+			row := ga4Row{date: date, value: value, samples: int(samples)}
+			for _, dimensionValue := range respRow.DimensionValues[1:] {
+				row.dimensions = append(row.dimensions, dimensionValue.Value)
+			}
+			rows = append(rows, row)
+		}
+
+		request.Offset += len(resp.Rows)
+		if len(resp.Rows) == 0 || request.Offset >= resp.RowCount {
+			return rows, nil
+		}
+	}
+}
+
+//post sends a single runReport request, reporting the API error message on non 2xx statuses
+func (client *ga4Client) post(token string, request ga4Request) (ga4Response, error) {
+	body, err := json.Marshal(request)
+	if err != nil {
+		return ga4Response{}, err
+	}
+	req, err := http.NewRequest(http.MethodPost, client.runReportUrl, bytes.NewReader(body))
+	if err != nil {
+		return ga4Response{}, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := client.client.Do(req)
+	if err != nil {
+		return ga4Response{}, fmt.Errorf("ga4 - %s", err.Error())
+	}
+	defer resp.Body.Close()
+
+	var gaResp ga4Response
+	decodeErr := json.NewDecoder(resp.Body).Decode(&gaResp)
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return ga4Response{}, fmt.Errorf("ga4 - unexpected status %s %s", resp.Status, gaResp.Error.Message)
+	}
+	if decodeErr != nil {
+		return ga4Response{}, fmt.Errorf("ga4 - %s", decodeErr.Error())
+	}
+	return gaResp, nil
+}
@@ -0,0 +1,146 @@
+//go:build ga4 || full
+
+package collector
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ftfmtavares/anomalies-detector/config"
+	"github.com/ftfmtavares/anomalies-detector/utils"
+)
+
+func TestGa4Client_getMetric(t *testing.T) {
+	dateStart := time.Date(2022, 9, 1, 0, 0, 0, 0, time.UTC)
+
+	//Report rows by their dimensions, each row holding the date, dimension values, metric value and samples
+	//The first daily row is outside the requested period while the total report is served one row per page
+	reportRows := map[string][][]string{
+		"date":                            {{"20220831", "50", "20"}, {"20220901", "100", "40"}, {"20220902", "150", "60"}},
+		"date,deviceCategory":             {{"20220901", "mobile", "70", "30"}, {"20220901", "desktop", "30", "10"}, {"20220902", "mobile", "150", "60"}},
+		"dateHour":                        {{"2022090100", "2", "10"}, {"2022090101", "5", "40"}, {"2022090106", "3", "20"}},
+		"dateHour,deviceCategory":         {{"2022090100", "mobile", "2", "10"}},
+		"dateHour,deviceCategory,browser": {{"2022090100", "mobile", "Chrome>Mobile", "2", "10"}},
+	}
+
+	tests := []struct {
+		name    string
+		metric  config.Ga4Metric
+		filters config.CollectFilters
+		dateEnd time.Time
+		step    time.Duration
+		want    MetricData
+	}{
+		{
+			name:    "Daily sums limited to the first attribute level",
+			metric:  config.Ga4Metric{Metric: "purchaseRevenue", Unit: "€"},
+			filters: config.CollectFilters{AttributesFilterParams: map[string]config.FilterParams{"DeviceType": {Level: 1}}},
+			dateEnd: dateStart.AddDate(0, 0, 2),
+			step:    24 * time.Hour,
+			want: MetricData{Metric: "Revenue", Unit: "€", Attributes: []string{"Total", "DeviceType>desktop", "DeviceType>mobile"}, AttributeData: map[string][]TimeStepData{
+				"Total":              {{DateStart: dateStart, Value: 100, Samples: 40}, {DateStart: dateStart.AddDate(0, 0, 1), Value: 150, Samples: 60}},
+				"DeviceType>desktop": {{DateStart: dateStart, Value: 30, Samples: 10}},
+				"DeviceType>mobile":  {{DateStart: dateStart, Value: 70, Samples: 30}, {DateStart: dateStart.AddDate(0, 0, 1), Value: 150, Samples: 60}},
+			}},
+		},
+		{
+			name:    "Hourly rows averaged by samples into 6 hours steps",
+			metric:  config.Ga4Metric{Metric: "averagePurchaseRevenue", SamplesMetric: "transactions", Aggregation: "mean"},
+			dateEnd: dateStart.Add(12 * time.Hour),
+			step:    6 * time.Hour,
+			want: MetricData{Metric: "Revenue", Attributes: []string{"Total", "DeviceType>mobile", "DeviceType>mobile>Chrome Mobile"}, AttributeData: map[string][]TimeStepData{
+				"Total":                           {{DateStart: dateStart, Value: 4.4, Samples: 50}, {DateStart: dateStart.Add(6 * time.Hour), Value: 3, Samples: 20}},
+				"DeviceType>mobile":               {{DateStart: dateStart, Value: 2, Samples: 10}},
+				"DeviceType>mobile>Chrome Mobile": {{DateStart: dateStart, Value: 2, Samples: 10}},
+			}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+				var request ga4Request
+				json.NewDecoder(req.Body).Decode(&request)
+				if req.URL.Path != "/v1beta/properties/123:runReport" || req.Header.Get("Authorization") != "Bearer token" {
+					t.Errorf("unexpected request %s", req.URL.String())
+				}
+				wantRange := ga4DateRange{StartDate: "2022-09-01", EndDate: tt.dateEnd.Add(-time.Nanosecond).Format("2006-01-02")}
+				if len(request.DateRanges) != 1 || request.DateRanges[0] != wantRange || len(request.Metrics) != 2 || request.Metrics[0].Name != tt.metric.Metric {
+					t.Errorf("unexpected request body %v", request)
+				}
+
+				dimensions := []string{}
+				for _, dimension := range request.Dimensions {
+					dimensions = append(dimensions, dimension.Name)
+				}
+				rows, found := reportRows[strings.Join(dimensions, ",")]
+				if !found {
+					t.Errorf("unexpected dimensions %v", dimensions)
+				}
+				end := len(rows)
+				if len(dimensions) == 1 && dimensions[0] == "date" && request.Offset < end {
+					end = request.Offset + 1
+				}
+
+				page := []string{}
+				for _, row := range rows[request.Offset:end] {
+					dimensionValues, metricValues := []string{}, []string{}
+					for i, value := range row {
+						if i < len(dimensions) {
+							dimensionValues = append(dimensionValues, fmt.Sprintf(`{"value": %q}`, value))
+						} else {
+							metricValues = append(metricValues, fmt.Sprintf(`{"value": %q}`, value))
+						}
+					}
+					page = append(page, fmt.Sprintf(`{"dimensionValues": [%s], "metricValues": [%s]}`, strings.Join(dimensionValues, ","), strings.Join(metricValues, ",")))
+				}
+				res.Write([]byte(fmt.Sprintf(`{"rows": [%s], "rowCount": %d}`, strings.Join(page, ","), len(rows))))
+			}))
+			defer server.Close()
+
+			client, err := newGa4Client(config.Ga4Params{
+				PropertyId:  "123",
+				AccessToken: "token",
+				Endpoint:    server.URL,
+				Metrics:     map[string]config.Ga4Metric{"Revenue": tt.metric},
+				Attributes:  map[string][]string{"DeviceType": {"deviceCategory", "browser"}},
+			}, tt.filters)
+			if err != nil {
+				t.Fatalf("newGa4Client() error = %v", err)
+			}
+			got, err := client.getMetric("Revenue", dateStart, tt.dateEnd, utils.TimeStep{Duration: tt.step})
+			if err != nil {
+				t.Fatalf("getMetric() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("getMetric() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGa4Client_periodEnd(t *testing.T) {
+	client, err := newGa4Client(config.Ga4Params{PropertyId: "123", TimeZone: "Europe/Lisbon"}, config.CollectFilters{})
+	if err != nil {
+		t.Fatalf("newGa4Client() error = %v", err)
+	}
+	now := time.Date(2022, 9, 2, 15, 30, 0, 0, time.UTC)
+	if got, want := client.periodEnd(now, 24*time.Hour), time.Date(2022, 9, 1, 23, 0, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("periodEnd() daily = %v, want %v", got, want)
+	}
+	if got, want := client.periodEnd(now, time.Hour), time.Date(2022, 9, 2, 15, 0, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("periodEnd() hourly = %v, want %v", got, want)
+	}
+
+	if _, err := newGa4Client(config.Ga4Params{}, config.CollectFilters{}); err == nil {
+		t.Errorf("newGa4Client() expected an error without propertyId")
+	}
+	if _, err := newGa4Client(config.Ga4Params{PropertyId: "123", Metrics: map[string]config.Ga4Metric{"Revenue": {Metric: "purchaseRevenue", Aggregation: "max"}}}, config.CollectFilters{}); err == nil {
+		t.Errorf("newGa4Client() expected an error for invalid aggregations")
+	}
+}
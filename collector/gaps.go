@@ -0,0 +1,105 @@
+package collector
+
+import (
+	"sort"
+	"time"
+
+	"github.com/ftfmtavares/anomalies-detector/config"
+	"github.com/ftfmtavares/anomalies-detector/utils"
+)
+
+//FillGaps returns a copy of the site data with the time steps missing from every series filled according to the gap strategy, unchanged if none is set
+//The gap settings are validated with the rest of the configuration, by config.ApplicationConfig.Validate
+//The expected time steps are those of the analysed period on the grid of the earliest collected time step of each metric, a slot holding any collected time step
+//not being missing, so time steps shifted by daylight saving changes are kept as they are
+func FillGaps(siteData SiteData, gaps *config.GapParams) SiteData {
+	timeStep, err := utils.ParseTimeStep(siteData.TimeStep)
+	if gaps == nil || err != nil || timeStep.Duration <= 0 {
+		return siteData
+	}
+
+	res := siteData
+	res.Metrics = make([]MetricData, len(siteData.Metrics))
+	for i, metricData := range siteData.Metrics {
+		res.Metrics[i] = copyMetricData(metricData)
+
+		//Anchoring the grid on the earliest time step of the metric and extending it back to the start of the period
+		var anchor time.Time
+		for _, data := range metricData.AttributeData {
+			if len(data) > 0 && (anchor.IsZero() || data[0].DateStart.Before(anchor)) {
+				anchor = data[0].DateStart
+			}
+		}
+		if anchor.IsZero() {
+			continue
+		}
+		first := anchor
+		if !siteData.DateStart.IsZero() && anchor.After(siteData.DateStart) {
+			first = timeStep.Next(anchor, -timeStep.Index(siteData.DateStart, anchor))
+		}
+		end := siteData.DateEnd
+		if end.IsZero() {
+			for _, data := range metricData.AttributeData {
+				if len(data) > 0 && timeStep.Next(data[len(data)-1].DateStart, 1).After(end) {
+					end = timeStep.Next(data[len(data)-1].DateStart, 1)
+				}
+			}
+		}
+
+		for _, attribute := range metricData.Attributes {
+			res.Metrics[i].AttributeData[attribute] = fillSeries(res.Metrics[i].AttributeData[attribute], first, end, timeStep, gaps.Strategy)
+		}
+	}
+	return res
+}
+
+//fillSeries adds the time steps missing from a series between first and end, the last one ending by end, and fills them with the given strategy
+func fillSeries(data []TimeStepData, first, end time.Time, timeStep utils.TimeStep, strategy string) []TimeStepData {
+	sort.SliceStable(data, func(i, j int) bool { return data[i].DateStart.Before(data[j].DateStart) })
+
+	res := make([]TimeStepData, 0, len(data))
+	ind := 0
+	for slot := first; !timeStep.Next(slot, 1).After(end); slot = timeStep.Next(slot, 1) {
+		covered := false
+		for ind < len(data) && data[ind].DateStart.Before(timeStep.Next(slot, 1)) {
+			covered = covered || !data[ind].DateStart.Before(slot)
+			res = append(res, data[ind])
+			ind++
+		}
+		if !covered {
+			res = append(res, TimeStepData{DateStart: slot, Filled: strategy != config.GapMarkMissing, Missing: strategy == config.GapMarkMissing})
+		}
+	}
+	res = append(res, data[ind:]...)
+
+	if strategy == config.GapInterpolate {
+		interpolateFilled(res)
+	}
+	return res
+}
+
+//interpolateFilled sets the value of the filled time steps of a series by linear interpolation in time between the nearest collected ones
+//Filled time steps before the first or after the last collected one take its value
+func interpolateFilled(data []TimeStepData) {
+	previous := -1
+	for ind := 0; ind <= len(data); ind++ {
+		if ind < len(data) && data[ind].Filled {
+			continue
+		}
+		for gap := previous + 1; gap < ind; gap++ {
+			switch {
+			case previous < 0 && ind == len(data):
+				data[gap].Value = 0
+			case previous < 0:
+				data[gap].Value = data[ind].Value
+			case ind == len(data):
+				data[gap].Value = data[previous].Value
+			default:
+				span := data[ind].DateStart.Sub(data[previous].DateStart).Seconds()
+				elapsed := data[gap].DateStart.Sub(data[previous].DateStart).Seconds()
+				data[gap].Value = data[previous].Value + (data[ind].Value-data[previous].Value)*elapsed/span
+			}
+		}
+		previous = ind
+	}
+}
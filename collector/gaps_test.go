@@ -0,0 +1,71 @@
+package collector
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ftfmtavares/anomalies-detector/config"
+)
+
+func TestFillGaps(t *testing.T) {
+	timeRef := time.Date(2022, 9, 1, 0, 0, 0, 0, time.UTC)
+	hour := func(h int) time.Time { return timeRef.Add(time.Duration(h) * time.Hour) }
+
+	//Collected from 01:00, the period starting at 00:00, with 02:00 and 03:00 missing and 05:00 shifted by 30 minutes
+	siteData := SiteData{SiteId: "shop", TimeStep: "1h", DateStart: timeRef, DateEnd: hour(6), Metrics: []MetricData{{
+		Metric:     "Revenue",
+		Attributes: []string{"Total"},
+		AttributeData: map[string][]TimeStepData{"Total": {
+			{DateStart: hour(1), Value: 10, Samples: 1},
+			{DateStart: hour(4), Value: 40, Samples: 1},
+			{DateStart: hour(5).Add(30 * time.Minute), Value: 50, Samples: 1},
+		}},
+	}}}
+
+	tests := []struct {
+		name     string
+		strategy string
+		want     []TimeStepData
+	}{
+		{
+			name:     "Zero fill",
+			strategy: config.GapZeroFill,
+			want: []TimeStepData{
+				{DateStart: hour(0), Filled: true}, {DateStart: hour(1), Value: 10, Samples: 1}, {DateStart: hour(2), Filled: true}, {DateStart: hour(3), Filled: true},
+				{DateStart: hour(4), Value: 40, Samples: 1}, {DateStart: hour(5).Add(30 * time.Minute), Value: 50, Samples: 1},
+			},
+		},
+		{
+			name:     "Interpolate",
+			strategy: config.GapInterpolate,
+			want: []TimeStepData{
+				{DateStart: hour(0), Value: 10, Filled: true}, {DateStart: hour(1), Value: 10, Samples: 1}, {DateStart: hour(2), Value: 20, Filled: true}, {DateStart: hour(3), Value: 30, Filled: true},
+				{DateStart: hour(4), Value: 40, Samples: 1}, {DateStart: hour(5).Add(30 * time.Minute), Value: 50, Samples: 1},
+			},
+		},
+		{
+			name:     "Mark missing",
+			strategy: config.GapMarkMissing,
+			want: []TimeStepData{
+				{DateStart: hour(0), Missing: true}, {DateStart: hour(1), Value: 10, Samples: 1}, {DateStart: hour(2), Missing: true}, {DateStart: hour(3), Missing: true},
+				{DateStart: hour(4), Value: 40, Samples: 1}, {DateStart: hour(5).Add(30 * time.Minute), Value: 50, Samples: 1},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := FillGaps(siteData, &config.GapParams{Strategy: tt.strategy})
+			if !reflect.DeepEqual(got.Metrics[0].AttributeData["Total"], tt.want) {
+				t.Errorf("FillGaps() = %+v, want %+v", got.Metrics[0].AttributeData["Total"], tt.want)
+			}
+			if len(siteData.Metrics[0].AttributeData["Total"]) != 3 {
+				t.Errorf("FillGaps() changed the given site data")
+			}
+		})
+	}
+
+	if got := FillGaps(siteData, nil); !reflect.DeepEqual(got, siteData) {
+		t.Errorf("FillGaps() without gap settings = %+v, want the site data unchanged", got)
+	}
+}
@@ -0,0 +1,138 @@
+package collector
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ftfmtavares/anomalies-detector/config"
+	"github.com/ftfmtavares/anomalies-detector/utils"
+)
+
+//httpApiClient collects metrics from a JSON HTTP API, one request per metric
+type httpApiClient struct {
+	siteId   string
+	url      string
+	token    string
+	username string
+	password string
+	headers  map[string]string
+	metrics  []string
+	units    map[string]string
+	client   *http.Client
+}
+
+//httpApiResponse provides the structure of the metric responses expected from the API
+//AttributeData field maps each attribute path ("Total" for the total) to its time steps, Unit field being used when none is configured
+type httpApiResponse struct {
+	Unit          string                   `json:"unit"`
+	AttributeData map[string][]httpApiStep `json:"attributeData"`
+}
+
+//httpApiStep provides the structure of a single time step on the API responses, 1 sample being assumed when samples are missing
+type httpApiStep struct {
+	DateStart time.Time `json:"dateStart"`
+	Value     float64   `json:"value"`
+	Samples   *int      `json:"samples"`
+}
+
+//newHttpApiClient creates a httpApiClient for the given site from the given parameters
+func newHttpApiClient(siteId string, params config.HttpParams) *httpApiClient {
+	return &httpApiClient{
+		siteId:   siteId,
+		url:      params.Url,
+		token:    utils.ResolveSecret(params.BearerToken),
+		username: utils.ResolveSecret(params.Username),
+		password: utils.ResolveSecret(params.Password),
+		headers:  params.Headers,
+		metrics:  params.Metrics,
+		units:    params.Units,
+		client:   utils.NewHttpClient(30 * time.Second),
+	}
+}
+
+//openHttpApi creates the JSON HTTP API client of a dataset
+func openHttpApi(dataSet config.Dataset, siteData *SiteData, timeAgo, timeStep time.Duration) (sourceCollection, error) {
+	client := newHttpApiClient(dataSet.SiteId, *dataSet.Http)
+	return sourceCollection{
+		metrics: client.metricNames(),
+		getMetric: func(metric string) (MetricData, error) {
+			return client.getMetric(metric, siteData.DateStart, siteData.DateEnd, timeStep)
+		},
+	}, nil
+}
+
+//metricNames returns the configured metrics
+func (client *httpApiClient) metricNames() []string {
+	return client.metrics
+}
+
+//getMetric requests a metric for the given period and converts the response into MetricData
+//It returns an error if the request fails, if the API doesn't answer with a 2xx status or if the response can't be decoded
+func (client *httpApiClient) getMetric(metric string, dateStart, dateEnd time.Time, timeStep time.Duration) (MetricData, error) {
+	req, err := http.NewRequest(http.MethodGet, client.requestUrl(metric, dateStart, dateEnd, timeStep), nil)
+	if err != nil {
+		return MetricData{}, fmt.Errorf("http api - %s", err.Error())
+	}
+	req.Header.Set("Accept", "application/json")
+	for name, value := range client.headers {
+		req.Header.Set(name, utils.ResolveSecret(value))
+	}
+	if client.token != "" {
+		req.Header.Set("Authorization", "Bearer "+client.token)
+	} else if client.username != "" {
+		req.SetBasicAuth(client.username, client.password)
+	}
+
+	resp, err := client.client.Do(req)
+	if err != nil {
+		return MetricData{}, fmt.Errorf("http api - %s", err.Error())
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return MetricData{}, fmt.Errorf("http api - unexpected status %s", resp.Status)
+	}
+
+	var apiResp httpApiResponse
+	if err := json.NewDecoder(resp.Body).Decode(&apiResp); err != nil {
+		return MetricData{}, fmt.Errorf("http api - %s", err.Error())
+	}
+
+	//Converting the response time steps, keeping only those within the requested period
+	series := map[string][]TimeStepData{}
+	for attribute, steps := range apiResp.AttributeData {
+		data := []TimeStepData{}
+		for _, step := range steps {
+			if step.DateStart.Before(dateStart) || !step.DateStart.Before(dateEnd) {
+				continue
+			}
+			samples := 1
+			if step.Samples != nil {
+				samples = *step.Samples
+			}
+			data = append(data, TimeStepData{DateStart: step.DateStart, Value: step.Value, Samples: samples})
+		}
+		series[attribute] = data
+	}
+
+	unit, found := client.units[metric]
+	if !found {
+		unit = apiResp.Unit
+	}
+	return metricDataFromSeries(metric, unit, series), nil
+}
+
+//requestUrl replaces the placeholders of the url template by the escaped request values
+func (client *httpApiClient) requestUrl(metric string, dateStart, dateEnd time.Time, timeStep time.Duration) string {
+	return strings.NewReplacer(
+		"{siteId}", url.QueryEscape(client.siteId),
+		"{metric}", url.QueryEscape(metric),
+		"{start}", url.QueryEscape(dateStart.UTC().Format(time.RFC3339)),
+		"{end}", url.QueryEscape(dateEnd.UTC().Format(time.RFC3339)),
+		"{step}", strconv.FormatInt(int64(timeStep.Seconds()), 10),
+	).Replace(client.url)
+}
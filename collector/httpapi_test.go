@@ -0,0 +1,89 @@
+package collector
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ftfmtavares/anomalies-detector/config"
+)
+
+func TestHttpApiClient_getMetric(t *testing.T) {
+	dateStart := time.Date(2022, 9, 1, 0, 0, 0, 0, time.UTC)
+	dateEnd := dateStart.AddDate(0, 0, 2)
+	day := 24 * time.Hour
+
+	//The last time step of each response is outside the requested period
+	responses := map[string]string{
+		"Revenue": `{"unit": "$", "attributeData": {
+			"Browser>Chrome": [{"dateStart": "2022-09-02T00:00:00Z", "value": 70, "samples": 30}, {"dateStart": "2022-09-01T00:00:00Z", "value": 60, "samples": 25}],
+			"Total": [{"dateStart": "2022-09-01T00:00:00Z", "value": 100, "samples": 40}, {"dateStart": "2022-09-02T00:00:00Z", "value": 150, "samples": 60}, {"dateStart": "2022-09-03T00:00:00Z", "value": 90, "samples": 35}]}}`,
+		"Basket Size": `{"unit": "items", "attributeData": {"Total": [{"dateStart": "2022-09-01T00:00:00Z", "value": 2.5}]}}`,
+		"Malformed":   `{"attributeData": [`,
+	}
+
+	tests := []struct {
+		name    string
+		params  config.HttpParams
+		metric  string
+		want    MetricData
+		wantErr bool
+	}{
+		{
+			name:   "Bearer token with configured unit",
+			params: config.HttpParams{BearerToken: "token", Units: map[string]string{"Revenue": "€"}},
+			metric: "Revenue",
+			want: MetricData{Metric: "Revenue", Unit: "€", Attributes: []string{"Total", "Browser>Chrome"}, AttributeData: map[string][]TimeStepData{
+				"Total":          {{DateStart: dateStart, Value: 100, Samples: 40}, {DateStart: dateStart.Add(day), Value: 150, Samples: 60}},
+				"Browser>Chrome": {{DateStart: dateStart, Value: 60, Samples: 25}, {DateStart: dateStart.Add(day), Value: 70, Samples: 30}},
+			}},
+		},
+		{
+			name:   "Basic authentication with response unit and missing samples",
+			params: config.HttpParams{Username: "user", Password: "secret"},
+			metric: "Basket Size",
+			want: MetricData{Metric: "Basket Size", Unit: "items", Attributes: []string{"Total"}, AttributeData: map[string][]TimeStepData{
+				"Total": {{DateStart: dateStart, Value: 2.5, Samples: 1}},
+			}},
+		},
+		{name: "Malformed response", params: config.HttpParams{BearerToken: "token"}, metric: "Malformed", wantErr: true},
+		{name: "Unknown metric", params: config.HttpParams{BearerToken: "token"}, metric: "Conversion", wantErr: true},
+		{name: "Unauthorized", metric: "Revenue", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+				query := req.URL.Query()
+				if req.URL.Path != "/sites/site-1/metrics" || query.Get("start") != "2022-09-01T00:00:00Z" || query.Get("end") != "2022-09-03T00:00:00Z" ||
+					query.Get("step") != "86400" || req.Header.Get("X-Api-Version") != "2" {
+					t.Errorf("unexpected request %s", req.URL.String())
+				}
+				username, password, basic := req.BasicAuth()
+				if req.Header.Get("Authorization") != "Bearer token" && !(basic && username == "user" && password == "secret") {
+					res.WriteHeader(http.StatusUnauthorized)
+					return
+				}
+				response, found := responses[query.Get("metric")]
+				if !found {
+					res.WriteHeader(http.StatusNotFound)
+					return
+				}
+				res.Write([]byte(response))
+			}))
+			defer server.Close()
+
+			tt.params.Url = server.URL + "/sites/{siteId}/metrics?metric={metric}&start={start}&end={end}&step={step}"
+			tt.params.Headers = map[string]string{"X-Api-Version": "2"}
+			client := newHttpApiClient("site-1", tt.params)
+			got, err := client.getMetric(tt.metric, dateStart, dateEnd, day)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("getMetric() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("getMetric() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
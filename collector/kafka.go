@@ -0,0 +1,66 @@
+//go:build kafka || full
+
+package collector
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/ftfmtavares/anomalies-detector/config"
+	"github.com/ftfmtavares/anomalies-detector/utils"
+
+	"github.com/segmentio/kafka-go"
+)
+
+//defaultKafkaGroupId is the consumer group committing the read offsets when none is configured
+const defaultKafkaGroupId = "anomalies-detector"
+
+//init registers the Kafka consumer, built in with the kafka build tag
+func init() {
+	utils.RegisterIntegration(utils.IntegrationKafka)
+	kafkaConsumer = consumeKafka
+}
+
+//consumeKafka implements ConsumeKafka with the kafka-go reader
+func consumeKafka(ctx context.Context, siteId string, params config.KafkaParams, buffer *StreamBuffer) error {
+	if len(params.Brokers) == 0 || params.Topic == "" {
+		return errors.New("kafka - brokers and topic are required")
+	}
+	groupId := params.GroupId
+	if groupId == "" {
+		groupId = defaultKafkaGroupId
+	}
+
+	//Connecting over TLS if requested, with the outbound CA bundle and client certificate if configured
+	readerConfig := kafka.ReaderConfig{
+		Brokers: params.Brokers,
+		Topic:   params.Topic,
+		GroupID: groupId,
+	}
+	if params.Tls {
+		tlsConfig := utils.TlsConfig
+		if tlsConfig == nil {
+			tlsConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+		}
+		readerConfig.Dialer = &kafka.Dialer{Timeout: 10 * time.Second, DualStack: true, TLS: tlsConfig}
+	}
+	reader := kafka.NewReader(readerConfig)
+	defer reader.Close()
+
+	for {
+		message, err := reader.ReadMessage(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("kafka - %s", err.Error())
+		}
+		if err := buffer.Add(message.Value, message.Time); err != nil {
+			log.Printf("Skipping event - %s - partition %d offset %d - %s\n", siteId, message.Partition, message.Offset, err.Error())
+		}
+	}
+}
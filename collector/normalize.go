@@ -0,0 +1,74 @@
+package collector
+
+import (
+	"math"
+)
+
+//Const block defines the units of the metrics expressed as a percentage of their baseline and as z-scores
+const (
+	PercentOfBaselineUnit = "% of baseline"
+	ZScoreUnit            = "z-score"
+)
+
+//PercentOfBaseline returns a copy of a metric whose values are expressed as a percentage of the baseline of their series, so metrics of different sites and units compare on one axis
+//The baseline is the median value of the series, not distorted by the anomalies it holds, and series whose baseline is zero are left out as they have no percentage
+func PercentOfBaseline(metricData MetricData) MetricData {
+	res := copyMetricData(metricData)
+	res.Unit = PercentOfBaselineUnit
+	res.Attributes = []string{}
+	for _, attribute := range metricData.Attributes {
+		data := res.AttributeData[attribute]
+		values := make([]float64, len(data))
+		for ind, stepData := range data {
+			values[ind] = stepData.Value
+		}
+		baseline := math.Abs(medianValue(values))
+		if baseline == 0 {
+			delete(res.AttributeData, attribute)
+			continue
+		}
+		for ind := range data {
+			data[ind].Value = data[ind].Value * 100 / baseline
+		}
+		res.Attributes = append(res.Attributes, attribute)
+	}
+	return res
+}
+
+//ZScores returns a copy of a metric whose values are expressed as their z-score, the number of standard deviations from the mean of their series
+//Mean and standard deviation are those the 3-sigmas method computes over the whole series, missing time steps being left out and kept as missing
+//Constant series have no deviation, so all their time steps get a zero z-score
+func ZScores(metricData MetricData) MetricData {
+	res := copyMetricData(metricData)
+	res.Unit = ZScoreUnit
+	for _, attribute := range res.Attributes {
+		data := res.AttributeData[attribute]
+		count, sum := 0, 0.0
+		for _, stepData := range data {
+			if !stepData.Missing {
+				count++
+				sum += stepData.Value
+			}
+		}
+		if count == 0 {
+			continue
+		}
+		mean := sum / float64(count)
+		sd := 0.0
+		for _, stepData := range data {
+			if !stepData.Missing {
+				sd += math.Pow(stepData.Value-mean, 2)
+			}
+		}
+		sd = math.Sqrt(sd / float64(count))
+
+		for ind := range data {
+			if data[ind].Missing || sd == 0 {
+				data[ind].Value = 0
+				continue
+			}
+			data[ind].Value = (data[ind].Value - mean) / sd
+		}
+	}
+	return res
+}
@@ -0,0 +1,66 @@
+package collector
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestPercentOfBaseline(t *testing.T) {
+	timeRef := time.Date(2022, 9, 1, 0, 0, 0, 0, time.UTC)
+	series := func(values ...float64) []TimeStepData {
+		data := []TimeStepData{}
+		for i, value := range values {
+			data = append(data, TimeStepData{DateStart: timeRef.AddDate(0, 0, i), Value: value, Samples: 1})
+		}
+		return data
+	}
+
+	metricData := MetricData{Metric: "Revenue", Unit: "EUR", Attributes: []string{"Total", "Browser>Edge", "Browser>Lynx"}, AttributeData: map[string][]TimeStepData{
+		"Total":        series(900, 1000, 1100, 1000, 3000),
+		"Browser>Edge": series(40, 50, 50, 60, 200),
+		"Browser>Lynx": series(0, 0, 0, 0, 1),
+	}}
+	got := PercentOfBaseline(metricData)
+	want := MetricData{Metric: "Revenue", Unit: PercentOfBaselineUnit, Attributes: []string{"Total", "Browser>Edge"}, AttributeData: map[string][]TimeStepData{
+		"Total":        series(90, 100, 110, 100, 300),
+		"Browser>Edge": series(80, 100, 100, 120, 400),
+	}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("PercentOfBaseline() = %v, want %v", got, want)
+	}
+	if metricData.Unit != "EUR" || metricData.AttributeData["Total"][0].Value != 900 {
+		t.Errorf("PercentOfBaseline() changed the collected data")
+	}
+}
+
+func TestZScores(t *testing.T) {
+	timeRef := time.Date(2022, 9, 1, 0, 0, 0, 0, time.UTC)
+	series := func(values ...float64) []TimeStepData {
+		data := []TimeStepData{}
+		for i, value := range values {
+			data = append(data, TimeStepData{DateStart: timeRef.AddDate(0, 0, i), Value: value, Samples: 1})
+		}
+		return data
+	}
+
+	withGap := series(2, 4, 4, 4, 5, 5, 7, 9, 1000)
+	withGap[8].Missing = true
+	metricData := MetricData{Metric: "Revenue", Unit: "EUR", Attributes: []string{"Total", "Browser>Lynx"}, AttributeData: map[string][]TimeStepData{
+		"Total":        withGap,
+		"Browser>Lynx": series(3, 3, 3),
+	}}
+	got := ZScores(metricData)
+	wantTotal := series(-1.5, -0.5, -0.5, -0.5, 0, 0, 1, 2, 0)
+	wantTotal[8].Missing = true
+	want := MetricData{Metric: "Revenue", Unit: ZScoreUnit, Attributes: []string{"Total", "Browser>Lynx"}, AttributeData: map[string][]TimeStepData{
+		"Total":        wantTotal,
+		"Browser>Lynx": series(0, 0, 0),
+	}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ZScores() = %v, want %v", got, want)
+	}
+	if metricData.Unit != "EUR" || metricData.AttributeData["Total"][0].Value != 2 {
+		t.Errorf("ZScores() changed the collected data")
+	}
+}
@@ -0,0 +1,168 @@
+package collector
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ftfmtavares/anomalies-detector/config"
+	"github.com/ftfmtavares/anomalies-detector/utils"
+)
+
+//prometheusClient collects metrics through the Prometheus HTTP query_range API
+type prometheusClient struct {
+	url     string
+	token   string
+	queries map[string]config.PrometheusQuery
+	client  *http.Client
+}
+
+//prometheusResponse provides the structure of a query_range API response
+//Each value is a [unix timestamp, "value"] pair
+type prometheusResponse struct {
+	Status string `json:"status"`
+	Error  string `json:"error"`
+	Data   struct {
+		ResultType string `json:"resultType"`
+		Result     []struct {
+			Metric map[string]string `json:"metric"`
+			Values [][2]interface{}  `json:"values"`
+		} `json:"result"`
+	} `json:"data"`
+}
+
+//newPrometheusClient creates a prometheusClient from the given parameters
+func newPrometheusClient(params config.PrometheusParams) *prometheusClient {
+	return &prometheusClient{
+		url:     strings.TrimSuffix(params.Url, "/"),
+		token:   utils.ResolveSecret(params.BearerToken),
+		queries: params.Queries,
+		client:  utils.NewHttpClient(30 * time.Second),
+	}
+}
+
+//openPrometheus creates the Prometheus client of a dataset
+func openPrometheus(dataSet config.Dataset, siteData *SiteData, timeAgo, timeStep time.Duration) (sourceCollection, error) {
+	client := newPrometheusClient(*dataSet.Prometheus)
+	return sourceCollection{
+		metrics: client.metricNames(),
+		getMetric: func(metric string) (MetricData, error) {
+			return client.getMetric(metric, siteData.DateStart, siteData.DateEnd, timeStep)
+		},
+	}, nil
+}
+
+//metricNames returns the names of all metrics with configured queries in alphabetical order
+func (client *prometheusClient) metricNames() []string {
+	names := []string{}
+	for name := range client.queries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+//getMetric collects a metric for the given period with one value per time step, held by the "Total" attribute
+//Every query is evaluated at the end of each time step, so the value at a given evaluation time belongs to the time step that finishes there
+//Time steps without value (missing or non finite) are left out
+func (client *prometheusClient) getMetric(metric string, dateStart, dateEnd time.Time, timeStep time.Duration) (MetricData, error) {
+	query, found := client.queries[metric]
+	if !found {
+		return MetricData{}, fmt.Errorf("no prometheus query for metric %s", metric)
+	}
+
+	values, err := client.queryRange(query.Query, dateStart.Add(timeStep), dateEnd, timeStep)
+	if err != nil {
+		return MetricData{}, err
+	}
+	samples := map[int64]float64{}
+	if query.SamplesQuery != "" {
+		if samples, err = client.queryRange(query.SamplesQuery, dateStart.Add(timeStep), dateEnd, timeStep); err != nil {
+			return MetricData{}, err
+		}
+	}
+
+	//Ordering the evaluation times and converting them to time steps
+	timestamps := []int64{}
+	for timestamp := range values {
+		timestamps = append(timestamps, timestamp)
+	}
+	sort.Slice(timestamps, func(i, j int) bool { return timestamps[i] < timestamps[j] })
+
+	data := []TimeStepData{}
+	for _, timestamp := range timestamps {
+		stepSamples := 1
+		if query.SamplesQuery != "" {
+			stepSamples = int(math.Round(samples[timestamp]))
+		}
+		data = append(data, TimeStepData{DateStart: time.UnixMilli(timestamp).Add(-timeStep), Value: values[timestamp], Samples: stepSamples})
+	}
+
+	return MetricData{
+		Metric:        metric,
+		Unit:          query.Unit,
+		Attributes:    []string{"Total"},
+		AttributeData: map[string][]TimeStepData{"Total": data},
+	}, nil
+}
+
+//queryRange runs a PromQL expression over the given period and returns its finite values by evaluation time in unix milliseconds
+//It returns an error if the request fails or if the expression doesn't return exactly one series
+func (client *prometheusClient) queryRange(query string, start, end time.Time, step time.Duration) (map[int64]float64, error) {
+	params := url.Values{}
+	params.Set("query", query)
+	params.Set("start", strconv.FormatInt(start.Unix(), 10))
+	params.Set("end", strconv.FormatInt(end.Unix(), 10))
+	params.Set("step", strconv.FormatFloat(step.Seconds(), 'f', -1, 64))
+
+	req, err := http.NewRequest(http.MethodGet, client.url+"/api/v1/query_range?"+params.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	if client.token != "" {
+		req.Header.Set("Authorization", "Bearer "+client.token)
+	}
+
+	resp, err := client.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("prometheus - %s", err.Error())
+	}
+	defer resp.Body.Close()
+
+	//Prometheus reports query errors on the body with a non 2xx status, so the body is decoded before checking it
+	var promResp prometheusResponse
+	if err := json.NewDecoder(resp.Body).Decode(&promResp); err != nil {
+		return nil, fmt.Errorf("prometheus - unexpected status %s", resp.Status)
+	}
+	if promResp.Status != "success" {
+		return nil, fmt.Errorf("prometheus - %s", promResp.Error)
+	}
+	if promResp.Data.ResultType != "matrix" {
+		return nil, fmt.Errorf("prometheus - unexpected result type %s", promResp.Data.ResultType)
+	}
+	if len(promResp.Data.Result) != 1 {
+		return nil, fmt.Errorf("prometheus - query \"%s\" returned %d series, a single one is expected", query, len(promResp.Data.Result))
+	}
+
+	res := map[int64]float64{}
+	for _, pair := range promResp.Data.Result[0].Values {
+		timestamp, okTimestamp := pair[0].(float64)
+		text, okValue := pair[1].(string)
+		if !okTimestamp || !okValue {
+			return nil, errors.New("prometheus - malformed sample")
+		}
+		value, err := strconv.ParseFloat(text, 64)
+		if err != nil || math.IsNaN(value) || math.IsInf(value, 0) {
+			continue
+		}
+		res[int64(math.Round(timestamp*1000))] = value
+	}
+	return res, nil
+}
@@ -0,0 +1,102 @@
+package collector
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ftfmtavares/anomalies-detector/config"
+)
+
+func TestPrometheusClient_getMetric(t *testing.T) {
+	dateStart := time.Date(2022, 9, 1, 0, 0, 0, 0, time.UTC)
+	dateEnd := dateStart.AddDate(0, 0, 3)
+	day := 24 * time.Hour
+
+	//Values are evaluated at the end of each day, the last one being NaN
+	values := fmt.Sprintf(`[[%d, "100.5"], [%d, "120"], [%d, "NaN"]]`, dateStart.Add(day).Unix(), dateStart.Add(2*day).Unix(), dateEnd.Unix())
+	samples := fmt.Sprintf(`[[%d, "40"], [%d, "52"], [%d, "47"]]`, dateStart.Add(day).Unix(), dateStart.Add(2*day).Unix(), dateEnd.Unix())
+	responses := map[string]string{
+		"revenue":   `{"status": "success", "data": {"resultType": "matrix", "result": [{"metric": {}, "values": ` + values + `}]}}`,
+		"visitors":  `{"status": "success", "data": {"resultType": "matrix", "result": [{"metric": {}, "values": ` + samples + `}]}}`,
+		"by_device": `{"status": "success", "data": {"resultType": "matrix", "result": [{"metric": {"device": "a"}, "values": []}, {"metric": {"device": "b"}, "values": []}]}}`,
+		"invalid":   `{"status": "error", "errorType": "bad_data", "error": "parse error"}`,
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		query := req.URL.Query()
+		if req.URL.Path != "/api/v1/query_range" || req.Header.Get("Authorization") != "Bearer token" || query.Get("step") != "86400" ||
+			query.Get("start") != fmt.Sprint(dateStart.Add(day).Unix()) || query.Get("end") != fmt.Sprint(dateEnd.Unix()) {
+			t.Errorf("unexpected request %s", req.URL.String())
+		}
+		if query.Get("query") == "invalid" {
+			res.WriteHeader(http.StatusBadRequest)
+		}
+		res.Write([]byte(responses[query.Get("query")]))
+	}))
+	defer server.Close()
+
+	client := newPrometheusClient(config.PrometheusParams{
+		Url:         server.URL + "/",
+		BearerToken: "token",
+		Queries: map[string]config.PrometheusQuery{
+			"Revenue": {Query: "revenue", SamplesQuery: "visitors", Unit: "€"},
+			"Basket":  {Query: "revenue"},
+			"Devices": {Query: "by_device"},
+			"Invalid": {Query: "invalid"},
+			"Samples": {Query: "revenue", SamplesQuery: "invalid"},
+		},
+	})
+
+	tests := []struct {
+		name    string
+		metric  string
+		want    MetricData
+		wantErr bool
+	}{
+		{
+			name:   "Values with companion samples, skipping NaN",
+			metric: "Revenue",
+			want: MetricData{Metric: "Revenue", Unit: "€", Attributes: []string{"Total"}, AttributeData: map[string][]TimeStepData{"Total": {
+				{DateStart: dateStart, Value: 100.5, Samples: 40},
+				{DateStart: dateStart.Add(day), Value: 120, Samples: 52},
+			}}},
+		},
+		{
+			name:   "Values without samples query",
+			metric: "Basket",
+			want: MetricData{Metric: "Basket", Attributes: []string{"Total"}, AttributeData: map[string][]TimeStepData{"Total": {
+				{DateStart: dateStart, Value: 100.5, Samples: 1},
+				{DateStart: dateStart.Add(day), Value: 120, Samples: 1},
+			}}},
+		},
+		{name: "Several series", metric: "Devices", wantErr: true},
+		{name: "Query error", metric: "Invalid", wantErr: true},
+		{name: "Samples query error", metric: "Samples", wantErr: true},
+		{name: "Metric without query", metric: "Conversion", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := client.getMetric(tt.metric, dateStart, dateEnd, day)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("getMetric() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			for i := range got.AttributeData["Total"] {
+				got.AttributeData["Total"][i].DateStart = got.AttributeData["Total"][i].DateStart.UTC()
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("getMetric() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if names := client.metricNames(); !reflect.DeepEqual(names, []string{"Basket", "Devices", "Invalid", "Revenue", "Samples"}) {
+		t.Errorf("metricNames() = %v", names)
+	}
+}
@@ -0,0 +1,71 @@
+package collector
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/ftfmtavares/anomalies-detector/config"
+	"github.com/ftfmtavares/anomalies-detector/utils"
+)
+
+//sourceCollection provides the structure for the collection of a dataset from its source, with the names of all its metrics and the function reading each of them
+//Close field, if set, releases the source once all metrics are read
+type sourceCollection struct {
+	metrics   []string
+	getMetric func(metric string) (MetricData, error)
+	close     func()
+}
+
+//source provides the structure for a data source of the collector, selected for the datasets with its section
+//Open field prepares the collection of a dataset over the period of its site data, which it may move to the data available at the source
+//Optional sources are only opened if their integration is built in, the file of their build tag setting the open function
+type source struct {
+	name       string
+	configured func(dataSet config.Dataset) bool
+	open       func(dataSet config.Dataset, siteData *SiteData, timeAgo, timeStep time.Duration) (sourceCollection, error)
+}
+
+//sources lists the data sources in order of precedence, datasets without any of their sections being generated
+var sources = []*source{
+	{name: "csv", configured: func(dataSet config.Dataset) bool { return dataSet.Csv != nil }, open: openCsv},
+	{name: "sql", configured: func(dataSet config.Dataset) bool { return dataSet.Sql != nil }, open: openSql},
+	{name: "prometheus", configured: func(dataSet config.Dataset) bool { return dataSet.Prometheus != nil }, open: openPrometheus},
+	{name: "http", configured: func(dataSet config.Dataset) bool { return dataSet.Http != nil }, open: openHttpApi},
+	{name: utils.IntegrationGa4, configured: func(dataSet config.Dataset) bool { return dataSet.Ga4 != nil }},
+}
+
+//registerSource sets the open function of an optional source, being called on init by the file of its build tag
+func registerSource(name string, open func(dataSet config.Dataset, siteData *SiteData, timeAgo, timeStep time.Duration) (sourceCollection, error)) {
+	for _, source := range sources {
+		if source.name == name {
+			source.open = open
+		}
+	}
+}
+
+//datasetSource returns the source a dataset is collected from, nil if it's generated
+func datasetSource(dataSet config.Dataset) *source {
+	for _, source := range sources {
+		if source.configured(dataSet) {
+			return source
+		}
+	}
+	return nil
+}
+
+//CheckSource returns an error if the source of a dataset, or its database driver, requires an integration not built into the binary
+func CheckSource(dataSet config.Dataset) error {
+	source := datasetSource(dataSet)
+	if source == nil {
+		return nil
+	}
+	if source.open == nil {
+		return utils.CheckIntegration(source.name)
+	}
+	if dataSet.Sql != nil && sqlDrivers[dataSet.Sql.Driver] {
+		if err := utils.CheckIntegration(dataSet.Sql.Driver); err != nil {
+			return fmt.Errorf("sql - %s", err.Error())
+		}
+	}
+	return nil
+}
@@ -0,0 +1,41 @@
+package collector
+
+import (
+	"testing"
+
+	"github.com/ftfmtavares/anomalies-detector/config"
+	"github.com/ftfmtavares/anomalies-detector/utils"
+)
+
+func TestCheckSource(t *testing.T) {
+	tests := []struct {
+		name        string
+		dataSet     config.Dataset
+		integration string
+	}{
+		{name: "Generated data", dataSet: config.Dataset{SiteId: "shop"}},
+		{name: "Core source", dataSet: config.Dataset{SiteId: "shop", Prometheus: &config.PrometheusParams{}}},
+		{name: "Google Analytics", dataSet: config.Dataset{SiteId: "shop", Ga4: &config.Ga4Params{}}, integration: utils.IntegrationGa4},
+		{name: "MySQL database", dataSet: config.Dataset{SiteId: "shop", Sql: &config.SqlParams{Driver: "mysql"}}, integration: utils.IntegrationMysql},
+		{name: "PostgreSQL database", dataSet: config.Dataset{SiteId: "shop", Sql: &config.SqlParams{Driver: "postgres"}}, integration: utils.IntegrationPostgres},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			//Optional sources are only accepted when their integration is built in, which depends on the build tags of the test
+			wantErr := tt.integration != "" && utils.CheckIntegration(tt.integration) != nil
+			if err := CheckSource(tt.dataSet); (err != nil) != wantErr {
+				t.Errorf("CheckSource() error = %v, wantErr %v", err, wantErr)
+			}
+		})
+	}
+}
+
+func TestGetDataSourceNotBuiltIn(t *testing.T) {
+	if utils.CheckIntegration(utils.IntegrationGa4) == nil {
+		t.Skip("ga4 integration built in")
+	}
+	dataSet := config.Dataset{SiteId: "shop", TimeAgo: "7d", TimeStep: "1d", MetricesList: []string{"all"}, Ga4: &config.Ga4Params{}, SiteCollectFilters: &config.CollectFilters{}}
+	if siteData := GetData(dataSet); len(siteData.Metrics) != 0 {
+		t.Errorf("GetData() = %d metrics, want none for a source not built in", len(siteData.Metrics))
+	}
+}
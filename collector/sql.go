@@ -0,0 +1,160 @@
+package collector
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ftfmtavares/anomalies-detector/config"
+	"github.com/ftfmtavares/anomalies-detector/utils"
+)
+
+//sqlDrivers lists the supported database drivers, each registered by the file of its build tag
+var sqlDrivers = map[string]bool{utils.IntegrationPostgres: true, utils.IntegrationMysql: true}
+
+//sqlTlsDsns maps the supported database drivers to the function applying the outbound TLS settings to their data source names,
+//MySQL being added by the file of its build tag along with its driver
+var sqlTlsDsns = map[string]func(dsn string) (string, error){utils.IntegrationPostgres: utils.PostgresTlsDsn}
+
+//sqlPlaceholders lists the query placeholders replaced by bound parameters
+var sqlPlaceholders = []string{"{start}", "{end}", "{step}"}
+
+//sqlCollector collects metrics by querying a database through database/sql
+type sqlCollector struct {
+	driver  string
+	db      *sql.DB
+	queries map[string]config.SqlQuery
+}
+
+//sqlRow represents a single result row of a metric query
+type sqlRow struct {
+	dateStart time.Time
+	attribute sql.NullString
+	value     float64
+	samples   int64
+}
+
+//newSqlCollector opens the configured database, the connection itself being only established by the first query
+//It returns an error if the driver isn't supported or isn't built into the binary
+func newSqlCollector(params config.SqlParams) (*sqlCollector, error) {
+	if !sqlDrivers[params.Driver] {
+		return nil, fmt.Errorf("sql - unsupported driver \"%s\", it must be \"postgres\" or \"mysql\"", params.Driver)
+	}
+	if err := utils.CheckIntegration(params.Driver); err != nil {
+		return nil, fmt.Errorf("sql - %s", err.Error())
+	}
+	dsn, err := sqlTlsDsns[params.Driver](utils.ResolveSecret(params.Dsn))
+	if err != nil {
+		return nil, fmt.Errorf("sql - %s", err.Error())
+	}
+	db, err := sql.Open(params.Driver, dsn)
+	if err != nil {
+		return nil, fmt.Errorf("sql - %s", err.Error())
+	}
+	return &sqlCollector{driver: params.Driver, db: db, queries: params.Queries}, nil
+}
+
+//openSql opens the database of a dataset, closed once all its metrics are read
+func openSql(dataSet config.Dataset, siteData *SiteData, timeAgo, timeStep time.Duration) (sourceCollection, error) {
+	collector, err := newSqlCollector(*dataSet.Sql)
+	if err != nil {
+		return sourceCollection{}, err
+	}
+	return sourceCollection{
+		metrics: collector.metricNames(),
+		getMetric: func(metric string) (MetricData, error) {
+			return collector.getMetric(metric, siteData.DateStart, siteData.DateEnd, timeStep)
+		},
+		close: func() { collector.db.Close() },
+	}, nil
+}
+
+//metricNames returns the names of all metrics with configured queries in alphabetical order
+func (collector *sqlCollector) metricNames() []string {
+	names := []string{}
+	for name := range collector.queries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+//getMetric runs the query of a metric for the given period and groups its rows by attribute path
+func (collector *sqlCollector) getMetric(metric string, dateStart, dateEnd time.Time, timeStep time.Duration) (MetricData, error) {
+	query, found := collector.queries[metric]
+	if !found {
+		return MetricData{}, fmt.Errorf("no sql query for metric %s", metric)
+	}
+
+	statement, args := bindSqlPlaceholders(query.Query, collector.driver, dateStart, dateEnd, timeStep)
+	rows, err := collector.db.Query(statement, args...)
+	if err != nil {
+		return MetricData{}, fmt.Errorf("sql - %s", err.Error())
+	}
+	defer rows.Close()
+
+	results := []sqlRow{}
+	for rows.Next() {
+		var row sqlRow
+		if err := rows.Scan(&row.dateStart, &row.attribute, &row.value, &row.samples); err != nil {
+			return MetricData{}, fmt.Errorf("sql - %s", err.Error())
+		}
+		results = append(results, row)
+	}
+	if err := rows.Err(); err != nil {
+		return MetricData{}, fmt.Errorf("sql - %s", err.Error())
+	}
+
+	return metricDataFromSqlRows(metric, query.Unit, results), nil
+}
+
+//bindSqlPlaceholders replaces the {start}, {end} and {step} placeholders of a query by the driver bind parameters
+//It returns the resulting statement along with the parameter values in the order they are referenced
+func bindSqlPlaceholders(query, driver string, dateStart, dateEnd time.Time, timeStep time.Duration) (string, []interface{}) {
+	values := map[string]interface{}{
+		"{start}": dateStart,
+		"{end}":   dateEnd,
+		"{step}":  int64(timeStep.Seconds()),
+	}
+
+	var statement strings.Builder
+	args := []interface{}{}
+	for {
+		//Finding the next placeholder occurrence
+		next, placeholder := -1, ""
+		for _, candidate := range sqlPlaceholders {
+			if ind := strings.Index(query, candidate); ind != -1 && (next == -1 || ind < next) {
+				next, placeholder = ind, candidate
+			}
+		}
+		if next == -1 {
+			statement.WriteString(query)
+			return statement.String(), args
+		}
+
+		args = append(args, values[placeholder])
+		statement.WriteString(query[:next])
+		if driver == "postgres" {
+			statement.WriteString("$" + strconv.Itoa(len(args)))
+		} else {
+			statement.WriteString("?")
+		}
+		query = query[next+len(placeholder):]
+	}
+}
+
+//metricDataFromSqlRows groups the result rows of a metric query by attribute path, rows without path belonging to "Total"
+func metricDataFromSqlRows(metric, unit string, rows []sqlRow) MetricData {
+	series := map[string][]TimeStepData{}
+	for _, row := range rows {
+		attribute := "Total"
+		if row.attribute.Valid && row.attribute.String != "" {
+			attribute = row.attribute.String
+		}
+		series[attribute] = append(series[attribute], TimeStepData{DateStart: row.dateStart, Value: row.value, Samples: int(row.samples)})
+	}
+	return metricDataFromSeries(metric, unit, series)
+}
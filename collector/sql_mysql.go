@@ -0,0 +1,36 @@
+//go:build mysql || full
+
+package collector
+
+import (
+	"github.com/ftfmtavares/anomalies-detector/utils"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+//mysqlTlsName is the name the outbound TLS configuration is registered with on the MySQL driver
+const mysqlTlsName = "outbound"
+
+//init registers the MySQL driver, built in with the mysql build tag, along with the outbound TLS settings of its connections
+func init() {
+	utils.RegisterIntegration(utils.IntegrationMysql)
+	sqlTlsDsns[utils.IntegrationMysql] = mysqlTlsDsn
+}
+
+//mysqlTlsDsn makes the MySQL data source names with tls=true connect with the outbound TLS configuration, if any
+//Other tls values (false, preferred, skip-verify or a registered name) are kept as they are
+//It returns an error if the data source name can't be parsed
+func mysqlTlsDsn(dsn string) (string, error) {
+	dsnConfig, err := mysql.ParseDSN(dsn)
+	if err != nil {
+		return "", err
+	}
+	if utils.TlsConfig == nil || dsnConfig.TLSConfig != "true" {
+		return dsn, nil
+	}
+	if err := mysql.RegisterTLSConfig(mysqlTlsName, utils.TlsConfig.Clone()); err != nil {
+		return "", err
+	}
+	dsnConfig.TLSConfig = mysqlTlsName
+	return dsnConfig.FormatDSN(), nil
+}
@@ -0,0 +1,40 @@
+//go:build mysql || full
+
+package collector
+
+import (
+	"crypto/tls"
+	"testing"
+
+	"github.com/ftfmtavares/anomalies-detector/utils"
+)
+
+func Test_mysqlTlsDsn(t *testing.T) {
+	tests := []struct {
+		name      string
+		tlsConfig *tls.Config
+		dsn       string
+		want      string
+		wantErr   bool
+	}{
+		{name: "TLS with the outbound configuration", tlsConfig: &tls.Config{ServerName: "db"}, dsn: "app:secret@tcp(db:3306)/metrics?parseTime=true&tls=true", want: "app:secret@tcp(db:3306)/metrics?parseTime=true&tls=outbound"},
+		{name: "TLS skipping verification kept", tlsConfig: &tls.Config{}, dsn: "app@tcp(db:3306)/metrics?tls=skip-verify", want: "app@tcp(db:3306)/metrics?tls=skip-verify"},
+		{name: "Plain connection kept", tlsConfig: &tls.Config{}, dsn: "app@tcp(db:3306)/metrics", want: "app@tcp(db:3306)/metrics"},
+		{name: "No outbound configuration", dsn: "app@tcp(db:3306)/metrics?tls=true", want: "app@tcp(db:3306)/metrics?tls=true"},
+		{name: "Invalid data source name", tlsConfig: &tls.Config{}, dsn: "app@tcp(db:3306)", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			utils.TlsConfig = tt.tlsConfig
+			defer func() { utils.TlsConfig = nil }()
+
+			got, err := mysqlTlsDsn(tt.dsn)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("mysqlTlsDsn() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("mysqlTlsDsn() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,15 @@
+//go:build postgres || full
+
+package collector
+
+import (
+	"github.com/ftfmtavares/anomalies-detector/utils"
+
+	//Registering the PostgreSQL driver of the SQL source
+	_ "github.com/lib/pq"
+)
+
+//init registers the PostgreSQL driver, built in with the postgres build tag
+func init() {
+	utils.RegisterIntegration(utils.IntegrationPostgres)
+}
@@ -0,0 +1,85 @@
+package collector
+
+import (
+	"database/sql"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ftfmtavares/anomalies-detector/config"
+	"github.com/ftfmtavares/anomalies-detector/utils"
+)
+
+func TestBindSqlPlaceholders(t *testing.T) {
+	dateStart := time.Date(2022, 9, 1, 0, 0, 0, 0, time.UTC)
+	dateEnd := dateStart.AddDate(0, 0, 7)
+	query := "SELECT time_bucket({step} * interval '1 second', ts), path, sum(revenue), count(*) FROM orders WHERE ts >= {start} AND ts < {end} GROUP BY 1, 2"
+
+	tests := []struct {
+		name          string
+		driver        string
+		wantStatement string
+	}{
+		{
+			name:          "PostgreSQL numbered parameters",
+			driver:        "postgres",
+			wantStatement: "SELECT time_bucket($1 * interval '1 second', ts), path, sum(revenue), count(*) FROM orders WHERE ts >= $2 AND ts < $3 GROUP BY 1, 2",
+		},
+		{
+			name:          "MySQL positional parameters",
+			driver:        "mysql",
+			wantStatement: "SELECT time_bucket(? * interval '1 second', ts), path, sum(revenue), count(*) FROM orders WHERE ts >= ? AND ts < ? GROUP BY 1, 2",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			statement, args := bindSqlPlaceholders(query, tt.driver, dateStart, dateEnd, 24*time.Hour)
+			if statement != tt.wantStatement {
+				t.Errorf("bindSqlPlaceholders() statement = %s, want %s", statement, tt.wantStatement)
+			}
+			if wantArgs := []interface{}{int64(86400), dateStart, dateEnd}; !reflect.DeepEqual(args, wantArgs) {
+				t.Errorf("bindSqlPlaceholders() args = %v, want %v", args, wantArgs)
+			}
+		})
+	}
+}
+
+func TestMetricDataFromSqlRows(t *testing.T) {
+	day := time.Date(2022, 9, 1, 0, 0, 0, 0, time.UTC)
+	rows := []sqlRow{
+		{dateStart: day.AddDate(0, 0, 1), attribute: sql.NullString{String: "Browser>Chrome", Valid: true}, value: 70, samples: 30},
+		{dateStart: day.AddDate(0, 0, 1), attribute: sql.NullString{}, value: 150, samples: 60},
+		{dateStart: day, attribute: sql.NullString{String: "Total", Valid: true}, value: 100, samples: 40},
+		{dateStart: day, attribute: sql.NullString{String: "Browser>Chrome", Valid: true}, value: 60, samples: 25},
+		{dateStart: day, attribute: sql.NullString{String: "Browser>Edge", Valid: true}, value: 10, samples: 5},
+	}
+	want := MetricData{Metric: "Revenue", Unit: "€", Attributes: []string{"Total", "Browser>Chrome", "Browser>Edge"}, AttributeData: map[string][]TimeStepData{
+		"Total":          {{DateStart: day, Value: 100, Samples: 40}, {DateStart: day.AddDate(0, 0, 1), Value: 150, Samples: 60}},
+		"Browser>Chrome": {{DateStart: day, Value: 60, Samples: 25}, {DateStart: day.AddDate(0, 0, 1), Value: 70, Samples: 30}},
+		"Browser>Edge":   {{DateStart: day, Value: 10, Samples: 5}},
+	}}
+
+	if got := metricDataFromSqlRows("Revenue", "€", rows); !reflect.DeepEqual(got, want) {
+		t.Errorf("metricDataFromSqlRows() = %v, want %v", got, want)
+	}
+}
+
+func TestNewSqlCollector(t *testing.T) {
+	if _, err := newSqlCollector(config.SqlParams{Driver: "sqlite", Dsn: "file.db"}); err == nil {
+		t.Errorf("newSqlCollector() expected an error for unsupported drivers")
+	}
+	if err := utils.CheckIntegration(utils.IntegrationPostgres); err != nil {
+		if _, err := newSqlCollector(config.SqlParams{Driver: "postgres", Dsn: "postgres://user@localhost/analytics"}); err == nil {
+			t.Errorf("newSqlCollector() expected an error for drivers not built in")
+		}
+		t.Skip(err.Error())
+	}
+	collector, err := newSqlCollector(config.SqlParams{Driver: "postgres", Dsn: "postgres://user@localhost/analytics", Queries: map[string]config.SqlQuery{"Revenue": {}, "Basket": {}}})
+	if err != nil {
+		t.Fatalf("newSqlCollector() error = %v", err)
+	}
+	defer collector.db.Close()
+	if names := collector.metricNames(); !reflect.DeepEqual(names, []string{"Basket", "Revenue"}) {
+		t.Errorf("metricNames() = %v", names)
+	}
+}
@@ -0,0 +1,232 @@
+package collector
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ftfmtavares/anomalies-detector/config"
+	"github.com/ftfmtavares/anomalies-detector/utils"
+)
+
+//kafkaConsumer reads the events of a topic into a buffer, set by the Kafka integration when built in
+var kafkaConsumer func(ctx context.Context, siteId string, params config.KafkaParams, buffer *StreamBuffer) error
+
+//ConsumeKafka reads the events of the configured topic into the buffer until the context is cancelled
+//Events that can't be parsed are logged and skipped, their offsets being committed as the others so they are never read again
+//It returns an error if the Kafka integration isn't built in, if the parameters are incomplete or if the topic can't be read anymore
+func ConsumeKafka(ctx context.Context, siteId string, params config.KafkaParams, buffer *StreamBuffer) error {
+	if kafkaConsumer == nil {
+		return fmt.Errorf("kafka - %s", utils.CheckIntegration(utils.IntegrationKafka).Error())
+	}
+	return kafkaConsumer(ctx, siteId, params, buffer)
+}
+
+//StreamBuffer aggregates raw streamed events into the time steps of every metric and attribute path, in memory
+//Only the time steps of the analysed period are kept, older ones being dropped on each snapshot
+type StreamBuffer struct {
+	mu        sync.Mutex
+	params    config.KafkaParams
+	timeStep  time.Duration
+	retention time.Duration
+	buckets   map[string]map[string]map[int64]*stepAggregator
+}
+
+//NewStreamBuffer creates a StreamBuffer for the given stream parameters, time step and analysed period
+//It returns an error if the aggregation is invalid or if no metric can be read from the events
+func NewStreamBuffer(params config.KafkaParams, timeStep, retention time.Duration) (*StreamBuffer, error) {
+	if params.MetricField == "" && params.Metric == "" {
+		return nil, errors.New("kafka - either metricField or metric is required")
+	}
+	if params.Aggregation == "" {
+		params.Aggregation = "sum"
+	}
+	if params.Aggregation != "sum" && params.Aggregation != "mean" {
+		return nil, fmt.Errorf("kafka - invalid aggregation \"%s\", it must be \"sum\" or \"mean\"", params.Aggregation)
+	}
+	if timeStep <= 0 {
+		return nil, errors.New("kafka - invalid time step")
+	}
+	return &StreamBuffer{params: params, timeStep: timeStep, retention: retention, buckets: map[string]map[string]map[int64]*stepAggregator{}}, nil
+}
+
+//Add parses a JSON event and adds it to the time step of its total and of every level of each configured attribute
+//Events missing an attribute field only count for the levels above it
+//It returns an error if the event can't be parsed or misses its metric, time or value
+func (buffer *StreamBuffer) Add(message []byte, messageTime time.Time) error {
+	var event map[string]interface{}
+	decoder := json.NewDecoder(bytes.NewReader(message))
+	decoder.UseNumber()
+	if err := decoder.Decode(&event); err != nil {
+		return fmt.Errorf("kafka - invalid event - %s", err.Error())
+	}
+
+	metric := buffer.params.Metric
+	if buffer.params.MetricField != "" {
+		if metric = streamString(event[buffer.params.MetricField]); metric == "" {
+			return fmt.Errorf("kafka - event without field %s", buffer.params.MetricField)
+		}
+	}
+	timestamp := messageTime
+	if buffer.params.TimestampField != "" {
+		var err error
+		if timestamp, err = streamTime(event[buffer.params.TimestampField]); err != nil {
+			return err
+		}
+	}
+	value, samples := 1.0, 1
+	if buffer.params.ValueField != "" {
+		var err error
+		if value, err = streamNumber(event[buffer.params.ValueField]); err != nil {
+			return fmt.Errorf("kafka - field %s - %s", buffer.params.ValueField, err.Error())
+		}
+	}
+	if buffer.params.SamplesField != "" {
+		count, err := streamNumber(event[buffer.params.SamplesField])
+		if err != nil {
+			return fmt.Errorf("kafka - field %s - %s", buffer.params.SamplesField, err.Error())
+		}
+		samples = int(math.Round(count))
+	}
+
+	//Building the attribute paths the event counts for
+	paths := []string{"Total"}
+	for attribute, fields := range buffer.params.Attributes {
+		path := attribute
+		for _, field := range fields {
+			fieldValue := streamString(event[field])
+			if fieldValue == "" {
+				break
+			}
+			path += ">" + strings.ReplaceAll(fieldValue, ">", " ")
+			paths = append(paths, path)
+		}
+	}
+
+	step := timestamp.Truncate(buffer.timeStep).UnixNano()
+	buffer.mu.Lock()
+	defer buffer.mu.Unlock()
+	if buffer.buckets[metric] == nil {
+		buffer.buckets[metric] = map[string]map[int64]*stepAggregator{}
+	}
+	for _, path := range paths {
+		if buffer.buckets[metric][path] == nil {
+			buffer.buckets[metric][path] = map[int64]*stepAggregator{}
+		}
+		if buffer.buckets[metric][path][step] == nil {
+			buffer.buckets[metric][path][step] = newStepAggregator(buffer.params.Aggregation)
+		}
+		buffer.buckets[metric][path][step].add(value, samples)
+	}
+	return nil
+}
+
+//Snapshot returns the buffered data of the analysed period ending at the start of the ongoing time step, ready for the analyser
+//Metrics are selected and filtered according to the dataset configuration, as collected ones, while time steps before the period are dropped for every buffered metric
+func (buffer *StreamBuffer) Snapshot(dataSet config.Dataset, now time.Time) SiteData {
+	siteData := SiteData{SiteId: dataSet.SiteId, TimeStep: dataSet.TimeStep, Metrics: []MetricData{}}
+	siteData.DateEnd = now.Truncate(buffer.timeStep)
+	siteData.DateStart = siteData.DateEnd.Add(-1 * buffer.retention)
+
+	buffer.mu.Lock()
+	defer buffer.mu.Unlock()
+
+	//Dropping the expired time steps of every metric, including the ones the dataset doesn't cover, so the buffer doesn't grow forever
+	allMetrics := []string{}
+	for metric, paths := range buffer.buckets {
+		for path, steps := range paths {
+			for step := range steps {
+				if time.Unix(0, step).Before(siteData.DateStart) {
+					delete(steps, step)
+				}
+			}
+			if len(steps) == 0 {
+				delete(paths, path)
+			}
+		}
+		if len(paths) == 0 {
+			delete(buffer.buckets, metric)
+			continue
+		}
+		allMetrics = append(allMetrics, metric)
+	}
+	sort.Strings(allMetrics)
+	coveredMetrics := dataSet.MetricesList
+	if len(dataSet.MetricesList) > 0 && strings.ToLower(dataSet.MetricesList[0]) == "all" {
+		coveredMetrics = allMetrics
+	}
+
+	for _, metric := range coveredMetrics {
+		series := map[string][]TimeStepData{}
+		for path, steps := range buffer.buckets[metric] {
+			for step, aggregator := range steps {
+				dateStart := time.Unix(0, step).UTC()
+				if !dateStart.Before(siteData.DateEnd) {
+					continue
+				}
+				value, samples := aggregator.result()
+				series[path] = append(series[path], TimeStepData{DateStart: dateStart, Value: value, Samples: samples})
+			}
+		}
+		if len(series) == 0 {
+			continue
+		}
+
+		metricData := metricDataFromSeries(metric, buffer.params.Units[metric], series)
+		if dataSet.SiteCollectFilters != nil {
+			metricData = filterData(metricData, *dataSet.SiteCollectFilters)
+		}
+		siteData.Metrics = append(siteData.Metrics, metricData)
+	}
+
+	return siteData
+}
+
+//streamString converts an event field into a string, missing and null fields being empty
+func streamString(value interface{}) string {
+	if value == nil {
+		return ""
+	}
+	if text, ok := value.(string); ok {
+		return text
+	}
+	return fmt.Sprint(value)
+}
+
+//streamNumber converts an event field holding a number or a numeric string into a float
+func streamNumber(value interface{}) (float64, error) {
+	switch typed := value.(type) {
+	case json.Number:
+		return typed.Float64()
+	case string:
+		return strconv.ParseFloat(typed, 64)
+	}
+	return 0, errors.New("missing or not a number")
+}
+
+//streamTime converts an event field holding an RFC 3339 string or unix seconds into a time
+func streamTime(value interface{}) (time.Time, error) {
+	switch typed := value.(type) {
+	case json.Number:
+		seconds, err := typed.Float64()
+		if err != nil {
+			return time.Time{}, fmt.Errorf("kafka - invalid timestamp - %s", err.Error())
+		}
+		return time.Unix(0, int64(seconds*float64(time.Second))).UTC(), nil
+	case string:
+		timestamp, err := time.Parse(time.RFC3339, typed)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("kafka - invalid timestamp - %s", err.Error())
+		}
+		return timestamp, nil
+	}
+	return time.Time{}, errors.New("kafka - missing timestamp")
+}
@@ -0,0 +1,135 @@
+package collector
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ftfmtavares/anomalies-detector/config"
+)
+
+func TestStreamBuffer_Snapshot(t *testing.T) {
+	timeRef := time.Date(2022, 9, 1, 0, 0, 0, 0, time.UTC)
+	hour := time.Hour
+	dataSet := config.Dataset{SiteId: "shop", TimeStep: "1h", MetricesList: []string{"all"}}
+
+	tests := []struct {
+		name    string
+		params  config.KafkaParams
+		events  []string
+		want    []MetricData
+		wantErr []bool
+	}{
+		{
+			name:   "Summed events with attribute levels",
+			params: config.KafkaParams{MetricField: "type", TimestampField: "ts", ValueField: "amount", Attributes: map[string][]string{"Location": {"country", "city"}}, Units: map[string]string{"Revenue": "€"}},
+			events: []string{
+				`{"type": "Revenue", "ts": "2022-09-01T00:10:00Z", "amount": 10, "country": "PT", "city": "Lisbon"}`,
+				`{"type": "Revenue", "ts": "2022-09-01T00:50:00Z", "amount": "5.5", "country": "PT"}`,
+				`{"type": "Revenue", "ts": 1661994000, "amount": 20, "country": "PT", "city": "Porto"}`,
+				`{"type": "Revenue", "ts": "2022-09-01T02:10:00Z", "amount": 100}`,
+			},
+			want: []MetricData{{Metric: "Revenue", Unit: "€", Attributes: []string{"Total", "Location>PT", "Location>PT>Lisbon", "Location>PT>Porto"}, AttributeData: map[string][]TimeStepData{
+				"Total":              {{DateStart: timeRef, Value: 15.5, Samples: 2}, {DateStart: timeRef.Add(hour), Value: 20, Samples: 1}},
+				"Location>PT":        {{DateStart: timeRef, Value: 15.5, Samples: 2}, {DateStart: timeRef.Add(hour), Value: 20, Samples: 1}},
+				"Location>PT>Lisbon": {{DateStart: timeRef, Value: 10, Samples: 1}},
+				"Location>PT>Porto":  {{DateStart: timeRef.Add(hour), Value: 20, Samples: 1}},
+			}}},
+			wantErr: []bool{false, false, false, false},
+		},
+		{
+			name:   "Averaged events weighted by samples",
+			params: config.KafkaParams{Metric: "Latency", ValueField: "ms", SamplesField: "count", Aggregation: "mean"},
+			events: []string{
+				`{"ms": 100, "count": 3}`,
+				`{"ms": 200, "count": 1}`,
+			},
+			want: []MetricData{{Metric: "Latency", Attributes: []string{"Total"}, AttributeData: map[string][]TimeStepData{
+				"Total": {{DateStart: timeRef, Value: 125, Samples: 4}},
+			}}},
+			wantErr: []bool{false, false},
+		},
+		{
+			name:   "Invalid and incomplete events",
+			params: config.KafkaParams{MetricField: "type", TimestampField: "ts"},
+			events: []string{
+				`{"type": "Visits", "ts": "2022-08-01T00:00:00Z"}`,
+				`{"type": "Visits", "ts": "2022-09-01T00:00:00Z"}`,
+				`{"type": "Visits", "ts": "yesterday"}`,
+				`{"ts": "2022-09-01T00:00:00Z"}`,
+				`{"type": `,
+			},
+			want: []MetricData{{Metric: "Visits", Attributes: []string{"Total"}, AttributeData: map[string][]TimeStepData{
+				"Total": {{DateStart: timeRef, Value: 1, Samples: 1}},
+			}}},
+			wantErr: []bool{false, false, true, true, true},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buffer, err := NewStreamBuffer(tt.params, hour, 24*hour)
+			if err != nil {
+				t.Fatalf("NewStreamBuffer() error = %v", err)
+			}
+
+			//Events without a timestamp field use the message time
+			for i, event := range tt.events {
+				if err := buffer.Add([]byte(event), timeRef.Add(5*time.Minute)); (err != nil) != tt.wantErr[i] {
+					t.Errorf("Add() event %d error = %v, wantErr %v", i+1, err, tt.wantErr[i])
+				}
+			}
+
+			//The ongoing time step is left out of the snapshot
+			got := buffer.Snapshot(dataSet, timeRef.Add(2*hour+30*time.Minute))
+			if !got.DateStart.Equal(timeRef.Add(-22*hour)) || !got.DateEnd.Equal(timeRef.Add(2*hour)) {
+				t.Errorf("Snapshot() period = %v - %v", got.DateStart, got.DateEnd)
+			}
+			if !reflect.DeepEqual(got.Metrics, tt.want) {
+				t.Errorf("Snapshot() = %v, want %v", got.Metrics, tt.want)
+			}
+		})
+	}
+
+	if _, err := NewStreamBuffer(config.KafkaParams{Metric: "Visits", Aggregation: "max"}, hour, 24*hour); err == nil {
+		t.Errorf("NewStreamBuffer() expected an error for invalid aggregations")
+	}
+	if _, err := NewStreamBuffer(config.KafkaParams{}, hour, 24*hour); err == nil {
+		t.Errorf("NewStreamBuffer() expected an error without metric")
+	}
+}
+
+func TestStreamBuffer_SnapshotEvictsUncoveredMetrics(t *testing.T) {
+	timeRef := time.Date(2022, 9, 1, 0, 0, 0, 0, time.UTC)
+	hour := time.Hour
+	dataSet := config.Dataset{SiteId: "shop", TimeStep: "1h", MetricesList: []string{"Visits"}}
+
+	buffer, err := NewStreamBuffer(config.KafkaParams{MetricField: "type", TimestampField: "ts", Attributes: map[string][]string{"Location": {"country"}}}, hour, 24*hour)
+	if err != nil {
+		t.Fatalf("NewStreamBuffer() error = %v", err)
+	}
+	for _, event := range []string{
+		`{"type": "Visits", "ts": "2022-09-01T12:10:00Z"}`,
+		`{"type": "Orders", "ts": "2022-09-01T00:10:00Z", "country": "PT"}`,
+		`{"type": "Orders", "ts": "2022-09-02T00:10:00Z"}`,
+	} {
+		if err := buffer.Add([]byte(event), timeRef); err != nil {
+			t.Fatalf("Add() error = %v", err)
+		}
+	}
+
+	//Only the time step of the next day is kept for the metric the dataset doesn't cover
+	got := buffer.Snapshot(dataSet, timeRef.Add(25*hour))
+	if len(got.Metrics) != 1 || got.Metrics[0].Metric != "Visits" {
+		t.Errorf("Snapshot() = %v, want only Visits", got.Metrics)
+	}
+	orders := buffer.buckets["Orders"]
+	if len(orders) != 1 || len(orders["Total"]) != 1 || orders["Total"][timeRef.Add(24*hour).UnixNano()] == nil {
+		t.Errorf("Snapshot() kept Orders buckets %v", buffer.buckets["Orders"])
+	}
+
+	//Once all its time steps are expired the metric is dropped altogether
+	buffer.Snapshot(dataSet, timeRef.Add(50*hour))
+	if _, ok := buffer.buckets["Orders"]; ok {
+		t.Errorf("Snapshot() kept Orders buckets %v", buffer.buckets["Orders"])
+	}
+}
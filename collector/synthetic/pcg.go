@@ -0,0 +1,75 @@
+package synthetic
+
+import (
+	"hash/fnv"
+	"math/bits"
+	"math/rand"
+)
+
+//PCG-XSL-RR 128/64 multiplier, split in its high and low 64 bits
+const (
+	pcgMultiplierHigh = 2549297995355413924
+	pcgMultiplierLow  = 4865540595714422341
+)
+
+//pcgSource is a rand.Source64 implementing the PCG-XSL-RR 128/64 generator
+//Sources built with the same seed but different streams produce statistically independent sequences,
+//which keeps synthetic attribute series uncorrelated even when all of them derive from a single run seed
+type pcgSource struct {
+	stateHigh, stateLow         uint64
+	incrementHigh, incrementLow uint64
+}
+
+//newPcgSource returns a PCG source for the given seed and stream
+func newPcgSource(seed int64, stream uint64) *pcgSource {
+	src := &pcgSource{
+		incrementHigh: stream >> 63,
+		incrementLow:  stream<<1 | 1,
+	}
+	src.Seed(seed)
+	return src
+}
+
+//Seed resets the source state for the given seed keeping its stream
+func (src *pcgSource) Seed(seed int64) {
+	src.stateHigh, src.stateLow = 0, 0
+	src.step()
+	var carry uint64
+	src.stateLow, carry = bits.Add64(src.stateLow, uint64(seed), 0)
+	src.stateHigh += carry
+	src.step()
+}
+
+//step advances the 128 bits state as state * multiplier + increment
+func (src *pcgSource) step() {
+	high, low := bits.Mul64(src.stateLow, pcgMultiplierLow)
+	high += src.stateHigh*pcgMultiplierLow + src.stateLow*pcgMultiplierHigh
+	var carry uint64
+	src.stateLow, carry = bits.Add64(low, src.incrementLow, 0)
+	src.stateHigh, _ = bits.Add64(high, src.incrementHigh, carry)
+}
+
+//Uint64 returns the next pseudo-random 64 bits value
+func (src *pcgSource) Uint64() uint64 {
+	src.step()
+	return bits.RotateLeft64(src.stateHigh^src.stateLow, -int(src.stateHigh>>58))
+}
+
+//Int63 returns the next pseudo-random non-negative 63 bits value
+func (src *pcgSource) Int63() int64 {
+	return int64(src.Uint64() >> 1)
+}
+
+//DeriveSeed mixes a run seed with a key (e.g. a metric or site name) so each key gets its own seed
+func DeriveSeed(seed int64, key string) int64 {
+	hash := fnv.New64a()
+	hash.Write([]byte(key))
+	return seed ^ int64(hash.Sum64())
+}
+
+//attributeRand returns a random generator on its own PCG stream for a given generation stage and attribute path
+func attributeRand(seed int64, stage, path string) *rand.Rand {
+	hash := fnv.New64a()
+	hash.Write([]byte(stage + "/" + path))
+	return rand.New(newPcgSource(seed, hash.Sum64()))
+}
@@ -0,0 +1,60 @@
+package synthetic
+
+import (
+	"math"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestPcgSourceStreams(t *testing.T) {
+	const draws = 10000
+
+	sameA := attributeRand(1, "values", "Browser>Chrome")
+	sameB := attributeRand(1, "values", "Browser>Chrome")
+	other := attributeRand(1, "values", "Browser>Edge")
+
+	a := make([]float64, draws)
+	b := make([]float64, draws)
+	for i := 0; i < draws; i++ {
+		a[i] = sameA.Float64()
+		if sameB.Float64() != a[i] {
+			t.Fatalf("attributeRand() draw #%d differs for the same seed and stream", i)
+		}
+		b[i] = other.Float64()
+	}
+
+	//Pearson correlation between both streams should be close to 0
+	meanA, meanB := 0.0, 0.0
+	for i := range a {
+		meanA += a[i] / draws
+		meanB += b[i] / draws
+	}
+	cov, varA, varB := 0.0, 0.0, 0.0
+	for i := range a {
+		cov += (a[i] - meanA) * (b[i] - meanB)
+		varA += (a[i] - meanA) * (a[i] - meanA)
+		varB += (b[i] - meanB) * (b[i] - meanB)
+	}
+	if corr := cov / math.Sqrt(varA*varB); math.Abs(corr) > 0.05 {
+		t.Errorf("attributeRand() streams correlation = %f, want ~0", corr)
+	}
+	if math.Abs(meanA-0.5) > 0.02 {
+		t.Errorf("attributeRand() mean = %f, want ~0.5", meanA)
+	}
+}
+
+func TestGeneratorReproducible(t *testing.T) {
+	timeRef := time.Date(2022, 9, 1, 0, 0, 0, 0, time.UTC)
+
+	first, _ := New(DefaultOptions(42)).Generate("Revenue", timeRef.AddDate(0, 0, -30), timeRef, 24*time.Hour)
+	second, _ := New(DefaultOptions(42)).Generate("Revenue", timeRef.AddDate(0, 0, -30), timeRef, 24*time.Hour)
+	if !reflect.DeepEqual(first, second) {
+		t.Errorf("Generate() differs between runs with the same seed")
+	}
+
+	third, _ := New(DefaultOptions(43)).Generate("Revenue", timeRef.AddDate(0, 0, -30), timeRef, 24*time.Hour)
+	if reflect.DeepEqual(first.AttributeData["Total"], third.AttributeData["Total"]) {
+		t.Errorf("Generate() is the same for different seeds")
+	}
+}
@@ -0,0 +1,557 @@
+//Package synthetic generates random e-commerce metrics data following standard distributions with ocasional deviations
+//It's used as the application data source while there's no access to a data repository, and can be reused to test other detection code
+package synthetic
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"math/rand"
+	"time"
+
+	"github.com/ftfmtavares/anomalies-detector/config"
+	"github.com/ftfmtavares/anomalies-detector/utils"
+)
+
+//Options holds all the parameters of the data simulation
+//Metrics field lists the simulated metrics in the order they are returned when all metrics are requested
+//Tree field holds the attributes used on the simulation, every node splitting its parent data according to the nodes weights
+//OutlierProb field is the probability of a deviation starting on each time step of the main total data, spread through sub-attributes on every level
+//OutlierMaxSize field is the maximum number of time steps of each deviation, while OutlierDiffMultiplier field is its size in standard deviations (halved on every level)
+//SampleDivisionDeviation and ValueDivisionDeviation fields are the relative random variation when splitting samples and values through sub-attributes
+type Options struct {
+	Seed                    int64
+	Metrics                 []MetricParams
+	Tree                    []AttributeNode
+	OutlierProb             float64
+	OutlierMaxSize          int
+	OutlierDiffMultiplier   float64
+	SampleDivisionDeviation float64
+	ValueDivisionDeviation  float64
+}
+
+//MetricParams holds the metric mathematical parameters
+//Type field is one of the config metric types, config.MetricTypeSum, config.MetricTypeAverage or config.MetricTypeCount
+type MetricParams struct {
+	Name         string
+	Unit         string
+	Type         string
+	ValStdDev    float64
+	ValMean      float64
+	SampleStdDev float64
+	SampleMean   float64
+}
+
+//AttributeNode is the node structure that holds the attributes parameters
+type AttributeNode struct {
+	Name          string
+	Weight        float64
+	SubAttributes []AttributeNode
+}
+
+//Metric contains the generated data of a metric for each attribute/sub-values combination, "Total" holding the data without attribute
+//Outliers field lists the deviations injected into the data, the ground truth the detection can be scored against
+type Metric struct {
+	Metric        string            `json:"metric"`
+	Unit          string            `json:"unit"`
+	Attributes    []string          `json:"attributes"`
+	AttributeData map[string][]Step `json:"attributeData"`
+	Outliers      []Outlier         `json:"outliers"`
+}
+
+//Outlier represents a deviation injected into the data of an attribute/sub-values combination, from the start of its first time step to the end of its last one
+//Diff field is the deviation added to every time step value, which is also carried to the parent combinations
+type Outlier struct {
+	Attribute   string    `json:"attribute"`
+	PeriodStart time.Time `json:"periodStart"`
+	PeriodEnd   time.Time `json:"periodEnd"`
+	Diff        float64   `json:"diff"`
+}
+
+//Step represents the generated data of a single time step
+type Step struct {
+	DateStart time.Time `json:"dateStart"`
+	Value     float64   `json:"value"`
+	Samples   int       `json:"samples"`
+}
+
+//Generator simulates metrics data with the given options
+type Generator struct {
+	opts Options
+}
+
+//defaultMetricStats holds the distribution parameters of the default metrics, their units and types coming from the config registry
+var defaultMetricStats = map[string]MetricParams{
+	"Revenue": {ValStdDev: 20000, ValMean: 100000, SampleStdDev: 300, SampleMean: 1500},
+	"Basket":  {ValStdDev: 80, ValMean: 400, SampleStdDev: 300, SampleMean: 1500},
+	"Visits":  {ValStdDev: 4000, ValMean: 20000, SampleStdDev: 4000, SampleMean: 20000},
+}
+
+//defaultMetricParams returns the parameters of the default metrics, in the order of their definitions
+func defaultMetricParams() []MetricParams {
+	params := []MetricParams{}
+	for _, definition := range config.DefaultMetrics() {
+		metric := defaultMetricStats[definition.Name]
+		metric.Name, metric.Unit, metric.Type = definition.Name, definition.Unit, definition.Type
+		params = append(params, metric)
+	}
+	return params
+}
+
+//DefaultOptions returns the options used by the application, simulating Revenue, Basket and Visits metrics
+//over DeviceType and Browser attributes, with the given seed
+func DefaultOptions(seed int64) Options {
+	return Options{
+		Seed:    seed,
+		Metrics: defaultMetricParams(),
+		Tree: []AttributeNode{
+			{
+				Name: "DeviceType",
+				SubAttributes: []AttributeNode{
+					{Name: "Desktop", Weight: 50},
+					{Name: "Tablet", Weight: 10},
+					{Name: "Mobile", Weight: 40},
+				},
+			},
+			{
+				Name: "Browser",
+				SubAttributes: []AttributeNode{
+					{Name: "Chrome", Weight: 50, SubAttributes: []AttributeNode{
+						{Name: "v1", Weight: 5},
+						{Name: "v2", Weight: 15},
+						{Name: "v3", Weight: 80}}},
+					{Name: "Edge", Weight: 20},
+					{Name: "Firefox", Weight: 10},
+					{Name: "Safari", Weight: 20},
+				},
+			},
+		},
+		OutlierProb:             0.001,
+		OutlierMaxSize:          6,
+		OutlierDiffMultiplier:   20.0,
+		SampleDivisionDeviation: 0.2,
+		ValueDivisionDeviation:  0.4,
+	}
+}
+
+//ProfileOptions returns the options of a config simulation profile with the given seed, the settings it doesn't give keeping those of DefaultOptions
+//Metrics take the unit, type and distributions of the default metric with the same name for those they don't give, while Count metrics take the distribution of their samples from their values
+func ProfileOptions(seed int64, profile config.SimulationParams) Options {
+	opts := DefaultOptions(seed)
+	if len(profile.Metrics) > 0 {
+		defaults := map[string]MetricParams{}
+		for _, metric := range opts.Metrics {
+			defaults[metric.Name] = metric
+		}
+		opts.Metrics = []MetricParams{}
+		for _, simulated := range profile.Metrics {
+			metric := defaults[simulated.Name]
+			metric.Name = simulated.Name
+			if simulated.Unit != "" {
+				metric.Unit = simulated.Unit
+			}
+			if simulated.Type != "" {
+				metric.Type = simulated.Type
+			}
+			if simulated.Mean != 0 || simulated.StdDev != 0 {
+				metric.ValMean, metric.ValStdDev = simulated.Mean, simulated.StdDev
+			}
+			if simulated.SampleMean != 0 || simulated.SampleStdDev != 0 {
+				metric.SampleMean, metric.SampleStdDev = simulated.SampleMean, simulated.SampleStdDev
+			} else if metric.Type == config.MetricTypeCount && (simulated.Mean != 0 || simulated.StdDev != 0) {
+				metric.SampleMean, metric.SampleStdDev = simulated.Mean, simulated.StdDev
+			}
+			opts.Metrics = append(opts.Metrics, metric)
+		}
+	}
+	if len(profile.Attributes) > 0 {
+		opts.Tree = attributeNodes(profile.Attributes)
+	}
+	if profile.OutlierProb != nil {
+		opts.OutlierProb = *profile.OutlierProb
+	}
+	if profile.OutlierMaxSize > 0 {
+		opts.OutlierMaxSize = profile.OutlierMaxSize
+	}
+	if profile.OutlierDiffMultiplier > 0 {
+		opts.OutlierDiffMultiplier = profile.OutlierDiffMultiplier
+	}
+	return opts
+}
+
+//attributeNodes converts the simulated attributes of a config profile into an attributes tree
+func attributeNodes(attributes []config.SimulatedAttribute) []AttributeNode {
+	nodes := []AttributeNode{}
+	for _, attribute := range attributes {
+		nodes = append(nodes, AttributeNode{Name: attribute.Name, Weight: attribute.Weight, SubAttributes: attributeNodes(attribute.SubAttributes)})
+	}
+	return nodes
+}
+
+//New returns a Generator for the given options
+func New(opts Options) *Generator {
+	return &Generator{opts: opts}
+}
+
+//MetricNames returns the names of all simulated metrics
+func (g *Generator) MetricNames() []string {
+	names := []string{}
+	for _, metric := range g.opts.Metrics {
+		names = append(names, metric.Name)
+	}
+	return names
+}
+
+//Generate simulates a metric data over the given period and returns it
+//The simulation tries to create data as most realistic as possible following standard distributions and ocasional deviations in order to test the detection methods
+//Every attribute draws its random numbers from its own PCG stream derived from the options seed, so attribute series are independent and runs are reproducible
+func (g *Generator) Generate(metricName string, dateStart, dateEnd time.Time, timeStep time.Duration) (Metric, error) {
+	return g.GenerateSteps(metricName, dateStart, dateEnd, utils.TimeStep{Duration: timeStep})
+}
+
+//GenerateSteps simulates a metric data over the given period as Generate does, with time steps that may move by calendar days, weeks or months
+func (g *Generator) GenerateSteps(metricName string, dateStart, dateEnd time.Time, timeStep utils.TimeStep) (Metric, error) {
+	var metric MetricParams
+	found := false
+	for _, metricParams := range g.opts.Metrics {
+		if metricParams.Name == metricName {
+			metric = metricParams
+			found = true
+		}
+	}
+	if !found {
+		return Metric{}, fmt.Errorf("unknown metric %s", metricName)
+	}
+	if timeStep.Duration <= 0 {
+		return Metric{}, fmt.Errorf("invalid time step %s", timeStep.Duration)
+	}
+	if g.opts.OutlierProb > 0 && g.opts.OutlierMaxSize < 1 {
+		return Metric{}, fmt.Errorf("invalid outlier max size %d", g.opts.OutlierMaxSize)
+	}
+
+	//Deriving the metric seed so that different metrics of the same run don't share streams
+	seed := DeriveSeed(g.opts.Seed, metricName)
+
+	//Initializing the Metric object to be returned
+	metricData := Metric{Metric: metric.Name, Unit: metric.Unit, Attributes: []string{}, AttributeData: map[string][]Step{}, Outliers: []Outlier{}}
+
+	//Calculating and allocating the time steps for the main total data (no attribute)
+	metricData = allocMasterData(metricData, "Total", dateStart, dateEnd, timeStep)
+
+	//Randomly generating standard distribution number of samples for the main total data (no attribute)
+	fillMasterSamples(metricData.AttributeData["Total"], metric, seed)
+
+	//Randomly adding deviations on the metric values for the main total data (no attribute)
+	metricData.Outliers = addMasterOutliers(metricData.AttributeData["Total"], metric, g.opts.OutlierProb, g.opts.OutlierMaxSize, g.opts.OutlierDiffMultiplier, timeStep, seed)
+
+	//Looping each main attribute
+	for _, attributeNode := range g.opts.Tree {
+
+		//Allocating and adding the time steps for all main attribute/sub-values combinations following the attributes tree recursively
+		metricData = allocAttributesData(metricData, attributeNode, attributeNode.Name, dateStart, dateEnd, timeStep)
+
+		//Distributing main total number of samples through the several attribute/sub-values combinations following the attributes tree recursively
+		metricData = splitSamples(metricData, attributeNode, metricData.AttributeData["Total"], attributeNode.Name, g.opts.SampleDivisionDeviation, seed)
+
+		//Randomly adding deviations on the metric values for all main attribute/sub-values combinations following the attributes tree recursively
+		//Added deviations are then returned and added to the top layer attribute/sub-values combinations, including the main total
+		if len(attributeNode.SubAttributes) > 0 {
+			var subOutliersInc []float64
+			metricData, subOutliersInc = addAttributesOutliers(metricData, attributeNode, metric, attributeNode.Name, g.opts.OutlierProb/float64(len(attributeNode.SubAttributes)), g.opts.OutlierMaxSize, g.opts.OutlierDiffMultiplier/2, timeStep, seed)
+			for i := range metricData.AttributeData["Total"] {
+				metricData.AttributeData["Total"][i].Value += subOutliersInc[i]
+			}
+		}
+	}
+
+	//Randomly generating standard distribution metric values for the main total data (no attribute)
+	//The random standard distribution values are added to the existing deviations already generated
+	fillMasterValues(metricData.AttributeData["Total"], metric, seed)
+
+	//Looping each main attribute
+	for _, attributeNode := range g.opts.Tree {
+
+		//Distributing main total metric values through the several attribute/sub-values combinations following the attributes tree recursively
+		//The random standard distribution values are added to the existing deviations already generated
+		metricData = splitValues(metricData, attributeNode, metricData.AttributeData["Total"], metric, attributeNode.Name, g.opts.ValueDivisionDeviation, seed)
+	}
+
+	return metricData, nil
+}
+
+//allocMasterData calculates and allocates the time steps for an isolated attribute
+//Used for the main total data
+func allocMasterData(metricData Metric, path string, dateStart, dateEnd time.Time, timeStep utils.TimeStep) Metric {
+	newData := []Step{}
+	dateStep := dateStart
+	for dateStep.Before(dateEnd) {
+		newTimeStepData := Step{DateStart: dateStep}
+		newData = append(newData, newTimeStepData)
+		dateStep = timeStep.Next(dateStep, 1)
+	}
+	metricData.Attributes = append(metricData.Attributes, path)
+	metricData.AttributeData[path] = newData
+
+	return metricData
+}
+
+//allocAttributesData calculates and allocates the time steps for all attribute/sub-values combinations following the given AttributeNode tree recursively
+func allocAttributesData(metricData Metric, node AttributeNode, path string, dateStart, dateEnd time.Time, timeStep utils.TimeStep) Metric {
+	for _, attribute := range node.SubAttributes {
+		newData := []Step{}
+		dateStep := dateStart
+		for dateStep.Before(dateEnd) {
+			newTimeStepData := Step{DateStart: dateStep}
+			newData = append(newData, newTimeStepData)
+			dateStep = timeStep.Next(dateStep, 1)
+		}
+		newPath := fmt.Sprintf("%s>%s", path, attribute.Name)
+		metricData.Attributes = append(metricData.Attributes, newPath)
+		metricData.AttributeData[newPath] = newData
+		metricData = allocAttributesData(metricData, attribute, newPath, dateStart, dateEnd, timeStep)
+	}
+
+	return metricData
+}
+
+//fillMasterSamples generates standard distribution number of samples for a given Time Step slice
+//Used for the main total data
+func fillMasterSamples(data []Step, metric MetricParams, seed int64) {
+	randGen := attributeRand(seed, "samples", "Total")
+	for i := range data {
+		data[i].Samples = int(math.Round(randGen.NormFloat64()*metric.SampleStdDev + metric.SampleMean))
+		if data[i].Samples < 0 {
+			data[i].Samples = 0
+		}
+	}
+}
+
+//splitSamples distributes main total number of samples through all attribute/sub-values combinations following the given AttributeNode tree recursively
+func splitSamples(metricData Metric, node AttributeNode, masterData []Step, path string, deviation float64, seed int64) Metric {
+	randGens := make([]*rand.Rand, len(node.SubAttributes))
+	totalWeight := 0.0
+	for i, subAttribute := range node.SubAttributes {
+		randGens[i] = attributeRand(seed, "samples", fmt.Sprintf("%s>%s", path, subAttribute.Name))
+		totalWeight += subAttribute.Weight
+	}
+	for step := range masterData {
+		remain := masterData[step].Samples
+		for i := 0; i < len(node.SubAttributes)-1; i++ {
+			data := metricData.AttributeData[fmt.Sprintf("%s>%s", path, node.SubAttributes[i].Name)]
+			weight := node.SubAttributes[i].Weight / totalWeight * (1 + randGens[i].Float64()*deviation - deviation/2)
+			data[step].Samples = int(math.Round(weight * float64(masterData[step].Samples)))
+			remain -= data[step].Samples
+		}
+		data := metricData.AttributeData[fmt.Sprintf("%s>%s", path, node.SubAttributes[len(node.SubAttributes)-1].Name)]
+		data[step].Samples = remain
+	}
+	for _, subAttribute := range node.SubAttributes {
+		if len(subAttribute.SubAttributes) > 0 {
+			metricData = splitSamples(metricData, subAttribute, metricData.AttributeData[fmt.Sprintf("%s>%s", path, subAttribute.Name)], fmt.Sprintf("%s>%s", path, subAttribute.Name), deviation, seed)
+		}
+	}
+
+	return metricData
+}
+
+//addMasterOutliers adds random deviations on the metric values for a given Time Step slice, returning them
+//Used for the main total data
+func addMasterOutliers(data []Step, metric MetricParams, outlierProb float64, outlierMaxSize int, outlierDiffMultiplier float64, timeStep utils.TimeStep, seed int64) []Outlier {
+	outliers := []Outlier{}
+	randGen := attributeRand(seed, "outliers", "Total")
+	for step := 0; step < len(data); step++ {
+		if randGen.Float64() < outlierProb {
+			outlierDiff := outlierDiffMultiplier * metric.ValStdDev
+			if randGen.Float64() < 0.5 {
+				outlierDiff *= -1
+			}
+			if metric.Type == config.MetricTypeCount {
+				outlierDiff = math.Round(outlierDiff)
+			}
+			outlierSize := randGen.Intn(outlierMaxSize) + 1
+			if step+outlierSize > len(data)-1 {
+				outlierSize = len(data) - step
+			}
+
+			log.Printf("Added Outlier - Total - %s <-> %s\n", data[step].DateStart.Format("2006-01-02 15:04"), data[step+outlierSize-1].DateStart.Format("2006-01-02 15:04"))
+			outliers = append(outliers, Outlier{Attribute: "Total", PeriodStart: data[step].DateStart, PeriodEnd: timeStep.Next(data[step+outlierSize-1].DateStart, 1), Diff: outlierDiff})
+
+			for i := step; i < step+outlierSize; i++ {
+				data[i].Value += outlierDiff
+			}
+			step += outlierSize - 1
+		}
+	}
+	return outliers
+}
+
+//addAttributesOutliers adds random deviations on the metric values for all attribute/sub-values combinations following given AttributeNode tree recursively
+//Added deviations are listed on the metric outliers, their values being returned and added to the parent attribute/sub-values node
+func addAttributesOutliers(metricData Metric, node AttributeNode, metric MetricParams, path string, outlierProb float64, outlierMaxSize int, outlierDiffMultiplier float64, timeStep utils.TimeStep, seed int64) (Metric, []float64) {
+	topInc := make([]float64, len(metricData.AttributeData["Total"]))
+
+	for _, subAttribute := range node.SubAttributes {
+		randGen := attributeRand(seed, "outliers", fmt.Sprintf("%s>%s", path, subAttribute.Name))
+		data := metricData.AttributeData[fmt.Sprintf("%s>%s", path, subAttribute.Name)]
+		for step := 0; step < len(data); step++ {
+			if randGen.Float64() < outlierProb {
+				outlierDiff := outlierDiffMultiplier * metric.ValStdDev
+				if randGen.Float64() < 0.5 {
+					outlierDiff *= -1
+				}
+				if metric.Type == config.MetricTypeCount {
+					outlierDiff = math.Round(outlierDiff)
+				}
+				outlierSize := randGen.Intn(outlierMaxSize) + 1
+				if step+outlierSize > len(data)-1 {
+					outlierSize = len(data) - step
+				}
+
+				log.Printf("Added Outlier - %s>%s - %s <-> %s\n", path, subAttribute.Name, data[step].DateStart.Format("2006-01-02 15:04"), data[step+outlierSize-1].DateStart.Format("2006-01-02 15:04"))
+				metricData.Outliers = append(metricData.Outliers, Outlier{Attribute: fmt.Sprintf("%s>%s", path, subAttribute.Name), PeriodStart: data[step].DateStart, PeriodEnd: timeStep.Next(data[step+outlierSize-1].DateStart, 1), Diff: outlierDiff})
+
+				for i := step; i < step+outlierSize; i++ {
+					data[i].Value += outlierDiff
+				}
+				step += outlierSize - 1
+			}
+		}
+
+		if len(subAttribute.SubAttributes) > 0 {
+			var subOutliersInc []float64
+			metricData, subOutliersInc = addAttributesOutliers(metricData, subAttribute, metric, fmt.Sprintf("%s>%s", path, subAttribute.Name), outlierProb/float64(len(node.SubAttributes)), outlierMaxSize, outlierDiffMultiplier/2, timeStep, seed)
+			for step := 0; step < len(data); step++ {
+				data[step].Value += subOutliersInc[step]
+			}
+		}
+
+		for step := 0; step < len(data); step++ {
+			if data[step].Value != 0 {
+				switch metric.Type {
+				case config.MetricTypeSum, config.MetricTypeCount:
+					topInc[step] += data[step].Value
+				case config.MetricTypeAverage:
+					totalSamples := 0.0
+					for _, subAttribute := range node.SubAttributes {
+						totalSamples += float64(metricData.AttributeData[fmt.Sprintf("%s>%s", path, subAttribute.Name)][step].Samples)
+					}
+					topInc[step] += data[step].Value * float64(data[step].Samples) / totalSamples
+				}
+			}
+		}
+	}
+
+	return metricData, topInc
+}
+
+//fillMasterValues generates random standard distribution metric values for a given Time Step slice
+//The random standard distribution values are added, not replacing the existing values
+//Used for the main total data
+func fillMasterValues(data []Step, metric MetricParams, seed int64) {
+	randGen := attributeRand(seed, "values", "Total")
+	for i := range data {
+		switch metric.Type {
+		case config.MetricTypeSum, config.MetricTypeAverage:
+			data[i].Value += randGen.NormFloat64()*metric.ValStdDev + metric.ValMean
+			if data[i].Value < 0 {
+				data[i].Value = 0
+			}
+		case config.MetricTypeCount:
+			data[i].Samples += int(data[i].Value)
+			if data[i].Samples < 0 {
+				data[i].Samples = 0
+			}
+			data[i].Value = float64(data[i].Samples)
+		}
+	}
+}
+
+//splitValues distributes main total metric values through the several attribute/sub-values combinations following given AttributeNode tree recursively
+//The random standard distribution values are added, not replacing the existing values
+func splitValues(metricData Metric, node AttributeNode, masterData []Step, metric MetricParams, path string, deviation float64, seed int64) Metric {
+	randGens := make([]*rand.Rand, len(node.SubAttributes))
+	for i, subAttribute := range node.SubAttributes {
+		randGens[i] = attributeRand(seed, "values", fmt.Sprintf("%s>%s", path, subAttribute.Name))
+	}
+	for step := range masterData {
+		switch metric.Type {
+		case config.MetricTypeSum:
+			splitValue := masterData[step].Value
+			for _, subAttribute := range node.SubAttributes {
+				data := metricData.AttributeData[fmt.Sprintf("%s>%s", path, subAttribute.Name)]
+				splitValue -= data[step].Value
+			}
+			remain := splitValue
+			for i := 0; i < len(node.SubAttributes)-1; i++ {
+				data := metricData.AttributeData[fmt.Sprintf("%s>%s", path, node.SubAttributes[i].Name)]
+				ratio := float64(data[step].Samples) / float64(masterData[step].Samples) * (1 + randGens[i].Float64()*deviation - deviation/2)
+				partValue := ratio * splitValue
+				data[step].Value += partValue
+				if data[step].Value < 0 {
+					data[step].Value = 0
+				}
+				remain -= partValue
+			}
+			data := metricData.AttributeData[fmt.Sprintf("%s>%s", path, node.SubAttributes[len(node.SubAttributes)-1].Name)]
+			data[step].Value += remain
+			if data[step].Value < 0 {
+				data[step].Value = 0
+			}
+		case config.MetricTypeAverage:
+			splitValue := masterData[step].Value
+			for _, subAttribute := range node.SubAttributes {
+				data := metricData.AttributeData[fmt.Sprintf("%s>%s", path, subAttribute.Name)]
+				splitValue -= data[step].Value * float64(data[step].Samples) / float64(masterData[step].Samples)
+			}
+			remain := splitValue
+			for i := 0; i < len(node.SubAttributes)-1; i++ {
+				data := metricData.AttributeData[fmt.Sprintf("%s>%s", path, node.SubAttributes[i].Name)]
+				ratio := 1 + randGens[i].Float64()*deviation - deviation/2
+				partValue := ratio * splitValue
+				data[step].Value += partValue
+				if data[step].Value < 0 {
+					data[step].Value = 0
+				}
+				remain -= partValue * float64(data[step].Samples) / float64(masterData[step].Samples)
+			}
+			data := metricData.AttributeData[fmt.Sprintf("%s>%s", path, node.SubAttributes[len(node.SubAttributes)-1].Name)]
+			data[step].Value += remain * float64(masterData[step].Samples) / float64(data[step].Samples)
+			if data[step].Value < 0 {
+				data[step].Value = 0
+			}
+		case config.MetricTypeCount:
+			splitValue := masterData[step].Value
+			originalSamples := 0
+			for _, subAttribute := range node.SubAttributes {
+				data := metricData.AttributeData[fmt.Sprintf("%s>%s", path, subAttribute.Name)]
+				splitValue -= data[step].Value
+				originalSamples += data[step].Samples
+			}
+			remain := splitValue
+			for i := 0; i < len(node.SubAttributes)-1; i++ {
+				data := metricData.AttributeData[fmt.Sprintf("%s>%s", path, node.SubAttributes[i].Name)]
+				ratio := float64(data[step].Samples) / float64(originalSamples)
+				partValue := math.Round(ratio * splitValue)
+				data[step].Value += partValue
+				if data[step].Value < 0 {
+					data[step].Value = 0
+				}
+				data[step].Samples = int(data[step].Value)
+				remain -= partValue
+			}
+			data := metricData.AttributeData[fmt.Sprintf("%s>%s", path, node.SubAttributes[len(node.SubAttributes)-1].Name)]
+			data[step].Value += remain
+			if data[step].Value < 0 {
+				data[step].Value = 0
+			}
+			data[step].Samples = int(data[step].Value)
+		}
+	}
+	for _, subAttribute := range node.SubAttributes {
+		if len(subAttribute.SubAttributes) > 0 {
+			metricData = splitValues(metricData, subAttribute, metricData.AttributeData[fmt.Sprintf("%s>%s", path, subAttribute.Name)], metric, fmt.Sprintf("%s>%s", path, subAttribute.Name), deviation, seed)
+		}
+	}
+
+	return metricData
+}
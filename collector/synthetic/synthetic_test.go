@@ -0,0 +1,183 @@
+package synthetic
+
+import (
+	"math"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ftfmtavares/anomalies-detector/config"
+)
+
+func TestGeneratorGenerate(t *testing.T) {
+	type args struct {
+		metric    string
+		dateStart time.Time
+		dateEnd   time.Time
+		timeStep  time.Duration
+		seed      int64
+	}
+	type wantArgs struct {
+		length    int
+		dateStart time.Time
+	}
+
+	timeRef := time.Now()
+
+	tests := []struct {
+		name string
+		args args
+		want wantArgs
+	}{
+		{
+			name: "Data Generation with time steps of 1 day for a total of 5 days",
+			args: args{
+				metric:    "Revenue",
+				dateStart: timeRef.AddDate(0, 0, -5),
+				dateEnd:   timeRef,
+				timeStep:  time.Duration(int64(time.Hour) * 24),
+				seed:      3,
+			},
+			want: wantArgs{
+				length:    5,
+				dateStart: timeRef.AddDate(0, 0, -5),
+			},
+		},
+		{
+			name: "Data Generation with time steps of 1 hour for a total of 30 days",
+			args: args{
+				metric:    "Basket",
+				dateStart: timeRef.AddDate(0, 0, -30),
+				dateEnd:   timeRef,
+				timeStep:  time.Duration(int64(time.Hour)),
+				seed:      3,
+			},
+			want: wantArgs{
+				length:    30 * 24,
+				dateStart: timeRef.AddDate(0, 0, -30),
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := New(DefaultOptions(tt.args.seed)).Generate(tt.args.metric, tt.args.dateStart, tt.args.dateEnd, tt.args.timeStep)
+			if err != nil {
+				t.Fatalf("Generate() error = %v", err)
+			}
+
+			//Generate returns random numbers which makes it impossible to define an expected exact result, so only the dataset length and time distribution are tested
+			if len(got.AttributeData["Total"]) != tt.want.length {
+				t.Errorf("len(Generate().AttributeData[\"Total\"] = %d, want %d", len(got.AttributeData["Total"]), tt.want.length)
+			}
+			for i, step := range got.AttributeData["Total"] {
+				if step.Samples == 0 {
+					t.Errorf("Generate().AttributeData[\"Total\"][%d].Samples = %d, want >0", i, step.Samples)
+				}
+				if step.Value == 0 {
+					t.Errorf("Generate().AttributeData[\"Total\"][%d].Value = %f, want >0", i, step.Value)
+				}
+				if !step.DateStart.Equal(tt.want.dateStart.Add(tt.args.timeStep * time.Duration(i))) {
+					t.Errorf("Generate().AttributeData[\"Total\"][%d].DateStart = %v, want %v", i, step.DateStart, tt.want.dateStart.Add(tt.args.timeStep*time.Duration(i)))
+				}
+			}
+		})
+	}
+}
+
+func TestGeneratorUnknownMetric(t *testing.T) {
+	timeRef := time.Now()
+	if _, err := New(DefaultOptions(1)).Generate("Conversion", timeRef.AddDate(0, 0, -5), timeRef, 24*time.Hour); err == nil {
+		t.Errorf("Generate() error = nil, want unknown metric error")
+	}
+}
+
+func TestProfileOptions(t *testing.T) {
+	noOutliers := 0.0
+	profile := config.SimulationParams{
+		Metrics: []config.SimulatedMetric{
+			{Name: "Revenue", Mean: 5000, StdDev: 500},
+			{Name: "Signups", Unit: "Accounts", Type: config.MetricTypeCount, Mean: 300, StdDev: 30},
+			{Name: "Latency", Unit: "ms", Type: config.MetricTypeAverage, Mean: 120, StdDev: 10, SampleMean: 2000, SampleStdDev: 100},
+		},
+		Attributes: []config.SimulatedAttribute{
+			{Name: "Country", SubAttributes: []config.SimulatedAttribute{{Name: "PT", Weight: 70}, {Name: "ES", Weight: 30, SubAttributes: []config.SimulatedAttribute{{Name: "Madrid", Weight: 1}}}}},
+		},
+		OutlierProb: &noOutliers,
+	}
+	got := ProfileOptions(7, profile)
+
+	wantMetrics := []MetricParams{
+		{Name: "Revenue", Unit: "Total Orders (EUR)", Type: config.MetricTypeSum, ValMean: 5000, ValStdDev: 500, SampleMean: 1500, SampleStdDev: 300},
+		{Name: "Signups", Unit: "Accounts", Type: config.MetricTypeCount, ValMean: 300, ValStdDev: 30, SampleMean: 300, SampleStdDev: 30},
+		{Name: "Latency", Unit: "ms", Type: config.MetricTypeAverage, ValMean: 120, ValStdDev: 10, SampleMean: 2000, SampleStdDev: 100},
+	}
+	if !reflect.DeepEqual(got.Metrics, wantMetrics) {
+		t.Errorf("ProfileOptions() metrics = %+v, want %+v", got.Metrics, wantMetrics)
+	}
+	wantTree := []AttributeNode{{Name: "Country", SubAttributes: []AttributeNode{{Name: "PT", Weight: 70, SubAttributes: []AttributeNode{}}, {Name: "ES", Weight: 30, SubAttributes: []AttributeNode{{Name: "Madrid", Weight: 1, SubAttributes: []AttributeNode{}}}}}}}
+	if !reflect.DeepEqual(got.Tree, wantTree) {
+		t.Errorf("ProfileOptions() tree = %+v, want %+v", got.Tree, wantTree)
+	}
+	defaults := DefaultOptions(7)
+	if got.Seed != 7 || got.OutlierProb != 0 || got.OutlierMaxSize != defaults.OutlierMaxSize || got.OutlierDiffMultiplier != defaults.OutlierDiffMultiplier {
+		t.Errorf("ProfileOptions() outliers = %v, %d, %v", got.OutlierProb, got.OutlierMaxSize, got.OutlierDiffMultiplier)
+	}
+
+	//The generated data follows the profile attributes and, without outliers, a Count metric values are its samples
+	timeRef := time.Date(2022, 9, 1, 0, 0, 0, 0, time.UTC)
+	generated, err := New(got).Generate("Signups", timeRef.AddDate(0, 0, -10), timeRef, 24*time.Hour)
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	if want := []string{"Total", "Country>PT", "Country>ES", "Country>ES>Madrid"}; !reflect.DeepEqual(generated.Attributes, want) {
+		t.Errorf("Generate() attributes = %v, want %v", generated.Attributes, want)
+	}
+	for _, step := range generated.AttributeData["Total"] {
+		if step.Value != float64(step.Samples) || step.Value < 150 || step.Value > 450 {
+			t.Errorf("Generate() Total step = %+v, want around 300 counted samples", step)
+		}
+	}
+
+	//Profiles without settings keep the default options
+	if got := ProfileOptions(7, config.SimulationParams{}); !reflect.DeepEqual(got, defaults) {
+		t.Errorf("ProfileOptions() empty profile = %+v, want the defaults", got)
+	}
+}
+
+func TestGeneratorOutliers(t *testing.T) {
+	timeRef := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	options := DefaultOptions(5)
+	options.Tree = nil
+	options.OutlierProb = 0.05
+	clean := options
+	clean.OutlierProb = 0
+
+	got, err := New(options).Generate("Revenue", timeRef, timeRef.AddDate(0, 0, 30), time.Hour)
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	want, _ := New(clean).Generate("Revenue", timeRef, timeRef.AddDate(0, 0, 30), time.Hour)
+	if len(got.Outliers) == 0 {
+		t.Fatalf("Generate().Outliers is empty")
+	}
+
+	//The listed outliers are the whole difference from the data generated without them, every other random draw being on its own stream
+	//Values are never negative, so drops can be cut short by the zero floor
+	diffs := make([]float64, len(want.AttributeData["Total"]))
+	for _, outlier := range got.Outliers {
+		if outlier.Attribute != "Total" || !outlier.PeriodEnd.After(outlier.PeriodStart) || outlier.PeriodStart.Before(timeRef) || outlier.PeriodEnd.After(timeRef.AddDate(0, 0, 30)) {
+			t.Errorf("Generate() outlier = %+v", outlier)
+		}
+		for i, step := range want.AttributeData["Total"] {
+			if !step.DateStart.Before(outlier.PeriodStart) && step.DateStart.Before(outlier.PeriodEnd) {
+				diffs[i] += outlier.Diff
+			}
+		}
+	}
+	for i, step := range got.AttributeData["Total"] {
+		diff := step.Value - want.AttributeData["Total"][i].Value
+		if (diffs[i] >= 0 && math.Abs(diff-diffs[i]) > 1e-6) || (diffs[i] < 0 && (diff > 0 || (step.Value > 0 && math.Abs(diff-diffs[i]) > 1e-6))) {
+			t.Errorf("Generate().AttributeData[\"Total\"][%d] deviation = %f, want %f", i, diff, diffs[i])
+		}
+	}
+}
@@ -0,0 +1,310 @@
+package collector
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"time"
+
+	"github.com/ftfmtavares/anomalies-detector/config"
+	"github.com/ftfmtavares/anomalies-detector/utils"
+)
+
+//unsafeTierChars matches the characters replaced when site ids are used as file names
+var unsafeTierChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+//storageTier is a parsed storage tier, a zero time step standing for the raw tier
+type storageTier struct {
+	name      string
+	step      string
+	timeStep  time.Duration
+	retention time.Duration
+}
+
+//TierStore keeps the collected data of every site in tiers of increasing time steps, as <dir>/<tier>/<site>.json
+//Every run appends its data to the raw tier and rolls it up into the coarser ones, each tier being pruned to its retention,
+//so long ranges are read from coarse tiers while recent detection keeps the raw data
+//Sites given an encryption key are stored as encrypted records, decrypted when read
+type TierStore struct {
+	dir   string
+	tiers []storageTier
+	keys  map[string][]byte
+}
+
+//NewTierStore creates a TierStore from the storage configuration, using the default tiers if none are configured
+//It returns an error if the directory is missing or a tier is invalid, tiers having to be listed by increasing time steps with the raw tier first
+func NewTierStore(params config.StorageParams) (*TierStore, error) {
+	if params.Dir == "" {
+		return nil, fmt.Errorf("storage - dir is required")
+	}
+	tiersParams := params.Tiers
+	if len(tiersParams) == 0 {
+		tiersParams = config.DefaultStorageTiers()
+	}
+
+	store := &TierStore{dir: params.Dir, keys: map[string][]byte{}}
+	for i, tierParams := range tiersParams {
+		tier := storageTier{name: tierParams.Name, step: tierParams.TimeStep}
+		if tier.name == "" || unsafeTierChars.MatchString(tier.name) {
+			return nil, fmt.Errorf("storage - tier #%d - invalid name \"%s\"", i+1, tier.name)
+		}
+		if tierParams.TimeStep != "" {
+			var err error
+			if tier.timeStep, err = utils.StrToDuration(tierParams.TimeStep); err != nil || tier.timeStep <= 0 {
+				return nil, fmt.Errorf("storage - tier %s - invalid time step \"%s\"", tier.name, tierParams.TimeStep)
+			}
+		}
+		if (i == 0) != (tier.timeStep == 0) || (i > 0 && tier.timeStep <= store.tiers[i-1].timeStep) {
+			return nil, fmt.Errorf("storage - tier %s - tiers must start with the raw one and follow increasing time steps", tier.name)
+		}
+		retention, err := utils.StrToDuration(tierParams.Retention)
+		if err != nil || retention <= 0 {
+			return nil, fmt.Errorf("storage - tier %s - invalid retention \"%s\"", tier.name, tierParams.Retention)
+		}
+		tier.retention = retention
+		store.tiers = append(store.tiers, tier)
+	}
+	return store, nil
+}
+
+//EncryptSite sets the AES-256 key the data of a site is encrypted with on every tier
+func (store *TierStore) EncryptSite(siteId string, key []byte) {
+	store.keys[siteId] = key
+}
+
+//Append merges the collected data of a site into the raw tier and rolls it up into the coarser tiers, each coarser tier being built from the previous one
+//Time steps collected again replace the stored ones, and every tier is pruned to its retention from the current time
+func (store *TierStore) Append(siteData SiteData) error {
+	now := Clock.Now()
+	source := siteData
+	for i, tier := range store.tiers {
+		stored, err := store.read(tier, siteData.SiteId)
+		if err != nil {
+			return err
+		}
+
+		//Coarse time steps are only recomputed when fully covered by the finer tier, so those whose data was pruned there are kept
+		update := source
+		if i > 0 {
+			update = rollupSiteData(source, tier.timeStep)
+		}
+		merged := mergeSiteData(stored, update)
+		merged.SiteId = siteData.SiteId
+		merged.TimeStep = siteData.TimeStep
+		if i > 0 {
+			merged.TimeStep = tier.step
+		}
+		if err := store.write(tier, pruneSiteData(merged, now.Add(-tier.retention))); err != nil {
+			return err
+		}
+
+		//The next tier is rolled up before pruning, so data older than this tier retention still reaches it
+		source = merged
+	}
+	return nil
+}
+
+//Query returns the stored data of a site over the given period from the finest tier still holding its start and returning at most maxPoints time steps (0 for any)
+//The coarsest tier is used when none fits, the time step of the returned data telling the tier it was read from
+func (store *TierStore) Query(siteId string, dateStart, dateEnd time.Time, maxPoints int) (SiteData, error) {
+	var res SiteData
+	for i, tier := range store.tiers {
+		stored, err := store.read(tier, siteId)
+		if err != nil {
+			return res, err
+		}
+		if len(stored.Metrics) == 0 {
+			continue
+		}
+		res = trimSiteData(stored, dateStart, dateEnd)
+		timeStep := tier.timeStep
+		if i == 0 {
+			timeStep, _ = utils.StrToDuration(stored.TimeStep)
+		}
+		fits := maxPoints <= 0 || timeStep <= 0 || int(dateEnd.Sub(dateStart)/timeStep) <= maxPoints
+		if !stored.DateStart.After(dateStart) && fits {
+			return res, nil
+		}
+	}
+	return res, nil
+}
+
+//Stored returns all the stored data of a site from the finest tier holding any, without metrics if the site was never stored
+func (store *TierStore) Stored(siteId string) (SiteData, error) {
+	for _, tier := range store.tiers {
+		stored, err := store.read(tier, siteId)
+		if err != nil {
+			return SiteData{}, err
+		}
+		if len(stored.Metrics) > 0 {
+			return stored, nil
+		}
+	}
+	return SiteData{SiteId: siteId, Metrics: []MetricData{}}, nil
+}
+
+//tierFile returns the file holding a site data in a tier
+func (store *TierStore) tierFile(tier storageTier, siteId string) string {
+	return filepath.Join(store.dir, tier.name, unsafeTierChars.ReplaceAllString(siteId, "_")+".json")
+}
+
+//read loads the data of a site from a tier, empty if it has none yet, decrypting it if it's stored encrypted
+//It returns an error if the data is encrypted and the site has no key, or if it can't be decrypted with it
+func (store *TierStore) read(tier storageTier, siteId string) (SiteData, error) {
+	siteData := SiteData{SiteId: siteId, Metrics: []MetricData{}}
+	byteValue, err := os.ReadFile(store.tierFile(tier, siteId))
+	if os.IsNotExist(err) {
+		return siteData, nil
+	}
+	if err != nil {
+		return siteData, fmt.Errorf("storage - tier %s - %s", tier.name, err.Error())
+	}
+	var encrypted utils.EncryptedRecord
+	if err := json.Unmarshal(byteValue, &encrypted); err != nil {
+		return siteData, fmt.Errorf("storage - tier %s - site %s - %s", tier.name, siteId, err.Error())
+	}
+	if encrypted.Ciphertext != "" {
+		key := store.keys[siteId]
+		if key == nil {
+			return siteData, fmt.Errorf("storage - tier %s - site %s - stored encrypted, no key given", tier.name, siteId)
+		}
+		if err := utils.DecryptRecord(encrypted, key, &siteData); err != nil {
+			return siteData, fmt.Errorf("storage - tier %s - site %s - %s", tier.name, siteId, err.Error())
+		}
+		return siteData, nil
+	}
+	if err := json.Unmarshal(byteValue, &siteData); err != nil {
+		return siteData, fmt.Errorf("storage - tier %s - site %s - %s", tier.name, siteId, err.Error())
+	}
+	return siteData, nil
+}
+
+//write stores the data of a site in a tier, encrypted if the site has a key, replacing the file atomically so readers never see it half written
+func (store *TierStore) write(tier storageTier, siteData SiteData) error {
+	fileName := store.tierFile(tier, siteData.SiteId)
+	if err := utils.Files.MkdirAll(filepath.Dir(fileName)); err != nil {
+		return fmt.Errorf("storage - tier %s - %s", tier.name, err.Error())
+	}
+	var record interface{} = siteData
+	if key := store.keys[siteData.SiteId]; key != nil {
+		encrypted, err := utils.EncryptRecord(siteData.SiteId, siteData, key)
+		if err != nil {
+			return fmt.Errorf("storage - tier %s - site %s - %s", tier.name, siteData.SiteId, err.Error())
+		}
+		record = encrypted
+	}
+	byteValue, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("storage - tier %s - %s", tier.name, err.Error())
+	}
+	if err := utils.Files.WriteFile(fileName, byteValue, 0o644); err != nil {
+		return fmt.Errorf("storage - tier %s - %s", tier.name, err.Error())
+	}
+	return nil
+}
+
+//rollupSiteData resamples every series of a site into the given time step, according to the metric types
+//Only the time steps starting after the first finer time step are returned, partial ones at the start of the data being left out
+func rollupSiteData(siteData SiteData, timeStep time.Duration) SiteData {
+	res := SiteData{SiteId: siteData.SiteId, DateEnd: siteData.DateEnd, Metrics: []MetricData{}}
+	for _, metricData := range siteData.Metrics {
+		rolled := MetricData{Metric: metricData.Metric, Unit: metricData.Unit, Type: metricData.Type, AlertDirection: metricData.AlertDirection, Attributes: append([]string{}, metricData.Attributes...), AttributeData: map[string][]TimeStepData{}, Injected: metricData.Injected}
+		for attribute, data := range metricData.AttributeData {
+			if len(data) == 0 {
+				rolled.AttributeData[attribute] = []TimeStepData{}
+				continue
+			}
+			dateStart := data[0].DateStart.Truncate(timeStep)
+			if dateStart.Before(data[0].DateStart) {
+				dateStart = dateStart.Add(timeStep)
+			}
+			rolled.AttributeData[attribute] = ResampleSeries(data, dateStart, timeStep, metricData.Aggregation())
+		}
+		res.Metrics = append(res.Metrics, rolled)
+	}
+	return res
+}
+
+//mergeSiteData merges the metrics of the update into the stored data, time steps of the update replacing the stored ones starting at the same time
+//The merged data ends at the latest end of both
+func mergeSiteData(stored, update SiteData) SiteData {
+	res := SiteData{SiteId: stored.SiteId, TimeStep: stored.TimeStep, DateEnd: stored.DateEnd, Metrics: []MetricData{}}
+	if update.DateEnd.After(res.DateEnd) {
+		res.DateEnd = update.DateEnd
+	}
+	metrics := map[string]int{}
+	for _, metricData := range stored.Metrics {
+		metrics[metricData.Metric] = len(res.Metrics)
+		res.Metrics = append(res.Metrics, copyMetricData(metricData))
+	}
+
+	for _, metricData := range update.Metrics {
+		ind, found := metrics[metricData.Metric]
+		if !found {
+			metrics[metricData.Metric] = len(res.Metrics)
+			res.Metrics = append(res.Metrics, copyMetricData(metricData))
+			continue
+		}
+		merged := &res.Metrics[ind]
+		merged.Unit, merged.Type = metricData.Unit, metricData.Type
+		for _, attribute := range metricData.Attributes {
+			if _, found := merged.AttributeData[attribute]; !found {
+				merged.Attributes = append(merged.Attributes, attribute)
+			}
+			steps := map[int64]TimeStepData{}
+			for _, stepData := range merged.AttributeData[attribute] {
+				steps[stepData.DateStart.UnixNano()] = stepData
+			}
+			for _, stepData := range metricData.AttributeData[attribute] {
+				steps[stepData.DateStart.UnixNano()] = stepData
+			}
+			data := []TimeStepData{}
+			for _, stepData := range steps {
+				data = append(data, stepData)
+			}
+			sort.Slice(data, func(i, j int) bool { return data[i].DateStart.Before(data[j].DateStart) })
+			merged.AttributeData[attribute] = data
+		}
+	}
+	return res
+}
+
+//pruneSiteData removes the time steps starting before the cutoff, the data period starting at the earliest remaining time step
+func pruneSiteData(siteData SiteData, cutoff time.Time) SiteData {
+	siteData = trimSiteData(siteData, cutoff, time.Time{})
+	siteData.DateStart = time.Time{}
+	for _, metricData := range siteData.Metrics {
+		for _, data := range metricData.AttributeData {
+			if len(data) > 0 && (siteData.DateStart.IsZero() || data[0].DateStart.Before(siteData.DateStart)) {
+				siteData.DateStart = data[0].DateStart
+			}
+		}
+	}
+	return siteData
+}
+
+//trimSiteData returns a copy of the site data with only the time steps starting within the period, a zero end standing for no end
+func trimSiteData(siteData SiteData, dateStart, dateEnd time.Time) SiteData {
+	res := siteData
+	res.Metrics = []MetricData{}
+	for _, metricData := range siteData.Metrics {
+		trimmed := copyMetricData(metricData)
+		for attribute, data := range trimmed.AttributeData {
+			kept := []TimeStepData{}
+			for _, stepData := range data {
+				if !stepData.DateStart.Before(dateStart) && (dateEnd.IsZero() || stepData.DateStart.Before(dateEnd)) {
+					kept = append(kept, stepData)
+				}
+			}
+			trimmed.AttributeData[attribute] = kept
+		}
+		res.Metrics = append(res.Metrics, trimmed)
+	}
+	if !dateEnd.IsZero() {
+		res.DateStart, res.DateEnd = dateStart, dateEnd
+	}
+	return res
+}
@@ -0,0 +1,151 @@
+package collector
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ftfmtavares/anomalies-detector/config"
+	"github.com/ftfmtavares/anomalies-detector/utils"
+)
+
+func TestTierStore(t *testing.T) {
+	defer func(clock utils.Clock) { Clock = clock }(Clock)
+	timeRef := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	//Two days of 30 minutes revenue steps, each worth 1 with 2 samples, starting in the middle of an hour
+	collected := func(start time.Time, steps int) SiteData {
+		data := []TimeStepData{}
+		for i := 0; i < steps; i++ {
+			data = append(data, TimeStepData{DateStart: start.Add(time.Duration(i) * 30 * time.Minute), Value: 1, Samples: 2})
+		}
+		return SiteData{
+			SiteId:    "shop/eu",
+			TimeStep:  "30m",
+			DateStart: start,
+			DateEnd:   start.Add(time.Duration(steps) * 30 * time.Minute),
+			Metrics:   []MetricData{{Metric: "Revenue", Type: config.MetricTypeSum, Attributes: []string{"Total"}, AttributeData: map[string][]TimeStepData{"Total": data}}},
+		}
+	}
+
+	store, err := NewTierStore(config.StorageParams{Dir: t.TempDir(), Tiers: []config.StorageTier{
+		{Name: "raw", Retention: "1d"},
+		{Name: "hourly", TimeStep: "1h", Retention: "3d"},
+		{Name: "daily", TimeStep: "1d", Retention: "30d"},
+	}})
+	if err != nil {
+		t.Fatalf("NewTierStore() error = %v", err)
+	}
+	Clock = utils.FixedClock(timeRef.Add(48*time.Hour + 30*time.Minute))
+	if err := store.Append(collected(timeRef.Add(30*time.Minute), 96)); err != nil {
+		t.Fatalf("Append() error = %v", err)
+	}
+
+	tests := []struct {
+		name         string
+		start        time.Time
+		maxPoints    int
+		wantTimeStep string
+		wantSteps    int
+		wantValue    float64
+	}{
+		{
+			name:         "Recent period from the raw tier",
+			start:        timeRef.Add(36 * time.Hour),
+			maxPoints:    100,
+			wantTimeStep: "30m",
+			wantSteps:    24,
+			wantValue:    1,
+		},
+		{
+			name:         "Period pruned from the raw tier read from the hourly one",
+			start:        timeRef.Add(12 * time.Hour),
+			maxPoints:    100,
+			wantTimeStep: "1h",
+			wantSteps:    36,
+			wantValue:    2,
+		},
+		{
+			name:         "Long period read from the daily tier",
+			start:        timeRef,
+			maxPoints:    10,
+			wantTimeStep: "1d",
+			wantSteps:    1,
+			wantValue:    48,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := store.Query("shop/eu", tt.start, timeRef.Add(48*time.Hour), tt.maxPoints)
+			if err != nil {
+				t.Fatalf("Query() error = %v", err)
+			}
+			if got.TimeStep != tt.wantTimeStep || len(got.Metrics) != 1 {
+				t.Fatalf("Query() time step = %s with %d metrics, want %s", got.TimeStep, len(got.Metrics), tt.wantTimeStep)
+			}
+			data := got.Metrics[0].AttributeData["Total"]
+			if len(data) != tt.wantSteps || data[len(data)/2].Value != tt.wantValue {
+				t.Errorf("Query() = %d steps (%v), want %d steps of %v", len(data), data, tt.wantSteps, tt.wantValue)
+			}
+		})
+	}
+
+	//Collecting the latest hour again replaces its steps instead of adding them
+	if err := store.Append(collected(timeRef.Add(47*time.Hour+30*time.Minute), 2)); err != nil {
+		t.Fatalf("Append() error = %v", err)
+	}
+	got, _ := store.Query("shop/eu", timeRef.Add(47*time.Hour), timeRef.Add(48*time.Hour), 0)
+	if data := got.Metrics[0].AttributeData["Total"]; len(data) != 2 || data[0].Value != 1 {
+		t.Errorf("Query() after collecting again = %v, want 2 raw steps", data)
+	}
+
+	if _, err := NewTierStore(config.StorageParams{Dir: "tiers", Tiers: []config.StorageTier{{Name: "hourly", TimeStep: "1h", Retention: "3d"}}}); err == nil {
+		t.Errorf("NewTierStore() expected an error without raw tier")
+	}
+}
+
+func TestTierStoreEncrypted(t *testing.T) {
+	defer func(clock utils.Clock) { Clock = clock }(Clock)
+	timeRef := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	Clock = utils.FixedClock(timeRef.Add(24 * time.Hour))
+	dir := t.TempDir()
+	siteData := SiteData{
+		SiteId:    "vault",
+		TimeStep:  "1h",
+		DateStart: timeRef,
+		DateEnd:   timeRef.Add(2 * time.Hour),
+		Metrics:   []MetricData{{Metric: "Visits", Type: config.MetricTypeCount, Attributes: []string{"Total"}, AttributeData: map[string][]TimeStepData{"Total": {{DateStart: timeRef, Value: 3, Samples: 1}, {DateStart: timeRef.Add(time.Hour), Value: 5, Samples: 1}}}}},
+	}
+
+	store, err := NewTierStore(config.StorageParams{Dir: dir})
+	if err != nil {
+		t.Fatalf("NewTierStore() error = %v", err)
+	}
+	store.EncryptSite("vault", make([]byte, 32))
+	if err := store.Append(siteData); err != nil {
+		t.Fatalf("Append() error = %v", err)
+	}
+	entries, _ := os.ReadDir(dir)
+	for _, entry := range entries {
+		content, _ := os.ReadFile(filepath.Join(dir, entry.Name(), "vault.json"))
+		if !strings.Contains(string(content), `"ciphertext"`) || strings.Contains(string(content), "Visits") {
+			t.Errorf("Append() stored %s on tier %s, want an encrypted record", content, entry.Name())
+		}
+	}
+
+	got, err := store.Stored("vault")
+	if err != nil || len(got.Metrics) != 1 || len(got.Metrics[0].AttributeData["Total"]) != 2 {
+		t.Errorf("Stored() = %v, %v, want the decrypted data", got, err)
+	}
+	plain, _ := NewTierStore(config.StorageParams{Dir: dir})
+	if _, err := plain.Stored("vault"); err == nil {
+		t.Errorf("Stored() expected an error without the key")
+	}
+	other, _ := NewTierStore(config.StorageParams{Dir: dir})
+	other.EncryptSite("vault", append(make([]byte, 31), 1))
+	if _, err := other.Stored("vault"); err == nil {
+		t.Errorf("Stored() expected an error with another key")
+	}
+}
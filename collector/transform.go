@@ -0,0 +1,186 @@
+package collector
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/ftfmtavares/anomalies-detector/config"
+)
+
+//transformMadScale converts a median absolute deviation into a standard deviation estimate for normally distributed values
+const transformMadScale = 1.4826
+
+//Transform is a validated config.Transform ready to be applied to collected data
+type Transform struct {
+	config.Transform
+}
+
+//CompileTransforms validates the configured transform steps
+//It returns an error if an unknown type is given or if any step misses its parameters
+func CompileTransforms(confTransforms []config.Transform) ([]Transform, error) {
+	compiled := []Transform{}
+	for i, confTransform := range confTransforms {
+		switch confTransform.Type {
+		case "rename":
+			if confTransform.Metric == "" || confTransform.To == "" {
+				return nil, fmt.Errorf("transform #%d - rename requires metric and to", i+1)
+			}
+		case "scale":
+			if confTransform.Factor == 0 {
+				return nil, fmt.Errorf("transform #%d - scale requires a non zero factor", i+1)
+			}
+		case "clamp":
+			if confTransform.Multiplier <= 0 {
+				return nil, fmt.Errorf("transform #%d - clamp requires a positive multiplier", i+1)
+			}
+		case "merge":
+			if len(confTransform.Attributes) == 0 || confTransform.Into == "" {
+				return nil, fmt.Errorf("transform #%d - merge requires attributes and into", i+1)
+			}
+			if confTransform.Aggregation != "" && confTransform.Aggregation != "sum" && confTransform.Aggregation != "mean" {
+				return nil, fmt.Errorf("transform #%d - invalid aggregation \"%s\", it must be \"sum\" or \"mean\"", i+1, confTransform.Aggregation)
+			}
+		default:
+			return nil, fmt.Errorf("transform #%d - unknown type \"%s\"", i+1, confTransform.Type)
+		}
+		compiled = append(compiled, Transform{confTransform})
+	}
+
+	return compiled, nil
+}
+
+//ApplyTransforms runs the transform steps in order over the collected data of a site, returning the transformed copy
+func ApplyTransforms(siteData SiteData, transforms []Transform) SiteData {
+	if len(transforms) == 0 {
+		return siteData
+	}
+
+	metrics := make([]MetricData, len(siteData.Metrics))
+	for i, metricData := range siteData.Metrics {
+		metrics[i] = copyMetricData(metricData)
+	}
+
+	for _, transform := range transforms {
+		for i := range metrics {
+			if transform.Metric != "" && metrics[i].Metric != transform.Metric {
+				continue
+			}
+			switch transform.Type {
+			case "rename":
+				metrics[i].Metric = transform.To
+			case "scale":
+				scaleMetric(&metrics[i], transform.Factor, transform.Unit)
+			case "clamp":
+				for _, attribute := range metrics[i].Attributes {
+					clampSeries(metrics[i].AttributeData[attribute], transform.Multiplier)
+				}
+			case "merge":
+				aggregation := transform.Aggregation
+				if aggregation == "" {
+					aggregation = metrics[i].Aggregation()
+				}
+				mergeAttributes(&metrics[i], transform.Attributes, transform.Into, aggregation)
+			}
+		}
+	}
+
+	siteData.Metrics = metrics
+	return siteData
+}
+
+//copyMetricData returns a deep copy of a metric so that transforms never change the collected data
+//The deviations injected into generated data, never changed by transforms, are shared so the copy is still evaluated against them
+func copyMetricData(metricData MetricData) MetricData {
+	res := MetricData{Metric: metricData.Metric, Unit: metricData.Unit, Type: metricData.Type, AlertDirection: metricData.AlertDirection, Attributes: append([]string{}, metricData.Attributes...), AttributeData: map[string][]TimeStepData{}, Injected: metricData.Injected}
+	for attribute, data := range metricData.AttributeData {
+		res.AttributeData[attribute] = append([]TimeStepData{}, data...)
+	}
+	return res
+}
+
+//scaleMetric multiplies all values of a metric by the given factor, setting its unit if one is given
+func scaleMetric(metricData *MetricData, factor float64, unit string) {
+	for _, data := range metricData.AttributeData {
+		for ind := range data {
+			data[ind].Value *= factor
+		}
+	}
+	if unit != "" {
+		metricData.Unit = unit
+	}
+}
+
+//clampSeries limits the values of a series to multiplier robust standard deviations (median absolute deviation) around its median
+//Series without spread are left untouched
+func clampSeries(data []TimeStepData, multiplier float64) {
+	values := make([]float64, len(data))
+	for ind, stepData := range data {
+		values[ind] = stepData.Value
+	}
+	center := medianValue(values)
+	for ind, value := range values {
+		values[ind] = math.Abs(value - center)
+	}
+	limit := multiplier * transformMadScale * medianValue(values)
+	if limit == 0 {
+		return
+	}
+
+	for ind := range data {
+		data[ind].Value = math.Max(center-limit, math.Min(center+limit, data[ind].Value))
+	}
+}
+
+//mergeAttributes combines the given attribute paths of a metric into a single one, matching their time steps by date
+//The merged path takes the place of the first source found, sources missing on the metric being ignored
+func mergeAttributes(metricData *MetricData, sources []string, into, aggregation string) {
+	groups := map[int64][]TimeStepData{}
+	dates := map[int64]time.Time{}
+
+	attributes := []string{}
+	merged := false
+	for _, attribute := range metricData.Attributes {
+		if !containsName(sources, attribute) && attribute != into {
+			attributes = append(attributes, attribute)
+			continue
+		}
+		if !merged {
+			attributes = append(attributes, into)
+			merged = true
+		}
+		for _, stepData := range metricData.AttributeData[attribute] {
+			key := stepData.DateStart.UnixNano()
+			groups[key] = append(groups[key], stepData)
+			dates[key] = stepData.DateStart
+		}
+		delete(metricData.AttributeData, attribute)
+	}
+	if !merged {
+		return
+	}
+
+	data := []TimeStepData{}
+	for key, group := range groups {
+		data = append(data, CombineSteps(dates[key], group, aggregation))
+	}
+	sort.Slice(data, func(i, j int) bool { return data[i].DateStart.Before(data[j].DateStart) })
+
+	metricData.Attributes = attributes
+	metricData.AttributeData[into] = data
+}
+
+//medianValue returns the median of the given values without changing their order
+func medianValue(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sorted := append([]float64{}, values...)
+	sort.Float64s(sorted)
+	middle := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[middle-1] + sorted[middle]) / 2
+	}
+	return sorted[middle]
+}
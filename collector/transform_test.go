@@ -0,0 +1,165 @@
+package collector
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ftfmtavares/anomalies-detector/config"
+)
+
+func TestCompileTransforms(t *testing.T) {
+	tests := []struct {
+		name           string
+		confTransforms []config.Transform
+		wantErr        bool
+	}{
+		{
+			name:           "Valid steps",
+			confTransforms: []config.Transform{{Type: "rename", Metric: "Revenue", To: "Sales"}, {Type: "scale", Factor: 0.001, Unit: "k€"}, {Type: "clamp", Multiplier: 3}},
+		},
+		{
+			name:           "Merge following the metric type",
+			confTransforms: []config.Transform{{Type: "merge", Attributes: []string{"Browser>Edge", "Browser>Opera"}, Into: "Browser>Other"}},
+		},
+		{
+			name:           "Rename without target",
+			confTransforms: []config.Transform{{Type: "rename", Metric: "Revenue"}},
+			wantErr:        true,
+		},
+		{
+			name:           "Scale without factor",
+			confTransforms: []config.Transform{{Type: "scale", Unit: "k€"}},
+			wantErr:        true,
+		},
+		{
+			name:           "Clamp without multiplier",
+			confTransforms: []config.Transform{{Type: "clamp"}},
+			wantErr:        true,
+		},
+		{
+			name:           "Merge with invalid aggregation",
+			confTransforms: []config.Transform{{Type: "merge", Attributes: []string{"Browser>Edge"}, Into: "Browser>Other", Aggregation: "max"}},
+			wantErr:        true,
+		},
+		{
+			name:           "Unknown type",
+			confTransforms: []config.Transform{{Type: "filter"}},
+			wantErr:        true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := CompileTransforms(tt.confTransforms)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("CompileTransforms() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if len(got) != len(tt.confTransforms) {
+				t.Errorf("CompileTransforms() returned %d steps, want %d", len(got), len(tt.confTransforms))
+			}
+		})
+	}
+}
+
+func TestApplyTransforms(t *testing.T) {
+	day := time.Date(2022, 9, 1, 0, 0, 0, 0, time.UTC)
+	series := func(values []float64, samples []int) []TimeStepData {
+		data := []TimeStepData{}
+		for i := range values {
+			data = append(data, TimeStepData{DateStart: day.AddDate(0, 0, i), Value: values[i], Samples: samples[i]})
+		}
+		return data
+	}
+	siteData := SiteData{SiteId: "site", Metrics: []MetricData{
+		{Metric: "Revenue", Unit: "€", Attributes: []string{"Total", "Browser>Chrome", "Browser>Edge", "Browser>Opera"}, AttributeData: map[string][]TimeStepData{
+			"Total":          series([]float64{1000, 1100, 900, 1000, 9000}, []int{10, 10, 10, 10, 10}),
+			"Browser>Chrome": series([]float64{800, 900, 700, 800, 800}, []int{8, 8, 8, 8, 8}),
+			"Browser>Edge":   series([]float64{150, 150, 150, 150}, []int{1, 1, 1, 1}),
+			"Browser>Opera":  series([]float64{50, 50, 50, 50, 50}, []int{1, 3, 1, 1, 1}),
+		}},
+		{Metric: "Visits", Unit: "", Attributes: []string{"Total"}, AttributeData: map[string][]TimeStepData{
+			"Total": series([]float64{100, 100}, []int{100, 100}),
+		}},
+	}}
+
+	tests := []struct {
+		name           string
+		confTransforms []config.Transform
+		wantMetric     MetricData
+	}{
+		{
+			name:           "Rename and scale",
+			confTransforms: []config.Transform{{Type: "rename", Metric: "Revenue", To: "Sales"}, {Type: "scale", Metric: "Sales", Factor: 0.5, Unit: "k€"}},
+			wantMetric: MetricData{Metric: "Sales", Unit: "k€", Attributes: []string{"Total", "Browser>Chrome", "Browser>Edge", "Browser>Opera"}, AttributeData: map[string][]TimeStepData{
+				"Total":          series([]float64{500, 550, 450, 500, 4500}, []int{10, 10, 10, 10, 10}),
+				"Browser>Chrome": series([]float64{400, 450, 350, 400, 400}, []int{8, 8, 8, 8, 8}),
+				"Browser>Edge":   series([]float64{75, 75, 75, 75}, []int{1, 1, 1, 1}),
+				"Browser>Opera":  series([]float64{25, 25, 25, 25, 25}, []int{1, 3, 1, 1, 1}),
+			}},
+		},
+		{
+			name:           "Clamp limits spikes around the median",
+			confTransforms: []config.Transform{{Type: "clamp", Metric: "Revenue", Multiplier: 2}},
+			wantMetric: MetricData{Metric: "Revenue", Unit: "€", Attributes: []string{"Total", "Browser>Chrome", "Browser>Edge", "Browser>Opera"}, AttributeData: map[string][]TimeStepData{
+				"Total":          series([]float64{1000, 1100, 900, 1000, 1296.52}, []int{10, 10, 10, 10, 10}),
+				"Browser>Chrome": series([]float64{800, 900, 700, 800, 800}, []int{8, 8, 8, 8, 8}),
+				"Browser>Edge":   series([]float64{150, 150, 150, 150}, []int{1, 1, 1, 1}),
+				"Browser>Opera":  series([]float64{50, 50, 50, 50, 50}, []int{1, 3, 1, 1, 1}),
+			}},
+		},
+		{
+			name:           "Merge by sum",
+			confTransforms: []config.Transform{{Type: "merge", Metric: "Revenue", Attributes: []string{"Browser>Edge", "Browser>Opera"}, Into: "Browser>Other", Aggregation: "sum"}},
+			wantMetric: MetricData{Metric: "Revenue", Unit: "€", Attributes: []string{"Total", "Browser>Chrome", "Browser>Other"}, AttributeData: map[string][]TimeStepData{
+				"Total":          series([]float64{1000, 1100, 900, 1000, 9000}, []int{10, 10, 10, 10, 10}),
+				"Browser>Chrome": series([]float64{800, 900, 700, 800, 800}, []int{8, 8, 8, 8, 8}),
+				"Browser>Other":  series([]float64{200, 200, 200, 200, 50}, []int{2, 4, 2, 2, 1}),
+			}},
+		},
+		{
+			name:           "Merge by samples weighted mean",
+			confTransforms: []config.Transform{{Type: "merge", Metric: "Revenue", Attributes: []string{"Browser>Edge", "Browser>Opera"}, Into: "Browser>Other", Aggregation: "mean"}},
+			wantMetric: MetricData{Metric: "Revenue", Unit: "€", Attributes: []string{"Total", "Browser>Chrome", "Browser>Other"}, AttributeData: map[string][]TimeStepData{
+				"Total":          series([]float64{1000, 1100, 900, 1000, 9000}, []int{10, 10, 10, 10, 10}),
+				"Browser>Chrome": series([]float64{800, 900, 700, 800, 800}, []int{8, 8, 8, 8, 8}),
+				"Browser>Other":  series([]float64{100, 75, 100, 100, 50}, []int{2, 4, 2, 2, 1}),
+			}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			transforms, err := CompileTransforms(tt.confTransforms)
+			if err != nil {
+				t.Fatalf("CompileTransforms() error = %v", err)
+			}
+			got := ApplyTransforms(siteData, transforms)
+			if len(got.Metrics) != 2 {
+				t.Fatalf("ApplyTransforms() returned %d metrics, want 2", len(got.Metrics))
+			}
+			if !reflect.DeepEqual(roundMetricData(got.Metrics[0]), tt.wantMetric) {
+				t.Errorf("ApplyTransforms() = %v, want %v", got.Metrics[0], tt.wantMetric)
+			}
+			if !reflect.DeepEqual(got.Metrics[1], siteData.Metrics[1]) {
+				t.Errorf("ApplyTransforms() changed metric %s, which wasn't targeted", got.Metrics[1].Metric)
+			}
+
+			//The collected data must be left untouched
+			if siteData.Metrics[0].Metric != "Revenue" || siteData.Metrics[0].AttributeData["Total"][4].Value != 9000 || len(siteData.Metrics[0].Attributes) != 4 {
+				t.Errorf("ApplyTransforms() changed its input data")
+			}
+		})
+	}
+}
+
+//roundMetricData rounds all values of a metric to 2 decimal places to allow comparisons
+func roundMetricData(metricData MetricData) MetricData {
+	for _, data := range metricData.AttributeData {
+		for ind := range data {
+			data[ind].Value = float64(int64(data[ind].Value*100+0.5)) / 100
+		}
+	}
+	return metricData
+}
@@ -1,32 +1,663 @@
 package config
 
 import (
-	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
-	"os"
+	"strings"
+	"time"
+)
+
+//Const block defines the supported metric types, telling how the values of several time steps or attribute paths of a metric combine
+//Sum metrics add up (e.g. revenue), Average metrics are means weighted by their samples (e.g. basket value) and Count metrics count their own samples (e.g. visits)
+const (
+	MetricTypeSum     = "Sum"
+	MetricTypeAverage = "Average"
+	MetricTypeCount   = "Count"
+)
+
+//Const block defines the supported cumulative metrics, collected as running totals and differenced to per time step values
+//Daily totals restart at midnight, while counters only restart when their value decreases (e.g. on a process restart), as daily totals may as well
+const (
+	CumulativeDaily   = "daily"
+	CumulativeCounter = "counter"
+)
+
+//Const block defines the directions of a detected event, above (spike) or below (drop) the expected value
+const (
+	DirectionSpike = "spike"
+	DirectionDrop  = "drop"
 )
 
 //ApplicationConfig provides the structure for the entire configuration file
+//Fields holding credentials are tagged secret:"true", so they are masked whenever the configuration is logged
+//Concurrency field is the number of datasets collected and analysed in parallel (0 for the number of CPUs)
+//Storage field optionally keeps the collected data of every run in raw and rolled up tiers
+//EventState field optionally tracks the reported events across runs, so that overlapping runs don't notify the same events again
+//Database field optionally writes the collected data and detected events of every run to a SQLite or PostgreSQL database for historical queries
+//Seed field optionally fixes the seed the generated datasets derive from, so runs with the same seed generate the same values (random if 0)
+//Simulation field optionally replaces the default simulation profile of the generated datasets
+//Files field optionally sets the permissions, ownership and location of the written files
+//KnownEvents field optionally lists the known events of every site, such as holidays, added to those of each dataset
 type ApplicationConfig struct {
 	Datasets          []Dataset              `json:"datasets"`
 	DetectionMethods  DetectionMethodsParams `json:"detectionMethods"`
 	GenCollectFilters CollectFilters         `json:"genCollectFilters"`
+	ReportServer      ReportServerParams     `json:"reportServer"`
+	AlertRules        []AlertRule            `json:"alertRules"`
+	Notifiers         NotifiersParams        `json:"notifiers"`
+	Subscriptions     []Subscription         `json:"subscriptions"`
+	Watchdog          *WatchdogParams        `json:"watchdog"`
+	Metrics           []MetricDefinition     `json:"metrics"`
+	Concurrency       int                    `json:"concurrency"`
+	Storage           *StorageParams         `json:"storage"`
+	Outbound          *OutboundParams        `json:"outbound"`
+	EventState        *EventStateParams      `json:"eventState"`
+	Database          *DatabaseParams        `json:"database"`
+	Seed              int64                  `json:"seed"`
+	Simulation        *SimulationParams      `json:"simulation"`
+	Files             *FilesParams           `json:"files"`
+	KnownEvents       []KnownEvent           `json:"knownEvents"`
+}
+
+//MetricDefinition provides the structure for the declaration of a metric unit and type, whatever the backend collecting it
+//Type field is one of "Sum", "Average" or "Count", while Unit field is used for collected metrics without their own unit
+//AlertDirection field optionally restricts alarms to "spike" or "drop" events, those of the other direction being downgraded to warnings
+//Cumulative field optionally declares Sum and Count metrics collected as running totals, either "daily" or "counter"
+//Expression field optionally derives the metric from collected ones instead of collecting it, e.g. "Orders / Visits", with +, -, *, / and parentheses
+type MetricDefinition struct {
+	Name           string `json:"name"`
+	Unit           string `json:"unit"`
+	Type           string `json:"type"`
+	AlertDirection string `json:"alertDirection"`
+	Cumulative     string `json:"cumulative"`
+	Expression     string `json:"expression"`
+}
+
+//DefaultMetrics returns the definitions of the generated Revenue, Basket and Visits metrics, used unless declared otherwise
+func DefaultMetrics() []MetricDefinition {
+	return []MetricDefinition{
+		{Name: "Revenue", Unit: "Total Orders (EUR)", Type: MetricTypeSum},
+		{Name: "Basket", Unit: "Average Basket Value (EUR)", Type: MetricTypeAverage},
+		{Name: "Visits", Unit: "Number of Sessions", Type: MetricTypeCount},
+	}
+}
+
+//Aggregation returns how the values of several time steps or attribute paths of the metric combine,
+//"mean" weighted by samples for Average metrics and "sum" otherwise, undeclared metrics being summed as well
+func (definition MetricDefinition) Aggregation() string {
+	if definition.Type == MetricTypeAverage {
+		return "mean"
+	}
+	return "sum"
+}
+
+//MetricRegistry returns the metric definitions by name, the declared ones overriding the simulated ones, which override the defaults
+//It returns an error if a declared metric misses its name or has an unknown type or alert direction
+func (appConfig ApplicationConfig) MetricRegistry() (map[string]MetricDefinition, error) {
+	registry := map[string]MetricDefinition{}
+	for _, definition := range DefaultMetrics() {
+		registry[definition.Name] = definition
+	}
+	for _, simulation := range appConfig.simulations() {
+		for _, metric := range simulation.Metrics {
+			if metric.Unit != "" || metric.Type != "" {
+				definition := registry[metric.Name]
+				definition.Name = metric.Name
+				if metric.Unit != "" {
+					definition.Unit = metric.Unit
+				}
+				if metric.Type != "" {
+					definition.Type = metric.Type
+				}
+				registry[metric.Name] = definition
+			}
+		}
+	}
+	for i, definition := range appConfig.Metrics {
+		if definition.Name == "" {
+			return nil, fmt.Errorf("metric #%d - name is required", i+1)
+		}
+		if definition.Type != MetricTypeSum && definition.Type != MetricTypeAverage && definition.Type != MetricTypeCount {
+			return nil, fmt.Errorf("metric %s - invalid type \"%s\", it must be \"Sum\", \"Average\" or \"Count\"", definition.Name, definition.Type)
+		}
+		if definition.AlertDirection != "" && definition.AlertDirection != DirectionSpike && definition.AlertDirection != DirectionDrop {
+			return nil, fmt.Errorf("metric %s - invalid alertDirection \"%s\", it must be \"spike\" or \"drop\"", definition.Name, definition.AlertDirection)
+		}
+		if definition.Cumulative != "" && definition.Cumulative != CumulativeDaily && definition.Cumulative != CumulativeCounter {
+			return nil, fmt.Errorf("metric %s - invalid cumulative \"%s\", it must be \"daily\" or \"counter\"", definition.Name, definition.Cumulative)
+		}
+		if definition.Cumulative != "" && definition.Type == MetricTypeAverage {
+			return nil, fmt.Errorf("metric %s - Average metrics can't be cumulative", definition.Name)
+		}
+		if definition.Cumulative != "" && definition.Expression != "" {
+			return nil, fmt.Errorf("metric %s - derived metrics can't be cumulative", definition.Name)
+		}
+		registry[definition.Name] = definition
+	}
+	return registry, nil
 }
 
 //Dataset provides the structure for each site configurations
 //SiteCollectFilters field is an optional collection filter to be used for this site instead of the general filters
+//SiteDetectionMethods field optionally holds the detection methods parameters to be used for this site instead of the general ones, e.g. to tune its sensitivity
+//ShadowDetectionMethod field optionally names a method run alongside the main one, whose events are recorded and visualized but never notified
+//EncryptionKey field optionally references an AES-256 key ("env:VAR" or "file:path") used to encrypt this site data and report at rest
+//OutliersDetectionMethods field optionally lists several methods run together, replacing OutliersDetectionMethod, their events being merged
+//Consensus field is the number of those methods that must agree on a time step to raise an alarm (0 or 1 for any), time steps flagged by fewer methods being warnings
+//TimeSteps field optionally lists several time steps to analyse the site in the same run (e.g. hourly for fast spikes and daily for slow drifts), replacing TimeStep
+//Objectives field optionally lists the service level objectives whose compliance is tracked on the report
+//Prometheus field optionally collects the site metrics from a Prometheus server instead of generating them
+//Csv field optionally loads the site metrics from an exported CSV file instead of generating them
+//Sql field optionally queries the site metrics from a PostgreSQL or MySQL database instead of generating them
+//Http field optionally fetches the site metrics from a JSON HTTP API instead of generating them
+//Ga4 field optionally collects the site metrics from a Google Analytics 4 property instead of generating them
+//Kafka field optionally streams raw events from a Kafka topic, the site being analysed continuously by the stream command
+//Transforms field optionally lists the transform steps applied in order to the collected data before its analysis
+//BaselineResets field optionally lists the dates ("2006-01-02" or RFC 3339) of tracking changes (e.g. an analytics retagging), the data before the latest one being discarded by the detection
+//BurnIn field is the period after a reset (e.g. "7d") whose events are dropped while the new baseline builds up, none if empty
+//DetectionProfile field optionally names a detection preset ("sensitive", "balanced" or "quiet") bundling the method, multipliers, debounce and cooldown of the site
+//Debounce field is the minimum number of time steps an event must last to be reported, Cooldown field the period after an event (e.g. "2d") during which
+//new events of the same metric and attribute are dropped, both overriding those of the profile
+//Locale field optionally sets the date formats, number separators and first day of the week the site charts and reports are shown with
+//Archived field stops collecting, analysing and alerting on the site while its stored data and reports stay browsable on the report server
+//EmailRecipients field optionally replaces the recipients of the email notifier digests of the site
+//PostProcessors field optionally lists the post-processing steps run in order over the detected events of the site before they are reported and notified
+//Seed field optionally fixes the seed of the generated data of the site, overriding the one derived from the general seed (random if 0)
+//Simulation field optionally sets the simulation profile of the generated data of the site, replacing the general one
+//MetricDetection field optionally maps metric names to the detection settings of those metrics, e.g. to analyse Revenue less sensitively than Visits
+//Gaps field optionally fills the time steps missing from the collected data and reports the gaps of the metric totals apart from the outliers
+//KnownEvents field optionally lists the known events of the site, such as Black Friday or campaign launches, whose outliers are expected
+//Timezone field optionally sets the IANA time zone (e.g. "Europe/Lisbon") daily and weekly time steps are aligned to and timestamps are reported in, the server one if empty
+//Expectations field exports on the report the value every time step was expected to have and the deviations tolerated around it, so charts show the bands the data is checked against
 type Dataset struct {
-	SiteId                  string          `json:"siteId"`
-	TimeAgo                 string          `json:"timeAgo"`
-	TimeStep                string          `json:"timeStep"`
-	OutliersDetectionMethod string          `json:"outliersDetectionMethod"`
-	MetricesList            []string        `json:"metricesList"`
-	SiteCollectFilters      *CollectFilters `json:"siteCollectFilters"`
+	SiteId                   string                     `json:"siteId"`
+	TimeAgo                  string                     `json:"timeAgo"`
+	TimeStep                 string                     `json:"timeStep"`
+	TimeSteps                []string                   `json:"timeSteps"`
+	OutliersDetectionMethod  string                     `json:"outliersDetectionMethod"`
+	OutliersDetectionMethods []string                   `json:"outliersDetectionMethods"`
+	Consensus                int                        `json:"consensus"`
+	ShadowDetectionMethod    string                     `json:"shadowDetectionMethod"`
+	MetricesList             []string                   `json:"metricesList"`
+	SiteCollectFilters       *CollectFilters            `json:"siteCollectFilters"`
+	SiteDetectionMethods     *DetectionMethodsParams    `json:"siteDetectionMethods"`
+	EncryptionKey            string                     `json:"encryptionKey" secret:"true"`
+	Objectives               []Objective                `json:"objectives"`
+	Prometheus               *PrometheusParams          `json:"prometheus"`
+	Csv                      *CsvParams                 `json:"csv"`
+	Sql                      *SqlParams                 `json:"sql"`
+	Http                     *HttpParams                `json:"http"`
+	Ga4                      *Ga4Params                 `json:"ga4"`
+	Kafka                    *KafkaParams               `json:"kafka"`
+	Transforms               []Transform                `json:"transforms"`
+	BaselineResets           []string                   `json:"baselineResets"`
+	BurnIn                   string                     `json:"burnIn"`
+	DetectionProfile         string                     `json:"detectionProfile"`
+	Debounce                 int                        `json:"debounce"`
+	Cooldown                 string                     `json:"cooldown"`
+	Locale                   *LocaleParams              `json:"locale"`
+	Archived                 bool                       `json:"archived"`
+	EmailRecipients          []string                   `json:"emailRecipients"`
+	PostProcessors           []PostProcessor            `json:"postProcessors"`
+	Seed                     int64                      `json:"seed"`
+	Simulation               *SimulationParams          `json:"simulation"`
+	MetricDetection          map[string]MetricDetection `json:"metricDetection"`
+	Gaps                     *GapParams                 `json:"gaps"`
+	Timezone                 string                     `json:"timezone"`
+	KnownEvents              []KnownEvent               `json:"knownEvents"`
+	Expectations             bool                       `json:"expectations"`
+}
+
+//Const block defines the strategies filling the time steps missing from the collected data
+const (
+	GapZeroFill    = "zero-fill"
+	GapInterpolate = "interpolate"
+	GapMarkMissing = "mark-missing"
+)
+
+//GapParams provides the structure for the filling of the time steps missing from the collected data, against the time step grid of the analysed period
+//Strategy field fills them with 0 ("zero-fill") or with values interpolated between the neighbouring time steps ("interpolate"),
+//or only marks them as missing ("mark-missing") so the detection leaves them out
+//MinSteps field is the number of consecutive time steps missing from a metric total for a data gap to be reported (1 if 0)
+type GapParams struct {
+	Strategy string `json:"strategy"`
+	MinSteps int    `json:"minSteps"`
+}
+
+//Const block defines how the outliers of a known event are handled, either left out of the detection or reported as expected
+const (
+	KnownEventExclude = "exclude"
+	KnownEventTag     = "tag"
+)
+
+//Const block defines the supported recurrences of a known event
+const (
+	RecurrenceWeekly  = "weekly"
+	RecurrenceMonthly = "monthly"
+	RecurrenceYearly  = "yearly"
+)
+
+//KnownEvent provides the structure for a known event window, such as a holiday, a sale or a campaign launch, tripping the detection every time it happens
+//Start and End fields are the start and the (exclusive) end of its first occurrence, as dates or RFC 3339 times, dates being taken in the dataset time zone
+//Recurrence field optionally repeats the event "weekly", "monthly" or "yearly" after its first occurrence
+//Action field either leaves its time steps out of the detection ("exclude"), so they don't skew the baseline statistics, or marks the events overlapping it
+//as expected ("tag", the default), alarms being reported as warnings
+//Metrics field optionally restricts the event to the listed metrics, all of them being affected otherwise
+type KnownEvent struct {
+	Name       string   `json:"name"`
+	Start      string   `json:"start"`
+	End        string   `json:"end"`
+	Recurrence string   `json:"recurrence"`
+	Action     string   `json:"action"`
+	Metrics    []string `json:"metrics"`
+}
+
+//Period returns the start and end of the first occurrence of the known event, dates being taken in the given location
+//It returns an error if any of them is invalid or if the event doesn't end after it starts
+func (event KnownEvent) Period(location *time.Location) (time.Time, time.Time, error) {
+	parse := func(value string) (time.Time, error) {
+		date, err := time.ParseInLocation("2006-01-02", value, location)
+		if err != nil {
+			if date, err = time.Parse(time.RFC3339, value); err != nil {
+				return time.Time{}, fmt.Errorf("invalid time \"%s\", it must be a date or an RFC 3339 time", value)
+			}
+		}
+		return date, nil
+	}
+	start, err := parse(event.Start)
+	if err != nil {
+		return start, start, err
+	}
+	end, err := parse(event.End)
+	if err != nil {
+		return start, end, err
+	}
+	if !end.After(start) {
+		return start, end, fmt.Errorf("end \"%s\" must be after start \"%s\"", event.End, event.Start)
+	}
+	return start, end, nil
+}
+
+//MetricDetection provides the structure for the detection settings of a single metric of a dataset, replacing those of the dataset
+//OutliersDetectionMethod or OutliersDetectionMethods fields, along with Consensus field, replace the dataset methods if given
+//DetectionMethods field optionally holds the detection methods parameters of the metric, replacing those of the dataset as a whole
+type MetricDetection struct {
+	OutliersDetectionMethod  string                  `json:"outliersDetectionMethod"`
+	OutliersDetectionMethods []string                `json:"outliersDetectionMethods"`
+	Consensus                int                     `json:"consensus"`
+	DetectionMethods         *DetectionMethodsParams `json:"detectionMethods"`
+}
+
+//Apply returns the detection methods, consensus and parameters of the metric, those given being taken from the metric settings
+func (detection MetricDetection) Apply(methods []string, consensus int, methodParams DetectionMethodsParams) ([]string, int, DetectionMethodsParams) {
+	if len(detection.OutliersDetectionMethods) > 0 {
+		methods, consensus = detection.OutliersDetectionMethods, detection.Consensus
+	} else if detection.OutliersDetectionMethod != "" {
+		methods, consensus = []string{detection.OutliersDetectionMethod}, 0
+	}
+	if detection.DetectionMethods != nil {
+		methodParams = *detection.DetectionMethods
+	}
+	return methods, consensus, methodParams
+}
+
+//MetricParamsOnly returns the metric detection settings keeping only their parameters, for runs whose methods are set apart (e.g. shadow methods)
+func MetricParamsOnly(metricDetection map[string]MetricDetection) map[string]MetricDetection {
+	res := map[string]MetricDetection{}
+	for metric, detection := range metricDetection {
+		res[metric] = MetricDetection{DetectionMethods: detection.DetectionMethods}
+	}
+	return res
+}
+
+//Location returns the time zone of the dataset, the server local one if none is set
+//It returns an error if the time zone is unknown
+func (dataset Dataset) Location() (*time.Location, error) {
+	if dataset.Timezone == "" {
+		return time.Local, nil
+	}
+	location, err := time.LoadLocation(dataset.Timezone)
+	if err != nil {
+		return nil, fmt.Errorf("unknown timezone \"%s\"", dataset.Timezone)
+	}
+	return location, nil
+}
+
+//Resolutions returns the time steps the dataset is analysed at, TimeSteps if given or TimeStep otherwise
+func (dataset Dataset) Resolutions() []string {
+	if len(dataset.TimeSteps) > 0 {
+		return dataset.TimeSteps
+	}
+	return []string{dataset.TimeStep}
+}
+
+//Methods returns the detection methods the dataset is analysed with, OutliersDetectionMethods if given or OutliersDetectionMethod otherwise
+func (dataset Dataset) Methods() []string {
+	if len(dataset.OutliersDetectionMethods) > 0 {
+		return dataset.OutliersDetectionMethods
+	}
+	return []string{dataset.OutliersDetectionMethod}
+}
+
+//MethodParams returns the detection methods parameters the dataset is analysed with, SiteDetectionMethods if given or the general ones otherwise
+func (dataset Dataset) MethodParams(general DetectionMethodsParams) DetectionMethodsParams {
+	if dataset.SiteDetectionMethods != nil {
+		return *dataset.SiteDetectionMethods
+	}
+	return general
+}
+
+//DetectionProfile provides the structure for a named detection preset, so sites get sane detection without tuning every parameter
+//Method field is used for sites without their own detection method, while the multipliers replace those of the 3-sigmas, rolling-3-sigmas, stl, ewma, holt-winters and seasonal-buckets methods
+//Debounce and Cooldown fields are used for sites without their own ones
+type DetectionProfile struct {
+	Method                   string
+	OutliersMultiplier       float64
+	StrongOutliersMultiplier float64
+	Debounce                 int
+	Cooldown                 string
+}
+
+//DetectionProfiles returns the supported detection presets by name
+//"sensitive" flags every short deviation, "balanced" is the usual trade-off and "quiet" only reports lasting, strong deviations and repeats them less often
+func DetectionProfiles() map[string]DetectionProfile {
+	return map[string]DetectionProfile{
+		"sensitive": {Method: "3-sigmas", OutliersMultiplier: 1.5, StrongOutliersMultiplier: 2.5, Debounce: 1},
+		"balanced":  {Method: "3-sigmas", OutliersMultiplier: 2.0, StrongOutliersMultiplier: 3.0, Debounce: 1, Cooldown: "2d"},
+		"quiet":     {Method: "3-sigmas", OutliersMultiplier: 3.0, StrongOutliersMultiplier: 4.0, Debounce: 2, Cooldown: "7d"},
+	}
+}
+
+//ApplyDetectionProfile returns the dataset and the detection methods parameters with the dataset profile applied, unchanged if it has none
+//It returns an error if the profile is unknown
+func (dataset Dataset) ApplyDetectionProfile(methodParams DetectionMethodsParams) (Dataset, DetectionMethodsParams, error) {
+	if dataset.DetectionProfile == "" {
+		return dataset, methodParams, nil
+	}
+	profile, found := DetectionProfiles()[dataset.DetectionProfile]
+	if !found {
+		return dataset, methodParams, fmt.Errorf("unknown detection profile \"%s\", it must be \"sensitive\", \"balanced\" or \"quiet\"", dataset.DetectionProfile)
+	}
+
+	if dataset.OutliersDetectionMethod == "" && len(dataset.OutliersDetectionMethods) == 0 {
+		dataset.OutliersDetectionMethod = profile.Method
+	}
+	if dataset.Debounce == 0 {
+		dataset.Debounce = profile.Debounce
+	}
+	if dataset.Cooldown == "" {
+		dataset.Cooldown = profile.Cooldown
+	}
+	return dataset, methodParams.WithMultipliers(profile.OutliersMultiplier, profile.StrongOutliersMultiplier), nil
+}
+
+//LocaleParams provides the structure for the regional display settings of a site
+//Name field optionally picks a preset ("iso", "en-US", "en-GB", "de-DE", "fr-FR", "es-ES", "pt-PT" or "pt-BR") whose settings are used for the fields left empty
+//DateFormat field is a Go layout (e.g. "02/01/2006"), DecimalSeparator and ThousandsSeparator fields format the numbers and FirstDayOfWeek field is a weekday name (e.g. "sunday")
+type LocaleParams struct {
+	Name               string `json:"name"`
+	DateFormat         string `json:"dateFormat"`
+	DecimalSeparator   string `json:"decimalSeparator"`
+	ThousandsSeparator string `json:"thousandsSeparator"`
+	FirstDayOfWeek     string `json:"firstDayOfWeek"`
+}
+
+//Locales returns the supported locale presets by name, "iso" being the formats used for sites without a locale
+func Locales() map[string]LocaleParams {
+	return map[string]LocaleParams{
+		"iso":   {DateFormat: "2006-01-02", DecimalSeparator: ".", FirstDayOfWeek: "monday"},
+		"en-US": {DateFormat: "01/02/2006", DecimalSeparator: ".", ThousandsSeparator: ",", FirstDayOfWeek: "sunday"},
+		"en-GB": {DateFormat: "02/01/2006", DecimalSeparator: ".", ThousandsSeparator: ",", FirstDayOfWeek: "monday"},
+		"de-DE": {DateFormat: "02.01.2006", DecimalSeparator: ",", ThousandsSeparator: ".", FirstDayOfWeek: "monday"},
+		"fr-FR": {DateFormat: "02/01/2006", DecimalSeparator: ",", ThousandsSeparator: " ", FirstDayOfWeek: "monday"},
+		"es-ES": {DateFormat: "02/01/2006", DecimalSeparator: ",", ThousandsSeparator: ".", FirstDayOfWeek: "monday"},
+		"pt-PT": {DateFormat: "02/01/2006", DecimalSeparator: ",", ThousandsSeparator: " ", FirstDayOfWeek: "monday"},
+		"pt-BR": {DateFormat: "02/01/2006", DecimalSeparator: ",", ThousandsSeparator: ".", FirstDayOfWeek: "sunday"},
+	}
+}
+
+//Resolve returns the locale with the fields left empty taken from its preset, the "iso" one if it has no name
+//It returns an error if the preset or the first day of the week is unknown, or if both separators are the same
+func (locale LocaleParams) Resolve() (LocaleParams, error) {
+	name := locale.Name
+	if name == "" {
+		name = "iso"
+	}
+	preset, found := Locales()[name]
+	if !found {
+		return locale, fmt.Errorf("unknown locale \"%s\"", locale.Name)
+	}
+	if locale.DateFormat == "" {
+		locale.DateFormat = preset.DateFormat
+	}
+	if locale.DecimalSeparator == "" {
+		locale.DecimalSeparator = preset.DecimalSeparator
+	}
+	if locale.ThousandsSeparator == "" {
+		locale.ThousandsSeparator = preset.ThousandsSeparator
+	}
+	if locale.FirstDayOfWeek == "" {
+		locale.FirstDayOfWeek = preset.FirstDayOfWeek
+	}
+	if _, err := locale.Weekday(); err != nil {
+		return locale, err
+	}
+	if locale.DecimalSeparator == locale.ThousandsSeparator {
+		return locale, errors.New("decimalSeparator and thousandsSeparator must differ")
+	}
+	return locale, nil
+}
+
+//Weekday returns the first day of the week of the locale
+//It returns an error if it isn't a weekday name
+func (locale LocaleParams) Weekday() (time.Weekday, error) {
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		if strings.EqualFold(locale.FirstDayOfWeek, day.String()) {
+			return day, nil
+		}
+	}
+	return time.Monday, fmt.Errorf("unknown firstDayOfWeek \"%s\"", locale.FirstDayOfWeek)
+}
+
+//PrometheusParams provides the structure for the Prometheus collector backend
+//Url field is the Prometheus server base address and BearerToken field an optional token, accepting "env:VAR" references
+//Queries field maps each collected metric name to its PromQL expressions, all of them being collected when the metrics list is "all"
+type PrometheusParams struct {
+	Url         string                     `json:"url"`
+	BearerToken string                     `json:"bearerToken" secret:"true"`
+	Queries     map[string]PrometheusQuery `json:"queries"`
+}
+
+//PrometheusQuery provides the structure for the PromQL expressions of a single metric, each one returning a single series
+//Query field is evaluated at the end of every time step (e.g. sum(increase(orders_revenue_total[1d]))) giving the metric value
+//SamplesQuery field is the companion expression counting the samples behind each value (e.g. visitors), 1 sample per time step being assumed if empty
+type PrometheusQuery struct {
+	Query        string `json:"query"`
+	SamplesQuery string `json:"samplesQuery"`
+	Unit         string `json:"unit"`
+}
+
+//CsvParams provides the structure for the CSV file collector backend
+//File field is the CSV file whose header line names the columns mapped by the other fields, Delimiter field defaulting to ","
+//TimestampColumn and ValueColumn fields are required, TimestampFormat field being a Go time layout, "unix" or "unix-ms" (RFC 3339 by default)
+//MetricColumn field names the metric of each row, Metric field naming the single metric of files without such column
+//AttributeColumn field holds the attribute path of each row (e.g. Browser>Chrome), rows without it belonging to "Total"
+//SamplesColumn field is optional, 1 sample per row being assumed without it
+//Aggregation field combines the rows of the same time step, either "sum" (default) or "mean" weighted by samples, Units field mapping metrics to their units
+type CsvParams struct {
+	File            string            `json:"file"`
+	Delimiter       string            `json:"delimiter"`
+	TimestampColumn string            `json:"timestampColumn"`
+	TimestampFormat string            `json:"timestampFormat"`
+	MetricColumn    string            `json:"metricColumn"`
+	Metric          string            `json:"metric"`
+	ValueColumn     string            `json:"valueColumn"`
+	SamplesColumn   string            `json:"samplesColumn"`
+	AttributeColumn string            `json:"attributeColumn"`
+	Aggregation     string            `json:"aggregation"`
+	Units           map[string]string `json:"units"`
+}
+
+//HttpParams provides the structure for the JSON HTTP API collector backend
+//Url field is the request address template, whose {siteId}, {metric}, {start}, {end} (RFC 3339) and {step} (seconds) placeholders are replaced on each request
+//BearerToken field, or Username and Password fields for basic authentication, are optional and accept "env:VAR" references
+//Headers field adds extra request headers, Metrics field lists the metrics collected when the metrics list is "all" and Units field maps them to their units
+type HttpParams struct {
+	Url         string            `json:"url"`
+	BearerToken string            `json:"bearerToken" secret:"true"`
+	Username    string            `json:"username"`
+	Password    string            `json:"password" secret:"true"`
+	Headers     map[string]string `json:"headers" secret:"true"`
+	Metrics     []string          `json:"metrics"`
+	Units       map[string]string `json:"units"`
+}
+
+//Ga4Params provides the structure for the Google Analytics 4 Data API collector backend
+//PropertyId field is the numeric GA4 property and TimeZone field its reporting time zone (UTC by default), both days and hours being read on it
+//AccessToken field is an OAuth2 token accepting "env:VAR" references, the compute metadata server token being requested if empty
+//Metrics field maps each collected metric name to its GA4 metrics, all of them being collected when the metrics list is "all"
+//Attributes field maps each attribute name to the GA4 dimensions of its successive levels (e.g. "DeviceType": ["deviceCategory", "browser"])
+//Endpoint field optionally replaces the Data API address
+type Ga4Params struct {
+	PropertyId  string               `json:"propertyId"`
+	TimeZone    string               `json:"timeZone"`
+	AccessToken string               `json:"accessToken" secret:"true"`
+	Metrics     map[string]Ga4Metric `json:"metrics"`
+	Attributes  map[string][]string  `json:"attributes"`
+	Endpoint    string               `json:"endpoint"`
+}
+
+//Ga4Metric provides the structure for the GA4 metrics behind a single collected metric
+//Metric field is the GA4 metric giving the value (e.g. purchaseRevenue) and SamplesMetric field the one counting its samples ("sessions" by default)
+//Aggregation field combines the GA4 rows of the same time step, either "sum" (default) or "mean" weighted by samples for ratios (e.g. averagePurchaseRevenue)
+type Ga4Metric struct {
+	Metric        string `json:"metric"`
+	SamplesMetric string `json:"samplesMetric"`
+	Aggregation   string `json:"aggregation"`
+	Unit          string `json:"unit"`
+}
+
+//KafkaParams provides the structure for the Kafka streaming ingestion mode
+//Brokers and Topic fields locate the raw events, GroupId field being the consumer group committing the read offsets ("anomalies-detector" by default)
+//Each message is a JSON event object, TimestampField naming its RFC 3339 or unix seconds time (the message time if empty)
+//MetricField names the metric of each event, Metric field naming the single metric of topics without such field
+//ValueField and SamplesField are optional, each event counting as a value and sample of 1 without them
+//Attributes field maps each attribute name to the event fields of its successive levels (e.g. "Browser": ["browser", "browserVersion"])
+//Aggregation field combines the events of the same time step, either "sum" (default) or "mean" weighted by samples, Units field mapping metrics to their units
+//AnalysisInterval field is how often the buffered events are analysed (every time step by default)
+//Tls field connects to the brokers over TLS, with the CA bundle and client certificate of the outbound settings if any
+type KafkaParams struct {
+	Brokers          []string            `json:"brokers"`
+	Topic            string              `json:"topic"`
+	GroupId          string              `json:"groupId"`
+	TimestampField   string              `json:"timestampField"`
+	MetricField      string              `json:"metricField"`
+	Metric           string              `json:"metric"`
+	ValueField       string              `json:"valueField"`
+	SamplesField     string              `json:"samplesField"`
+	Attributes       map[string][]string `json:"attributes"`
+	Aggregation      string              `json:"aggregation"`
+	Units            map[string]string   `json:"units"`
+	AnalysisInterval string              `json:"analysisInterval"`
+	Tls              bool                `json:"tls"`
+}
+
+//SqlParams provides the structure for the SQL database collector backend
+//Driver field is either "postgres" or "mysql" and Dsn field the driver data source name, accepting "env:VAR" references (MySQL requires parseTime=true)
+//Queries field maps each collected metric name to its query, all of them being collected when the metrics list is "all"
+type SqlParams struct {
+	Driver  string              `json:"driver"`
+	Dsn     string              `json:"dsn" secret:"true"`
+	Queries map[string]SqlQuery `json:"queries"`
+}
+
+//SqlQuery provides the structure for the query of a single metric
+//Query field may use the {start}, {end} and {step} placeholders, bound as parameters with the period start and end times and the time step in seconds
+//Each result row must hold the time step start, the attribute path (empty or "Total" for the total), the value and the samples count, in this order
+type SqlQuery struct {
+	Query string `json:"query"`
+	Unit  string `json:"unit"`
+}
+
+//Transform provides the structure for a transform step applied to the collected data before its analysis
+//Type field is one of "rename" (Metric renamed To), "scale" (values multiplied by Factor, Unit being set if given),
+//"clamp" (values beyond Multiplier robust standard deviations from the median limited to that range, so extreme points don't distort the baseline)
+//or "merge" (Attributes paths combined into the Into path, with the "sum" or samples weighted "mean" Aggregation, following the metric type if empty)
+//Metric field selects the transformed metric, all metrics being transformed by "scale", "clamp" and "merge" if empty
+type Transform struct {
+	Type        string   `json:"type"`
+	Metric      string   `json:"metric"`
+	To          string   `json:"to"`
+	Factor      float64  `json:"factor"`
+	Unit        string   `json:"unit"`
+	Multiplier  float64  `json:"multiplier"`
+	Attributes  []string `json:"attributes"`
+	Into        string   `json:"into"`
+	Aggregation string   `json:"aggregation"`
+}
+
+//PostProcessor provides the structure for a post-processing step run over the detected events of a site, after the alert rules
+//Type field is one of the registered post-processors: "merge" (events of the same metric and attribute less than Gap apart, e.g. "2h", joined into one),
+//"rank" (events ordered by decreasing score, only the MaxEvents highest of each severity being kept if set),
+//"rollup" (events dropped when an overlapping event of the same metric and severity is reported on a parent attribute path),
+//"suppress" (events of the Metric and Attributes paths, or scoring below MinScore, dropped), "explain" (a plain language explanation added to every event)
+//or "group" (events of any metric and attribute overlapping in time, or less than Gap apart, grouped into incidents, the events being kept as they are)
+//Params field holds the parameters of the post-processors registered by Go callers
+type PostProcessor struct {
+	Type       string            `json:"type"`
+	Metric     string            `json:"metric"`
+	Attributes []string          `json:"attributes"`
+	Gap        string            `json:"gap"`
+	MaxEvents  int               `json:"maxEvents"`
+	MinScore   float64           `json:"minScore"`
+	Params     map[string]string `json:"params"`
+}
+
+//Objective provides the structure for a service level objective on a metric (e.g. Revenue >= 1000 per day on 99% of the days)
+//Operator field is either ">=" or "<=", a time step complying with the objective when its value compared with Target satisfies it
+//Compliance field is the required percentage of complying time steps, the remaining percentage being the error budget
+//Window field is the rolling window evaluated up to the end of the collected data (e.g. "30d"), the whole range being used if empty
+//Attribute field defaults to "Total" while TimeStep field optionally restricts the objective to sites analysed at that resolution
+type Objective struct {
+	Name       string  `json:"name"`
+	Metric     string  `json:"metric"`
+	Attribute  string  `json:"attribute"`
+	Operator   string  `json:"operator"`
+	Target     float64 `json:"target"`
+	Compliance float64 `json:"compliance"`
+	Window     string  `json:"window"`
+	TimeStep   string  `json:"timeStep"`
 }
 
 //DetectionMethodsParams provides the structure to store all detection methods parameters
+//AttributeParallelism field is the maximum number of attribute/sub-values combinations analysed at the same time for each site (0 for the number of CPUs)
+//MinPoints field is the minimum number of time steps with a value an attribute/sub-values combination needs to be analysed (0 for the default 3)
+//MinVariance field is the variance of the values an attribute/sub-values combination must exceed to be analysed, constant series never being analysed
 type DetectionMethodsParams struct {
-	ThreeSigmas ThreeSigmasParams `json:"3-sigmas"`
+	ThreeSigmas          ThreeSigmasParams        `json:"3-sigmas"`
+	RollingThreeSigmas   RollingThreeSigmasParams `json:"rolling-3-sigmas"`
+	Stl                  StlParams                `json:"stl"`
+	Ewma                 EwmaParams               `json:"ewma"`
+	Cusum                CusumParams              `json:"cusum"`
+	Esd                  EsdParams                `json:"esd"`
+	HoltWinters          HoltWintersParams        `json:"holt-winters"`
+	SeasonalBuckets      SeasonalBucketsParams    `json:"seasonal-buckets"`
+	PeriodOverPeriod     PeriodOverPeriodParams   `json:"period-over-period"`
+	AttributeParallelism int                      `json:"attributeParallelism"`
+	MinPoints            int                      `json:"minPoints"`
+	MinVariance          float64                  `json:"minVariance"`
+}
+
+//WithMultipliers returns a copy of the parameters with the given multipliers set on the 3-sigmas, rolling-3-sigmas, stl, ewma, holt-winters and seasonal-buckets methods
+func (methodParams DetectionMethodsParams) WithMultipliers(outliersMultiplier, strongOutliersMultiplier float64) DetectionMethodsParams {
+	methodParams.ThreeSigmas.OutliersMultiplier, methodParams.ThreeSigmas.StrongOutliersMultiplier = outliersMultiplier, strongOutliersMultiplier
+	methodParams.RollingThreeSigmas.OutliersMultiplier, methodParams.RollingThreeSigmas.StrongOutliersMultiplier = outliersMultiplier, strongOutliersMultiplier
+	methodParams.Stl.OutliersMultiplier, methodParams.Stl.StrongOutliersMultiplier = outliersMultiplier, strongOutliersMultiplier
+	methodParams.Ewma.OutliersMultiplier, methodParams.Ewma.StrongOutliersMultiplier = outliersMultiplier, strongOutliersMultiplier
+	methodParams.HoltWinters.OutliersMultiplier, methodParams.HoltWinters.StrongOutliersMultiplier = outliersMultiplier, strongOutliersMultiplier
+	methodParams.SeasonalBuckets.OutliersMultiplier, methodParams.SeasonalBuckets.StrongOutliersMultiplier = outliersMultiplier, strongOutliersMultiplier
+	return methodParams
 }
 
 //ThreeSigmasParams provides the structure for the 3-sigmas detection method parameters
@@ -35,10 +666,95 @@ type ThreeSigmasParams struct {
 	StrongOutliersMultiplier float64 `json:"strongOutliersMultiplier"`
 }
 
+//RollingThreeSigmasParams provides the structure for the rolling-3-sigmas detection method parameters
+//WindowSize field is the number of preceding time steps used to compute each point statistics
+type RollingThreeSigmasParams struct {
+	WindowSize               int     `json:"windowSize"`
+	OutliersMultiplier       float64 `json:"outliersMultiplier"`
+	StrongOutliersMultiplier float64 `json:"strongOutliersMultiplier"`
+}
+
+//StlParams provides the structure for the stl (seasonal decomposition) detection method parameters
+//SeasonLength field is the number of time steps of each season (e.g. 7 for weekly seasonality of daily steps)
+//The multipliers are applied to the standard deviation of the residuals left after removing trend and seasonality
+type StlParams struct {
+	SeasonLength             int     `json:"seasonLength"`
+	OutliersMultiplier       float64 `json:"outliersMultiplier"`
+	StrongOutliersMultiplier float64 `json:"strongOutliersMultiplier"`
+}
+
+//EwmaParams provides the structure for the ewma detection method parameters
+//Alpha field is the smoothing factor between 0 and 1, higher values making the baseline follow recent values faster
+//The multipliers are applied to the exponentially weighted standard deviation to define the control limits
+type EwmaParams struct {
+	Alpha                    float64 `json:"alpha"`
+	OutliersMultiplier       float64 `json:"outliersMultiplier"`
+	StrongOutliersMultiplier float64 `json:"strongOutliersMultiplier"`
+}
+
+//CusumParams provides the structure for the cusum detection method parameters
+//BaselineSize field is the number of initial time steps used to estimate the reference mean and standard deviation
+//Drift and Threshold fields are given in standard deviations, Drift being the tolerated slack per time step and Threshold the cumulative sum that triggers an alarm
+type CusumParams struct {
+	BaselineSize int     `json:"baselineSize"`
+	Drift        float64 `json:"drift"`
+	Threshold    float64 `json:"threshold"`
+}
+
+//EsdParams provides the structure for the esd (generalized extreme studentized deviate) detection method parameters
+//MaxOutliers field is the upper bound of outliers tested, Alpha field is the significance level for alarms
+//WarningAlpha field is an optional looser significance level for warnings (0 to disable them)
+type EsdParams struct {
+	MaxOutliers  int     `json:"maxOutliers"`
+	Alpha        float64 `json:"alpha"`
+	WarningAlpha float64 `json:"warningAlpha"`
+}
+
+//HoltWintersParams provides the structure for the holt-winters detection method parameters
+//Alpha, Beta and Gamma fields are the smoothing factors of level, trend and seasonality, between 0 and 1
+//SeasonLength field is the number of time steps of each season, while the multipliers define the confidence bands around the forecast
+//in standard deviations of the forecast errors
+type HoltWintersParams struct {
+	Alpha                    float64 `json:"alpha"`
+	Beta                     float64 `json:"beta"`
+	Gamma                    float64 `json:"gamma"`
+	SeasonLength             int     `json:"seasonLength"`
+	OutliersMultiplier       float64 `json:"outliersMultiplier"`
+	StrongOutliersMultiplier float64 `json:"strongOutliersMultiplier"`
+}
+
+//Const block defines the keys the seasonal-buckets method groups time steps by, the day of the week, the hour of the day or both
+const (
+	BucketDayOfWeek     = "dow"
+	BucketHour          = "hour"
+	BucketDayOfWeekHour = "dow+hour"
+)
+
+//SeasonalBucketsParams provides the structure for the seasonal-buckets detection method parameters
+//BucketKey field is "dow", "hour" or "dow+hour", telling which time steps share a baseline ("dow" if empty)
+//The multipliers are applied to the robust standard deviation of each bucket around its median
+type SeasonalBucketsParams struct {
+	BucketKey                string  `json:"bucketKey"`
+	OutliersMultiplier       float64 `json:"outliersMultiplier"`
+	StrongOutliersMultiplier float64 `json:"strongOutliersMultiplier"`
+}
+
+//PeriodOverPeriodParams provides the structure for the period-over-period detection method parameters
+//Offsets field lists how far back the reference time steps are (e.g. "1w" for the same time step last week, "52w" for last year, "1w" if empty),
+//the reference value being the mean of those found on the collected data
+//WarningPercent and AlarmPercent fields are the deviations from the reference value, in percentage, beyond which time steps are warnings and alarms
+type PeriodOverPeriodParams struct {
+	Offsets        []string `json:"offsets"`
+	WarningPercent float64  `json:"warningPercent"`
+	AlarmPercent   float64  `json:"alarmPercent"`
+}
+
 //CollectFilters provides the structure for collection filters
+//MinSamplesPercentage field is a relative alternative to MinVisitorsPerTimeStep, keeping only attributes covering at least that percentage of the total samples (0 to disable)
 //AttributesFilterParams field is a map that points to the respective attributes parameters
 type CollectFilters struct {
 	MinVisitorsPerTimeStep int                     `json:"minVisitorsPerTimeStep"`
+	MinSamplesPercentage   float64                 `json:"minSamplesPercentage"`
 	AttributesFilterParams map[string]FilterParams `json:"attributesFilterParams"`
 }
 
@@ -50,19 +766,317 @@ type FilterParams struct {
 	Top   int `json:"top"`
 }
 
+//AlertRule provides the structure for a custom alert rule evaluated against every detected event
+//Condition field is an expression (e.g. metric == "Revenue" && severity >= alarm) while Action field is one of "suppress", "upgrade", "downgrade" or "route"
+//Route field names the notification route added to matching events when Action is "route"
+type AlertRule struct {
+	Name      string `json:"name"`
+	Condition string `json:"condition"`
+	Action    string `json:"action"`
+	Route     string `json:"route"`
+}
+
+//NotifiersParams provides the structure to store all notification channels parameters
+//Each channel is optional and only enabled when present
+type NotifiersParams struct {
+	Slack       *SlackParams       `json:"slack"`
+	CloudEvents *CloudEventsParams `json:"cloudEvents"`
+	EventBridge *EventBridgeParams `json:"eventBridge"`
+	PubSub      *PubSubParams      `json:"pubSub"`
+	Webhook     *WebhookParams     `json:"webhook"`
+	Email       *EmailParams       `json:"email"`
+}
+
+//SlackParams provides the structure for the Slack notification channel
+//Either WebhookUrl or BotToken together with Channel must be given, both accepting "env:VAR" references to environment variables
+//Template field is a Go text/template for each message, Severities and Routes fields optionally restrict the notified events
+//MessagesPerMinute and MaxMessagesPerRun fields protect the channel from being flooded by a burst of events (0 for defaults)
+//SigningSecret field enables the HMAC signature header on every outgoing payload so that receivers can authenticate it
+//Batch field groups the events of a site on each run into a single message with expandable details, only their counts and DashboardUrl field being sent above BatchSummaryThreshold events (20 by default)
+//Incidents field posts a single message per incident grouped by the "group" post-processor, with its events as details, instead of one per event
+type SlackParams struct {
+	WebhookUrl            string   `json:"webhookUrl" secret:"true"`
+	BotToken              string   `json:"botToken" secret:"true"`
+	Channel               string   `json:"channel"`
+	Template              string   `json:"template"`
+	Severities            []string `json:"severities"`
+	Routes                []string `json:"routes"`
+	MessagesPerMinute     float64  `json:"messagesPerMinute"`
+	MaxMessagesPerRun     int      `json:"maxMessagesPerRun"`
+	SigningSecret         string   `json:"signingSecret" secret:"true"`
+	Batch                 bool     `json:"batch"`
+	BatchSummaryThreshold int      `json:"batchSummaryThreshold"`
+	DashboardUrl          string   `json:"dashboardUrl"`
+	Incidents             bool     `json:"incidents"`
+}
+
+//CloudEventsParams provides the structure for publishing events in the CloudEvents 1.0 format to an HTTP sink (e.g. a Knative broker)
+//Source field is the CloudEvents source attribute ("/anomalies-detector" by default) while Batch field sends all events of a report in a single batch request
+//Severities and Routes fields optionally restrict the published events, SigningSecret field enabling the HMAC signature header as for Slack
+type CloudEventsParams struct {
+	SinkUrl       string   `json:"sinkUrl"`
+	Source        string   `json:"source"`
+	Batch         bool     `json:"batch"`
+	Severities    []string `json:"severities"`
+	Routes        []string `json:"routes"`
+	SigningSecret string   `json:"signingSecret" secret:"true"`
+}
+
+//WebhookParams provides the structure for the generic webhook channel, posting every event as JSON to the URL of its severity
+//Url field receives the events whose severity has no AlarmsUrl or WarningsUrl field, severities without any URL not being posted, all of them accepting "env:VAR" references
+//Routes field optionally restricts the posted events, SigningSecret field enabling the HMAC signature header as for Slack
+//MaxRetries field is how many times a failed post is retried (3 by default, -1 for none), waiting InitialBackoff ("1s" by default) and then twice as long every time
+type WebhookParams struct {
+	Url            string   `json:"url" secret:"true"`
+	AlarmsUrl      string   `json:"alarmsUrl" secret:"true"`
+	WarningsUrl    string   `json:"warningsUrl" secret:"true"`
+	Routes         []string `json:"routes"`
+	SigningSecret  string   `json:"signingSecret" secret:"true"`
+	MaxRetries     int      `json:"maxRetries"`
+	InitialBackoff string   `json:"initialBackoff"`
+}
+
+//EmailParams provides the structure for the email channel, sending a digest of the events of every report with the charts of their metrics embedded
+//Host and Port fields locate the SMTP server (port 587 by default), Tls field being "starttls" (the default), "tls" for implicit TLS or "none"
+//Username and Password fields are optional, accepting "env:VAR" references, while To field lists the recipients of the sites without their own emailRecipients
+//Severities and Routes fields optionally restrict the events of the digests
+type EmailParams struct {
+	Host       string   `json:"host"`
+	Port       int      `json:"port"`
+	Tls        string   `json:"tls"`
+	Username   string   `json:"username"`
+	Password   string   `json:"password" secret:"true"`
+	From       string   `json:"from"`
+	To         []string `json:"to"`
+	Severities []string `json:"severities"`
+	Routes     []string `json:"routes"`
+}
+
+//EventBridgeParams provides the structure for publishing events to an AWS EventBridge bus through the PutEvents API
+//Source and DetailType fields are the entries attributes consumers match on ("anomalies-detector" and "Anomaly Alarm" or "Anomaly Warning" by default)
+//AccessKeyId, SecretAccessKey and SessionToken fields accept "env:VAR" references, the standard AWS environment variables being used if empty
+//Endpoint field optionally replaces the regional endpoint (e.g. VPC endpoints or local emulators)
+type EventBridgeParams struct {
+	Region          string   `json:"region"`
+	EventBusName    string   `json:"eventBusName"`
+	Source          string   `json:"source"`
+	DetailType      string   `json:"detailType"`
+	Resources       []string `json:"resources"`
+	AccessKeyId     string   `json:"accessKeyId"`
+	SecretAccessKey string   `json:"secretAccessKey" secret:"true"`
+	SessionToken    string   `json:"sessionToken" secret:"true"`
+	Endpoint        string   `json:"endpoint"`
+	Severities      []string `json:"severities"`
+	Routes          []string `json:"routes"`
+}
+
+//PubSubParams provides the structure for publishing events to a GCP Pub/Sub topic through its REST API
+//Attributes field maps message attribute names to Go text/templates evaluated on each event, on top of the default siteId, severity, metric and attribute ones
+//AccessToken field is an OAuth2 token accepting "env:VAR" references, the compute metadata server token being requested if empty
+//Endpoint field optionally replaces the Pub/Sub API address (e.g. regional endpoints or the local emulator)
+type PubSubParams struct {
+	Project     string            `json:"project"`
+	Topic       string            `json:"topic"`
+	Attributes  map[string]string `json:"attributes"`
+	AccessToken string            `json:"accessToken" secret:"true"`
+	Endpoint    string            `json:"endpoint"`
+	Severities  []string          `json:"severities"`
+	Routes      []string          `json:"routes"`
+}
+
+//Subscription provides the structure for a subscription to the events of specific sites, metrics and attribute paths, delivered to its own channel
+//SiteId, Metric and Attribute fields are optional filters, Attribute field matching the given path and all its sub-paths (e.g. Category>Shoes)
+//Channel field is either "slack" or "cloudEvents", Target field being a Slack webhook url or channel (posted with the configured Slack bot token) or a CloudEvents sink url
+//Severities field optionally restricts the delivered events while Id field is assigned to the subscriptions created through the API
+type Subscription struct {
+	Id         string   `json:"id,omitempty"`
+	Name       string   `json:"name"`
+	SiteId     string   `json:"siteId"`
+	Metric     string   `json:"metric"`
+	Attribute  string   `json:"attribute"`
+	Severities []string `json:"severities"`
+	Channel    string   `json:"channel"`
+	Target     string   `json:"target"`
+}
+
+//StorageParams provides the structure for the tiered storage of the collected data, kept across runs under the Dir directory
+//Tiers field replaces the default raw (14 days), hourly (90 days) and daily (2 years) tiers, listed from the finest to the coarsest
+//RunRetention field is how long the reports of every run are kept to compare runs with earlier ones ("90d" by default)
+//RunSnapshots field keeps the collected data of every run along with its reports, so past runs are browsed with the charts of their own data
+type StorageParams struct {
+	Dir          string        `json:"dir"`
+	Tiers        []StorageTier `json:"tiers"`
+	RunRetention string        `json:"runRetention"`
+	RunSnapshots bool          `json:"runSnapshots"`
+}
+
+//Const block defines the supported database drivers of the results store
+const (
+	DatabaseSqlite   = "sqlite"
+	DatabasePostgres = "postgres"
+)
+
+//SimulationParams provides the structure for a simulation profile, so generated datasets model the traffic shape of a real site
+//Metrics field replaces the simulated metrics and Attributes field the attributes tree, each attribute splitting the data of its parent according to the weights of its subAttributes
+//OutlierProb field is the probability of a deviation starting on each time step (0 for none), OutlierMaxSize field its maximum number of time steps and
+//OutlierDiffMultiplier field its size in standard deviations, halved on every attribute level
+//Fields not given keep the generator defaults
+type SimulationParams struct {
+	Metrics               []SimulatedMetric    `json:"metrics"`
+	Attributes            []SimulatedAttribute `json:"attributes"`
+	OutlierProb           *float64             `json:"outlierProb"`
+	OutlierMaxSize        int                  `json:"outlierMaxSize"`
+	OutlierDiffMultiplier float64              `json:"outlierDiffMultiplier"`
+}
+
+//SimulatedMetric provides the structure for the distributions of a simulated metric, its values and number of samples per time step following normal distributions
+//Type field is one of "Sum", "Average" or "Count", while Unit, Type and zero fields take those of the default metric with the same name, if any
+type SimulatedMetric struct {
+	Name         string  `json:"name"`
+	Unit         string  `json:"unit"`
+	Type         string  `json:"type"`
+	Mean         float64 `json:"mean"`
+	StdDev       float64 `json:"stdDev"`
+	SampleMean   float64 `json:"sampleMean"`
+	SampleStdDev float64 `json:"sampleStdDev"`
+}
+
+//SimulatedAttribute provides the structure for a node of the simulated attributes tree, Weight field being its share of the data of its parent (unused on top level attributes)
+type SimulatedAttribute struct {
+	Name          string               `json:"name"`
+	Weight        float64              `json:"weight"`
+	SubAttributes []SimulatedAttribute `json:"subAttributes"`
+}
+
+//simulations returns the general simulation profile and those of the datasets, in configuration order
+func (appConfig ApplicationConfig) simulations() []SimulationParams {
+	simulations := []SimulationParams{}
+	if appConfig.Simulation != nil {
+		simulations = append(simulations, *appConfig.Simulation)
+	}
+	for _, dataSet := range appConfig.Datasets {
+		if dataSet.Simulation != nil {
+			simulations = append(simulations, *dataSet.Simulation)
+		}
+	}
+	return simulations
+}
+
+//DatabaseParams provides the structure for the results store, Driver field being "sqlite" or "postgres"
+//Dsn field is the SQLite file name or the PostgreSQL connection string, accepting "env:VAR" references
+type DatabaseParams struct {
+	Driver string `json:"driver"`
+	Dsn    string `json:"dsn" secret:"true"`
+}
+
+//StorageTier provides the structure for a storage tier
+//TimeStep field is the rollup time step of the tier (e.g. "1h"), empty for the raw tier keeping the data as collected, while Retention field is how long its data is kept
+type StorageTier struct {
+	Name      string `json:"name"`
+	TimeStep  string `json:"timeStep"`
+	Retention string `json:"retention"`
+}
+
+//DefaultStorageTiers returns the raw, hourly and daily tiers used unless configured otherwise
+func DefaultStorageTiers() []StorageTier {
+	return []StorageTier{
+		{Name: "raw", Retention: "14d"},
+		{Name: "hourly", TimeStep: "1h", Retention: "90d"},
+		{Name: "daily", TimeStep: "1d", Retention: "730d"},
+	}
+}
+
+//FilesParams provides the structure for the permissions, ownership and location of the data, report, export, archive, storage and state files
+//Umask field (octal, e.g. "027") masks the modes of the created files and directories, FileMode and DirMode fields (octal, e.g. "0640") setting them explicitly instead
+//Owner and Group fields (names or numeric ids) change the ownership of the written files, which requires the matching privileges
+//CreateDirs field creates the missing directories of the written files, while OutputRoot field refuses to write any file outside that directory
+type FilesParams struct {
+	Umask      string `json:"umask"`
+	FileMode   string `json:"fileMode"`
+	DirMode    string `json:"dirMode"`
+	Owner      string `json:"owner"`
+	Group      string `json:"group"`
+	CreateDirs bool   `json:"createDirs"`
+	OutputRoot string `json:"outputRoot"`
+}
+
+//OutboundParams provides the structure for the proxy and TLS settings of every outbound integration, collectors and notifiers alike
+//Proxy field is the URL of the HTTP(S) proxy requests go through, the HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment variables being used if empty,
+//while NoProxy field lists the hosts (and their subdomains) reached directly
+//CaFile field is a PEM bundle of private CAs trusted besides the system ones, ClientCertFile and ClientKeyFile fields being the PEM client certificate and key presented for mTLS
+//They also apply to Kafka brokers and SQL databases, PostgreSQL trusting the CA bundle instead of the system CAs as its driver only reads TLS files
+type OutboundParams struct {
+	Proxy          string   `json:"proxy" secret:"true"`
+	NoProxy        []string `json:"noProxy"`
+	CaFile         string   `json:"caFile"`
+	ClientCertFile string   `json:"clientCertFile"`
+	ClientKeyFile  string   `json:"clientKeyFile"`
+}
+
+//WatchdogParams provides the structure for the self-monitoring watchdog, alerting through the notifiers when the detector itself stops working
+//StateFile field keeps the consecutive failed collections of each site across scheduled runs, MaxFailedRuns field being how many trigger an alert (3 by default)
+//MaxDataAge field is how old the served data may get before an alert (e.g. "2d", disabled if empty), checked every CheckInterval ("5m" by default)
+type WatchdogParams struct {
+	StateFile     string `json:"stateFile"`
+	MaxFailedRuns int    `json:"maxFailedRuns"`
+	MaxDataAge    string `json:"maxDataAge"`
+	CheckInterval string `json:"checkInterval"`
+}
+
+//EventStateParams provides the structure for the event lifecycle tracking, kept across runs on the StateFile field
+//SuppressionWindow field is how long after an event resolves its recurrence is reopened quietly instead of notified again (disabled if empty),
+//while Expiry field is how long resolved events are kept on the state ("30d" by default)
+type EventStateParams struct {
+	StateFile         string `json:"stateFile"`
+	SuppressionWindow string `json:"suppressionWindow"`
+	Expiry            string `json:"expiry"`
+}
+
+//ReportServerParams provides the structure for the report web server parameters
+//MaxConcurrentRenders field is the number of workers rendering charts at the same time (0 for default)
+//RenderQueueSize field limits the chart requests waiting for a worker and RenderMemoryBudgetMB field the memory estimated for all running renders (0 for defaults)
+//RenderCacheMB field limits the rendered charts kept in memory for repeated views (0 for default, negative disabling the cache)
+//GraphQL field enables the optional GraphQL endpoint, whose queries are limited in depth and complexity
+//SubscriptionsFile field enables the subscriptions API, requiring the Auth field, the subscriptions created through it being stored on the given file and delivered from the next run
+//Listen field is the host:port the server binds to (":8080" by default, every interface), "127.0.0.1:8080" keeping it to the local host
+//CertFile and KeyFile fields serve the report over HTTPS with the given PEM certificate chain and private key, both being required for TLS
+//Auth field optionally requires every request to authenticate, with a bearer token or basic authentication
+type ReportServerParams struct {
+	RateLimit            RateLimitParams   `json:"rateLimit"`
+	MaxConcurrentRenders int               `json:"maxConcurrentRenders"`
+	RenderQueueSize      int               `json:"renderQueueSize"`
+	RenderMemoryBudgetMB int               `json:"renderMemoryBudgetMB"`
+	RenderCacheMB        int               `json:"renderCacheMB"`
+	GraphQL              bool              `json:"graphql"`
+	SubscriptionsFile    string            `json:"subscriptionsFile"`
+	Listen               string            `json:"listen"`
+	CertFile             string            `json:"certFile"`
+	KeyFile              string            `json:"keyFile"`
+	Auth                 *ReportAuthParams `json:"auth"`
+}
+
+//ReportAuthParams provides the structure for the report server authentication, requests being accepted with any of the bearer tokens or user credentials
+//Tokens field lists the accepted bearer tokens while Users field maps user names to their basic authentication passwords,
+//both accepting "env:VAR" references and passwords also being given as their SHA-256 hex digest with the "sha256:" prefix
+type ReportAuthParams struct {
+	Tokens []string          `json:"tokens" secret:"true"`
+	Users  map[string]string `json:"users" secret:"true"`
+}
+
+//RateLimitParams provides the structure for the per client rate limiting parameters
+//Clients are identified by their IP address, whatever credentials they send
+//RequestsPerMinute field defines the sustained rate while Burst field defines how many requests can be made at once (0 for defaults)
+type RateLimitParams struct {
+	RequestsPerMinute float64 `json:"requestsPerMinute"`
+	Burst             int     `json:"burst"`
+}
+
 //ReadConfFile simply reads the configuration file
-//It parses its contents in Json format and returns an ApplicationConfig structure
+//It parses and validates its contents in Json format and returns an ApplicationConfig structure, exiting the application if any error is detected
 func ReadConfFile(confFile string) ApplicationConfig {
-
-	//Opening and reading the configuration file, exiting the application if an error is detected
-	byteValue, err := os.ReadFile(confFile)
+	appConf, err := LoadConfFile(confFile)
 	if err != nil {
 		log.Fatalln(err.Error())
 	}
-
-	//Parsing the file content in Json format and returning the respective ApplicationConfig structure
-	var appConf ApplicationConfig
-	json.Unmarshal(byteValue, &appConf)
-
 	return appConf
 }
@@ -0,0 +1,58 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+//DiffConfigs lists the changes between two configurations, one line per changed setting, so reloads can log what they apply
+//Datasets are matched by site id, being listed as added, removed or with their changed settings, e.g. datasets[shop].timeStep
+func DiffConfigs(previous, next ApplicationConfig) []string {
+	changes := []string{}
+	for _, field := range changedFields(previous, next) {
+		if field != "datasets" {
+			changes = append(changes, fmt.Sprintf("%s changed", field))
+		}
+	}
+
+	previousSets := map[string]Dataset{}
+	for _, dataSet := range previous.Datasets {
+		previousSets[dataSet.SiteId] = dataSet
+	}
+	nextSets := map[string]bool{}
+	for _, dataSet := range next.Datasets {
+		nextSets[dataSet.SiteId] = true
+		previousSet, found := previousSets[dataSet.SiteId]
+		if !found {
+			changes = append(changes, fmt.Sprintf("datasets[%s] added", dataSet.SiteId))
+			continue
+		}
+		for _, field := range changedFields(previousSet, dataSet) {
+			changes = append(changes, fmt.Sprintf("datasets[%s].%s changed", dataSet.SiteId, field))
+		}
+	}
+	for _, dataSet := range previous.Datasets {
+		if !nextSets[dataSet.SiteId] {
+			changes = append(changes, fmt.Sprintf("datasets[%s] removed", dataSet.SiteId))
+		}
+	}
+	return changes
+}
+
+//changedFields returns the Json names of the fields differing between two values of the same structure, in declaration order
+func changedFields(previous, next interface{}) []string {
+	fields := []string{}
+	previousValue, nextValue := reflect.ValueOf(previous), reflect.ValueOf(next)
+	for i := 0; i < previousValue.NumField(); i++ {
+		if reflect.DeepEqual(previousValue.Field(i).Interface(), nextValue.Field(i).Interface()) {
+			continue
+		}
+		name := strings.Split(previousValue.Type().Field(i).Tag.Get("json"), ",")[0]
+		if name == "" {
+			name = previousValue.Type().Field(i).Name
+		}
+		fields = append(fields, name)
+	}
+	return fields
+}
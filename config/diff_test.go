@@ -0,0 +1,56 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDiffConfigs(t *testing.T) {
+	previous := ApplicationConfig{
+		Datasets: []Dataset{
+			{SiteId: "shop", TimeAgo: "30d", TimeStep: "1d", MetricesList: []string{"Revenue"}},
+			{SiteId: "blog", TimeAgo: "30d", TimeStep: "1d"},
+		},
+		DetectionMethods: DetectionMethodsParams{ThreeSigmas: ThreeSigmasParams{OutliersMultiplier: 2, StrongOutliersMultiplier: 3}},
+	}
+
+	tests := []struct {
+		name   string
+		update func(next *ApplicationConfig)
+		want   []string
+	}{
+		{
+			name:   "Unchanged",
+			update: func(next *ApplicationConfig) {},
+			want:   []string{},
+		},
+		{
+			name: "Detection parameters and filters",
+			update: func(next *ApplicationConfig) {
+				next.DetectionMethods.ThreeSigmas.OutliersMultiplier = 2.5
+				next.GenCollectFilters.MinVisitorsPerTimeStep = 30
+			},
+			want: []string{"detectionMethods changed", "genCollectFilters changed"},
+		},
+		{
+			name: "Datasets added, removed and changed",
+			update: func(next *ApplicationConfig) {
+				next.Datasets = []Dataset{
+					{SiteId: "shop", TimeAgo: "60d", TimeStep: "1d", MetricesList: []string{"Revenue", "Visits"}},
+					{SiteId: "store", TimeAgo: "30d", TimeStep: "1h"},
+				}
+			},
+			want: []string{"datasets[shop].timeAgo changed", "datasets[shop].metricesList changed", "datasets[store] added", "datasets[blog] removed"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			next := previous
+			next.Datasets = append([]Dataset{}, previous.Datasets...)
+			tt.update(&next)
+			if got := DiffConfigs(previous, next); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("DiffConfigs() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,509 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ftfmtavares/anomalies-detector/utils"
+)
+
+//DetectionMethods lists the names of the supported detection methods
+var DetectionMethods = []string{"3-sigmas", "rolling-3-sigmas", "stl", "ewma", "cusum", "esd", "holt-winters", "seasonal-buckets", "period-over-period"}
+
+//ValidationError provides the structure for a single configuration problem
+//Path field locates the faulty setting (e.g. datasets[1].timeStep) and Line field its line on the configuration file, 0 if unknown
+type ValidationError struct {
+	Path    string
+	Line    int
+	Message string
+}
+
+//Error returns the problem prefixed with its line and path
+func (validationError ValidationError) Error() string {
+	message := validationError.Message
+	if validationError.Path != "" {
+		message = fmt.Sprintf("%s - %s", validationError.Path, message)
+	}
+	if validationError.Line > 0 {
+		message = fmt.Sprintf("line %d - %s", validationError.Line, message)
+	}
+	return message
+}
+
+//ValidationErrors holds all the problems found on a configuration, in configuration order
+type ValidationErrors []ValidationError
+
+//Error joins all the problems, one per line
+func (validationErrors ValidationErrors) Error() string {
+	messages := []string{}
+	for _, validationError := range validationErrors {
+		messages = append(messages, validationError.Error())
+	}
+	return strings.Join(messages, "\n")
+}
+
+//LoadConfFile reads and validates the configuration file, rejecting unknown keys so that typos aren't silently ignored
+//It returns the parsing error or all the validation problems found, referencing their lines on the file
+func LoadConfFile(confFile string) (ApplicationConfig, error) {
+	var appConf ApplicationConfig
+	byteValue, err := os.ReadFile(confFile)
+	if err != nil {
+		return appConf, err
+	}
+
+	//Parsing the file content in Json format, locating the syntax, type and unknown key errors on the file
+	lines := jsonPathLines(byteValue)
+	decoder := json.NewDecoder(bytes.NewReader(byteValue))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&appConf); err != nil {
+		var syntaxError *json.SyntaxError
+		var typeError *json.UnmarshalTypeError
+		if errors.As(err, &syntaxError) {
+			return appConf, ValidationErrors{{Line: lineAt(byteValue, syntaxError.Offset), Message: err.Error()}}
+		}
+		if errors.As(err, &typeError) {
+			return appConf, ValidationErrors{{Path: typeError.Field, Line: lineAt(byteValue, typeError.Offset), Message: fmt.Sprintf("invalid value, expecting %s", typeError.Type.String())}}
+		}
+		if key := strings.TrimPrefix(err.Error(), "json: unknown field "); key != err.Error() {
+			key = strings.Trim(key, "\"")
+			validationError := ValidationError{Path: key, Message: "unknown key"}
+			for path, line := range lines {
+				if (path == key || strings.HasSuffix(path, "."+key)) && (validationError.Line == 0 || line < validationError.Line) {
+					validationError.Path, validationError.Line = path, line
+				}
+			}
+			return appConf, ValidationErrors{validationError}
+		}
+		return appConf, err
+	}
+
+	if err := appConf.Validate(); err != nil {
+		validationErrors, ok := err.(ValidationErrors)
+		if !ok {
+			return appConf, err
+		}
+		for i := range validationErrors {
+			validationErrors[i].Line = lines[validationErrors[i].Path]
+		}
+		return appConf, validationErrors
+	}
+	return appConf, nil
+}
+
+//Validate checks the required fields, durations, detection methods and metric names of the configuration
+//It returns a ValidationErrors with all the problems found, nil if none
+func (appConfig ApplicationConfig) Validate() error {
+	validationErrors := ValidationErrors{}
+	addError := func(path, format string, args ...interface{}) {
+		validationErrors = append(validationErrors, ValidationError{Path: path, Message: fmt.Sprintf(format, args...)})
+	}
+	checkDuration := func(path, value string, required bool) {
+		if value == "" {
+			if required {
+				addError(path, "is required")
+			}
+			return
+		}
+		if step, err := utils.ParseTimeStep(value); err != nil || step.Duration <= 0 {
+			addError(path, "invalid duration \"%s\"", value)
+		}
+	}
+	checkMethod := func(path, method string) {
+		for _, supported := range DetectionMethods {
+			if method == supported {
+				return
+			}
+		}
+		addError(path, "unknown detection method \"%s\", it must be one of %s", method, strings.Join(DetectionMethods, ", "))
+	}
+
+	checkSimulation := func(path string, simulation *SimulationParams) {
+		if simulation == nil {
+			return
+		}
+		defaults := map[string]bool{}
+		for _, definition := range DefaultMetrics() {
+			defaults[definition.Name] = true
+		}
+		for j, metric := range simulation.Metrics {
+			metricPath := fmt.Sprintf("%s.metrics[%d]", path, j)
+			if metric.Name == "" {
+				addError(metricPath+".name", "is required")
+			}
+			if metric.Type != "" && metric.Type != MetricTypeSum && metric.Type != MetricTypeAverage && metric.Type != MetricTypeCount {
+				addError(metricPath+".type", "invalid type \"%s\", it must be \"Sum\", \"Average\" or \"Count\"", metric.Type)
+			}
+			if metric.StdDev < 0 || metric.SampleStdDev < 0 {
+				addError(metricPath, "standard deviations can't be negative")
+			}
+
+			//Metrics other than the default ones have no distribution to fall back to, Count metrics taking their samples from their values
+			if defaults[metric.Name] {
+				continue
+			}
+			if metric.Type == "" {
+				addError(metricPath+".type", "is required")
+			}
+			if metric.Mean <= 0 {
+				addError(metricPath+".mean", "must be positive")
+			}
+			if metric.SampleMean <= 0 && metric.Type != MetricTypeCount {
+				addError(metricPath+".sampleMean", "must be positive")
+			}
+		}
+		var checkAttributes func(path string, attributes []SimulatedAttribute, weighted bool)
+		checkAttributes = func(path string, attributes []SimulatedAttribute, weighted bool) {
+			for j, attribute := range attributes {
+				attributePath := fmt.Sprintf("%s[%d]", path, j)
+				if attribute.Name == "" || strings.Contains(attribute.Name, ">") {
+					addError(attributePath+".name", "is required and can't hold \">\"")
+				}
+				if weighted && attribute.Weight <= 0 {
+					addError(attributePath+".weight", "must be positive")
+				}
+				checkAttributes(attributePath+".subAttributes", attribute.SubAttributes, true)
+			}
+		}
+		checkAttributes(path+".attributes", simulation.Attributes, false)
+		if simulation.OutlierProb != nil && (*simulation.OutlierProb < 0 || *simulation.OutlierProb > 1) {
+			addError(path+".outlierProb", "must be between 0 and 1")
+		}
+		if simulation.OutlierMaxSize < 0 {
+			addError(path+".outlierMaxSize", "can't be negative")
+		}
+		if simulation.OutlierDiffMultiplier < 0 {
+			addError(path+".outlierDiffMultiplier", "can't be negative")
+		}
+	}
+
+	checkKnownEvents := func(path string, events []KnownEvent, location *time.Location) {
+		for j, event := range events {
+			eventPath := fmt.Sprintf("%s[%d]", path, j)
+			if event.Name == "" {
+				addError(eventPath+".name", "is required")
+			}
+			if _, _, err := event.Period(location); err != nil {
+				addError(eventPath, "%s", err.Error())
+			}
+			if event.Recurrence != "" && event.Recurrence != RecurrenceWeekly && event.Recurrence != RecurrenceMonthly && event.Recurrence != RecurrenceYearly {
+				addError(eventPath+".recurrence", "invalid recurrence \"%s\", it must be \"%s\", \"%s\" or \"%s\"", event.Recurrence, RecurrenceWeekly, RecurrenceMonthly, RecurrenceYearly)
+			}
+			if event.Action != "" && event.Action != KnownEventExclude && event.Action != KnownEventTag {
+				addError(eventPath+".action", "invalid action \"%s\", it must be \"%s\" or \"%s\"", event.Action, KnownEventExclude, KnownEventTag)
+			}
+		}
+	}
+
+	if len(appConfig.Datasets) == 0 {
+		addError("datasets", "at least one dataset is required")
+	}
+	checkSimulation("simulation", appConfig.Simulation)
+	checkKnownEvents("knownEvents", appConfig.KnownEvents, time.Local)
+	registry, err := appConfig.MetricRegistry()
+	if err != nil {
+		addError("metrics", "%s", err.Error())
+	}
+
+	for i, dataSet := range appConfig.Datasets {
+		path := fmt.Sprintf("datasets[%d]", i)
+		if dataSet.SiteId == "" {
+			addError(path+".siteId", "is required")
+		}
+		checkDuration(path+".timeAgo", dataSet.TimeAgo, true)
+		if len(dataSet.TimeSteps) > 0 {
+			for j, timeStep := range dataSet.TimeSteps {
+				checkDuration(fmt.Sprintf("%s.timeSteps[%d]", path, j), timeStep, true)
+			}
+		} else {
+			checkDuration(path+".timeStep", dataSet.TimeStep, true)
+		}
+		checkDuration(path+".burnIn", dataSet.BurnIn, false)
+		checkSimulation(path+".simulation", dataSet.Simulation)
+		checkDuration(path+".cooldown", dataSet.Cooldown, false)
+		if dataSet.Gaps != nil {
+			if strategy := dataSet.Gaps.Strategy; strategy != GapZeroFill && strategy != GapInterpolate && strategy != GapMarkMissing {
+				addError(path+".gaps.strategy", "invalid strategy \"%s\", it must be \"%s\", \"%s\" or \"%s\"", strategy, GapZeroFill, GapInterpolate, GapMarkMissing)
+			}
+			if dataSet.Gaps.MinSteps < 0 {
+				addError(path+".gaps.minSteps", "can't be negative")
+			}
+		}
+		if dataSet.Kafka != nil {
+			checkDuration(path+".kafka.analysisInterval", dataSet.Kafka.AnalysisInterval, false)
+		}
+		for j, objective := range dataSet.Objectives {
+			checkDuration(fmt.Sprintf("%s.objectives[%d].window", path, j), objective.Window, false)
+		}
+
+		location, err := dataSet.Location()
+		if err != nil {
+			addError(path+".timezone", "%s", err.Error())
+			location = time.Local
+		}
+		checkKnownEvents(path+".knownEvents", dataSet.KnownEvents, location)
+
+		//Detection methods are either listed or taken from the detection profile
+		if dataSet.Locale != nil {
+			if _, err := dataSet.Locale.Resolve(); err != nil {
+				addError(path+".locale", "%s", err.Error())
+			}
+		}
+		if dataSet.DetectionProfile != "" {
+			if _, found := DetectionProfiles()[dataSet.DetectionProfile]; !found {
+				addError(path+".detectionProfile", "unknown detection profile \"%s\"", dataSet.DetectionProfile)
+			}
+		}
+		if len(dataSet.OutliersDetectionMethods) > 0 {
+			for j, method := range dataSet.OutliersDetectionMethods {
+				checkMethod(fmt.Sprintf("%s.outliersDetectionMethods[%d]", path, j), method)
+			}
+		} else if dataSet.OutliersDetectionMethod != "" {
+			checkMethod(path+".outliersDetectionMethod", dataSet.OutliersDetectionMethod)
+		} else if dataSet.DetectionProfile == "" {
+			addError(path+".outliersDetectionMethod", "is required unless a detection profile is selected")
+		}
+		if dataSet.ShadowDetectionMethod != "" {
+			checkMethod(path+".shadowDetectionMethod", dataSet.ShadowDetectionMethod)
+		}
+		detectionMetrics := []string{}
+		for metric := range dataSet.MetricDetection {
+			detectionMetrics = append(detectionMetrics, metric)
+		}
+		sort.Strings(detectionMetrics)
+		for _, metric := range detectionMetrics {
+			detection, metricPath := dataSet.MetricDetection[metric], joinJsonPath(path+".metricDetection", metric)
+			for j, method := range detection.OutliersDetectionMethods {
+				checkMethod(fmt.Sprintf("%s.outliersDetectionMethods[%d]", metricPath, j), method)
+			}
+			if len(detection.OutliersDetectionMethods) == 0 && detection.OutliersDetectionMethod != "" {
+				checkMethod(metricPath+".outliersDetectionMethod", detection.OutliersDetectionMethod)
+			}
+			if detection.Consensus < 0 {
+				addError(metricPath+".consensus", "can't be negative")
+			}
+		}
+
+		//Metric names are only known upfront for generated data and the backends mapping each metric
+		if len(dataSet.MetricesList) == 0 {
+			addError(path+".metricesList", "at least one metric, or \"all\", is required")
+			continue
+		}
+		if strings.ToLower(dataSet.MetricesList[0]) == "all" {
+			continue
+		}
+		if dataSet.Simulation == nil {
+			dataSet.Simulation = appConfig.Simulation
+		}
+		known := datasetMetrics(dataSet, registry)
+		if known == nil {
+			continue
+		}
+		for j, metric := range dataSet.MetricesList {
+			if !known[metric] {
+				addError(fmt.Sprintf("%s.metricesList[%d]", path, j), "unknown metric \"%s\"", metric)
+			}
+		}
+		for _, metric := range detectionMetrics {
+			if !known[metric] {
+				addError(joinJsonPath(path+".metricDetection", metric), "unknown metric \"%s\"", metric)
+			}
+		}
+	}
+
+	if appConfig.ReportServer.Listen != "" {
+		if _, port, err := net.SplitHostPort(appConfig.ReportServer.Listen); err != nil || port == "" {
+			addError("reportServer.listen", "invalid address \"%s\", host:port expected", appConfig.ReportServer.Listen)
+		}
+	}
+	if (appConfig.ReportServer.CertFile == "") != (appConfig.ReportServer.KeyFile == "") {
+		addError("reportServer.certFile", "certFile and keyFile are both required for TLS")
+	}
+	if appConfig.ReportServer.SubscriptionsFile != "" && appConfig.ReportServer.Auth == nil {
+		addError("reportServer.subscriptionsFile", "the subscriptions API requires the report server auth")
+	}
+	if auth := appConfig.ReportServer.Auth; auth != nil {
+		if len(auth.Tokens) == 0 && len(auth.Users) == 0 {
+			addError("reportServer.auth", "at least one token or user is required")
+		}
+		for i, token := range auth.Tokens {
+			if token == "" {
+				addError(fmt.Sprintf("reportServer.auth.tokens[%d]", i), "is empty")
+			}
+		}
+		users := []string{}
+		for user := range auth.Users {
+			users = append(users, user)
+		}
+		sort.Strings(users)
+		for _, user := range users {
+			if user == "" || strings.Contains(user, ":") {
+				addError("reportServer.auth.users", "invalid user name \"%s\", it must be non empty and without \":\"", user)
+			} else if auth.Users[user] == "" {
+				addError(joinJsonPath("reportServer.auth.users", user), "password is required")
+			}
+		}
+	}
+
+	if appConfig.Outbound != nil {
+		if appConfig.Outbound.Proxy != "" {
+			if proxyUrl, err := url.Parse(appConfig.Outbound.Proxy); err != nil || proxyUrl.Host == "" {
+				addError("outbound.proxy", "invalid proxy URL \"%s\"", appConfig.Outbound.Proxy)
+			}
+		}
+		if (appConfig.Outbound.ClientCertFile == "") != (appConfig.Outbound.ClientKeyFile == "") {
+			addError("outbound.clientCertFile", "clientCertFile and clientKeyFile are both required for mTLS")
+		}
+	}
+
+	if appConfig.EventState != nil {
+		if appConfig.EventState.StateFile == "" {
+			addError("eventState.stateFile", "is required")
+		}
+		checkDuration("eventState.suppressionWindow", appConfig.EventState.SuppressionWindow, false)
+		checkDuration("eventState.expiry", appConfig.EventState.Expiry, false)
+	}
+
+	if appConfig.Files != nil {
+		modes := []struct{ key, mode string }{{"umask", appConfig.Files.Umask}, {"fileMode", appConfig.Files.FileMode}, {"dirMode", appConfig.Files.DirMode}}
+		for _, mode := range modes {
+			if _, err := utils.ParseFileMode(mode.mode); mode.mode != "" && err != nil {
+				addError("files."+mode.key, "%s", err.Error())
+			}
+		}
+	}
+
+	if appConfig.Database != nil {
+		if appConfig.Database.Driver != DatabaseSqlite && appConfig.Database.Driver != DatabasePostgres {
+			addError("database.driver", "invalid driver \"%s\", %s or %s expected", appConfig.Database.Driver, DatabaseSqlite, DatabasePostgres)
+		}
+		if appConfig.Database.Dsn == "" {
+			addError("database.dsn", "is required")
+		}
+		for i, dataSet := range appConfig.Datasets {
+			if dataSet.EncryptionKey != "" {
+				addError(fmt.Sprintf("datasets[%d].encryptionKey", i), "can't be used along with the database, whose rows are stored in plain text")
+			}
+		}
+	}
+
+	if len(validationErrors) == 0 {
+		return nil
+	}
+	return validationErrors
+}
+
+//datasetMetrics returns the metric names collectable by a dataset, the simulated ones for generated datasets with a simulation profile, nil when they can only be known while collecting (CSV files and Kafka topics)
+//Derived metrics are always known, their operands being checked when collected
+func datasetMetrics(dataSet Dataset, registry map[string]MetricDefinition) map[string]bool {
+	known := map[string]bool{}
+	switch {
+	case dataSet.Csv != nil || dataSet.Kafka != nil:
+		return nil
+	case dataSet.Prometheus != nil:
+		for metric := range dataSet.Prometheus.Queries {
+			known[metric] = true
+		}
+	case dataSet.Sql != nil:
+		for metric := range dataSet.Sql.Queries {
+			known[metric] = true
+		}
+	case dataSet.Http != nil:
+		if len(dataSet.Http.Metrics) == 0 {
+			return nil
+		}
+		for _, metric := range dataSet.Http.Metrics {
+			known[metric] = true
+		}
+	case dataSet.Ga4 != nil:
+		for metric := range dataSet.Ga4.Metrics {
+			known[metric] = true
+		}
+	case dataSet.Simulation != nil && len(dataSet.Simulation.Metrics) > 0:
+		for _, metric := range dataSet.Simulation.Metrics {
+			known[metric.Name] = true
+		}
+	default:
+		for metric := range registry {
+			known[metric] = true
+		}
+	}
+	for metric, definition := range registry {
+		if definition.Expression != "" {
+			known[metric] = true
+		}
+	}
+	return known
+}
+
+//jsonPathFrame is an object or array being walked by jsonPathLines
+type jsonPathFrame struct {
+	path      string
+	object    bool
+	expectKey bool
+	key       string
+	index     int
+}
+
+//jsonPathLines maps the path of every key and array element of a JSON document (e.g. datasets[1].timeStep) to its line
+//Invalid documents are mapped up to their first error
+func jsonPathLines(data []byte) map[string]int {
+	lines := map[string]int{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	stack := []*jsonPathFrame{}
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return lines
+		}
+		if delim, ok := token.(json.Delim); ok && (delim == '}' || delim == ']') {
+			stack = stack[:len(stack)-1]
+			continue
+		}
+
+		//Keys are located where they are read, values taking the path of their key or array index
+		path := ""
+		if len(stack) > 0 {
+			top := stack[len(stack)-1]
+			if top.object && top.expectKey {
+				top.key, top.expectKey = token.(string), false
+				lines[joinJsonPath(top.path, top.key)] = lineAt(data, decoder.InputOffset())
+				continue
+			}
+			if top.object {
+				path = joinJsonPath(top.path, top.key)
+				top.expectKey = true
+			} else {
+				path = fmt.Sprintf("%s[%d]", top.path, top.index)
+				top.index++
+				lines[path] = lineAt(data, decoder.InputOffset())
+			}
+		}
+		if delim, ok := token.(json.Delim); ok {
+			stack = append(stack, &jsonPathFrame{path: path, object: delim == '{', expectKey: delim == '{'})
+		}
+	}
+}
+
+//joinJsonPath appends a key to a JSON path
+func joinJsonPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+//lineAt returns the line of the given offset of a document, starting at 1
+func lineAt(data []byte, offset int64) int {
+	if offset > int64(len(data)) {
+		offset = int64(len(data))
+	}
+	return bytes.Count(data[:offset], []byte("\n")) + 1
+}
@@ -0,0 +1,307 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadConfFile(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		wantErrs []string
+	}{
+		{
+			name: "Valid configuration",
+			content: `{
+    "datasets": [
+        {"siteId": "shop", "timeAgo": "30d", "timeStep": "1d", "outliersDetectionMethod": "3-sigmas", "metricesList": ["Revenue"]}
+    ]
+}`,
+		},
+		{
+			name: "Unknown key referenced by line",
+			content: `{
+    "datasets": [
+        {"siteId": "shop", "timeAgo": "30d", "timeStep": "1d",
+         "outlierDetectionMethod": "3-sigmas", "metricesList": ["Revenue"]}
+    ]
+}`,
+			wantErrs: []string{`line 4 - datasets[0].outlierDetectionMethod - unknown key`},
+		},
+		{
+			name:     "Syntax error referenced by line",
+			content:  "{\n    \"datasets\": [\n        {\"siteId\": \"shop\",}\n    ]\n}",
+			wantErrs: []string{"line 3 - invalid character"},
+		},
+		{
+			name:     "Invalid value type",
+			content:  "{\n    \"concurrency\": \"4\"\n}",
+			wantErrs: []string{"line 2 - concurrency - invalid value, expecting int"},
+		},
+		{
+			name: "Aggregated validation errors",
+			content: `{
+    "datasets": [
+        {"siteId": "shop", "timeAgo": "30d", "timeStep": "1x", "outliersDetectionMethod": "3-sigmas", "metricesList": ["Revenue"]},
+        {"timeAgo": "30d", "timeSteps": ["1h", "soon"],
+         "outliersDetectionMethods": ["3-sigmas", "magic"],
+         "metricesList": ["Revenue", "Refunds"]}
+    ]
+}`,
+			wantErrs: []string{
+				`line 3 - datasets[0].timeStep - invalid duration "1x"`,
+				`datasets[1].siteId - is required`,
+				`line 4 - datasets[1].timeSteps[1] - invalid duration "soon"`,
+				`line 5 - datasets[1].outliersDetectionMethods[1] - unknown detection method "magic"`,
+				`line 6 - datasets[1].metricesList[1] - unknown metric "Refunds"`,
+			},
+		},
+		{
+			name: "Invalid outbound settings",
+			content: `{
+    "datasets": [
+        {"siteId": "shop", "timeAgo": "30d", "timeStep": "1d", "outliersDetectionMethod": "3-sigmas", "metricesList": ["Revenue"]}
+    ],
+    "outbound": {
+        "proxy": "proxy.internal:3128",
+        "clientCertFile": "client.pem"
+    }
+}`,
+			wantErrs: []string{
+				`line 6 - outbound.proxy - invalid proxy URL "proxy.internal:3128"`,
+				`line 7 - outbound.clientCertFile - clientCertFile and clientKeyFile are both required for mTLS`,
+			},
+		},
+		{
+			name: "Invalid locale",
+			content: `{
+    "datasets": [
+        {"siteId": "shop", "timeAgo": "30d", "timeStep": "1d", "outliersDetectionMethod": "3-sigmas", "metricesList": ["Revenue"], "locale": {"name": "en-US", "firstDayOfWeek": "funday"}}
+    ]
+}`,
+			wantErrs: []string{
+				`line 3 - datasets[0].locale - unknown firstDayOfWeek "funday"`,
+			},
+		},
+		{
+			name: "Invalid event state settings",
+			content: `{
+    "datasets": [
+        {"siteId": "shop", "timeAgo": "30d", "timeStep": "1d", "outliersDetectionMethod": "3-sigmas", "metricesList": ["Revenue"]}
+    ],
+    "eventState": {
+        "suppressionWindow": "2 days"
+    }
+}`,
+			wantErrs: []string{
+				`eventState.stateFile - is required`,
+				`line 6 - eventState.suppressionWindow - invalid duration "2 days"`,
+			},
+		},
+		{
+			name: "Invalid database settings",
+			content: `{
+    "datasets": [
+        {"siteId": "shop", "timeAgo": "30d", "timeStep": "1d", "outliersDetectionMethod": "3-sigmas", "metricesList": ["Revenue"]}
+    ],
+    "database": {
+        "driver": "mysql"
+    }
+}`,
+			wantErrs: []string{
+				`line 6 - database.driver - invalid driver "mysql", sqlite or postgres expected`,
+				`database.dsn - is required`,
+			},
+		},
+		{
+			name: "Encrypted dataset with the database",
+			content: `{
+    "datasets": [
+        {"siteId": "shop", "timeAgo": "30d", "timeStep": "1d", "outliersDetectionMethod": "3-sigmas", "metricesList": ["Revenue"], "encryptionKey": "shop.key"}
+    ],
+    "database": {
+        "driver": "sqlite",
+        "dsn": "results.db"
+    }
+}`,
+			wantErrs: []string{
+				`line 3 - datasets[0].encryptionKey - can't be used along with the database, whose rows are stored in plain text`,
+			},
+		},
+		{
+			name: "Invalid file modes",
+			content: `{
+    "datasets": [
+        {"siteId": "shop", "timeAgo": "30d", "timeStep": "1d", "outliersDetectionMethod": "3-sigmas", "metricesList": ["Revenue"]}
+    ],
+    "files": {
+        "umask": "027",
+        "fileMode": "0640",
+        "dirMode": "0989"
+    }
+}`,
+			wantErrs: []string{
+				`line 8 - files.dirMode - invalid mode "0989", an octal value up to 0777 expected`,
+			},
+		},
+		{
+			name: "Invalid metric detection and gap filling",
+			content: `{
+    "datasets": [
+        {"siteId": "shop", "timeAgo": "30d", "timeStep": "1d", "outliersDetectionMethod": "3-sigmas", "metricesList": ["Revenue", "Visits"],
+         "metricDetection": {
+            "Visits": {"outliersDetectionMethods": ["esd", "median"], "consensus": -1},
+            "Revenue": {"outliersDetectionMethod": "ewma", "detectionMethods": {"ewma": {"alpha": 0.3, "outliersMultiplier": 3, "strongOutliersMultiplier": 4}}},
+            "Orders": {"outliersDetectionMethod": "stl"}
+         },
+         "gaps": {"strategy": "hold", "minSteps": -1}}
+    ]
+}`,
+			wantErrs: []string{
+				`line 9 - datasets[0].gaps.strategy - invalid strategy "hold", it must be "zero-fill", "interpolate" or "mark-missing"`,
+				`line 9 - datasets[0].gaps.minSteps - can't be negative`,
+				`line 5 - datasets[0].metricDetection.Visits.outliersDetectionMethods[1] - unknown detection method "median"`,
+				`line 5 - datasets[0].metricDetection.Visits.consensus - can't be negative`,
+				`line 7 - datasets[0].metricDetection.Orders - unknown metric "Orders"`,
+			},
+		},
+		{
+			name: "Unknown timezone and calendar time steps",
+			content: `{
+    "datasets": [
+        {"siteId": "shop", "timeAgo": "30d", "timeStep": "1d", "outliersDetectionMethod": "3-sigmas", "metricesList": ["Revenue"], "timezone": "Europe/Porto"},
+        {"siteId": "blog", "timeAgo": "12mo", "timeSteps": ["1w-calendar", "1mo", "6h-calendar"], "outliersDetectionMethod": "3-sigmas", "metricesList": ["Revenue"]},
+        {"siteId": "news", "timeAgo": "1mo2d", "timeStep": "1d-calendar", "outliersDetectionMethod": "3-sigmas", "metricesList": ["Revenue"]}
+    ]
+}`,
+			wantErrs: []string{
+				`line 3 - datasets[0].timezone - unknown timezone "Europe/Porto"`,
+				`line 4 - datasets[1].timeSteps[2] - invalid duration "6h-calendar"`,
+				`line 5 - datasets[2].timeAgo - invalid duration "1mo2d"`,
+			},
+		},
+		{
+			name: "Invalid known events",
+			content: `{
+    "knownEvents": [{"name": "Black Friday", "start": "2022-11-25", "end": "2022-11-28", "recurrence": "yearly", "action": "exclude"}],
+    "datasets": [
+        {"siteId": "shop", "timeAgo": "30d", "timeStep": "1d", "outliersDetectionMethod": "3-sigmas", "metricesList": ["Revenue"],
+         "knownEvents": [
+            {"name": "Launch", "start": "2022-11-10", "end": "2022-11-01", "recurrence": "daily", "action": "ignore"},
+            {"start": "tomorrow", "end": "2022-11-01"}
+         ]}
+    ]
+}`,
+			wantErrs: []string{
+				`line 6 - datasets[0].knownEvents[0] - end "2022-11-01" must be after start "2022-11-10"`,
+				`line 6 - datasets[0].knownEvents[0].recurrence - invalid recurrence "daily", it must be "weekly", "monthly" or "yearly"`,
+				`line 6 - datasets[0].knownEvents[0].action - invalid action "ignore", it must be "exclude" or "tag"`,
+				`datasets[0].knownEvents[1].name - is required`,
+				`line 7 - datasets[0].knownEvents[1] - invalid time "tomorrow", it must be a date or an RFC 3339 time`,
+			},
+		},
+		{
+			name: "Invalid report server address, TLS files and authentication",
+			content: `{
+    "datasets": [
+        {"siteId": "shop", "timeAgo": "30d", "timeStep": "1d", "outliersDetectionMethod": "3-sigmas", "metricesList": ["Revenue"]}
+    ],
+    "reportServer": {"listen": "8080", "certFile": "cert.pem",
+                     "auth": {"tokens": [""], "users": {"analyst": "", "ops:admin": "secret"}}}
+}`,
+			wantErrs: []string{
+				`line 5 - reportServer.listen - invalid address "8080", host:port expected`,
+				`line 5 - reportServer.certFile - certFile and keyFile are both required for TLS`,
+				`line 6 - reportServer.auth.tokens[0] - is empty`,
+				`line 6 - reportServer.auth.users.analyst - password is required`,
+				`line 6 - reportServer.auth.users - invalid user name "ops:admin", it must be non empty and without ":"`,
+			},
+		},
+		{
+			name: "Subscriptions API without authentication",
+			content: `{
+    "datasets": [
+        {"siteId": "shop", "timeAgo": "30d", "timeStep": "1d", "outliersDetectionMethod": "3-sigmas", "metricesList": ["Revenue"]}
+    ],
+    "reportServer": {"subscriptionsFile": "subscriptions.json"}
+}`,
+			wantErrs: []string{
+				`line 5 - reportServer.subscriptionsFile - the subscriptions API requires the report server auth`,
+			},
+		},
+		{
+			name: "Derived metrics",
+			content: `{
+    "metrics": [{"name": "ConversionRate", "type": "Average", "expression": "Orders / Visits"}],
+    "datasets": [
+        {"siteId": "shop", "timeAgo": "30d", "timeStep": "1d", "outliersDetectionMethod": "3-sigmas", "metricesList": ["Visits", "ConversionRate", "Bounces"]}
+    ]
+}`,
+			wantErrs: []string{
+				`line 4 - datasets[0].metricesList[2] - unknown metric "Bounces"`,
+			},
+		},
+		{
+			name: "Simulation profile",
+			content: `{
+    "datasets": [
+        {"siteId": "shop", "timeAgo": "30d", "timeStep": "1d", "outliersDetectionMethod": "3-sigmas", "metricesList": ["Signups", "Revenue"]},
+        {"siteId": "blog", "timeAgo": "30d", "timeStep": "1d", "outliersDetectionMethod": "3-sigmas", "metricesList": ["Revenue"],
+         "simulation": {"metrics": [{"name": "Revenue", "mean": 500}]}}
+    ],
+    "simulation": {
+        "metrics": [
+            {"name": "Signups", "type": "Count", "mean": 300},
+            {"name": "Latency", "type": "Median", "mean": 120, "stdDev": -1}
+        ],
+        "attributes": [
+            {"name": "Country", "subAttributes": [{"name": "PT", "weight": 0}]}
+        ],
+        "outlierProb": 2
+    }
+}`,
+			wantErrs: []string{
+				`line 10 - simulation.metrics[1].type - invalid type "Median"`,
+				`line 10 - simulation.metrics[1] - standard deviations can't be negative`,
+				`simulation.metrics[1].sampleMean - must be positive`,
+				`line 13 - simulation.attributes[0].subAttributes[0].weight - must be positive`,
+				`line 15 - simulation.outlierProb - must be between 0 and 1`,
+				`line 3 - datasets[0].metricesList[1] - unknown metric "Revenue"`,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			confFile := filepath.Join(t.TempDir(), "config.json")
+			os.WriteFile(confFile, []byte(tt.content), 0644)
+			_, err := LoadConfFile(confFile)
+			if len(tt.wantErrs) == 0 {
+				if err != nil {
+					t.Errorf("LoadConfFile() error = %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("LoadConfFile() expected errors %v", tt.wantErrs)
+			}
+			messages := strings.Split(err.Error(), "\n")
+			if len(messages) != len(tt.wantErrs) {
+				t.Fatalf("LoadConfFile() errors = %q, want %d errors", messages, len(tt.wantErrs))
+			}
+			for i, wantErr := range tt.wantErrs {
+				if !strings.HasPrefix(messages[i], wantErr) {
+					t.Errorf("LoadConfFile() error #%d = %q, want %q", i+1, messages[i], wantErr)
+				}
+			}
+		})
+	}
+}
+
+func TestLoadConfFileSample(t *testing.T) {
+	if _, err := LoadConfFile("../config.json"); err != nil {
+		t.Errorf("LoadConfFile() sample configuration error = %v", err)
+	}
+}
@@ -0,0 +1,626 @@
+//Package anomaliesdetector exposes the collection, detection and reporting pipeline so other Go programs can embed it without running the CLI
+//The anomalies-detector command lives in cmd/anomalies-detector and is built on top of this package
+package anomaliesdetector
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ftfmtavares/anomalies-detector/analyser"
+	"github.com/ftfmtavares/anomalies-detector/collector"
+	"github.com/ftfmtavares/anomalies-detector/collector/synthetic"
+	"github.com/ftfmtavares/anomalies-detector/config"
+	"github.com/ftfmtavares/anomalies-detector/reporting"
+	"github.com/ftfmtavares/anomalies-detector/utils"
+)
+
+//Detector runs the anomalies detection pipeline of an application configuration
+//Notify field is optional and is called with every report produced by Analyse, e.g. to send it through notification channels
+//History field is optional as well and keeps the reports of every stored run, to compare runs week over week
+type Detector struct {
+	Notify  func(report analyser.OutlierReport)
+	History *analyser.RunHistory
+
+	appConfig      config.ApplicationConfig
+	alertRules     []analyser.AlertRule
+	metricRegistry map[string]config.MetricDefinition
+	transforms     [][]collector.Transform
+	derived        [][]collector.DerivedMetric
+	postProcessors [][]analyser.PostProcessor
+	encryptionKeys [][]byte
+	runs           []detectorRun
+	sitesData      []collector.SiteData
+	reports        []analyser.OutlierReport
+	stats          reporting.RunStats
+	collectFailed  int
+}
+
+//detectorRun is a dataset analysed at one of its time steps, along with the index of the dataset on the configuration
+type detectorRun struct {
+	dataset int
+	dataSet config.Dataset
+}
+
+//New creates a Detector for the given configuration, validating its sources, alert rules, metric definitions, derived metrics, transforms, post-processors, baseline resets, known events, detection profiles and encryption keys upfront
+//The general known events are added to those of every dataset
+//Archived datasets are left out of the runs, so they are neither collected nor analysed
+//Derived metrics on a metrics list are collected through their operands, computed once collected
+//It returns an error if any of them is invalid, before any collection
+func New(appConfig config.ApplicationConfig) (*Detector, error) {
+	detector := &Detector{appConfig: appConfig}
+
+	var err error
+	if detector.alertRules, err = analyser.CompileAlertRules(appConfig.AlertRules); err != nil {
+		return nil, err
+	}
+	if detector.metricRegistry, err = appConfig.MetricRegistry(); err != nil {
+		return nil, err
+	}
+	derivedMetrics, err := collector.CompileDerivedMetrics(detector.metricRegistry)
+	if err != nil {
+		return nil, err
+	}
+
+	detector.transforms = make([][]collector.Transform, len(appConfig.Datasets))
+	detector.derived = make([][]collector.DerivedMetric, len(appConfig.Datasets))
+	detector.postProcessors = make([][]analyser.PostProcessor, len(appConfig.Datasets))
+	detector.encryptionKeys = make([][]byte, len(appConfig.Datasets))
+	for i, dataSet := range appConfig.Datasets {
+		if dataSet.EncryptionKey != "" {
+			if detector.encryptionKeys[i], err = utils.LoadEncryptionKey(dataSet.EncryptionKey); err != nil {
+				return nil, fmt.Errorf("site %s - %s", dataSet.SiteId, err.Error())
+			}
+		}
+		if dataSet.Archived {
+			continue
+		}
+		if err = collector.CheckSource(dataSet); err != nil {
+			return nil, fmt.Errorf("site %s - %s", dataSet.SiteId, err.Error())
+		}
+		if _, err = dataSet.Location(); err != nil {
+			return nil, fmt.Errorf("site %s - %s", dataSet.SiteId, err.Error())
+		}
+		if detector.transforms[i], err = collector.CompileTransforms(dataSet.Transforms); err != nil {
+			return nil, fmt.Errorf("site %s - %s", dataSet.SiteId, err.Error())
+		}
+		detector.derived[i] = collector.SelectDerivedMetrics(dataSet.MetricesList, derivedMetrics)
+		dataSet.MetricesList = collector.CollectedMetrics(dataSet.MetricesList, derivedMetrics)
+		if detector.postProcessors[i], err = analyser.CompilePostProcessors(dataSet.PostProcessors); err != nil {
+			return nil, fmt.Errorf("site %s - %s", dataSet.SiteId, err.Error())
+		}
+		if _, _, err = analyser.ParseBaselineResets(dataSet); err != nil {
+			return nil, fmt.Errorf("site %s - %s", dataSet.SiteId, err.Error())
+		}
+		if len(appConfig.KnownEvents) > 0 {
+			dataSet.KnownEvents = append(append([]config.KnownEvent{}, appConfig.KnownEvents...), dataSet.KnownEvents...)
+		}
+		if _, err = analyser.ParseKnownEvents(dataSet, time.Time{}, time.Time{}); err != nil {
+			return nil, fmt.Errorf("site %s - %s", dataSet.SiteId, err.Error())
+		}
+		if _, _, _, err = analyser.ResolveDetectionProfile(dataSet, dataSet.MethodParams(appConfig.DetectionMethods)); err != nil {
+			return nil, fmt.Errorf("site %s - %s", dataSet.SiteId, err.Error())
+		}
+
+		//Using general collection filters and simulation profile if none defined for the specific site, and analysing it once for each configured time step
+		//With a general seed, every site and time step generates from its own seed derived from it, whatever the order sites are collected in
+		if dataSet.SiteCollectFilters == nil {
+			dataSet.SiteCollectFilters = &detector.appConfig.GenCollectFilters
+		}
+		if dataSet.Simulation == nil {
+			dataSet.Simulation = detector.appConfig.Simulation
+		}
+		for _, timeStep := range dataSet.Resolutions() {
+			resolutionSet := dataSet
+			resolutionSet.TimeStep = timeStep
+			if resolutionSet.Seed == 0 && appConfig.Seed != 0 {
+				resolutionSet.Seed = synthetic.DeriveSeed(appConfig.Seed, resolutionSet.SiteId+"/"+timeStep)
+			}
+			detector.runs = append(detector.runs, detectorRun{dataset: i, dataSet: resolutionSet})
+		}
+	}
+
+	return detector, nil
+}
+
+//Run collects and analyses every dataset, returning the context error if it's cancelled before all of them are done
+//Datasets failing to be collected or analysed don't stop the others, their errors being returned together once all are done
+func (detector *Detector) Run(ctx context.Context) error {
+	if err := detector.collect(ctx); err != nil {
+		if ctx.Err() != nil {
+			return err
+		}
+		_, analyseErr := detector.Analyse()
+		return joinRunErrors(err, analyseErr)
+	}
+	_, err := detector.Analyse()
+	return err
+}
+
+//Collect reads, gap fills, types, derives and transforms the data of every dataset at each of its time steps, returning it in configuration order
+//Datasets are collected in parallel by Concurrency workers, those failing being returned without metrics along with a RunErrors
+func (detector *Detector) Collect() ([]collector.SiteData, error) {
+	err := detector.collect(context.Background())
+	return detector.sitesData, err
+}
+
+//collect implements Collect, no further dataset being started once the context is cancelled
+//Cancelled runs only keep the data collected before the first dataset left behind, so data and datasets stay aligned
+func (detector *Detector) collect(ctx context.Context) error {
+	start := reporting.Clock.Now()
+	sitesData := make([]collector.SiteData, len(detector.runs))
+	done, err := detector.parallel(ctx, len(detector.runs), func(i int) {
+		run := detector.runs[i]
+		sitesData[i] = collector.SiteData{SiteId: run.dataSet.SiteId, TimeStep: run.dataSet.TimeStep}
+		sitesData[i] = detector.prepare(run, collector.GetData(run.dataSet))
+	})
+	for i := range done {
+		if !done[i] {
+			sitesData = sitesData[:i]
+			break
+		}
+	}
+	detector.sitesData = sitesData
+	detector.stats = reporting.RunStats{DatasetsProcessed: len(sitesData), CollectionDuration: reporting.Clock.Now().Sub(start)}
+	detector.collectFailed = failedRuns(err)
+	return err
+}
+
+//Load reads the data of every dataset at each of its time steps from a data file previously written by the application, in place of collecting it, returning it in configuration order
+//Records are matched to the datasets by site and time step, encrypted ones being decrypted with the key of their site, and records of sites no longer configured are ignored
+//Datasets not found on the file are returned without metrics along with a RunErrors, so the others can still be analysed, while an unreadable file returns an error alone
+func (detector *Detector) Load(dataFile string) ([]collector.SiteData, error) {
+	file, err := os.Open(dataFile)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return detector.LoadReader(file)
+}
+
+//LoadReader reads the data of every dataset at each of its time steps as Load does, from a reader such as the standard input
+func (detector *Detector) LoadReader(reader io.Reader) ([]collector.SiteData, error) {
+	start := reporting.Clock.Now()
+	byteValue, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+	var records []json.RawMessage
+	if err := json.Unmarshal(byteValue, &records); err != nil {
+		return nil, fmt.Errorf("data file - %s", err.Error())
+	}
+
+	//Decrypting every record with the key of its site, records of sites without a configured key being left out
+	siteKeys := map[string][]byte{}
+	for _, run := range detector.runs {
+		siteKeys[run.dataSet.SiteId] = detector.encryptionKeys[run.dataset]
+	}
+	loaded := map[string]collector.SiteData{}
+	for i, record := range records {
+		var encrypted utils.EncryptedRecord
+		if err := json.Unmarshal(record, &encrypted); err != nil {
+			return nil, fmt.Errorf("data file - record #%d - %s", i+1, err.Error())
+		}
+		var siteData collector.SiteData
+		if encrypted.Ciphertext != "" {
+			key := siteKeys[encrypted.SiteId]
+			if key == nil {
+				continue
+			}
+			if err := utils.DecryptRecord(encrypted, key, &siteData); err != nil {
+				return nil, fmt.Errorf("data file - record #%d - site %s - %s", i+1, encrypted.SiteId, err.Error())
+			}
+		} else if err := json.Unmarshal(record, &siteData); err != nil {
+			return nil, fmt.Errorf("data file - record #%d - %s", i+1, err.Error())
+		}
+		loaded[siteData.SiteId+"/"+siteData.TimeStep] = siteData
+	}
+
+	sitesData := make([]collector.SiteData, len(detector.runs))
+	errs := RunErrors{}
+	for i, run := range detector.runs {
+		siteData, found := loaded[run.dataSet.SiteId+"/"+run.dataSet.TimeStep]
+		if !found {
+			siteData = collector.SiteData{SiteId: run.dataSet.SiteId, TimeStep: run.dataSet.TimeStep}
+			errs = append(errs, fmt.Errorf("site %s - time step %s not found on the data file", run.dataSet.SiteId, run.dataSet.TimeStep))
+		}
+		sitesData[i] = siteData
+	}
+	detector.sitesData = sitesData
+	detector.stats = reporting.RunStats{DatasetsProcessed: len(sitesData), CollectionDuration: reporting.Clock.Now().Sub(start)}
+	detector.collectFailed = len(errs)
+	if len(errs) > 0 {
+		return sitesData, errs
+	}
+	return sitesData, nil
+}
+
+//Analyse runs the detection methods, alert rules and post-processors over the collected data, returning a report for each collected site and time step
+//Sites are analysed in parallel as well, every report being then passed to Notify, if set, in configuration order
+func (detector *Detector) Analyse() ([]analyser.OutlierReport, error) {
+	start := reporting.Clock.Now()
+	reports := make([]analyser.OutlierReport, len(detector.sitesData))
+	_, err := detector.parallel(context.Background(), len(detector.sitesData), func(i int) {
+		reports[i] = analyser.OutlierReport{SiteId: detector.sitesData[i].SiteId, TimeStep: detector.sitesData[i].TimeStep}
+		reports[i] = detector.analyse(detector.runs[i], detector.sitesData[i])
+	})
+	detector.reports = reports
+	detector.stats.DetectionDuration = reporting.Clock.Now().Sub(start)
+	detector.stats.DatasetsFailed = detector.collectFailed + failedRuns(err)
+	detector.stats.Finished = reporting.Clock.Now()
+	detector.stats.LastSuccess = time.Time{}
+	if detector.stats.DatasetsFailed == 0 {
+		detector.stats.LastSuccess = detector.stats.Finished
+	}
+	if detector.Notify != nil {
+		for _, report := range reports {
+			detector.Notify(report)
+		}
+	}
+	return reports, err
+}
+
+//Stream collects and analyses a dataset at its first time step over data read by the given function, such as a stream buffer snapshot, in place of its source
+//The read data goes through the same gap filling, metric definitions, derived metrics and transforms as collected data, and through the same analysis, the function receiving the dataset as resolved for detection
+//Neither the latest run nor Notify are involved, and false is returned if the site isn't a dataset being analysed
+func (detector *Detector) Stream(siteId string, read func(dataSet config.Dataset) collector.SiteData) (collector.SiteData, analyser.OutlierReport, bool) {
+	for _, run := range detector.runs {
+		if run.dataSet.SiteId == siteId {
+			siteData := detector.prepare(run, read(run.dataSet))
+			return siteData, detector.analyse(run, siteData), true
+		}
+	}
+	return collector.SiteData{}, analyser.OutlierReport{}, false
+}
+
+//prepare runs the collection stages over the raw data of a run, filling its gaps and applying the metric definitions, derived metrics and transforms
+func (detector *Detector) prepare(run detectorRun, siteData collector.SiteData) collector.SiteData {
+	siteData = collector.FillGaps(siteData, run.dataSet.Gaps)
+	siteData = collector.ApplyMetricDefinitions(siteData, detector.metricRegistry)
+	siteData = collector.ApplyDerivedMetrics(siteData, detector.derived[run.dataset])
+	return collector.ApplyTransforms(siteData, detector.transforms[run.dataset])
+}
+
+//analyse runs the detection methods, alert rules and post-processors of a run over its collected data
+func (detector *Detector) analyse(run detectorRun, siteData collector.SiteData) analyser.OutlierReport {
+	report := analyser.GetResults(siteData, run.dataSet, run.dataSet.MethodParams(detector.appConfig.DetectionMethods))
+	report = analyser.ApplyAlertRules(report, detector.alertRules)
+	return analyser.ApplyPostProcessors(report, detector.postProcessors[run.dataset])
+}
+
+//Evaluate scores the given detection methods, all supported ones if none, over the data of the latest collection, each method running alone with the site settings otherwise
+//Every site is scored against the deviations injected into its generated data along with the given labels, so collected sites are only scored against the latter
+//It returns the evaluations in configuration order, the errors of the sites failing to be evaluated being returned together
+func (detector *Detector) Evaluate(labels []analyser.Label, methods []string) ([]analyser.Evaluation, error) {
+	if len(methods) == 0 {
+		methods = config.DetectionMethods
+	}
+	evaluations := make([]analyser.Evaluation, len(detector.sitesData))
+	_, err := detector.parallel(context.Background(), len(detector.sitesData), func(i int) {
+		evaluations[i] = analyser.Evaluation{SiteId: detector.sitesData[i].SiteId, TimeStep: detector.sitesData[i].TimeStep, Scores: []analyser.MethodScore{}}
+		siteLabels := append(analyser.GroundTruth(detector.sitesData[i]), labels...)
+		evaluations[i] = analyser.EvaluateMethods(detector.sitesData[i], detector.runs[i].dataSet, detector.runs[i].dataSet.MethodParams(detector.appConfig.DetectionMethods), siteLabels, methods)
+	})
+	return evaluations, err
+}
+
+//Backtest scores a detection method over the collected data of every site with each given value of one of its parameters, against the labelled incidents and the deviations injected into generated data
+//It returns the scores of every value over all sites, in the order of the values, or an error if the parameter can't be set on any site
+func (detector *Detector) Backtest(labels []analyser.Label, sweep analyser.BacktestSweep, values []float64) ([]analyser.BacktestRun, error) {
+	evaluations := make([][]analyser.MethodScore, len(detector.sitesData))
+	errs := make([]error, len(detector.sitesData))
+	_, err := detector.parallel(context.Background(), len(detector.sitesData), func(i int) {
+		siteLabels := append(analyser.GroundTruth(detector.sitesData[i]), labels...)
+		dataSet := detector.runs[i].dataSet
+		evaluations[i], errs[i] = analyser.BacktestSite(detector.sitesData[i], dataSet, dataSet.MethodParams(detector.appConfig.DetectionMethods), siteLabels, sweep, values)
+	})
+	for i, siteErr := range errs {
+		if siteErr != nil {
+			return nil, fmt.Errorf("%s - %s", detector.sitesData[i].SiteId, siteErr.Error())
+		}
+	}
+
+	runs := []analyser.BacktestRun{}
+	for v, value := range values {
+		siteScores := []analyser.Evaluation{}
+		for _, scores := range evaluations {
+			if scores != nil {
+				siteScores = append(siteScores, analyser.Evaluation{Scores: []analyser.MethodScore{scores[v]}})
+			}
+		}
+		score := analyser.MethodScore{Method: sweep.Method}
+		if totals := analyser.SumScores(siteScores); len(totals) > 0 {
+			score = totals[0]
+		}
+		runs = append(runs, analyser.BacktestRun{Value: value, Score: score})
+	}
+	return runs, err
+}
+
+//RunErrors holds the errors of the datasets failing within a run, in configuration order
+type RunErrors []error
+
+//Error joins the errors of all failed datasets
+func (runErrors RunErrors) Error() string {
+	messages := []string{}
+	for _, err := range runErrors {
+		messages = append(messages, err.Error())
+	}
+	return strings.Join(messages, "; ")
+}
+
+//failedRuns returns the number of datasets failing within a stage given its error, a single one for errors other than RunErrors
+func failedRuns(err error) int {
+	if runErrors, ok := err.(RunErrors); ok {
+		return len(runErrors)
+	}
+	if err != nil {
+		return 1
+	}
+	return 0
+}
+
+//joinRunErrors combines the errors of several stages into a single RunErrors, nil if none failed
+func joinRunErrors(errs ...error) error {
+	joined := RunErrors{}
+	for _, err := range errs {
+		if runErrors, ok := err.(RunErrors); ok {
+			joined = append(joined, runErrors...)
+		} else if err != nil {
+			joined = append(joined, err)
+		}
+	}
+	if len(joined) == 0 {
+		return nil
+	}
+	return joined
+}
+
+//parallel runs the given task for the first count dataset runs on a pool of Concurrency workers, returning which ones were done
+//Panicking tasks are recovered as errors of their dataset, returned together in configuration order, or the context error if it was cancelled
+func (detector *Detector) parallel(ctx context.Context, count int, task func(i int)) ([]bool, error) {
+	if count == 0 {
+		return []bool{}, nil
+	}
+	workers := detector.appConfig.Concurrency
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	if workers > count {
+		workers = count
+	}
+
+	done := make([]bool, count)
+	errs := make([]error, count)
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for worker := 0; worker < workers; worker++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				func() {
+					defer func() {
+						if recovered := recover(); recovered != nil {
+							run := detector.runs[i].dataSet
+							errs[i] = fmt.Errorf("site %s (%s) - %v", run.SiteId, run.TimeStep, recovered)
+						}
+						done[i] = true
+					}()
+					task(i)
+				}()
+			}
+		}()
+	}
+
+	//Feeding the workers in configuration order, stopping as soon as the context is cancelled
+	var err error
+	for i := 0; i < count && err == nil; i++ {
+		if err = ctx.Err(); err != nil {
+			break
+		}
+		select {
+		case indexes <- i:
+		case <-ctx.Done():
+			err = ctx.Err()
+		}
+	}
+	close(indexes)
+	wg.Wait()
+	if err != nil {
+		return done, err
+	}
+	return done, joinRunErrors(errs...)
+}
+
+//Store appends the collected data of every dataset to the tiered storage, at its finest collected time step only since coarser ones are rolled up by the store
+//Datasets with an encryption key are stored encrypted with it, and the errors of the failed datasets are returned together
+//The reports of every dataset are saved on the History as well, if set, along with their data if it keeps run snapshots
+func (detector *Detector) Store(store *collector.TierStore) error {
+	detector.encryptSites(store)
+	finest := map[int]int{}
+	for i, siteData := range detector.sitesData {
+		dataset := detector.runs[i].dataset
+		if len(siteData.Metrics) == 0 {
+			continue
+		}
+		current, found := finest[dataset]
+		if !found {
+			finest[dataset] = i
+			continue
+		}
+		timeStep, _ := utils.StrToDuration(siteData.TimeStep)
+		currentTimeStep, _ := utils.StrToDuration(detector.sitesData[current].TimeStep)
+		if timeStep < currentTimeStep {
+			finest[dataset] = i
+		}
+	}
+
+	errs := make([]error, len(detector.sitesData), len(detector.sitesData)+1)
+	for _, i := range finest {
+		if err := store.Append(detector.sitesData[i]); err != nil {
+			errs[i] = fmt.Errorf("site %s - %s", detector.sitesData[i].SiteId, err.Error())
+		}
+	}
+	if detector.History != nil {
+		runTime := analyser.RunTime(detector.reports)
+		errs = append(errs, detector.History.SaveRun(runTime, detector.reports), detector.History.SaveRunData(runTime, detector.sitesData))
+	}
+	return joinRunErrors(errs...)
+}
+
+//SaveResults writes the collected data and reports of the latest run to the results store, at the run time of its reports
+//Datasets with an encryption key, rejected along with a database by the configuration, are still left out and logged as such since the store holds plain rows
+func (detector *Detector) SaveResults(store *reporting.ResultStore) error {
+	detector.logEncrypted("Results store")
+	sitesData := []collector.SiteData{}
+	reports := []analyser.OutlierReport{}
+	for i, siteData := range detector.sitesData {
+		if detector.encryptionKeys[detector.runs[i].dataset] != nil {
+			continue
+		}
+		sitesData = append(sitesData, siteData)
+		if i < len(detector.reports) {
+			reports = append(reports, detector.reports[i])
+		}
+	}
+	return store.SaveRun(analyser.RunTime(detector.reports), sitesData, reports)
+}
+
+//Archived returns the stored data of the archived datasets, marked as archived, along with their reports of the latest stored run including them if History is set
+//Datasets with an encryption key are decrypted with it, and the errors of the failed datasets are returned together
+func (detector *Detector) Archived(store *collector.TierStore) ([]collector.SiteData, []analyser.OutlierReport, error) {
+	detector.encryptSites(store)
+	sitesData := []collector.SiteData{}
+	reports := []analyser.OutlierReport{}
+	errs := []error{}
+	for _, dataSet := range detector.appConfig.Datasets {
+		if !dataSet.Archived {
+			continue
+		}
+		siteData, err := store.Stored(dataSet.SiteId)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("site %s - %s", dataSet.SiteId, err.Error()))
+			continue
+		}
+		if len(siteData.Metrics) == 0 {
+			continue
+		}
+		siteData.Archived = true
+		sitesData = append(sitesData, siteData)
+		if detector.History == nil {
+			continue
+		}
+		siteReports, err := detector.History.LatestSiteReports(dataSet.SiteId)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("site %s - %s", dataSet.SiteId, err.Error()))
+			continue
+		}
+		reports = append(reports, siteReports...)
+	}
+	return sitesData, reports, joinRunErrors(errs...)
+}
+
+//logEncrypted logs the datasets with an encryption key, once each, as left out of the given storage
+func (detector *Detector) logEncrypted(storage string) {
+	for i, dataSet := range detector.appConfig.Datasets {
+		if detector.encryptionKeys[i] != nil {
+			log.Printf("%s skipped - site %s is encrypted, its data and events are never stored in plain text\n", storage, dataSet.SiteId)
+		}
+	}
+}
+
+//encryptSites sets the encryption keys of the datasets on the tiered storage, if given, and on the History, if set
+func (detector *Detector) encryptSites(store *collector.TierStore) {
+	for i, dataSet := range detector.appConfig.Datasets {
+		if detector.encryptionKeys[i] == nil {
+			continue
+		}
+		if store != nil {
+			store.EncryptSite(dataSet.SiteId, detector.encryptionKeys[i])
+		}
+		if detector.History != nil {
+			detector.History.EncryptSite(dataSet.SiteId, detector.encryptionKeys[i])
+		}
+	}
+}
+
+//Stats returns the measures of the latest run, the datasets collected and failing and the durations of its collection and detection, timed with the reporting Clock
+func (detector *Detector) Stats() reporting.RunStats {
+	return detector.stats
+}
+
+//Metrics returns the metric definitions by name, the declared ones overriding the defaults
+func (detector *Detector) Metrics() map[string]config.MetricDefinition {
+	return detector.metricRegistry
+}
+
+//Datasets returns the configured datasets as resolved for detection, with the general known events, collection filters and simulation profile, one per configured dataset
+//Archived datasets, never collected, are returned with the general known events only
+func (detector *Detector) Datasets() []config.Dataset {
+	datasets := make([]config.Dataset, len(detector.appConfig.Datasets))
+	resolved := make([]bool, len(datasets))
+	for _, run := range detector.runs {
+		if !resolved[run.dataset] {
+			datasets[run.dataset] = run.dataSet
+			datasets[run.dataset].TimeStep = detector.appConfig.Datasets[run.dataset].TimeStep
+			datasets[run.dataset].Seed = detector.appConfig.Datasets[run.dataset].Seed
+			resolved[run.dataset] = true
+		}
+	}
+	for i, dataSet := range detector.appConfig.Datasets {
+		if resolved[i] {
+			continue
+		}
+		if len(detector.appConfig.KnownEvents) > 0 {
+			dataSet.KnownEvents = append(append([]config.KnownEvent{}, detector.appConfig.KnownEvents...), dataSet.KnownEvents...)
+		}
+		datasets[i] = dataSet
+	}
+	return datasets
+}
+
+//Report returns an HTTP handler serving the report index, charts and APIs over the collected data and analysed reports, detection being recomputed on demand with the resolved datasets
+func (detector *Detector) Report() http.Handler {
+	detector.encryptSites(nil)
+	return reporting.NewReportHandler(detector.sitesData, detector.reports, detector.appConfig.ReportServer, reporting.DetectionSettings{Datasets: detector.Datasets(), Methods: detector.appConfig.DetectionMethods, History: detector.History, Run: &detector.stats, Metrics: detector.metricRegistry})
+}
+
+//Records returns the collected data and reports ready to be persisted, encrypted for the sites configured with an encryption key
+//It returns an error if any record fails to be encrypted
+func (detector *Detector) Records() ([]interface{}, []interface{}, error) {
+	dataRecords := []interface{}{}
+	reportRecords := []interface{}{}
+	for i, siteData := range detector.sitesData {
+		encryptionKey := detector.encryptionKeys[detector.runs[i].dataset]
+		if encryptionKey == nil {
+			dataRecords = append(dataRecords, siteData)
+			if i < len(detector.reports) {
+				reportRecords = append(reportRecords, detector.reports[i])
+			}
+			continue
+		}
+
+		dataRecord, err := utils.EncryptRecord(siteData.SiteId, siteData, encryptionKey)
+		if err != nil {
+			return nil, nil, fmt.Errorf("encrypting data of site %s - %s", siteData.SiteId, err.Error())
+		}
+		dataRecords = append(dataRecords, dataRecord)
+		if i < len(detector.reports) {
+			reportRecord, err := utils.EncryptRecord(siteData.SiteId, detector.reports[i], encryptionKey)
+			if err != nil {
+				return nil, nil, fmt.Errorf("encrypting report of site %s - %s", siteData.SiteId, err.Error())
+			}
+			reportRecords = append(reportRecords, reportRecord)
+		}
+	}
+	return dataRecords, reportRecords, nil
+}
@@ -0,0 +1,510 @@
+package anomaliesdetector
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ftfmtavares/anomalies-detector/analyser"
+	"github.com/ftfmtavares/anomalies-detector/collector"
+	"github.com/ftfmtavares/anomalies-detector/config"
+	"github.com/ftfmtavares/anomalies-detector/reporting"
+	"github.com/ftfmtavares/anomalies-detector/utils"
+)
+
+func TestDetector_Run(t *testing.T) {
+	defer func(clock utils.Clock) { reporting.Clock = clock }(reporting.Clock)
+	finished := time.Date(2022, 9, 1, 10, 0, 0, 0, time.UTC)
+	reporting.Clock = utils.FixedClock(finished)
+	t.Setenv("DETECTOR_TEST_KEY", hex.EncodeToString(make([]byte, 32)))
+	appConfig := config.ApplicationConfig{
+		Datasets: []config.Dataset{
+			{SiteId: "shop", TimeAgo: "14d", TimeSteps: []string{"1d", "12h"}, OutliersDetectionMethod: "3-sigmas", MetricesList: []string{"Revenue"}},
+			{SiteId: "vault", TimeAgo: "14d", TimeStep: "1d", OutliersDetectionMethod: "3-sigmas", MetricesList: []string{"Visits"}, EncryptionKey: "env:DETECTOR_TEST_KEY"},
+		},
+		DetectionMethods: config.DetectionMethodsParams{ThreeSigmas: config.ThreeSigmasParams{OutliersMultiplier: 2, StrongOutliersMultiplier: 3}},
+	}
+
+	detector, err := New(appConfig)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	notified := []string{}
+	detector.Notify = func(report analyser.OutlierReport) { notified = append(notified, report.SiteId+"/"+report.TimeStep) }
+	if err := detector.Run(context.Background()); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	//Sites are collected, analysed and notified once per time step, in configuration order
+	if want := []string{"shop/1d", "shop/12h", "vault/1d"}; strings.Join(notified, ",") != strings.Join(want, ",") {
+		t.Errorf("Run() notified %v, want %v", notified, want)
+	}
+	if detector.sitesData[2].Metrics[0].Type != config.MetricTypeCount {
+		t.Errorf("Run() metric type = %s, want %s", detector.sitesData[2].Metrics[0].Type, config.MetricTypeCount)
+	}
+
+	//Only the records of the site with an encryption key are encrypted
+	dataRecords, reportRecords, err := detector.Records()
+	if err != nil {
+		t.Fatalf("Records() error = %v", err)
+	}
+	if len(dataRecords) != 3 || len(reportRecords) != 3 {
+		t.Fatalf("Records() returned %d data and %d report records, want 3", len(dataRecords), len(reportRecords))
+	}
+	if _, encrypted := dataRecords[0].(utils.EncryptedRecord); encrypted {
+		t.Errorf("Records() encrypted the data of a site without key")
+	}
+	if _, encrypted := reportRecords[2].(utils.EncryptedRecord); !encrypted {
+		t.Errorf("Records() didn't encrypt the report of a site with key")
+	}
+
+	//Only the finest resolution of each site is stored, the site with key being stored encrypted
+	storeDir := t.TempDir()
+	store, err := collector.NewTierStore(config.StorageParams{Dir: storeDir})
+	if err != nil {
+		t.Fatalf("NewTierStore() error = %v", err)
+	}
+	if err := detector.Store(store); err != nil {
+		t.Fatalf("Store() error = %v", err)
+	}
+	stored, _ := store.Query("shop", detector.sitesData[1].DateEnd.AddDate(0, 0, -7), detector.sitesData[1].DateEnd, 0)
+	if stored.TimeStep != "12h" {
+		t.Errorf("Store() stored the %s resolution, want 12h", stored.TimeStep)
+	}
+	var record utils.EncryptedRecord
+	content, err := os.ReadFile(filepath.Join(storeDir, "raw", "vault.json"))
+	if err != nil || json.Unmarshal(content, &record) != nil || record.Ciphertext == "" || strings.Contains(string(content), "Visits") {
+		t.Errorf("Store() stored the data of a site with key as %s, %v, want an encrypted record", content, err)
+	}
+	stored, err = store.Query("vault", detector.sitesData[2].DateEnd.AddDate(0, 0, -7), detector.sitesData[2].DateEnd, 0)
+	if err != nil || len(stored.Metrics) != 1 || stored.Metrics[0].Metric != "Visits" {
+		t.Errorf("Query() = %+v, %v, want the decrypted Visits of the site with key", stored, err)
+	}
+	plainStore, _ := collector.NewTierStore(config.StorageParams{Dir: storeDir})
+	if _, err := plainStore.Query("vault", detector.sitesData[2].DateEnd.AddDate(0, 0, -7), detector.sitesData[2].DateEnd, 0); err == nil {
+		t.Errorf("Query() without the key read the data of a site with key")
+	}
+
+	res := httptest.NewRecorder()
+	detector.Report().ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/report", nil))
+	if res.Code != http.StatusOK || !strings.Contains(res.Body.String(), "<h2>shop (12h)</h2>") {
+		t.Errorf("Report() index = %d %s", res.Code, res.Body.String())
+	}
+
+	//The run measures are served on /metrics
+	if stats := detector.Stats(); stats.DatasetsProcessed != 3 || stats.DatasetsFailed != 0 || !stats.LastSuccess.Equal(finished) || stats.CollectionDuration != 0 {
+		t.Errorf("Stats() = %+v, want 3 datasets processed without failures, timed on the reporting clock", stats)
+	}
+	res = httptest.NewRecorder()
+	detector.Report().ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if !strings.Contains(res.Body.String(), "anomalies_datasets_processed_total 3\n") || !strings.Contains(res.Body.String(), "anomalies_last_success_timestamp_seconds ") {
+		t.Errorf("Report() metrics = %s", res.Body.String())
+	}
+}
+
+func TestDetector_Evaluate(t *testing.T) {
+	outlierProb := 0.02
+	dataSet := config.Dataset{SiteId: "shop", TimeAgo: "14d", TimeStep: "1h", OutliersDetectionMethod: "3-sigmas", MetricesList: []string{"Revenue"}, Seed: 7, Simulation: &config.SimulationParams{OutlierProb: &outlierProb}}
+	filled := dataSet
+	filled.SiteId = "filled"
+	filled.Gaps = &config.GapParams{Strategy: config.GapInterpolate}
+	detector, err := New(config.ApplicationConfig{
+		Datasets:         []config.Dataset{dataSet, filled},
+		DetectionMethods: config.DetectionMethodsParams{ThreeSigmas: config.ThreeSigmasParams{OutliersMultiplier: 2, StrongOutliersMultiplier: 3}},
+	})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if _, err := detector.Collect(); err != nil {
+		t.Fatalf("Collect() error = %v", err)
+	}
+
+	//Gap filled data keeps the deviations injected into the generated one, so both are scored against the same incidents
+	evaluations, err := detector.Evaluate(nil, []string{"3-sigmas"})
+	if err != nil {
+		t.Fatalf("Evaluate() error = %v", err)
+	}
+	if len(evaluations) != 2 || evaluations[0].Scores[0].Incidents == 0 {
+		t.Fatalf("Evaluate() = %+v, want incidents on the generated site", evaluations)
+	}
+	if evaluations[1].Scores[0].Incidents != evaluations[0].Scores[0].Incidents {
+		t.Errorf("Evaluate() gap filled site incidents = %d, want %d", evaluations[1].Scores[0].Incidents, evaluations[0].Scores[0].Incidents)
+	}
+}
+
+func TestDetector_Datasets(t *testing.T) {
+	sale := config.KnownEvent{Name: "Sale", Start: "2022-09-21", End: "2022-09-22"}
+	appConfig := config.ApplicationConfig{
+		Datasets: []config.Dataset{
+			{SiteId: "shop", TimeAgo: "14d", TimeSteps: []string{"1d", "12h"}, OutliersDetectionMethod: "3-sigmas", MetricesList: []string{"Revenue"}},
+			{SiteId: "blog", TimeAgo: "14d", TimeStep: "1d", OutliersDetectionMethod: "3-sigmas", MetricesList: []string{"Visits"}, Archived: true, KnownEvents: []config.KnownEvent{{Name: "Launch", Start: "2022-10-01", End: "2022-10-02"}}},
+		},
+		KnownEvents:      []config.KnownEvent{sale},
+		DetectionMethods: config.DetectionMethodsParams{ThreeSigmas: config.ThreeSigmasParams{OutliersMultiplier: 2, StrongOutliersMultiplier: 3}},
+	}
+	detector, err := New(appConfig)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	//Every configured dataset is returned once, with the general known events first, as detection runs with them
+	datasets := detector.Datasets()
+	if len(datasets) != 2 || datasets[0].SiteId != "shop" || datasets[1].SiteId != "blog" {
+		t.Fatalf("Datasets() = %v, want shop and blog", datasets)
+	}
+	if len(datasets[0].KnownEvents) != 1 || datasets[0].KnownEvents[0].Name != "Sale" || datasets[0].SiteCollectFilters == nil || datasets[0].TimeStep != "" {
+		t.Errorf("Datasets() shop = %+v, want the general known events and filters as configured time steps", datasets[0])
+	}
+	if len(datasets[1].KnownEvents) != 2 || datasets[1].KnownEvents[0].Name != "Sale" || datasets[1].KnownEvents[1].Name != "Launch" {
+		t.Errorf("Datasets() archived blog known events = %v, want Sale and Launch", datasets[1].KnownEvents)
+	}
+	if len(appConfig.Datasets[1].KnownEvents) != 1 {
+		t.Errorf("Datasets() changed the configured known events to %v", appConfig.Datasets[1].KnownEvents)
+	}
+}
+
+func TestDetector_Stream(t *testing.T) {
+	timeRef := time.Date(2022, 9, 20, 0, 0, 0, 0, time.UTC)
+	appConfig := config.ApplicationConfig{
+		Datasets: []config.Dataset{
+			{SiteId: "shop", TimeAgo: "1d", TimeSteps: []string{"1h", "1d"}, OutliersDetectionMethod: "3-sigmas", MetricesList: []string{"Revenue"}, Gaps: &config.GapParams{Strategy: config.GapInterpolate}},
+		},
+		KnownEvents:      []config.KnownEvent{{Name: "Sale", Start: "2022-09-21", End: "2022-09-22"}},
+		DetectionMethods: config.DetectionMethodsParams{ThreeSigmas: config.ThreeSigmasParams{OutliersMultiplier: 2, StrongOutliersMultiplier: 3}},
+	}
+	detector, err := New(appConfig)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	//The read data goes through the collection stages at the first time step, with the dataset resolved as for a run
+	var read config.Dataset
+	siteData, report, found := detector.Stream("shop", func(dataSet config.Dataset) collector.SiteData {
+		read = dataSet
+		data := []collector.TimeStepData{{DateStart: timeRef, Value: 10, Samples: 1}, {DateStart: timeRef.Add(2 * time.Hour), Value: 30, Samples: 1}}
+		return collector.SiteData{SiteId: "shop", TimeStep: dataSet.TimeStep, DateStart: timeRef, DateEnd: timeRef.Add(3 * time.Hour), Metrics: []collector.MetricData{{Metric: "Revenue", Attributes: []string{"Total"}, AttributeData: map[string][]collector.TimeStepData{"Total": data}}}}
+	})
+	if !found || read.TimeStep != "1h" || len(read.KnownEvents) != 1 || read.SiteCollectFilters == nil {
+		t.Fatalf("Stream() read with %+v, want the resolved dataset at 1h", read)
+	}
+	if data := siteData.Metrics[0].AttributeData["Total"]; len(data) != 3 || data[1].Value != 20 {
+		t.Errorf("Stream() data = %v, want the missing time step interpolated", data)
+	}
+	if report.SiteId != "shop" || report.TimeStep != "1h" {
+		t.Errorf("Stream() report = %s/%s, want shop/1h", report.SiteId, report.TimeStep)
+	}
+	if _, _, found := detector.Stream("blog", func(config.Dataset) collector.SiteData { return collector.SiteData{} }); found {
+		t.Errorf("Stream() found a site not configured")
+	}
+}
+
+func TestDetector_errors(t *testing.T) {
+	dataSet := config.Dataset{SiteId: "shop", TimeAgo: "14d", TimeStep: "1d", OutliersDetectionMethod: "3-sigmas", MetricesList: []string{"Revenue"}}
+
+	invalidTransform := dataSet
+	invalidTransform.Transforms = []config.Transform{{Type: "filter"}}
+	missingKey := dataSet
+	missingKey.EncryptionKey = "env:DETECTOR_TEST_MISSING_KEY"
+	unknownProfile := dataSet
+	unknownProfile.DetectionProfile = "loud"
+	for _, appConfig := range []config.ApplicationConfig{
+		{Datasets: []config.Dataset{invalidTransform}},
+		{Datasets: []config.Dataset{missingKey}},
+		{Datasets: []config.Dataset{unknownProfile}},
+		{Datasets: []config.Dataset{dataSet}, Metrics: []config.MetricDefinition{{Name: "Revenue", Type: "Median"}}},
+	} {
+		if _, err := New(appConfig); err == nil {
+			t.Errorf("New() expected an error for %v", appConfig)
+		}
+	}
+
+	//A cancelled run stops before collecting any dataset
+	detector, err := New(config.ApplicationConfig{Datasets: []config.Dataset{dataSet}})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := detector.Run(ctx); err == nil || len(detector.sitesData) != 0 {
+		t.Errorf("Run() error = %v with %d sites collected, want a cancelled run", err, len(detector.sitesData))
+	}
+}
+
+func TestDetector_parallel(t *testing.T) {
+	dataSets := []config.Dataset{}
+	for _, siteId := range []string{"a", "b", "c", "d", "e", "f"} {
+		dataSets = append(dataSets, config.Dataset{SiteId: siteId, TimeAgo: "7d", TimeStep: "1d", OutliersDetectionMethod: "3-sigmas", MetricesList: []string{"Revenue"}})
+	}
+	detector, err := New(config.ApplicationConfig{Datasets: dataSets, Concurrency: 3})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	//Results are written by index so their order doesn't depend on the workers, while panics become errors of their dataset
+	results := make([]string, len(dataSets))
+	done, err := detector.parallel(context.Background(), len(dataSets), func(i int) {
+		if i == 1 || i == 4 {
+			panic("backend unavailable")
+		}
+		results[i] = dataSets[i].SiteId
+	})
+	if want := "a,,c,d,,f"; strings.Join(results, ",") != want {
+		t.Errorf("parallel() results = %v, want %s", results, want)
+	}
+	runErrors, ok := err.(RunErrors)
+	if !ok || len(runErrors) != 2 || runErrors[0].Error() != "site b (1d) - backend unavailable" || runErrors[1].Error() != "site e (1d) - backend unavailable" {
+		t.Errorf("parallel() error = %v, want the errors of sites b and e", err)
+	}
+	for i := range done {
+		if !done[i] {
+			t.Errorf("parallel() dataset %d not done", i)
+		}
+	}
+
+	//The collected data keeps the configuration order whatever the concurrency
+	sitesData, err := detector.Collect()
+	if err != nil {
+		t.Fatalf("Collect() error = %v", err)
+	}
+	for i, siteData := range sitesData {
+		if siteData.SiteId != dataSets[i].SiteId || len(siteData.Metrics) != 1 {
+			t.Errorf("Collect() site %d = %s with %d metrics, want %s with 1", i, siteData.SiteId, len(siteData.Metrics), dataSets[i].SiteId)
+		}
+	}
+}
+
+func TestDetector_siteDetectionMethods(t *testing.T) {
+	dataSet := config.Dataset{SiteId: "shop", TimeAgo: "14d", TimeStep: "1d", OutliersDetectionMethod: "3-sigmas", MetricesList: []string{"Revenue"}}
+	quiet := dataSet
+	quiet.SiteId = "quiet"
+	quiet.SiteDetectionMethods = &config.DetectionMethodsParams{ThreeSigmas: config.ThreeSigmasParams{OutliersMultiplier: 1000, StrongOutliersMultiplier: 2000}}
+	detector, err := New(config.ApplicationConfig{
+		Datasets:         []config.Dataset{dataSet, quiet},
+		DetectionMethods: config.DetectionMethodsParams{ThreeSigmas: config.ThreeSigmasParams{OutliersMultiplier: 0.01, StrongOutliersMultiplier: 0.02}},
+	})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if _, err := detector.Collect(); err != nil {
+		t.Fatalf("Collect() error = %v", err)
+	}
+	reports, err := detector.Analyse()
+	if err != nil {
+		t.Fatalf("Analyse() error = %v", err)
+	}
+
+	//The site with its own parameters ignores the general ones flagging almost every time step
+	countEvents := func(report analyser.OutlierReport) int {
+		return len(report.Result.Alarms) + len(report.Result.Warnings)
+	}
+	if countEvents(reports[0]) == 0 {
+		t.Errorf("Analyse() found no events with the general parameters")
+	}
+	if countEvents(reports[1]) != 0 {
+		t.Errorf("Analyse() found %d events with the site parameters, want 0", countEvents(reports[1]))
+	}
+}
+
+func TestDetector_Archived(t *testing.T) {
+	appConfig := config.ApplicationConfig{
+		Datasets: []config.Dataset{
+			{SiteId: "shop", TimeAgo: "14d", TimeStep: "1d", OutliersDetectionMethod: "3-sigmas", MetricesList: []string{"Revenue"}},
+			{SiteId: "legacy", TimeAgo: "14d", TimeStep: "1d", OutliersDetectionMethod: "3-sigmas", MetricesList: []string{"Visits"}},
+		},
+		DetectionMethods: config.DetectionMethodsParams{ThreeSigmas: config.ThreeSigmasParams{OutliersMultiplier: 2, StrongOutliersMultiplier: 3}},
+	}
+	storage := config.StorageParams{Dir: t.TempDir()}
+	store, err := collector.NewTierStore(storage)
+	if err != nil {
+		t.Fatalf("NewTierStore() error = %v", err)
+	}
+	history, err := analyser.NewRunHistory(storage)
+	if err != nil {
+		t.Fatalf("NewRunHistory() error = %v", err)
+	}
+
+	//A first run stores both sites, before the legacy one is archived
+	detector, err := New(appConfig)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	detector.History = history
+	if err := detector.Run(context.Background()); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if err := detector.Store(store); err != nil {
+		t.Fatalf("Store() error = %v", err)
+	}
+
+	appConfig.Datasets[1].Archived = true
+	detector, err = New(appConfig)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	detector.History = history
+	notified := []string{}
+	detector.Notify = func(report analyser.OutlierReport) { notified = append(notified, report.SiteId) }
+	sitesData, err := detector.Collect()
+	if err != nil {
+		t.Fatalf("Collect() error = %v", err)
+	}
+	if _, err := detector.Analyse(); err != nil {
+		t.Fatalf("Analyse() error = %v", err)
+	}
+	if len(sitesData) != 1 || sitesData[0].SiteId != "shop" || strings.Join(notified, ",") != "shop" {
+		t.Errorf("Collect() = %d sites and notified %v, want the shop site only", len(sitesData), notified)
+	}
+
+	archivedData, archivedReports, err := detector.Archived(store)
+	if err != nil {
+		t.Fatalf("Archived() error = %v", err)
+	}
+	if len(archivedData) != 1 || archivedData[0].SiteId != "legacy" || !archivedData[0].Archived || len(archivedData[0].Metrics) == 0 {
+		t.Errorf("Archived() data = %+v, want the stored legacy site", archivedData)
+	}
+	if len(archivedReports) != 1 || archivedReports[0].SiteId != "legacy" {
+		t.Errorf("Archived() reports = %+v, want the latest legacy report", archivedReports)
+	}
+}
+
+func TestDetector_seed(t *testing.T) {
+	appConfig := config.ApplicationConfig{
+		Datasets: []config.Dataset{
+			{SiteId: "shop", TimeAgo: "7d", TimeSteps: []string{"1d", "12h"}, OutliersDetectionMethod: "3-sigmas", MetricesList: []string{"all"}},
+			{SiteId: "blog", TimeAgo: "7d", TimeStep: "1d", OutliersDetectionMethod: "3-sigmas", MetricesList: []string{"all"}, Seed: 7},
+		},
+		Concurrency: 3,
+		Seed:        42,
+	}
+	collect := func(appConfig config.ApplicationConfig) []collector.SiteData {
+		detector, err := New(appConfig)
+		if err != nil {
+			t.Fatalf("New() error = %v", err)
+		}
+		sitesData, err := detector.Collect()
+		if err != nil {
+			t.Fatalf("Collect() error = %v", err)
+		}
+		return sitesData
+	}
+	values := func(siteData collector.SiteData) []float64 {
+		res := []float64{}
+		for _, metricData := range siteData.Metrics {
+			for _, attribute := range metricData.Attributes {
+				for _, stepData := range metricData.AttributeData[attribute] {
+					res = append(res, stepData.Value)
+				}
+			}
+		}
+		return res
+	}
+
+	//Runs with the same seed generate the same values whatever the concurrency, while every site and time step gets its own series
+	first, second := collect(appConfig), collect(appConfig)
+	for i := range first {
+		if !reflect.DeepEqual(values(first[i]), values(second[i])) {
+			t.Errorf("Collect() site %s (%s) values differ between runs with the same seed", first[i].SiteId, first[i].TimeStep)
+		}
+	}
+	if reflect.DeepEqual(values(first[0])[:7], values(first[2])[:7]) {
+		t.Errorf("Collect() sites shop and blog generated the same values")
+	}
+
+	//A site seed overrides the general one, which changes the values of the other sites only
+	appConfig.Seed = 43
+	third := collect(appConfig)
+	if reflect.DeepEqual(values(first[0]), values(third[0])) {
+		t.Errorf("Collect() site shop values kept with a different seed")
+	}
+	if !reflect.DeepEqual(values(first[2]), values(third[2])) {
+		t.Errorf("Collect() site blog values changed despite its own seed")
+	}
+}
+
+func TestDetector_Load(t *testing.T) {
+	t.Setenv("DETECTOR_TEST_KEY", hex.EncodeToString(make([]byte, 32)))
+	appConfig := config.ApplicationConfig{
+		Datasets: []config.Dataset{
+			{SiteId: "shop", TimeAgo: "14d", TimeSteps: []string{"1d", "12h"}, OutliersDetectionMethod: "3-sigmas", MetricesList: []string{"Revenue"}},
+			{SiteId: "vault", TimeAgo: "14d", TimeStep: "1d", OutliersDetectionMethod: "3-sigmas", MetricesList: []string{"Visits"}, EncryptionKey: "env:DETECTOR_TEST_KEY"},
+		},
+		DetectionMethods: config.DetectionMethodsParams{ThreeSigmas: config.ThreeSigmasParams{OutliersMultiplier: 2, StrongOutliersMultiplier: 3}},
+		Seed:             1,
+	}
+	collected, err := New(appConfig)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if err := collected.Run(context.Background()); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	dataRecords, _, err := collected.Records()
+	if err != nil {
+		t.Fatalf("Records() error = %v", err)
+	}
+	dataFile := filepath.Join(t.TempDir(), "data.json")
+	utils.WriteJsonStruct(dataRecords, dataFile)
+
+	//Loaded data, decrypted for the site with key, is analysed as the collected one
+	detector, err := New(appConfig)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	sitesData, err := detector.Load(dataFile)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if len(sitesData) != 3 || sitesData[2].SiteId != "vault" || len(sitesData[2].Metrics) != 1 {
+		t.Fatalf("Load() = %d sites, want shop/1d, shop/12h and the decrypted vault/1d", len(sitesData))
+	}
+	reports, err := detector.Analyse()
+	if err != nil {
+		t.Fatalf("Analyse() error = %v", err)
+	}
+	for i := range reports {
+		loadedResult, _ := json.Marshal(reports[i].Result)
+		collectedResult, _ := json.Marshal(collected.reports[i].Result)
+		if string(loadedResult) != string(collectedResult) {
+			t.Errorf("Analyse() site %s (%s) results of the loaded data differ from the collected ones", reports[i].SiteId, reports[i].TimeStep)
+		}
+	}
+
+	//Datasets missing from the file are returned without metrics along with their errors, while sites no longer configured are ignored
+	appConfig.Datasets = append([]config.Dataset{{SiteId: "blog", TimeAgo: "14d", TimeStep: "1d", OutliersDetectionMethod: "3-sigmas"}}, appConfig.Datasets[0])
+	detector, err = New(appConfig)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	sitesData, err = detector.Load(dataFile)
+	if runErrors, ok := err.(RunErrors); !ok || len(runErrors) != 1 {
+		t.Fatalf("Load() error = %v, want the missing blog site", err)
+	}
+	if len(sitesData) != 3 || sitesData[0].SiteId != "blog" || len(sitesData[0].Metrics) != 0 || len(sitesData[1].Metrics) != 1 {
+		t.Errorf("Load() = %+v, want blog without metrics and both shop time steps", sitesData)
+	}
+	if stats := detector.Stats(); stats.DatasetsProcessed != 3 {
+		t.Errorf("Stats() = %+v, want 3 datasets processed", stats)
+	}
+
+	if _, err := detector.Load(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Errorf("Load() of a missing file returned no error")
+	}
+
+	//Data piped through a reader, such as the standard input, is loaded the same way
+	encoded, _ := json.Marshal(dataRecords)
+	if sitesData, err = detector.LoadReader(bytes.NewReader(encoded)); len(sitesData) != 3 || len(sitesData[1].Metrics) != 1 {
+		t.Errorf("LoadReader() = %+v, %v, want blog without metrics and both shop time steps", sitesData, err)
+	}
+	if _, err := detector.LoadReader(strings.NewReader("not json")); err == nil {
+		t.Errorf("LoadReader() of invalid data returned no error")
+	}
+}
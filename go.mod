@@ -3,11 +3,18 @@ module github.com/ftfmtavares/anomalies-detector
 go 1.19
 
 require (
+	github.com/go-sql-driver/mysql v1.7.1
 	github.com/gorilla/mux v1.8.0
+	github.com/graphql-go/graphql v0.8.1
+	github.com/lib/pq v1.10.9
+	github.com/mattn/go-sqlite3 v1.14.22
+	github.com/segmentio/kafka-go v0.4.47
 	github.com/wcharczuk/go-chart/v2 v2.1.0
 )
 
 require (
 	github.com/golang/freetype v0.0.0-20170609003504-e2365dfdc4a0 // indirect
+	github.com/klauspost/compress v1.15.9 // indirect
+	github.com/pierrec/lz4/v4 v4.1.15 // indirect
 	golang.org/x/image v0.0.0-20220902085622-e7cb96979f69 // indirect
 )
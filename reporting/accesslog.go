@@ -0,0 +1,259 @@
+package reporting
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ftfmtavares/anomalies-detector/analyser"
+
+	"github.com/gorilla/mux"
+)
+
+//Var block defines the upper bounds of the latency (seconds) and response size (bytes) histogram buckets
+var (
+	latencyBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+	sizeBuckets    = []float64{100, 1000, 10000, 100000, 1000000, 10000000}
+)
+
+//RunStats provides the structure for the measures of the detection run served by the report server, exposed on /metrics along with its events
+//DatasetsProcessed field counts the collected datasets and time steps, DatasetsFailed field those failing to be collected or analysed
+//LastSuccess field is the end of the run if none failed, zero otherwise, while Finished field is the end of the run whatever its failures
+type RunStats struct {
+	DatasetsProcessed  int
+	DatasetsFailed     int
+	CollectionDuration time.Duration
+	DetectionDuration  time.Duration
+	LastSuccess        time.Time
+	Finished           time.Time
+}
+
+//eventLabels identifies the events of a site, time step, metric and severity counted together
+type eventLabels struct {
+	siteId   string
+	timeStep string
+	metric   string
+	severity string
+}
+
+//accessLogEntry provides the structure of each structured access log line
+type accessLogEntry struct {
+	Method    string  `json:"method"`
+	Path      string  `json:"path"`
+	Route     string  `json:"route"`
+	Status    int     `json:"status"`
+	LatencyMs float64 `json:"latencyMs"`
+	Bytes     int     `json:"bytes"`
+}
+
+//accessLog records every served request on the log and on the latency and response size histograms exposed on /metrics
+//Histograms are labelled by route template instead of path so their number stays bounded whatever the requested sites and metrics
+type accessLog struct {
+	mu        sync.Mutex
+	latencies map[requestLabels]*histogram
+	sizes     map[requestLabels]*histogram
+	logEntry  func(entry accessLogEntry)
+	now       func() time.Time
+}
+
+//requestLabels identifies the requests sharing the same histograms
+type requestLabels struct {
+	method string
+	route  string
+	status int
+}
+
+//histogram holds the cumulative observation counts of each bucket, their sum and their count
+type histogram struct {
+	bounds []float64
+	counts []int
+	sum    float64
+	count  int
+}
+
+//statusRecorder wraps a response writer recording its status and written bytes
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int
+}
+
+//newAccessLog creates an accessLog writing its entries as JSON lines on the standard logger
+func newAccessLog() *accessLog {
+	return &accessLog{
+		latencies: map[requestLabels]*histogram{},
+		sizes:     map[requestLabels]*histogram{},
+		logEntry: func(entry accessLogEntry) {
+			line, _ := json.Marshal(entry)
+			log.Printf("%s\n", line)
+		},
+		now: Clock.Now,
+	}
+}
+
+//middleware wraps an HTTP handler logging and measuring each of its requests
+func (accesses *accessLog) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		start := accesses.now()
+		recorder := &statusRecorder{ResponseWriter: res, status: http.StatusOK}
+		next.ServeHTTP(recorder, req)
+		accesses.record(req, recorder.status, recorder.bytes, accesses.now().Sub(start))
+	})
+}
+
+//record logs a served request and adds it to the histograms of its method, route and status
+func (accesses *accessLog) record(req *http.Request, status, bytes int, latency time.Duration) {
+	route := "unmatched"
+	if current := mux.CurrentRoute(req); current != nil {
+		if template, err := current.GetPathTemplate(); err == nil {
+			route = template
+		}
+	}
+	accesses.logEntry(accessLogEntry{Method: req.Method, Path: req.URL.Path, Route: route, Status: status, LatencyMs: float64(latency.Microseconds()) / 1000, Bytes: bytes})
+
+	labels := requestLabels{method: req.Method, route: route, status: status}
+	accesses.mu.Lock()
+	defer accesses.mu.Unlock()
+	if accesses.latencies[labels] == nil {
+		accesses.latencies[labels] = newHistogram(latencyBuckets)
+		accesses.sizes[labels] = newHistogram(sizeBuckets)
+	}
+	accesses.latencies[labels].observe(latency.Seconds())
+	accesses.sizes[labels].observe(float64(bytes))
+}
+
+//metricsHandler implements an HTTP response returning the histograms in the Prometheus text exposition format
+func (accesses *accessLog) metricsHandler(res http.ResponseWriter, req *http.Request) {
+	accesses.mu.Lock()
+	defer accesses.mu.Unlock()
+
+	res.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	res.WriteHeader(http.StatusOK)
+	writeHistograms(res, "report_http_request_duration_seconds", "Report server request latency in seconds.", accesses.latencies)
+	writeHistograms(res, "report_http_response_size_bytes", "Report server response size in bytes.", accesses.sizes)
+}
+
+//detectorMetricsHandler implements an HTTP response returning the request histograms followed by the measures of the detection run and the number of events of its reports
+//The run measures are left out if the server isn't given any, e.g. when serving data loaded from files
+func detectorMetricsHandler(accesses *accessLog, outlierReports []analyser.OutlierReport, run *RunStats) http.HandlerFunc {
+	return func(res http.ResponseWriter, req *http.Request) {
+		accesses.metricsHandler(res, req)
+
+		if run != nil {
+			writeMetric(res, "anomalies_datasets_processed_total", "counter", "Datasets and time steps collected by the detection run.", float64(run.DatasetsProcessed))
+			writeMetric(res, "anomalies_datasets_failed_total", "counter", "Datasets and time steps failing to be collected or analysed by the detection run.", float64(run.DatasetsFailed))
+			writeMetric(res, "anomalies_collection_duration_seconds", "gauge", "Duration of the collection of the detection run in seconds.", run.CollectionDuration.Seconds())
+			writeMetric(res, "anomalies_detection_duration_seconds", "gauge", "Duration of the detection of the detection run in seconds.", run.DetectionDuration.Seconds())
+			if !run.LastSuccess.IsZero() {
+				writeMetric(res, "anomalies_last_success_timestamp_seconds", "gauge", "Unix time of the end of the last detection run without failures.", float64(run.LastSuccess.UnixNano())/1e9)
+			}
+		}
+
+		//Counting the events of every site, time step, metric and severity, ordered by their labels so the output is stable
+		counts := map[eventLabels]int{}
+		for _, report := range outlierReports {
+			for _, event := range report.Result.Alarms {
+				counts[eventLabels{siteId: report.SiteId, timeStep: report.TimeStep, metric: event.Metric, severity: analyser.VerdictAlarm}]++
+			}
+			for _, event := range report.Result.Warnings {
+				counts[eventLabels{siteId: report.SiteId, timeStep: report.TimeStep, metric: event.Metric, severity: analyser.VerdictWarning}]++
+			}
+		}
+		labelsList := []eventLabels{}
+		for labels := range counts {
+			labelsList = append(labelsList, labels)
+		}
+		sort.Slice(labelsList, func(i, j int) bool {
+			a, b := labelsList[i], labelsList[j]
+			if a.siteId != b.siteId {
+				return a.siteId < b.siteId
+			}
+			if a.timeStep != b.timeStep {
+				return a.timeStep < b.timeStep
+			}
+			if a.metric != b.metric {
+				return a.metric < b.metric
+			}
+			return a.severity < b.severity
+		})
+		res.Write([]byte("# HELP anomalies_events Events detected by the detection run.\n# TYPE anomalies_events gauge\n"))
+		for _, labels := range labelsList {
+			res.Write([]byte(fmt.Sprintf("anomalies_events{site=\"%s\",time_step=\"%s\",metric=\"%s\",severity=\"%s\"} %d\n",
+				escapeLabel(labels.siteId), escapeLabel(labels.timeStep), escapeLabel(labels.metric), labels.severity, counts[labels])))
+		}
+	}
+}
+
+//writeMetric writes a metric without labels along with its help and type
+func writeMetric(res http.ResponseWriter, name, metricType, help string, value float64) {
+	res.Write([]byte(fmt.Sprintf("# HELP %s %s\n# TYPE %s %s\n%s %s\n", name, help, name, metricType, name, strconv.FormatFloat(value, 'g', -1, 64))))
+}
+
+//escapeLabel escapes the backslashes, quotes and line feeds of a label value
+func escapeLabel(value string) string {
+	return strings.NewReplacer("\\", "\\\\", "\"", "\\\"", "\n", "\\n").Replace(value)
+}
+
+//writeHistograms writes a family of histograms, ordered by their labels so the output is stable
+func writeHistograms(res http.ResponseWriter, name, help string, histograms map[requestLabels]*histogram) {
+	labelsList := []requestLabels{}
+	for labels := range histograms {
+		labelsList = append(labelsList, labels)
+	}
+	sort.Slice(labelsList, func(i, j int) bool {
+		if labelsList[i].route != labelsList[j].route {
+			return labelsList[i].route < labelsList[j].route
+		}
+		if labelsList[i].method != labelsList[j].method {
+			return labelsList[i].method < labelsList[j].method
+		}
+		return labelsList[i].status < labelsList[j].status
+	})
+
+	res.Write([]byte(fmt.Sprintf("# HELP %s %s\n# TYPE %s histogram\n", name, help, name)))
+	for _, labels := range labelsList {
+		hist := histograms[labels]
+		labelText := fmt.Sprintf("method=\"%s\",route=\"%s\",code=\"%d\"", labels.method, strings.ReplaceAll(labels.route, "\"", "\\\""), labels.status)
+		for i, bound := range hist.bounds {
+			res.Write([]byte(fmt.Sprintf("%s_bucket{%s,le=\"%s\"} %d\n", name, labelText, strconv.FormatFloat(bound, 'g', -1, 64), hist.counts[i])))
+		}
+		res.Write([]byte(fmt.Sprintf("%s_bucket{%s,le=\"+Inf\"} %d\n", name, labelText, hist.count)))
+		res.Write([]byte(fmt.Sprintf("%s_sum{%s} %s\n", name, labelText, strconv.FormatFloat(hist.sum, 'g', -1, 64))))
+		res.Write([]byte(fmt.Sprintf("%s_count{%s} %d\n", name, labelText, hist.count)))
+	}
+}
+
+//newHistogram creates an empty histogram with the given bucket upper bounds
+func newHistogram(bounds []float64) *histogram {
+	return &histogram{bounds: bounds, counts: make([]int, len(bounds))}
+}
+
+//observe adds a value to every bucket whose upper bound isn't below it
+func (hist *histogram) observe(value float64) {
+	for i, bound := range hist.bounds {
+		if value <= bound {
+			hist.counts[i]++
+		}
+	}
+	hist.sum += value
+	hist.count++
+}
+
+//WriteHeader records the response status before writing it
+func (recorder *statusRecorder) WriteHeader(status int) {
+	recorder.status = status
+	recorder.ResponseWriter.WriteHeader(status)
+}
+
+//Write records the written bytes before writing them
+func (recorder *statusRecorder) Write(data []byte) (int, error) {
+	written, err := recorder.ResponseWriter.Write(data)
+	recorder.bytes += written
+	return written, err
+}
@@ -0,0 +1,92 @@
+package reporting
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+func Test_accessLog_middleware(t *testing.T) {
+	timeRef := time.Date(2022, 9, 1, 0, 0, 0, 0, time.UTC)
+
+	//The access log clock is read at the start and end of each request, which take 30ms, 60ms and 90ms before the metrics one
+	accesses := newAccessLog()
+	entries := []accessLogEntry{}
+	accesses.logEntry = func(entry accessLogEntry) { entries = append(entries, entry) }
+	clock := []time.Duration{0, 30, 100, 160, 200, 290, 300, 301}
+	accesses.now = func() time.Time {
+		now := timeRef.Add(clock[0] * time.Millisecond)
+		clock = clock[1:]
+		return now
+	}
+
+	router := mux.NewRouter()
+	router.NotFoundHandler = accesses.middleware(http.NotFoundHandler())
+	router.Use(accesses.middleware)
+	router.PathPrefix("/report/{siteid}/{metric}").Subrouter().HandleFunc("", func(res http.ResponseWriter, req *http.Request) {
+		res.Write([]byte(strings.Repeat("x", 500)))
+	})
+	router.PathPrefix("/metrics").Subrouter().HandleFunc("", accesses.metricsHandler)
+
+	for _, path := range []string{"/report/shop/Revenue", "/report/shop/Visits", "/unknown"} {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	wantEntries := []accessLogEntry{
+		{Method: "GET", Path: "/report/shop/Revenue", Route: "/report/{siteid}/{metric}", Status: 200, LatencyMs: 30, Bytes: 500},
+		{Method: "GET", Path: "/report/shop/Visits", Route: "/report/{siteid}/{metric}", Status: 200, LatencyMs: 60, Bytes: 500},
+		{Method: "GET", Path: "/unknown", Route: "unmatched", Status: 404, LatencyMs: 90, Bytes: 19},
+	}
+	if !reflect.DeepEqual(entries, wantEntries) {
+		t.Errorf("accessLog entries = %v, want %v", entries, wantEntries)
+	}
+
+	res := httptest.NewRecorder()
+	router.ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body := res.Body.String()
+	for _, want := range []string{
+		"# TYPE report_http_request_duration_seconds histogram\n",
+		"report_http_request_duration_seconds_bucket{method=\"GET\",route=\"/report/{siteid}/{metric}\",code=\"200\",le=\"0.05\"} 1\n",
+		"report_http_request_duration_seconds_bucket{method=\"GET\",route=\"/report/{siteid}/{metric}\",code=\"200\",le=\"0.1\"} 2\n",
+		"report_http_request_duration_seconds_count{method=\"GET\",route=\"/report/{siteid}/{metric}\",code=\"200\"} 2\n",
+		"report_http_request_duration_seconds_count{method=\"GET\",route=\"unmatched\",code=\"404\"} 1\n",
+		"report_http_response_size_bytes_bucket{method=\"GET\",route=\"/report/{siteid}/{metric}\",code=\"200\",le=\"100\"} 0\n",
+		"report_http_response_size_bytes_bucket{method=\"GET\",route=\"/report/{siteid}/{metric}\",code=\"200\",le=\"1000\"} 2\n",
+		"report_http_response_size_bytes_sum{method=\"GET\",route=\"/report/{siteid}/{metric}\",code=\"200\"} 1000\n",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("metricsHandler() missing %q in\n%s", want, body)
+		}
+	}
+}
+
+func Test_detectorMetricsHandler(t *testing.T) {
+	_, reports := reportFixture()
+	run := &RunStats{DatasetsProcessed: 3, DatasetsFailed: 1, CollectionDuration: 1500 * time.Millisecond, DetectionDuration: 250 * time.Millisecond}
+
+	res := httptest.NewRecorder()
+	detectorMetricsHandler(newAccessLog(), reports, run)(res, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body := res.Body.String()
+	for _, want := range []string{
+		"# TYPE report_http_request_duration_seconds histogram\n",
+		"# TYPE anomalies_datasets_processed_total counter\nanomalies_datasets_processed_total 3\n",
+		"anomalies_datasets_failed_total 1\n",
+		"# TYPE anomalies_collection_duration_seconds gauge\nanomalies_collection_duration_seconds 1.5\n",
+		"anomalies_detection_duration_seconds 0.25\n",
+		"anomalies_events{site=\"shop\",time_step=\"1d\",metric=\"Revenue\",severity=\"alarm\"} 2\n" +
+			"anomalies_events{site=\"shop\",time_step=\"1d\",metric=\"Revenue\",severity=\"warning\"} 1\n" +
+			"anomalies_events{site=\"shop\",time_step=\"1h\",metric=\"Revenue\",severity=\"alarm\"} 1\n",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("detectorMetricsHandler() missing %q in\n%s", want, body)
+		}
+	}
+	if strings.Contains(body, "anomalies_last_success_timestamp_seconds") {
+		t.Errorf("detectorMetricsHandler() exposed the last success of a failed run")
+	}
+}
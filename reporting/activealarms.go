@@ -0,0 +1,65 @@
+package reporting
+
+import (
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/ftfmtavares/anomalies-detector/analyser"
+)
+
+//Const block defines the overall statuses of the active alarms endpoint, from the highest severity of the open events
+const (
+	StatusOperational = "operational"
+	StatusDegraded    = "degraded"
+	StatusMajor       = "major"
+)
+
+//ActiveAlarms provides the compact structure returned by the active alarms endpoint, meant to power status page widgets
+//Status field is "major" if any alarm is open, "degraded" if only warnings are and "operational" otherwise
+type ActiveAlarms struct {
+	Status    string        `json:"status"`
+	UpdatedAt time.Time     `json:"updatedAt"`
+	Alarms    []ActiveAlarm `json:"alarms"`
+}
+
+//ActiveAlarm holds an open event, Since field being when it was first reported and LastSeen field when it was last reported
+type ActiveAlarm struct {
+	Site      string    `json:"site"`
+	TimeStep  string    `json:"timeStep"`
+	Metric    string    `json:"metric"`
+	Attribute string    `json:"attribute"`
+	Severity  string    `json:"severity"`
+	Since     time.Time `json:"since"`
+	LastSeen  time.Time `json:"lastSeen"`
+}
+
+//activeAlarmsHandler implements an HTTP response returning the events left open by the tracked runs in JSON format, alarms first and then the most recent
+//The site query string optionally restricts them to a site, and any origin may read them so status pages can be hosted elsewhere
+func activeAlarmsHandler(store *EventStateStore) http.HandlerFunc {
+	return func(res http.ResponseWriter, req *http.Request) {
+		site := req.URL.Query().Get("site")
+		active := ActiveAlarms{Status: StatusOperational, UpdatedAt: Clock.Now().UTC(), Alarms: []ActiveAlarm{}}
+		for _, state := range store.Events() {
+			if state.Status == EventResolved || (site != "" && state.SiteId != site) {
+				continue
+			}
+			active.Alarms = append(active.Alarms, ActiveAlarm{Site: state.SiteId, TimeStep: state.TimeStep, Metric: state.Metric, Attribute: state.Attribute, Severity: state.Severity, Since: state.FirstSeen, LastSeen: state.LastSeen})
+			if state.Severity == analyser.VerdictAlarm {
+				active.Status = StatusMajor
+			} else if active.Status == StatusOperational {
+				active.Status = StatusDegraded
+			}
+		}
+		sort.SliceStable(active.Alarms, func(i, j int) bool {
+			if active.Alarms[i].Severity != active.Alarms[j].Severity {
+				return active.Alarms[i].Severity == analyser.VerdictAlarm
+			}
+			return active.Alarms[i].Since.After(active.Alarms[j].Since)
+		})
+
+		res.Header().Set("Access-Control-Allow-Origin", "*")
+		res.Header().Set("Cache-Control", "no-cache")
+		writeJson(res, http.StatusOK, active)
+	}
+}
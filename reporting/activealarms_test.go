@@ -0,0 +1,83 @@
+package reporting
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ftfmtavares/anomalies-detector/analyser"
+	"github.com/ftfmtavares/anomalies-detector/config"
+)
+
+func Test_activeAlarmsHandler(t *testing.T) {
+	timeRef := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	event := func(metric string) analyser.OutlierEvent {
+		return analyser.OutlierEvent{OutlierPeriodStart: timeRef, OutlierPeriodEnd: timeRef.AddDate(0, 0, 1), Metric: metric, Attribute: "Total"}
+	}
+	store, err := NewEventStateStore(config.EventStateParams{StateFile: filepath.Join(t.TempDir(), "events.json")})
+	if err != nil {
+		t.Fatalf("NewEventStateStore() error = %v", err)
+	}
+	if _, _, err := store.Track(analyser.OutlierReport{SiteId: "shop", TimeStep: "1d", Result: analyser.OutlierResults{Alarms: []analyser.OutlierEvent{event("Revenue")}, Warnings: []analyser.OutlierEvent{event("Visits")}}}, timeRef.AddDate(0, 0, 2)); err != nil {
+		t.Fatalf("Track() error = %v", err)
+	}
+	if _, _, err := store.Track(analyser.OutlierReport{SiteId: "blog", TimeStep: "1d", Result: analyser.OutlierResults{Warnings: []analyser.OutlierEvent{event("Visits")}}}, timeRef.AddDate(0, 0, 2)); err != nil {
+		t.Fatalf("Track() error = %v", err)
+	}
+	if _, _, err := store.Track(analyser.OutlierReport{SiteId: "blog", TimeStep: "1d"}, timeRef.AddDate(0, 0, 3)); err != nil {
+		t.Fatalf("Track() error = %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		query       string
+		wantStatus  string
+		wantMetrics []string
+	}{
+		{
+			name:        "All open events, alarms first",
+			wantStatus:  StatusMajor,
+			wantMetrics: []string{"Revenue", "Visits"},
+		},
+		{
+			name:        "Site without open events",
+			query:       "?site=blog",
+			wantStatus:  StatusOperational,
+			wantMetrics: []string{},
+		},
+		{
+			name:        "Unknown site",
+			query:       "?site=none",
+			wantStatus:  StatusOperational,
+			wantMetrics: []string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := httptest.NewRecorder()
+			activeAlarmsHandler(store)(res, httptest.NewRequest(http.MethodGet, "/api/active-alarms"+tt.query, nil))
+			if res.Code != http.StatusOK || res.Header().Get("Access-Control-Allow-Origin") != "*" {
+				t.Fatalf("activeAlarmsHandler() status = %d, allowed origin %q", res.Code, res.Header().Get("Access-Control-Allow-Origin"))
+			}
+			var got ActiveAlarms
+			if err := json.Unmarshal(res.Body.Bytes(), &got); err != nil {
+				t.Fatalf("activeAlarmsHandler() invalid JSON - %v", err)
+			}
+			metrics := []string{}
+			for _, alarm := range got.Alarms {
+				metrics = append(metrics, alarm.Metric)
+			}
+			if got.Status != tt.wantStatus || len(metrics) != len(tt.wantMetrics) {
+				t.Fatalf("activeAlarmsHandler() = %s with %v, want %s with %v", got.Status, metrics, tt.wantStatus, tt.wantMetrics)
+			}
+			for i := range metrics {
+				if metrics[i] != tt.wantMetrics[i] {
+					t.Errorf("activeAlarmsHandler() = %v, want %v", metrics, tt.wantMetrics)
+				}
+			}
+		})
+	}
+}
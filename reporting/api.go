@@ -0,0 +1,409 @@
+package reporting
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ftfmtavares/anomalies-detector/analyser"
+	"github.com/ftfmtavares/anomalies-detector/collector"
+
+	"github.com/gorilla/mux"
+)
+
+//Const block defines the page size limits used by the series endpoint
+const (
+	defaultSeriesLimit = 100
+	maxSeriesLimit     = 1000
+)
+
+//SeriesPage provides the structure returned by the series endpoint
+//NextCursor field is only filled when more attribute series are available after the returned page
+type SeriesPage struct {
+	SiteId     string       `json:"siteId"`
+	Metric     string       `json:"metric"`
+	Unit       string       `json:"unit"`
+	Series     []SeriesData `json:"series"`
+	NextCursor string       `json:"nextCursor,omitempty"`
+}
+
+//SeriesData holds the time steps of a single attribute/sub-values combination
+type SeriesData struct {
+	Attribute string                   `json:"attribute"`
+	Data      []collector.TimeStepData `json:"data"`
+}
+
+//seriesQuery holds the parsed query string parameters of the series endpoint
+//A zero from or to time means the range is open on that side, while percent expresses the values as a percentage of the baseline of their series and zscore as their z-score
+//A non zero downsample reduces every series to that number of points at most
+type seriesQuery struct {
+	attributes []string
+	from       time.Time
+	to         time.Time
+	limit      int
+	offset     int
+	percent    bool
+	zscore     bool
+	downsample int
+}
+
+//seriesHandler implements an HTTP response returning the collected data of a given site and metric in JSON format
+//Supported query strings are attributes, or attribute as on the chart endpoint (prefix match, repeated or comma separated), from and to (RFC3339), limit and cursor for pagination over attributes
+//resolution to pick one of the time steps of a site analysed at several resolutions, scale, "absolute" (default) or "percent" of the series baseline, view, "values" (default) or "zscore",
+//and downsample to a number of points
+func seriesHandler(sitesData []collector.SiteData) http.HandlerFunc {
+	return func(res http.ResponseWriter, req *http.Request) {
+		siteUrl := mux.Vars(req)["siteid"]
+		metricUrl := mux.Vars(req)["metric"]
+
+		query, err := parseSeriesQuery(req)
+		if err != nil {
+			writeJsonError(res, http.StatusBadRequest, err)
+			return
+		}
+
+		metricData, found := findMetric(sitesData, siteUrl, req.URL.Query().Get("resolution"), metricUrl)
+		if !found {
+			writeJsonError(res, http.StatusNotFound, errors.New("site or metric not found"))
+			return
+		}
+
+		page := filterSeries(metricData, query)
+		page.SiteId = siteUrl
+		writeJson(res, http.StatusOK, page)
+	}
+}
+
+//parseSeriesQuery reads and validates the series endpoint query string parameters
+func parseSeriesQuery(req *http.Request) (seriesQuery, error) {
+	values := req.URL.Query()
+	query := seriesQuery{limit: defaultSeriesLimit}
+
+	for _, value := range append(values["attributes"], values["attribute"]...) {
+		for _, attr := range strings.Split(value, ",") {
+			if attr = strings.TrimSpace(attr); attr != "" {
+				query.attributes = append(query.attributes, attr)
+			}
+		}
+	}
+
+	var err error
+	if from := values.Get("from"); from != "" {
+		if query.from, err = time.Parse(time.RFC3339, from); err != nil {
+			return query, errors.New("invalid from parameter, RFC3339 expected")
+		}
+	}
+	if to := values.Get("to"); to != "" {
+		if query.to, err = time.Parse(time.RFC3339, to); err != nil {
+			return query, errors.New("invalid to parameter, RFC3339 expected")
+		}
+	}
+	if !query.from.IsZero() && !query.to.IsZero() && !query.from.Before(query.to) {
+		return query, errors.New("from parameter must be before to parameter")
+	}
+
+	if limit := values.Get("limit"); limit != "" {
+		if query.limit, err = strconv.Atoi(limit); err != nil || query.limit <= 0 {
+			return query, errors.New("invalid limit parameter, positive integer expected")
+		}
+		if query.limit > maxSeriesLimit {
+			query.limit = maxSeriesLimit
+		}
+	}
+
+	if cursor := values.Get("cursor"); cursor != "" {
+		if query.offset, err = decodeCursor(cursor); err != nil {
+			return query, errors.New("invalid cursor parameter")
+		}
+	}
+
+	if downsample := values.Get("downsample"); downsample != "" {
+		if query.downsample, err = strconv.Atoi(downsample); err != nil || query.downsample < collector.MinDownsamplePoints {
+			return query, fmt.Errorf("invalid downsample parameter, integer of at least %d expected", collector.MinDownsamplePoints)
+		}
+	}
+
+	if query.percent, err = parseScale(req); err != nil {
+		return query, err
+	}
+	if query.zscore, err = parseView(req); err != nil {
+		return query, err
+	}
+	if query.percent && query.zscore {
+		return query, errors.New("percent scale can't be used with the zscore view")
+	}
+
+	return query, nil
+}
+
+//filterSeries applies the attributes and time range filters to the given metric data and returns the requested page
+//Pagination is done over the ordered attributes list so each series is always returned whole within the time range
+//Percentages and z-scores are computed over the whole series, so the baseline doesn't depend on the requested time range, while downsampling only applies to that range
+func filterSeries(metricData collector.MetricData, query seriesQuery) SeriesPage {
+	if query.percent {
+		metricData = collector.PercentOfBaseline(metricData)
+	}
+	if query.zscore {
+		metricData = collector.ZScores(metricData)
+	}
+	page := SeriesPage{
+		Metric: metricData.Metric,
+		Unit:   metricData.Unit,
+		Series: []SeriesData{},
+	}
+
+	//Selecting the attribute/sub-values combinations matching the attributes filter
+	attributes := []string{}
+	for _, attribute := range metricData.Attributes {
+		if matchAttribute(attribute, query.attributes) {
+			attributes = append(attributes, attribute)
+		}
+	}
+
+	//Slicing the selected attributes according to the cursor and limit
+	if query.offset >= len(attributes) {
+		return page
+	}
+	end := query.offset + query.limit
+	if end < len(attributes) {
+		page.NextCursor = encodeCursor(end)
+	} else {
+		end = len(attributes)
+	}
+
+	//Copying only the time steps within the requested time range
+	for _, attribute := range attributes[query.offset:end] {
+		series := SeriesData{Attribute: attribute, Data: []collector.TimeStepData{}}
+		for _, stepData := range metricData.AttributeData[attribute] {
+			if !query.from.IsZero() && stepData.DateStart.Before(query.from) {
+				continue
+			}
+			if !query.to.IsZero() && !stepData.DateStart.Before(query.to) {
+				continue
+			}
+			series.Data = append(series.Data, stepData)
+		}
+		series.Data = collector.DownsampleSeries(series.Data, query.downsample)
+		page.Series = append(page.Series, series)
+	}
+
+	return page
+}
+
+//prefersJson checks if the Accept header of a request prefers JSON over a PNG image, following the quality values of its media ranges
+//Requests without Accept header, or accepting both equally, get the image
+func prefersJson(req *http.Request) bool {
+	quality := func(mediaType string) float64 {
+		best, bestSpecificity := 0.0, -1
+		for _, mediaRange := range strings.Split(req.Header.Get("Accept"), ",") {
+			params := strings.Split(mediaRange, ";")
+			accepted := strings.ToLower(strings.TrimSpace(params[0]))
+			specificity := 0
+			switch {
+			case accepted == mediaType:
+				specificity = 2
+			case accepted == strings.Split(mediaType, "/")[0]+"/*":
+				specificity = 1
+			case accepted == "*/*":
+			default:
+				continue
+			}
+			q := 1.0
+			for _, param := range params[1:] {
+				if name, value, found := strings.Cut(strings.TrimSpace(param), "="); found && strings.TrimSpace(name) == "q" {
+					if parsed, err := strconv.ParseFloat(strings.TrimSpace(value), 64); err == nil {
+						q = parsed
+					}
+				}
+			}
+			if specificity > bestSpecificity {
+				best, bestSpecificity = q, specificity
+			}
+		}
+		return best
+	}
+	return quality("application/json") > quality("image/png")
+}
+
+//parseScale reads the scale query string parameter of the chart and series endpoints, returning true for "percent" of the baseline and false for "absolute" or no scale
+func parseScale(req *http.Request) (bool, error) {
+	switch req.URL.Query().Get("scale") {
+	case "", "absolute":
+		return false, nil
+	case "percent":
+		return true, nil
+	default:
+		return false, errors.New("invalid scale parameter, absolute or percent expected")
+	}
+}
+
+//parseView reads the view query string parameter of the chart and series endpoints, returning true for "zscore" and false for "values" or no view
+func parseView(req *http.Request) (bool, error) {
+	switch req.URL.Query().Get("view") {
+	case "", "values":
+		return false, nil
+	case "zscore":
+		return true, nil
+	default:
+		return false, errors.New("invalid view parameter, values or zscore expected")
+	}
+}
+
+//chartSites returns the collected data with a metric of a site shaped as requested by the query, the other sites and metrics being shared
+//The metric is expressed as a percentage of its baseline or as z-scores over the whole series, then limited to the time range and downsampled, the site period following that range
+func chartSites(sitesData []collector.SiteData, siteId, timeStep, metric string, query seriesQuery) []collector.SiteData {
+	res := make([]collector.SiteData, len(sitesData))
+	copy(res, sitesData)
+	for i, siteData := range res {
+		if siteData.SiteId != siteId || (timeStep != "" && siteData.TimeStep != timeStep) {
+			continue
+		}
+		metrics := make([]collector.MetricData, len(siteData.Metrics))
+		for j, metricData := range siteData.Metrics {
+			if metricData.Metric == metric {
+				if query.percent {
+					metricData = collector.PercentOfBaseline(metricData)
+				}
+				if query.zscore {
+					metricData = collector.ZScores(metricData)
+				}
+				if !query.from.IsZero() || !query.to.IsZero() {
+					metricData = collector.TimeRange(metricData, query.from, query.to)
+				}
+				if query.downsample != 0 {
+					metricData = collector.Downsample(metricData, query.downsample)
+				}
+			}
+			metrics[j] = metricData
+		}
+		res[i].Metrics = metrics
+		if !query.from.IsZero() && res[i].DateStart.Before(query.from) {
+			res[i].DateStart = query.from
+		}
+		if !query.to.IsZero() && res[i].DateEnd.After(query.to) {
+			res[i].DateEnd = query.to
+		}
+	}
+	return res
+}
+
+//clipReports returns copies of the reports keeping only the events overlapping the given time range, their periods cut to it so the chart annotations stay within the shown range
+//A zero from or to time leaves the range open on that side, the reports being returned as they are if both are
+func clipReports(outlierReports []analyser.OutlierReport, from, to time.Time) []analyser.OutlierReport {
+	if from.IsZero() && to.IsZero() {
+		return outlierReports
+	}
+	clip := func(events []analyser.OutlierEvent) []analyser.OutlierEvent {
+		res := []analyser.OutlierEvent{}
+		for _, event := range events {
+			if (!from.IsZero() && !event.OutlierPeriodEnd.After(from)) || (!to.IsZero() && !event.OutlierPeriodStart.Before(to)) {
+				continue
+			}
+			if !from.IsZero() && event.OutlierPeriodStart.Before(from) {
+				event.OutlierPeriodStart = from
+			}
+			if !to.IsZero() && event.OutlierPeriodEnd.After(to) {
+				event.OutlierPeriodEnd = to
+			}
+			res = append(res, event)
+		}
+		return res
+	}
+	res := make([]analyser.OutlierReport, len(outlierReports))
+	for i, report := range outlierReports {
+		report.Result.Alarms = clip(report.Result.Alarms)
+		report.Result.Warnings = clip(report.Result.Warnings)
+		if report.Shadow != nil {
+			shadow := *report.Shadow
+			shadow.Alarms = clip(shadow.Alarms)
+			shadow.Warnings = clip(shadow.Warnings)
+			report.Shadow = &shadow
+		}
+		res[i] = report
+	}
+	return res
+}
+
+//matchAttribute checks if an attribute/sub-values combination is selected by the given attributes filter
+//The comparison is a case insensitive prefix match, while an empty filter or "all" selects every combination
+func matchAttribute(attribute string, filters []string) bool {
+	if len(filters) == 0 {
+		return true
+	}
+	for _, filter := range filters {
+		if strings.ToLower(filter) == "all" || strings.HasPrefix(strings.ToLower(attribute), strings.ToLower(filter)) {
+			return true
+		}
+	}
+	return false
+}
+
+//findMetric looks for the metric data of a given site, time step and metric
+func findMetric(sitesData []collector.SiteData, siteId, timeStep, metric string) (collector.MetricData, bool) {
+	siteData, found := findSite(sitesData, siteId, timeStep)
+	if !found {
+		return collector.MetricData{}, false
+	}
+	for _, metricData := range siteData.Metrics {
+		if metricData.Metric == metric {
+			return metricData, true
+		}
+	}
+	return collector.MetricData{}, false
+}
+
+//findSite looks for the collected data of a given site at a given time step, the first resolution found being returned if no time step is given
+func findSite(sitesData []collector.SiteData, siteId, timeStep string) (collector.SiteData, bool) {
+	for _, siteData := range sitesData {
+		if siteData.SiteId == siteId && (timeStep == "" || siteData.TimeStep == timeStep) {
+			return siteData, true
+		}
+	}
+	return collector.SiteData{}, false
+}
+
+//multiResolution checks if a given site was analysed at more than one time step
+func multiResolution(sitesData []collector.SiteData, siteId string) bool {
+	count := 0
+	for _, siteData := range sitesData {
+		if siteData.SiteId == siteId {
+			count++
+		}
+	}
+	return count > 1
+}
+
+//encodeCursor converts an attributes offset into an opaque pagination cursor
+func encodeCursor(offset int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.Itoa(offset)))
+}
+
+//decodeCursor converts an opaque pagination cursor back into an attributes offset
+func decodeCursor(cursor string) (int, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, err
+	}
+	offset, err := strconv.Atoi(string(raw))
+	if err != nil || offset < 0 {
+		return 0, errors.New("invalid cursor")
+	}
+	return offset, nil
+}
+
+//writeJson writes any given variable as a JSON HTTP response with the given status code
+func writeJson(res http.ResponseWriter, status int, v interface{}) {
+	res.Header().Set("Content-Type", "application/json")
+	res.WriteHeader(status)
+	json.NewEncoder(res).Encode(v)
+}
+
+//writeJsonError writes an error message as a JSON HTTP response with the given status code
+func writeJsonError(res http.ResponseWriter, status int, err error) {
+	writeJson(res, status, map[string]string{"error": err.Error()})
+}
@@ -0,0 +1,213 @@
+package reporting
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ftfmtavares/anomalies-detector/analyser"
+	"github.com/ftfmtavares/anomalies-detector/collector"
+)
+
+func Test_filterSeries(t *testing.T) {
+	timeRef := time.Now()
+
+	metricData := collector.MetricData{
+		Metric:     "metric",
+		Unit:       "unit",
+		Attributes: []string{"Total", "Attribute1>Sub1", "Attribute1>Sub2", "Attribute2>Sub1"},
+		AttributeData: map[string][]collector.TimeStepData{
+			"Total":           {{DateStart: timeRef, Value: 10, Samples: 100}, {DateStart: timeRef.AddDate(0, 0, 1), Value: 20, Samples: 100}},
+			"Attribute1>Sub1": {{DateStart: timeRef, Value: 5, Samples: 50}, {DateStart: timeRef.AddDate(0, 0, 1), Value: 10, Samples: 50}},
+			"Attribute1>Sub2": {{DateStart: timeRef, Value: 5, Samples: 50}, {DateStart: timeRef.AddDate(0, 0, 1), Value: 10, Samples: 50}},
+			"Attribute2>Sub1": {{DateStart: timeRef, Value: 10, Samples: 100}, {DateStart: timeRef.AddDate(0, 0, 1), Value: 20, Samples: 100}},
+		},
+	}
+
+	tests := []struct {
+		name  string
+		query seriesQuery
+		want  SeriesPage
+	}{
+		{
+			name:  "Filter by attribute prefix",
+			query: seriesQuery{attributes: []string{"attribute1"}, limit: defaultSeriesLimit},
+			want: SeriesPage{
+				Metric: "metric",
+				Unit:   "unit",
+				Series: []SeriesData{
+					{Attribute: "Attribute1>Sub1", Data: metricData.AttributeData["Attribute1>Sub1"]},
+					{Attribute: "Attribute1>Sub2", Data: metricData.AttributeData["Attribute1>Sub2"]},
+				},
+			},
+		},
+		{
+			name:  "Filter by time range",
+			query: seriesQuery{attributes: []string{"Total"}, from: timeRef.AddDate(0, 0, 1), limit: defaultSeriesLimit},
+			want: SeriesPage{
+				Metric: "metric",
+				Unit:   "unit",
+				Series: []SeriesData{
+					{Attribute: "Total", Data: metricData.AttributeData["Total"][1:]},
+				},
+			},
+		},
+		{
+			name:  "Percent of the baseline of the whole series",
+			query: seriesQuery{attributes: []string{"Total"}, from: timeRef.AddDate(0, 0, 1), limit: defaultSeriesLimit, percent: true},
+			want: SeriesPage{
+				Metric: "metric",
+				Unit:   collector.PercentOfBaselineUnit,
+				Series: []SeriesData{
+					{Attribute: "Total", Data: []collector.TimeStepData{{DateStart: timeRef.AddDate(0, 0, 1), Value: 400.0 / 3, Samples: 100}}},
+				},
+			},
+		},
+		{
+			name:  "Z-scores of the whole series",
+			query: seriesQuery{attributes: []string{"Total"}, from: timeRef.AddDate(0, 0, 1), limit: defaultSeriesLimit, zscore: true},
+			want: SeriesPage{
+				Metric: "metric",
+				Unit:   collector.ZScoreUnit,
+				Series: []SeriesData{
+					{Attribute: "Total", Data: []collector.TimeStepData{{DateStart: timeRef.AddDate(0, 0, 1), Value: 1, Samples: 100}}},
+				},
+			},
+		},
+		{
+			name:  "Downsampling keeping series shorter than the points whole",
+			query: seriesQuery{attributes: []string{"Total"}, limit: defaultSeriesLimit, downsample: 3},
+			want: SeriesPage{
+				Metric: "metric",
+				Unit:   "unit",
+				Series: []SeriesData{
+					{Attribute: "Total", Data: metricData.AttributeData["Total"]},
+				},
+			},
+		},
+		{
+			name:  "First page with cursor to the next one",
+			query: seriesQuery{limit: 3},
+			want: SeriesPage{
+				Metric: "metric",
+				Unit:   "unit",
+				Series: []SeriesData{
+					{Attribute: "Total", Data: metricData.AttributeData["Total"]},
+					{Attribute: "Attribute1>Sub1", Data: metricData.AttributeData["Attribute1>Sub1"]},
+					{Attribute: "Attribute1>Sub2", Data: metricData.AttributeData["Attribute1>Sub2"]},
+				},
+				NextCursor: encodeCursor(3),
+			},
+		},
+		{
+			name:  "Last page without cursor",
+			query: seriesQuery{limit: 3, offset: 3},
+			want: SeriesPage{
+				Metric: "metric",
+				Unit:   "unit",
+				Series: []SeriesData{
+					{Attribute: "Attribute2>Sub1", Data: metricData.AttributeData["Attribute2>Sub1"]},
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := filterSeries(metricData, tt.query); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("filterSeries() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_findMetric(t *testing.T) {
+	sitesData := []collector.SiteData{
+		{SiteId: "site", TimeStep: "1h", Metrics: []collector.MetricData{{Metric: "Revenue", Unit: "hourly"}}},
+		{SiteId: "site", TimeStep: "1d", Metrics: []collector.MetricData{{Metric: "Revenue", Unit: "daily"}}},
+	}
+
+	tests := []struct {
+		name      string
+		timeStep  string
+		wantUnit  string
+		wantFound bool
+	}{
+		{name: "First resolution when none is given", timeStep: "", wantUnit: "hourly", wantFound: true},
+		{name: "Given resolution", timeStep: "1d", wantUnit: "daily", wantFound: true},
+		{name: "Unknown resolution", timeStep: "1w", wantFound: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, found := findMetric(sitesData, "site", tt.timeStep, "Revenue")
+			if found != tt.wantFound || got.Unit != tt.wantUnit {
+				t.Errorf("findMetric() = %v, %v, want unit %s, %v", got, found, tt.wantUnit, tt.wantFound)
+			}
+		})
+	}
+}
+
+func Test_prefersJson(t *testing.T) {
+	tests := []struct {
+		name   string
+		accept string
+		want   bool
+	}{
+		{name: "No Accept header", accept: "", want: false},
+		{name: "JSON only", accept: "application/json", want: true},
+		{name: "Browser defaults", accept: "text/html,application/xhtml+xml,image/webp,*/*;q=0.8", want: false},
+		{name: "JSON preferred over images", accept: "image/*;q=0.5, application/json", want: true},
+		{name: "PNG preferred over JSON", accept: "application/json;q=0.9, image/png", want: false},
+		{name: "Equal preference", accept: "application/json, image/png", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/report/shop/Revenue", nil)
+			if tt.accept != "" {
+				req.Header.Set("Accept", tt.accept)
+			}
+			if got := prefersJson(req); got != tt.want {
+				t.Errorf("prefersJson() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	//The chart endpoint answers with the series of the charted attributes
+	server := newTestReportServer(t)
+	req, _ := http.NewRequest(http.MethodGet, server.URL+"/report/shop/Revenue?attribute=browser>edge&resolution=1h", nil)
+	req.Header.Set("Accept", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("GET error = %v", err)
+	}
+	defer resp.Body.Close()
+	var page SeriesPage
+	if err := json.NewDecoder(resp.Body).Decode(&page); err != nil || resp.Header.Get("Vary") != "Accept" {
+		t.Fatalf("GET error = %v, vary = %s", err, resp.Header.Get("Vary"))
+	}
+	if page.SiteId != "shop" || len(page.Series) != 1 || page.Series[0].Attribute != "Browser>Edge" || len(page.Series[0].Data) != 48 {
+		t.Errorf("GET chart as JSON = %+v", page)
+	}
+}
+
+func Test_clipReports(t *testing.T) {
+	timeRef := time.Date(2022, 9, 1, 0, 0, 0, 0, time.UTC)
+	event := func(start, end int) analyser.OutlierEvent {
+		return analyser.OutlierEvent{OutlierPeriodStart: timeRef.AddDate(0, 0, start), OutlierPeriodEnd: timeRef.AddDate(0, 0, end), Metric: "Revenue", Attribute: "Total"}
+	}
+	reports := []analyser.OutlierReport{{SiteId: "shop", TimeStep: "1d", Result: analyser.OutlierResults{
+		Alarms:   []analyser.OutlierEvent{event(0, 2), event(5, 6)},
+		Warnings: []analyser.OutlierEvent{event(3, 5)},
+	}, Shadow: &analyser.OutlierResults{Alarms: []analyser.OutlierEvent{event(9, 10)}}}}
+
+	got := clipReports(reports, timeRef.AddDate(0, 0, 1), timeRef.AddDate(0, 0, 4))
+	want := analyser.OutlierResults{Alarms: []analyser.OutlierEvent{event(1, 2)}, Warnings: []analyser.OutlierEvent{event(3, 4)}}
+	if !reflect.DeepEqual(got[0].Result, want) || len(got[0].Shadow.Alarms) != 0 {
+		t.Errorf("clipReports() = %v and shadow %v, want %v and no shadow alarm", got[0].Result, got[0].Shadow.Alarms, want)
+	}
+	if len(reports[0].Result.Alarms) != 2 || len(reports[0].Shadow.Alarms) != 1 {
+		t.Errorf("clipReports() changed the given reports")
+	}
+}
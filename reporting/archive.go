@@ -0,0 +1,99 @@
+package reporting
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"time"
+
+	"github.com/ftfmtavares/anomalies-detector/analyser"
+	"github.com/ftfmtavares/anomalies-detector/collector"
+	"github.com/ftfmtavares/anomalies-detector/config"
+	"github.com/ftfmtavares/anomalies-detector/utils"
+
+	"github.com/wcharczuk/go-chart/v2"
+)
+
+//unsafeFileChars matches the characters replaced when attribute paths are used as file names
+var unsafeFileChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+//ArchiveCharts renders and stores the charts of a run into a dated directory, so historical alerts keep their visual context after raw data is pruned
+//A chart is stored for every metric of every site, plus one for each alarmed attribute, as <baseDir>/<run time>/<site>/<metric>[_<attribute>].png
+//Sites analysed at several time steps get one directory per resolution, as <site>_<time step>
+//Charts are drawn with the locale of their site configuration, if any, while encrypted sites are skipped since charts are stored in plain
+//It returns the run directory where the charts were stored
+func ArchiveCharts(sitesData []collector.SiteData, outlierReports []analyser.OutlierReport, datasets []config.Dataset, baseDir string, runTime time.Time) (string, error) {
+	runDir := filepath.Join(baseDir, runTime.Format("2006-01-02T150405"))
+
+	for _, siteData := range sitesData {
+		if siteEncrypted(datasets, siteData.SiteId) {
+			continue
+		}
+		siteDirName := siteData.SiteId
+		if multiResolution(sitesData, siteData.SiteId) {
+			siteDirName = fmt.Sprintf("%s_%s", siteData.SiteId, siteData.TimeStep)
+		}
+		siteDir := filepath.Join(runDir, unsafeFileChars.ReplaceAllString(siteDirName, "_"))
+		if err := utils.Files.MkdirAll(siteDir); err != nil {
+			return runDir, err
+		}
+
+		//Listing the alarmed attributes of each metric only once, even if they alarmed several times
+		alarmedAttributes := map[string][]string{}
+		for _, report := range outlierReports {
+			if !reportMatchesSite(report, siteData) {
+				continue
+			}
+			for _, alarm := range report.Result.Alarms {
+				if !containsString(alarmedAttributes[alarm.Metric], alarm.Attribute) {
+					alarmedAttributes[alarm.Metric] = append(alarmedAttributes[alarm.Metric], alarm.Attribute)
+				}
+			}
+		}
+
+		locale := siteLocale(datasets, siteData.SiteId)
+		for _, metricData := range siteData.Metrics {
+			metricFile := unsafeFileChars.ReplaceAllString(metricData.Metric, "_")
+			if err := archiveChart(sitesData, outlierReports, siteData.SiteId, siteData.TimeStep, metricData.Metric, nil, locale, filepath.Join(siteDir, metricFile+".png")); err != nil {
+				return runDir, err
+			}
+			for _, attribute := range alarmedAttributes[metricData.Metric] {
+				attributeFile := fmt.Sprintf("%s_%s.png", metricFile, unsafeFileChars.ReplaceAllString(attribute, "_"))
+				if err := archiveChart(sitesData, outlierReports, siteData.SiteId, siteData.TimeStep, metricData.Metric, []string{attribute}, locale, filepath.Join(siteDir, attributeFile)); err != nil {
+					return runDir, err
+				}
+			}
+		}
+	}
+
+	return runDir, nil
+}
+
+//siteEncrypted tells if the data of a site is encrypted at rest according to its configuration
+func siteEncrypted(datasets []config.Dataset, siteId string) bool {
+	for _, dataset := range datasets {
+		if dataset.SiteId == siteId && dataset.EncryptionKey != "" {
+			return true
+		}
+	}
+	return false
+}
+
+//archiveChart renders a single chart into the given file
+func archiveChart(sitesData []collector.SiteData, outlierReports []analyser.OutlierReport, siteId, timeStep, metric string, attributes []string, locale *config.LocaleParams, fileName string) error {
+	graph, found := buildChart(sitesData, outlierReports, siteId, timeStep, metric, attributes, locale)
+	if !found {
+		return fmt.Errorf("no data for %s - %s", siteId, metric)
+	}
+
+	f, err := utils.Files.Create(fileName)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if err := graph.Render(chart.PNG, f); err != nil {
+		return fmt.Errorf("rendering %s - %s", fileName, err.Error())
+	}
+	return nil
+}
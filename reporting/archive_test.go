@@ -0,0 +1,89 @@
+package reporting
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ftfmtavares/anomalies-detector/analyser"
+	"github.com/ftfmtavares/anomalies-detector/collector"
+	"github.com/ftfmtavares/anomalies-detector/config"
+)
+
+func TestArchiveCharts(t *testing.T) {
+	timeRef := time.Date(2022, 9, 1, 0, 0, 0, 0, time.UTC)
+
+	data := []collector.TimeStepData{}
+	for i := 0; i < 10; i++ {
+		data = append(data, collector.TimeStepData{DateStart: timeRef.AddDate(0, 0, i), Value: float64(100 + i*i), Samples: 100})
+	}
+	sitesData := []collector.SiteData{
+		{
+			SiteId:    "site",
+			DateStart: timeRef,
+			DateEnd:   timeRef.AddDate(0, 0, 10),
+			Metrics: []collector.MetricData{
+				{
+					Metric:        "Revenue",
+					Unit:          "EUR",
+					Attributes:    []string{"Total", "Browser>Chrome"},
+					AttributeData: map[string][]collector.TimeStepData{"Total": data, "Browser>Chrome": data},
+				},
+			},
+		},
+		{
+			SiteId:    "secret",
+			DateStart: timeRef,
+			DateEnd:   timeRef.AddDate(0, 0, 10),
+			Metrics: []collector.MetricData{
+				{
+					Metric:        "Revenue",
+					Attributes:    []string{"Total"},
+					AttributeData: map[string][]collector.TimeStepData{"Total": data},
+				},
+			},
+		},
+	}
+	datasets := []config.Dataset{{SiteId: "site"}, {SiteId: "secret", EncryptionKey: "env:SECRET_KEY"}}
+	reports := []analyser.OutlierReport{
+		{
+			SiteId:   "site",
+			TimeAgo:  "10d",
+			TimeStep: "1d",
+			Result: analyser.OutlierResults{
+				Alarms: []analyser.OutlierEvent{
+					{OutlierPeriodStart: timeRef.AddDate(0, 0, 8), OutlierPeriodEnd: timeRef.AddDate(0, 0, 10), Metric: "Revenue", Attribute: "Browser>Chrome"},
+					{OutlierPeriodStart: timeRef.AddDate(0, 0, 2), OutlierPeriodEnd: timeRef.AddDate(0, 0, 3), Metric: "Revenue", Attribute: "Browser>Chrome"},
+				},
+			},
+		},
+	}
+
+	baseDir := t.TempDir()
+	runDir, err := ArchiveCharts(sitesData, reports, datasets, baseDir, timeRef)
+	if err != nil {
+		t.Fatalf("ArchiveCharts() error = %v", err)
+	}
+	if want := filepath.Join(baseDir, "2022-09-01T000000"); runDir != want {
+		t.Errorf("ArchiveCharts() = %s, want %s", runDir, want)
+	}
+
+	entries, err := os.ReadDir(filepath.Join(runDir, "site"))
+	if err != nil {
+		t.Fatalf("os.ReadDir() error = %v", err)
+	}
+	got := []string{}
+	for _, entry := range entries {
+		got = append(got, entry.Name())
+	}
+	want := []string{"Revenue.png", "Revenue_Browser_Chrome.png"}
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("ArchiveCharts() files = %v, want %v", got, want)
+	}
+
+	//Encrypted sites get no plain charts
+	if _, err := os.Stat(filepath.Join(runDir, "secret")); !os.IsNotExist(err) {
+		t.Errorf("ArchiveCharts() stored charts of an encrypted site, error = %v", err)
+	}
+}
@@ -0,0 +1,97 @@
+package reporting
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/ftfmtavares/anomalies-detector/config"
+	"github.com/ftfmtavares/anomalies-detector/utils"
+)
+
+//authRealm is the realm browsers are asked to log in to on unauthenticated requests
+const authRealm = "anomalies-detector"
+
+//authenticator checks the credentials of the report server requests against the configured bearer tokens and users
+//Tokens and passwords are resolved once, passwords being kept as their SHA-256 digest so they're all compared the same way
+//Rejected and preflight requests are rate limited by their IP address, while accepted ones carry the verified client on their context
+type authenticator struct {
+	tokens  []string
+	users   map[string][]byte
+	limiter *rateLimiter
+}
+
+//newAuthenticator creates an authenticator from the given parameters, resolving their "env:VAR" references, the requests it answers itself consuming the given rate limiter
+func newAuthenticator(params config.ReportAuthParams, limiter *rateLimiter) *authenticator {
+	auth := &authenticator{users: map[string][]byte{}, limiter: limiter}
+	for _, token := range params.Tokens {
+		if token = utils.ResolveSecret(token); token != "" {
+			auth.tokens = append(auth.tokens, token)
+		}
+	}
+	for user, password := range params.Users {
+		password = utils.ResolveSecret(password)
+		if digest, err := hex.DecodeString(strings.TrimPrefix(password, "sha256:")); strings.HasPrefix(password, "sha256:") && err == nil && len(digest) == sha256.Size {
+			auth.users[user] = digest
+		} else if password != "" {
+			sum := sha256.Sum256([]byte(password))
+			auth.users[user] = sum[:]
+		}
+	}
+	return auth
+}
+
+//authenticate checks if a request carries one of the bearer tokens or the credentials of one of the users, comparing them in constant time
+//It returns the verified client, identified by the position of its token or by its user name, so tokens are never kept as rate limiting keys
+func (auth *authenticator) authenticate(req *http.Request) (string, bool) {
+	if header := req.Header.Get("Authorization"); strings.HasPrefix(header, "Bearer ") {
+		token := []byte(strings.TrimPrefix(header, "Bearer "))
+		client := ""
+		for i, accepted := range auth.tokens {
+			if subtle.ConstantTimeCompare(token, []byte(accepted)) == 1 {
+				client = "token:" + strconv.Itoa(i)
+			}
+		}
+		return client, client != ""
+	}
+	if user, password, ok := req.BasicAuth(); ok {
+		digest, found := auth.users[user]
+		sum := sha256.Sum256([]byte(password))
+		return "user:" + user, found && subtle.ConstantTimeCompare(sum[:], digest) == 1
+	}
+	return "", false
+}
+
+//middleware wraps an HTTP handler rejecting unauthenticated requests with a 401 status, asking browsers for basic authentication if users are configured
+//CORS preflight requests are answered here without reaching the handler, since browsers never send credentials with them
+//Both are rate limited by their IP address first, so credentials can't be guessed faster than the rate allows, and accepted requests go on with their verified client
+func (auth *authenticator) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		client, ok := auth.authenticate(req)
+		if req.Method == http.MethodOptions || !ok {
+			if auth.limiter != nil && auth.limiter.limited(res, clientKey(req)) {
+				return
+			}
+		}
+		if req.Method == http.MethodOptions {
+			res.Header().Set("Allow", "GET, POST, DELETE, OPTIONS")
+			res.Header().Set("Access-Control-Allow-Origin", "*")
+			res.Header().Set("Access-Control-Allow-Methods", "GET, POST, DELETE, OPTIONS")
+			res.Header().Set("Access-Control-Allow-Headers", "Authorization, Content-Type")
+			res.WriteHeader(http.StatusNoContent)
+			return
+		}
+		if !ok {
+			if len(auth.users) > 0 {
+				res.Header().Set("WWW-Authenticate", `Basic realm="`+authRealm+`", charset="UTF-8"`)
+			}
+			http.Error(res, "401 unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(res, req.WithContext(context.WithValue(req.Context(), clientContextKey{}, client)))
+	})
+}
@@ -0,0 +1,135 @@
+package reporting
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ftfmtavares/anomalies-detector/config"
+)
+
+func Test_authenticator_middleware(t *testing.T) {
+	t.Setenv("REPORT_TEST_TOKEN", "env-token")
+	digest := sha256.Sum256([]byte("hashed-secret"))
+	params := config.ReportServerParams{Auth: &config.ReportAuthParams{
+		Tokens: []string{"static-token", "env:REPORT_TEST_TOKEN"},
+		Users:  map[string]string{"analyst": "secret", "manager": "sha256:" + hex.EncodeToString(digest[:])},
+	}}
+	handler := NewReportHandler(nil, nil, params, DetectionSettings{})
+
+	tests := []struct {
+		name           string
+		method         string
+		path           string
+		setAuth        func(req *http.Request)
+		wantStatus     int
+		wantChallenged bool
+	}{
+		{name: "No credentials", wantStatus: http.StatusUnauthorized, wantChallenged: true},
+		{name: "Static token", setAuth: func(req *http.Request) { req.Header.Set("Authorization", "Bearer static-token") }, wantStatus: http.StatusOK},
+		{name: "Token from the environment", setAuth: func(req *http.Request) { req.Header.Set("Authorization", "Bearer env-token") }, wantStatus: http.StatusOK},
+		{name: "Unknown token", setAuth: func(req *http.Request) { req.Header.Set("Authorization", "Bearer other") }, wantStatus: http.StatusUnauthorized, wantChallenged: true},
+		{name: "Basic authentication", setAuth: func(req *http.Request) { req.SetBasicAuth("analyst", "secret") }, wantStatus: http.StatusOK},
+		{name: "Basic authentication with a hashed password", setAuth: func(req *http.Request) { req.SetBasicAuth("manager", "hashed-secret") }, wantStatus: http.StatusOK},
+		{name: "Wrong password", setAuth: func(req *http.Request) { req.SetBasicAuth("analyst", "guess") }, wantStatus: http.StatusUnauthorized, wantChallenged: true},
+		{name: "Password of another user", setAuth: func(req *http.Request) { req.SetBasicAuth("manager", "secret") }, wantStatus: http.StatusUnauthorized, wantChallenged: true},
+		{name: "CORS preflight", method: http.MethodOptions, wantStatus: http.StatusNoContent},
+		{name: "CORS preflight of the sites", method: http.MethodOptions, path: "/api/v1/sites", wantStatus: http.StatusNoContent},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			method := tt.method
+			if method == "" {
+				method = http.MethodGet
+			}
+			path := tt.path
+			if path == "" {
+				path = "/api/v1/alarms"
+			}
+			req := httptest.NewRequest(method, path, nil)
+			if tt.setAuth != nil {
+				tt.setAuth(req)
+			}
+			res := httptest.NewRecorder()
+			handler.ServeHTTP(res, req)
+			if res.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", res.Code, tt.wantStatus)
+			}
+			if challenged := res.Header().Get("WWW-Authenticate") != ""; challenged != tt.wantChallenged {
+				t.Errorf("WWW-Authenticate = %q, want challenged %v", res.Header().Get("WWW-Authenticate"), tt.wantChallenged)
+			}
+			//Preflight requests carry no credentials, so they're answered without any data
+			if method == http.MethodOptions && (res.Body.Len() != 0 || res.Header().Get("Access-Control-Allow-Headers") == "") {
+				t.Errorf("preflight body = %q, headers = %v, want an empty CORS answer", res.Body.String(), res.Header())
+			}
+		})
+	}
+}
+
+func Test_authenticator_guessedTokens(t *testing.T) {
+	params := config.ReportServerParams{
+		RateLimit: config.RateLimitParams{RequestsPerMinute: 1, Burst: 5},
+		Auth:      &config.ReportAuthParams{Tokens: []string{"static-token"}},
+	}
+	handler := NewReportHandler(nil, nil, params, DetectionSettings{})
+
+	//Every guess uses a different token from the same address, so they all share its bucket
+	for i := 0; i < 10; i++ {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/alarms", nil)
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer guess-%d", i))
+		res := httptest.NewRecorder()
+		handler.ServeHTTP(res, req)
+
+		want := http.StatusUnauthorized
+		if i >= params.RateLimit.Burst {
+			want = http.StatusTooManyRequests
+		}
+		if res.Code != want {
+			t.Errorf("guess #%d status = %d, want %d", i, res.Code, want)
+		}
+	}
+}
+
+func Test_authenticator_clientBuckets(t *testing.T) {
+	params := config.ReportServerParams{
+		RateLimit: config.RateLimitParams{RequestsPerMinute: 1, Burst: 2},
+		Auth:      &config.ReportAuthParams{Tokens: []string{"first-token", "second-token"}, Users: map[string]string{"analyst": "secret"}},
+	}
+	handler := NewReportHandler(nil, nil, params, DetectionSettings{})
+
+	//Every request comes from the same address, verified clients getting their own bucket apart from the rejected requests
+	requests := []struct {
+		name       string
+		setAuth    func(req *http.Request)
+		wantStatus int
+	}{
+		{name: "First token", setAuth: func(req *http.Request) { req.Header.Set("Authorization", "Bearer first-token") }, wantStatus: http.StatusOK},
+		{name: "First token again", setAuth: func(req *http.Request) { req.Header.Set("Authorization", "Bearer first-token") }, wantStatus: http.StatusOK},
+		{name: "First token over its rate", setAuth: func(req *http.Request) { req.Header.Set("Authorization", "Bearer first-token") }, wantStatus: http.StatusTooManyRequests},
+		{name: "Second token", setAuth: func(req *http.Request) { req.Header.Set("Authorization", "Bearer second-token") }, wantStatus: http.StatusOK},
+		{name: "User", setAuth: func(req *http.Request) { req.SetBasicAuth("analyst", "secret") }, wantStatus: http.StatusOK},
+		{name: "Guessed token", setAuth: func(req *http.Request) { req.Header.Set("Authorization", "Bearer guess") }, wantStatus: http.StatusUnauthorized},
+		{name: "Guessed password", setAuth: func(req *http.Request) { req.SetBasicAuth("analyst", "guess") }, wantStatus: http.StatusUnauthorized},
+		{name: "Guess over the address rate", setAuth: func(req *http.Request) { req.Header.Set("Authorization", "Bearer other") }, wantStatus: http.StatusTooManyRequests},
+		{name: "Preflight over the address rate", wantStatus: http.StatusTooManyRequests},
+		{name: "User still within its rate", setAuth: func(req *http.Request) { req.SetBasicAuth("analyst", "secret") }, wantStatus: http.StatusOK},
+	}
+	for _, request := range requests {
+		method := http.MethodGet
+		if request.setAuth == nil {
+			method = http.MethodOptions
+		}
+		req := httptest.NewRequest(method, "/api/v1/alarms", nil)
+		if request.setAuth != nil {
+			request.setAuth(req)
+		}
+		res := httptest.NewRecorder()
+		handler.ServeHTTP(res, req)
+		if res.Code != request.wantStatus {
+			t.Errorf("%s status = %d, want %d", request.name, res.Code, request.wantStatus)
+		}
+	}
+}
@@ -0,0 +1,267 @@
+package reporting
+
+import (
+	"compress/gzip"
+	"container/list"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ftfmtavares/anomalies-detector/config"
+
+	"github.com/gorilla/mux"
+)
+
+//defaultRenderCacheMB is the memory taken by the rendered charts kept for repeated views when none is configured
+const defaultRenderCacheMB = 32
+
+//uncachedRoutes lists the routes whose responses change within a run or on every request, served without validators
+var uncachedRoutes = map[string]bool{
+	"/metrics":                   true,
+	"/graphql":                   true,
+	"/api/active-alarms":         true,
+	"/api/v1/subscriptions":      true,
+	"/api/v1/subscriptions/{id}": true,
+}
+
+//gzipWriters recycles the gzip writers of compressed responses, since each one allocates its compression tables
+var gzipWriters = sync.Pool{New: func() interface{} { return gzip.NewWriter(nil) }}
+
+//runValidators holds the Last-Modified date and ETag of the responses served over the data and reports of a run
+//Report handlers are created once per run so every response of a handler stays the same until it's replaced
+type runValidators struct {
+	lastModified time.Time
+	etag         string
+}
+
+//newRunValidators creates the validators of a run from its end, HTTP dates being kept to the second
+func newRunValidators(runTime time.Time) runValidators {
+	runTime = runTime.UTC().Truncate(time.Second)
+	return runValidators{lastModified: runTime, etag: fmt.Sprintf(`W/"run-%x"`, runTime.Unix())}
+}
+
+//middleware wraps an HTTP handler setting the run validators on GET and HEAD responses and answering requests still holding them with a 304 status
+//The ETag is weak since the same response can be served compressed or not, and clients are asked to revalidate so they never show the data of a previous run
+func (validators runValidators) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		if (req.Method != http.MethodGet && req.Method != http.MethodHead) || uncachedRoute(req) {
+			next.ServeHTTP(res, req)
+			return
+		}
+		res.Header().Set("Last-Modified", validators.lastModified.Format(http.TimeFormat))
+		res.Header().Set("ETag", validators.etag)
+		res.Header().Set("Cache-Control", "private, no-cache")
+		if validators.notModified(req) {
+			res.WriteHeader(http.StatusNotModified)
+			return
+		}
+		next.ServeHTTP(res, req)
+	})
+}
+
+//notModified checks if a request holds the current validators, If-None-Match taking precedence over If-Modified-Since
+func (validators runValidators) notModified(req *http.Request) bool {
+	if match := req.Header.Get("If-None-Match"); match != "" {
+		for _, etag := range strings.Split(match, ",") {
+			etag = strings.TrimSpace(etag)
+			if etag == "*" || strings.TrimPrefix(etag, "W/") == strings.TrimPrefix(validators.etag, "W/") {
+				return true
+			}
+		}
+		return false
+	}
+	since, err := http.ParseTime(req.Header.Get("If-Modified-Since"))
+	return err == nil && !validators.lastModified.After(since)
+}
+
+//uncachedRoute checks if a request was matched to one of the uncached routes
+func uncachedRoute(req *http.Request) bool {
+	if current := mux.CurrentRoute(req); current != nil {
+		if template, err := current.GetPathTemplate(); err == nil {
+			return uncachedRoutes[template]
+		}
+	}
+	return false
+}
+
+//gzipMiddleware wraps an HTTP handler compressing its text, JSON and SVG responses for clients accepting gzip
+//PNG charts are already compressed, so they're sent as they are
+func gzipMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		res.Header().Add("Vary", "Accept-Encoding")
+		if req.Method == http.MethodHead || !acceptsGzip(req) {
+			next.ServeHTTP(res, req)
+			return
+		}
+		writer := &gzipResponseWriter{ResponseWriter: res}
+		defer writer.close()
+		next.ServeHTTP(writer, req)
+	})
+}
+
+//acceptsGzip checks if the Accept-Encoding header of a request lists gzip, or any encoding, without refusing it with a zero quality
+func acceptsGzip(req *http.Request) bool {
+	for _, encoding := range strings.Split(req.Header.Get("Accept-Encoding"), ",") {
+		params := strings.Split(encoding, ";")
+		name := strings.TrimSpace(params[0])
+		if name != "gzip" && name != "*" {
+			continue
+		}
+		for _, param := range params[1:] {
+			if quality := strings.TrimSpace(param); strings.HasPrefix(quality, "q=") {
+				if value, err := strconv.ParseFloat(quality[2:], 64); err == nil && value == 0 {
+					return false
+				}
+			}
+		}
+		return true
+	}
+	return false
+}
+
+//gzipResponseWriter compresses a response once its status and content type show it's worth it
+//Statuses written before the content type is known are held until the first write, so the content type can be sniffed from it
+//Partial, empty and already encoded responses are written unchanged
+type gzipResponseWriter struct {
+	http.ResponseWriter
+	gz          *gzip.Writer
+	status      int
+	wroteHeader bool
+}
+
+//WriteHeader writes the response status, holding it until the first write if the response has a body of unknown content type
+func (writer *gzipResponseWriter) WriteHeader(status int) {
+	if writer.wroteHeader || writer.status != 0 {
+		return
+	}
+	if writer.Header().Get("Content-Type") == "" && hasBody(status) {
+		writer.status = status
+		return
+	}
+	writer.writeHeader(status)
+}
+
+//writeHeader starts compressing the response if its status and content type allow it before writing the status
+func (writer *gzipResponseWriter) writeHeader(status int) {
+	writer.wroteHeader = true
+	header := writer.Header()
+	if hasBody(status) && header.Get("Content-Encoding") == "" && compressible(header.Get("Content-Type")) {
+		header.Set("Content-Encoding", "gzip")
+		header.Del("Content-Length")
+		writer.gz = gzipWriters.Get().(*gzip.Writer)
+		writer.gz.Reset(writer.ResponseWriter)
+	}
+	writer.ResponseWriter.WriteHeader(status)
+}
+
+//Write compresses the written bytes if compressing, sniffing the content type as the server would when none is set
+func (writer *gzipResponseWriter) Write(data []byte) (int, error) {
+	if !writer.wroteHeader {
+		if writer.Header().Get("Content-Type") == "" {
+			writer.Header().Set("Content-Type", http.DetectContentType(data))
+		}
+		if writer.status == 0 {
+			writer.status = http.StatusOK
+		}
+		writer.writeHeader(writer.status)
+	}
+	if writer.gz != nil {
+		return writer.gz.Write(data)
+	}
+	return writer.ResponseWriter.Write(data)
+}
+
+//close writes a status still held, flushes the compressed response and returns its gzip writer to the pool
+func (writer *gzipResponseWriter) close() {
+	if !writer.wroteHeader && writer.status != 0 {
+		writer.ResponseWriter.WriteHeader(writer.status)
+	}
+	if writer.gz != nil {
+		writer.gz.Close()
+		gzipWriters.Put(writer.gz)
+		writer.gz = nil
+	}
+}
+
+//hasBody checks if a response status carries a body worth compressing, partial contents being left out as their ranges refer to the uncompressed body
+func hasBody(status int) bool {
+	return status >= http.StatusOK && status != http.StatusNoContent && status != http.StatusPartialContent && status != http.StatusNotModified
+}
+
+//compressible checks if a content type is worth compressing, i.e. text, JSON, JavaScript or XML, images included for SVG only
+func compressible(contentType string) bool {
+	mediaType := strings.ToLower(strings.TrimSpace(strings.Split(contentType, ";")[0]))
+	return strings.HasPrefix(mediaType, "text/") || strings.HasSuffix(mediaType, "json") || strings.HasSuffix(mediaType, "javascript") || strings.HasSuffix(mediaType, "xml")
+}
+
+//renderCache keeps the PNG images of rendered charts for repeated views, evicting the least recently used once over its memory budget
+//A cache only lives as long as the report handler of its run, so it never serves the charts of a previous run
+type renderCache struct {
+	mu      sync.Mutex
+	budget  int64
+	used    int64
+	order   *list.List
+	entries map[string]*list.Element
+}
+
+//renderCacheEntry holds a cached chart along with its key, so evicted entries can be removed from the index
+type renderCacheEntry struct {
+	key string
+	png []byte
+}
+
+//newRenderCache creates a renderCache from the report server parameters, with a zero budget if it's disabled
+func newRenderCache(params config.ReportServerParams) *renderCache {
+	budgetMB := params.RenderCacheMB
+	if budgetMB == 0 {
+		budgetMB = defaultRenderCacheMB
+	} else if budgetMB < 0 {
+		budgetMB = 0
+	}
+	return &renderCache{budget: int64(budgetMB) << 20, order: list.New(), entries: map[string]*list.Element{}}
+}
+
+//renderCacheKey returns the cache key of a chart request, its path along with its query strings sorted by name
+func renderCacheKey(req *http.Request) string {
+	return req.URL.Path + "?" + req.URL.Query().Encode()
+}
+
+//Get returns the cached chart of a key, if any, marking it as the most recently used
+func (cache *renderCache) Get(key string) ([]byte, bool) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	element, found := cache.entries[key]
+	if !found {
+		return nil, false
+	}
+	cache.order.MoveToFront(element)
+	return element.Value.(renderCacheEntry).png, true
+}
+
+//Add caches a rendered chart, evicting the least recently used ones until it fits the budget
+//Charts larger than the whole budget aren't cached
+func (cache *renderCache) Add(key string, png []byte) {
+	size := int64(len(png))
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	if size > cache.budget {
+		return
+	}
+	if element, found := cache.entries[key]; found {
+		cache.used -= int64(len(element.Value.(renderCacheEntry).png))
+		cache.order.Remove(element)
+		delete(cache.entries, key)
+	}
+	for cache.used+size > cache.budget {
+		oldest := cache.order.Back()
+		entry := oldest.Value.(renderCacheEntry)
+		cache.used -= int64(len(entry.png))
+		cache.order.Remove(oldest)
+		delete(cache.entries, entry.key)
+	}
+	cache.entries[key] = cache.order.PushFront(renderCacheEntry{key: key, png: png})
+	cache.used += size
+}
@@ -0,0 +1,110 @@
+package reporting
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ftfmtavares/anomalies-detector/config"
+)
+
+func TestReportCaching(t *testing.T) {
+	sitesData, reports := reportFixture()
+	runEnd := time.Date(2022, 9, 1, 12, 30, 15, 0, time.UTC)
+	handler := newReportRouter(sitesData, reports, config.ReportServerParams{RateLimit: config.RateLimitParams{Burst: 100}}, DetectionSettings{Run: &RunStats{Finished: runEnd}})
+	etag := `W/"run-` + "6310a5d7" + `"`
+
+	tests := []struct {
+		name             string
+		url              string
+		header           http.Header
+		wantStatus       int
+		wantEncoding     string
+		wantContentType  string
+		wantLastModified string
+		wantEtag         string
+	}{
+		{name: "Index compressed with validators", url: "/report", header: http.Header{"Accept-Encoding": {"gzip, deflate"}}, wantStatus: http.StatusOK, wantEncoding: "gzip", wantLastModified: "Thu, 01 Sep 2022 12:30:15 GMT", wantEtag: etag},
+		{name: "JSON not compressed without gzip", url: "/api/v1/alarms", header: http.Header{"Accept-Encoding": {"gzip;q=0, br"}}, wantStatus: http.StatusOK, wantContentType: "application/json", wantEtag: etag},
+		{name: "JSON compressed", url: "/api/v1/alarms", header: http.Header{"Accept-Encoding": {"*"}}, wantStatus: http.StatusOK, wantEncoding: "gzip", wantContentType: "application/json", wantEtag: etag},
+		{name: "Chart left uncompressed", url: "/report/shop/Revenue", header: http.Header{"Accept-Encoding": {"gzip"}}, wantStatus: http.StatusOK, wantContentType: "image/png", wantEtag: etag},
+		{name: "Chart from the cache", url: "/report/shop/Revenue", header: http.Header{"Accept-Encoding": {"gzip"}}, wantStatus: http.StatusOK, wantContentType: "image/png", wantEtag: etag},
+		{name: "Matching ETag", url: "/report/shop/Revenue", header: http.Header{"If-None-Match": {`"other", ` + etag}}, wantStatus: http.StatusNotModified, wantEtag: etag},
+		{name: "Strong ETag of the run", url: "/api/v1/sites", header: http.Header{"If-None-Match": {`"run-6310a5d7"`}}, wantStatus: http.StatusNotModified, wantEtag: etag},
+		{name: "ETag of another run", url: "/api/v1/sites", header: http.Header{"If-None-Match": {`W/"run-6310a5d6"`}, "If-Modified-Since": {"Thu, 01 Sep 2022 13:00:00 GMT"}}, wantStatus: http.StatusOK, wantEtag: etag},
+		{name: "Modified since the run", url: "/report", header: http.Header{"If-Modified-Since": {"Thu, 01 Sep 2022 12:30:15 GMT"}}, wantStatus: http.StatusNotModified, wantEtag: etag},
+		{name: "Modified before the run", url: "/report", header: http.Header{"If-Modified-Since": {"Thu, 01 Sep 2022 12:30:14 GMT"}}, wantStatus: http.StatusOK, wantEtag: etag},
+		{name: "Metrics without validators", url: "/metrics", header: http.Header{"If-None-Match": {etag}}, wantStatus: http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.url, nil)
+			req.Header = tt.header
+			res := httptest.NewRecorder()
+			handler.ServeHTTP(res, req)
+
+			if res.Code != tt.wantStatus {
+				t.Fatalf("GET %s status = %d, want %d", tt.url, res.Code, tt.wantStatus)
+			}
+			if got := res.Header().Get("Content-Encoding"); got != tt.wantEncoding {
+				t.Errorf("GET %s content encoding = %q, want %q", tt.url, got, tt.wantEncoding)
+			}
+			if got := res.Header().Get("ETag"); got != tt.wantEtag {
+				t.Errorf("GET %s ETag = %q, want %q", tt.url, got, tt.wantEtag)
+			}
+			if got := res.Header().Get("Last-Modified"); tt.wantLastModified != "" && got != tt.wantLastModified {
+				t.Errorf("GET %s Last-Modified = %q, want %q", tt.url, got, tt.wantLastModified)
+			}
+			if tt.wantContentType != "" && res.Header().Get("Content-Type") != tt.wantContentType {
+				t.Errorf("GET %s content type = %s, want %s", tt.url, res.Header().Get("Content-Type"), tt.wantContentType)
+			}
+			if res.Code == http.StatusNotModified && res.Body.Len() > 0 {
+				t.Errorf("GET %s not modified response has a body", tt.url)
+			}
+			if tt.wantEncoding == "gzip" {
+				reader, err := gzip.NewReader(res.Body)
+				if err != nil {
+					t.Fatalf("GET %s body isn't gzip - %v", tt.url, err)
+				}
+				if _, err := io.ReadAll(reader); err != nil {
+					t.Errorf("GET %s body isn't valid gzip - %v", tt.url, err)
+				}
+			}
+		})
+	}
+}
+
+func TestRenderCache(t *testing.T) {
+	cache := newRenderCache(config.ReportServerParams{RenderCacheMB: 1})
+	chart := func(fill byte) []byte { return bytes.Repeat([]byte{fill}, 400<<10) }
+
+	cache.Add("a", chart('a'))
+	cache.Add("b", chart('b'))
+	if _, found := cache.Get("a"); !found {
+		t.Fatalf("Get(a) found = false, want true")
+	}
+	cache.Add("c", chart('c'))
+	if _, found := cache.Get("b"); found {
+		t.Errorf("Get(b) found = true, want the least recently used chart evicted")
+	}
+	if png, found := cache.Get("a"); !found || png[0] != 'a' {
+		t.Errorf("Get(a) = %v, want the recently used chart kept", found)
+	}
+	cache.Add("large", bytes.Repeat([]byte{'l'}, 2<<20))
+	if _, found := cache.Get("large"); found {
+		t.Errorf("Get(large) found = true, want charts over the budget left out")
+	}
+	if cache.used != 800<<10 {
+		t.Errorf("used = %d, want %d", cache.used, 800<<10)
+	}
+
+	disabled := newRenderCache(config.ReportServerParams{RenderCacheMB: -1})
+	disabled.Add("a", chart('a'))
+	if _, found := disabled.Get("a"); found {
+		t.Errorf("Get(a) found = true on a disabled cache")
+	}
+}
@@ -0,0 +1,84 @@
+package reporting
+
+import (
+	"net/http"
+	"sort"
+
+	"github.com/ftfmtavares/anomalies-detector/collector"
+	"github.com/ftfmtavares/anomalies-detector/config"
+)
+
+//CatalogMetric provides the structure returned by the catalog endpoint for each known metric
+//Type field is empty and Aggregation field "sum" for metrics collected without a definition, while Sites field lists the sites collecting the metric along with their time steps
+type CatalogMetric struct {
+	Metric         string        `json:"metric"`
+	Unit           string        `json:"unit"`
+	Type           string        `json:"type"`
+	Aggregation    string        `json:"aggregation"`
+	AlertDirection string        `json:"alertDirection,omitempty"`
+	Cumulative     string        `json:"cumulative,omitempty"`
+	Sites          []CatalogSite `json:"sites"`
+}
+
+//CatalogSite holds a site collecting a metric and the time steps it is collected at
+type CatalogSite struct {
+	SiteId    string   `json:"siteId"`
+	TimeSteps []string `json:"timeSteps"`
+}
+
+//catalogHandler implements an HTTP response returning every known metric in JSON format, ordered by name, whether declared or only collected
+//Metrics are described by their definitions (the default ones if none are given) and their units fall back to the collected ones when not declared
+func catalogHandler(sitesData []collector.SiteData, metrics map[string]config.MetricDefinition) http.HandlerFunc {
+	return func(res http.ResponseWriter, req *http.Request) {
+		writeJson(res, http.StatusOK, buildCatalog(sitesData, metrics))
+	}
+}
+
+//buildCatalog lists the defined and collected metrics along with the sites collecting them
+func buildCatalog(sitesData []collector.SiteData, metrics map[string]config.MetricDefinition) []CatalogMetric {
+	if metrics == nil {
+		metrics = map[string]config.MetricDefinition{}
+		for _, definition := range config.DefaultMetrics() {
+			metrics[definition.Name] = definition
+		}
+	}
+
+	catalog := map[string]*CatalogMetric{}
+	for name, definition := range metrics {
+		catalog[name] = &CatalogMetric{Metric: name, Unit: definition.Unit, Type: definition.Type, Aggregation: definition.Aggregation(), AlertDirection: definition.AlertDirection, Cumulative: definition.Cumulative, Sites: []CatalogSite{}}
+	}
+	for _, siteData := range sitesData {
+		for _, metricData := range siteData.Metrics {
+			entry, found := catalog[metricData.Metric]
+			if !found {
+				entry = &CatalogMetric{Metric: metricData.Metric, Aggregation: config.MetricDefinition{}.Aggregation(), Sites: []CatalogSite{}}
+				catalog[metricData.Metric] = entry
+			}
+			if entry.Unit == "" {
+				entry.Unit = metricData.Unit
+			}
+
+			//Sites analysed at several time steps are listed once, with all of them
+			listed := false
+			for i := range entry.Sites {
+				if entry.Sites[i].SiteId == siteData.SiteId {
+					entry.Sites[i].TimeSteps = append(entry.Sites[i].TimeSteps, siteData.TimeStep)
+					listed = true
+					break
+				}
+			}
+			if !listed {
+				entry.Sites = append(entry.Sites, CatalogSite{SiteId: siteData.SiteId, TimeSteps: []string{siteData.TimeStep}})
+			}
+		}
+	}
+
+	res := make([]CatalogMetric, 0, len(catalog))
+	for _, entry := range catalog {
+		res = append(res, *entry)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Metric < res[j].Metric
+	})
+	return res
+}
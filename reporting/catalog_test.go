@@ -0,0 +1,54 @@
+package reporting
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/ftfmtavares/anomalies-detector/collector"
+	"github.com/ftfmtavares/anomalies-detector/config"
+)
+
+func Test_catalogHandler(t *testing.T) {
+	server := newTestReportServer(t)
+
+	resp, err := http.Get(server.URL + "/api/catalog")
+	if err != nil {
+		t.Fatalf("GET error = %v", err)
+	}
+	defer resp.Body.Close()
+	var catalog []CatalogMetric
+	if err := json.NewDecoder(resp.Body).Decode(&catalog); err != nil || resp.StatusCode != http.StatusOK {
+		t.Fatalf("GET status = %d, error = %v", resp.StatusCode, err)
+	}
+
+	want := []CatalogMetric{
+		{Metric: "Basket", Unit: "Average Basket Value (EUR)", Type: config.MetricTypeAverage, Aggregation: "mean", Sites: []CatalogSite{}},
+		{Metric: "Revenue", Unit: "Total Orders (EUR)", Type: config.MetricTypeSum, Aggregation: "sum", Sites: []CatalogSite{{SiteId: "shop", TimeSteps: []string{"1d", "1h"}}}},
+		{Metric: "Visits", Unit: "Number of Sessions", Type: config.MetricTypeCount, Aggregation: "sum", Sites: []CatalogSite{{SiteId: "blog", TimeSteps: []string{"1d"}}}},
+	}
+	if !reflect.DeepEqual(catalog, want) {
+		t.Errorf("catalogHandler() = %+v, want %+v", catalog, want)
+	}
+}
+
+func Test_buildCatalog(t *testing.T) {
+	sitesData := []collector.SiteData{
+		{SiteId: "shop", TimeStep: "1d", Metrics: []collector.MetricData{{Metric: "Orders", Unit: "orders"}, {Metric: "Signups", Unit: "users"}}},
+		{SiteId: "blog", TimeStep: "1h", Metrics: []collector.MetricData{{Metric: "Signups", Unit: "accounts"}}},
+	}
+	metrics := map[string]config.MetricDefinition{
+		"Orders": {Name: "Orders", Type: config.MetricTypeCount, AlertDirection: config.DirectionDrop, Cumulative: config.CumulativeCounter},
+		"Margin": {Name: "Margin", Unit: "%", Type: config.MetricTypeAverage},
+	}
+
+	want := []CatalogMetric{
+		{Metric: "Margin", Unit: "%", Type: config.MetricTypeAverage, Aggregation: "mean", Sites: []CatalogSite{}},
+		{Metric: "Orders", Unit: "orders", Type: config.MetricTypeCount, Aggregation: "sum", AlertDirection: config.DirectionDrop, Cumulative: config.CumulativeCounter, Sites: []CatalogSite{{SiteId: "shop", TimeSteps: []string{"1d"}}}},
+		{Metric: "Signups", Unit: "users", Aggregation: "sum", Sites: []CatalogSite{{SiteId: "shop", TimeSteps: []string{"1d"}}, {SiteId: "blog", TimeSteps: []string{"1h"}}}},
+	}
+	if got := buildCatalog(sitesData, metrics); !reflect.DeepEqual(got, want) {
+		t.Errorf("buildCatalog() = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,191 @@
+package reporting
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/ftfmtavares/anomalies-detector/analyser"
+	"github.com/ftfmtavares/anomalies-detector/config"
+	"github.com/ftfmtavares/anomalies-detector/utils"
+)
+
+//Const block defines the CloudEvents attributes and content types
+const (
+	cloudEventsSpecVersion      = "1.0"
+	cloudEventsTypePrefix       = "com.github.ftfmtavares.anomalies-detector.outlier."
+	defaultCloudEventsSource    = "/anomalies-detector"
+	cloudEventsContentType      = "application/cloudevents+json; charset=utf-8"
+	cloudEventsBatchContentType = "application/cloudevents-batch+json; charset=utf-8"
+)
+
+//CloudEvent provides the structure of a detected event wrapped in the CloudEvents 1.0 envelope (structured content mode)
+//Type field ends with the event severity, Subject field identifies the site, metric and attribute and Time field is the outlier period start
+//Id field is derived from the event itself so that consumers can deduplicate events published by several runs
+type CloudEvent struct {
+	SpecVersion     string         `json:"specversion"`
+	Id              string         `json:"id"`
+	Source          string         `json:"source"`
+	Type            string         `json:"type"`
+	Subject         string         `json:"subject"`
+	Time            time.Time      `json:"time"`
+	DataContentType string         `json:"datacontenttype"`
+	Data            CloudEventData `json:"data"`
+}
+
+//CloudEventData provides the structure of the CloudEvents data holding the event details
+type CloudEventData struct {
+	SiteId           string    `json:"siteId"`
+	Severity         string    `json:"severity"`
+	Metric           string    `json:"metric"`
+	Attribute        string    `json:"attribute"`
+	Resolution       string    `json:"resolution,omitempty"`
+	PeriodStart      time.Time `json:"outlierPeriodStart"`
+	PeriodEnd        time.Time `json:"outlierPeriodEnd"`
+	Routes           []string  `json:"routes,omitempty"`
+	Score            float64   `json:"score"`
+	Observed         float64   `json:"observed"`
+	Expected         float64   `json:"expected"`
+	DeviationPercent float64   `json:"deviationPercent"`
+	Direction        string    `json:"direction"`
+}
+
+//CloudEventsPublisher posts detected warnings and alarms in the CloudEvents format to an HTTP sink
+type CloudEventsPublisher struct {
+	sinkUrl    string
+	source     string
+	batch      bool
+	secret     string
+	severities map[string]bool
+	routes     []string
+	client     *http.Client
+}
+
+//NewCloudEventsPublisher creates a CloudEventsPublisher from the given parameters
+//It returns an error if no sink is configured
+func NewCloudEventsPublisher(params config.CloudEventsParams) (*CloudEventsPublisher, error) {
+	publisher := &CloudEventsPublisher{
+		sinkUrl:    utils.ResolveSecret(params.SinkUrl),
+		source:     params.Source,
+		batch:      params.Batch,
+		secret:     utils.ResolveSecret(params.SigningSecret),
+		severities: map[string]bool{},
+		routes:     params.Routes,
+		client:     utils.NewHttpClient(10 * time.Second),
+	}
+
+	if publisher.sinkUrl == "" {
+		return nil, errors.New("cloudevents publisher requires a sinkUrl")
+	}
+
+	if len(params.Severities) == 0 {
+		params.Severities = []string{"warning", "alarm"}
+	}
+	for _, severity := range params.Severities {
+		publisher.severities[severity] = true
+	}
+
+	return publisher, nil
+}
+
+//Publish posts the warnings and alarms of a report to the sink, alarms first
+//Events are sent one per request in structured content mode, or all together in a single batch request if configured
+func (publisher *CloudEventsPublisher) Publish(report analyser.OutlierReport) error {
+	events := CloudEvents(report, publisher.source, publisher.severities, publisher.routes)
+	if len(events) == 0 {
+		return nil
+	}
+
+	if publisher.batch {
+		return publisher.post(events, cloudEventsBatchContentType)
+	}
+	for _, event := range events {
+		if err := publisher.post(event, cloudEventsContentType); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//post sends a single event or a batch of events to the sink, any 2xx status being accepted
+func (publisher *CloudEventsPublisher) post(payload interface{}, contentType string) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, publisher.sinkUrl, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentType)
+	signRequest(req, publisher.secret, body)
+
+	resp, err := publisher.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("cloudevents publisher - %s", err.Error())
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("cloudevents publisher - unexpected status %s", resp.Status)
+	}
+	return nil
+}
+
+//CloudEvents wraps the alarms and warnings of a report in CloudEvents envelopes with the given source (the default one if empty), alarms first
+//Only events with the given severities are kept and, when routes are given, only events carrying one of them
+func CloudEvents(report analyser.OutlierReport, source string, severities map[string]bool, routes []string) []CloudEvent {
+	if source == "" {
+		source = defaultCloudEventsSource
+	}
+	events := []CloudEvent{}
+	for _, event := range notificationEvents(report, severities, routes) {
+		subject := fmt.Sprintf("%s/%s/%s", event.SiteId, event.Metric, event.Attribute)
+		if event.Resolution != "" {
+			subject = fmt.Sprintf("%s/%s", subject, event.Resolution)
+		}
+		events = append(events, CloudEvent{
+			SpecVersion:     cloudEventsSpecVersion,
+			Id:              cloudEventId(event),
+			Source:          source,
+			Type:            cloudEventsTypePrefix + event.Severity,
+			Subject:         subject,
+			Time:            event.PeriodStart,
+			DataContentType: "application/json",
+			Data:            cloudEventData(event),
+		})
+	}
+	return events
+}
+
+//cloudEventData returns the details of a notification event as carried by the CloudEvents data
+func cloudEventData(event NotificationEvent) CloudEventData {
+	return CloudEventData{
+		SiteId:           event.SiteId,
+		Severity:         event.Severity,
+		Metric:           event.Metric,
+		Attribute:        event.Attribute,
+		Resolution:       event.Resolution,
+		PeriodStart:      event.PeriodStart,
+		PeriodEnd:        event.PeriodEnd,
+		Routes:           event.Routes,
+		Score:            event.Score,
+		Observed:         event.Observed,
+		Expected:         event.Expected,
+		DeviationPercent: event.DeviationPercent,
+		Direction:        event.Direction,
+	}
+}
+
+//cloudEventId returns a hex encoded hash of the fields identifying an event, the same event always getting the same id
+func cloudEventId(event NotificationEvent) string {
+	hash := sha256.Sum256([]byte(fmt.Sprintf("%s|%s|%s|%s|%s|%d|%d", event.SiteId, event.Resolution, event.Severity, event.Metric, event.Attribute,
+		event.PeriodStart.Unix(), event.PeriodEnd.Unix())))
+	return hex.EncodeToString(hash[:16])
+}
@@ -0,0 +1,123 @@
+package reporting
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ftfmtavares/anomalies-detector/analyser"
+	"github.com/ftfmtavares/anomalies-detector/config"
+)
+
+func TestCloudEventsPublisher_Publish(t *testing.T) {
+	timeRef := time.Date(2022, 9, 1, 0, 0, 0, 0, time.UTC)
+
+	report := analyser.OutlierReport{
+		SiteId: "site",
+		Result: analyser.OutlierResults{
+			Warnings: []analyser.OutlierEvent{
+				{OutlierPeriodStart: timeRef, OutlierPeriodEnd: timeRef.AddDate(0, 0, 1), Metric: "Basket", Attribute: "Total", Resolution: "1d"},
+			},
+			Alarms: []analyser.OutlierEvent{
+				{OutlierPeriodStart: timeRef, OutlierPeriodEnd: timeRef.AddDate(0, 0, 1), Metric: "Revenue", Attribute: "Browser>Edge", Score: 4.5, Observed: 50, Expected: 200, DeviationPercent: -75},
+			},
+		},
+	}
+
+	tests := []struct {
+		name            string
+		params          config.CloudEventsParams
+		wantContentType string
+		wantRequests    int
+		wantTypes       []string
+		wantSubjects    []string
+	}{
+		{
+			name:            "One structured event per request with alarms first",
+			params:          config.CloudEventsParams{},
+			wantContentType: "application/cloudevents+json; charset=utf-8",
+			wantRequests:    2,
+			wantTypes:       []string{"com.github.ftfmtavares.anomalies-detector.outlier.alarm", "com.github.ftfmtavares.anomalies-detector.outlier.warning"},
+			wantSubjects:    []string{"site/Revenue/Browser>Edge", "site/Basket/Total/1d"},
+		},
+		{
+			name:            "Single batch request filtered by severity",
+			params:          config.CloudEventsParams{Batch: true, Severities: []string{"warning"}},
+			wantContentType: "application/cloudevents-batch+json; charset=utf-8",
+			wantRequests:    1,
+			wantTypes:       []string{"com.github.ftfmtavares.anomalies-detector.outlier.warning"},
+			wantSubjects:    []string{"site/Basket/Total/1d"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requests := 0
+			got := []CloudEvent{}
+			server := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+				requests++
+				if contentType := req.Header.Get("Content-Type"); contentType != tt.wantContentType {
+					t.Errorf("Publish() content type = %s, want %s", contentType, tt.wantContentType)
+				}
+				if tt.params.Batch {
+					var events []CloudEvent
+					json.NewDecoder(req.Body).Decode(&events)
+					got = append(got, events...)
+				} else {
+					var event CloudEvent
+					json.NewDecoder(req.Body).Decode(&event)
+					got = append(got, event)
+				}
+				res.WriteHeader(http.StatusAccepted)
+			}))
+			defer server.Close()
+
+			tt.params.SinkUrl = server.URL
+			publisher, err := NewCloudEventsPublisher(tt.params)
+			if err != nil {
+				t.Fatalf("NewCloudEventsPublisher() error = %v", err)
+			}
+			if err := publisher.Publish(report); err != nil {
+				t.Fatalf("Publish() error = %v", err)
+			}
+
+			if requests != tt.wantRequests {
+				t.Errorf("Publish() sent %d requests, want %d", requests, tt.wantRequests)
+			}
+			types := []string{}
+			subjects := []string{}
+			for _, event := range got {
+				types = append(types, event.Type)
+				subjects = append(subjects, event.Subject)
+				if event.SpecVersion != "1.0" || event.Source != "/anomalies-detector" || event.Id == "" || !event.Time.Equal(timeRef) {
+					t.Errorf("Publish() invalid envelope %+v", event)
+				}
+				if event.Data.Metric == "Revenue" && (event.Data.Score != 4.5 || event.Data.Observed != 50 || event.Data.Expected != 200 || event.Data.DeviationPercent != -75) {
+					t.Errorf("Publish() data = %+v, want the event score", event.Data)
+				}
+			}
+			if !reflect.DeepEqual(types, tt.wantTypes) {
+				t.Errorf("Publish() types = %v, want %v", types, tt.wantTypes)
+			}
+			if !reflect.DeepEqual(subjects, tt.wantSubjects) {
+				t.Errorf("Publish() subjects = %v, want %v", subjects, tt.wantSubjects)
+			}
+		})
+	}
+}
+
+func TestCloudEventId(t *testing.T) {
+	timeRef := time.Date(2022, 9, 1, 0, 0, 0, 0, time.UTC)
+	event := NotificationEvent{SiteId: "site", Severity: "alarm", Metric: "Revenue", Attribute: "Total", PeriodStart: timeRef, PeriodEnd: timeRef.AddDate(0, 0, 1)}
+	other := event
+	other.Severity = "warning"
+
+	if cloudEventId(event) != cloudEventId(event) {
+		t.Errorf("cloudEventId() is not stable for the same event")
+	}
+	if cloudEventId(event) == cloudEventId(other) {
+		t.Errorf("cloudEventId() is the same for different events")
+	}
+}
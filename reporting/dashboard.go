@@ -0,0 +1,26 @@
+package reporting
+
+import (
+	"embed"
+	"io/fs"
+	"net/http"
+)
+
+//dashboardAssets holds the interactive dashboard page, script and style sheet, embedded so the binary serves them without any file
+//
+//go:embed dashboard
+var dashboardAssets embed.FS
+
+//dashboardHandler implements an HTTP response serving the dashboard assets under /dashboard/, the page drawing its charts from the JSON API
+//Requests to /dashboard are redirected to /dashboard/ so the assets resolve relatively to the page
+func dashboardHandler() http.Handler {
+	assets, _ := fs.Sub(dashboardAssets, "dashboard")
+	files := http.StripPrefix("/dashboard/", http.FileServer(http.FS(assets)))
+	return http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		if req.URL.Path == "/dashboard" {
+			http.Redirect(res, req, "/dashboard/", http.StatusMovedPermanently)
+			return
+		}
+		files.ServeHTTP(res, req)
+	})
+}
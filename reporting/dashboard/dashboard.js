@@ -0,0 +1,423 @@
+//Anomalies dashboard, drawing the series and events of the report server JSON API as interactive SVG charts
+//Dragging over the chart zooms into a period, double clicking or the reset button zooms back out,
+//hovering shows the values and samples of the shown series and clicking an event zooms into it
+(function () {
+  "use strict";
+
+  var palette = ["#1f77b4", "#2ca02c", "#9467bd", "#8c564b", "#e377c2", "#17becf", "#bcbd22", "#7f7f7f", "#ff7f0e", "#d62728"];
+  var margin = { top: 16, right: 16, bottom: 36, left: 72 };
+  var height = 560;
+  var svgNs = "http://www.w3.org/2000/svg";
+
+  var state = { sites: [], site: null, metric: "", unit: "", series: [], events: [], hidden: {}, zoom: null, highlight: null };
+
+  function byId(id) {
+    return document.getElementById(id);
+  }
+
+  //getJson fetches a JSON document, failing on any non 2xx status
+  function getJson(url) {
+    return fetch(url, { headers: { Accept: "application/json" } }).then(function (res) {
+      if (!res.ok) {
+        throw new Error(res.status + " " + res.statusText);
+      }
+      return res.json();
+    });
+  }
+
+  //getPages fetches every page of a paginated endpoint, concatenating the lists held by the given key
+  function getPages(url, key) {
+    var items = [];
+    function next(cursor) {
+      var pageUrl = url + (url.indexOf("?") >= 0 ? "&" : "?") + "limit=1000" + (cursor ? "&cursor=" + encodeURIComponent(cursor) : "");
+      return getJson(pageUrl).then(function (page) {
+        items = items.concat(page[key] || []);
+        return page.nextCursor ? next(page.nextCursor) : items;
+      });
+    }
+    return next("");
+  }
+
+  function svg(name, attributes, parent) {
+    var node = document.createElementNS(svgNs, name);
+    Object.keys(attributes || {}).forEach(function (key) {
+      node.setAttribute(key, attributes[key]);
+    });
+    if (parent) {
+      parent.appendChild(node);
+    }
+    return node;
+  }
+
+  function formatNumber(value) {
+    return value.toLocaleString(undefined, { maximumFractionDigits: 2 });
+  }
+
+  function formatDate(time, withTime) {
+    var date = new Date(time);
+    return withTime ? date.toLocaleString() : date.toLocaleDateString();
+  }
+
+  //niceTicks returns about count round values covering the range
+  function niceTicks(min, max, count) {
+    var span = max - min || 1;
+    var step = Math.pow(10, Math.floor(Math.log10(span / count)));
+    var error = span / count / step;
+    step *= error >= 7.5 ? 10 : error >= 3.5 ? 5 : error >= 1.5 ? 2 : 1;
+    var ticks = [];
+    for (var value = Math.ceil(min / step) * step; value <= max + step / 1e6; value += step) {
+      ticks.push(value);
+    }
+    return ticks;
+  }
+
+  //timeTicks returns about count times covering the range, spaced by a whole number of hours or days
+  function timeTicks(from, to, count) {
+    var hour = 3600000;
+    var steps = [hour, 3 * hour, 6 * hour, 12 * hour, 24 * hour, 48 * hour, 168 * hour, 336 * hour, 720 * hour];
+    var step = steps[steps.length - 1];
+    for (var i = 0; i < steps.length; i++) {
+      if ((to - from) / steps[i] <= count) {
+        step = steps[i];
+        break;
+      }
+    }
+    var offset = new Date(from).getTimezoneOffset() * 60000;
+    var ticks = [];
+    for (var time = Math.ceil((from - offset) / step) * step + offset; time <= to; time += step) {
+      ticks.push(time);
+    }
+    return ticks;
+  }
+
+  function message(text) {
+    byId("chart").innerHTML = "";
+    var paragraph = document.createElement("p");
+    paragraph.className = "message";
+    paragraph.textContent = text;
+    byId("chart").appendChild(paragraph);
+  }
+
+  //Loading the sites and filling the site selector, the site and metric being restored from the location hash
+  function loadSites() {
+    getJson("/api/v1/sites").then(function (sites) {
+      state.sites = sites;
+      var selector = byId("site");
+      selector.innerHTML = "";
+      sites.forEach(function (site, i) {
+        var option = document.createElement("option");
+        option.value = i;
+        option.textContent = site.siteId + " (" + site.timeStep + ")" + (site.archived ? " - archived" : "") + (site.alarms ? " - " + site.alarms + " alarms" : "");
+        selector.appendChild(option);
+      });
+      if (sites.length === 0) {
+        message("No collected sites.");
+        return;
+      }
+      var hash = decodeURIComponent(location.hash.slice(1)).split("/");
+      sites.forEach(function (site, i) {
+        if (site.siteId === hash[0] && site.timeStep === hash[1]) {
+          selector.value = i;
+        }
+      });
+      selectSite(hash[2]);
+    }).catch(function (err) {
+      message("Sites not loaded - " + err.message);
+    });
+  }
+
+  function selectSite(metric) {
+    state.site = state.sites[byId("site").value];
+    var selector = byId("metric");
+    selector.innerHTML = "";
+    state.site.metrics.forEach(function (metricSummary) {
+      var option = document.createElement("option");
+      option.value = metricSummary.metric;
+      option.textContent = metricSummary.metric;
+      selector.appendChild(option);
+    });
+    if (metric && state.site.metrics.some(function (metricSummary) { return metricSummary.metric === metric; })) {
+      selector.value = metric;
+    }
+    loadMetric();
+  }
+
+  //Loading the series and events of the selected site metric, all series but the total of metrics with many being hidden at first
+  function loadMetric() {
+    state.metric = byId("metric").value;
+    if (!state.metric) {
+      message("No collected metrics.");
+      return;
+    }
+    location.hash = encodeURIComponent([state.site.siteId, state.site.timeStep, state.metric].join("/"));
+    var site = encodeURIComponent(state.site.siteId);
+    var resolution = encodeURIComponent(state.site.timeStep);
+    var metric = encodeURIComponent(state.metric);
+    var scale = byId("percent").checked ? "&scale=percent" : "";
+    Promise.all([
+      getPages("/api/v1/sites/" + site + "/metrics/" + metric + "/data?resolution=" + resolution + scale, "series"),
+      getPages("/api/v1/alarms?site=" + site + "&resolution=" + resolution + "&metric=" + metric, "events")
+    ]).then(function (results) {
+      state.series = results[0].map(function (series, i) {
+        return {
+          attribute: series.attribute,
+          color: palette[i % palette.length],
+          points: series.data.map(function (step) {
+            return { time: Date.parse(step.dateStart), value: step.value, samples: step.samples };
+          })
+        };
+      });
+      state.events = results[1].map(function (event) {
+        return { severity: event.severity, attribute: event.attribute, start: Date.parse(event.outlierPeriodStart), end: Date.parse(event.outlierPeriodEnd), score: event.score, direction: event.direction };
+      });
+      state.unit = byId("percent").checked ? "% of baseline" : (state.site.metrics.filter(function (m) { return m.metric === state.metric; })[0] || {}).unit;
+      state.hidden = {};
+      if (state.series.length > 8) {
+        state.series.forEach(function (series) {
+          state.hidden[series.attribute] = series.attribute !== "Total";
+        });
+      }
+      state.zoom = null;
+      state.highlight = null;
+      renderLegend();
+      renderEvents();
+      render();
+    }).catch(function (err) {
+      message("Metric not loaded - " + err.message);
+    });
+  }
+
+  function renderLegend() {
+    var legend = byId("legend");
+    legend.innerHTML = "";
+    state.series.forEach(function (series) {
+      var label = document.createElement("label");
+      var checkbox = document.createElement("input");
+      checkbox.type = "checkbox";
+      checkbox.checked = !state.hidden[series.attribute];
+      checkbox.addEventListener("change", function () {
+        state.hidden[series.attribute] = !checkbox.checked;
+        renderEvents();
+        render();
+      });
+      var swatch = document.createElement("span");
+      swatch.className = "swatch";
+      swatch.style.background = series.color;
+      label.appendChild(checkbox);
+      label.appendChild(swatch);
+      label.appendChild(document.createTextNode(series.attribute));
+      legend.appendChild(label);
+    });
+  }
+
+  function visibleEvents() {
+    return state.events.filter(function (event) {
+      return !state.hidden[event.attribute];
+    });
+  }
+
+  function renderEvents() {
+    var list = byId("events");
+    list.innerHTML = "";
+    var events = visibleEvents();
+    if (events.length === 0) {
+      var empty = document.createElement("li");
+      empty.className = "message";
+      empty.textContent = "No events on the shown series.";
+      list.appendChild(empty);
+    }
+    events.forEach(function (event) {
+      var item = document.createElement("li");
+      item.className = event.severity;
+      item.textContent = event.severity + " - " + event.attribute + " - " + formatDate(event.start, true) + " (" + event.direction + ", score " + formatNumber(event.score) + ")";
+      item.addEventListener("click", function () {
+        var padding = Math.max((event.end - event.start) * 4, stepDuration() * 6);
+        state.zoom = [event.start - padding, event.end + padding];
+        state.highlight = event;
+        render();
+      });
+      list.appendChild(item);
+    });
+  }
+
+  function stepDuration() {
+    var points = state.series.length ? state.series[0].points : [];
+    return points.length > 1 ? points[1].time - points[0].time : 3600000;
+  }
+
+  //render draws the chart of the shown series within the zoomed period, along with the bands of their events
+  function render() {
+    var container = byId("chart");
+    var shown = state.series.filter(function (series) {
+      return !state.hidden[series.attribute];
+    });
+    var times = [];
+    state.series.forEach(function (series) {
+      series.points.forEach(function (point) {
+        times.push(point.time);
+      });
+    });
+    if (times.length === 0) {
+      message("No data.");
+      return;
+    }
+
+    var domain = state.zoom || [Math.min.apply(null, times), Math.max.apply(null, times)];
+    if (domain[0] === domain[1]) {
+      domain = [domain[0] - stepDuration(), domain[1] + stepDuration()];
+    }
+    var min = 0;
+    var max = 0;
+    shown.forEach(function (series) {
+      series.points.forEach(function (point) {
+        if (point.time >= domain[0] && point.time <= domain[1]) {
+          min = Math.min(min, point.value);
+          max = Math.max(max, point.value);
+        }
+      });
+    });
+    max = max === min ? min + 1 : max + (max - min) * 0.1;
+
+    var width = container.clientWidth || 900;
+    var plotWidth = width - margin.left - margin.right;
+    var plotHeight = height - margin.top - margin.bottom;
+    function x(time) {
+      return margin.left + (time - domain[0]) / (domain[1] - domain[0]) * plotWidth;
+    }
+    function y(value) {
+      return margin.top + plotHeight - (value - min) / (max - min) * plotHeight;
+    }
+
+    container.innerHTML = "";
+    var root = svg("svg", { viewBox: "0 0 " + width + " " + height });
+    container.appendChild(root);
+    var clip = svg("clipPath", { id: "plot" }, svg("defs", {}, root));
+    svg("rect", { x: margin.left, y: margin.top, width: plotWidth, height: plotHeight }, clip);
+
+    //Value axis and grid
+    var axis = svg("g", { "class": "axis" }, root);
+    niceTicks(min, max, 6).forEach(function (value) {
+      svg("line", { x1: margin.left, x2: margin.left + plotWidth, y1: y(value), y2: y(value) }, axis);
+      svg("text", { x: margin.left - 6, y: y(value) + 4, "text-anchor": "end" }, axis).textContent = formatNumber(value);
+    });
+    var unit = svg("text", { x: 12, y: margin.top + plotHeight / 2, "text-anchor": "middle", transform: "rotate(-90 12 " + (margin.top + plotHeight / 2) + ")" }, axis);
+    unit.textContent = state.unit || "";
+
+    //Time axis
+    var withTime = domain[1] - domain[0] < 3 * 86400000 || stepDuration() < 86400000;
+    timeTicks(domain[0], domain[1], Math.max(2, Math.floor(plotWidth / 140))).forEach(function (time) {
+      svg("line", { x1: x(time), x2: x(time), y1: margin.top, y2: margin.top + plotHeight }, axis);
+      svg("text", { x: x(time), y: height - margin.bottom + 18, "text-anchor": "middle" }, axis).textContent = formatDate(time, withTime);
+    });
+
+    //Event bands and series lines, clipped to the plot area
+    var plot = svg("g", { "clip-path": "url(#plot)" }, root);
+    visibleEvents().forEach(function (event) {
+      var left = x(event.start);
+      var band = svg("rect", { "class": "band " + event.severity + (event === state.highlight ? " highlight" : ""), x: left, y: margin.top, width: Math.max(x(event.end) - left, 2), height: plotHeight }, plot);
+      svg("title", {}, band).textContent = event.severity + " - " + event.attribute;
+    });
+    shown.forEach(function (series) {
+      var path = series.points.map(function (point, i) {
+        return (i === 0 ? "M" : "L") + x(point.time).toFixed(1) + "," + y(point.value).toFixed(1);
+      }).join("");
+      svg("path", { d: path, fill: "none", stroke: series.color, "stroke-width": 1.5 }, plot);
+    });
+
+    //Hover tooltip, drag to zoom and double click to zoom out, all handled on a transparent overlay
+    var cursor = svg("line", { "class": "cursor", y1: margin.top, y2: margin.top + plotHeight, visibility: "hidden" }, root);
+    var selection = svg("rect", { "class": "selection", y: margin.top, height: plotHeight, width: 0, visibility: "hidden" }, root);
+    var overlay = svg("rect", { x: margin.left, y: margin.top, width: plotWidth, height: plotHeight, fill: "transparent" }, root);
+    var tooltip = byId("tooltip");
+    var dragStart = null;
+
+    function timeAt(evt) {
+      var box = root.getBoundingClientRect();
+      var offset = (evt.clientX - box.left) * width / box.width;
+      return domain[0] + (offset - margin.left) / plotWidth * (domain[1] - domain[0]);
+    }
+
+    overlay.addEventListener("mousemove", function (evt) {
+      var time = timeAt(evt);
+      if (dragStart !== null) {
+        var from = Math.min(dragStart, time);
+        selection.setAttribute("x", x(from));
+        selection.setAttribute("width", Math.abs(x(time) - x(dragStart)));
+        selection.setAttribute("visibility", "visible");
+      }
+
+      //Picking the time step nearest to the pointer on each shown series
+      var rows = [];
+      var nearestTime = null;
+      shown.forEach(function (series) {
+        var nearest = null;
+        series.points.forEach(function (point) {
+          if (nearest === null || Math.abs(point.time - time) < Math.abs(nearest.time - time)) {
+            nearest = point;
+          }
+        });
+        if (nearest !== null) {
+          nearestTime = nearestTime === null ? nearest.time : nearestTime;
+          rows.push("<span style=\"color:" + series.color + "\">&#9632;</span> " + escapeHtml(series.attribute) + ": <b>" + formatNumber(nearest.value) + "</b> (" + nearest.samples + " samples)");
+        }
+      });
+      if (nearestTime === null) {
+        return;
+      }
+      cursor.setAttribute("x1", x(nearestTime));
+      cursor.setAttribute("x2", x(nearestTime));
+      cursor.setAttribute("visibility", "visible");
+      tooltip.innerHTML = "<b>" + formatDate(nearestTime, withTime) + "</b><br>" + rows.join("<br>");
+      tooltip.hidden = false;
+      tooltip.style.left = Math.min(evt.clientX + 14, window.innerWidth - tooltip.offsetWidth - 8) + "px";
+      tooltip.style.top = (evt.clientY + 14) + "px";
+    });
+    overlay.addEventListener("mouseleave", function () {
+      dragStart = null;
+      selection.setAttribute("visibility", "hidden");
+      cursor.setAttribute("visibility", "hidden");
+      tooltip.hidden = true;
+    });
+    overlay.addEventListener("mousedown", function (evt) {
+      dragStart = timeAt(evt);
+    });
+    overlay.addEventListener("mouseup", function (evt) {
+      var dragEnd = timeAt(evt);
+      var start = dragStart;
+      dragStart = null;
+      selection.setAttribute("visibility", "hidden");
+      if (start !== null && Math.abs(x(dragEnd) - x(start)) > 5) {
+        state.zoom = [Math.min(start, dragEnd), Math.max(start, dragEnd)];
+        tooltip.hidden = true;
+        render();
+      }
+    });
+    overlay.addEventListener("dblclick", resetZoom);
+    byId("reset").disabled = state.zoom === null;
+  }
+
+  function resetZoom() {
+    state.zoom = null;
+    state.highlight = null;
+    render();
+  }
+
+  function escapeHtml(text) {
+    return text.replace(/[&<>"]/g, function (c) {
+      return { "&": "&amp;", "<": "&lt;", ">": "&gt;", "\"": "&quot;" }[c];
+    });
+  }
+
+  byId("site").addEventListener("change", function () {
+    selectSite();
+  });
+  byId("metric").addEventListener("change", loadMetric);
+  byId("percent").addEventListener("change", loadMetric);
+  byId("reset").addEventListener("click", resetZoom);
+  window.addEventListener("resize", function () {
+    if (state.series.length) {
+      render();
+    }
+  });
+  loadSites();
+})();
@@ -0,0 +1,46 @@
+package reporting
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func Test_dashboardHandler(t *testing.T) {
+	server := newTestReportServer(t)
+	client := &http.Client{CheckRedirect: func(req *http.Request, via []*http.Request) error { return http.ErrUseLastResponse }}
+
+	tests := []struct {
+		name            string
+		url             string
+		wantStatus      int
+		wantContentType string
+		wantBody        string
+	}{
+		{name: "Redirect to the page", url: "/dashboard", wantStatus: http.StatusMovedPermanently},
+		{name: "Page", url: "/dashboard/", wantStatus: http.StatusOK, wantContentType: "text/html", wantBody: "<script src=\"dashboard.js\"></script>"},
+		{name: "Script", url: "/dashboard/dashboard.js", wantStatus: http.StatusOK, wantContentType: "javascript", wantBody: "/api/v1/alarms?site="},
+		{name: "Style sheet", url: "/dashboard/dashboard.css", wantStatus: http.StatusOK, wantContentType: "text/css"},
+		{name: "Unknown asset", url: "/dashboard/unknown.js", wantStatus: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := client.Get(server.URL + tt.url)
+			if err != nil {
+				t.Fatalf("GET %s error = %v", tt.url, err)
+			}
+			defer resp.Body.Close()
+			body, _ := io.ReadAll(resp.Body)
+			if resp.StatusCode != tt.wantStatus {
+				t.Fatalf("GET %s status = %d, want %d", tt.url, resp.StatusCode, tt.wantStatus)
+			}
+			if !strings.Contains(resp.Header.Get("Content-Type"), tt.wantContentType) {
+				t.Errorf("GET %s content type = %s, want %s", tt.url, resp.Header.Get("Content-Type"), tt.wantContentType)
+			}
+			if !strings.Contains(string(body), tt.wantBody) {
+				t.Errorf("GET %s body misses %s", tt.url, tt.wantBody)
+			}
+		})
+	}
+}
@@ -0,0 +1,280 @@
+package reporting
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"html"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ftfmtavares/anomalies-detector/analyser"
+	"github.com/ftfmtavares/anomalies-detector/collector"
+	"github.com/ftfmtavares/anomalies-detector/config"
+	"github.com/ftfmtavares/anomalies-detector/utils"
+
+	"github.com/wcharczuk/go-chart/v2"
+)
+
+//Const block defines the email notifier defaults
+//Digests embed the charts of at most emailMaxCharts metrics, so that a burst of events doesn't produce huge emails
+const (
+	defaultEmailPort    = 587
+	defaultEmailTlsPort = 465
+	emailMaxCharts      = 5
+	emailDialTimeout    = 10 * time.Second
+)
+
+//EmailNotifier sends a digest of the warnings and alarms of every report by email, with the charts of their metrics embedded
+type EmailNotifier struct {
+	host       string
+	port       int
+	tls        string
+	username   string
+	password   string
+	from       string
+	to         []string
+	recipients map[string][]string
+	locales    map[string]*config.LocaleParams
+	severities map[string]bool
+	routes     []string
+	send       func(to []string, message []byte) error
+}
+
+//NewEmailNotifier creates an EmailNotifier from the given parameters, the datasets providing the recipients and locale of each site
+//It returns an error if the server or the sender is missing or if the TLS mode is unknown
+func NewEmailNotifier(params config.EmailParams, datasets []config.Dataset) (*EmailNotifier, error) {
+	notifier := &EmailNotifier{
+		host:       params.Host,
+		port:       params.Port,
+		tls:        params.Tls,
+		username:   utils.ResolveSecret(params.Username),
+		password:   utils.ResolveSecret(params.Password),
+		from:       params.From,
+		to:         params.To,
+		recipients: map[string][]string{},
+		locales:    map[string]*config.LocaleParams{},
+		severities: map[string]bool{},
+		routes:     params.Routes,
+	}
+	notifier.send = notifier.sendMail
+
+	if notifier.host == "" || notifier.from == "" {
+		return nil, errors.New("email notifier requires a host and a from address")
+	}
+	if notifier.tls == "" {
+		notifier.tls = "starttls"
+	}
+	if notifier.tls != "starttls" && notifier.tls != "tls" && notifier.tls != "none" {
+		return nil, fmt.Errorf("email notifier - invalid tls \"%s\", it must be \"starttls\", \"tls\" or \"none\"", notifier.tls)
+	}
+	if notifier.port == 0 {
+		notifier.port = defaultEmailPort
+		if notifier.tls == "tls" {
+			notifier.port = defaultEmailTlsPort
+		}
+	}
+
+	for _, dataset := range datasets {
+		if len(dataset.EmailRecipients) > 0 {
+			notifier.recipients[dataset.SiteId] = dataset.EmailRecipients
+		}
+		notifier.locales[dataset.SiteId] = siteLocale(datasets, dataset.SiteId)
+	}
+	if len(params.Severities) == 0 {
+		params.Severities = []string{"warning", "alarm"}
+	}
+	for _, severity := range params.Severities {
+		notifier.severities[severity] = true
+	}
+
+	return notifier, nil
+}
+
+//Notify emails a digest of the warnings and alarms of a report to the recipients of its site, alarms first
+//The charts of the metrics with events are embedded when the site data is given, sites without recipients being skipped
+func (notifier *EmailNotifier) Notify(report analyser.OutlierReport, siteData collector.SiteData, found bool) error {
+	events := notificationEvents(report, notifier.severities, notifier.routes)
+	to := notifier.recipients[report.SiteId]
+	if len(to) == 0 {
+		to = notifier.to
+	}
+	if len(events) == 0 || len(to) == 0 {
+		return nil
+	}
+
+	message, err := notifier.digest(report, events, siteData, found, to)
+	if err != nil {
+		return fmt.Errorf("email notifier - %s", err.Error())
+	}
+	if err := notifier.send(to, message); err != nil {
+		return fmt.Errorf("email notifier - %s", err.Error())
+	}
+	return nil
+}
+
+//digest builds the MIME message of a report, an HTML list of its events followed by the charts of their metrics as inline images
+func (notifier *EmailNotifier) digest(report analyser.OutlierReport, events []NotificationEvent, siteData collector.SiteData, found bool, to []string) ([]byte, error) {
+	locale := notifier.locales[report.SiteId]
+	step, _ := utils.StrToDuration(report.TimeStep)
+	alarms := 0
+	for _, event := range events {
+		if event.Severity == analyser.VerdictAlarm {
+			alarms++
+		}
+	}
+
+	//Listing the events and the attributes of each metric to chart, in the order their metrics first appear
+	var body strings.Builder
+	body.WriteString(fmt.Sprintf("<html><body>\n<h2>%s</h2>\n<ul>\n", html.EscapeString(report.SiteId)))
+	metrics := []string{}
+	attributes := map[string][]string{}
+	for _, event := range events {
+		body.WriteString(fmt.Sprintf("<li><b>%s</b> - %s / %s from %s to %s (score %.1f, %s)</li>\n", event.Severity, html.EscapeString(event.Metric), html.EscapeString(event.Attribute),
+			formatLocaleTime(event.PeriodStart, step, locale), formatLocaleTime(event.PeriodEnd, step, locale), event.Score, event.Direction))
+		if _, present := attributes[event.Metric]; !present {
+			metrics = append(metrics, event.Metric)
+		}
+		if !containsString(attributes[event.Metric], event.Attribute) {
+			attributes[event.Metric] = append(attributes[event.Metric], event.Attribute)
+		}
+	}
+	body.WriteString("</ul>\n")
+
+	charts := map[string][]byte{}
+	if found {
+		for i, metric := range metrics {
+			if i == emailMaxCharts {
+				break
+			}
+			graph, ok := buildChart([]collector.SiteData{siteData}, []analyser.OutlierReport{report}, report.SiteId, siteData.TimeStep, metric, attributes[metric], locale)
+			if !ok {
+				continue
+			}
+			var png bytes.Buffer
+			if err := graph.Render(chart.PNG, &png); err != nil {
+				return nil, err
+			}
+			cid := fmt.Sprintf("chart-%d", i+1)
+			charts[cid] = png.Bytes()
+			body.WriteString(fmt.Sprintf("<p><img src=\"cid:%s\" alt=\"%s\" width=\"683\" /></p>\n", cid, html.EscapeString(metric)))
+		}
+	}
+	body.WriteString("</body></html>\n")
+
+	//Writing the headers and the related parts, the HTML body first
+	var message bytes.Buffer
+	parts := multipart.NewWriter(&message)
+	subject := fmt.Sprintf("[anomalies-detector] %s - %d alarms, %d warnings", report.SiteId, alarms, len(events)-alarms)
+	headers := []string{
+		"From: " + notifier.from,
+		"To: " + strings.Join(to, ", "),
+		"Subject: " + subject,
+		"Date: " + Clock.Now().Format(time.RFC1123Z),
+		"MIME-Version: 1.0",
+		fmt.Sprintf("Content-Type: multipart/related; boundary=%s", parts.Boundary()),
+	}
+	var res bytes.Buffer
+	res.WriteString(strings.Join(headers, "\r\n") + "\r\n\r\n")
+
+	htmlPart, err := parts.CreatePart(textproto.MIMEHeader{"Content-Type": {"text/html; charset=utf-8"}, "Content-Transfer-Encoding": {"quoted-printable"}})
+	if err != nil {
+		return nil, err
+	}
+	encoder := quotedprintable.NewWriter(htmlPart)
+	encoder.Write([]byte(body.String()))
+	encoder.Close()
+	for i := range metrics {
+		cid := fmt.Sprintf("chart-%d", i+1)
+		png, present := charts[cid]
+		if !present {
+			continue
+		}
+		imagePart, err := parts.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {"image/png"},
+			"Content-Transfer-Encoding": {"base64"},
+			"Content-Id":                {"<" + cid + ">"},
+			"Content-Disposition":       {fmt.Sprintf("inline; filename=\"%s.png\"", cid)},
+		})
+		if err != nil {
+			return nil, err
+		}
+		encoded := base64.StdEncoding.EncodeToString(png)
+		for len(encoded) > 76 {
+			imagePart.Write([]byte(encoded[:76] + "\r\n"))
+			encoded = encoded[76:]
+		}
+		imagePart.Write([]byte(encoded + "\r\n"))
+	}
+	if err := parts.Close(); err != nil {
+		return nil, err
+	}
+	res.Write(message.Bytes())
+	return res.Bytes(), nil
+}
+
+//sendMail delivers a message through the SMTP server, over implicit TLS, STARTTLS or plain text according to the TLS mode
+//The outbound TLS settings are used if configured, and the credentials are only sent if a username is given
+func (notifier *EmailNotifier) sendMail(to []string, message []byte) error {
+	addr := net.JoinHostPort(notifier.host, strconv.Itoa(notifier.port))
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	if utils.TlsConfig != nil {
+		tlsConfig = utils.TlsConfig.Clone()
+	}
+	tlsConfig.ServerName = notifier.host
+
+	var conn net.Conn
+	var err error
+	if notifier.tls == "tls" {
+		conn, err = tls.DialWithDialer(&net.Dialer{Timeout: emailDialTimeout}, "tcp", addr, tlsConfig)
+	} else {
+		conn, err = net.DialTimeout("tcp", addr, emailDialTimeout)
+	}
+	if err != nil {
+		return err
+	}
+	client, err := smtp.NewClient(conn, notifier.host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+
+	if notifier.tls == "starttls" {
+		if err := client.StartTLS(tlsConfig); err != nil {
+			return err
+		}
+	}
+	if notifier.username != "" {
+		if err := client.Auth(smtp.PlainAuth("", notifier.username, notifier.password, notifier.host)); err != nil {
+			return err
+		}
+	}
+	if err := client.Mail(notifier.from); err != nil {
+		return err
+	}
+	for _, recipient := range to {
+		if err := client.Rcpt(recipient); err != nil {
+			return err
+		}
+	}
+	writer, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := writer.Write(message); err != nil {
+		return err
+	}
+	if err := writer.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
@@ -0,0 +1,141 @@
+package reporting
+
+import (
+	"bytes"
+	"encoding/base64"
+	"image/png"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"strings"
+	"testing"
+
+	"github.com/ftfmtavares/anomalies-detector/collector"
+	"github.com/ftfmtavares/anomalies-detector/config"
+)
+
+func TestEmailNotifier_Notify(t *testing.T) {
+	sitesData, reports := reportFixture()
+	datasets := []config.Dataset{{SiteId: "shop", EmailRecipients: []string{"shop@example.com"}}, {SiteId: "blog"}}
+
+	tests := []struct {
+		name       string
+		severities []string
+		to         []string
+		reportIdx  int
+		found      bool
+		wantTo     []string
+		wantCharts int
+		wantEvents []string
+	}{
+		{
+			name:       "Digest with the chart of the metric",
+			reportIdx:  0,
+			found:      true,
+			wantTo:     []string{"shop@example.com"},
+			wantCharts: 1,
+			wantEvents: []string{"alarm", "Browser&gt;Edge", "warning", "Browser&gt;Chrome"},
+		},
+		{
+			name:       "Digest without data to chart",
+			severities: []string{"alarm"},
+			reportIdx:  0,
+			wantTo:     []string{"shop@example.com"},
+			wantEvents: []string{"alarm"},
+		},
+		{
+			name:      "Site without recipients nor default ones skipped",
+			reportIdx: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			notifier, err := NewEmailNotifier(config.EmailParams{Host: "smtp.example.com", From: "detector@example.com", To: tt.to, Severities: tt.severities}, datasets)
+			if err != nil {
+				t.Fatalf("NewEmailNotifier() error = %v", err)
+			}
+			var sentTo []string
+			var message []byte
+			notifier.send = func(to []string, msg []byte) error {
+				sentTo, message = to, msg
+				return nil
+			}
+
+			report := reports[tt.reportIdx]
+			siteData := collector.SiteData{}
+			if tt.found {
+				siteData = sitesData[0]
+			}
+			if err := notifier.Notify(report, siteData, tt.found); err != nil {
+				t.Fatalf("Notify() error = %v", err)
+			}
+			if strings.Join(sentTo, ",") != strings.Join(tt.wantTo, ",") {
+				t.Fatalf("Notify() sent to %v, want %v", sentTo, tt.wantTo)
+			}
+			if tt.wantTo == nil {
+				return
+			}
+
+			parsed, err := mail.ReadMessage(bytes.NewReader(message))
+			if err != nil {
+				t.Fatalf("ReadMessage() error = %v", err)
+			}
+			if subject := parsed.Header.Get("Subject"); !strings.Contains(subject, report.SiteId) {
+				t.Errorf("Notify() subject = %s", subject)
+			}
+			mediaType, mediaParams, err := mime.ParseMediaType(parsed.Header.Get("Content-Type"))
+			if err != nil || mediaType != "multipart/related" {
+				t.Fatalf("Notify() content type = %s", parsed.Header.Get("Content-Type"))
+			}
+
+			parts := multipart.NewReader(parsed.Body, mediaParams["boundary"])
+			body, charts := "", 0
+			for {
+				part, err := parts.NextPart()
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					t.Fatalf("NextPart() error = %v", err)
+				}
+				content, _ := io.ReadAll(part)
+				switch part.Header.Get("Content-Type") {
+				case "text/html; charset=utf-8":
+					body = string(content)
+				case "image/png":
+					charts++
+					decoded, err := base64.StdEncoding.DecodeString(strings.ReplaceAll(string(content), "\r\n", ""))
+					if err != nil {
+						t.Fatalf("DecodeString() error = %v", err)
+					}
+					if _, err := png.Decode(bytes.NewReader(decoded)); err != nil {
+						t.Errorf("Notify() chart is not a PNG - %v", err)
+					}
+					cid := strings.Trim(part.Header.Get("Content-Id"), "<>")
+					if !strings.Contains(body, "cid:"+cid) {
+						t.Errorf("Notify() chart %s not referenced by the body", cid)
+					}
+				}
+			}
+			if charts != tt.wantCharts {
+				t.Errorf("Notify() charts = %d, want %d", charts, tt.wantCharts)
+			}
+			for _, want := range tt.wantEvents {
+				if !strings.Contains(body, want) {
+					t.Errorf("Notify() body missing %s\n%s", want, body)
+				}
+			}
+			if len(tt.severities) == 1 && strings.Contains(body, "warning") {
+				t.Errorf("Notify() body with filtered severity\n%s", body)
+			}
+		})
+	}
+
+	if _, err := NewEmailNotifier(config.EmailParams{From: "detector@example.com"}, nil); err == nil {
+		t.Errorf("NewEmailNotifier() expected an error without host")
+	}
+	if _, err := NewEmailNotifier(config.EmailParams{Host: "smtp.example.com", From: "detector@example.com", Tls: "ssl"}, nil); err == nil {
+		t.Errorf("NewEmailNotifier() expected an error with an unknown tls mode")
+	}
+}
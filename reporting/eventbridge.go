@@ -0,0 +1,180 @@
+package reporting
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/ftfmtavares/anomalies-detector/analyser"
+	"github.com/ftfmtavares/anomalies-detector/config"
+	"github.com/ftfmtavares/anomalies-detector/utils"
+)
+
+//Const block defines the EventBridge publisher defaults
+//PutEvents accepts at most 10 entries per request
+const (
+	defaultEventBridgeSource = "anomalies-detector"
+	eventBridgeMaxEntries    = 10
+)
+
+//EventBridgePublisher puts detected warnings and alarms on an AWS EventBridge bus
+type EventBridgePublisher struct {
+	endpoint     string
+	region       string
+	eventBusName string
+	source       string
+	detailType   string
+	resources    []string
+	credentials  awsCredentials
+	severities   map[string]bool
+	routes       []string
+	client       *http.Client
+	now          func() time.Time
+}
+
+//eventBridgeEntry provides the structure of a PutEvents request entry, Detail being a JSON document in string format
+type eventBridgeEntry struct {
+	EventBusName string   `json:"EventBusName,omitempty"`
+	Source       string   `json:"Source"`
+	DetailType   string   `json:"DetailType"`
+	Detail       string   `json:"Detail"`
+	Resources    []string `json:"Resources,omitempty"`
+	Time         int64    `json:"Time"`
+}
+
+//NewEventBridgePublisher creates an EventBridgePublisher from the given parameters
+//It returns an error if the region or the credentials are missing
+func NewEventBridgePublisher(params config.EventBridgeParams) (*EventBridgePublisher, error) {
+	publisher := &EventBridgePublisher{
+		endpoint:     params.Endpoint,
+		region:       params.Region,
+		eventBusName: params.EventBusName,
+		source:       params.Source,
+		detailType:   params.DetailType,
+		resources:    params.Resources,
+		credentials: awsCredentials{
+			AccessKeyId:     utils.ResolveSecret(params.AccessKeyId),
+			SecretAccessKey: utils.ResolveSecret(params.SecretAccessKey),
+			SessionToken:    utils.ResolveSecret(params.SessionToken),
+		},
+		severities: map[string]bool{},
+		routes:     params.Routes,
+		client:     utils.NewHttpClient(10 * time.Second),
+		now:        Clock.Now,
+	}
+
+	if publisher.region == "" {
+		return nil, errors.New("eventbridge publisher requires a region")
+	}
+	if publisher.credentials.AccessKeyId == "" {
+		publisher.credentials = awsCredentials{
+			AccessKeyId:     os.Getenv("AWS_ACCESS_KEY_ID"),
+			SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+			SessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+		}
+	}
+	if publisher.credentials.AccessKeyId == "" || publisher.credentials.SecretAccessKey == "" {
+		return nil, errors.New("eventbridge publisher requires an accessKeyId and secretAccessKey")
+	}
+	if publisher.endpoint == "" {
+		publisher.endpoint = fmt.Sprintf("https://events.%s.amazonaws.com/", publisher.region)
+	}
+	if publisher.source == "" {
+		publisher.source = defaultEventBridgeSource
+	}
+
+	if len(params.Severities) == 0 {
+		params.Severities = []string{"warning", "alarm"}
+	}
+	for _, severity := range params.Severities {
+		publisher.severities[severity] = true
+	}
+
+	return publisher, nil
+}
+
+//Publish puts the warnings and alarms of a report on the bus, alarms first, in requests of up to 10 entries
+//Each entry detail holds the same event details as the CloudEvents data
+func (publisher *EventBridgePublisher) Publish(report analyser.OutlierReport) error {
+	entries := []eventBridgeEntry{}
+	for _, event := range CloudEvents(report, "", publisher.severities, publisher.routes) {
+		detail, err := json.Marshal(event.Data)
+		if err != nil {
+			return err
+		}
+		detailType := publisher.detailType
+		if detailType == "" && event.Data.Severity == "alarm" {
+			detailType = "Anomaly Alarm"
+		} else if detailType == "" {
+			detailType = "Anomaly Warning"
+		}
+		entries = append(entries, eventBridgeEntry{
+			EventBusName: publisher.eventBusName,
+			Source:       publisher.source,
+			DetailType:   detailType,
+			Detail:       string(detail),
+			Resources:    publisher.resources,
+			Time:         event.Time.Unix(),
+		})
+	}
+
+	for start := 0; start < len(entries); start += eventBridgeMaxEntries {
+		end := start + eventBridgeMaxEntries
+		if end > len(entries) {
+			end = len(entries)
+		}
+		if err := publisher.putEvents(entries[start:end]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//putEvents sends a single PutEvents request
+//It returns an error if the request fails or if any entry was rejected
+func (publisher *EventBridgePublisher) putEvents(entries []eventBridgeEntry) error {
+	body, err := json.Marshal(map[string]interface{}{"Entries": entries})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, publisher.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", "AWSEvents.PutEvents")
+	signAwsRequest(req, body, publisher.credentials, publisher.region, "events", publisher.now())
+
+	resp, err := publisher.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("eventbridge publisher - %s", err.Error())
+	}
+	defer resp.Body.Close()
+
+	var apiResp struct {
+		FailedEntryCount int    `json:"FailedEntryCount"`
+		Message          string `json:"message"`
+		Entries          []struct {
+			ErrorCode    string `json:"ErrorCode"`
+			ErrorMessage string `json:"ErrorMessage"`
+		} `json:"Entries"`
+	}
+	json.NewDecoder(resp.Body).Decode(&apiResp)
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("eventbridge publisher - unexpected status %s %s", resp.Status, apiResp.Message)
+	}
+	if apiResp.FailedEntryCount > 0 {
+		for _, entry := range apiResp.Entries {
+			if entry.ErrorCode != "" {
+				return fmt.Errorf("eventbridge publisher - %d entries failed, %s %s", apiResp.FailedEntryCount, entry.ErrorCode, entry.ErrorMessage)
+			}
+		}
+		return fmt.Errorf("eventbridge publisher - %d entries failed", apiResp.FailedEntryCount)
+	}
+	return nil
+}
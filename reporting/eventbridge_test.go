@@ -0,0 +1,73 @@
+package reporting
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ftfmtavares/anomalies-detector/analyser"
+	"github.com/ftfmtavares/anomalies-detector/config"
+)
+
+func TestEventBridgePublisher_Publish(t *testing.T) {
+	timeRef := time.Date(2022, 9, 1, 0, 0, 0, 0, time.UTC)
+
+	//12 alarms are published in 2 requests of up to 10 entries
+	report := analyser.OutlierReport{SiteId: "site", Result: analyser.OutlierResults{Warnings: []analyser.OutlierEvent{}, Alarms: []analyser.OutlierEvent{}}}
+	for i := 0; i < 12; i++ {
+		report.Result.Alarms = append(report.Result.Alarms, analyser.OutlierEvent{OutlierPeriodStart: timeRef, OutlierPeriodEnd: timeRef.AddDate(0, 0, 1), Metric: "Revenue", Attribute: fmt.Sprintf("Attribute>Sub%d", i)})
+	}
+
+	tests := []struct {
+		name        string
+		failed      int
+		wantBatches []int
+		wantErr     bool
+	}{
+		{name: "Entries split in batches of 10", wantBatches: []int{10, 2}},
+		{name: "Rejected entries", failed: 1, wantBatches: []int{10}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			batches := []int{}
+			server := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+				if req.Header.Get("X-Amz-Target") != "AWSEvents.PutEvents" || !strings.HasPrefix(req.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=AKID/20220901/eu-west-1/events/aws4_request") {
+					t.Errorf("unexpected headers %v", req.Header)
+				}
+				var payload struct {
+					Entries []eventBridgeEntry `json:"Entries"`
+				}
+				json.NewDecoder(req.Body).Decode(&payload)
+				batches = append(batches, len(payload.Entries))
+
+				entry := payload.Entries[0]
+				var detail CloudEventData
+				json.Unmarshal([]byte(entry.Detail), &detail)
+				if entry.Source != "anomalies-detector" || entry.DetailType != "Anomaly Alarm" || entry.EventBusName != "bus" || detail.SiteId != "site" || detail.Severity != "alarm" {
+					t.Errorf("unexpected entry %+v", entry)
+				}
+
+				json.NewEncoder(res).Encode(map[string]interface{}{"FailedEntryCount": tt.failed, "Entries": []map[string]string{{"ErrorCode": "InternalFailure"}}})
+			}))
+			defer server.Close()
+
+			publisher, err := NewEventBridgePublisher(config.EventBridgeParams{Region: "eu-west-1", EventBusName: "bus", AccessKeyId: "AKID", SecretAccessKey: "secret", Endpoint: server.URL})
+			if err != nil {
+				t.Fatalf("NewEventBridgePublisher() error = %v", err)
+			}
+			publisher.now = func() time.Time { return timeRef }
+
+			if err := publisher.Publish(report); (err != nil) != tt.wantErr {
+				t.Errorf("Publish() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(batches, tt.wantBatches) {
+				t.Errorf("Publish() batches = %v, want %v", batches, tt.wantBatches)
+			}
+		})
+	}
+}
@@ -0,0 +1,178 @@
+package reporting
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/ftfmtavares/anomalies-detector/analyser"
+	"github.com/ftfmtavares/anomalies-detector/config"
+	"github.com/ftfmtavares/anomalies-detector/utils"
+)
+
+//Const block defines the lifecycle statuses of a tracked event and how long resolved events are kept by default
+const (
+	EventOpened   = "opened"
+	EventOngoing  = "ongoing"
+	EventResolved = "resolved"
+
+	defaultEventStateExpiry = 30 * 24 * time.Hour
+)
+
+//EventState provides the structure for the lifecycle of a reported event, identified by its site, time step, metric and attribute along with its period
+//PeriodStart and PeriodEnd fields grow as later runs report the event again, while Severity field keeps the highest severity reported
+type EventState struct {
+	SiteId      string    `json:"siteId"`
+	TimeStep    string    `json:"timeStep"`
+	Metric      string    `json:"metric"`
+	Attribute   string    `json:"attribute"`
+	Severity    string    `json:"severity"`
+	PeriodStart time.Time `json:"periodStart"`
+	PeriodEnd   time.Time `json:"periodEnd"`
+	Status      string    `json:"status"`
+	FirstSeen   time.Time `json:"firstSeen"`
+	LastSeen    time.Time `json:"lastSeen"`
+	ResolvedAt  time.Time `json:"resolvedAt,omitempty"`
+}
+
+//EventStateStore tracks the events reported by every run on a state file, so that the events already notified by an earlier run are suppressed
+type EventStateStore struct {
+	mu                sync.Mutex
+	stateFile         string
+	suppressionWindow time.Duration
+	expiry            time.Duration
+	events            []*EventState
+}
+
+//NewEventStateStore creates an EventStateStore from the given parameters, loading the events tracked by the previous runs if any
+//It returns an error if a duration is invalid or if the state file can't be read
+func NewEventStateStore(params config.EventStateParams) (*EventStateStore, error) {
+	store := &EventStateStore{stateFile: params.StateFile, expiry: defaultEventStateExpiry, events: []*EventState{}}
+	var err error
+	if params.SuppressionWindow != "" {
+		if store.suppressionWindow, err = utils.StrToDuration(params.SuppressionWindow); err != nil || store.suppressionWindow <= 0 {
+			return nil, fmt.Errorf("event state suppressionWindow - invalid duration \"%s\"", params.SuppressionWindow)
+		}
+	}
+	if params.Expiry != "" {
+		if store.expiry, err = utils.StrToDuration(params.Expiry); err != nil || store.expiry <= 0 {
+			return nil, fmt.Errorf("event state expiry - invalid duration \"%s\"", params.Expiry)
+		}
+	}
+
+	content, err := os.ReadFile(store.stateFile)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("event state file - %s", err.Error())
+	}
+	if err == nil {
+		if err := json.Unmarshal(content, &store.events); err != nil {
+			return nil, fmt.Errorf("event state file - %s", err.Error())
+		}
+	}
+	return store, nil
+}
+
+//Track updates the lifecycle of the events of a report and returns the report holding only the events to notify, along with the events resolved by it
+//An event overlapping or adjoining a tracked one of the same site, time step, metric and attribute was already reported, being ongoing and suppressed unless it escalates
+//from warning to alarm, a recurrence within the suppression window of a resolved event reopening it quietly as well, while any other event is opened and notified
+//Tracked events of the site and time step not reported anymore are resolved, and resolved events older than the expiry are dropped before the state is saved
+func (store *EventStateStore) Track(report analyser.OutlierReport, now time.Time) (analyser.OutlierReport, []EventState, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	seen := map[*EventState]bool{}
+	track := func(event analyser.OutlierEvent, severity string) bool {
+		state := store.find(report, event, now)
+		if state == nil {
+			state = &EventState{SiteId: report.SiteId, TimeStep: report.TimeStep, Metric: event.Metric, Attribute: event.Attribute, Severity: severity,
+				PeriodStart: event.OutlierPeriodStart, PeriodEnd: event.OutlierPeriodEnd, Status: EventOpened, FirstSeen: now, LastSeen: now}
+			store.events = append(store.events, state)
+			seen[state] = true
+			return true
+		}
+
+		notify := state.Status != EventResolved && severity == analyser.VerdictAlarm && state.Severity != analyser.VerdictAlarm
+		if !seen[state] {
+			state.Status = EventOngoing
+		}
+		if severity == analyser.VerdictAlarm {
+			state.Severity = severity
+		}
+		if event.OutlierPeriodStart.Before(state.PeriodStart) {
+			state.PeriodStart = event.OutlierPeriodStart
+		}
+		if event.OutlierPeriodEnd.After(state.PeriodEnd) {
+			state.PeriodEnd = event.OutlierPeriodEnd
+		}
+		state.LastSeen, state.ResolvedAt = now, time.Time{}
+		seen[state] = true
+		return notify
+	}
+
+	res := report
+	res.Result = analyser.OutlierResults{Alarms: []analyser.OutlierEvent{}, Warnings: []analyser.OutlierEvent{}}
+	for _, event := range report.Result.Alarms {
+		if track(event, analyser.VerdictAlarm) {
+			res.Result.Alarms = append(res.Result.Alarms, event)
+		}
+	}
+	for _, event := range report.Result.Warnings {
+		if track(event, analyser.VerdictWarning) {
+			res.Result.Warnings = append(res.Result.Warnings, event)
+		}
+	}
+
+	//Resolving the events of the site no longer reported and dropping the expired ones
+	resolved := []EventState{}
+	events := []*EventState{}
+	for _, state := range store.events {
+		if state.SiteId == report.SiteId && state.TimeStep == report.TimeStep && state.Status != EventResolved && !seen[state] {
+			state.Status, state.ResolvedAt = EventResolved, now
+			resolved = append(resolved, *state)
+		}
+		if state.Status != EventResolved || now.Sub(state.ResolvedAt) <= store.expiry {
+			events = append(events, state)
+		}
+	}
+	store.events = events
+
+	content, err := json.MarshalIndent(store.events, "", "  ")
+	if err != nil {
+		return res, resolved, err
+	}
+	if err := utils.Files.WriteFile(store.stateFile, content, 0600); err != nil {
+		return res, resolved, fmt.Errorf("event state file - %s", err.Error())
+	}
+	return res, resolved, nil
+}
+
+//Events returns a copy of the tracked events, in the order they were first reported
+func (store *EventStateStore) Events() []EventState {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	events := []EventState{}
+	for _, state := range store.events {
+		events = append(events, *state)
+	}
+	return events
+}
+
+//find returns the tracked event of the report site and time step matching the given event, nil if none
+//Open events match when their periods overlap or adjoin, resolved ones as well or whatever their period within the suppression window
+func (store *EventStateStore) find(report analyser.OutlierReport, event analyser.OutlierEvent, now time.Time) *EventState {
+	var recurrence *EventState
+	for _, state := range store.events {
+		if state.SiteId != report.SiteId || state.TimeStep != report.TimeStep || state.Metric != event.Metric || state.Attribute != event.Attribute {
+			continue
+		}
+		if !event.OutlierPeriodStart.After(state.PeriodEnd) && !state.PeriodStart.After(event.OutlierPeriodEnd) {
+			return state
+		}
+		if state.Status == EventResolved && now.Sub(state.ResolvedAt) <= store.suppressionWindow {
+			recurrence = state
+		}
+	}
+	return recurrence
+}
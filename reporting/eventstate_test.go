@@ -0,0 +1,85 @@
+package reporting
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ftfmtavares/anomalies-detector/analyser"
+	"github.com/ftfmtavares/anomalies-detector/config"
+)
+
+func TestEventStateStore(t *testing.T) {
+	timeRef := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	stateFile := filepath.Join(t.TempDir(), "events.json")
+	event := func(metric string, start, end int) analyser.OutlierEvent {
+		return analyser.OutlierEvent{OutlierPeriodStart: timeRef.AddDate(0, 0, start), OutlierPeriodEnd: timeRef.AddDate(0, 0, end), Metric: metric, Attribute: "Total"}
+	}
+	report := func(alarms, warnings []analyser.OutlierEvent) analyser.OutlierReport {
+		return analyser.OutlierReport{SiteId: "shop", TimeStep: "1d", Result: analyser.OutlierResults{Alarms: alarms, Warnings: warnings}}
+	}
+
+	tests := []struct {
+		name         string
+		report       analyser.OutlierReport
+		wantAlarms   int
+		wantWarnings int
+		wantResolved int
+		wantStatus   map[string]string
+	}{
+		{
+			name:         "New events opened",
+			report:       report([]analyser.OutlierEvent{event("Revenue", 10, 11)}, []analyser.OutlierEvent{event("Visits", 10, 11)}),
+			wantAlarms:   1,
+			wantWarnings: 1,
+			wantStatus:   map[string]string{"Revenue": EventOpened, "Visits": EventOpened},
+		},
+		{
+			name:       "Overlapping run suppresses ongoing events and notifies the escalated one",
+			report:     report([]analyser.OutlierEvent{event("Revenue", 10, 12), event("Visits", 11, 12)}, nil),
+			wantAlarms: 1,
+			wantStatus: map[string]string{"Revenue": EventOngoing, "Visits": EventOngoing},
+		},
+		{
+			name:         "Events not reported anymore resolved",
+			report:       report(nil, []analyser.OutlierEvent{event("Visits", 11, 13)}),
+			wantResolved: 1,
+			wantStatus:   map[string]string{"Revenue": EventResolved, "Visits": EventOngoing},
+		},
+		{
+			name:       "Recurrence within the suppression window reopened quietly",
+			report:     report([]analyser.OutlierEvent{event("Revenue", 15, 16)}, []analyser.OutlierEvent{event("Visits", 11, 13)}),
+			wantStatus: map[string]string{"Revenue": EventOngoing, "Visits": EventOngoing},
+		},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			//Every run reloads the state saved by the previous one
+			store, err := NewEventStateStore(config.EventStateParams{StateFile: stateFile, SuppressionWindow: "2d"})
+			if err != nil {
+				t.Fatalf("NewEventStateStore() error = %v", err)
+			}
+			got, resolved, err := store.Track(tt.report, timeRef.AddDate(0, 0, 12).Add(time.Duration(i)*time.Hour))
+			if err != nil {
+				t.Fatalf("Track() error = %v", err)
+			}
+			if len(got.Result.Alarms) != tt.wantAlarms || len(got.Result.Warnings) != tt.wantWarnings || len(resolved) != tt.wantResolved {
+				t.Errorf("Track() = %d alarms, %d warnings and %d resolved, want %d, %d and %d", len(got.Result.Alarms), len(got.Result.Warnings), len(resolved), tt.wantAlarms, tt.wantWarnings, tt.wantResolved)
+			}
+			status := map[string]string{}
+			for _, state := range store.Events() {
+				status[state.Metric] = state.Status
+			}
+			for metric, want := range tt.wantStatus {
+				if status[metric] != want {
+					t.Errorf("Track() %s status = %s, want %s", metric, status[metric], want)
+				}
+			}
+		})
+	}
+
+	if _, err := NewEventStateStore(config.EventStateParams{StateFile: stateFile, Expiry: "never"}); err == nil {
+		t.Errorf("NewEventStateStore() expected an error on an invalid expiry")
+	}
+}
@@ -0,0 +1,170 @@
+package reporting
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/ftfmtavares/anomalies-detector/analyser"
+	"github.com/ftfmtavares/anomalies-detector/collector"
+
+	"github.com/wcharczuk/go-chart/v2"
+	"github.com/wcharczuk/go-chart/v2/drawing"
+)
+
+//Var block defines the colors of the expected values line and of the bands of tolerated deviations drawn around it
+var (
+	expectedColor    = drawing.Color{R: 96, G: 96, B: 96, A: 255}
+	normalBandColor  = drawing.Color{R: 0, G: 160, B: 0, A: 30}
+	warningBandColor = drawing.Color{R: 255, G: 140, B: 0, A: 50}
+)
+
+//bandSeries provides the structure for a chart series shading the band between a lower and an upper limit on each time step
+type bandSeries struct {
+	style   chart.Style
+	xValues []time.Time
+	lower   []float64
+	upper   []float64
+}
+
+//GetName returns an empty name, as bands are described by the expected values line on the legend
+func (band bandSeries) GetName() string { return "" }
+
+//GetStyle returns the style the band is filled with
+func (band bandSeries) GetStyle() chart.Style { return band.style }
+
+//GetYAxis returns the primary axis the band is drawn against
+func (band bandSeries) GetYAxis() chart.YAxisType { return chart.YAxisPrimary }
+
+//Validate checks that both limits are given for every time step of the band
+func (band bandSeries) Validate() error {
+	if len(band.lower) != len(band.xValues) || len(band.upper) != len(band.xValues) {
+		return errors.New("band limits and time steps lengths differ")
+	}
+	return nil
+}
+
+//Render fills the polygon going along the upper limit and back along the lower one
+func (band bandSeries) Render(r chart.Renderer, canvasBox chart.Box, xrange, yrange chart.Range, defaults chart.Style) {
+	if len(band.xValues) == 0 {
+		return
+	}
+	x := func(ind int) int { return canvasBox.Left + xrange.Translate(chart.TimeToFloat64(band.xValues[ind])) }
+	y := func(value float64) int { return canvasBox.Bottom - yrange.Translate(value) }
+
+	band.style.GetFillOptions().WriteDrawingOptionsToRenderer(r)
+	r.MoveTo(x(0), y(band.upper[0]))
+	for ind := 1; ind < len(band.xValues); ind++ {
+		r.LineTo(x(ind), y(band.upper[ind]))
+	}
+	for ind := len(band.xValues) - 1; ind >= 0; ind-- {
+		r.LineTo(x(ind), y(band.lower[ind]))
+	}
+	r.Close()
+	r.Fill()
+}
+
+//findExpectations returns the expectations the report of a site holds for an attribute/sub-values combination of a metric, if exported
+func findExpectations(outlierReports []analyser.OutlierReport, siteData collector.SiteData, metric, attribute string) (analyser.AttributeExpectations, bool) {
+	for _, outlierReport := range outlierReports {
+		if !reportMatchesSite(outlierReport, siteData) {
+			continue
+		}
+		for _, expectations := range outlierReport.Expectations {
+			if expectations.Metric == metric && expectations.Attribute == attribute {
+				return expectations, true
+			}
+		}
+	}
+	return analyser.AttributeExpectations{}, false
+}
+
+//bandedAttribute returns the charted attribute/sub-values combination the expectations are drawn for, the only one shown or the total among several
+func bandedAttribute(graph *chart.Chart) (string, bool) {
+	shown := []string{}
+	for _, series := range graph.Series {
+		if timeSeries, isTimeSeries := series.(chart.TimeSeries); isTimeSeries && timeSeries.Name != "" {
+			shown = append(shown, timeSeries.Name)
+		}
+	}
+	if len(shown) == 1 {
+		return shown[0], true
+	}
+	for _, attribute := range shown {
+		if attribute == "Total" {
+			return attribute, true
+		}
+	}
+	return "", false
+}
+
+//addExpectationBands draws the expected values of a charted series as a dashed line, along with the bands of the deviations its detection method tolerated
+//Deviations within the warning limits are shaded green and those between the warning and alarm limits orange, only the time steps within the charted range being drawn
+//Bands are drawn under the charted series and clipped to the value range of the chart, so wide bands don't flatten the series
+func addExpectationBands(graph *chart.Chart, expectations analyser.AttributeExpectations) {
+	var from, to time.Time
+	for _, series := range graph.Series {
+		if timeSeries, isTimeSeries := series.(chart.TimeSeries); isTimeSeries && timeSeries.Name == expectations.Attribute && len(timeSeries.XValues) > 0 {
+			from, to = timeSeries.XValues[0], timeSeries.XValues[len(timeSeries.XValues)-1]
+		}
+	}
+	steps := []analyser.StepExpectation{}
+	for _, step := range expectations.Steps {
+		if !step.DateStart.Before(from) && !step.DateStart.After(to) {
+			steps = append(steps, step)
+		}
+	}
+	if len(steps) < 2 || graph.YAxis.Range == nil {
+		return
+	}
+	bottom, top := graph.YAxis.Range.GetMin(), graph.YAxis.Range.GetMax()
+
+	//Limits are given as multiples of the tolerated deviations, a zero deviation meaning the method has no such limit
+	band := func(lowerWarning, lowerAlarm, upperWarning, upperAlarm float64, color drawing.Color) bandSeries {
+		series := bandSeries{
+			style:   chart.Style{FillColor: color},
+			xValues: make([]time.Time, len(steps)),
+			lower:   make([]float64, len(steps)),
+			upper:   make([]float64, len(steps)),
+		}
+		for i, step := range steps {
+			series.xValues[i] = step.DateStart
+			series.lower[i] = math.Max(bottom, math.Min(top, step.Expected+lowerWarning*step.WarningDeviation+lowerAlarm*step.AlarmDeviation))
+			series.upper[i] = math.Max(bottom, math.Min(top, step.Expected+upperWarning*step.WarningDeviation+upperAlarm*step.AlarmDeviation))
+		}
+		return series
+	}
+	bands := []chart.Series{}
+	switch {
+	case steps[0].WarningDeviation > 0 && steps[0].AlarmDeviation > 0:
+		bands = append(bands, band(-1, 0, 1, 0, normalBandColor), band(1, 0, 0, 1, warningBandColor), band(0, -1, -1, 0, warningBandColor))
+	case steps[0].WarningDeviation > 0:
+		bands = append(bands, band(-1, 0, 1, 0, normalBandColor))
+	case steps[0].AlarmDeviation > 0:
+		bands = append(bands, band(0, -1, 0, 1, normalBandColor))
+	}
+
+	expected := chart.TimeSeries{
+		Name:    fmt.Sprintf("expected (%s)", expectations.Method),
+		Style:   chart.Style{StrokeColor: expectedColor, StrokeWidth: 1, StrokeDashArray: []float64{4, 4}},
+		XValues: make([]time.Time, len(steps)),
+		YValues: make([]float64, len(steps)),
+	}
+	for i, step := range steps {
+		expected.XValues[i] = step.DateStart
+		expected.YValues[i] = step.Expected
+	}
+
+	//Charted series keep the colors of their position, default colors following the position of the series drawn before them
+	for i, series := range graph.Series {
+		if timeSeries, isTimeSeries := series.(chart.TimeSeries); isTimeSeries && timeSeries.Style.StrokeColor.IsZero() {
+			timeSeries.Style.StrokeColor = graph.GetColorPalette().GetSeriesColor(i)
+			graph.Series[i] = timeSeries
+		}
+	}
+	graph.Series = append(append(bands, expected), graph.Series...)
+
+	//The legend is rebuilt to list the expected values, the one set by buildChart holding its own copy of the chart
+	graph.Elements = []chart.Renderable{chart.LegendLeft(graph)}
+}
@@ -0,0 +1,107 @@
+package reporting
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ftfmtavares/anomalies-detector/analyser"
+	"github.com/ftfmtavares/anomalies-detector/config"
+
+	"github.com/wcharczuk/go-chart/v2"
+)
+
+func TestReportExpectations(t *testing.T) {
+	sitesData, reports := reportFixture()
+
+	//The daily revenue total is expected to stay around its weekly level from its 8th day on, with its spike out of both bands
+	steps := []analyser.StepExpectation{}
+	for _, stepData := range sitesData[0].Metrics[0].AttributeData["Total"][7:] {
+		steps = append(steps, analyser.StepExpectation{DateStart: stepData.DateStart, Expected: 1150, WarningDeviation: 120, AlarmDeviation: 240})
+	}
+	reports[0].Expectations = []analyser.AttributeExpectations{{Metric: "Revenue", Attribute: "Total", Method: "rolling-3-sigmas", Steps: steps}}
+	handler := newReportRouter(sitesData, reports, config.ReportServerParams{RateLimit: config.RateLimitParams{Burst: 100}}, DetectionSettings{})
+
+	tests := []struct {
+		name   string
+		url    string
+		golden string
+	}{
+		{name: "Bands of the total among all attributes", url: "/report/shop/Revenue", golden: "shop-revenue-expectations.png.sha256"},
+		{name: "Bands of the total zoomed into", url: "/report/shop/Revenue?attribute=Total&from=2022-08-20T00:00:00Z", golden: "shop-revenue-expectations-zoom.png.sha256"},
+		{name: "No bands for an attribute without expectations", url: "/report/shop/Revenue?attribute=browser>edge", golden: "shop-revenue-edge.png.sha256"},
+		{name: "No bands on the percent scale", url: "/report/shop/Revenue?scale=percent", golden: "shop-revenue-percent.png.sha256"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := httptest.NewRecorder()
+			handler.ServeHTTP(res, httptest.NewRequest(http.MethodGet, tt.url, nil))
+			if res.Code != http.StatusOK || res.Header().Get("Content-Type") != "image/png" {
+				t.Fatalf("GET %s status = %d, content type %s, want a PNG chart", tt.url, res.Code, res.Header().Get("Content-Type"))
+			}
+			hash := sha256.Sum256(res.Body.Bytes())
+			assertGolden(t, tt.golden, []byte(hex.EncodeToString(hash[:])+"\n"))
+		})
+	}
+}
+
+func Test_addExpectationBands(t *testing.T) {
+	timeRef := time.Date(2022, 9, 1, 0, 0, 0, 0, time.UTC)
+	dates := []time.Time{timeRef, timeRef.AddDate(0, 0, 1), timeRef.AddDate(0, 0, 2)}
+	newGraph := func(names ...string) chart.Chart {
+		graph := chart.Chart{YAxis: chart.YAxis{Range: &chart.ContinuousRange{Min: 0, Max: 120}}}
+		for _, name := range names {
+			graph.Series = append(graph.Series, chart.TimeSeries{Name: name, XValues: dates, YValues: []float64{80, 100, 90}})
+		}
+		return graph
+	}
+	expectations := func(warning, alarm float64) analyser.AttributeExpectations {
+		steps := []analyser.StepExpectation{{DateStart: timeRef.AddDate(0, 0, -1), Expected: 90}}
+		for _, date := range dates {
+			steps = append(steps, analyser.StepExpectation{DateStart: date, Expected: 90, WarningDeviation: warning, AlarmDeviation: alarm})
+		}
+		return analyser.AttributeExpectations{Metric: "Revenue", Attribute: "Total", Method: "3-sigmas", Steps: steps}
+	}
+
+	tests := []struct {
+		name           string
+		graph          chart.Chart
+		expectations   analyser.AttributeExpectations
+		wantBanded     bool
+		wantBands      int
+		wantUpperLimit float64
+	}{
+		{name: "Warning and alarm bands clipped to the chart range", graph: newGraph("Total", "Browser>Edge"), expectations: expectations(20, 40), wantBanded: true, wantBands: 3, wantUpperLimit: 120},
+		{name: "Single band without an alarm limit", graph: newGraph("Total"), expectations: expectations(20, 0), wantBanded: true, wantBands: 1, wantUpperLimit: 110},
+		{name: "No bands among several attributes without the total", graph: newGraph("Browser>Chrome", "Browser>Edge"), expectations: expectations(20, 40)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attribute, banded := bandedAttribute(&tt.graph)
+			if banded != tt.wantBanded {
+				t.Fatalf("bandedAttribute() = %s, %v, want %v", attribute, banded, tt.wantBanded)
+			}
+			if !banded {
+				return
+			}
+			charted := len(tt.graph.Series)
+			addExpectationBands(&tt.graph, tt.expectations)
+			if got := len(tt.graph.Series) - charted; got != tt.wantBands+1 {
+				t.Fatalf("addExpectationBands() added %d series, want %d bands and the expected line", got, tt.wantBands)
+			}
+			upper := tt.graph.Series[tt.wantBands-1].(bandSeries)
+			if tt.wantBands > 1 {
+				upper = tt.graph.Series[1].(bandSeries)
+			}
+			if len(upper.xValues) != len(dates) || upper.upper[0] != tt.wantUpperLimit {
+				t.Errorf("addExpectationBands() upper limit = %v, want %d charted steps at %g", upper.upper, len(dates), tt.wantUpperLimit)
+			}
+			if expected := tt.graph.Series[tt.wantBands].(chart.TimeSeries); expected.Name != "expected (3-sigmas)" {
+				t.Errorf("addExpectationBands() expected line = %s, want it named after the method", expected.Name)
+			}
+		})
+	}
+}
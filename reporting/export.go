@@ -0,0 +1,177 @@
+package reporting
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/ftfmtavares/anomalies-detector/analyser"
+	"github.com/ftfmtavares/anomalies-detector/collector"
+	"github.com/ftfmtavares/anomalies-detector/utils"
+)
+
+//Const block defines the supported formats of the data and report files
+const (
+	FormatJson    = "json"
+	FormatCsv     = "csv"
+	FormatParquet = "parquet"
+)
+
+//Const block defines the kinds of values held by the exported columns
+const (
+	columnString = iota
+	columnInt
+	columnFloat
+	columnTime
+)
+
+//exportTable provides the structure for the flat rows written on CSV and Parquet files, held by column
+type exportTable struct {
+	columns []exportColumn
+	rows    int
+}
+
+//exportColumn holds the values of a column on the slice matching its kind, times being kept as milliseconds since the epoch
+type exportColumn struct {
+	name    string
+	kind    int
+	strings []string
+	ints    []int64
+	floats  []float64
+}
+
+//ValidateFormat checks if a data or report file format is supported
+func ValidateFormat(format string) error {
+	switch format {
+	case FormatJson, FormatCsv, FormatParquet:
+		return nil
+	default:
+		return fmt.Errorf("invalid format, %s, %s or %s expected", FormatJson, FormatCsv, FormatParquet)
+	}
+}
+
+//WriteData stores the collected data on a CSV or Parquet file, as one row per site, time step, metric, attribute/sub-values combination and timestamp
+func WriteData(sitesData []collector.SiteData, format, filename string) error {
+	table := newExportTable(
+		exportColumn{name: "site", kind: columnString},
+		exportColumn{name: "time_step", kind: columnString},
+		exportColumn{name: "metric", kind: columnString},
+		exportColumn{name: "attribute", kind: columnString},
+		exportColumn{name: "timestamp", kind: columnTime},
+		exportColumn{name: "value", kind: columnFloat},
+		exportColumn{name: "samples", kind: columnInt},
+	)
+	for _, siteData := range sitesData {
+		for _, metricData := range siteData.Metrics {
+			for _, attribute := range metricData.Attributes {
+				for _, stepData := range metricData.AttributeData[attribute] {
+					table.addRow(siteData.SiteId, siteData.TimeStep, metricData.Metric, attribute, stepData.DateStart, stepData.Value, stepData.Samples)
+				}
+			}
+		}
+	}
+	return writeTable(table, format, filename)
+}
+
+//WriteReports stores the detected events on a CSV or Parquet file, as one row per event with its site, time step and severity
+func WriteReports(reports []analyser.OutlierReport, format, filename string) error {
+	table := newExportTable(
+		exportColumn{name: "site", kind: columnString},
+		exportColumn{name: "time_step", kind: columnString},
+		exportColumn{name: "severity", kind: columnString},
+		exportColumn{name: "metric", kind: columnString},
+		exportColumn{name: "attribute", kind: columnString},
+		exportColumn{name: "period_start", kind: columnTime},
+		exportColumn{name: "period_end", kind: columnTime},
+		exportColumn{name: "score", kind: columnFloat},
+		exportColumn{name: "observed", kind: columnFloat},
+		exportColumn{name: "expected", kind: columnFloat},
+		exportColumn{name: "deviation_percent", kind: columnFloat},
+		exportColumn{name: "direction", kind: columnString},
+	)
+	for _, report := range reports {
+		for _, event := range filterEvents([]analyser.OutlierReport{report}, "", "", "", map[string]bool{analyser.VerdictAlarm: true, analyser.VerdictWarning: true}, seriesQuery{}) {
+			table.addRow(event.SiteId, event.TimeStep, event.Severity, event.Metric, event.Attribute, event.OutlierPeriodStart, event.OutlierPeriodEnd, event.Score, event.Observed, event.Expected, event.DeviationPercent, event.Direction)
+		}
+	}
+	return writeTable(table, format, filename)
+}
+
+//newExportTable creates an empty table with the given columns
+func newExportTable(columns ...exportColumn) exportTable {
+	return exportTable{columns: columns}
+}
+
+//addRow appends a row to the table, its values being given in the columns order
+func (table *exportTable) addRow(values ...interface{}) {
+	for i := range table.columns {
+		column := &table.columns[i]
+		switch value := values[i].(type) {
+		case string:
+			column.strings = append(column.strings, value)
+		case int:
+			column.ints = append(column.ints, int64(value))
+		case float64:
+			column.floats = append(column.floats, value)
+		case time.Time:
+			column.ints = append(column.ints, value.UnixMilli())
+		}
+	}
+	table.rows++
+}
+
+//writeTable writes the table on the file in the given format, atomically so an invalid format or a failed export leaves any previous file untouched
+func writeTable(table exportTable, format, filename string) error {
+	if err := ValidateFormat(format); err != nil {
+		return err
+	}
+
+	var buffer bytes.Buffer
+	var err error
+	switch format {
+	case FormatCsv:
+		err = writeCsv(&buffer, table)
+	case FormatParquet:
+		err = writeParquet(&buffer, table)
+	default:
+		return fmt.Errorf("invalid format, %s or %s expected for flat rows", FormatCsv, FormatParquet)
+	}
+	if err != nil {
+		return err
+	}
+	return utils.Files.WriteFile(filename, buffer.Bytes(), 0o666)
+}
+
+//writeCsv writes a table as a CSV file with a header row, times being formatted as RFC3339 in UTC
+func writeCsv(w io.Writer, table exportTable) error {
+	writer := csv.NewWriter(w)
+	record := make([]string, len(table.columns))
+	for i, column := range table.columns {
+		record[i] = column.name
+	}
+	if err := writer.Write(record); err != nil {
+		return err
+	}
+	for row := 0; row < table.rows; row++ {
+		for i, column := range table.columns {
+			switch column.kind {
+			case columnString:
+				record[i] = column.strings[row]
+			case columnInt:
+				record[i] = strconv.FormatInt(column.ints[row], 10)
+			case columnFloat:
+				record[i] = strconv.FormatFloat(column.floats[row], 'f', -1, 64)
+			case columnTime:
+				record[i] = time.UnixMilli(column.ints[row]).UTC().Format(time.RFC3339)
+			}
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
@@ -0,0 +1,121 @@
+package reporting
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestWriteData(t *testing.T) {
+	sitesData, _ := reportFixture()
+	file := filepath.Join(t.TempDir(), "data.csv")
+	if err := WriteData(sitesData, FormatCsv, file); err != nil {
+		t.Fatalf("WriteData() error = %v", err)
+	}
+	content, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	//Every time step of every series is a row, after the header row
+	lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+	if want := 1 + 3*30 + 3*48 + 2*30; len(lines) != want {
+		t.Fatalf("WriteData() = %d lines, want %d", len(lines), want)
+	}
+	if want := "site,time_step,metric,attribute,timestamp,value,samples"; lines[0] != want {
+		t.Errorf("WriteData() header = %s, want %s", lines[0], want)
+	}
+	if want := "shop,1d,Revenue,Total,2022-08-02T00:00:00Z,1000,1000"; lines[1] != want {
+		t.Errorf("WriteData() first row = %s, want %s", lines[1], want)
+	}
+}
+
+func TestWriteReports(t *testing.T) {
+	_, reports := reportFixture()
+	dir := t.TempDir()
+
+	tests := []struct {
+		name   string
+		format string
+		check  func(t *testing.T, content []byte)
+	}{
+		{
+			name:   "CSV with one row per event",
+			format: FormatCsv,
+			check: func(t *testing.T, content []byte) {
+				lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+				if len(lines) != 5 {
+					t.Fatalf("WriteReports() = %d lines, want 5", len(lines))
+				}
+				if want := "shop,1h,alarm,Revenue,Browser>Edge,2022-08-31T21:00:00Z,2022-08-31T22:00:00Z,0,0,0,0,"; lines[4] != want {
+					t.Errorf("WriteReports() last row = %s, want %s", lines[4], want)
+				}
+			},
+		},
+		{
+			name:   "Parquet framed by its magic with the footer length",
+			format: FormatParquet,
+			check: func(t *testing.T, content []byte) {
+				if !bytes.HasPrefix(content, parquetMagic) || !bytes.HasSuffix(content, parquetMagic) {
+					t.Fatalf("WriteReports() missing Parquet magic")
+				}
+				footer := int(binary.LittleEndian.Uint32(content[len(content)-8:]))
+				if footer <= 0 || footer > len(content)-12 {
+					t.Fatalf("WriteReports() footer length = %d on %d bytes", footer, len(content))
+				}
+				for _, name := range []string{"deviation_percent", "anomalies-detector"} {
+					if !bytes.Contains(content[len(content)-8-footer:], []byte(name)) {
+						t.Errorf("WriteReports() footer missing %s", name)
+					}
+				}
+				if !bytes.Contains(content[4:len(content)-8-footer], []byte("Browser>Chrome")) {
+					t.Errorf("WriteReports() pages missing the event attributes")
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := filepath.Join(dir, "report."+tt.format)
+			if err := WriteReports(reports, tt.format, file); err != nil {
+				t.Fatalf("WriteReports() error = %v", err)
+			}
+			content, err := os.ReadFile(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			tt.check(t, content)
+		})
+	}
+
+	//Invalid formats fail before any file is written, keeping the previous output
+	for _, format := range []string{"xml", FormatJson} {
+		file := filepath.Join(dir, "report.csv")
+		if err := WriteReports(reports, format, file); err == nil {
+			t.Errorf("WriteReports() expected an error on the %s format", format)
+		}
+		if content, err := os.ReadFile(file); err != nil || !strings.HasPrefix(string(content), "site,time_step,severity") {
+			t.Errorf("WriteReports() on the %s format changed the previous file, error = %v", format, err)
+		}
+		if _, err := os.Stat(file + ".tmp"); !os.IsNotExist(err) {
+			t.Errorf("WriteReports() on the %s format left a temporary file", format)
+		}
+	}
+}
+
+func Test_thriftWriter(t *testing.T) {
+	tw := &thriftWriter{}
+	tw.i32(1, -1)
+	tw.binary(4, "a")
+	tw.structBegin(20)
+	tw.i64(1, 300)
+	tw.structEnd()
+	tw.stop()
+	want := []byte{0x15, 0x01, 0x38, 0x01, 'a', 0x0c, 0x28, 0x16, 0xd8, 0x04, 0x00, 0x00}
+	if got := tw.buf.Bytes(); !bytes.Equal(got, want) {
+		t.Errorf("thriftWriter = %x, want %x", got, want)
+	}
+}
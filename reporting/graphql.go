@@ -0,0 +1,443 @@
+package reporting
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ftfmtavares/anomalies-detector/analyser"
+	"github.com/ftfmtavares/anomalies-detector/collector"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/parser"
+)
+
+//Const block defines the limits of the GraphQL queries, checked before executing them so that nested contributors or repeated fields and fragments can't exhaust the server
+//Complexity counts every selected field and fragment spread, fragments being expanded where they're spread
+const (
+	graphqlMaxDepth      = 10
+	graphqlMaxComplexity = 500
+)
+
+//gqlSite is the source object resolved by the GraphQL Site type
+type gqlSite struct {
+	data   collector.SiteData
+	report analyser.OutlierReport
+}
+
+//gqlMetric is the source object resolved by the GraphQL Metric type
+type gqlMetric struct {
+	site gqlSite
+	data collector.MetricData
+}
+
+//gqlEvent is the source object resolved by the GraphQL Event type
+//The whole site report is kept so that contributors can be looked up
+type gqlEvent struct {
+	event    analyser.OutlierEvent
+	severity string
+	report   analyser.OutlierReport
+}
+
+//graphqlRequest provides the structure of a GraphQL request received through POST
+type graphqlRequest struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+//newGraphqlSchema builds the GraphQL schema over the collected data and outlier reports
+//The schema allows nested queries from sites to metrics, series and events, where events expose their contributors
+//(events of descendant attributes of the same metric overlapping the same period)
+func newGraphqlSchema(sitesData []collector.SiteData, outlierReports []analyser.OutlierReport) (graphql.Schema, error) {
+
+	timeStepType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "TimeStep",
+		Fields: graphql.Fields{
+			"dateStart": &graphql.Field{Type: graphql.DateTime, Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return p.Source.(collector.TimeStepData).DateStart, nil
+			}},
+			"value": &graphql.Field{Type: graphql.Float, Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return p.Source.(collector.TimeStepData).Value, nil
+			}},
+			"samples": &graphql.Field{Type: graphql.Int, Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return p.Source.(collector.TimeStepData).Samples, nil
+			}},
+		},
+	})
+
+	seriesType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Series",
+		Fields: graphql.Fields{
+			"attribute": &graphql.Field{Type: graphql.String, Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return p.Source.(SeriesData).Attribute, nil
+			}},
+			"data": &graphql.Field{Type: graphql.NewList(timeStepType), Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return p.Source.(SeriesData).Data, nil
+			}},
+		},
+	})
+
+	eventType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Event",
+		Fields: graphql.Fields{
+			"siteId": &graphql.Field{Type: graphql.String, Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return p.Source.(gqlEvent).report.SiteId, nil
+			}},
+			"severity": &graphql.Field{Type: graphql.String, Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return p.Source.(gqlEvent).severity, nil
+			}},
+			"metric": &graphql.Field{Type: graphql.String, Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return p.Source.(gqlEvent).event.Metric, nil
+			}},
+			"attribute": &graphql.Field{Type: graphql.String, Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return p.Source.(gqlEvent).event.Attribute, nil
+			}},
+			"resolution": &graphql.Field{Type: graphql.String, Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return p.Source.(gqlEvent).event.Resolution, nil
+			}},
+			"periodStart": &graphql.Field{Type: graphql.DateTime, Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return p.Source.(gqlEvent).event.OutlierPeriodStart, nil
+			}},
+			"periodEnd": &graphql.Field{Type: graphql.DateTime, Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return p.Source.(gqlEvent).event.OutlierPeriodEnd, nil
+			}},
+			"routes": &graphql.Field{Type: graphql.NewList(graphql.String), Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return p.Source.(gqlEvent).event.Routes, nil
+			}},
+			"score": &graphql.Field{Type: graphql.Float, Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return p.Source.(gqlEvent).event.Score, nil
+			}},
+			"observed": &graphql.Field{Type: graphql.Float, Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return p.Source.(gqlEvent).event.Observed, nil
+			}},
+			"expected": &graphql.Field{Type: graphql.Float, Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return p.Source.(gqlEvent).event.Expected, nil
+			}},
+			"deviationPercent": &graphql.Field{Type: graphql.Float, Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return p.Source.(gqlEvent).event.DeviationPercent, nil
+			}},
+			"direction": &graphql.Field{Type: graphql.String, Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return p.Source.(gqlEvent).event.Direction, nil
+			}},
+		},
+	})
+	eventType.AddFieldConfig("contributors", &graphql.Field{
+		Type: graphql.NewList(eventType),
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			return eventContributors(p.Source.(gqlEvent)), nil
+		},
+	})
+
+	//eventsArgs is reused by the events fields of both Site and Metric types
+	eventsArgs := graphql.FieldConfigArgument{
+		"attributes": &graphql.ArgumentConfig{Type: graphql.NewList(graphql.String)},
+	}
+
+	metricType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Metric",
+		Fields: graphql.Fields{
+			"metric": &graphql.Field{Type: graphql.String, Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return p.Source.(gqlMetric).data.Metric, nil
+			}},
+			"unit": &graphql.Field{Type: graphql.String, Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return p.Source.(gqlMetric).data.Unit, nil
+			}},
+			"attributes": &graphql.Field{Type: graphql.NewList(graphql.String), Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return p.Source.(gqlMetric).data.Attributes, nil
+			}},
+			"series": &graphql.Field{
+				Type: graphql.NewList(seriesType),
+				Args: graphql.FieldConfigArgument{
+					"attributes": &graphql.ArgumentConfig{Type: graphql.NewList(graphql.String)},
+					"from":       &graphql.ArgumentConfig{Type: graphql.DateTime},
+					"to":         &graphql.ArgumentConfig{Type: graphql.DateTime},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					metric := p.Source.(gqlMetric)
+					query := seriesQuery{attributes: stringListArg(p.Args, "attributes"), limit: len(metric.data.Attributes)}
+					query.from, _ = p.Args["from"].(time.Time)
+					query.to, _ = p.Args["to"].(time.Time)
+					return filterSeries(metric.data, query).Series, nil
+				},
+			},
+			"alarms": &graphql.Field{Type: graphql.NewList(eventType), Args: eventsArgs, Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				metric := p.Source.(gqlMetric)
+				return selectEvents(metric.site.report, metric.site.report.Result.Alarms, "alarm", metric.data.Metric, stringListArg(p.Args, "attributes")), nil
+			}},
+			"warnings": &graphql.Field{Type: graphql.NewList(eventType), Args: eventsArgs, Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				metric := p.Source.(gqlMetric)
+				return selectEvents(metric.site.report, metric.site.report.Result.Warnings, "warning", metric.data.Metric, stringListArg(p.Args, "attributes")), nil
+			}},
+		},
+	})
+
+	siteType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Site",
+		Fields: graphql.Fields{
+			"siteId": &graphql.Field{Type: graphql.String, Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return p.Source.(gqlSite).data.SiteId, nil
+			}},
+			"timeStep": &graphql.Field{Type: graphql.String, Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return p.Source.(gqlSite).data.TimeStep, nil
+			}},
+			"dateStart": &graphql.Field{Type: graphql.DateTime, Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return p.Source.(gqlSite).data.DateStart, nil
+			}},
+			"dateEnd": &graphql.Field{Type: graphql.DateTime, Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return p.Source.(gqlSite).data.DateEnd, nil
+			}},
+			"detectionMethod": &graphql.Field{Type: graphql.String, Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return p.Source.(gqlSite).report.OutliersDetectionMethod, nil
+			}},
+			"metrics": &graphql.Field{
+				Type: graphql.NewList(metricType),
+				Args: graphql.FieldConfigArgument{
+					"names": &graphql.ArgumentConfig{Type: graphql.NewList(graphql.String)},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					site := p.Source.(gqlSite)
+					names := stringListArg(p.Args, "names")
+					metrics := []gqlMetric{}
+					for _, metricData := range site.data.Metrics {
+						if len(names) == 0 || containsString(names, metricData.Metric) {
+							metrics = append(metrics, gqlMetric{site: site, data: metricData})
+						}
+					}
+					return metrics, nil
+				},
+			},
+			"alarms": &graphql.Field{Type: graphql.NewList(eventType), Args: eventsArgs, Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				site := p.Source.(gqlSite)
+				return selectEvents(site.report, site.report.Result.Alarms, "alarm", "", stringListArg(p.Args, "attributes")), nil
+			}},
+			"warnings": &graphql.Field{Type: graphql.NewList(eventType), Args: eventsArgs, Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				site := p.Source.(gqlSite)
+				return selectEvents(site.report, site.report.Result.Warnings, "warning", "", stringListArg(p.Args, "attributes")), nil
+			}},
+		},
+	})
+
+	//Joining each site data with the respective report once, since both lists are immutable while the server runs
+	sites := []gqlSite{}
+	for _, siteData := range sitesData {
+		site := gqlSite{data: siteData}
+		for _, report := range outlierReports {
+			if reportMatchesSite(report, siteData) {
+				site.report = report
+				break
+			}
+		}
+		sites = append(sites, site)
+	}
+
+	queryType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Query",
+		Fields: graphql.Fields{
+			"sites": &graphql.Field{
+				Type: graphql.NewList(siteType),
+				Args: graphql.FieldConfigArgument{
+					"ids": &graphql.ArgumentConfig{Type: graphql.NewList(graphql.String)},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					ids := stringListArg(p.Args, "ids")
+					res := []gqlSite{}
+					for _, site := range sites {
+						if len(ids) == 0 || containsString(ids, site.data.SiteId) {
+							res = append(res, site)
+						}
+					}
+					return res, nil
+				},
+			},
+			"site": &graphql.Field{
+				Type: siteType,
+				Args: graphql.FieldConfigArgument{
+					"id":       &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+					"timeStep": &graphql.ArgumentConfig{Type: graphql.String},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					timeStep, _ := p.Args["timeStep"].(string)
+					for _, site := range sites {
+						if site.data.SiteId == p.Args["id"] && (timeStep == "" || site.data.TimeStep == timeStep) {
+							return site, nil
+						}
+					}
+					return nil, nil
+				},
+			},
+		},
+	})
+
+	return graphql.NewSchema(graphql.SchemaConfig{Query: queryType})
+}
+
+//graphqlHandler implements an HTTP response executing GraphQL queries received either as a GET query string or as a POST JSON body
+//Preflight OPTIONS requests are answered with the allowed methods, and queries beyond the depth or complexity limits are rejected without being executed
+func graphqlHandler(schema graphql.Schema) http.HandlerFunc {
+	return func(res http.ResponseWriter, req *http.Request) {
+		var gqlReq graphqlRequest
+		switch req.Method {
+		case http.MethodOptions:
+			res.Header().Set("Allow", "GET, POST, OPTIONS")
+			res.WriteHeader(http.StatusNoContent)
+			return
+		case http.MethodGet:
+			gqlReq.Query = req.URL.Query().Get("query")
+			gqlReq.OperationName = req.URL.Query().Get("operationName")
+			if variables := req.URL.Query().Get("variables"); variables != "" {
+				if err := json.Unmarshal([]byte(variables), &gqlReq.Variables); err != nil {
+					writeJsonError(res, http.StatusBadRequest, errors.New("invalid variables parameter"))
+					return
+				}
+			}
+		case http.MethodPost:
+			if err := json.NewDecoder(http.MaxBytesReader(res, req.Body, 1<<20)).Decode(&gqlReq); err != nil {
+				writeJsonError(res, http.StatusBadRequest, errors.New("invalid JSON body"))
+				return
+			}
+		}
+
+		if strings.TrimSpace(gqlReq.Query) == "" {
+			writeJsonError(res, http.StatusBadRequest, errors.New("missing query"))
+			return
+		}
+		if err := checkGraphqlLimits(gqlReq.Query); err != nil {
+			writeJsonError(res, http.StatusBadRequest, err)
+			return
+		}
+
+		result := graphql.Do(graphql.Params{
+			Schema:         schema,
+			RequestString:  gqlReq.Query,
+			VariableValues: gqlReq.Variables,
+			OperationName:  gqlReq.OperationName,
+			Context:        req.Context(),
+		})
+		writeJson(res, http.StatusOK, result)
+	}
+}
+
+//checkGraphqlLimits checks that the operations of a GraphQL query don't nest their fields deeper than graphqlMaxDepth nor exceed graphqlMaxComplexity
+//Queries that can't be parsed or spread unknown or cyclic fragments pass, being rejected by the GraphQL validation itself
+func checkGraphqlLimits(query string) error {
+	document, err := parser.Parse(parser.ParseParams{Source: query})
+	if err != nil {
+		return nil
+	}
+	fragments := map[string]*ast.FragmentDefinition{}
+	for _, definition := range document.Definitions {
+		if fragment, isFragment := definition.(*ast.FragmentDefinition); isFragment {
+			fragments[fragment.Name.Value] = fragment
+		}
+	}
+
+	complexity := 0
+	spreading := map[string]bool{}
+	var check func(selectionSet *ast.SelectionSet, depth int) error
+	check = func(selectionSet *ast.SelectionSet, depth int) error {
+		if selectionSet == nil {
+			return nil
+		}
+		if depth > graphqlMaxDepth {
+			return fmt.Errorf("query too deep, at most %d levels of fields are allowed", graphqlMaxDepth)
+		}
+		for _, selection := range selectionSet.Selections {
+			complexity++
+			if complexity > graphqlMaxComplexity {
+				return fmt.Errorf("query too complex, at most %d fields and fragment spreads are allowed", graphqlMaxComplexity)
+			}
+
+			var err error
+			switch selection := selection.(type) {
+			case *ast.Field:
+				err = check(selection.SelectionSet, depth+1)
+			case *ast.InlineFragment:
+				err = check(selection.SelectionSet, depth)
+			case *ast.FragmentSpread:
+				name := selection.Name.Value
+				if fragment, found := fragments[name]; found && !spreading[name] {
+					spreading[name] = true
+					err = check(fragment.SelectionSet, depth)
+					delete(spreading, name)
+				}
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	for _, definition := range document.Definitions {
+		if operation, isOperation := definition.(*ast.OperationDefinition); isOperation {
+			if err := check(operation.SelectionSet, 1); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+//selectEvents wraps the events of a report matching the given metric (empty for all) and attributes filter
+func selectEvents(report analyser.OutlierReport, events []analyser.OutlierEvent, severity, metric string, attributes []string) []gqlEvent {
+	res := []gqlEvent{}
+	for _, event := range events {
+		if (metric == "" || event.Metric == metric) && matchAttribute(event.Attribute, attributes) {
+			res = append(res, gqlEvent{event: event, severity: severity, report: report})
+		}
+	}
+	return res
+}
+
+//eventContributors returns the events of descendant attribute/sub-values combinations of the same metric that overlap the given event period
+//Every other attribute is considered a descendant of the Total
+func eventContributors(parent gqlEvent) []gqlEvent {
+	res := []gqlEvent{}
+	isContributor := func(event analyser.OutlierEvent) bool {
+		if event.Metric != parent.event.Metric || event.Attribute == parent.event.Attribute {
+			return false
+		}
+		if parent.event.Attribute != "Total" && !strings.HasPrefix(event.Attribute, parent.event.Attribute+">") {
+			return false
+		}
+		return event.OutlierPeriodStart.Before(parent.event.OutlierPeriodEnd) && parent.event.OutlierPeriodStart.Before(event.OutlierPeriodEnd)
+	}
+	for _, alarm := range parent.report.Result.Alarms {
+		if isContributor(alarm) {
+			res = append(res, gqlEvent{event: alarm, severity: "alarm", report: parent.report})
+		}
+	}
+	for _, warning := range parent.report.Result.Warnings {
+		if isContributor(warning) {
+			res = append(res, gqlEvent{event: warning, severity: "warning", report: parent.report})
+		}
+	}
+	return res
+}
+
+//stringListArg reads an optional list of strings argument from the GraphQL resolver arguments
+func stringListArg(args map[string]interface{}, name string) []string {
+	res := []string{}
+	list, _ := args[name].([]interface{})
+	for _, item := range list {
+		if s, ok := item.(string); ok {
+			res = append(res, s)
+		}
+	}
+	return res
+}
+
+//containsString checks if a given string is present in a slice
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
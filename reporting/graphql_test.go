@@ -0,0 +1,155 @@
+package reporting
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ftfmtavares/anomalies-detector/analyser"
+	"github.com/ftfmtavares/anomalies-detector/collector"
+
+	"github.com/graphql-go/graphql"
+)
+
+func Test_newGraphqlSchema(t *testing.T) {
+	timeRef := time.Date(2022, 9, 1, 0, 0, 0, 0, time.UTC)
+
+	sitesData := []collector.SiteData{
+		{
+			SiteId: "site",
+			Metrics: []collector.MetricData{
+				{
+					Metric:     "Revenue",
+					Unit:       "EUR",
+					Attributes: []string{"Total", "Browser>Chrome"},
+					AttributeData: map[string][]collector.TimeStepData{
+						"Total":          {{DateStart: timeRef, Value: 10, Samples: 100}},
+						"Browser>Chrome": {{DateStart: timeRef, Value: 5, Samples: 50}},
+					},
+				},
+			},
+		},
+	}
+	reports := []analyser.OutlierReport{
+		{
+			SiteId: "site",
+			Result: analyser.OutlierResults{
+				Alarms:   []analyser.OutlierEvent{{OutlierPeriodStart: timeRef, OutlierPeriodEnd: timeRef.AddDate(0, 0, 1), Metric: "Revenue", Attribute: "Total"}},
+				Warnings: []analyser.OutlierEvent{{OutlierPeriodStart: timeRef, OutlierPeriodEnd: timeRef.AddDate(0, 0, 1), Metric: "Revenue", Attribute: "Browser>Chrome"}},
+			},
+		},
+	}
+
+	schema, err := newGraphqlSchema(sitesData, reports)
+	if err != nil {
+		t.Fatalf("newGraphqlSchema() error = %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		query string
+		want  string
+	}{
+		{
+			name:  "Series of a filtered attribute",
+			query: `{ site(id: "site") { metrics(names: ["Revenue"]) { unit series(attributes: ["browser"]) { attribute data { value samples } } } } }`,
+			want:  `{"site":{"metrics":[{"series":[{"attribute":"Browser\u003eChrome","data":[{"samples":50,"value":5}]}],"unit":"EUR"}]}}`,
+		},
+		{
+			name:  "Alarms with contributors",
+			query: `{ sites { siteId metrics { alarms { attribute severity contributors { attribute severity } } } } }`,
+			want:  `{"sites":[{"metrics":[{"alarms":[{"attribute":"Total","contributors":[{"attribute":"Browser\u003eChrome","severity":"warning"}],"severity":"alarm"}]}],"siteId":"site"}]}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := graphql.Do(graphql.Params{Schema: schema, RequestString: tt.query})
+			if result.HasErrors() {
+				t.Fatalf("graphql.Do() errors = %v", result.Errors)
+			}
+			got, _ := json.Marshal(result.Data)
+			if string(got) != tt.want {
+				t.Errorf("graphql.Do() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_checkGraphqlLimits(t *testing.T) {
+	nested := func(levels int) string {
+		return "{ sites { alarms" + strings.Repeat(" { contributors", levels-3) + " { attribute }" + strings.Repeat(" }", levels-3) + " } }"
+	}
+	repeated := func(fields int) string {
+		query := "{ sites {"
+		for i := 0; i < fields; i++ {
+			query += fmt.Sprintf(" s%d: siteId", i)
+		}
+		return query + " } }"
+	}
+
+	tests := []struct {
+		name    string
+		query   string
+		wantErr bool
+	}{
+		{name: "Nested up to the depth limit", query: nested(graphqlMaxDepth)},
+		{name: "Nested beyond the depth limit", query: nested(graphqlMaxDepth + 1), wantErr: true},
+		{name: "Aliased fields up to the complexity limit", query: repeated(graphqlMaxComplexity - 1)},
+		{name: "Aliased fields beyond the complexity limit", query: repeated(graphqlMaxComplexity), wantErr: true},
+		{
+			name:    "Fragments expanded where they're spread",
+			query:   `{ sites { ...deep } } fragment deep on Site { alarms { contributors { contributors { contributors { contributors { contributors { contributors { contributors { contributors { attribute } } } } } } } } } }`,
+			wantErr: true,
+		},
+		{
+			name:    "Fragments spread repeatedly",
+			query:   `{ sites { ...a ...a ...a ...a } } fragment a on Site { ...b ...b ...b ...b } fragment b on Site { ...c ...c ...c ...c } fragment c on Site { ...d ...d ...d ...d } fragment d on Site { siteId timeStep }`,
+			wantErr: true,
+		},
+		{name: "Cyclic fragments left to the validation", query: `{ sites { ...a } } fragment a on Site { ...b } fragment b on Site { ...a }`},
+		{name: "Syntax errors left to the validation", query: `{ sites { siteId`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := checkGraphqlLimits(tt.query); (err != nil) != tt.wantErr {
+				t.Errorf("checkGraphqlLimits() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func Test_graphqlHandler(t *testing.T) {
+	schema, err := newGraphqlSchema(nil, nil)
+	if err != nil {
+		t.Fatalf("newGraphqlSchema() error = %v", err)
+	}
+	handler := graphqlHandler(schema)
+
+	tests := []struct {
+		name       string
+		method     string
+		body       string
+		wantStatus int
+		wantBody   string
+	}{
+		{name: "Preflight answered without executing", method: http.MethodOptions, wantStatus: http.StatusNoContent},
+		{name: "Query executed", method: http.MethodPost, body: `{"query": "{ sites { siteId } }"}`, wantStatus: http.StatusOK, wantBody: `{"data":{"sites":[]}}`},
+		{name: "Query beyond the limits rejected", method: http.MethodPost, body: `{"query": "{ sites { ...a ...a ...a ...a } } fragment a on Site { ...b ...b ...b ...b } fragment b on Site { ...c ...c ...c ...c } fragment c on Site { ...d ...d ...d ...d } fragment d on Site { siteId timeStep }"}`, wantStatus: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := httptest.NewRecorder()
+			handler.ServeHTTP(res, httptest.NewRequest(tt.method, "/graphql", strings.NewReader(tt.body)))
+			if res.Code != tt.wantStatus {
+				t.Errorf("%s /graphql status = %d, want %d", tt.method, res.Code, tt.wantStatus)
+			}
+			if body := strings.TrimSpace(res.Body.String()); tt.wantBody != "" && body != tt.wantBody {
+				t.Errorf("%s /graphql body = %s, want %s", tt.method, body, tt.wantBody)
+			}
+		})
+	}
+}
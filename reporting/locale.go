@@ -0,0 +1,114 @@
+package reporting
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ftfmtavares/anomalies-detector/config"
+
+	"github.com/wcharczuk/go-chart/v2"
+)
+
+//weekTicksMinSpan is the shortest chart period whose time axis is ticked on the first day of every week instead of the default ticks
+const weekTicksMinSpan = 14 * 24 * time.Hour
+
+//siteLocale returns the resolved locale of a site, nil if the site has none or an invalid one so its charts and reports keep the default formats
+func siteLocale(datasets []config.Dataset, siteId string) *config.LocaleParams {
+	for _, dataset := range datasets {
+		if dataset.SiteId != siteId || dataset.Locale == nil {
+			continue
+		}
+		locale, err := dataset.Locale.Resolve()
+		if err != nil {
+			return nil
+		}
+		return &locale
+	}
+	return nil
+}
+
+//formatLocaleTime formats a time with the date format of the locale, followed by its hours and minutes for sub-daily time steps, RFC 3339 if there's no locale
+func formatLocaleTime(value time.Time, timeStep time.Duration, locale *config.LocaleParams) string {
+	if locale == nil {
+		return value.Format(time.RFC3339)
+	}
+	if timeStep > 0 && timeStep < 24*time.Hour {
+		return value.Format(locale.DateFormat + " 15:04")
+	}
+	return value.Format(locale.DateFormat)
+}
+
+//formatLocaleNumber formats a value with the given decimals and the decimal and thousands separators of the locale
+func formatLocaleNumber(value float64, decimals int, locale config.LocaleParams) string {
+	text := strconv.FormatFloat(math.Abs(value), 'f', decimals, 64)
+	integer, fraction := text, ""
+	if dot := strings.Index(text, "."); dot >= 0 {
+		integer, fraction = text[:dot], text[dot+1:]
+	}
+
+	var res strings.Builder
+	if value < 0 && strings.Trim(text, "0.") != "" {
+		res.WriteString("-")
+	}
+	for i, digit := range integer {
+		if i > 0 && (len(integer)-i)%3 == 0 {
+			res.WriteString(locale.ThousandsSeparator)
+		}
+		res.WriteRune(digit)
+	}
+	if fraction != "" {
+		res.WriteString(locale.DecimalSeparator + fraction)
+	}
+	return res.String()
+}
+
+//weekStarts returns the midnight of the first day of every week starting within the period, in the location of its start
+func weekStarts(from, to time.Time, firstDay time.Weekday) []time.Time {
+	day := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, from.Location())
+	day = day.AddDate(0, 0, (int(firstDay)-int(day.Weekday())+7)%7)
+	if day.Before(from) {
+		day = day.AddDate(0, 0, 7)
+	}
+	starts := []time.Time{}
+	for ; !day.After(to); day = day.AddDate(0, 0, 7) {
+		starts = append(starts, day)
+	}
+	return starts
+}
+
+//applyLocale formats the axes of a chart with the locale, ticking its time axis with grid lines on the first day of every week when it spans two weeks or more
+//Weeks are skipped evenly on long periods so that the time axis holds at most a dozen ticks
+func applyLocale(graph *chart.Chart, locale config.LocaleParams, timeStep time.Duration, from, to time.Time) {
+	graph.XAxis.ValueFormatter = func(value interface{}) string {
+		if typed, isTyped := value.(float64); isTyped {
+			return formatLocaleTime(chart.TimeFromFloat64(typed).In(from.Location()), timeStep, &locale)
+		}
+		if typed, isTyped := value.(time.Time); isTyped {
+			return formatLocaleTime(typed, timeStep, &locale)
+		}
+		return ""
+	}
+	graph.YAxis.ValueFormatter = func(value interface{}) string {
+		if typed, isTyped := value.(float64); isTyped {
+			return formatLocaleNumber(typed, 2, locale)
+		}
+		return ""
+	}
+
+	firstDay, _ := locale.Weekday()
+	starts := weekStarts(from, to, firstDay)
+	if to.Sub(from) < weekTicksMinSpan || len(starts) < 2 {
+		return
+	}
+	every := (len(starts) + 11) / 12
+	graph.XAxis.Ticks = []chart.Tick{}
+	graph.XAxis.GridLines = []chart.GridLine{}
+	graph.XAxis.GridMajorStyle = chart.Style{StrokeColor: chart.ColorLightGray, StrokeWidth: 1}
+	for i := 0; i < len(starts); i += every {
+		value := chart.TimeToFloat64(starts[i])
+		graph.XAxis.Ticks = append(graph.XAxis.Ticks, chart.Tick{Value: value, Label: starts[i].Format(locale.DateFormat)})
+		graph.XAxis.GridLines = append(graph.XAxis.GridLines, chart.GridLine{Value: value})
+	}
+}
@@ -0,0 +1,92 @@
+package reporting
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/ftfmtavares/anomalies-detector/config"
+
+	"github.com/wcharczuk/go-chart/v2"
+)
+
+func Test_formatLocaleNumber(t *testing.T) {
+	tests := []struct {
+		name   string
+		locale string
+		value  float64
+		want   string
+	}{
+		{name: "ISO without thousands separator", locale: "iso", value: 1234567.891, want: "1234567.89"},
+		{name: "US separators", locale: "en-US", value: 1234567.891, want: "1,234,567.89"},
+		{name: "German separators", locale: "de-DE", value: -1234.5, want: "-1.234,50"},
+		{name: "Small value", locale: "pt-PT", value: 12.345, want: "12,35"},
+		{name: "Negative zero", locale: "fr-FR", value: -0.001, want: "0,00"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			locale, err := config.LocaleParams{Name: tt.locale}.Resolve()
+			if err != nil {
+				t.Fatalf("Resolve() error = %v", err)
+			}
+			if got := formatLocaleNumber(tt.value, 2, locale); got != tt.want {
+				t.Errorf("formatLocaleNumber() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_weekStarts(t *testing.T) {
+	from := time.Date(2024, 3, 6, 12, 0, 0, 0, time.UTC) //Wednesday
+	to := time.Date(2024, 3, 25, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		firstDay time.Weekday
+		want     []int
+	}{
+		{name: "Weeks starting on monday", firstDay: time.Monday, want: []int{11, 18, 25}},
+		{name: "Weeks starting on sunday", firstDay: time.Sunday, want: []int{10, 17, 24}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := weekStarts(from, to, tt.firstDay)
+			if len(got) != len(tt.want) {
+				t.Fatalf("weekStarts() = %v, want days %v", got, tt.want)
+			}
+			for i, day := range tt.want {
+				if got[i].Day() != day || got[i].Hour() != 0 || got[i].Weekday() != tt.firstDay {
+					t.Errorf("weekStarts()[%d] = %v, want day %d", i, got[i], day)
+				}
+			}
+		})
+	}
+}
+
+func Test_buildChartLocale(t *testing.T) {
+	sitesData, reports := reportFixture()
+	locale := siteLocale([]config.Dataset{{SiteId: "shop", Locale: &config.LocaleParams{Name: "en-US", ThousandsSeparator: " "}}}, "shop")
+	if locale == nil || locale.DateFormat != "01/02/2006" || locale.ThousandsSeparator != " " {
+		t.Fatalf("siteLocale() = %+v, want the en-US preset with its thousands separator replaced", locale)
+	}
+
+	graph, found := buildChart(sitesData, reports, "shop", "1d", "Revenue", nil, locale)
+	if !found {
+		t.Fatalf("buildChart() found = false")
+	}
+	if len(graph.XAxis.Ticks) == 0 || len(graph.XAxis.Ticks) > 12 {
+		t.Fatalf("buildChart() ticks = %v, want weekly ticks", graph.XAxis.Ticks)
+	}
+	for _, tick := range graph.XAxis.Ticks {
+		day, err := time.Parse("01/02/2006", tick.Label)
+		if err != nil || day.Weekday() != time.Sunday {
+			t.Errorf("buildChart() tick = %s, want a sunday in the en-US format", tick.Label)
+		}
+	}
+	if got := graph.YAxis.ValueFormatter(1500.0); got != "1 500.00" {
+		t.Errorf("buildChart() value = %s, want 1 500.00", got)
+	}
+	if err := graph.Render(chart.PNG, &bytes.Buffer{}); err != nil {
+		t.Errorf("Render() error = %v", err)
+	}
+}
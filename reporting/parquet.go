@@ -0,0 +1,234 @@
+package reporting
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"math"
+)
+
+//Const block defines the Parquet physical types, converted types and thrift compact protocol types used by the Parquet writer
+const (
+	parquetInt32     = 1
+	parquetInt64     = 2
+	parquetDouble    = 5
+	parquetByteArray = 6
+
+	parquetUtf8            = 0
+	parquetTimestampMillis = 9
+
+	thriftI32    = 5
+	thriftI64    = 6
+	thriftBinary = 8
+	thriftList   = 9
+	thriftStruct = 12
+)
+
+//parquetMagic opens and closes every Parquet file
+var parquetMagic = []byte("PAR1")
+
+//writeParquet writes a table as a Parquet file with a single row group, each column held on a single uncompressed data page with plain encoding
+//All columns are required, so pages hold no repetition nor definition levels
+func writeParquet(w io.Writer, table exportTable) error {
+	var file bytes.Buffer
+	file.Write(parquetMagic)
+
+	//Writing the data page of every column, keeping their offsets and sizes for the file metadata
+	offsets := make([]int64, len(table.columns))
+	sizes := make([]int64, len(table.columns))
+	for i, column := range table.columns {
+		var values bytes.Buffer
+		for row := 0; row < table.rows; row++ {
+			switch column.kind {
+			case columnString:
+				binary.Write(&values, binary.LittleEndian, uint32(len(column.strings[row])))
+				values.WriteString(column.strings[row])
+			case columnInt:
+				binary.Write(&values, binary.LittleEndian, int32(column.ints[row]))
+			case columnTime:
+				binary.Write(&values, binary.LittleEndian, column.ints[row])
+			case columnFloat:
+				binary.Write(&values, binary.LittleEndian, math.Float64bits(column.floats[row]))
+			}
+		}
+
+		header := &thriftWriter{}
+		header.i32(1, 0)
+		header.i32(2, int32(values.Len()))
+		header.i32(3, int32(values.Len()))
+		header.structBegin(5)
+		header.i32(1, int32(table.rows))
+		header.i32(2, 0)
+		header.i32(3, 3)
+		header.i32(4, 3)
+		header.structEnd()
+		header.stop()
+
+		offsets[i] = int64(file.Len())
+		sizes[i] = int64(header.buf.Len() + values.Len())
+		file.Write(header.buf.Bytes())
+		file.Write(values.Bytes())
+	}
+
+	//Writing the file metadata with the flat schema and the single row group
+	meta := &thriftWriter{}
+	meta.i32(1, 1)
+	meta.listBegin(2, thriftStruct, len(table.columns)+1)
+	meta.elementBegin()
+	meta.binary(4, "schema")
+	meta.i32(5, int32(len(table.columns)))
+	meta.elementEnd()
+	for _, column := range table.columns {
+		meta.elementBegin()
+		meta.i32(1, column.parquetType())
+		meta.i32(3, 0)
+		meta.binary(4, column.name)
+		switch column.kind {
+		case columnString:
+			meta.i32(6, parquetUtf8)
+		case columnTime:
+			meta.i32(6, parquetTimestampMillis)
+		}
+		meta.elementEnd()
+	}
+	meta.i64(3, int64(table.rows))
+	meta.listBegin(4, thriftStruct, 1)
+	meta.elementBegin()
+	meta.listBegin(1, thriftStruct, len(table.columns))
+	total := int64(0)
+	for i, column := range table.columns {
+		meta.elementBegin()
+		meta.i64(2, offsets[i])
+		meta.structBegin(3)
+		meta.i32(1, column.parquetType())
+		meta.listBegin(2, thriftI32, 1)
+		meta.varint(0)
+		meta.listBegin(3, thriftBinary, 1)
+		meta.varint(uint64(len(column.name)))
+		meta.buf.WriteString(column.name)
+		meta.i32(4, 0)
+		meta.i64(5, int64(table.rows))
+		meta.i64(6, sizes[i])
+		meta.i64(7, sizes[i])
+		meta.i64(9, offsets[i])
+		meta.structEnd()
+		meta.elementEnd()
+		total += sizes[i]
+	}
+	meta.i64(2, total)
+	meta.i64(3, int64(table.rows))
+	meta.elementEnd()
+	meta.binary(6, "anomalies-detector")
+	meta.stop()
+
+	file.Write(meta.buf.Bytes())
+	binary.Write(&file, binary.LittleEndian, uint32(meta.buf.Len()))
+	file.Write(parquetMagic)
+
+	_, err := w.Write(file.Bytes())
+	return err
+}
+
+//parquetType returns the Parquet physical type of a column
+func (column exportColumn) parquetType() int32 {
+	switch column.kind {
+	case columnInt:
+		return parquetInt32
+	case columnTime:
+		return parquetInt64
+	case columnFloat:
+		return parquetDouble
+	default:
+		return parquetByteArray
+	}
+}
+
+//thriftWriter encodes the Parquet metadata structures with the thrift compact protocol
+//Field ids are written as deltas from the previous field of the same structure, so the last id of every open structure is kept on a stack
+type thriftWriter struct {
+	buf  bytes.Buffer
+	last []int16
+}
+
+//field writes a field header, the stack being initialized for the top structure
+func (tw *thriftWriter) field(id int16, kind byte) {
+	if len(tw.last) == 0 {
+		tw.last = []int16{0}
+	}
+	delta := id - tw.last[len(tw.last)-1]
+	if delta > 0 && delta <= 15 {
+		tw.buf.WriteByte(byte(delta)<<4 | kind)
+	} else {
+		tw.buf.WriteByte(kind)
+		tw.varint(zigzag(int64(id)))
+	}
+	tw.last[len(tw.last)-1] = id
+}
+
+func (tw *thriftWriter) i32(id int16, value int32) {
+	tw.field(id, thriftI32)
+	tw.varint(zigzag(int64(value)))
+}
+
+func (tw *thriftWriter) i64(id int16, value int64) {
+	tw.field(id, thriftI64)
+	tw.varint(zigzag(value))
+}
+
+func (tw *thriftWriter) binary(id int16, value string) {
+	tw.field(id, thriftBinary)
+	tw.varint(uint64(len(value)))
+	tw.buf.WriteString(value)
+}
+
+//listBegin writes a list field header, its elements being written next
+func (tw *thriftWriter) listBegin(id int16, kind byte, size int) {
+	tw.field(id, thriftList)
+	if size < 15 {
+		tw.buf.WriteByte(byte(size)<<4 | kind)
+	} else {
+		tw.buf.WriteByte(0xf0 | kind)
+		tw.varint(uint64(size))
+	}
+}
+
+//structBegin opens a structure field, closed by structEnd
+func (tw *thriftWriter) structBegin(id int16) {
+	tw.field(id, thriftStruct)
+	tw.elementBegin()
+}
+
+func (tw *thriftWriter) structEnd() {
+	tw.elementEnd()
+}
+
+//elementBegin opens a structure element of a list, closed by elementEnd
+func (tw *thriftWriter) elementBegin() {
+	if len(tw.last) == 0 {
+		tw.last = []int16{0}
+	}
+	tw.last = append(tw.last, 0)
+}
+
+func (tw *thriftWriter) elementEnd() {
+	tw.stop()
+	tw.last = tw.last[:len(tw.last)-1]
+}
+
+//stop writes the end of a structure
+func (tw *thriftWriter) stop() {
+	tw.buf.WriteByte(0)
+}
+
+func (tw *thriftWriter) varint(value uint64) {
+	for value >= 0x80 {
+		tw.buf.WriteByte(byte(value) | 0x80)
+		value >>= 7
+	}
+	tw.buf.WriteByte(byte(value))
+}
+
+//zigzag maps signed integers to unsigned ones so small negative values stay short
+func zigzag(value int64) uint64 {
+	return uint64(value<<1) ^ uint64(value>>63)
+}
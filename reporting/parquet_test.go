@@ -0,0 +1,177 @@
+package reporting
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"testing"
+	"time"
+)
+
+//thriftReader decodes the thrift compact protocol structures written by thriftWriter, structures being read as maps of their field ids
+type thriftReader struct {
+	buf []byte
+	pos int
+}
+
+func (tr *thriftReader) byte() byte {
+	value := tr.buf[tr.pos]
+	tr.pos++
+	return value
+}
+
+func (tr *thriftReader) varint() uint64 {
+	value, shift := uint64(0), uint(0)
+	for {
+		b := tr.byte()
+		value |= uint64(b&0x7f) << shift
+		if b < 0x80 {
+			return value
+		}
+		shift += 7
+	}
+}
+
+func (tr *thriftReader) zigzag() int64 {
+	value := tr.varint()
+	return int64(value>>1) ^ -int64(value&1)
+}
+
+//value reads a value of the given compact type, integers as int64, binaries as strings, lists as slices and structures as maps
+func (tr *thriftReader) value(kind byte) interface{} {
+	switch kind {
+	case thriftI32, thriftI64:
+		return tr.zigzag()
+	case thriftBinary:
+		size := int(tr.varint())
+		tr.pos += size
+		return string(tr.buf[tr.pos-size : tr.pos])
+	case thriftList:
+		header := tr.byte()
+		size := int(header >> 4)
+		if size == 15 {
+			size = int(tr.varint())
+		}
+		values := make([]interface{}, size)
+		for i := range values {
+			values[i] = tr.value(header & 0x0f)
+		}
+		return values
+	case thriftStruct:
+		fields := map[int16]interface{}{}
+		last := int16(0)
+		for {
+			header := tr.byte()
+			if header == 0 {
+				return fields
+			}
+			if delta := int16(header >> 4); delta != 0 {
+				last += delta
+			} else {
+				last = int16(tr.zigzag())
+			}
+			fields[last] = tr.value(header & 0x0f)
+		}
+	}
+	panic(fmt.Sprintf("unexpected thrift type %d", kind))
+}
+
+func (tr *thriftReader) structure() map[int16]interface{} {
+	return tr.value(thriftStruct).(map[int16]interface{})
+}
+
+func TestWriteParquet(t *testing.T) {
+	timeRef := time.Date(2022, 9, 1, 0, 0, 0, 0, time.UTC)
+	table := newExportTable(
+		exportColumn{name: "site", kind: columnString},
+		exportColumn{name: "timestamp", kind: columnTime},
+		exportColumn{name: "value", kind: columnFloat},
+		exportColumn{name: "samples", kind: columnInt},
+	)
+	table.addRow("shop", timeRef, 1000.5, 10)
+	table.addRow("blog", timeRef.AddDate(0, 0, 1), 2.25, 20)
+	table.addRow("shop", timeRef.AddDate(0, 0, 2), 0.0, 30)
+
+	var file bytes.Buffer
+	if err := writeParquet(&file, table); err != nil {
+		t.Fatalf("writeParquet() error = %v", err)
+	}
+	content := file.Bytes()
+	if !bytes.HasPrefix(content, parquetMagic) || !bytes.HasSuffix(content, parquetMagic) {
+		t.Fatalf("writeParquet() missing Parquet magic")
+	}
+
+	//The footer length precedes the closing magic, the file metadata ending right before it
+	footerLength := int(binary.LittleEndian.Uint32(content[len(content)-8:]))
+	footerStart := len(content) - 8 - footerLength
+	if footerLength <= 0 || footerStart < 4 {
+		t.Fatalf("writeParquet() footer length = %d on %d bytes", footerLength, len(content))
+	}
+	footer := &thriftReader{buf: content[:len(content)-8], pos: footerStart}
+	meta := footer.structure()
+	if footer.pos != len(footer.buf) {
+		t.Fatalf("writeParquet() file metadata read up to byte %d, want %d", footer.pos, len(footer.buf))
+	}
+	if meta[1] != int64(1) || meta[3] != int64(table.rows) || meta[6] != "anomalies-detector" {
+		t.Errorf("writeParquet() version = %v, num_rows = %v, created_by = %v, want 1, %d and anomalies-detector", meta[1], meta[3], meta[6], table.rows)
+	}
+
+	//The flat schema lists its root with the number of columns, then every required column with its physical and converted types
+	wantSchema := []map[int16]interface{}{
+		{4: "schema", 5: int64(4)},
+		{1: int64(parquetByteArray), 3: int64(0), 4: "site", 6: int64(parquetUtf8)},
+		{1: int64(parquetInt64), 3: int64(0), 4: "timestamp", 6: int64(parquetTimestampMillis)},
+		{1: int64(parquetDouble), 3: int64(0), 4: "value"},
+		{1: int64(parquetInt32), 3: int64(0), 4: "samples"},
+	}
+	schema := meta[2].([]interface{})
+	if len(schema) != len(wantSchema) {
+		t.Fatalf("writeParquet() schema = %v, want %d elements", schema, len(wantSchema))
+	}
+	for i, element := range schema {
+		if got := fmt.Sprint(element); got != fmt.Sprint(wantSchema[i]) {
+			t.Errorf("writeParquet() schema element %d = %s, want %v", i, got, wantSchema[i])
+		}
+	}
+
+	//The single row group chains the column chunks from the opening magic up to the file metadata
+	rowGroups := meta[4].([]interface{})
+	if len(rowGroups) != 1 {
+		t.Fatalf("writeParquet() row groups = %v, want 1", rowGroups)
+	}
+	rowGroup := rowGroups[0].(map[int16]interface{})
+	chunks := rowGroup[1].([]interface{})
+	if len(chunks) != len(table.columns) || rowGroup[3] != int64(table.rows) || rowGroup[2] != int64(footerStart-4) {
+		t.Fatalf("writeParquet() row group = %v, want %d chunks of %d rows over %d bytes", rowGroup, len(table.columns), table.rows, footerStart-4)
+	}
+	offset := int64(4)
+	for i, element := range chunks {
+		chunk := element.(map[int16]interface{})
+		chunkMeta := chunk[3].(map[int16]interface{})
+		column := table.columns[i]
+		if chunk[2] != offset || chunkMeta[9] != offset {
+			t.Errorf("writeParquet() %s chunk offsets = %v and %v, want %d", column.name, chunk[2], chunkMeta[9], offset)
+		}
+		if chunkMeta[1] != int64(column.parquetType()) || fmt.Sprint(chunkMeta[3]) != fmt.Sprintf("[%s]", column.name) || chunkMeta[5] != int64(table.rows) {
+			t.Errorf("writeParquet() %s chunk metadata = %v", column.name, chunkMeta)
+		}
+
+		//Each chunk is a single data page, whose header gives the size of the values it holds
+		page := &thriftReader{buf: content, pos: int(offset)}
+		header := page.structure()
+		pageHeader := header[5].(map[int16]interface{})
+		if header[1] != int64(0) || pageHeader[1] != int64(table.rows) || int64(page.pos)+header[3].(int64) != offset+chunkMeta[6].(int64) {
+			t.Errorf("writeParquet() %s page header = %v, want a data page of %d values filling its chunk", column.name, header, table.rows)
+		}
+		if i == 0 {
+			values := content[page.pos : page.pos+int(header[3].(int64))]
+			if want := "\x04\x00\x00\x00shop\x04\x00\x00\x00blog\x04\x00\x00\x00shop"; string(values) != want {
+				t.Errorf("writeParquet() site values = %q, want %q", values, want)
+			}
+		}
+		offset += chunkMeta[6].(int64)
+	}
+	if offset != int64(footerStart) {
+		t.Errorf("writeParquet() column chunks end at byte %d, want the file metadata start %d", offset, footerStart)
+	}
+}
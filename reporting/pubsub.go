@@ -0,0 +1,181 @@
+package reporting
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/ftfmtavares/anomalies-detector/analyser"
+	"github.com/ftfmtavares/anomalies-detector/config"
+	"github.com/ftfmtavares/anomalies-detector/utils"
+)
+
+//Const block defines the Pub/Sub publisher defaults
+//The publish API accepts at most 1000 messages per request
+const (
+	defaultPubSubEndpoint = "https://pubsub.googleapis.com"
+	pubSubMaxMessages     = 1000
+)
+
+//PubSubPublisher publishes detected warnings and alarms to a GCP Pub/Sub topic
+type PubSubPublisher struct {
+	topicUrl    string
+	tokenUrl    string
+	accessToken string
+	attributes  map[string]*template.Template
+	severities  map[string]bool
+	routes      []string
+	client      *http.Client
+}
+
+//pubSubMessage provides the structure of a published message, Data being the base64 encoded event details
+type pubSubMessage struct {
+	Data       string            `json:"data"`
+	Attributes map[string]string `json:"attributes"`
+}
+
+//NewPubSubPublisher creates a PubSubPublisher from the given parameters
+//It returns an error if the project or topic are missing or if any attribute template is invalid
+func NewPubSubPublisher(params config.PubSubParams) (*PubSubPublisher, error) {
+	if params.Project == "" || params.Topic == "" {
+		return nil, errors.New("pubsub publisher requires a project and topic")
+	}
+	endpoint := params.Endpoint
+	if endpoint == "" {
+		endpoint = defaultPubSubEndpoint
+	}
+
+	publisher := &PubSubPublisher{
+		topicUrl:    fmt.Sprintf("%s/v1/projects/%s/topics/%s:publish", strings.TrimSuffix(endpoint, "/"), url.PathEscape(params.Project), url.PathEscape(params.Topic)),
+		tokenUrl:    utils.GcpMetadataTokenUrl,
+		accessToken: utils.ResolveSecret(params.AccessToken),
+		attributes:  map[string]*template.Template{},
+		severities:  map[string]bool{},
+		routes:      params.Routes,
+		client:      utils.NewHttpClient(10 * time.Second),
+	}
+
+	for name, text := range params.Attributes {
+		tmpl, err := template.New(name).Parse(text)
+		if err != nil {
+			return nil, fmt.Errorf("pubsub publisher attribute %s - %s", name, err.Error())
+		}
+		publisher.attributes[name] = tmpl
+	}
+
+	if len(params.Severities) == 0 {
+		params.Severities = []string{"warning", "alarm"}
+	}
+	for _, severity := range params.Severities {
+		publisher.severities[severity] = true
+	}
+
+	return publisher, nil
+}
+
+//Publish sends the warnings and alarms of a report to the topic, alarms first, in requests of up to 1000 messages
+//Each message data holds the same event details as the CloudEvents data, while its attributes allow subscriptions to filter them
+func (publisher *PubSubPublisher) Publish(report analyser.OutlierReport) error {
+	messages := []pubSubMessage{}
+	for _, event := range notificationEvents(report, publisher.severities, publisher.routes) {
+		message, err := publisher.message(event)
+		if err != nil {
+			return err
+		}
+		messages = append(messages, message)
+	}
+	if len(messages) == 0 {
+		return nil
+	}
+
+	token := publisher.accessToken
+	if token == "" {
+		var err error
+		if token, err = publisher.metadataToken(); err != nil {
+			return err
+		}
+	}
+
+	for start := 0; start < len(messages); start += pubSubMaxMessages {
+		end := start + pubSubMaxMessages
+		if end > len(messages) {
+			end = len(messages)
+		}
+		if err := publisher.publish(messages[start:end], token); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//message builds the Pub/Sub message of an event with the default attributes and the configured ones
+func (publisher *PubSubPublisher) message(event NotificationEvent) (pubSubMessage, error) {
+	data, err := json.Marshal(cloudEventData(event))
+	if err != nil {
+		return pubSubMessage{}, err
+	}
+
+	attributes := map[string]string{
+		"siteId":    event.SiteId,
+		"severity":  event.Severity,
+		"metric":    event.Metric,
+		"attribute": event.Attribute,
+	}
+	for name, tmpl := range publisher.attributes {
+		var value bytes.Buffer
+		if err := tmpl.Execute(&value, event); err != nil {
+			return pubSubMessage{}, fmt.Errorf("pubsub publisher attribute %s - %s", name, err.Error())
+		}
+		attributes[name] = value.String()
+	}
+
+	return pubSubMessage{Data: base64.StdEncoding.EncodeToString(data), Attributes: attributes}, nil
+}
+
+//publish sends a single publish request
+func (publisher *PubSubPublisher) publish(messages []pubSubMessage, token string) error {
+	body, err := json.Marshal(map[string]interface{}{"messages": messages})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, publisher.topicUrl, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := publisher.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("pubsub publisher - %s", err.Error())
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var apiResp struct {
+			Error struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		json.NewDecoder(resp.Body).Decode(&apiResp)
+		return fmt.Errorf("pubsub publisher - unexpected status %s %s", resp.Status, apiResp.Error.Message)
+	}
+	return nil
+}
+
+//metadataToken requests an access token of the default service account from the compute metadata server
+func (publisher *PubSubPublisher) metadataToken() (string, error) {
+	token, err := utils.GcpMetadataToken(publisher.client, publisher.tokenUrl)
+	if err != nil {
+		return "", fmt.Errorf("pubsub publisher - %s", err.Error())
+	}
+	return token, nil
+}
@@ -0,0 +1,97 @@
+package reporting
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ftfmtavares/anomalies-detector/analyser"
+	"github.com/ftfmtavares/anomalies-detector/config"
+)
+
+func TestPubSubPublisher_Publish(t *testing.T) {
+	timeRef := time.Date(2022, 9, 1, 0, 0, 0, 0, time.UTC)
+
+	report := analyser.OutlierReport{
+		SiteId: "site",
+		Result: analyser.OutlierResults{
+			Warnings: []analyser.OutlierEvent{{OutlierPeriodStart: timeRef, OutlierPeriodEnd: timeRef.AddDate(0, 0, 1), Metric: "Basket", Attribute: "Total"}},
+			Alarms:   []analyser.OutlierEvent{{OutlierPeriodStart: timeRef, OutlierPeriodEnd: timeRef.AddDate(0, 0, 1), Metric: "Revenue", Attribute: "Total", Routes: []string{"finance"}}},
+		},
+	}
+
+	tests := []struct {
+		name           string
+		params         config.PubSubParams
+		wantToken      string
+		wantAttributes []map[string]string
+	}{
+		{
+			name:      "Configured token and templated attributes",
+			params:    config.PubSubParams{AccessToken: "token", Attributes: map[string]string{"team": "{{if .Routes}}{{index .Routes 0}}{{else}}default{{end}}"}},
+			wantToken: "token",
+			wantAttributes: []map[string]string{
+				{"siteId": "site", "severity": "alarm", "metric": "Revenue", "attribute": "Total", "team": "finance"},
+				{"siteId": "site", "severity": "warning", "metric": "Basket", "attribute": "Total", "team": "default"},
+			},
+		},
+		{
+			name:           "Metadata server token and severity filter",
+			params:         config.PubSubParams{Severities: []string{"alarm"}},
+			wantToken:      "metadata-token",
+			wantAttributes: []map[string]string{{"siteId": "site", "severity": "alarm", "metric": "Revenue", "attribute": "Total"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := []map[string]string{}
+			server := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+				if req.URL.Path == "/token" {
+					if req.Header.Get("Metadata-Flavor") != "Google" {
+						t.Errorf("metadata request without flavor header")
+					}
+					json.NewEncoder(res).Encode(map[string]string{"access_token": "metadata-token"})
+					return
+				}
+				if req.URL.Path != "/v1/projects/project/topics/anomalies:publish" || req.Header.Get("Authorization") != "Bearer "+tt.wantToken {
+					t.Errorf("unexpected request %s %v", req.URL.Path, req.Header)
+				}
+
+				var payload struct {
+					Messages []pubSubMessage `json:"messages"`
+				}
+				json.NewDecoder(req.Body).Decode(&payload)
+				for _, message := range payload.Messages {
+					got = append(got, message.Attributes)
+					data, _ := base64.StdEncoding.DecodeString(message.Data)
+					var detail CloudEventData
+					if err := json.Unmarshal(data, &detail); err != nil || detail.SiteId != "site" || !detail.PeriodStart.Equal(timeRef) {
+						t.Errorf("unexpected message data %s", data)
+					}
+				}
+				res.Write([]byte(`{"messageIds": ["1"]}`))
+			}))
+			defer server.Close()
+
+			tt.params.Project = "project"
+			tt.params.Topic = "anomalies"
+			tt.params.Endpoint = server.URL
+			publisher, err := NewPubSubPublisher(tt.params)
+			if err != nil {
+				t.Fatalf("NewPubSubPublisher() error = %v", err)
+			}
+			publisher.tokenUrl = server.URL + "/token"
+
+			if err := publisher.Publish(report); err != nil {
+				t.Fatalf("Publish() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.wantAttributes) {
+				t.Errorf("Publish() attributes = %v, want %v", got, tt.wantAttributes)
+			}
+		})
+	}
+}
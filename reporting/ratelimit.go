@@ -0,0 +1,127 @@
+package reporting
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/ftfmtavares/anomalies-detector/config"
+)
+
+//Const block defines the default protection parameters used when none are configured
+const (
+	defaultRequestsPerMinute = 60.0
+	defaultBurst             = 20
+	clientIdleTimeout        = 10 * time.Minute
+)
+
+//rateLimiter implements a token bucket per client
+//Buckets from clients idle for longer than clientIdleTimeout are removed in order to keep memory bounded
+type rateLimiter struct {
+	mu        sync.Mutex
+	rate      float64
+	burst     float64
+	clients   map[string]*tokenBucket
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+//tokenBucket holds the available tokens of a single client and when they were last refilled
+type tokenBucket struct {
+	tokens   float64
+	lastSeen time.Time
+}
+
+//newRateLimiter creates a rateLimiter from the given parameters, using defaults for unset values
+func newRateLimiter(params config.RateLimitParams) *rateLimiter {
+	if params.RequestsPerMinute <= 0 {
+		params.RequestsPerMinute = defaultRequestsPerMinute
+	}
+	if params.Burst <= 0 {
+		params.Burst = defaultBurst
+	}
+	return &rateLimiter{
+		rate:    params.RequestsPerMinute / 60,
+		burst:   float64(params.Burst),
+		clients: map[string]*tokenBucket{},
+		now:     Clock.Now,
+	}
+}
+
+//allow checks if the given client still has tokens available, consuming one if so
+//When no tokens are available, it also returns how long the client should wait before retrying
+func (limiter *rateLimiter) allow(client string) (bool, time.Duration) {
+	limiter.mu.Lock()
+	defer limiter.mu.Unlock()
+
+	now := limiter.now()
+	limiter.sweep(now)
+
+	//New clients start with a full bucket while existing ones are refilled according to the elapsed time
+	bucket, present := limiter.clients[client]
+	if !present {
+		bucket = &tokenBucket{tokens: limiter.burst, lastSeen: now}
+		limiter.clients[client] = bucket
+	} else {
+		bucket.tokens = math.Min(limiter.burst, bucket.tokens+now.Sub(bucket.lastSeen).Seconds()*limiter.rate)
+		bucket.lastSeen = now
+	}
+
+	if bucket.tokens < 1 {
+		return false, time.Duration((1 - bucket.tokens) / limiter.rate * float64(time.Second))
+	}
+	bucket.tokens--
+	return true, 0
+}
+
+//sweep removes the buckets of idle clients, running at most once per clientIdleTimeout
+func (limiter *rateLimiter) sweep(now time.Time) {
+	if now.Sub(limiter.lastSweep) < clientIdleTimeout {
+		return
+	}
+	for client, bucket := range limiter.clients {
+		if now.Sub(bucket.lastSeen) > clientIdleTimeout {
+			delete(limiter.clients, client)
+		}
+	}
+	limiter.lastSweep = now
+}
+
+//clientContextKey is the request context key of the client identity verified by the authenticator
+type clientContextKey struct{}
+
+//middleware wraps an HTTP handler rejecting requests from clients that exceeded their rate with a 429 status
+func (limiter *rateLimiter) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		if limiter.limited(res, clientKey(req)) {
+			return
+		}
+		next.ServeHTTP(res, req)
+	})
+}
+
+//limited consumes a token of the given client, answering with a 429 status and returning true if it exceeded its rate
+func (limiter *rateLimiter) limited(res http.ResponseWriter, client string) bool {
+	ok, wait := limiter.allow(client)
+	if !ok {
+		res.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+		http.Error(res, "429 too many requests", http.StatusTooManyRequests)
+	}
+	return !ok
+}
+
+//clientKey identifies the client of a request by the identity verified by the authenticator, if any, or by its IP address otherwise
+//Credentials are never read here, so every guessed or made up token is keyed by the address it comes from
+func clientKey(req *http.Request) string {
+	if client, ok := req.Context().Value(clientContextKey{}).(string); ok {
+		return client
+	}
+	host, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		host = req.RemoteAddr
+	}
+	return "ip:" + host
+}
@@ -0,0 +1,81 @@
+package reporting
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ftfmtavares/anomalies-detector/config"
+)
+
+func Test_rateLimiter_allow(t *testing.T) {
+	timeRef := time.Now()
+
+	tests := []struct {
+		name     string
+		params   config.RateLimitParams
+		requests []time.Duration
+		want     []bool
+	}{
+		{
+			name:     "Burst consumed and rejected until refilled",
+			params:   config.RateLimitParams{RequestsPerMinute: 60, Burst: 2},
+			requests: []time.Duration{0, 0, 0, time.Second},
+			want:     []bool{true, true, false, true},
+		},
+		{
+			name:     "Sustained rate below limit",
+			params:   config.RateLimitParams{RequestsPerMinute: 60, Burst: 1},
+			requests: []time.Duration{0, time.Second, 2 * time.Second, 3 * time.Second},
+			want:     []bool{true, true, true, true},
+		},
+		{
+			name:     "Sustained rate above limit",
+			params:   config.RateLimitParams{RequestsPerMinute: 30, Burst: 1},
+			requests: []time.Duration{0, time.Second, 2 * time.Second, 3 * time.Second},
+			want:     []bool{true, false, true, false},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			limiter := newRateLimiter(tt.params)
+			for i, offset := range tt.requests {
+				limiter.now = func() time.Time { return timeRef.Add(offset) }
+				if got, _ := limiter.allow("client"); got != tt.want[i] {
+					t.Errorf("rateLimiter.allow() request #%d = %v, want %v", i, got, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func Test_clientKey(t *testing.T) {
+	tests := []struct {
+		name          string
+		remoteAddr    string
+		authorization string
+		client        string
+		want          string
+	}{
+		{name: "IP address without port", remoteAddr: "192.0.2.1", want: "ip:192.0.2.1"},
+		{name: "IP address with port", remoteAddr: "192.0.2.1:51234", want: "ip:192.0.2.1"},
+		{name: "Bearer token ignored", remoteAddr: "192.0.2.1:51235", authorization: "Bearer guessed", want: "ip:192.0.2.1"},
+		{name: "Client verified by the authenticator", remoteAddr: "192.0.2.1:51236", authorization: "Bearer static-token", client: "token:0", want: "token:0"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/report", nil)
+			req.RemoteAddr = tt.remoteAddr
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			if tt.client != "" {
+				req = req.WithContext(context.WithValue(req.Context(), clientContextKey{}, tt.client))
+			}
+			if got := clientKey(req); got != tt.want {
+				t.Errorf("clientKey() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
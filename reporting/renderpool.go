@@ -0,0 +1,177 @@
+package reporting
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/ftfmtavares/anomalies-detector/config"
+
+	"github.com/wcharczuk/go-chart/v2"
+)
+
+//Const block defines the chart rendering pool defaults used when none are configured
+//Each render is estimated to take renderBytesPerPixel bytes per chart pixel, covering the raster canvas and the PNG encoding buffers
+const (
+	defaultMaxConcurrentRenders = 4
+	defaultRenderQueueSize      = 32
+	defaultRenderMemoryBudgetMB = 64
+	renderBytesPerPixel         = 8
+	renderQueueTimeout          = 5 * time.Second
+	renderWorkerIdleTimeout     = 5 * time.Second
+)
+
+//Errors returned when a chart can't be rendered because the pool is overloaded
+var (
+	errRenderQueueFull = errors.New("render queue full")
+	errRenderTimeout   = errors.New("render queue timeout")
+)
+
+//renderPool renders charts as PNG images on up to a fixed number of workers fed by a bounded queue
+//Workers only start a render when its estimated memory fits the remaining budget, so concurrent renders of large charts are serialized
+//Workers are started on demand and exit once idle, so the pools of report handlers replaced by later runs don't leave goroutines behind
+type renderPool struct {
+	jobs     chan renderJob
+	mu       sync.Mutex
+	released *sync.Cond
+	budget   int64
+	used     int64
+	workers  int
+	running  int
+	idle     time.Duration
+	render   func(graph chart.Chart, w io.Writer) error
+}
+
+//renderJob holds a queued chart along with the request context and the channel receiving its result
+type renderJob struct {
+	ctx    context.Context
+	graph  chart.Chart
+	result chan renderResult
+}
+
+//renderResult holds the rendered PNG image or the rendering error
+type renderResult struct {
+	png []byte
+	err error
+}
+
+//newRenderPool creates a renderPool from the report server parameters, its workers being started by the first renders
+func newRenderPool(params config.ReportServerParams) *renderPool {
+	workers := params.MaxConcurrentRenders
+	if workers <= 0 {
+		workers = defaultMaxConcurrentRenders
+	}
+	queueSize := params.RenderQueueSize
+	if queueSize <= 0 {
+		queueSize = defaultRenderQueueSize
+	}
+	budgetMB := params.RenderMemoryBudgetMB
+	if budgetMB <= 0 {
+		budgetMB = defaultRenderMemoryBudgetMB
+	}
+
+	pool := &renderPool{
+		jobs:    make(chan renderJob, queueSize),
+		budget:  int64(budgetMB) << 20,
+		workers: workers,
+		idle:    renderWorkerIdleTimeout,
+		render:  func(graph chart.Chart, w io.Writer) error { return graph.Render(chart.PNG, w) },
+	}
+	pool.released = sync.NewCond(&pool.mu)
+	return pool
+}
+
+//Render queues a chart and waits for its PNG image
+//It fails right away if the queue is full, and after renderQueueTimeout if the chart wasn't rendered by then
+func (pool *renderPool) Render(ctx context.Context, graph chart.Chart) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, renderQueueTimeout)
+	defer cancel()
+
+	job := renderJob{ctx: ctx, graph: graph, result: make(chan renderResult, 1)}
+	select {
+	case pool.jobs <- job:
+	default:
+		return nil, errRenderQueueFull
+	}
+
+	//Starting a worker for the queued chart if fewer than the maximum are running
+	pool.mu.Lock()
+	if pool.running < pool.workers {
+		pool.running++
+		go pool.worker()
+	}
+	pool.mu.Unlock()
+
+	select {
+	case result := <-job.result:
+		return result.png, result.err
+	case <-ctx.Done():
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return nil, errRenderTimeout
+		}
+		return nil, ctx.Err()
+	}
+}
+
+//worker renders the queued charts one at a time, skipping those whose requests were given up while queued
+//It exits once no chart was queued for the idle timeout, unless one was queued meanwhile, as Render only starts workers after queueing
+func (pool *renderPool) worker() {
+	idle := time.NewTimer(pool.idle)
+	defer idle.Stop()
+	for {
+		select {
+		case job := <-pool.jobs:
+			if job.ctx.Err() == nil {
+				cost := renderCost(job.graph)
+				pool.acquire(cost)
+				var png bytes.Buffer
+				err := pool.render(job.graph, &png)
+				pool.release(cost)
+				job.result <- renderResult{png: png.Bytes(), err: err}
+			}
+			if !idle.Stop() {
+				select {
+				case <-idle.C:
+				default:
+				}
+			}
+			idle.Reset(pool.idle)
+		case <-idle.C:
+			pool.mu.Lock()
+			if len(pool.jobs) == 0 {
+				pool.running--
+				pool.mu.Unlock()
+				return
+			}
+			pool.mu.Unlock()
+			idle.Reset(pool.idle)
+		}
+	}
+}
+
+//acquire waits until the given memory fits the remaining budget and reserves it
+//A render larger than the whole budget is still allowed once no other render is running, so it never waits forever
+func (pool *renderPool) acquire(cost int64) {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+	for pool.used > 0 && pool.used+cost > pool.budget {
+		pool.released.Wait()
+	}
+	pool.used += cost
+}
+
+//release returns the memory of a finished render to the budget and wakes up the waiting workers
+func (pool *renderPool) release(cost int64) {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+	pool.used -= cost
+	pool.released.Broadcast()
+}
+
+//renderCost estimates the memory taken by rendering a chart from its dimensions
+func renderCost(graph chart.Chart) int64 {
+	return int64(graph.GetWidth()) * int64(graph.GetHeight()) * renderBytesPerPixel
+}
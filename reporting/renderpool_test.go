@@ -0,0 +1,138 @@
+package reporting
+
+import (
+	"context"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ftfmtavares/anomalies-detector/config"
+
+	"github.com/wcharczuk/go-chart/v2"
+)
+
+func TestRenderPool_Render(t *testing.T) {
+
+	//Each 1366x768 chart is estimated at 8MB, so a 10MB budget only lets one of the 2 workers render at a time
+	tests := []struct {
+		name          string
+		params        config.ReportServerParams
+		requests      int
+		wantRendered  int
+		wantRejected  int
+		wantMaxActive int
+	}{
+		{
+			name:          "Memory budget serializes renders",
+			params:        config.ReportServerParams{MaxConcurrentRenders: 2, RenderQueueSize: 4, RenderMemoryBudgetMB: 10},
+			requests:      4,
+			wantRendered:  4,
+			wantMaxActive: 1,
+		},
+		{
+			name:          "Memory budget allows concurrent renders",
+			params:        config.ReportServerParams{MaxConcurrentRenders: 2, RenderQueueSize: 4, RenderMemoryBudgetMB: 20},
+			requests:      4,
+			wantRendered:  4,
+			wantMaxActive: 2,
+		},
+		{
+			name:          "Full queue rejects requests",
+			params:        config.ReportServerParams{MaxConcurrentRenders: 1, RenderQueueSize: 1, RenderMemoryBudgetMB: 20},
+			requests:      4,
+			wantRendered:  2,
+			wantRejected:  2,
+			wantMaxActive: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pool := newRenderPool(tt.params)
+
+			//Renders are held until all requests were submitted, tracking how many run at the same time
+			var mu sync.Mutex
+			active, maxActive := 0, 0
+			hold := make(chan struct{})
+			pool.render = func(graph chart.Chart, w io.Writer) error {
+				mu.Lock()
+				active++
+				if active > maxActive {
+					maxActive = active
+				}
+				mu.Unlock()
+				<-hold
+				time.Sleep(10 * time.Millisecond)
+				mu.Lock()
+				active--
+				mu.Unlock()
+				w.Write([]byte("png"))
+				return nil
+			}
+
+			var wg sync.WaitGroup
+			var results sync.Map
+			for i := 0; i < tt.requests; i++ {
+				wg.Add(1)
+				go func(i int) {
+					defer wg.Done()
+					png, err := pool.Render(context.Background(), chart.Chart{Width: 1366, Height: 768})
+					results.Store(i, renderResult{png: png, err: err})
+				}(i)
+
+				//Waiting for the request to be taken or queued before submitting the next one
+				time.Sleep(20 * time.Millisecond)
+			}
+			close(hold)
+			wg.Wait()
+
+			rendered, rejected := 0, 0
+			results.Range(func(key, value interface{}) bool {
+				result := value.(renderResult)
+				if result.err == errRenderQueueFull {
+					rejected++
+				} else if result.err == nil && string(result.png) == "png" {
+					rendered++
+				}
+				return true
+			})
+			if rendered != tt.wantRendered || rejected != tt.wantRejected {
+				t.Errorf("Render() rendered %d and rejected %d, want %d and %d", rendered, rejected, tt.wantRendered, tt.wantRejected)
+			}
+			if maxActive != tt.wantMaxActive {
+				t.Errorf("Render() max concurrent renders = %d, want %d", maxActive, tt.wantMaxActive)
+			}
+		})
+	}
+}
+
+func TestRenderPool_idleWorkers(t *testing.T) {
+	pool := newRenderPool(config.ReportServerParams{MaxConcurrentRenders: 2})
+	pool.idle = 20 * time.Millisecond
+	pool.render = func(graph chart.Chart, w io.Writer) error {
+		_, err := w.Write([]byte("png"))
+		return err
+	}
+	running := func() int {
+		pool.mu.Lock()
+		defer pool.mu.Unlock()
+		return pool.running
+	}
+
+	//Workers are only started by renders, exiting once idle and being started again by the next render
+	if got := running(); got != 0 {
+		t.Errorf("newRenderPool() running workers = %d, want 0", got)
+	}
+	for i := 0; i < 2; i++ {
+		if png, err := pool.Render(context.Background(), chart.Chart{Width: 100, Height: 100}); err != nil || string(png) != "png" {
+			t.Fatalf("Render() = %q, %v", png, err)
+		}
+		if got := running(); got != 1 {
+			t.Errorf("Render() running workers = %d, want 1", got)
+		}
+		time.Sleep(100 * time.Millisecond)
+		if got := running(); got != 0 {
+			t.Errorf("Render() running workers once idle = %d, want 0", got)
+		}
+	}
+}
@@ -1,13 +1,19 @@
 package reporting
 
 import (
+	"crypto/tls"
+	"errors"
 	"fmt"
+	"log"
+	"net"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/ftfmtavares/anomalies-detector/analyser"
 	"github.com/ftfmtavares/anomalies-detector/collector"
+	"github.com/ftfmtavares/anomalies-detector/config"
 	"github.com/ftfmtavares/anomalies-detector/utils"
 
 	"github.com/gorilla/mux"
@@ -15,225 +21,532 @@ import (
 	"github.com/wcharczuk/go-chart/v2/drawing"
 )
 
+//Clock is the time source of the rate limiter, access log, notifiers, signatures and watchdog, replaced by tests to make them deterministic
+var Clock utils.Clock = utils.SystemClock
+
+//DefaultReportListen is the address the report server binds to when none is configured
+const DefaultReportListen = ":8080"
+
 //GenerateReport takes all collected data and alarm reports and starts an web server from which different graphs can be downloaded
-func GenerateReport(sitesData []collector.SiteData, outlierReports []analyser.OutlierReport, port int) {
+//Every request is rate limited per client and chart rendering is capped according to the given server parameters
+//The server binds to the configured address, serving HTTPS when a certificate and key are configured
+//Detection settings are used by the verification endpoint to recompute the detection on demand
+//It returns an error if the server can't be started or stops
+func GenerateReport(sitesData []collector.SiteData, outlierReports []analyser.OutlierReport, serverParams config.ReportServerParams, detection DetectionSettings) error {
+	srv := http.Server{
+		Handler:      newReportRouter(sitesData, outlierReports, serverParams, detection),
+		Addr:         ReportListen(serverParams),
+		WriteTimeout: 10 * time.Second,
+		ReadTimeout:  10 * time.Second,
+	}
+	if serverParams.CertFile != "" {
+		srv.TLSConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+		return srv.ListenAndServeTLS(serverParams.CertFile, serverParams.KeyFile)
+	}
+	return srv.ListenAndServe()
+}
+
+//ReportListen returns the address the report server binds to, the default one if none is configured
+func ReportListen(serverParams config.ReportServerParams) string {
+	if serverParams.Listen == "" {
+		return DefaultReportListen
+	}
+	return serverParams.Listen
+}
+
+//ReportUrl returns the URL of the report index served with the given parameters, on localhost when bound to every interface
+func ReportUrl(serverParams config.ReportServerParams) string {
+	scheme := "http"
+	if serverParams.CertFile != "" {
+		scheme = "https"
+	}
+	host, port, _ := net.SplitHostPort(ReportListen(serverParams))
+	if host == "" || host == "0.0.0.0" || host == "::" {
+		host = "localhost"
+	}
+	return fmt.Sprintf("%s://%s/report", scheme, net.JoinHostPort(host, port))
+}
+
+//NewReportHandler returns the handler of the report server, so it can be served by programs embedding the detection pipeline
+func NewReportHandler(sitesData []collector.SiteData, outlierReports []analyser.OutlierReport, serverParams config.ReportServerParams, detection DetectionSettings) http.Handler {
+	return newReportRouter(sitesData, outlierReports, serverParams, detection)
+}
+
+//newReportRouter creates the router serving the report index, charts and APIs over the given data and reports
+func newReportRouter(sitesData []collector.SiteData, outlierReports []analyser.OutlierReport, serverParams config.ReportServerParams, detection DetectionSettings) *mux.Router {
+
+	//Stored runs of the history can be browsed instead of the current one with the run query string
+	browser := newRunBrowser(detection.History, sitesData, outlierReports)
 
 	//writeIndex implements an HTTP response returning a simple HTML bullet list with links to all available sites, metrics and main attributes
+	//Stored runs are listed to switch between them, the links of a stored run keeping it selected
 	writeIndex := func(res http.ResponseWriter, req *http.Request) {
+		run, ok := browser.run(res, req)
+		if !ok {
+			return
+		}
 		res.WriteHeader(http.StatusOK)
 		res.Write([]byte("<!DOCTYPE html>\n"))
 		res.Write([]byte("<title>Anomalies Report</title>\n"))
-		for _, siteData := range sitesData {
-			res.Write([]byte(fmt.Sprintf("<h2>%s</h2>\n", siteData.SiteId)))
+		res.Write([]byte("<p><a href=\"/dashboard/\">Interactive dashboard</a></p>\n"))
+		if detection.History != nil {
+			res.Write([]byte("<p><a href=\"/report/weekly\">Week over week</a></p>\n"))
+		}
+		browser.writeRuns(res, run)
+		for _, siteData := range run.sitesData {
+
+			//Sites analysed at several time steps are listed once per resolution, their links selecting it, and archived sites are marked as such
+			title := siteData.SiteId
+			resolution := ""
+			if multiResolution(run.sitesData, siteData.SiteId) {
+				title = fmt.Sprintf("%s (%s)", siteData.SiteId, siteData.TimeStep)
+				resolution = "resolution=" + siteData.TimeStep
+			}
+
+			if siteData.Archived {
+				title += " (archived)"
+			}
+			res.Write([]byte(fmt.Sprintf("<h2>%s</h2>\n", title)))
 			res.Write([]byte("<ul>\n"))
 			for _, metricData := range siteData.Metrics {
-				res.Write([]byte(fmt.Sprintf("<li><a href=\"/report/%s/%s\">%s</a></li>\n", siteData.SiteId, metricData.Metric, metricData.Metric)))
+				metricLink := withQuery(fmt.Sprintf("/report/%s/%s", siteData.SiteId, metricData.Metric), resolution, run.query)
+				treemapLink := withQuery(fmt.Sprintf("/report/%s/%s/treemap", siteData.SiteId, metricData.Metric), resolution, run.query)
+				res.Write([]byte(fmt.Sprintf("<li><a href=\"%s\">%s</a> (<a href=\"%s\">treemap</a>)</li>\n", metricLink, metricData.Metric, treemapLink)))
 				res.Write([]byte("<ul>\n"))
 				lastAttribute := ""
 				for _, attribute := range metricData.Attributes {
 					parts := strings.Split(attribute, ">")
 					if parts[0] != lastAttribute {
 						lastAttribute = parts[0]
-						res.Write([]byte(fmt.Sprintf("<li><a href=\"/report/%s/%s?attribute=%s\">%s</a></li>\n", siteData.SiteId, metricData.Metric, strings.ToLower(lastAttribute), lastAttribute)))
+						attributeLink := withQuery(fmt.Sprintf("/report/%s/%s", siteData.SiteId, metricData.Metric), "attribute="+strings.ToLower(lastAttribute), resolution, run.query)
+						res.Write([]byte(fmt.Sprintf("<li><a href=\"%s\">%s</a></li>\n", attributeLink, lastAttribute)))
 					}
 				}
 				res.Write([]byte("</ul>\n"))
 			}
 			res.Write([]byte("</ul>\n"))
+
+			//Listing the alarms and warnings of the site, so runs can be compared by switching between them
+			for _, outlierReport := range run.reports {
+				if reportMatchesSite(outlierReport, siteData) && len(outlierReport.Result.Alarms)+len(outlierReport.Result.Warnings) > 0 {
+					res.Write([]byte("<h3>Events</h3>\n"))
+					res.Write([]byte("<ul>\n"))
+					writeEvents(res, "alarm", outlierReport.Result.Alarms)
+					writeEvents(res, "warning", outlierReport.Result.Warnings)
+					res.Write([]byte("</ul>\n"))
+				}
+			}
+
+			//Listing the data gaps of the site, outages being told apart from the outliers
+			for _, outlierReport := range run.reports {
+				if reportMatchesSite(outlierReport, siteData) && len(outlierReport.DataGaps) > 0 {
+					res.Write([]byte("<h3>Data gaps</h3>\n"))
+					res.Write([]byte("<ul>\n"))
+					for _, gap := range outlierReport.DataGaps {
+						res.Write([]byte(fmt.Sprintf("<li>%s - %s to %s (%d time steps)</li>\n", gap.Metric, gap.PeriodStart.Format(time.RFC3339), gap.PeriodEnd.Format(time.RFC3339), gap.Steps)))
+					}
+					res.Write([]byte("</ul>\n"))
+				}
+			}
+
+			//Listing the compliance and remaining error budget of the site service level objectives
+			for _, outlierReport := range run.reports {
+				if reportMatchesSite(outlierReport, siteData) && len(outlierReport.Objectives) > 0 {
+					res.Write([]byte("<h3>Objectives</h3>\n"))
+					res.Write([]byte("<ul>\n"))
+					for _, objective := range outlierReport.Objectives {
+						res.Write([]byte(fmt.Sprintf("<li>%s</li>\n", objectiveSummary(objective))))
+					}
+					res.Write([]byte("</ul>\n"))
+				}
+			}
 			res.Write([]byte("<hr />\n"))
 		}
 	}
 
-	//writeIndex implements an HTTP response returning PNG images containing graphs with collected data and alarms annotations
+	//Chart renders are queued to a worker pool bounded by the configured memory budget, rendered charts being kept for repeated views of the run
+	pool := newRenderPool(serverParams)
+	renders := newRenderCache(serverParams)
+
+	//drawChart implements an HTTP response returning PNG images containing graphs with collected data and alarms annotations
+	//Clients preferring JSON on their Accept header get the charted series instead, as returned by the series endpoint which reads the same query strings
+	//Charts of a stored run are drawn from its own data and reports when selected with the run query string
 	drawChart := func(res http.ResponseWriter, req *http.Request) {
+		res.Header().Set("Vary", "Accept")
+
+		//Charts already rendered with the same query strings are served from the cache
+		cacheKey := renderCacheKey(req)
+		if png, found := renders.Get(cacheKey); found && !prefersJson(req) {
+			res.Header().Set("Content-Type", "image/png")
+			res.Write(png)
+			return
+		}
+
+		run, ok := browser.run(res, req)
+		if !ok {
+			return
+		}
+		if prefersJson(req) {
+			seriesHandler(run.sitesData)(res, req)
+			return
+		}
 
-		//It takes the site id and metric from the url address, as well as attributes from query strings, to generate the graph on demand
+		//It takes the site id and metric from the url address, as well as attributes, resolution, scale, view, time range and downsampling from query strings, to generate the graph on demand
 		siteUrl := mux.Vars(req)["siteid"]
 		metricUrl := mux.Vars(req)["metric"]
 		attributesUrl := req.URL.Query()["attribute"]
+		resolutionUrl := req.URL.Query().Get("resolution")
+		query, err := parseSeriesQuery(req)
+		if err != nil {
+			http.Error(res, "400 "+err.Error(), http.StatusBadRequest)
+			return
+		}
 
-		//If "all" or no attribute has been given in query strings attributes, all attribute/sub-value combinations will be shown
-		allAttributes := false
-		if len(attributesUrl) == 0 {
-			allAttributes = true
-		} else {
-			for _, attr := range attributesUrl {
-				if strings.ToLower(attr) == "all" {
-					allAttributes = true
-					break
-				}
+		//Series shown as a percentage of their baseline or as z-scores share a single axis, whatever their units and magnitudes
+		//Zooming into a time range only shows the events within it, and long series are downsampled keeping their shape
+		chartData := run.sitesData
+		if query.percent || query.zscore || !query.from.IsZero() || !query.to.IsZero() || query.downsample != 0 {
+			chartData = chartSites(run.sitesData, siteUrl, resolutionUrl, metricUrl, query)
+		}
+		warning, alarm := 0.0, 0.0
+		if query.zscore {
+			if warning, alarm, err = zscoreLimits(req, detection.Datasets, detection.Methods, siteUrl, metricUrl); err != nil {
+				http.Error(res, "400 "+err.Error(), http.StatusBadRequest)
+				return
 			}
 		}
 
-		//Looks for the respective metric data
-		chosenMetric := collector.MetricData{}
-	OuterLoop:
-		for _, siteData := range sitesData {
-			if siteData.SiteId == siteUrl {
-				for _, metric := range siteData.Metrics {
-					if metric.Metric == metricUrl {
-						chosenMetric = metric
-						break OuterLoop
-					}
+		//If an unknown site and metric was given, an HTTP not found error is returned, otherwise the respective graph is rendered
+		graph, found := buildChart(chartData, clipReports(run.reports, query.from, query.to), siteUrl, resolutionUrl, metricUrl, attributesUrl, siteLocale(detection.Datasets, siteUrl))
+		if !found {
+			res.WriteHeader(http.StatusNotFound)
+			res.Write([]byte("404 page not found\n"))
+			return
+		}
+
+		//Z-scores are shown with the warning and alarm limits they're compared with
+		//Values are shown against what the detection expected, when the report exported it, except on the percent scale whose units differ
+		if query.zscore {
+			addZScoreLimits(&graph, warning, alarm)
+		} else if chosenSite, found := findSite(run.sitesData, siteUrl, resolutionUrl); found && !query.percent {
+			if attribute, banded := bandedAttribute(&graph); banded {
+				if expectations, exported := findExpectations(run.reports, chosenSite, metricUrl, attribute); exported {
+					addExpectationBands(&graph, expectations)
 				}
 			}
 		}
 
-		//If an unknown site and metric was given, an HTTP not found error is returned, otherwise the respective graph is generated
-		if chosenMetric.Metric == "" {
-			res.WriteHeader(http.StatusNotFound)
-			res.Write([]byte("404 page not found\n"))
+		//Rendering on the pool, overloads being answered with a 503 status so clients retry later
+		png, err := pool.Render(req.Context(), graph)
+		if errors.Is(err, errRenderQueueFull) || errors.Is(err, errRenderTimeout) {
+			res.Header().Set("Retry-After", strconv.Itoa(int(renderQueueTimeout.Seconds())))
+			http.Error(res, "503 server busy rendering charts", http.StatusServiceUnavailable)
+		} else if err != nil {
+			log.Printf("Chart rendering failed - %s - %s - %s\n", siteUrl, metricUrl, err.Error())
+			http.Error(res, "500 chart rendering failed", http.StatusInternalServerError)
 		} else {
-			graph := chart.Chart{
-				Title:  fmt.Sprintf("%s - %s", siteUrl, metricUrl),
-				Width:  1366,
-				Height: 768,
-				Background: chart.Style{
-					Padding: chart.Box{
-						Top:  30,
-						Left: 160,
-					},
-				},
-				XAxis: chart.XAxis{
-					Name: "Time",
-				},
-				YAxis: chart.YAxis{
-					Name: chosenMetric.Unit,
-				},
-				Series: []chart.Series{},
+			renders.Add(cacheKey, png)
+			res.Header().Set("Content-Type", "image/png")
+			res.Write(png)
+		}
+	}
+
+	//Registers the index, chart and JSON API functions as handles
+	//Chart rendering is CPU and memory heavy so it runs on a bounded pool on top of the per client rate limiting
+	//Every request is logged and measured, including the rate limited and unmatched ones, the measures being served on /metrics along with those of the run
+	//Requests are authenticated first, if configured, those rejected being rate limited per client IP so credentials can't be guessed faster than the rate allows,
+	//while accepted ones are rate limited per verified token or user, so clients sharing an address don't share their rate
+	//Responses are compressed for clients accepting gzip and those over the run data carry validators from the end of the run, so repeated views get a 304 status
+	accesses := newAccessLog()
+	router := mux.NewRouter()
+	router.NotFoundHandler = accesses.middleware(http.NotFoundHandler())
+	router.Use(accesses.middleware)
+	router.Use(gzipMiddleware)
+	limiter := newRateLimiter(serverParams.RateLimit)
+	if serverParams.Auth != nil {
+		router.Use(newAuthenticator(*serverParams.Auth, limiter).middleware)
+	}
+	router.Use(limiter.middleware)
+	router.Use(newRunValidators(runTime(detection.Run)).middleware)
+	router.PathPrefix("/metrics").Methods(http.MethodOptions, http.MethodGet).Subrouter().HandleFunc("", detectorMetricsHandler(accesses, outlierReports, detection.Run))
+	router.PathPrefix("/dashboard").Methods(http.MethodOptions, http.MethodGet).Handler(dashboardHandler())
+	router.PathPrefix("/report").Methods(http.MethodOptions, http.MethodGet).Subrouter().HandleFunc("", writeIndex)
+	router.PathPrefix("/report/weekly").Methods(http.MethodOptions, http.MethodGet).Subrouter().HandleFunc("", weeklyHandler(outlierReports, detection.History))
+	router.PathPrefix("/report/{siteid}/{metric}").Methods(http.MethodOptions, http.MethodGet).Subrouter().HandleFunc("", drawChart)
+	router.PathPrefix("/report/{siteid}/{metric}/treemap").Methods(http.MethodOptions, http.MethodGet).Subrouter().HandleFunc("", func(res http.ResponseWriter, req *http.Request) {
+		if run, ok := browser.run(res, req); ok {
+			treemapHandler(run.sitesData, run.reports, detection.Datasets)(res, req)
+		}
+	})
+	router.PathPrefix("/api/v1/sites").Methods(http.MethodOptions, http.MethodGet).Subrouter().HandleFunc("", sitesHandler(sitesData, outlierReports))
+	router.PathPrefix("/api/v1/alarms").Methods(http.MethodOptions, http.MethodGet).Subrouter().HandleFunc("", alarmsHandler(outlierReports))
+	router.PathPrefix("/api/v1/sites/{siteid}/metrics/{metric}/data").Methods(http.MethodOptions, http.MethodGet).Subrouter().HandleFunc("", seriesHandler(sitesData))
+	router.PathPrefix("/api/catalog").Methods(http.MethodOptions, http.MethodGet).Subrouter().HandleFunc("", catalogHandler(sitesData, detection.Metrics))
+	router.PathPrefix("/api/v1/sites/{siteid}/metrics/{metric}/verify").Methods(http.MethodOptions, http.MethodGet).Subrouter().HandleFunc("", verifyHandler(sitesData, detection))
+
+	//The GraphQL endpoint is optional and only registered if enabled on the configuration
+	if serverParams.GraphQL {
+		schema, err := newGraphqlSchema(sitesData, outlierReports)
+		if err != nil {
+			log.Panic(err)
+		}
+		router.PathPrefix("/graphql").Methods(http.MethodOptions, http.MethodGet, http.MethodPost).Subrouter().HandleFunc("", graphqlHandler(schema))
+	}
+
+	//The active alarms endpoint is only registered if the events are tracked across runs
+	if detection.EventStates != nil {
+		router.PathPrefix("/api/active-alarms").Methods(http.MethodOptions, http.MethodGet).Subrouter().HandleFunc("", activeAlarmsHandler(detection.EventStates))
+	}
+
+	//The subscriptions API is optional as well, only registered if a subscriptions file is configured along with the authentication,
+	//since subscriptions post the events to any given target
+	if serverParams.SubscriptionsFile != "" && serverParams.Auth != nil {
+		store, err := LoadSubscriptionStore(serverParams.SubscriptionsFile)
+		if err != nil {
+			log.Panic(err)
+		}
+		router.PathPrefix("/api/v1/subscriptions/{id}").Methods(http.MethodOptions, http.MethodGet, http.MethodDelete).Subrouter().HandleFunc("", subscriptionHandler(store))
+		router.PathPrefix("/api/v1/subscriptions").Methods(http.MethodOptions, http.MethodGet, http.MethodPost).Subrouter().HandleFunc("", subscriptionsHandler(store))
+	}
+
+	return router
+}
+
+//runTime returns the end of the run whose data and reports are served, the current time if it's unknown
+func runTime(run *RunStats) time.Time {
+	if run != nil && !run.Finished.IsZero() {
+		return run.Finished
+	}
+	return Clock.Now()
+}
+
+//buildChart generates the graph of a given site and metric with the collected data of the selected attributes and the respective alarms annotations
+//If "all" or no attribute is given, all attribute/sub-value combinations are shown
+//If no time step is given for a site analysed at several resolutions, the first one is shown
+//Sites with a locale get their dates, values and weeks shown accordingly, the others keeping the default formats
+//It returns false if the site or metric is unknown
+func buildChart(sitesData []collector.SiteData, outlierReports []analyser.OutlierReport, siteId, timeStep, metricName string, attributes []string, locale *config.LocaleParams) (chart.Chart, bool) {
+
+	//If "all" or no attribute has been given, all attribute/sub-value combinations will be shown
+	allAttributes := false
+	if len(attributes) == 0 {
+		allAttributes = true
+	} else {
+		for _, attr := range attributes {
+			if strings.ToLower(attr) == "all" {
+				allAttributes = true
+				break
 			}
+		}
+	}
 
-			max := 0.0
-			shownAttributes := map[string]bool{}
-			//Looping through the available attribute/sub-value combinations in the selected metric data
-			for _, attribute := range chosenMetric.Attributes {
-
-				//Checking if the attribute/sub-value combination is to be shown and stores in a map for future use
-				if !allAttributes {
-					for _, attr := range attributesUrl {
-						if strings.HasPrefix(strings.ToLower(attribute), strings.ToLower(attr)) {
-							shownAttributes[attribute] = true
-							break
-						}
-					}
-				}
+	//Looks for the respective site and metric data
+	chosenSite, found := findSite(sitesData, siteId, timeStep)
+	if !found {
+		return chart.Chart{}, false
+	}
+	chosenMetric, found := findMetric(sitesData, siteId, chosenSite.TimeStep, metricName)
+	if !found {
+		return chart.Chart{}, false
+	}
 
-				//Adding the data series in the graph if the attribute/sub-value combination is to be shown
-				if allAttributes || shownAttributes[attribute] {
-					newSeries := chart.TimeSeries{
-						Name:    attribute,
-						XValues: make([]time.Time, len(chosenMetric.AttributeData[attribute])),
-						YValues: make([]float64, len(chosenMetric.AttributeData[attribute])),
-					}
-					for i, timeStepData := range chosenMetric.AttributeData[attribute] {
-						newSeries.XValues[i] = timeStepData.DateStart
-						newSeries.YValues[i] = timeStepData.Value
-						if max < timeStepData.Value {
-							max = timeStepData.Value
-						}
-					}
-					graph.Series = append(graph.Series, newSeries)
+	title := fmt.Sprintf("%s - %s", siteId, metricName)
+	if multiResolution(sitesData, siteId) {
+		title = fmt.Sprintf("%s - %s (%s)", siteId, metricName, chosenSite.TimeStep)
+	}
+
+	graph := chart.Chart{
+		Title:  title,
+		Width:  1366,
+		Height: 768,
+		Background: chart.Style{
+			Padding: chart.Box{
+				Top:  30,
+				Left: 160,
+			},
+		},
+		XAxis: chart.XAxis{
+			Name: "Time",
+		},
+		YAxis: chart.YAxis{
+			Name: chosenMetric.Unit,
+		},
+		Series: []chart.Series{},
+	}
+
+	max := 0.0
+	shownAttributes := map[string]bool{}
+	//Looping through the available attribute/sub-value combinations in the selected metric data
+	for _, attribute := range chosenMetric.Attributes {
+
+		//Checking if the attribute/sub-value combination is to be shown and stores in a map for future use
+		if !allAttributes && matchAttribute(attribute, attributes) {
+			shownAttributes[attribute] = true
+		}
+
+		//Adding the data series in the graph if the attribute/sub-value combination is to be shown
+		if allAttributes || shownAttributes[attribute] {
+			newSeries := chart.TimeSeries{
+				Name:    attribute,
+				XValues: make([]time.Time, len(chosenMetric.AttributeData[attribute])),
+				YValues: make([]float64, len(chosenMetric.AttributeData[attribute])),
+			}
+			for i, timeStepData := range chosenMetric.AttributeData[attribute] {
+				newSeries.XValues[i] = timeStepData.DateStart
+				newSeries.YValues[i] = timeStepData.Value
+				if max < timeStepData.Value {
+					max = timeStepData.Value
 				}
 			}
+			graph.Series = append(graph.Series, newSeries)
+		}
+	}
+
+	//Looping through all alarms, checking if they belong to the shown metric and attributes, and adding them as annotations in the graph
+	alarmsMarkup := map[string]chart.AnnotationSeries{}
+	for _, outlierReport := range outlierReports {
+		if reportMatchesSite(outlierReport, chosenSite) {
+			for _, alarm := range outlierReport.Result.Alarms {
+				if alarm.Metric == metricName && (allAttributes || shownAttributes[alarm.Attribute]) {
+					if _, present := alarmsMarkup[strings.Join([]string{alarm.OutlierPeriodStart.String(), alarm.OutlierPeriodEnd.String()}, "")]; !present {
+						xOffset, _ := utils.StrToDuration(outlierReport.TimeStep)
+						xOffset = -1 * xOffset / 2
+
+						newAlarmShade := chart.TimeSeries{
+							Name: "",
+							Style: chart.Style{
+								StrokeWidth: 0,
+								StrokeColor: drawing.Color{R: 255, G: 0, B: 0, A: 0},
+								DotColor:    drawing.Color{R: 255, G: 0, B: 0, A: 0},
+								DotWidth:    0,
+								FillColor:   drawing.Color{R: 255, G: 0, B: 0, A: 40},
+							},
+							XValues: []time.Time{alarm.OutlierPeriodStart.Add(xOffset), alarm.OutlierPeriodEnd.Add(xOffset)},
+							YValues: []float64{max, max},
+						}
+						graph.Series = append(graph.Series, newAlarmShade)
+
+						xOffset2, _ := utils.StrToDuration(outlierReport.TimeAgo)
+						xOffset = xOffset - 1*xOffset2/100
 
-			//Looping through all alarms, checking if they belong to the shown metric and attributes, and adding them as annotations in the graph
-			alarmsMarkup := map[string]chart.AnnotationSeries{}
-			for _, outlierReport := range outlierReports {
-				if outlierReport.SiteId == siteUrl {
-					for _, alarm := range outlierReport.Result.Alarms {
-						if alarm.Metric == metricUrl && (allAttributes || shownAttributes[alarm.Attribute]) {
-							if _, present := alarmsMarkup[strings.Join([]string{alarm.OutlierPeriodStart.String(), alarm.OutlierPeriodEnd.String()}, "")]; !present {
-								xOffset, _ := utils.StrToDuration(outlierReport.TimeStep)
-								xOffset = -1 * xOffset / 2
-
-								newAlarmShade := chart.TimeSeries{
-									Name: "",
-									Style: chart.Style{
-										StrokeWidth: 0,
-										StrokeColor: drawing.Color{R: 255, G: 0, B: 0, A: 0},
-										DotColor:    drawing.Color{R: 255, G: 0, B: 0, A: 0},
-										DotWidth:    0,
-										FillColor:   drawing.Color{R: 255, G: 0, B: 0, A: 40},
-									},
-									XValues: []time.Time{alarm.OutlierPeriodStart.Add(xOffset), alarm.OutlierPeriodEnd.Add(xOffset)},
-									YValues: []float64{max, max},
-								}
-								graph.Series = append(graph.Series, newAlarmShade)
-
-								xOffset2, _ := utils.StrToDuration(outlierReport.TimeAgo)
-								xOffset = xOffset - 1*xOffset2/100
-
-								label := alarm.Attribute
-								parts := strings.Split(label, ">")
-								if len(parts) > 1 {
-									parts = parts[1:]
-									label = strings.Join(parts, ">")
-								}
-
-								newAlarmAnnotation := chart.AnnotationSeries{
-									Style: chart.Style{
-										DotColor:            drawing.Color{R: 255, G: 0, B: 0, A: 0},
-										FillColor:           drawing.Color{R: 255, G: 0, B: 0, A: 0},
-										StrokeColor:         drawing.Color{R: 255, G: 0, B: 0, A: 0},
-										FontColor:           drawing.Color{R: 255, G: 0, B: 0, A: 255},
-										FontSize:            8,
-										TextRotationDegrees: 90,
-									},
-									Annotations: []chart.Value2{{Label: label, XValue: float64(alarm.OutlierPeriodEnd.Add(xOffset).UnixNano()), YValue: max}},
-								}
-								graph.Series = append(graph.Series, newAlarmAnnotation)
-
-								alarmsMarkup[strings.Join([]string{alarm.OutlierPeriodStart.String(), alarm.OutlierPeriodEnd.String()}, "")] = newAlarmAnnotation
-							} else {
-								newLabel := alarm.Attribute
-								parts := strings.Split(newLabel, ">")
-								if len(parts) > 1 {
-									parts = parts[1:]
-									newLabel = strings.Join(parts, ">")
-								}
-
-								parts = strings.Split(alarmsMarkup[strings.Join([]string{alarm.OutlierPeriodStart.String(), alarm.OutlierPeriodEnd.String()}, "")].Annotations[0].Label, "+")
-								valid := true
-								for _, part := range parts {
-									if part == "Total" || strings.HasPrefix(newLabel, part) {
-										valid = false
-										break
-									}
-								}
-
-								if valid {
-									alarmsMarkup[strings.Join([]string{alarm.OutlierPeriodStart.String(), alarm.OutlierPeriodEnd.String()}, "")].Annotations[0].Label = fmt.Sprintf("%s+%s", alarmsMarkup[strings.Join([]string{alarm.OutlierPeriodStart.String(), alarm.OutlierPeriodEnd.String()}, "")].Annotations[0].Label, newLabel)
-								}
+						label := alarm.Attribute
+						parts := strings.Split(label, ">")
+						if len(parts) > 1 {
+							parts = parts[1:]
+							label = strings.Join(parts, ">")
+						}
+
+						newAlarmAnnotation := chart.AnnotationSeries{
+							Style: chart.Style{
+								DotColor:            drawing.Color{R: 255, G: 0, B: 0, A: 0},
+								FillColor:           drawing.Color{R: 255, G: 0, B: 0, A: 0},
+								StrokeColor:         drawing.Color{R: 255, G: 0, B: 0, A: 0},
+								FontColor:           drawing.Color{R: 255, G: 0, B: 0, A: 255},
+								FontSize:            8,
+								TextRotationDegrees: 90,
+							},
+							Annotations: []chart.Value2{{Label: label, XValue: float64(alarm.OutlierPeriodEnd.Add(xOffset).UnixNano()), YValue: max}},
+						}
+						graph.Series = append(graph.Series, newAlarmAnnotation)
+
+						alarmsMarkup[strings.Join([]string{alarm.OutlierPeriodStart.String(), alarm.OutlierPeriodEnd.String()}, "")] = newAlarmAnnotation
+					} else {
+						newLabel := alarm.Attribute
+						parts := strings.Split(newLabel, ">")
+						if len(parts) > 1 {
+							parts = parts[1:]
+							newLabel = strings.Join(parts, ">")
+						}
+
+						parts = strings.Split(alarmsMarkup[strings.Join([]string{alarm.OutlierPeriodStart.String(), alarm.OutlierPeriodEnd.String()}, "")].Annotations[0].Label, "+")
+						valid := true
+						for _, part := range parts {
+							if part == "Total" || strings.HasPrefix(newLabel, part) {
+								valid = false
+								break
 							}
 						}
+
+						if valid {
+							alarmsMarkup[strings.Join([]string{alarm.OutlierPeriodStart.String(), alarm.OutlierPeriodEnd.String()}, "")].Annotations[0].Label = fmt.Sprintf("%s+%s", alarmsMarkup[strings.Join([]string{alarm.OutlierPeriodStart.String(), alarm.OutlierPeriodEnd.String()}, "")].Annotations[0].Label, newLabel)
+						}
 					}
-					break
 				}
 			}
+			break
+		}
+	}
 
-			graph.YAxis.Range = &chart.ContinuousRange{
-				Min: 0.0,
-				Max: max * 1.2,
-			}
+	//Looping through the shadow method alarms, which are drawn as a blue strip at the bottom of the graph so they are not mistaken by notified alarms
+	shadowMarkup := map[string]bool{}
+	for _, outlierReport := range outlierReports {
+		if reportMatchesSite(outlierReport, chosenSite) && outlierReport.Shadow != nil {
+			for _, alarm := range outlierReport.Shadow.Alarms {
+				period := strings.Join([]string{alarm.OutlierPeriodStart.String(), alarm.OutlierPeriodEnd.String()}, "")
+				if alarm.Metric == metricName && (allAttributes || shownAttributes[alarm.Attribute]) && !shadowMarkup[period] {
+					xOffset, _ := utils.StrToDuration(outlierReport.TimeStep)
+					xOffset = -1 * xOffset / 2
 
-			graph.Elements = []chart.Renderable{
-				chart.LegendLeft(&graph),
+					newShadowShade := chart.TimeSeries{
+						Name: "",
+						Style: chart.Style{
+							StrokeWidth: 0,
+							StrokeColor: drawing.Color{R: 0, G: 0, B: 255, A: 0},
+							DotColor:    drawing.Color{R: 0, G: 0, B: 255, A: 0},
+							DotWidth:    0,
+							FillColor:   drawing.Color{R: 0, G: 0, B: 255, A: 60},
+						},
+						XValues: []time.Time{alarm.OutlierPeriodStart.Add(xOffset), alarm.OutlierPeriodEnd.Add(xOffset)},
+						YValues: []float64{max * 0.05, max * 0.05},
+					}
+					graph.Series = append(graph.Series, newShadowShade)
+					shadowMarkup[period] = true
+				}
 			}
+			break
+		}
+	}
 
-			res.Header().Set("Content-Type", "image/png")
-			graph.Render(chart.PNG, res)
+	graph.YAxis.Range = &chart.ContinuousRange{
+		Min: 0.0,
+		Max: max * 1.2,
+	}
+
+	graph.Elements = []chart.Renderable{
+		chart.LegendLeft(&graph),
+	}
+	if locale != nil {
+		step, _ := utils.StrToDuration(chosenSite.TimeStep)
+		applyLocale(&graph, *locale, step, chosenSite.DateStart, chosenSite.DateEnd)
+	} else if location := chosenSite.DateEnd.Location(); location != time.Local {
+		//Showing the time axis in the time zone of the site rather than the server one
+		graph.XAxis.ValueFormatter = func(value interface{}) string {
+			if typed, isTyped := value.(float64); isTyped {
+				value = chart.TimeFromFloat64(typed).In(location)
+			}
+			return chart.TimeValueFormatter(value)
 		}
 	}
 
-	//Registers both index and chart functions as handles and start the web server
-	router := mux.NewRouter()
-	router.PathPrefix("/report").Methods(http.MethodOptions, http.MethodGet).Subrouter().HandleFunc("", writeIndex)
-	router.PathPrefix("/report/{siteid}/{metric}").Methods(http.MethodOptions, http.MethodGet).Subrouter().HandleFunc("", drawChart)
-	srv := http.Server{
-		Handler:      router,
-		Addr:         fmt.Sprintf(":%d", port),
-		WriteTimeout: 10 * time.Second,
-		ReadTimeout:  10 * time.Second,
+	return graph, true
+}
+
+//objectiveSummary describes the compliance of a service level objective and its remaining error budget in a single line
+func objectiveSummary(objective analyser.ObjectiveReport) string {
+	status := "met"
+	if !objective.Met {
+		status = "BREACHED"
+	}
+	window := "whole range"
+	if objective.Window != "" {
+		window = "last " + objective.Window
 	}
-	srv.ListenAndServe()
+	return fmt.Sprintf("%s (%s %s %s %g on %g%% of time steps, %s) - %s - achieved %.1f%% of %d time steps, %d violations, %.0f%% of error budget remaining",
+		objective.Name, objective.Metric, objective.Attribute, objective.Operator, objective.Target, objective.Compliance, window, status,
+		objective.Achieved, objective.TimeSteps, objective.Violations, objective.BudgetRemaining)
+}
+
+//reportMatchesSite checks if a report results from the analysis of the given site data, taking its resolution into account
+func reportMatchesSite(report analyser.OutlierReport, siteData collector.SiteData) bool {
+	return report.SiteId == siteData.SiteId && (siteData.TimeStep == "" || report.TimeStep == siteData.TimeStep)
 }
@@ -0,0 +1,240 @@
+package reporting
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"encoding/pem"
+	"flag"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ftfmtavares/anomalies-detector/analyser"
+	"github.com/ftfmtavares/anomalies-detector/collector"
+	"github.com/ftfmtavares/anomalies-detector/config"
+)
+
+//update regenerates the golden files of the snapshot tests instead of comparing against them (go test ./reporting -run TestReportSnapshots -update)
+var update = flag.Bool("update", false, "update the report snapshot golden files")
+
+func TestReportSnapshots(t *testing.T) {
+	server := newTestReportServer(t)
+
+	//HTML responses are compared byte for byte while PNG charts are compared by their SHA-256 hash
+	tests := []struct {
+		name            string
+		url             string
+		wantStatus      int
+		wantContentType string
+		golden          string
+	}{
+		{name: "Index", url: "/report", wantStatus: http.StatusOK, golden: "index.html.golden"},
+		{name: "Chart of all attributes", url: "/report/shop/Revenue", wantStatus: http.StatusOK, wantContentType: "image/png", golden: "shop-revenue.png.sha256"},
+		{name: "Chart of a single attribute", url: "/report/shop/Revenue?attribute=browser>edge", wantStatus: http.StatusOK, wantContentType: "image/png", golden: "shop-revenue-edge.png.sha256"},
+		{name: "Chart of another resolution", url: "/report/shop/Revenue?resolution=1h", wantStatus: http.StatusOK, wantContentType: "image/png", golden: "shop-revenue-1h.png.sha256"},
+		{name: "Chart of a single resolution site", url: "/report/blog/Visits", wantStatus: http.StatusOK, wantContentType: "image/png", golden: "blog-visits.png.sha256"},
+		{name: "Chart as a percentage of the baseline", url: "/report/shop/Revenue?scale=percent", wantStatus: http.StatusOK, wantContentType: "image/png", golden: "shop-revenue-percent.png.sha256"},
+		{name: "Chart zoomed into a time range", url: "/report/shop/Revenue?resolution=1h&from=2022-08-31T12:00:00Z&to=2022-09-01T00:00:00Z", wantStatus: http.StatusOK, wantContentType: "image/png", golden: "shop-revenue-zoom.png.sha256"},
+		{name: "Chart downsampled", url: "/report/shop/Revenue?resolution=1h&downsample=12", wantStatus: http.StatusOK, wantContentType: "image/png", golden: "shop-revenue-downsample.png.sha256"},
+		{name: "Chart of z-scores without detection settings", url: "/report/shop/Revenue?view=zscore", wantStatus: http.StatusOK, wantContentType: "image/png"},
+		{name: "Chart of z-scores with tuned limits", url: "/report/shop/Revenue?view=zscore&outliersMultiplier=1.5&strongOutliersMultiplier=2.5", wantStatus: http.StatusOK, wantContentType: "image/png", golden: "shop-revenue-zscore.png.sha256"},
+		{name: "Unknown scale", url: "/report/shop/Revenue?scale=log", wantStatus: http.StatusBadRequest},
+		{name: "Unknown view", url: "/report/shop/Revenue?view=residuals", wantStatus: http.StatusBadRequest},
+		{name: "Z-scores as a percentage", url: "/report/shop/Revenue?view=zscore&scale=percent", wantStatus: http.StatusBadRequest},
+		{name: "Invalid limit", url: "/report/shop/Revenue?view=zscore&strongOutliersMultiplier=-3", wantStatus: http.StatusBadRequest},
+		{name: "Invalid downsample", url: "/report/shop/Revenue?downsample=1", wantStatus: http.StatusBadRequest},
+		{name: "Invalid time range", url: "/report/shop/Revenue?from=yesterday", wantStatus: http.StatusBadRequest},
+		{name: "Unknown site", url: "/report/unknown/Revenue", wantStatus: http.StatusNotFound},
+		{name: "Unknown metric", url: "/report/shop/Unknown", wantStatus: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := http.Get(server.URL + tt.url)
+			if err != nil {
+				t.Fatalf("GET %s error = %v", tt.url, err)
+			}
+			defer resp.Body.Close()
+			body, err := io.ReadAll(resp.Body)
+			if err != nil {
+				t.Fatalf("GET %s error = %v", tt.url, err)
+			}
+
+			if resp.StatusCode != tt.wantStatus {
+				t.Fatalf("GET %s status = %d, want %d", tt.url, resp.StatusCode, tt.wantStatus)
+			}
+			if tt.wantContentType != "" && resp.Header.Get("Content-Type") != tt.wantContentType {
+				t.Errorf("GET %s content type = %s, want %s", tt.url, resp.Header.Get("Content-Type"), tt.wantContentType)
+			}
+			if tt.golden == "" {
+				return
+			}
+			if tt.wantContentType == "image/png" {
+				hash := sha256.Sum256(body)
+				body = []byte(hex.EncodeToString(hash[:]) + "\n")
+			}
+			assertGolden(t, tt.golden, body)
+		})
+	}
+}
+
+//newTestReportServer starts a report server over the fixture data, closed once the test finishes
+func newTestReportServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	sitesData, reports := reportFixture()
+	server := httptest.NewServer(newReportRouter(sitesData, reports, config.ReportServerParams{}, DetectionSettings{}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+//assertGolden compares a response with its golden file on testdata, rewriting the file instead if the update flag is set
+func assertGolden(t *testing.T, golden string, got []byte) {
+	t.Helper()
+	file := filepath.Join("testdata", golden)
+	if *update {
+		if err := os.MkdirAll("testdata", 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(file, got, 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+
+	want, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatalf("reading golden file %s - %v (run with -update to create it)", file, err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("response differs from golden file %s (run with -update if the change is intended)\ngot:\n%s\nwant:\n%s", file, got, want)
+	}
+}
+
+//reportFixture returns deterministic data and reports covering the index and chart features
+//Site shop is analysed daily and hourly with alarms, warnings and shadow alarms while site blog has a single resolution with objectives
+func reportFixture() ([]collector.SiteData, []analyser.OutlierReport) {
+	timeRef := time.Date(2022, 9, 1, 0, 0, 0, 0, time.UTC)
+
+	series := func(steps int, step time.Duration, base float64, spike int) []collector.TimeStepData {
+		data := []collector.TimeStepData{}
+		for i := 0; i < steps; i++ {
+			value := base + float64(i%7)*base/20
+			if i == spike {
+				value *= 3
+			}
+			data = append(data, collector.TimeStepData{DateStart: timeRef.Add(time.Duration(i-steps) * step), Value: value, Samples: int(base)})
+		}
+		return data
+	}
+	revenue := func(steps int, step time.Duration) collector.MetricData {
+		return collector.MetricData{Metric: "Revenue", Unit: "€", Attributes: []string{"Total", "Browser>Chrome", "Browser>Edge"}, AttributeData: map[string][]collector.TimeStepData{
+			"Total":          series(steps, step, 1000, steps-3),
+			"Browser>Chrome": series(steps, step, 800, -1),
+			"Browser>Edge":   series(steps, step, 200, steps-3),
+		}}
+	}
+	visits := collector.MetricData{Metric: "Visits", Attributes: []string{"Total", "Country>PT"}, AttributeData: map[string][]collector.TimeStepData{
+		"Total":      series(30, 24*time.Hour, 500, -1),
+		"Country>PT": series(30, 24*time.Hour, 100, -1),
+	}}
+
+	sitesData := []collector.SiteData{
+		{SiteId: "shop", TimeStep: "1d", DateStart: timeRef.AddDate(0, 0, -30), DateEnd: timeRef, Metrics: []collector.MetricData{revenue(30, 24*time.Hour)}},
+		{SiteId: "shop", TimeStep: "1h", DateStart: timeRef.Add(-48 * time.Hour), DateEnd: timeRef, Metrics: []collector.MetricData{revenue(48, time.Hour)}},
+		{SiteId: "blog", TimeStep: "1d", DateStart: timeRef.AddDate(0, 0, -30), DateEnd: timeRef, Metrics: []collector.MetricData{visits}},
+	}
+
+	event := func(step time.Duration, metric, attribute string) analyser.OutlierEvent {
+		start := timeRef.Add(time.Duration(-3) * step)
+		return analyser.OutlierEvent{OutlierPeriodStart: start, OutlierPeriodEnd: start.Add(step), Metric: metric, Attribute: attribute}
+	}
+	reports := []analyser.OutlierReport{
+		{SiteId: "shop", TimeAgo: "30d", TimeStep: "1d", Result: analyser.OutlierResults{
+			Alarms:   []analyser.OutlierEvent{event(24*time.Hour, "Revenue", "Total"), event(24*time.Hour, "Revenue", "Browser>Edge")},
+			Warnings: []analyser.OutlierEvent{event(24*time.Hour, "Revenue", "Browser>Chrome")},
+		}, Shadow: &analyser.OutlierResults{
+			Alarms: []analyser.OutlierEvent{event(24*time.Hour, "Revenue", "Browser>Edge")},
+		}},
+		{SiteId: "shop", TimeAgo: "2d", TimeStep: "1h", Result: analyser.OutlierResults{
+			Alarms: []analyser.OutlierEvent{event(time.Hour, "Revenue", "Browser>Edge")},
+		}},
+		{SiteId: "blog", TimeAgo: "30d", TimeStep: "1d", Objectives: []analyser.ObjectiveReport{
+			{Name: "Visits floor", Metric: "Visits", Attribute: "Total", Operator: ">=", Target: 450, Compliance: 90, TimeSteps: 30, Violations: 0, Achieved: 100, ErrorBudget: 3, BudgetRemaining: 100, Met: true},
+			{Name: "PT visits", Metric: "Visits", Attribute: "Country>PT", Operator: ">=", Target: 110, Compliance: 95, Window: "7d", TimeSteps: 7, Violations: 3, Achieved: 57.1, ErrorBudget: 0, BudgetRemaining: 0},
+		}},
+	}
+
+	return sitesData, reports
+}
+
+func TestReportUrl(t *testing.T) {
+	tests := []struct {
+		name         string
+		serverParams config.ReportServerParams
+		want         string
+	}{
+		{name: "Default address", want: "http://localhost:8080/report"},
+		{name: "Local host only", serverParams: config.ReportServerParams{Listen: "127.0.0.1:9090"}, want: "http://127.0.0.1:9090/report"},
+		{name: "TLS on every IPv6 interface", serverParams: config.ReportServerParams{Listen: "[::]:8443", CertFile: "cert.pem", KeyFile: "key.pem"}, want: "https://localhost:8443/report"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ReportUrl(tt.serverParams); got != tt.want {
+				t.Errorf("ReportUrl() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGenerateReportTLS(t *testing.T) {
+	//Writing a self-signed certificate for localhost and picking a free port to serve the report on
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	template := x509.Certificate{SerialNumber: big.NewInt(1), Subject: pkix.Name{CommonName: "localhost"}, DNSNames: []string{"localhost"}, NotBefore: time.Now().Add(-time.Hour), NotAfter: time.Now().Add(time.Hour)}
+	cert, _ := x509.CreateCertificate(rand.Reader, &template, &template, &key.PublicKey, key)
+	keyBytes, _ := x509.MarshalECPrivateKey(key)
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert}), 0600)
+	os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyBytes}), 0600)
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("net.Listen() error = %v", err)
+	}
+	listen := listener.Addr().String()
+	listener.Close()
+
+	if err := GenerateReport(nil, nil, config.ReportServerParams{Listen: listen, CertFile: filepath.Join(dir, "missing.pem"), KeyFile: keyFile}, DetectionSettings{}); err == nil {
+		t.Fatalf("GenerateReport() without its certificate returned no error")
+	}
+	go GenerateReport(nil, nil, config.ReportServerParams{Listen: listen, CertFile: certFile, KeyFile: keyFile}, DetectionSettings{})
+
+	pool := x509.NewCertPool()
+	pool.AppendCertsFromPEM(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert}))
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: pool, ServerName: "localhost"}}, Timeout: time.Second}
+	for attempt := 0; ; attempt++ {
+		res, err := client.Get("https://" + listen + "/report")
+		if err == nil {
+			res.Body.Close()
+			if res.StatusCode != http.StatusOK {
+				t.Errorf("GET /report over TLS status = %d, want %d", res.StatusCode, http.StatusOK)
+			}
+			break
+		}
+		if attempt == 50 {
+			t.Fatalf("GET /report over TLS error = %v", err)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
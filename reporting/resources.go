@@ -0,0 +1,134 @@
+package reporting
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ftfmtavares/anomalies-detector/analyser"
+	"github.com/ftfmtavares/anomalies-detector/collector"
+)
+
+//SiteSummary provides the structure returned by the sites endpoint for each collected site and time step, along with its number of events
+type SiteSummary struct {
+	SiteId    string          `json:"siteId"`
+	TimeStep  string          `json:"timeStep"`
+	DateStart time.Time       `json:"dateStart"`
+	DateEnd   time.Time       `json:"dateEnd"`
+	Archived  bool            `json:"archived,omitempty"`
+	Metrics   []MetricSummary `json:"metrics"`
+	Alarms    int             `json:"alarms"`
+	Warnings  int             `json:"warnings"`
+}
+
+//MetricSummary holds the unit and attribute/sub-values combinations of a collected metric
+type MetricSummary struct {
+	Metric     string   `json:"metric"`
+	Unit       string   `json:"unit"`
+	Attributes []string `json:"attributes"`
+}
+
+//EventsPage provides the structure returned by the alarms endpoint
+//NextCursor field is only filled when more events are available after the returned page
+type EventsPage struct {
+	Events     []ReportEvent `json:"events"`
+	NextCursor string        `json:"nextCursor,omitempty"`
+}
+
+//ReportEvent holds a detected event along with its site, time step and severity
+type ReportEvent struct {
+	SiteId   string `json:"siteId"`
+	TimeStep string `json:"timeStep"`
+	Severity string `json:"severity"`
+	analyser.OutlierEvent
+}
+
+//sitesHandler implements an HTTP response returning the collected sites, their time steps and metrics in JSON format
+func sitesHandler(sitesData []collector.SiteData, outlierReports []analyser.OutlierReport) http.HandlerFunc {
+	return func(res http.ResponseWriter, req *http.Request) {
+		sites := []SiteSummary{}
+		for _, siteData := range sitesData {
+			site := SiteSummary{SiteId: siteData.SiteId, TimeStep: siteData.TimeStep, DateStart: siteData.DateStart, DateEnd: siteData.DateEnd, Archived: siteData.Archived, Metrics: []MetricSummary{}}
+			for _, metricData := range siteData.Metrics {
+				site.Metrics = append(site.Metrics, MetricSummary{Metric: metricData.Metric, Unit: metricData.Unit, Attributes: metricData.Attributes})
+			}
+			for _, outlierReport := range outlierReports {
+				if reportMatchesSite(outlierReport, siteData) {
+					site.Alarms = len(outlierReport.Result.Alarms)
+					site.Warnings = len(outlierReport.Result.Warnings)
+					break
+				}
+			}
+			sites = append(sites, site)
+		}
+		writeJson(res, http.StatusOK, sites)
+	}
+}
+
+//alarmsHandler implements an HTTP response returning the detected events of every site in JSON format, alarms before warnings within each site
+//Supported query strings are site, metric and resolution, severity ("alarm" or "warning", repeated or comma separated, both if not given),
+//attributes (prefix match), from and to (RFC3339, keeping the events overlapping that range) and limit and cursor for pagination over events
+func alarmsHandler(outlierReports []analyser.OutlierReport) http.HandlerFunc {
+	return func(res http.ResponseWriter, req *http.Request) {
+		values := req.URL.Query()
+		query, err := parseSeriesQuery(req)
+		if err != nil {
+			writeJsonError(res, http.StatusBadRequest, err)
+			return
+		}
+		severities := map[string]bool{}
+		for _, value := range values["severity"] {
+			for _, severity := range strings.Split(value, ",") {
+				if severity = strings.TrimSpace(severity); severity != analyser.VerdictAlarm && severity != analyser.VerdictWarning {
+					writeJsonError(res, http.StatusBadRequest, errors.New("invalid severity parameter, alarm or warning expected"))
+					return
+				}
+				severities[severity] = true
+			}
+		}
+		if len(severities) == 0 {
+			severities = map[string]bool{analyser.VerdictAlarm: true, analyser.VerdictWarning: true}
+		}
+
+		events := filterEvents(outlierReports, values.Get("site"), values.Get("resolution"), values.Get("metric"), severities, query)
+		page := EventsPage{Events: []ReportEvent{}}
+		if query.offset < len(events) {
+			end := query.offset + query.limit
+			if end < len(events) {
+				page.NextCursor = encodeCursor(end)
+			} else {
+				end = len(events)
+			}
+			page.Events = events[query.offset:end]
+		}
+		writeJson(res, http.StatusOK, page)
+	}
+}
+
+//filterEvents returns the events of the reports matching the given site, time step and metric (empty for all), severities and query attributes and time range
+func filterEvents(outlierReports []analyser.OutlierReport, siteId, timeStep, metric string, severities map[string]bool, query seriesQuery) []ReportEvent {
+	res := []ReportEvent{}
+	add := func(report analyser.OutlierReport, events []analyser.OutlierEvent, severity string) {
+		if !severities[severity] {
+			return
+		}
+		for _, event := range events {
+			if (metric != "" && event.Metric != metric) || !matchAttribute(event.Attribute, query.attributes) {
+				continue
+			}
+			if (!query.from.IsZero() && !event.OutlierPeriodEnd.After(query.from)) || (!query.to.IsZero() && !event.OutlierPeriodStart.Before(query.to)) {
+				continue
+			}
+			res = append(res, ReportEvent{SiteId: report.SiteId, TimeStep: report.TimeStep, Severity: severity, OutlierEvent: event})
+		}
+	}
+	for _, report := range outlierReports {
+		if (siteId != "" && report.SiteId != siteId) || (timeStep != "" && report.TimeStep != timeStep) {
+			continue
+		}
+		add(report, report.Result.Alarms, analyser.VerdictAlarm)
+		add(report, report.Result.Warnings, analyser.VerdictWarning)
+	}
+	return res
+}
@@ -0,0 +1,80 @@
+package reporting
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+func Test_sitesHandler(t *testing.T) {
+	server := newTestReportServer(t)
+
+	resp, err := http.Get(server.URL + "/api/v1/sites")
+	if err != nil {
+		t.Fatalf("GET error = %v", err)
+	}
+	defer resp.Body.Close()
+	var sites []SiteSummary
+	if err := json.NewDecoder(resp.Body).Decode(&sites); err != nil || resp.StatusCode != http.StatusOK {
+		t.Fatalf("GET status = %d, error = %v", resp.StatusCode, err)
+	}
+
+	if len(sites) != 3 || sites[0].SiteId != "shop" || sites[0].TimeStep != "1d" || sites[1].TimeStep != "1h" || sites[2].SiteId != "blog" {
+		t.Fatalf("sitesHandler() = %+v", sites)
+	}
+	if sites[0].Alarms != 2 || sites[0].Warnings != 1 || sites[1].Alarms != 1 || sites[2].Alarms != 0 {
+		t.Errorf("sitesHandler() events = %+v", sites)
+	}
+	if len(sites[0].Metrics) != 1 || sites[0].Metrics[0].Metric != "Revenue" || len(sites[0].Metrics[0].Attributes) != 3 {
+		t.Errorf("sitesHandler() metrics = %+v", sites[0].Metrics)
+	}
+}
+
+func Test_alarmsHandler(t *testing.T) {
+	server := newTestReportServer(t)
+
+	tests := []struct {
+		name       string
+		query      string
+		wantStatus int
+		wantEvents []string
+		wantCursor bool
+	}{
+		{name: "All events", query: "", wantStatus: http.StatusOK, wantEvents: []string{"1d/alarm/Total", "1d/alarm/Browser>Edge", "1d/warning/Browser>Chrome", "1h/alarm/Browser>Edge"}},
+		{name: "Filter by severity", query: "?severity=warning", wantStatus: http.StatusOK, wantEvents: []string{"1d/warning/Browser>Chrome"}},
+		{name: "Filter by resolution and attribute", query: "?site=shop&resolution=1d&attributes=browser", wantStatus: http.StatusOK, wantEvents: []string{"1d/alarm/Browser>Edge", "1d/warning/Browser>Chrome"}},
+		{name: "Filter by time range", query: "?from=2022-08-31T00:00:00Z", wantStatus: http.StatusOK, wantEvents: []string{"1h/alarm/Browser>Edge"}},
+		{name: "Unknown site", query: "?site=blog", wantStatus: http.StatusOK, wantEvents: []string{}},
+		{name: "Paginated", query: "?limit=3", wantStatus: http.StatusOK, wantEvents: []string{"1d/alarm/Total", "1d/alarm/Browser>Edge", "1d/warning/Browser>Chrome"}, wantCursor: true},
+		{name: "Invalid severity", query: "?severity=critical", wantStatus: http.StatusBadRequest},
+		{name: "Invalid time range", query: "?from=yesterday", wantStatus: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := http.Get(server.URL + "/api/v1/alarms" + tt.query)
+			if err != nil {
+				t.Fatalf("GET error = %v", err)
+			}
+			defer resp.Body.Close()
+			if resp.StatusCode != tt.wantStatus {
+				t.Fatalf("GET status = %d, want %d", resp.StatusCode, tt.wantStatus)
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+
+			var page EventsPage
+			if err := json.NewDecoder(resp.Body).Decode(&page); err != nil {
+				t.Fatalf("Decode() error = %v", err)
+			}
+			if len(page.Events) != len(tt.wantEvents) || (page.NextCursor != "") != tt.wantCursor {
+				t.Fatalf("alarmsHandler() = %+v, want %v", page, tt.wantEvents)
+			}
+			for i, event := range page.Events {
+				if got := event.TimeStep + "/" + event.Severity + "/" + event.Attribute; got != tt.wantEvents[i] || event.SiteId != "shop" {
+					t.Errorf("alarmsHandler() event %d = %s, want %s", i, got, tt.wantEvents[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,219 @@
+package reporting
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ftfmtavares/anomalies-detector/analyser"
+	"github.com/ftfmtavares/anomalies-detector/collector"
+	"github.com/ftfmtavares/anomalies-detector/config"
+	"github.com/ftfmtavares/anomalies-detector/utils"
+)
+
+//resultStoreDrivers maps the configured database drivers to the registered database/sql ones, each being added by the file of its build tag
+var resultStoreDrivers = map[string]string{}
+
+//ResultStore writes the collected data and detected events of every run to a SQLite or PostgreSQL database
+//Time steps and events collected again by later runs replace the stored ones, so the tables hold the whole history of every site without duplicates
+type ResultStore struct {
+	db     *sql.DB
+	driver string
+}
+
+//NewResultStore opens the configured database and creates its tables if missing
+//It returns an error if the driver is invalid or isn't built into the binary
+func NewResultStore(params config.DatabaseParams) (*ResultStore, error) {
+	if params.Driver != config.DatabaseSqlite && params.Driver != config.DatabasePostgres {
+		return nil, fmt.Errorf("database - invalid driver \"%s\"", params.Driver)
+	}
+	if err := utils.CheckIntegration(params.Driver); err != nil {
+		return nil, fmt.Errorf("database - %s", err.Error())
+	}
+	driver := resultStoreDrivers[params.Driver]
+	dsn := utils.ResolveSecret(params.Dsn)
+	if params.Driver == config.DatabasePostgres {
+		tlsDsn, err := utils.PostgresTlsDsn(dsn)
+		if err != nil {
+			return nil, fmt.Errorf("database - %s", err.Error())
+		}
+		dsn = tlsDsn
+	}
+	db, err := sql.Open(driver, dsn)
+	if err != nil {
+		return nil, fmt.Errorf("database - %s", err.Error())
+	}
+	store := &ResultStore{db: db, driver: params.Driver}
+
+	timestamp := "TIMESTAMP"
+	if params.Driver == config.DatabasePostgres {
+		timestamp = "TIMESTAMPTZ"
+	}
+	statements := []string{
+		`CREATE TABLE IF NOT EXISTS runs (run_time ` + timestamp + ` PRIMARY KEY, sites INTEGER NOT NULL, events INTEGER NOT NULL)`,
+		`CREATE TABLE IF NOT EXISTS site_data (site_id TEXT NOT NULL, time_step TEXT NOT NULL, metric TEXT NOT NULL, unit TEXT NOT NULL, attribute TEXT NOT NULL, date_start ` + timestamp + ` NOT NULL, value DOUBLE PRECISION NOT NULL, samples INTEGER NOT NULL, run_time ` + timestamp + ` NOT NULL, PRIMARY KEY (site_id, time_step, metric, attribute, date_start))`,
+		`CREATE TABLE IF NOT EXISTS outlier_events (site_id TEXT NOT NULL, time_step TEXT NOT NULL, metric TEXT NOT NULL, attribute TEXT NOT NULL, period_start ` + timestamp + ` NOT NULL, period_end ` + timestamp + ` NOT NULL, severity TEXT NOT NULL, score DOUBLE PRECISION NOT NULL, observed DOUBLE PRECISION NOT NULL, expected DOUBLE PRECISION NOT NULL, deviation_percent DOUBLE PRECISION NOT NULL, direction TEXT NOT NULL, run_time ` + timestamp + ` NOT NULL, PRIMARY KEY (site_id, time_step, metric, attribute, period_start))`,
+	}
+	for _, statement := range statements {
+		if _, err := db.Exec(statement); err != nil {
+			db.Close()
+			return nil, fmt.Errorf("database - %s", err.Error())
+		}
+	}
+	return store, nil
+}
+
+//Close closes the database
+func (store *ResultStore) Close() error {
+	return store.db.Close()
+}
+
+//SaveRun writes the collected data and the alarms and warnings of a run in a single transaction, nothing being written if any statement fails
+func (store *ResultStore) SaveRun(runTime time.Time, sitesData []collector.SiteData, reports []analyser.OutlierReport) error {
+	runTime = runTime.UTC()
+	tx, err := store.db.Begin()
+	if err != nil {
+		return fmt.Errorf("database - %s", err.Error())
+	}
+	defer tx.Rollback()
+
+	dataStmt, err := tx.Prepare(store.rebind(`INSERT INTO site_data (site_id, time_step, metric, unit, attribute, date_start, value, samples, run_time) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (site_id, time_step, metric, attribute, date_start) DO UPDATE SET unit = excluded.unit, value = excluded.value, samples = excluded.samples, run_time = excluded.run_time`))
+	if err != nil {
+		return fmt.Errorf("database - %s", err.Error())
+	}
+	defer dataStmt.Close()
+	for _, siteData := range sitesData {
+		for _, metricData := range siteData.Metrics {
+			for _, attribute := range metricData.Attributes {
+				for _, stepData := range metricData.AttributeData[attribute] {
+					if _, err := dataStmt.Exec(siteData.SiteId, siteData.TimeStep, metricData.Metric, metricData.Unit, attribute, stepData.DateStart.UTC(), stepData.Value, stepData.Samples, runTime); err != nil {
+						return fmt.Errorf("database - site %s - %s", siteData.SiteId, err.Error())
+					}
+				}
+			}
+		}
+	}
+
+	eventStmt, err := tx.Prepare(store.rebind(`INSERT INTO outlier_events (site_id, time_step, metric, attribute, period_start, period_end, severity, score, observed, expected, deviation_percent, direction, run_time) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (site_id, time_step, metric, attribute, period_start) DO UPDATE SET period_end = excluded.period_end, severity = excluded.severity, score = excluded.score, observed = excluded.observed,
+		expected = excluded.expected, deviation_percent = excluded.deviation_percent, direction = excluded.direction, run_time = excluded.run_time`))
+	if err != nil {
+		return fmt.Errorf("database - %s", err.Error())
+	}
+	defer eventStmt.Close()
+	events := filterEvents(reports, "", "", "", map[string]bool{analyser.VerdictAlarm: true, analyser.VerdictWarning: true}, seriesQuery{})
+	for _, event := range events {
+		if _, err := eventStmt.Exec(event.SiteId, event.TimeStep, event.Metric, event.Attribute, event.OutlierPeriodStart.UTC(), event.OutlierPeriodEnd.UTC(), event.Severity, event.Score, event.Observed, event.Expected, event.DeviationPercent, event.Direction, runTime); err != nil {
+			return fmt.Errorf("database - site %s - %s", event.SiteId, err.Error())
+		}
+	}
+
+	if _, err := tx.Exec(store.rebind(`INSERT INTO runs (run_time, sites, events) VALUES (?, ?, ?) ON CONFLICT (run_time) DO UPDATE SET sites = excluded.sites, events = excluded.events`), runTime, len(sitesData), len(events)); err != nil {
+		return fmt.Errorf("database - %s", err.Error())
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("database - %s", err.Error())
+	}
+	return nil
+}
+
+//Series returns the stored data of a site metric over the given period, a zero from or to time leaving the range open on that side
+//The returned metric holds no attribute if nothing is stored
+func (store *ResultStore) Series(siteId, timeStep, metric string, from, to time.Time) (collector.MetricData, error) {
+	res := collector.MetricData{Metric: metric, Attributes: []string{}, AttributeData: map[string][]collector.TimeStepData{}}
+	query, args := store.rangeQuery(`SELECT unit, attribute, date_start, value, samples FROM site_data WHERE site_id = ? AND time_step = ? AND metric = ?`, "date_start", []interface{}{siteId, timeStep, metric}, from, to)
+	rows, err := store.db.Query(query+` ORDER BY attribute, date_start`, args...)
+	if err != nil {
+		return res, fmt.Errorf("database - %s", err.Error())
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var attribute string
+		var stepData collector.TimeStepData
+		if err := rows.Scan(&res.Unit, &attribute, &stepData.DateStart, &stepData.Value, &stepData.Samples); err != nil {
+			return res, fmt.Errorf("database - %s", err.Error())
+		}
+		if _, found := res.AttributeData[attribute]; !found {
+			res.Attributes = append(res.Attributes, attribute)
+		}
+		stepData.DateStart = stepData.DateStart.UTC()
+		res.AttributeData[attribute] = append(res.AttributeData[attribute], stepData)
+	}
+	if err := rows.Err(); err != nil {
+		return res, fmt.Errorf("database - %s", err.Error())
+	}
+	return res, nil
+}
+
+//Events returns the stored events of a site (empty for all) overlapping the given period, ordered by period start
+func (store *ResultStore) Events(siteId string, from, to time.Time) ([]ReportEvent, error) {
+	res := []ReportEvent{}
+	query := `SELECT site_id, time_step, severity, metric, attribute, period_start, period_end, score, observed, expected, deviation_percent, direction FROM outlier_events WHERE 1 = 1`
+	args := []interface{}{}
+	if siteId != "" {
+		query += ` AND site_id = ?`
+		args = append(args, siteId)
+	}
+	if !from.IsZero() {
+		query += ` AND period_end > ?`
+		args = append(args, from.UTC())
+	}
+	if !to.IsZero() {
+		query += ` AND period_start < ?`
+		args = append(args, to.UTC())
+	}
+	rows, err := store.db.Query(store.rebind(query+` ORDER BY period_start, site_id, time_step, metric, attribute`), args...)
+	if err != nil {
+		return res, fmt.Errorf("database - %s", err.Error())
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var event ReportEvent
+		if err := rows.Scan(&event.SiteId, &event.TimeStep, &event.Severity, &event.Metric, &event.Attribute, &event.OutlierPeriodStart, &event.OutlierPeriodEnd, &event.Score, &event.Observed, &event.Expected, &event.DeviationPercent, &event.Direction); err != nil {
+			return res, fmt.Errorf("database - %s", err.Error())
+		}
+		event.OutlierPeriodStart = event.OutlierPeriodStart.UTC()
+		event.OutlierPeriodEnd = event.OutlierPeriodEnd.UTC()
+		res = append(res, event)
+	}
+	if err := rows.Err(); err != nil {
+		return res, fmt.Errorf("database - %s", err.Error())
+	}
+	return res, nil
+}
+
+//rangeQuery appends the conditions of a time range on the given column to a query, returning it rebound along with its arguments
+func (store *ResultStore) rangeQuery(query, column string, args []interface{}, from, to time.Time) (string, []interface{}) {
+	if !from.IsZero() {
+		query += ` AND ` + column + ` >= ?`
+		args = append(args, from.UTC())
+	}
+	if !to.IsZero() {
+		query += ` AND ` + column + ` < ?`
+		args = append(args, to.UTC())
+	}
+	return store.rebind(query), args
+}
+
+//rebind replaces the question mark placeholders of a query with the numbered ones PostgreSQL expects
+func (store *ResultStore) rebind(query string) string {
+	if store.driver != config.DatabasePostgres {
+		return query
+	}
+	var res strings.Builder
+	n := 0
+	for _, char := range query {
+		if char == '?' {
+			n++
+			res.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		res.WriteRune(char)
+	}
+	return res.String()
+}
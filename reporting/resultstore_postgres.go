@@ -0,0 +1,17 @@
+//go:build postgres || full
+
+package reporting
+
+import (
+	"github.com/ftfmtavares/anomalies-detector/config"
+	"github.com/ftfmtavares/anomalies-detector/utils"
+
+	//Registering the PostgreSQL driver of the result store
+	_ "github.com/lib/pq"
+)
+
+//init registers the PostgreSQL result store, built in with the postgres build tag
+func init() {
+	utils.RegisterIntegration(utils.IntegrationPostgres)
+	resultStoreDrivers[config.DatabasePostgres] = "postgres"
+}
@@ -0,0 +1,17 @@
+//go:build sqlite || full
+
+package reporting
+
+import (
+	"github.com/ftfmtavares/anomalies-detector/config"
+	"github.com/ftfmtavares/anomalies-detector/utils"
+
+	//Registering the SQLite driver of the result store, which requires cgo
+	_ "github.com/mattn/go-sqlite3"
+)
+
+//init registers the SQLite result store, built in with the sqlite build tag
+func init() {
+	utils.RegisterIntegration(utils.IntegrationSqlite)
+	resultStoreDrivers[config.DatabaseSqlite] = "sqlite3"
+}
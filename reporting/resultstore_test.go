@@ -0,0 +1,80 @@
+package reporting
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ftfmtavares/anomalies-detector/collector"
+	"github.com/ftfmtavares/anomalies-detector/config"
+	"github.com/ftfmtavares/anomalies-detector/utils"
+)
+
+func TestResultStore(t *testing.T) {
+	if err := utils.CheckIntegration(utils.IntegrationSqlite); err != nil {
+		t.Skip(err.Error())
+	}
+	sitesData, reports := reportFixture()
+	store, err := NewResultStore(config.DatabaseParams{Driver: config.DatabaseSqlite, Dsn: filepath.Join(t.TempDir(), "results.db")})
+	if err != nil {
+		t.Fatalf("NewResultStore() error = %v", err)
+	}
+	defer store.Close()
+
+	//Saving the same run twice replaces the stored rows instead of duplicating them
+	runTime := time.Date(2022, 9, 1, 6, 0, 0, 0, time.UTC)
+	for i := 0; i < 2; i++ {
+		if err := store.SaveRun(runTime, sitesData, reports); err != nil {
+			t.Fatalf("SaveRun() error = %v", err)
+		}
+	}
+
+	timeRef := time.Date(2022, 9, 1, 0, 0, 0, 0, time.UTC)
+	got, err := store.Series("shop", "1d", "Revenue", timeRef.AddDate(0, 0, -3), time.Time{})
+	if err != nil {
+		t.Fatalf("Series() error = %v", err)
+	}
+	want := collector.TimeRange(sitesData[0].Metrics[0], timeRef.AddDate(0, 0, -3), time.Time{})
+	if got.Unit != want.Unit || !reflect.DeepEqual(got.AttributeData, want.AttributeData) || len(got.Attributes) != 3 {
+		t.Errorf("Series() = %v, want %v", got, want)
+	}
+
+	tests := []struct {
+		name       string
+		siteId     string
+		from       time.Time
+		to         time.Time
+		wantEvents int
+	}{
+		{name: "All events", wantEvents: 4},
+		{name: "Events of a site", siteId: "blog", wantEvents: 0},
+		{name: "Events overlapping a period", from: timeRef.Add(-12 * time.Hour), wantEvents: 1},
+		{name: "Events before a period", to: timeRef.AddDate(0, 0, -2), wantEvents: 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			events, err := store.Events(tt.siteId, tt.from, tt.to)
+			if err != nil {
+				t.Fatalf("Events() error = %v", err)
+			}
+			if len(events) != tt.wantEvents {
+				t.Errorf("Events() = %d events, want %d", len(events), tt.wantEvents)
+			}
+		})
+	}
+
+	if _, err := NewResultStore(config.DatabaseParams{Driver: "mysql", Dsn: "test"}); err == nil {
+		t.Errorf("NewResultStore() expected an error on an unsupported driver")
+	}
+}
+
+func TestResultStore_rebind(t *testing.T) {
+	query := `SELECT value FROM site_data WHERE site_id = ? AND metric = ?`
+	if got := (&ResultStore{driver: config.DatabaseSqlite}).rebind(query); got != query {
+		t.Errorf("rebind() = %s, want %s", got, query)
+	}
+	if got, want := (&ResultStore{driver: config.DatabasePostgres}).rebind(query), `SELECT value FROM site_data WHERE site_id = $1 AND metric = $2`; got != want {
+		t.Errorf("rebind() = %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,139 @@
+package reporting
+
+import (
+	"fmt"
+	"html"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/ftfmtavares/anomalies-detector/analyser"
+	"github.com/ftfmtavares/anomalies-detector/collector"
+)
+
+//Const block defines how many stored runs are kept loaded for browsing and how many are linked from the report index
+const (
+	maxLoadedRuns = 4
+	maxListedRuns = 30
+)
+
+//reportRun provides the structure for the data and reports served for a request, either those of the current run or those of a stored one
+//Query field holds the run query string to keep on the links of a stored run, empty for the current one
+type reportRun struct {
+	sitesData []collector.SiteData
+	reports   []analyser.OutlierReport
+	runTime   time.Time
+	query     string
+}
+
+//runBrowser serves the stored runs of the run history, keeping the latest browsed ones loaded since charts of the same run are usually viewed together
+type runBrowser struct {
+	history *analyser.RunHistory
+	current reportRun
+	mu      sync.Mutex
+	loaded  []reportRun
+}
+
+//newRunBrowser creates a runBrowser over the given history, which may be nil, serving the given data and reports as the current run
+func newRunBrowser(history *analyser.RunHistory, sitesData []collector.SiteData, outlierReports []analyser.OutlierReport) *runBrowser {
+	return &runBrowser{history: history, current: reportRun{sitesData: sitesData, reports: outlierReports, runTime: analyser.RunTime(outlierReports)}}
+}
+
+//run returns the run a request refers to, the stored run given on its "run" query string (RFC3339) or the current one without it
+//Runs stored without their data are served with the data of the current run
+//It writes a 400 or 404 response and returns false if the run is invalid or isn't stored
+func (browser *runBrowser) run(res http.ResponseWriter, req *http.Request) (reportRun, bool) {
+	value := req.URL.Query().Get("run")
+	if value == "" {
+		return browser.current, true
+	}
+	runTime, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		http.Error(res, fmt.Sprintf("400 invalid run \"%s\", RFC3339 time expected", value), http.StatusBadRequest)
+		return reportRun{}, false
+	}
+	if browser.history == nil {
+		http.Error(res, "404 run history not configured", http.StatusNotFound)
+		return reportRun{}, false
+	}
+	runTime = runTime.UTC()
+
+	browser.mu.Lock()
+	defer browser.mu.Unlock()
+	for _, loaded := range browser.loaded {
+		if loaded.runTime.Equal(runTime) {
+			return loaded, true
+		}
+	}
+	reports, sitesData, found, err := browser.history.Run(runTime)
+	if err != nil {
+		http.Error(res, "500 "+err.Error(), http.StatusInternalServerError)
+		return reportRun{}, false
+	}
+	if !found {
+		http.Error(res, fmt.Sprintf("404 no run stored at %s", runTime.Format(time.RFC3339)), http.StatusNotFound)
+		return reportRun{}, false
+	}
+	if sitesData == nil {
+		sitesData = browser.current.sitesData
+	}
+	loaded := reportRun{sitesData: sitesData, reports: reports, runTime: runTime, query: "run=" + url.QueryEscape(runTime.Format(time.RFC3339))}
+	browser.loaded = append([]reportRun{loaded}, browser.loaded...)
+	if len(browser.loaded) > maxLoadedRuns {
+		browser.loaded = browser.loaded[:maxLoadedRuns]
+	}
+	return loaded, true
+}
+
+//writeRuns writes the navigation between the current run and the latest stored ones, the shown run being marked, nothing being written without a history
+func (browser *runBrowser) writeRuns(res http.ResponseWriter, shown reportRun) {
+	if browser.history == nil {
+		return
+	}
+	runTimes, err := browser.history.Runs()
+	if err != nil {
+		res.Write([]byte(fmt.Sprintf("<p>Stored runs not listed - %s</p>\n", err.Error())))
+		return
+	}
+
+	item := func(link, title string, selected bool) string {
+		if selected {
+			return fmt.Sprintf("<li><b>%s</b></li>\n", title)
+		}
+		return fmt.Sprintf("<li><a href=\"%s\">%s</a></li>\n", link, title)
+	}
+	res.Write([]byte("<h3>Runs</h3>\n"))
+	res.Write([]byte("<ul>\n"))
+	res.Write([]byte(item("/report", "Current run ("+browser.current.runTime.Format(time.RFC3339)+")", shown.query == "")))
+	for i, runTime := range runTimes {
+		if i == maxListedRuns {
+			break
+		}
+		if runTime.Equal(browser.current.runTime) {
+			continue
+		}
+		res.Write([]byte(item("/report?run="+url.QueryEscape(runTime.Format(time.RFC3339)), runTime.Format(time.RFC3339), shown.query != "" && runTime.Equal(shown.runTime))))
+	}
+	res.Write([]byte("</ul>\n"))
+}
+
+//writeEvents writes a list item for each of the given events of a severity, with its metric, attribute and period
+func writeEvents(res http.ResponseWriter, severity string, events []analyser.OutlierEvent) {
+	for _, event := range events {
+		res.Write([]byte(fmt.Sprintf("<li>%s - %s - %s - %s to %s</li>\n", severity, html.EscapeString(event.Metric), html.EscapeString(event.Attribute), event.OutlierPeriodStart.Format(time.RFC3339), event.OutlierPeriodEnd.Format(time.RFC3339))))
+	}
+}
+
+//withQuery appends the given query strings to a link, skipping the empty ones
+func withQuery(link string, queries ...string) string {
+	separator := "?"
+	for _, query := range queries {
+		if query == "" {
+			continue
+		}
+		link += separator + query
+		separator = "&"
+	}
+	return link
+}
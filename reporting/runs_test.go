@@ -0,0 +1,87 @@
+package reporting
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ftfmtavares/anomalies-detector/analyser"
+	"github.com/ftfmtavares/anomalies-detector/config"
+)
+
+func TestReportRuns(t *testing.T) {
+	sitesData, reports := reportFixture()
+	history, err := analyser.NewRunHistory(config.StorageParams{Dir: t.TempDir(), RunRetention: "100000d", RunSnapshots: true})
+	if err != nil {
+		t.Fatalf("NewRunHistory() error = %v", err)
+	}
+
+	//The run of the day before is stored with its data, keeping only the blog site, while the one of two days before keeps its reports only
+	yesterday := time.Date(2022, 8, 31, 0, 0, 0, 0, time.UTC)
+	if err := history.SaveRun(yesterday, reports[len(reports)-1:]); err != nil {
+		t.Fatalf("SaveRun() error = %v", err)
+	}
+	if err := history.SaveRunData(yesterday, sitesData[len(sitesData)-1:]); err != nil {
+		t.Fatalf("SaveRunData() error = %v", err)
+	}
+	if err := history.SaveRun(yesterday.AddDate(0, 0, -1), []analyser.OutlierReport{}); err != nil {
+		t.Fatalf("SaveRun() error = %v", err)
+	}
+	handler := newReportRouter(sitesData, reports, config.ReportServerParams{RateLimit: config.RateLimitParams{Burst: 100}}, DetectionSettings{History: history})
+
+	tests := []struct {
+		name        string
+		url         string
+		wantStatus  int
+		wantContain []string
+		wantMissing []string
+	}{
+		{
+			name:        "Current run",
+			url:         "/report",
+			wantStatus:  http.StatusOK,
+			wantContain: []string{"<li><b>Current run (", `<a href="/report?run=2022-08-31T00%3A00%3A00Z">2022-08-31T00:00:00Z</a>`, `<a href="/report?run=2022-08-30T00%3A00%3A00Z">`, `href="/report/shop/Revenue?resolution=1d"`},
+		},
+		{
+			name:        "Stored run with its data",
+			url:         "/report?run=2022-08-31T00:00:00Z",
+			wantStatus:  http.StatusOK,
+			wantContain: []string{`<li><a href="/report">Current run (`, "<li><b>2022-08-31T00:00:00Z</b></li>", `href="/report/blog/Visits?run=2022-08-31T00%3A00%3A00Z"`, `href="/report/blog/Visits?attribute=total&run=2022-08-31T00%3A00%3A00Z"`},
+			wantMissing: []string{"<h2>shop"},
+		},
+		{
+			name:        "Stored run without its data",
+			url:         "/report?run=2022-08-30T00:00:00Z",
+			wantStatus:  http.StatusOK,
+			wantContain: []string{"<h2>shop (1d)</h2>", `href="/report/shop/Revenue?resolution=1h&run=2022-08-30T00%3A00%3A00Z"`},
+			wantMissing: []string{"<h3>Events</h3>"},
+		},
+		{name: "Chart of a stored run", url: "/report/blog/Visits?run=2022-08-31T00:00:00Z", wantStatus: http.StatusOK},
+		{name: "Chart missing from a stored run", url: "/report/shop/Revenue?run=2022-08-31T00:00:00Z", wantStatus: http.StatusNotFound},
+		{name: "Treemap of a stored run without its data", url: "/report/shop/Revenue/treemap?run=2022-08-30T00:00:00Z", wantStatus: http.StatusOK},
+		{name: "Unknown run", url: "/report?run=2022-08-01T00:00:00Z", wantStatus: http.StatusNotFound},
+		{name: "Invalid run", url: "/report?run=yesterday", wantStatus: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := httptest.NewRecorder()
+			handler.ServeHTTP(res, httptest.NewRequest(http.MethodGet, tt.url, nil))
+			if res.Code != tt.wantStatus {
+				t.Fatalf("GET %s status = %d, want %d", tt.url, res.Code, tt.wantStatus)
+			}
+			body := res.Body.String()
+			for _, want := range tt.wantContain {
+				if !strings.Contains(body, want) {
+					t.Errorf("GET %s body doesn't contain %q\n%s", tt.url, want, body)
+				}
+			}
+			for _, missing := range tt.wantMissing {
+				if strings.Contains(body, missing) {
+					t.Errorf("GET %s body contains %q\n%s", tt.url, missing, body)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,88 @@
+package reporting
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//Const block defines the signature header and its verification defaults
+//The header has the format "t=<unix timestamp>,v1=<hex HMAC-SHA256 of "<timestamp>.<body>">" so that replayed payloads can be rejected
+const (
+	SignatureHeader           = "X-Anomalies-Signature"
+	defaultSignatureTolerance = 5 * time.Minute
+)
+
+//SignPayload computes the signature header value of a payload sent at the given time
+func SignPayload(secret string, timestamp time.Time, body []byte) string {
+	ts := strconv.FormatInt(timestamp.Unix(), 10)
+	return fmt.Sprintf("t=%s,v1=%s", ts, computeSignature(secret, ts, body))
+}
+
+//VerifySignature checks a signature header value against the payload and the shared secret
+//It returns an error if the header is malformed, if the timestamp is outside the tolerance or if no signature matches
+func VerifySignature(secret, header string, body []byte, now time.Time, tolerance time.Duration) error {
+	if header == "" {
+		return errors.New("missing signature")
+	}
+
+	ts := ""
+	signatures := []string{}
+	for _, part := range strings.Split(header, ",") {
+		key, value, found := strings.Cut(strings.TrimSpace(part), "=")
+		if !found {
+			return errors.New("malformed signature")
+		}
+		switch key {
+		case "t":
+			ts = value
+		case "v1":
+			signatures = append(signatures, value)
+		}
+	}
+	if ts == "" || len(signatures) == 0 {
+		return errors.New("malformed signature")
+	}
+
+	unix, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return errors.New("malformed signature timestamp")
+	}
+	if tolerance <= 0 {
+		tolerance = defaultSignatureTolerance
+	}
+	if diff := now.Sub(time.Unix(unix, 0)); diff > tolerance || diff < -tolerance {
+		return errors.New("signature timestamp outside tolerance")
+	}
+
+	//Several v1 values are accepted so that secrets can be rotated without downtime
+	expected := computeSignature(secret, ts, body)
+	for _, signature := range signatures {
+		if hmac.Equal([]byte(signature), []byte(expected)) {
+			return nil
+		}
+	}
+	return errors.New("signature mismatch")
+}
+
+//signRequest adds the signature header to an outgoing request if a secret is configured
+func signRequest(req *http.Request, secret string, body []byte) {
+	if secret != "" {
+		req.Header.Set(SignatureHeader, SignPayload(secret, Clock.Now(), body))
+	}
+}
+
+//computeSignature returns the hex encoded HMAC-SHA256 of the timestamp and body
+func computeSignature(secret, ts string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(ts))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,36 @@
+package reporting
+
+import (
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestVerifySignature(t *testing.T) {
+	timeRef := time.Now()
+	body := []byte(`{"text":"alarm"}`)
+	ts := strconv.FormatInt(timeRef.Unix(), 10)
+
+	tests := []struct {
+		name    string
+		secret  string
+		header  string
+		body    []byte
+		wantErr bool
+	}{
+		{name: "Valid signature", secret: "secret", header: SignPayload("secret", timeRef, body), body: body},
+		{name: "Rotated secret among several signatures", secret: "new", header: "t=" + ts + ",v1=" + computeSignature("old", ts, body) + ",v1=" + computeSignature("new", ts, body), body: body},
+		{name: "Tampered body", secret: "secret", header: SignPayload("secret", timeRef, body), body: []byte(`{"text":"warning"}`), wantErr: true},
+		{name: "Wrong secret", secret: "other", header: SignPayload("secret", timeRef, body), body: body, wantErr: true},
+		{name: "Expired timestamp", secret: "secret", header: SignPayload("secret", timeRef.Add(-time.Hour), body), body: body, wantErr: true},
+		{name: "Malformed header", secret: "secret", header: "v1", body: body, wantErr: true},
+		{name: "Missing header", secret: "secret", header: "", body: body, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := VerifySignature(tt.secret, tt.header, tt.body, timeRef, 0); (err != nil) != tt.wantErr {
+				t.Errorf("VerifySignature() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,25 @@
+package reporting
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestSignAwsRequest(t *testing.T) {
+
+	//Reference values from the "get-vanilla" case of the AWS Signature Version 4 test suite
+	credentials := awsCredentials{AccessKeyId: "AKIDEXAMPLE", SecretAccessKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY"}
+	now := time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC)
+
+	req, _ := http.NewRequest(http.MethodGet, "https://example.amazonaws.com/", nil)
+	signAwsRequest(req, []byte{}, credentials, "us-east-1", "service", now)
+
+	want := "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, SignedHeaders=host;x-amz-date, Signature=5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31"
+	if got := req.Header.Get("Authorization"); got != want {
+		t.Errorf("signAwsRequest() Authorization = %s, want %s", got, want)
+	}
+	if got := req.Header.Get("X-Amz-Date"); got != "20150830T123600Z" {
+		t.Errorf("signAwsRequest() X-Amz-Date = %s", got)
+	}
+}
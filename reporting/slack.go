@@ -0,0 +1,384 @@
+package reporting
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/ftfmtavares/anomalies-detector/analyser"
+	"github.com/ftfmtavares/anomalies-detector/config"
+	"github.com/ftfmtavares/anomalies-detector/utils"
+)
+
+//Const block defines the Slack notifier defaults
+const (
+	slackPostMessageUrl           = "https://slack.com/api/chat.postMessage"
+	defaultSlackTemplate          = `{{if eq .Severity "alarm"}}:rotating_light:{{else}}:warning:{{end}} *{{.Severity}}* on *{{.SiteId}}* - {{.Metric}} / {{.Attribute}} from {{.PeriodStart.Format "2006-01-02 15:04"}} to {{.PeriodEnd.Format "2006-01-02 15:04"}}`
+	defaultSlackMessagesPerMin    = 20.0
+	defaultSlackMaxMessagesPerRun = 50
+	defaultSlackBatchThreshold    = 20
+	slackMaxRetries               = 3
+	slackAlarmColor               = "#d00000"
+	slackWarningColor             = "#e8a000"
+)
+
+//SlackNotifier posts detected warnings and alarms to a Slack channel, either through an incoming webhook or a bot token
+type SlackNotifier struct {
+	webhookUrl  string
+	botToken    string
+	channel     string
+	secret      string
+	apiUrl      string
+	template    *template.Template
+	severities  map[string]bool
+	routes      []string
+	minInterval time.Duration
+	maxMessages int
+	batch       bool
+	batchLimit  int
+	incidents   bool
+	dashboard   string
+	client      *http.Client
+	lastSent    time.Time
+	sleep       func(time.Duration)
+}
+
+//NotificationEvent provides the structure passed to notification message templates
+//Score, Observed, Expected, DeviationPercent and Direction fields rank the event as detected, Explanation field describing it if explained by a post-processor (see analyser.OutlierEvent)
+//ProbableCauses field lists the child attribute paths that contributed most to alarms of "Total"
+type NotificationEvent struct {
+	SiteId           string
+	Severity         string
+	Metric           string
+	Attribute        string
+	Resolution       string
+	PeriodStart      time.Time
+	PeriodEnd        time.Time
+	Routes           []string
+	Score            float64
+	Observed         float64
+	Expected         float64
+	DeviationPercent float64
+	Direction        string
+	Explanation      string
+	ProbableCauses   []analyser.ProbableCause
+}
+
+//NewSlackNotifier creates a SlackNotifier from the given parameters
+//It returns an error if no destination is configured or if the message template is invalid
+func NewSlackNotifier(params config.SlackParams) (*SlackNotifier, error) {
+	notifier := &SlackNotifier{
+		webhookUrl:  utils.ResolveSecret(params.WebhookUrl),
+		botToken:    utils.ResolveSecret(params.BotToken),
+		channel:     params.Channel,
+		secret:      utils.ResolveSecret(params.SigningSecret),
+		apiUrl:      slackPostMessageUrl,
+		severities:  map[string]bool{},
+		routes:      params.Routes,
+		maxMessages: params.MaxMessagesPerRun,
+		batch:       params.Batch,
+		batchLimit:  params.BatchSummaryThreshold,
+		incidents:   params.Incidents,
+		dashboard:   params.DashboardUrl,
+		client:      utils.NewHttpClient(10 * time.Second),
+		sleep:       time.Sleep,
+	}
+
+	if notifier.webhookUrl == "" && (notifier.botToken == "" || notifier.channel == "") {
+		return nil, errors.New("slack notifier requires a webhookUrl or a botToken and channel")
+	}
+
+	text := params.Template
+	if text == "" {
+		text = defaultSlackTemplate
+	}
+	tmpl, err := template.New("slack").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("slack notifier template - %s", err.Error())
+	}
+	notifier.template = tmpl
+
+	if len(params.Severities) == 0 {
+		params.Severities = []string{"warning", "alarm"}
+	}
+	for _, severity := range params.Severities {
+		notifier.severities[severity] = true
+	}
+
+	messagesPerMinute := params.MessagesPerMinute
+	if messagesPerMinute <= 0 {
+		messagesPerMinute = defaultSlackMessagesPerMin
+	}
+	notifier.minInterval = time.Duration(float64(time.Minute) / messagesPerMinute)
+	if notifier.maxMessages <= 0 {
+		notifier.maxMessages = defaultSlackMaxMessagesPerRun
+	}
+	if notifier.batchLimit <= 0 {
+		notifier.batchLimit = defaultSlackBatchThreshold
+	}
+
+	return notifier, nil
+}
+
+//Notify posts the warnings and alarms of a report to Slack, alarms first
+//Messages are spaced according to the configured rate and, once the per run cap is reached, a single summary of the remaining events is sent instead
+//Batching notifiers post all of them in a single message instead, while incident notifiers post a message per incident of reports with grouped incidents
+func (notifier *SlackNotifier) Notify(report analyser.OutlierReport) error {
+	events := notificationEvents(report, notifier.severities, notifier.routes)
+	if len(events) == 0 {
+		return nil
+	}
+	if notifier.incidents && report.Incidents != nil {
+		return notifier.notifyIncidents(report)
+	}
+	if notifier.batch {
+		return notifier.notifyBatch(report, events)
+	}
+
+	sent := 0
+	for _, event := range events {
+		if sent == notifier.maxMessages-1 && len(events) > notifier.maxMessages {
+			break
+		}
+		var text bytes.Buffer
+		if err := notifier.template.Execute(&text, event); err != nil {
+			return fmt.Errorf("slack notifier template - %s", err.Error())
+		}
+		if err := notifier.post(text.String(), nil); err != nil {
+			return err
+		}
+		sent++
+	}
+
+	if remaining := len(events) - sent; remaining > 0 {
+		log.Printf("Slack notifier capped - %d events of %s not sent individually\n", remaining, report.SiteId)
+		return notifier.post(fmt.Sprintf("... and %d more events on *%s*, check the report for details", remaining, report.SiteId), nil)
+	}
+
+	return nil
+}
+
+//slackAttachment holds the details of a batched message, which Slack collapses behind "Show more" once they get long
+type slackAttachment struct {
+	Color string `json:"color"`
+	Text  string `json:"text"`
+}
+
+//notifyBatch posts the events of a report as a single message, its counts as text and each event on a line of an attachment following the template
+//Above the batch summary threshold only the counts are sent, pointing to the dashboard, so a large incident doesn't flood the channel
+func (notifier *SlackNotifier) notifyBatch(report analyser.OutlierReport, events []NotificationEvent) error {
+	alarms := 0
+	for _, event := range events {
+		if event.Severity == "alarm" {
+			alarms++
+		}
+	}
+	icon, color := ":warning:", slackWarningColor
+	if alarms > 0 {
+		icon, color = ":rotating_light:", slackAlarmColor
+	}
+	text := fmt.Sprintf("%s *%s*", icon, report.SiteId)
+	if report.TimeStep != "" {
+		text += fmt.Sprintf(" (%s)", report.TimeStep)
+	}
+	text += fmt.Sprintf(" - alarms: *%d*, warnings: *%d*", alarms, len(events)-alarms)
+
+	if len(events) > notifier.batchLimit {
+		log.Printf("Slack notifier summarized - %d events of %s not detailed\n", len(events), report.SiteId)
+		if notifier.dashboard != "" {
+			return notifier.post(fmt.Sprintf("%s, see <%s|the dashboard>", text, notifier.dashboard), nil)
+		}
+		return notifier.post(text+", see the dashboard", nil)
+	}
+
+	var details bytes.Buffer
+	for i, event := range events {
+		if i > 0 {
+			details.WriteString("\n")
+		}
+		if err := notifier.template.Execute(&details, event); err != nil {
+			return fmt.Errorf("slack notifier template - %s", err.Error())
+		}
+	}
+	return notifier.post(text, []slackAttachment{{Color: color, Text: details.String()}})
+}
+
+//notifyIncidents posts a message per incident of a report, its severity, events count, metrics and period as text and each event on a line of an attachment following the template
+//Incidents left without events by the severities and routes filters aren't notified and, once the per run cap is reached, a single summary of the remaining incidents is sent instead
+func (notifier *SlackNotifier) notifyIncidents(report analyser.OutlierReport) error {
+	incidents := [][]NotificationEvent{}
+	for _, incident := range report.Incidents {
+		members := report
+		members.Result = incident.Events
+		if events := notificationEvents(members, notifier.severities, notifier.routes); len(events) > 0 {
+			incidents = append(incidents, events)
+		}
+	}
+
+	sent := 0
+	for _, events := range incidents {
+		if sent == notifier.maxMessages-1 && len(incidents) > notifier.maxMessages {
+			break
+		}
+		icon, color, severity := ":warning:", slackWarningColor, "warning"
+		if events[0].Severity == "alarm" {
+			icon, color, severity = ":rotating_light:", slackAlarmColor, "alarm"
+		}
+		start, end, metrics := events[0].PeriodStart, events[0].PeriodEnd, []string{}
+		var details bytes.Buffer
+		for i, event := range events {
+			if event.PeriodStart.Before(start) {
+				start = event.PeriodStart
+			}
+			if event.PeriodEnd.After(end) {
+				end = event.PeriodEnd
+			}
+			if !containsString(metrics, event.Metric) {
+				metrics = append(metrics, event.Metric)
+			}
+			if i > 0 {
+				details.WriteString("\n")
+			}
+			if err := notifier.template.Execute(&details, event); err != nil {
+				return fmt.Errorf("slack notifier template - %s", err.Error())
+			}
+		}
+		text := fmt.Sprintf("%s *%s incident* on *%s*", icon, severity, report.SiteId)
+		if report.TimeStep != "" {
+			text += fmt.Sprintf(" (%s)", report.TimeStep)
+		}
+		text += fmt.Sprintf(" - %d events on %s from %s to %s", len(events), strings.Join(metrics, ", "), start.Format("2006-01-02 15:04"), end.Format("2006-01-02 15:04"))
+		if err := notifier.post(text, []slackAttachment{{Color: color, Text: details.String()}}); err != nil {
+			return err
+		}
+		sent++
+	}
+
+	if remaining := len(incidents) - sent; remaining > 0 {
+		log.Printf("Slack notifier capped - %d incidents of %s not sent individually\n", remaining, report.SiteId)
+		return notifier.post(fmt.Sprintf("... and %d more incidents on *%s*, check the report for details", remaining, report.SiteId), nil)
+	}
+
+	return nil
+}
+
+//post sends a single message, with optional attachments, respecting the minimum interval between messages
+//Slack rate limiting responses are retried after the requested delay
+func (notifier *SlackNotifier) post(text string, attachments []slackAttachment) error {
+	if wait := notifier.minInterval - Clock.Now().Sub(notifier.lastSent); wait > 0 {
+		notifier.sleep(wait)
+	}
+
+	url := notifier.webhookUrl
+	payload := map[string]interface{}{"text": text}
+	if len(attachments) > 0 {
+		payload["attachments"] = attachments
+	}
+	if url == "" {
+		url = notifier.apiUrl
+		payload["channel"] = notifier.channel
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	for attempt := 1; ; attempt++ {
+		req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "application/json; charset=utf-8")
+		if notifier.webhookUrl == "" {
+			req.Header.Set("Authorization", "Bearer "+notifier.botToken)
+		}
+		signRequest(req, notifier.secret, body)
+
+		resp, err := notifier.client.Do(req)
+		if err != nil {
+			return fmt.Errorf("slack notifier - %s", err.Error())
+		}
+		notifier.lastSent = Clock.Now()
+
+		if resp.StatusCode == http.StatusTooManyRequests && attempt < slackMaxRetries {
+			resp.Body.Close()
+			retryAfter, _ := strconv.Atoi(resp.Header.Get("Retry-After"))
+			notifier.sleep(time.Duration(retryAfter+1) * time.Second)
+			continue
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("slack notifier - unexpected status %s", resp.Status)
+		}
+
+		//The Web API always answers with 200 and reports errors in the body
+		if notifier.webhookUrl == "" {
+			var apiResp struct {
+				Ok    bool   `json:"ok"`
+				Error string `json:"error"`
+			}
+			if err := json.NewDecoder(resp.Body).Decode(&apiResp); err != nil {
+				return fmt.Errorf("slack notifier - %s", err.Error())
+			}
+			if !apiResp.Ok {
+				return fmt.Errorf("slack notifier - %s", apiResp.Error)
+			}
+		}
+		return nil
+	}
+}
+
+//notificationEvents flattens the alarms and warnings of a report into NotificationEvent, alarms first
+//Only events with the given severities are kept and, when routes are given, only events carrying one of them
+func notificationEvents(report analyser.OutlierReport, severities map[string]bool, routes []string) []NotificationEvent {
+	events := []NotificationEvent{}
+	add := func(outlierEvents []analyser.OutlierEvent, severity string) {
+		if !severities[severity] {
+			return
+		}
+		for _, event := range outlierEvents {
+			if len(routes) > 0 && !hasRoute(event.Routes, routes) {
+				continue
+			}
+			events = append(events, NotificationEvent{
+				SiteId:           report.SiteId,
+				Severity:         severity,
+				Metric:           event.Metric,
+				Attribute:        event.Attribute,
+				Resolution:       event.Resolution,
+				PeriodStart:      event.OutlierPeriodStart,
+				PeriodEnd:        event.OutlierPeriodEnd,
+				Routes:           event.Routes,
+				Score:            event.Score,
+				Observed:         event.Observed,
+				Expected:         event.Expected,
+				DeviationPercent: event.DeviationPercent,
+				Direction:        event.Direction,
+				Explanation:      event.Explanation,
+				ProbableCauses:   event.ProbableCauses,
+			})
+		}
+	}
+	add(report.Result.Alarms, "alarm")
+	add(report.Result.Warnings, "warning")
+
+	return events
+}
+
+//hasRoute checks if any of the event routes is present on the given routes list
+func hasRoute(eventRoutes, routes []string) bool {
+	for _, route := range eventRoutes {
+		if containsString(routes, route) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,200 @@
+package reporting
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ftfmtavares/anomalies-detector/analyser"
+	"github.com/ftfmtavares/anomalies-detector/config"
+	"github.com/ftfmtavares/anomalies-detector/utils"
+)
+
+func TestSlackNotifier_Notify(t *testing.T) {
+	timeRef := time.Date(2022, 9, 1, 0, 0, 0, 0, time.UTC)
+
+	report := analyser.OutlierReport{
+		SiteId:   "site",
+		TimeStep: "1d",
+		Result: analyser.OutlierResults{
+			Warnings: []analyser.OutlierEvent{
+				{OutlierPeriodStart: timeRef, OutlierPeriodEnd: timeRef.AddDate(0, 0, 1), Metric: "Basket", Attribute: "Total"},
+				{OutlierPeriodStart: timeRef, OutlierPeriodEnd: timeRef.AddDate(0, 0, 1), Metric: "Basket", Attribute: "Browser>Edge"},
+			},
+			Alarms: []analyser.OutlierEvent{
+				{OutlierPeriodStart: timeRef, OutlierPeriodEnd: timeRef.AddDate(0, 0, 1), Metric: "Revenue", Attribute: "Total", Routes: []string{"revenue"}},
+			},
+		},
+	}
+
+	tests := []struct {
+		name   string
+		params config.SlackParams
+		want   []string
+	}{
+		{
+			name:   "Templated messages with alarms first",
+			params: config.SlackParams{Template: "{{.Severity}} {{.Metric}} {{.Attribute}} {{.PeriodStart.Format \"2006-01-02\"}}"},
+			want:   []string{"alarm Revenue Total 2022-09-01", "warning Basket Total 2022-09-01", "warning Basket Browser>Edge 2022-09-01"},
+		},
+		{
+			name:   "Filtered by severity and route",
+			params: config.SlackParams{Template: "{{.Metric}}", Severities: []string{"alarm"}, Routes: []string{"revenue"}},
+			want:   []string{"Revenue"},
+		},
+		{
+			name:   "Capped with summary message",
+			params: config.SlackParams{Template: "{{.Metric}}", MaxMessagesPerRun: 2},
+			want:   []string{"Revenue", "... and 2 more events on *site*, check the report for details"},
+		},
+		{
+			name:   "Batched in a single message with the events as details",
+			params: config.SlackParams{Template: "{{.Severity}} {{.Attribute}}", Batch: true, MaxMessagesPerRun: 1},
+			want:   []string{":rotating_light: *site* (1d) - alarms: *1*, warnings: *2* | #d00000 | alarm Total\nwarning Total\nwarning Browser>Edge"},
+		},
+		{
+			name:   "Batched warnings only",
+			params: config.SlackParams{Template: "{{.Attribute}}", Batch: true, Severities: []string{"warning"}},
+			want:   []string{":warning: *site* (1d) - alarms: *0*, warnings: *2* | #e8a000 | Total\nBrowser>Edge"},
+		},
+		{
+			name:   "Incidents without grouped incidents on the report",
+			params: config.SlackParams{Template: "{{.Metric}}", Severities: []string{"alarm"}, Incidents: true},
+			want:   []string{"Revenue"},
+		},
+		{
+			name:   "Batch above the threshold summarized",
+			params: config.SlackParams{Batch: true, BatchSummaryThreshold: 2, DashboardUrl: "https://reports.example.com/dashboard/"},
+			want:   []string{":rotating_light: *site* (1d) - alarms: *1*, warnings: *2*, see <https://reports.example.com/dashboard/|the dashboard>"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := []string{}
+			server := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+				var payload struct {
+					Text        string            `json:"text"`
+					Attachments []slackAttachment `json:"attachments"`
+				}
+				json.NewDecoder(req.Body).Decode(&payload)
+				for _, attachment := range payload.Attachments {
+					payload.Text += " | " + attachment.Color + " | " + attachment.Text
+				}
+				got = append(got, payload.Text)
+			}))
+			defer server.Close()
+
+			tt.params.WebhookUrl = server.URL
+			notifier, err := NewSlackNotifier(tt.params)
+			if err != nil {
+				t.Fatalf("NewSlackNotifier() error = %v", err)
+			}
+			notifier.sleep = func(time.Duration) {}
+
+			if err := notifier.Notify(report); err != nil {
+				t.Fatalf("Notify() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Notify() sent %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSlackNotifier_NotifyIncidents(t *testing.T) {
+	timeRef := time.Date(2022, 9, 1, 10, 0, 0, 0, time.UTC)
+	results := analyser.OutlierResults{
+		Alarms: []analyser.OutlierEvent{
+			{OutlierPeriodStart: timeRef, OutlierPeriodEnd: timeRef.Add(2 * time.Hour), Metric: "Revenue", Attribute: "Total"},
+			{OutlierPeriodStart: timeRef.Add(time.Hour), OutlierPeriodEnd: timeRef.Add(3 * time.Hour), Metric: "Visits", Attribute: "Total"},
+		},
+		Warnings: []analyser.OutlierEvent{
+			{OutlierPeriodStart: timeRef, OutlierPeriodEnd: timeRef.Add(time.Hour), Metric: "Revenue", Attribute: "Browser>Edge"},
+			{OutlierPeriodStart: timeRef.Add(5 * time.Hour), OutlierPeriodEnd: timeRef.Add(6 * time.Hour), Metric: "Basket", Attribute: "Total"},
+		},
+	}
+	report := analyser.OutlierReport{SiteId: "site", TimeStep: "1h", Result: results, Incidents: analyser.GroupIncidents(results, 0)}
+
+	tests := []struct {
+		name   string
+		params config.SlackParams
+		want   []string
+	}{
+		{
+			name:   "A message per incident with its events as details",
+			params: config.SlackParams{Template: "{{.Severity}} {{.Metric}} {{.Attribute}}", Incidents: true},
+			want: []string{
+				":rotating_light: *alarm incident* on *site* (1h) - 3 events on Revenue, Visits from 2022-09-01 10:00 to 2022-09-01 13:00 | #d00000 | alarm Revenue Total\nalarm Visits Total\nwarning Revenue Browser>Edge",
+				":warning: *warning incident* on *site* (1h) - 1 events on Basket from 2022-09-01 15:00 to 2022-09-01 16:00 | #e8a000 | warning Basket Total",
+			},
+		},
+		{
+			name:   "Incidents without events left by the filters",
+			params: config.SlackParams{Template: "{{.Attribute}}", Severities: []string{"alarm"}, Incidents: true},
+			want:   []string{":rotating_light: *alarm incident* on *site* (1h) - 2 events on Revenue, Visits from 2022-09-01 10:00 to 2022-09-01 13:00 | #d00000 | Total\nTotal"},
+		},
+		{
+			name:   "Capped with summary message",
+			params: config.SlackParams{Template: "{{.Attribute}}", Incidents: true, MaxMessagesPerRun: 1},
+			want:   []string{"... and 2 more incidents on *site*, check the report for details"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := []string{}
+			server := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+				var payload struct {
+					Text        string            `json:"text"`
+					Attachments []slackAttachment `json:"attachments"`
+				}
+				json.NewDecoder(req.Body).Decode(&payload)
+				for _, attachment := range payload.Attachments {
+					payload.Text += " | " + attachment.Color + " | " + attachment.Text
+				}
+				got = append(got, payload.Text)
+			}))
+			defer server.Close()
+
+			tt.params.WebhookUrl = server.URL
+			notifier, err := NewSlackNotifier(tt.params)
+			if err != nil {
+				t.Fatalf("NewSlackNotifier() error = %v", err)
+			}
+			notifier.sleep = func(time.Duration) {}
+
+			if err := notifier.Notify(report); err != nil {
+				t.Fatalf("Notify() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Notify() sent %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSlackNotifier_post(t *testing.T) {
+	defer func(clock utils.Clock) { Clock = clock }(Clock)
+	Clock = utils.FixedClock(time.Date(2022, 9, 1, 10, 0, 0, 0, time.UTC))
+	server := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {}))
+	defer server.Close()
+
+	notifier, err := NewSlackNotifier(config.SlackParams{WebhookUrl: server.URL, MessagesPerMinute: 30})
+	if err != nil {
+		t.Fatalf("NewSlackNotifier() error = %v", err)
+	}
+	slept := []time.Duration{}
+	notifier.sleep = func(wait time.Duration) { slept = append(slept, wait) }
+
+	//Messages are paced on the notifier clock, the second one waiting the whole interval as no time went by
+	for _, text := range []string{"first", "second"} {
+		if err := notifier.post(text, nil); err != nil {
+			t.Fatalf("post() error = %v", err)
+		}
+	}
+	if len(slept) != 1 || slept[0] != 2*time.Second {
+		t.Errorf("post() slept %v, want a single 2s wait", slept)
+	}
+}
@@ -0,0 +1,283 @@
+package reporting
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/ftfmtavares/anomalies-detector/analyser"
+	"github.com/ftfmtavares/anomalies-detector/config"
+	"github.com/ftfmtavares/anomalies-detector/utils"
+
+	"github.com/gorilla/mux"
+)
+
+//SubscriptionPublisher delivers the events matching a subscription to its own channel
+type SubscriptionPublisher struct {
+	subscription config.Subscription
+	send         func(report analyser.OutlierReport) error
+}
+
+//NewSubscriptionPublisher creates a SubscriptionPublisher for the given subscription
+//The channel takes the remaining parameters (template, rate limits, signing secrets...) from the matching notifier configuration, if present,
+//except for the signing secrets of the subscriptions created through the API, so their targets can't collect payloads signed as the configured ones
+//It returns an error if the subscription is invalid or if its channel can't be created
+func NewSubscriptionPublisher(subscription config.Subscription, notifiers config.NotifiersParams) (*SubscriptionPublisher, error) {
+	if err := validateSubscription(subscription); err != nil {
+		return nil, err
+	}
+	publisher := &SubscriptionPublisher{subscription: subscription}
+
+	switch subscription.Channel {
+	case "slack":
+		params := config.SlackParams{}
+		if notifiers.Slack != nil {
+			params = *notifiers.Slack
+		}
+		if isUrl(subscription.Target) {
+			params.WebhookUrl = subscription.Target
+		} else {
+			params.WebhookUrl = ""
+			params.Channel = subscription.Target
+		}
+		params.Severities = subscription.Severities
+		params.Routes = nil
+		if subscription.Id != "" {
+			params.SigningSecret = ""
+		}
+		notifier, err := NewSlackNotifier(params)
+		if err != nil {
+			return nil, fmt.Errorf("subscription %s - %s", subscription.Name, err.Error())
+		}
+		publisher.send = notifier.Notify
+	case "cloudEvents":
+		params := config.CloudEventsParams{}
+		if notifiers.CloudEvents != nil {
+			params = *notifiers.CloudEvents
+		}
+		params.SinkUrl = subscription.Target
+		params.Severities = subscription.Severities
+		params.Routes = nil
+		if subscription.Id != "" {
+			params.SigningSecret = ""
+		}
+		cloudEventsPublisher, err := NewCloudEventsPublisher(params)
+		if err != nil {
+			return nil, fmt.Errorf("subscription %s - %s", subscription.Name, err.Error())
+		}
+		publisher.send = cloudEventsPublisher.Publish
+	}
+
+	return publisher, nil
+}
+
+//Publish delivers the warnings and alarms of a report matching the subscription filters, nothing being sent if none matches
+func (publisher *SubscriptionPublisher) Publish(report analyser.OutlierReport) error {
+	filtered := subscriptionReport(report, publisher.subscription)
+	if len(filtered.Result.Alarms) == 0 && len(filtered.Result.Warnings) == 0 {
+		return nil
+	}
+	if err := publisher.send(filtered); err != nil {
+		return fmt.Errorf("subscription %s - %s", publisher.subscription.Name, err.Error())
+	}
+	return nil
+}
+
+//validateSubscription checks if a subscription has a name, a supported channel with its target and valid severities
+func validateSubscription(subscription config.Subscription) error {
+	if subscription.Name == "" {
+		return errors.New("subscription requires a name")
+	}
+	if subscription.Channel != "slack" && subscription.Channel != "cloudEvents" {
+		return fmt.Errorf("subscription %s - invalid channel \"%s\", it must be \"slack\" or \"cloudEvents\"", subscription.Name, subscription.Channel)
+	}
+	if subscription.Target == "" {
+		return fmt.Errorf("subscription %s - missing target", subscription.Name)
+	}
+	if subscription.Channel == "cloudEvents" && !isUrl(subscription.Target) {
+		return fmt.Errorf("subscription %s - cloudEvents target must be an http(s) url", subscription.Name)
+	}
+	for _, severity := range subscription.Severities {
+		if severity != "warning" && severity != "alarm" {
+			return fmt.Errorf("subscription %s - invalid severity \"%s\", it must be \"warning\" or \"alarm\"", subscription.Name, severity)
+		}
+	}
+	return nil
+}
+
+//subscriptionReport returns a copy of the report keeping only the warnings and alarms matching the subscription filters
+func subscriptionReport(report analyser.OutlierReport, subscription config.Subscription) analyser.OutlierReport {
+	if subscription.SiteId != "" && report.SiteId != subscription.SiteId {
+		report.Result = analyser.OutlierResults{}
+		return report
+	}
+
+	filter := func(events []analyser.OutlierEvent) []analyser.OutlierEvent {
+		res := []analyser.OutlierEvent{}
+		for _, event := range events {
+			if subscription.Metric != "" && event.Metric != subscription.Metric {
+				continue
+			}
+			if subscription.Attribute != "" && event.Attribute != subscription.Attribute && !strings.HasPrefix(event.Attribute, subscription.Attribute+">") {
+				continue
+			}
+			res = append(res, event)
+		}
+		return res
+	}
+	report.Result = analyser.OutlierResults{Warnings: filter(report.Result.Warnings), Alarms: filter(report.Result.Alarms)}
+	return report
+}
+
+//isUrl checks if a subscription target is an http(s) address
+func isUrl(target string) bool {
+	return strings.HasPrefix(target, "http://") || strings.HasPrefix(target, "https://")
+}
+
+//SubscriptionStore keeps the subscriptions created through the API, persisting them on a JSON file
+type SubscriptionStore struct {
+	file          string
+	mu            sync.Mutex
+	subscriptions []config.Subscription
+}
+
+//LoadSubscriptionStore reads the subscriptions stored on the given file, a missing file meaning no subscriptions yet
+//It returns an error if the file can't be read or parsed
+func LoadSubscriptionStore(file string) (*SubscriptionStore, error) {
+	store := &SubscriptionStore{file: file, subscriptions: []config.Subscription{}}
+	content, err := os.ReadFile(file)
+	if os.IsNotExist(err) {
+		return store, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("subscriptions file - %s", err.Error())
+	}
+	if err := json.Unmarshal(content, &store.subscriptions); err != nil {
+		return nil, fmt.Errorf("subscriptions file - %s", err.Error())
+	}
+	return store, nil
+}
+
+//List returns a copy of all stored subscriptions
+func (store *SubscriptionStore) List() []config.Subscription {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	return append([]config.Subscription{}, store.subscriptions...)
+}
+
+//Get returns the stored subscription with the given id
+func (store *SubscriptionStore) Get(id string) (config.Subscription, bool) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	for _, subscription := range store.subscriptions {
+		if subscription.Id == id {
+			return subscription, true
+		}
+	}
+	return config.Subscription{}, false
+}
+
+//Add validates a new subscription, assigns it a random id and stores it
+func (store *SubscriptionStore) Add(subscription config.Subscription) (config.Subscription, error) {
+	if err := validateSubscription(subscription); err != nil {
+		return config.Subscription{}, err
+	}
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return config.Subscription{}, err
+	}
+	subscription.Id = hex.EncodeToString(id)
+
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	subscriptions := append(append([]config.Subscription{}, store.subscriptions...), subscription)
+	if err := store.save(subscriptions); err != nil {
+		return config.Subscription{}, err
+	}
+	store.subscriptions = subscriptions
+	return subscription, nil
+}
+
+//Remove deletes the stored subscription with the given id, returning false if there was none
+func (store *SubscriptionStore) Remove(id string) (bool, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	subscriptions := []config.Subscription{}
+	for _, subscription := range store.subscriptions {
+		if subscription.Id != id {
+			subscriptions = append(subscriptions, subscription)
+		}
+	}
+	if len(subscriptions) == len(store.subscriptions) {
+		return false, nil
+	}
+	if err := store.save(subscriptions); err != nil {
+		return false, err
+	}
+	store.subscriptions = subscriptions
+	return true, nil
+}
+
+//save writes the given subscriptions on the store file, replacing it atomically
+func (store *SubscriptionStore) save(subscriptions []config.Subscription) error {
+	content, err := json.MarshalIndent(subscriptions, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := utils.Files.WriteFile(store.file, content, 0600); err != nil {
+		return fmt.Errorf("subscriptions file - %s", err.Error())
+	}
+	return nil
+}
+
+//subscriptionsHandler implements the subscriptions collection endpoint, listing them on GET and creating a new one from the JSON body on POST
+func subscriptionsHandler(store *SubscriptionStore) http.HandlerFunc {
+	return func(res http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodPost {
+			writeJson(res, http.StatusOK, store.List())
+			return
+		}
+
+		var subscription config.Subscription
+		if err := json.NewDecoder(http.MaxBytesReader(res, req.Body, 1<<16)).Decode(&subscription); err != nil {
+			writeJsonError(res, http.StatusBadRequest, fmt.Errorf("invalid subscription - %s", err.Error()))
+			return
+		}
+		created, err := store.Add(subscription)
+		if err != nil {
+			writeJsonError(res, http.StatusBadRequest, err)
+			return
+		}
+		writeJson(res, http.StatusCreated, created)
+	}
+}
+
+//subscriptionHandler implements the single subscription endpoint, returning it on GET and deleting it on DELETE
+func subscriptionHandler(store *SubscriptionStore) http.HandlerFunc {
+	return func(res http.ResponseWriter, req *http.Request) {
+		id := mux.Vars(req)["id"]
+		if req.Method != http.MethodDelete {
+			subscription, found := store.Get(id)
+			if !found {
+				writeJsonError(res, http.StatusNotFound, errors.New("subscription not found"))
+				return
+			}
+			writeJson(res, http.StatusOK, subscription)
+			return
+		}
+
+		removed, err := store.Remove(id)
+		if err != nil {
+			writeJsonError(res, http.StatusInternalServerError, err)
+		} else if !removed {
+			writeJsonError(res, http.StatusNotFound, errors.New("subscription not found"))
+		} else {
+			res.WriteHeader(http.StatusNoContent)
+		}
+	}
+}
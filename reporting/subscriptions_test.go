@@ -0,0 +1,220 @@
+package reporting
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ftfmtavares/anomalies-detector/analyser"
+	"github.com/ftfmtavares/anomalies-detector/config"
+
+	"github.com/gorilla/mux"
+)
+
+func TestSubscriptionPublisher_Publish(t *testing.T) {
+	timeRef := time.Date(2022, 9, 1, 0, 0, 0, 0, time.UTC)
+
+	event := func(metric, attribute string) analyser.OutlierEvent {
+		return analyser.OutlierEvent{OutlierPeriodStart: timeRef, OutlierPeriodEnd: timeRef.AddDate(0, 0, 1), Metric: metric, Attribute: attribute}
+	}
+	report := analyser.OutlierReport{
+		SiteId: "site",
+		Result: analyser.OutlierResults{
+			Warnings: []analyser.OutlierEvent{event("Revenue", "Category>Shoes>Running"), event("Basket", "Category>Shoes")},
+			Alarms:   []analyser.OutlierEvent{event("Revenue", "Category>Shoes"), event("Revenue", "Category>Shoestrings"), event("Revenue", "Total")},
+		},
+	}
+
+	tests := []struct {
+		name         string
+		subscription config.Subscription
+		wantSubjects []string
+	}{
+		{
+			name:         "Attribute path and its sub-paths",
+			subscription: config.Subscription{Name: "shoes", SiteId: "site", Attribute: "Category>Shoes"},
+			wantSubjects: []string{"site/Revenue/Category>Shoes", "site/Revenue/Category>Shoes>Running", "site/Basket/Category>Shoes"},
+		},
+		{
+			name:         "Metric and severity",
+			subscription: config.Subscription{Name: "revenue", Metric: "Revenue", Severities: []string{"alarm"}},
+			wantSubjects: []string{"site/Revenue/Category>Shoes", "site/Revenue/Category>Shoestrings", "site/Revenue/Total"},
+		},
+		{
+			name:         "Other site",
+			subscription: config.Subscription{Name: "other", SiteId: "other-site"},
+			wantSubjects: []string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := []string{}
+			server := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+				var cloudEvent CloudEvent
+				if err := json.NewDecoder(req.Body).Decode(&cloudEvent); err != nil {
+					t.Errorf("invalid event - %v", err)
+				}
+				got = append(got, cloudEvent.Subject)
+			}))
+			defer server.Close()
+
+			tt.subscription.Channel = "cloudEvents"
+			tt.subscription.Target = server.URL
+			publisher, err := NewSubscriptionPublisher(tt.subscription, config.NotifiersParams{})
+			if err != nil {
+				t.Fatalf("NewSubscriptionPublisher() error = %v", err)
+			}
+			if err := publisher.Publish(report); err != nil {
+				t.Fatalf("Publish() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.wantSubjects) {
+				t.Errorf("Publish() subjects = %v, want %v", got, tt.wantSubjects)
+			}
+		})
+	}
+}
+
+func TestSubscriptionPublisher_signingSecret(t *testing.T) {
+	report := analyser.OutlierReport{
+		SiteId: "site",
+		Result: analyser.OutlierResults{Alarms: []analyser.OutlierEvent{{Metric: "Revenue", Attribute: "Total"}}},
+	}
+	notifiers := config.NotifiersParams{CloudEvents: &config.CloudEventsParams{SigningSecret: "global-secret"}}
+
+	//Subscriptions of the configuration file are signed as the notifier, those created through the API having an id aren't
+	tests := []struct {
+		name       string
+		id         string
+		wantSigned bool
+	}{
+		{name: "Configured subscription", wantSigned: true},
+		{name: "Subscription created through the API", id: "0123456789abcdef"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			signature := ""
+			server := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+				signature = req.Header.Get(SignatureHeader)
+			}))
+			defer server.Close()
+
+			publisher, err := NewSubscriptionPublisher(config.Subscription{Id: tt.id, Name: "revenue", Channel: "cloudEvents", Target: server.URL}, notifiers)
+			if err != nil {
+				t.Fatalf("NewSubscriptionPublisher() error = %v", err)
+			}
+			if err := publisher.Publish(report); err != nil {
+				t.Fatalf("Publish() error = %v", err)
+			}
+			if (signature != "") != tt.wantSigned {
+				t.Errorf("Publish() signature = %q, want signed %v", signature, tt.wantSigned)
+			}
+		})
+	}
+}
+
+func TestNewSubscriptionPublisher(t *testing.T) {
+	tests := []struct {
+		name         string
+		subscription config.Subscription
+		notifiers    config.NotifiersParams
+		wantErr      bool
+	}{
+		{name: "Slack webhook", subscription: config.Subscription{Name: "a", Channel: "slack", Target: "https://hooks.slack.com/services/T/B/X"}},
+		{name: "Slack channel with bot token", subscription: config.Subscription{Name: "a", Channel: "slack", Target: "#shoes"}, notifiers: config.NotifiersParams{Slack: &config.SlackParams{BotToken: "xoxb"}}},
+		{name: "Slack channel without bot token", subscription: config.Subscription{Name: "a", Channel: "slack", Target: "#shoes"}, wantErr: true},
+		{name: "CloudEvents without url", subscription: config.Subscription{Name: "a", Channel: "cloudEvents", Target: "sink"}, wantErr: true},
+		{name: "Unknown channel", subscription: config.Subscription{Name: "a", Channel: "email", Target: "a@b.c"}, wantErr: true},
+		{name: "Invalid severity", subscription: config.Subscription{Name: "a", Channel: "slack", Target: "https://hooks", Severities: []string{"critical"}}, wantErr: true},
+		{name: "Missing name", subscription: config.Subscription{Channel: "slack", Target: "https://hooks"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewSubscriptionPublisher(tt.subscription, tt.notifiers); (err != nil) != tt.wantErr {
+				t.Errorf("NewSubscriptionPublisher() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSubscriptionsApi(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "subscriptions.json")
+	store, err := LoadSubscriptionStore(file)
+	if err != nil {
+		t.Fatalf("LoadSubscriptionStore() error = %v", err)
+	}
+	router := mux.NewRouter()
+	router.HandleFunc("/api/v1/subscriptions/{id}", subscriptionHandler(store))
+	router.HandleFunc("/api/v1/subscriptions", subscriptionsHandler(store))
+	request := func(method, url, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(method, url, strings.NewReader(body)))
+		return rec
+	}
+
+	//Invalid subscriptions are rejected and valid ones created with an id
+	if rec := request(http.MethodPost, "/api/v1/subscriptions", `{"name": "shoes", "channel": "sms", "target": "123"}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("POST invalid subscription status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+	rec := request(http.MethodPost, "/api/v1/subscriptions", `{"name": "shoes", "attribute": "Category>Shoes", "channel": "slack", "target": "https://hooks.slack.com/services/T/B/X"}`)
+	var created config.Subscription
+	json.NewDecoder(rec.Body).Decode(&created)
+	if rec.Code != http.StatusCreated || created.Id == "" || created.Attribute != "Category>Shoes" {
+		t.Fatalf("POST subscription status = %d, subscription = %v", rec.Code, created)
+	}
+
+	//Stored subscriptions survive a reload of the file
+	reloaded, err := LoadSubscriptionStore(file)
+	if err != nil || !reflect.DeepEqual(reloaded.List(), []config.Subscription{created}) {
+		t.Errorf("LoadSubscriptionStore() = %v, %v, want the created subscription", reloaded.List(), err)
+	}
+
+	if rec := request(http.MethodGet, "/api/v1/subscriptions/"+created.Id, ""); rec.Code != http.StatusOK {
+		t.Errorf("GET subscription status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	if rec := request(http.MethodDelete, "/api/v1/subscriptions/"+created.Id, ""); rec.Code != http.StatusNoContent {
+		t.Errorf("DELETE subscription status = %d, want %d", rec.Code, http.StatusNoContent)
+	}
+	if rec := request(http.MethodDelete, "/api/v1/subscriptions/"+created.Id, ""); rec.Code != http.StatusNotFound {
+		t.Errorf("DELETE removed subscription status = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+	rec = request(http.MethodGet, "/api/v1/subscriptions", "")
+	if body := strings.TrimSpace(rec.Body.String()); rec.Code != http.StatusOK || body != "[]" {
+		t.Errorf("GET subscriptions status = %d, body = %s, want an empty list", rec.Code, body)
+	}
+}
+
+func TestSubscriptionsApi_authentication(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "subscriptions.json")
+	body := `{"name": "shoes", "channel": "cloudEvents", "target": "https://sink.example.com"}`
+
+	//The API is only served along with the authentication, every request then requiring credentials
+	tests := []struct {
+		name       string
+		auth       *config.ReportAuthParams
+		token      string
+		wantStatus int
+	}{
+		{name: "Not served without authentication", wantStatus: http.StatusNotFound},
+		{name: "Rejected without credentials", auth: &config.ReportAuthParams{Tokens: []string{"static-token"}}, wantStatus: http.StatusUnauthorized},
+		{name: "Created with credentials", auth: &config.ReportAuthParams{Tokens: []string{"static-token"}}, token: "static-token", wantStatus: http.StatusCreated},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewReportHandler(nil, nil, config.ReportServerParams{SubscriptionsFile: file, Auth: tt.auth}, DetectionSettings{})
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/subscriptions", strings.NewReader(body))
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			res := httptest.NewRecorder()
+			handler.ServeHTTP(res, req)
+			if res.Code != tt.wantStatus {
+				t.Errorf("POST subscription status = %d, want %d", res.Code, tt.wantStatus)
+			}
+		})
+	}
+}
@@ -0,0 +1,247 @@
+package reporting
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ftfmtavares/anomalies-detector/analyser"
+	"github.com/ftfmtavares/anomalies-detector/collector"
+	"github.com/ftfmtavares/anomalies-detector/config"
+	"github.com/ftfmtavares/anomalies-detector/utils"
+
+	"github.com/gorilla/mux"
+)
+
+//Const block defines the treemap dimensions, in pixels
+const (
+	treemapWidth   = 1366
+	treemapHeight  = 768
+	treemapHeader  = 16
+	treemapPadding = 2
+)
+
+//treemapColors maps each severity to the fill color of its attribute paths
+var treemapColors = map[string]string{
+	analyser.VerdictNormal:  "#a1d99b",
+	analyser.VerdictWarning: "#fdae6b",
+	analyser.VerdictAlarm:   "#e6550d",
+}
+
+//treemapNode is an attribute path of the treemap, sized by its value or samples over the selected period
+type treemapNode struct {
+	path     string
+	label    string
+	size     float64
+	severity string
+	children []*treemapNode
+}
+
+//treemapHandler implements an HTTP response returning an SVG treemap of the attribute paths of a site metric
+//Every attribute path is nested in its parent, sized by its value or samples within the period and colored by the highest severity of its events overlapping it
+//Supported query strings are attribute (the attribute to break the total down by, the first one by default), from and to (RFC3339, the whole data period by default),
+//size ("value", or "samples" which is the default for Average metrics) and resolution to pick one of the time steps of a site analysed at several resolutions
+//The period on the title and the sizes on hover are formatted with the locale of the site configuration, if any
+func treemapHandler(sitesData []collector.SiteData, outlierReports []analyser.OutlierReport, datasets []config.Dataset) http.HandlerFunc {
+	return func(res http.ResponseWriter, req *http.Request) {
+		siteUrl := mux.Vars(req)["siteid"]
+		metricUrl := mux.Vars(req)["metric"]
+		values := req.URL.Query()
+
+		siteData, found := findSite(sitesData, siteUrl, values.Get("resolution"))
+		if !found {
+			http.Error(res, "404 page not found", http.StatusNotFound)
+			return
+		}
+		metricData, found := findMetric(sitesData, siteUrl, siteData.TimeStep, metricUrl)
+		if !found {
+			http.Error(res, "404 page not found", http.StatusNotFound)
+			return
+		}
+
+		//Reading the period, defaulting to the whole data period, and how the attribute paths are sized
+		from, to := siteData.DateStart, siteData.DateEnd
+		var err error
+		if value := values.Get("from"); value != "" {
+			if from, err = time.Parse(time.RFC3339, value); err != nil {
+				http.Error(res, "400 invalid from parameter, RFC3339 expected", http.StatusBadRequest)
+				return
+			}
+		}
+		if value := values.Get("to"); value != "" {
+			if to, err = time.Parse(time.RFC3339, value); err != nil {
+				http.Error(res, "400 invalid to parameter, RFC3339 expected", http.StatusBadRequest)
+				return
+			}
+		}
+		if !from.Before(to) {
+			http.Error(res, "400 from parameter must be before to parameter", http.StatusBadRequest)
+			return
+		}
+		size := values.Get("size")
+		if size == "" {
+			size = "value"
+			if metricData.Aggregation() == "mean" {
+				size = "samples"
+			}
+		}
+		if size != "value" && size != "samples" {
+			http.Error(res, "400 invalid size parameter, value or samples expected", http.StatusBadRequest)
+			return
+		}
+
+		root, err := buildTreemap(metricData, values.Get("attribute"), from, to, size, treemapSeverities(outlierReports, siteData, metricUrl, from, to))
+		if err != nil {
+			http.Error(res, "404 "+err.Error(), http.StatusNotFound)
+			return
+		}
+		locale := siteLocale(datasets, siteUrl)
+		step, _ := utils.StrToDuration(siteData.TimeStep)
+		title := fmt.Sprintf("%s - %s - %s to %s", siteUrl, metricUrl, formatLocaleTime(from, step, locale), formatLocaleTime(to, step, locale))
+		res.Header().Set("Content-Type", "image/svg+xml")
+		res.Write([]byte(renderTreemap(root, title, locale)))
+	}
+}
+
+//treemapSeverities returns the highest severity of the events of a metric overlapping the period, by attribute path
+func treemapSeverities(outlierReports []analyser.OutlierReport, siteData collector.SiteData, metric string, from, to time.Time) map[string]string {
+	severities := map[string]string{}
+	for _, outlierReport := range outlierReports {
+		if !reportMatchesSite(outlierReport, siteData) {
+			continue
+		}
+		for _, event := range outlierReport.Result.Warnings {
+			if event.Metric == metric && event.OutlierPeriodStart.Before(to) && event.OutlierPeriodEnd.After(from) && severities[event.Attribute] == "" {
+				severities[event.Attribute] = analyser.VerdictWarning
+			}
+		}
+		for _, event := range outlierReport.Result.Alarms {
+			if event.Metric == metric && event.OutlierPeriodStart.Before(to) && event.OutlierPeriodEnd.After(from) {
+				severities[event.Attribute] = analyser.VerdictAlarm
+			}
+		}
+	}
+	return severities
+}
+
+//buildTreemap builds the tree of the total and the attribute paths breaking it down by the given attribute (the first one if empty), case insensitive
+//Nodes are sized by the value or samples of their time steps within the period, values of Average metrics being weighted by samples, and sorted by decreasing size
+//It returns an error if the metric has no such attribute
+func buildTreemap(metricData collector.MetricData, attribute string, from, to time.Time, size string, severities map[string]string) (*treemapNode, error) {
+	if attribute == "" {
+		for _, path := range metricData.Attributes {
+			if path != "Total" {
+				attribute = strings.Split(path, ">")[0]
+				break
+			}
+		}
+	}
+	nodeSize := func(path string) float64 {
+		var values, weighted, samples float64
+		for _, stepData := range metricData.AttributeData[path] {
+			if !stepData.DateStart.Before(from) && stepData.DateStart.Before(to) {
+				values += stepData.Value
+				weighted += stepData.Value * float64(stepData.Samples)
+				samples += float64(stepData.Samples)
+			}
+		}
+		switch {
+		case size == "samples":
+			return samples
+		case metricData.Aggregation() == "mean" && samples > 0:
+			return weighted / samples
+		default:
+			return values
+		}
+	}
+
+	//Attribute paths are nested under their parent path, the first level under the total
+	root := &treemapNode{path: "Total", label: "Total", size: nodeSize("Total"), severity: severities["Total"]}
+	nodes := map[string]*treemapNode{}
+	for _, path := range metricData.Attributes {
+		parts := strings.Split(path, ">")
+		if len(parts) < 2 || !strings.EqualFold(parts[0], attribute) {
+			continue
+		}
+		nodes[path] = &treemapNode{path: path, label: parts[len(parts)-1], size: nodeSize(path), severity: severities[path]}
+	}
+	if len(nodes) == 0 {
+		return nil, errors.New("attribute not found")
+	}
+	for path, node := range nodes {
+		parent, found := nodes[path[:strings.LastIndex(path, ">")]]
+		if !found {
+			parent = root
+		}
+		parent.children = append(parent.children, node)
+	}
+	var sortNodes func(node *treemapNode)
+	sortNodes = func(node *treemapNode) {
+		sort.Slice(node.children, func(i, j int) bool {
+			if node.children[i].size != node.children[j].size {
+				return node.children[i].size > node.children[j].size
+			}
+			return node.children[i].path < node.children[j].path
+		})
+		for _, child := range node.children {
+			sortNodes(child)
+		}
+	}
+	sortNodes(root)
+	return root, nil
+}
+
+//renderTreemap draws the tree as nested SVG rectangles, splitting each rectangle among its children proportionally to their sizes,
+//horizontally and vertically in turn as the depth increases, every rectangle showing its label and its path, size and severity on hover
+func renderTreemap(root *treemapNode, title string, locale *config.LocaleParams) string {
+	var svg strings.Builder
+	svg.WriteString(fmt.Sprintf("<svg xmlns=\"http://www.w3.org/2000/svg\" width=\"%d\" height=\"%d\" font-family=\"sans-serif\" font-size=\"11\">\n", treemapWidth, treemapHeight+20))
+	svg.WriteString(fmt.Sprintf("<text x=\"4\" y=\"14\" font-size=\"14\">%s</text>\n", html.EscapeString(title)))
+
+	var draw func(node *treemapNode, x, y, width, height float64, depth int)
+	draw = func(node *treemapNode, x, y, width, height float64, depth int) {
+		severity := node.severity
+		if severity == "" {
+			severity = analyser.VerdictNormal
+		}
+		size := strconv.FormatFloat(node.size, 'g', -1, 64)
+		if locale != nil {
+			size = formatLocaleNumber(node.size, 2, *locale)
+		}
+		svg.WriteString(fmt.Sprintf("<g><title>%s - %s - %s</title>", html.EscapeString(node.path), html.EscapeString(size), severity))
+		svg.WriteString(fmt.Sprintf("<rect x=\"%.1f\" y=\"%.1f\" width=\"%.1f\" height=\"%.1f\" fill=\"%s\" stroke=\"#ffffff\"/>", x, y, width, height, treemapColors[severity]))
+		if width > 30 && height > treemapHeader {
+			svg.WriteString(fmt.Sprintf("<text x=\"%.1f\" y=\"%.1f\">%s</text>", x+3, y+12, html.EscapeString(node.label)))
+		}
+		svg.WriteString("</g>\n")
+
+		//Children share the rectangle below the label, proportionally to their sizes
+		total := 0.0
+		for _, child := range node.children {
+			total += child.size
+		}
+		x, y, width, height = x+treemapPadding, y+treemapHeader, width-2*treemapPadding, height-treemapHeader-treemapPadding
+		if total <= 0 || width <= 0 || height <= 0 {
+			return
+		}
+		for _, child := range node.children {
+			share := child.size / total
+			if depth%2 == 0 {
+				draw(child, x, y, width*share, height, depth+1)
+				x += width * share
+			} else {
+				draw(child, x, y, width, height*share, depth+1)
+				y += height * share
+			}
+		}
+	}
+	draw(root, 0, 20, treemapWidth, treemapHeight, 0)
+
+	svg.WriteString("</svg>\n")
+	return svg.String()
+}
@@ -0,0 +1,73 @@
+package reporting
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ftfmtavares/anomalies-detector/config"
+)
+
+func Test_treemapHandler(t *testing.T) {
+	sitesData, reports := reportFixture()
+	router := newReportRouter(sitesData, reports, config.ReportServerParams{}, DetectionSettings{})
+
+	tests := []struct {
+		name       string
+		path       string
+		wantStatus int
+		wantParts  []string
+	}{
+		{
+			name:       "Whole period colored by severity",
+			path:       "/report/shop/Revenue/treemap",
+			wantStatus: http.StatusOK,
+			wantParts: []string{
+				`<title>Total - 36850 - alarm</title><rect x="0.0" y="20.0" width="1366.0" height="768.0" fill="#e6550d"`,
+				`<title>Browser&gt;Chrome - 27400 - warning</title><rect x="2.0" y="36.0" width="1073.3" height="750.0" fill="#fdae6b"`,
+				`<title>Browser&gt;Edge - 7370 - alarm</title>`,
+			},
+		},
+		{
+			name:       "Period before the events sized by samples",
+			path:       "/report/shop/Revenue/treemap?resolution=1d&from=2022-08-02T00:00:00Z&to=2022-08-12T00:00:00Z&size=samples",
+			wantStatus: http.StatusOK,
+			wantParts:  []string{`<title>Total - 10000 - normal</title>`, `<title>Browser&gt;Edge - 2000 - normal</title>`},
+		},
+		{
+			name:       "Unknown attribute",
+			path:       "/report/shop/Revenue/treemap?attribute=country",
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "Unknown metric",
+			path:       "/report/shop/Visits/treemap",
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "Invalid size",
+			path:       "/report/shop/Revenue/treemap?size=area",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "Empty period",
+			path:       "/report/shop/Revenue/treemap?from=2022-08-12T00:00:00Z&to=2022-08-02T00:00:00Z",
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := httptest.NewRecorder()
+			router.ServeHTTP(res, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			if res.Code != tt.wantStatus {
+				t.Fatalf("treemapHandler() status = %d, want %d - %s", res.Code, tt.wantStatus, res.Body.String())
+			}
+			for _, part := range tt.wantParts {
+				if !strings.Contains(res.Body.String(), part) {
+					t.Errorf("treemapHandler() missing %q in\n%s", part, res.Body.String())
+				}
+			}
+		})
+	}
+}
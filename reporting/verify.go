@@ -0,0 +1,115 @@
+package reporting
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ftfmtavares/anomalies-detector/analyser"
+	"github.com/ftfmtavares/anomalies-detector/collector"
+	"github.com/ftfmtavares/anomalies-detector/config"
+
+	"github.com/gorilla/mux"
+)
+
+//DetectionSettings provides the structure for the detection settings the verification endpoint recomputes detection with
+//Datasets field holds the site configurations and Methods field the general detection methods parameters, used for the sites without their own
+//History field optionally holds the stored runs the week over week view compares the current run with
+//Run field optionally holds the measures of the served run, exposed on /metrics
+//EventStates field optionally holds the events tracked across runs, whose open ones are served to status pages
+//Metrics field optionally holds the metric definitions by name served on the catalog, the default ones being served if not set
+type DetectionSettings struct {
+	Datasets    []config.Dataset
+	Methods     config.DetectionMethodsParams
+	History     *analyser.RunHistory
+	Run         *RunStats
+	EventStates *EventStateStore
+	Metrics     map[string]config.MetricDefinition
+}
+
+//verifyHandler implements an HTTP response recomputing the detection of a site metric on demand and returning the verdict on a period in JSON format
+//Required query strings are from and to (RFC3339), while attribute ("Total" by default), resolution, methods (comma separated),
+//outliersMultiplier, strongOutliersMultiplier and consensus are optional, the last ones overriding the site detection settings
+func verifyHandler(sitesData []collector.SiteData, settings DetectionSettings) http.HandlerFunc {
+	return func(res http.ResponseWriter, req *http.Request) {
+		siteUrl := mux.Vars(req)["siteid"]
+		values := req.URL.Query()
+
+		request, err := parseVerificationRequest(values)
+		if err != nil {
+			writeJsonError(res, http.StatusBadRequest, err)
+			return
+		}
+		request.Metric = mux.Vars(req)["metric"]
+
+		siteData, found := findSite(sitesData, siteUrl, values.Get("resolution"))
+		if !found {
+			writeJsonError(res, http.StatusNotFound, errors.New("site not found"))
+			return
+		}
+		dataConf := config.Dataset{SiteId: siteUrl, OutliersDetectionMethod: "3-sigmas"}
+		for _, dataSet := range settings.Datasets {
+			if dataSet.SiteId == siteUrl {
+				dataConf = dataSet
+				break
+			}
+		}
+
+		verification, err := analyser.VerifyPeriod(siteData, dataConf, dataConf.MethodParams(settings.Methods), request)
+		if err != nil {
+			status := http.StatusBadRequest
+			if strings.HasSuffix(err.Error(), "not found") {
+				status = http.StatusNotFound
+			}
+			writeJsonError(res, status, err)
+			return
+		}
+		writeJson(res, http.StatusOK, verification)
+	}
+}
+
+//parseVerificationRequest reads and validates the verification endpoint query string parameters
+func parseVerificationRequest(values url.Values) (analyser.VerificationRequest, error) {
+	get := func(key string) string { return strings.TrimSpace(values.Get(key)) }
+	request := analyser.VerificationRequest{Attribute: get("attribute")}
+
+	var err error
+	if request.PeriodStart, err = time.Parse(time.RFC3339, get("from")); err != nil {
+		return request, errors.New("invalid from parameter, RFC3339 expected")
+	}
+	if request.PeriodEnd, err = time.Parse(time.RFC3339, get("to")); err != nil {
+		return request, errors.New("invalid to parameter, RFC3339 expected")
+	}
+	if !request.PeriodStart.Before(request.PeriodEnd) {
+		return request, errors.New("from parameter must be before to parameter")
+	}
+
+	for _, method := range strings.Split(get("methods"), ",") {
+		if method = strings.TrimSpace(method); method == "" {
+			continue
+		}
+		if !containsString(config.DetectionMethods, method) {
+			return request, errors.New("invalid methods parameter, unknown method " + method)
+		}
+		request.Methods = append(request.Methods, method)
+	}
+	if value := get("outliersMultiplier"); value != "" {
+		if request.OutliersMultiplier, err = strconv.ParseFloat(value, 64); err != nil || request.OutliersMultiplier <= 0 {
+			return request, errors.New("invalid outliersMultiplier parameter, positive number expected")
+		}
+	}
+	if value := get("strongOutliersMultiplier"); value != "" {
+		if request.StrongOutliersMultiplier, err = strconv.ParseFloat(value, 64); err != nil || request.StrongOutliersMultiplier <= 0 {
+			return request, errors.New("invalid strongOutliersMultiplier parameter, positive number expected")
+		}
+	}
+	if value := get("consensus"); value != "" {
+		if request.Consensus, err = strconv.Atoi(value); err != nil || request.Consensus <= 0 {
+			return request, errors.New("invalid consensus parameter, positive integer expected")
+		}
+	}
+	return request, nil
+}
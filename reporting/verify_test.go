@@ -0,0 +1,94 @@
+package reporting
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ftfmtavares/anomalies-detector/analyser"
+	"github.com/ftfmtavares/anomalies-detector/collector"
+	"github.com/ftfmtavares/anomalies-detector/config"
+
+	"github.com/gorilla/mux"
+)
+
+func Test_verifyHandler(t *testing.T) {
+	timeRef := time.Date(2022, 9, 1, 0, 0, 0, 0, time.UTC)
+	data := []collector.TimeStepData{}
+	for day := 0; day < 30; day++ {
+		value := 100.0 + float64(day%3)
+		if day == 20 {
+			value = 130
+		}
+		data = append(data, collector.TimeStepData{DateStart: timeRef.AddDate(0, 0, day), Value: value, Samples: 100})
+	}
+	sitesData := []collector.SiteData{{
+		SiteId:    "shop",
+		TimeStep:  "1d",
+		DateStart: timeRef,
+		DateEnd:   timeRef.AddDate(0, 0, 30),
+		Metrics:   []collector.MetricData{{Metric: "Revenue", Attributes: []string{"Total"}, AttributeData: map[string][]collector.TimeStepData{"Total": data}}},
+	}}
+	settings := DetectionSettings{
+		Datasets: []config.Dataset{{SiteId: "shop", TimeAgo: "30d", TimeStep: "1d", OutliersDetectionMethod: "3-sigmas"}},
+		Methods:  config.DetectionMethodsParams{ThreeSigmas: config.ThreeSigmasParams{OutliersMultiplier: 2, StrongOutliersMultiplier: 3}},
+	}
+	router := mux.NewRouter()
+	router.PathPrefix("/api/v1/sites/{siteid}/metrics/{metric}/verify").Subrouter().HandleFunc("", verifyHandler(sitesData, settings))
+
+	tests := []struct {
+		name        string
+		path        string
+		wantStatus  int
+		wantVerdict string
+	}{
+		{
+			name:        "Anomaly confirmed",
+			path:        "/api/v1/sites/shop/metrics/Revenue/verify?from=2022-09-21T00:00:00Z&to=2022-09-22T00:00:00Z",
+			wantStatus:  http.StatusOK,
+			wantVerdict: analyser.VerdictAlarm,
+		},
+		{
+			name:        "Anomaly dismissed with overridden multipliers",
+			path:        "/api/v1/sites/shop/metrics/Revenue/verify?from=2022-09-21T00:00:00Z&to=2022-09-22T00:00:00Z&outliersMultiplier=10&strongOutliersMultiplier=12",
+			wantStatus:  http.StatusOK,
+			wantVerdict: analyser.VerdictNormal,
+		},
+		{
+			name:       "Missing period",
+			path:       "/api/v1/sites/shop/metrics/Revenue/verify",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "Unknown method",
+			path:       "/api/v1/sites/shop/metrics/Revenue/verify?from=2022-09-21T00:00:00Z&to=2022-09-22T00:00:00Z&methods=magic",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "Unknown metric",
+			path:       "/api/v1/sites/shop/metrics/Visits/verify?from=2022-09-21T00:00:00Z&to=2022-09-22T00:00:00Z",
+			wantStatus: http.StatusNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := httptest.NewRecorder()
+			router.ServeHTTP(res, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			if res.Code != tt.wantStatus {
+				t.Fatalf("verifyHandler() status = %d %s, want %d", res.Code, res.Body.String(), tt.wantStatus)
+			}
+			if tt.wantVerdict == "" {
+				return
+			}
+			var verification analyser.Verification
+			if err := json.NewDecoder(res.Body).Decode(&verification); err != nil {
+				t.Fatalf("verifyHandler() body error = %v", err)
+			}
+			if verification.Verdict != tt.wantVerdict {
+				t.Errorf("verifyHandler() verdict = %s, want %s", verification.Verdict, tt.wantVerdict)
+			}
+		})
+	}
+}
@@ -0,0 +1,176 @@
+package reporting
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/ftfmtavares/anomalies-detector/analyser"
+	"github.com/ftfmtavares/anomalies-detector/collector"
+	"github.com/ftfmtavares/anomalies-detector/config"
+	"github.com/ftfmtavares/anomalies-detector/utils"
+)
+
+//Const block defines the watchdog defaults and the details of its alerts
+//Alerts are raised as alarms of the watchdog metric carrying the watchdog route, so channels can route them apart from the detected anomalies
+const (
+	defaultWatchdogMaxFailedRuns = 3
+	defaultWatchdogCheckInterval = 5 * time.Minute
+	watchdogMetric               = "watchdog"
+	watchdogRoute                = "watchdog"
+)
+
+//Watchdog tracks the collection of every site across scheduled runs and the age of the served data
+type Watchdog struct {
+	stateFile     string
+	maxFailedRuns int
+	maxDataAge    time.Duration
+	checkInterval time.Duration
+	state         map[string]*watchdogSiteState
+	staleAlerted  map[string]bool
+}
+
+//watchdogSiteState holds the consecutive failed collections of a site and the time of its last successful one
+type watchdogSiteState struct {
+	FailedRuns  int       `json:"failedRuns"`
+	LastSuccess time.Time `json:"lastSuccess,omitempty"`
+}
+
+//NewWatchdog creates a Watchdog from the given parameters, loading the state of the previous runs if any
+//It returns an error if a duration is invalid or if the state file can't be read
+func NewWatchdog(params config.WatchdogParams) (*Watchdog, error) {
+	watchdog := &Watchdog{
+		stateFile:     params.StateFile,
+		maxFailedRuns: params.MaxFailedRuns,
+		checkInterval: defaultWatchdogCheckInterval,
+		state:         map[string]*watchdogSiteState{},
+		staleAlerted:  map[string]bool{},
+	}
+	if watchdog.maxFailedRuns <= 0 {
+		watchdog.maxFailedRuns = defaultWatchdogMaxFailedRuns
+	}
+	var err error
+	if params.MaxDataAge != "" {
+		if watchdog.maxDataAge, err = utils.StrToDuration(params.MaxDataAge); err != nil {
+			return nil, fmt.Errorf("watchdog maxDataAge - %s", err.Error())
+		}
+	}
+	if params.CheckInterval != "" {
+		if watchdog.checkInterval, err = utils.StrToDuration(params.CheckInterval); err != nil || watchdog.checkInterval <= 0 {
+			return nil, fmt.Errorf("watchdog checkInterval - invalid duration \"%s\"", params.CheckInterval)
+		}
+	}
+
+	if watchdog.stateFile != "" {
+		content, err := os.ReadFile(watchdog.stateFile)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, fmt.Errorf("watchdog state file - %s", err.Error())
+		}
+		if err == nil {
+			if err := json.Unmarshal(content, &watchdog.state); err != nil {
+				return nil, fmt.Errorf("watchdog state file - %s", err.Error())
+			}
+		}
+	}
+
+	return watchdog, nil
+}
+
+//RecordRun updates the state of every site with the outcome of its collection on this run, a collection without any metric being a failure
+//An alert is returned for each site reaching the maximum consecutive failed runs, and again every time that number of runs fails once more
+//The state is saved on the state file, if configured, so that failures are counted across scheduled runs
+func (watchdog *Watchdog) RecordRun(sitesData []collector.SiteData, now time.Time) ([]analyser.OutlierReport, error) {
+	alerts := []analyser.OutlierReport{}
+	for _, siteData := range sitesData {
+		key := watchdogKey(siteData)
+		state := watchdog.state[key]
+		if state == nil {
+			state = &watchdogSiteState{}
+			watchdog.state[key] = state
+		}
+
+		if len(siteData.Metrics) > 0 {
+			state.FailedRuns = 0
+			state.LastSuccess = now
+			continue
+		}
+		state.FailedRuns++
+		if state.FailedRuns%watchdog.maxFailedRuns == 0 {
+			since := state.LastSuccess
+			if since.IsZero() {
+				since = now
+			}
+			alerts = append(alerts, watchdogAlert(siteData, fmt.Sprintf("Collection failed %d runs", state.FailedRuns), since, now))
+		}
+	}
+
+	if watchdog.stateFile == "" {
+		return alerts, nil
+	}
+	content, err := json.MarshalIndent(watchdog.state, "", "  ")
+	if err != nil {
+		return alerts, err
+	}
+	if err := utils.Files.WriteFile(watchdog.stateFile, content, 0600); err != nil {
+		return alerts, fmt.Errorf("watchdog state file - %s", err.Error())
+	}
+	return alerts, nil
+}
+
+//CheckDataAge returns an alert for each served site whose data ends longer than the maximum data age ago
+//Each site is only alerted once, since the served data doesn't change until the next run
+func (watchdog *Watchdog) CheckDataAge(sitesData []collector.SiteData, now time.Time) []analyser.OutlierReport {
+	alerts := []analyser.OutlierReport{}
+	if watchdog.maxDataAge == 0 {
+		return alerts
+	}
+	for _, siteData := range sitesData {
+		key := watchdogKey(siteData)
+		if watchdog.staleAlerted[key] || siteData.DateEnd.IsZero() || now.Sub(siteData.DateEnd) <= watchdog.maxDataAge {
+			continue
+		}
+		watchdog.staleAlerted[key] = true
+		alerts = append(alerts, watchdogAlert(siteData, "Stale data", siteData.DateEnd, now))
+	}
+	return alerts
+}
+
+//Watch checks the age of the served data right away and then every check interval, passing the alerts to the given function
+//It never returns, being meant to run on its own goroutine alongside the report server
+func (watchdog *Watchdog) Watch(sitesData []collector.SiteData, alert func(report analyser.OutlierReport)) {
+	for {
+		for _, report := range watchdog.CheckDataAge(sitesData, Clock.Now()) {
+			alert(report)
+		}
+		time.Sleep(watchdog.checkInterval)
+	}
+}
+
+//watchdogKey identifies a site and resolution on the watchdog state
+func watchdogKey(siteData collector.SiteData) string {
+	if siteData.TimeStep == "" {
+		return siteData.SiteId
+	}
+	return siteData.SiteId + "/" + siteData.TimeStep
+}
+
+//watchdogAlert builds a report holding a single watchdog alarm, so it can be sent through the same notifiers as the detected anomalies
+func watchdogAlert(siteData collector.SiteData, attribute string, since, now time.Time) analyser.OutlierReport {
+	return analyser.OutlierReport{
+		SiteId:         siteData.SiteId,
+		CheckDateStart: now,
+		CheckDateEnd:   now,
+		TimeStep:       siteData.TimeStep,
+		DateStart:      siteData.DateStart,
+		DateEnd:        siteData.DateEnd,
+		Result: analyser.OutlierResults{Alarms: []analyser.OutlierEvent{{
+			OutlierPeriodStart: since,
+			OutlierPeriodEnd:   now,
+			Metric:             watchdogMetric,
+			Attribute:          attribute,
+			Resolution:         siteData.TimeStep,
+			Routes:             []string{watchdogRoute},
+		}}},
+	}
+}
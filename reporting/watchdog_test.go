@@ -0,0 +1,95 @@
+package reporting
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ftfmtavares/anomalies-detector/collector"
+	"github.com/ftfmtavares/anomalies-detector/config"
+)
+
+func TestWatchdog_RecordRun(t *testing.T) {
+	timeRef := time.Date(2022, 9, 1, 0, 0, 0, 0, time.UTC)
+	stateFile := filepath.Join(t.TempDir(), "watchdog.json")
+	collected := collector.SiteData{SiteId: "shop", TimeStep: "1d", Metrics: []collector.MetricData{{Metric: "Revenue"}}}
+	failed := collector.SiteData{SiteId: "shop", TimeStep: "1d"}
+
+	//Each run creates a new watchdog, as scheduled runs do, so failures are only counted through the state file
+	runs := []struct {
+		siteData   collector.SiteData
+		wantAlerts []string
+	}{
+		{siteData: collected},
+		{siteData: failed},
+		{siteData: failed},
+		{siteData: failed, wantAlerts: []string{"Collection failed 3 runs"}},
+		{siteData: failed},
+		{siteData: failed},
+		{siteData: failed, wantAlerts: []string{"Collection failed 6 runs"}},
+		{siteData: collected},
+		{siteData: failed},
+	}
+	for i, run := range runs {
+		watchdog, err := NewWatchdog(config.WatchdogParams{StateFile: stateFile})
+		if err != nil {
+			t.Fatalf("NewWatchdog() error = %v", err)
+		}
+		now := timeRef.AddDate(0, 0, i)
+		alerts, err := watchdog.RecordRun([]collector.SiteData{run.siteData}, now)
+		if err != nil {
+			t.Fatalf("RecordRun() error = %v", err)
+		}
+
+		got := []string{}
+		for _, alert := range alerts {
+			alarm := alert.Result.Alarms[0]
+			if alarm.Metric != "watchdog" || !reflect.DeepEqual(alarm.Routes, []string{"watchdog"}) || !alarm.OutlierPeriodStart.Equal(timeRef) || !alarm.OutlierPeriodEnd.Equal(now) {
+				t.Errorf("run %d - RecordRun() unexpected alarm %v", i+1, alarm)
+			}
+			got = append(got, alarm.Attribute)
+		}
+		if len(got) != len(run.wantAlerts) || (len(got) > 0 && !reflect.DeepEqual(got, run.wantAlerts)) {
+			t.Errorf("run %d - RecordRun() alerts = %v, want %v", i+1, got, run.wantAlerts)
+		}
+	}
+}
+
+func TestWatchdog_CheckDataAge(t *testing.T) {
+	timeRef := time.Date(2022, 9, 1, 0, 0, 0, 0, time.UTC)
+	sitesData := []collector.SiteData{
+		{SiteId: "shop", TimeStep: "1d", DateEnd: timeRef},
+		{SiteId: "shop", TimeStep: "1h", DateEnd: timeRef.AddDate(0, 0, 2)},
+	}
+
+	watchdog, err := NewWatchdog(config.WatchdogParams{MaxDataAge: "2d"})
+	if err != nil {
+		t.Fatalf("NewWatchdog() error = %v", err)
+	}
+	checks := []struct {
+		now        time.Time
+		wantAlerts []string
+	}{
+		{now: timeRef.AddDate(0, 0, 1)},
+		{now: timeRef.AddDate(0, 0, 3), wantAlerts: []string{"1d"}},
+		{now: timeRef.AddDate(0, 0, 5), wantAlerts: []string{"1h"}},
+		{now: timeRef.AddDate(0, 0, 9)},
+	}
+	for i, check := range checks {
+		got := []string{}
+		for _, alert := range watchdog.CheckDataAge(sitesData, check.now) {
+			if alert.Result.Alarms[0].Attribute != "Stale data" {
+				t.Errorf("check %d - CheckDataAge() unexpected alarm %v", i+1, alert.Result.Alarms[0])
+			}
+			got = append(got, alert.TimeStep)
+		}
+		if len(got) != len(check.wantAlerts) || (len(got) > 0 && !reflect.DeepEqual(got, check.wantAlerts)) {
+			t.Errorf("check %d - CheckDataAge() alerts = %v, want %v", i+1, got, check.wantAlerts)
+		}
+	}
+
+	if _, err := NewWatchdog(config.WatchdogParams{MaxDataAge: "2x"}); err == nil {
+		t.Errorf("NewWatchdog() expected an error for invalid durations")
+	}
+}
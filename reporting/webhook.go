@@ -0,0 +1,150 @@
+package reporting
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/ftfmtavares/anomalies-detector/analyser"
+	"github.com/ftfmtavares/anomalies-detector/config"
+	"github.com/ftfmtavares/anomalies-detector/utils"
+)
+
+//Const block defines the webhook notifier defaults
+const (
+	defaultWebhookMaxRetries     = 3
+	defaultWebhookInitialBackoff = time.Second
+)
+
+//WebhookEvent provides the structure posted by the webhook notifier, a detected event along with its site, time step and severity
+type WebhookEvent struct {
+	SiteId   string `json:"siteId"`
+	TimeStep string `json:"timeStep"`
+	Severity string `json:"severity"`
+	analyser.OutlierEvent
+}
+
+//WebhookNotifier posts detected warnings and alarms as JSON to the webhook URL of their severity, retrying failed posts with an exponential backoff
+type WebhookNotifier struct {
+	urls           map[string]string
+	routes         []string
+	secret         string
+	maxRetries     int
+	initialBackoff time.Duration
+	client         *http.Client
+	sleep          func(time.Duration)
+}
+
+//NewWebhookNotifier creates a WebhookNotifier from the given parameters
+//It returns an error if no URL is configured or if the initial backoff is invalid
+func NewWebhookNotifier(params config.WebhookParams) (*WebhookNotifier, error) {
+	notifier := &WebhookNotifier{
+		urls:           map[string]string{},
+		routes:         params.Routes,
+		secret:         utils.ResolveSecret(params.SigningSecret),
+		maxRetries:     params.MaxRetries,
+		initialBackoff: defaultWebhookInitialBackoff,
+		client:         utils.NewHttpClient(10 * time.Second),
+		sleep:          time.Sleep,
+	}
+
+	url := utils.ResolveSecret(params.Url)
+	for severity, severityUrl := range map[string]string{analyser.VerdictAlarm: params.AlarmsUrl, analyser.VerdictWarning: params.WarningsUrl} {
+		if severityUrl = utils.ResolveSecret(severityUrl); severityUrl == "" {
+			severityUrl = url
+		}
+		if severityUrl != "" {
+			notifier.urls[severity] = severityUrl
+		}
+	}
+	if len(notifier.urls) == 0 {
+		return nil, errors.New("webhook notifier requires a url, alarmsUrl or warningsUrl")
+	}
+
+	if notifier.maxRetries == 0 {
+		notifier.maxRetries = defaultWebhookMaxRetries
+	} else if notifier.maxRetries < 0 {
+		notifier.maxRetries = 0
+	}
+	if params.InitialBackoff != "" {
+		var err error
+		if notifier.initialBackoff, err = utils.StrToDuration(params.InitialBackoff); err != nil || notifier.initialBackoff <= 0 {
+			return nil, fmt.Errorf("webhook notifier initialBackoff - invalid duration \"%s\"", params.InitialBackoff)
+		}
+	}
+
+	return notifier, nil
+}
+
+//Notify posts the warnings and alarms of a report to the URLs of their severities, one event per request and alarms first
+//Every event is posted even if others fail, the last error being returned
+func (notifier *WebhookNotifier) Notify(report analyser.OutlierReport) error {
+	var lastErr error
+	post := func(events []analyser.OutlierEvent, severity string) {
+		url, found := notifier.urls[severity]
+		if !found {
+			return
+		}
+		for _, event := range events {
+			if len(notifier.routes) > 0 && !hasRoute(event.Routes, notifier.routes) {
+				continue
+			}
+			if err := notifier.post(url, WebhookEvent{SiteId: report.SiteId, TimeStep: report.TimeStep, Severity: severity, OutlierEvent: event}); err != nil {
+				lastErr = err
+			}
+		}
+	}
+	post(report.Result.Alarms, analyser.VerdictAlarm)
+	post(report.Result.Warnings, analyser.VerdictWarning)
+	return lastErr
+}
+
+//post sends a single event, retrying connection failures, server errors and throttled requests with an exponential backoff
+//Throttled requests wait for their Retry-After header instead, when it asks for longer, while any other status but 2xx fails right away
+func (notifier *WebhookNotifier) post(url string, event WebhookEvent) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	backoff := notifier.initialBackoff
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		signRequest(req, notifier.secret, body)
+
+		wait := backoff
+		resp, err := notifier.client.Do(req)
+		if err == nil {
+			resp.Body.Close()
+			switch {
+			case resp.StatusCode >= 200 && resp.StatusCode <= 299:
+				return nil
+			case resp.StatusCode == http.StatusTooManyRequests:
+				if retryAfter, _ := strconv.Atoi(resp.Header.Get("Retry-After")); time.Duration(retryAfter)*time.Second > wait {
+					wait = time.Duration(retryAfter) * time.Second
+				}
+				err = fmt.Errorf("webhook notifier - unexpected status %s", resp.Status)
+			case resp.StatusCode >= 500:
+				err = fmt.Errorf("webhook notifier - unexpected status %s", resp.Status)
+			default:
+				return fmt.Errorf("webhook notifier - unexpected status %s", resp.Status)
+			}
+		} else {
+			err = fmt.Errorf("webhook notifier - %s", err.Error())
+		}
+
+		if attempt >= notifier.maxRetries {
+			return err
+		}
+		notifier.sleep(wait)
+		backoff *= 2
+	}
+}
@@ -0,0 +1,135 @@
+package reporting
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ftfmtavares/anomalies-detector/analyser"
+	"github.com/ftfmtavares/anomalies-detector/config"
+)
+
+func TestWebhookNotifier_Notify(t *testing.T) {
+	timeRef := time.Date(2022, 9, 1, 0, 0, 0, 0, time.UTC)
+	report := analyser.OutlierReport{
+		SiteId:   "shop",
+		TimeStep: "1d",
+		Result: analyser.OutlierResults{
+			Alarms:   []analyser.OutlierEvent{{OutlierPeriodStart: timeRef, OutlierPeriodEnd: timeRef.AddDate(0, 0, 1), Metric: "Revenue", Attribute: "Total", Score: 4.2}},
+			Warnings: []analyser.OutlierEvent{{OutlierPeriodStart: timeRef, OutlierPeriodEnd: timeRef.AddDate(0, 0, 1), Metric: "Visits", Attribute: "Total"}},
+		},
+		//Shadow events are never posted, so they'd show as unexpected requests
+		ShadowDetectionMethod: "ewma",
+		Shadow: &analyser.OutlierResults{
+			Alarms:   []analyser.OutlierEvent{{OutlierPeriodStart: timeRef, OutlierPeriodEnd: timeRef.AddDate(0, 0, 1), Metric: "Basket", Attribute: "Total"}},
+			Warnings: []analyser.OutlierEvent{{OutlierPeriodStart: timeRef, OutlierPeriodEnd: timeRef.AddDate(0, 0, 1), Metric: "Orders", Attribute: "Total"}},
+		},
+	}
+
+	tests := []struct {
+		name         string
+		statuses     []int
+		warningsUrl  bool
+		wantErr      bool
+		wantRequests map[string]int
+		wantSleeps   []time.Duration
+	}{
+		{
+			name:         "Events routed by severity",
+			statuses:     []int{http.StatusOK},
+			warningsUrl:  true,
+			wantRequests: map[string]int{"/alarms": 1, "/warnings": 1},
+		},
+		{
+			name:         "Warnings dropped without their own URL nor a default one",
+			statuses:     []int{http.StatusOK},
+			wantRequests: map[string]int{"/alarms": 1},
+		},
+		{
+			name:         "Server errors retried with exponential backoff",
+			statuses:     []int{http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusAccepted},
+			wantRequests: map[string]int{"/alarms": 3},
+			wantSleeps:   []time.Duration{time.Second, 2 * time.Second},
+		},
+		{
+			name:         "Retries exhausted",
+			statuses:     []int{http.StatusInternalServerError},
+			wantErr:      true,
+			wantRequests: map[string]int{"/alarms": 4},
+			wantSleeps:   []time.Duration{time.Second, 2 * time.Second, 4 * time.Second},
+		},
+		{
+			name:         "Client errors not retried",
+			statuses:     []int{http.StatusBadRequest},
+			wantErr:      true,
+			wantRequests: map[string]int{"/alarms": 1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var mu sync.Mutex
+			requests := map[string]int{}
+			server := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+				mu.Lock()
+				defer mu.Unlock()
+				body, _ := io.ReadAll(req.Body)
+				if err := VerifySignature("secret", req.Header.Get(SignatureHeader), body, Clock.Now(), 0); err != nil {
+					t.Errorf("Notify() signature error = %v", err)
+				}
+				var event WebhookEvent
+				if err := json.Unmarshal(body, &event); err != nil || event.SiteId != "shop" || event.TimeStep != "1d" {
+					t.Errorf("Notify() posted %s", body)
+				}
+				if req.URL.Path == "/alarms" && (event.Severity != "alarm" || event.Metric != "Revenue" || event.Score != 4.2) {
+					t.Errorf("Notify() posted %s to the alarms URL", body)
+				}
+				status := tt.statuses[len(tt.statuses)-1]
+				if requests[req.URL.Path] < len(tt.statuses) {
+					status = tt.statuses[requests[req.URL.Path]]
+				}
+				requests[req.URL.Path]++
+				res.WriteHeader(status)
+			}))
+			defer server.Close()
+
+			params := config.WebhookParams{AlarmsUrl: server.URL + "/alarms", SigningSecret: "secret"}
+			if tt.warningsUrl {
+				params.WarningsUrl = server.URL + "/warnings"
+			}
+			notifier, err := NewWebhookNotifier(params)
+			if err != nil {
+				t.Fatalf("NewWebhookNotifier() error = %v", err)
+			}
+			sleeps := []time.Duration{}
+			notifier.sleep = func(wait time.Duration) { sleeps = append(sleeps, wait) }
+
+			if err := notifier.Notify(report); (err != nil) != tt.wantErr {
+				t.Errorf("Notify() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(requests) != len(tt.wantRequests) {
+				t.Errorf("Notify() requests = %v, want %v", requests, tt.wantRequests)
+			}
+			for path, want := range tt.wantRequests {
+				if requests[path] != want {
+					t.Errorf("Notify() requests = %v, want %v", requests, tt.wantRequests)
+				}
+			}
+			if len(sleeps) != len(tt.wantSleeps) {
+				t.Fatalf("Notify() slept %v, want %v", sleeps, tt.wantSleeps)
+			}
+			for i := range sleeps {
+				if sleeps[i] != tt.wantSleeps[i] {
+					t.Errorf("Notify() slept %v, want %v", sleeps, tt.wantSleeps)
+				}
+			}
+		})
+	}
+
+	if _, err := NewWebhookNotifier(config.WebhookParams{}); err == nil {
+		t.Errorf("NewWebhookNotifier() expected an error without URL")
+	}
+}
@@ -0,0 +1,76 @@
+package reporting
+
+import (
+	"fmt"
+	"html"
+	"net/http"
+	"time"
+
+	"github.com/ftfmtavares/anomalies-detector/analyser"
+)
+
+//Const block defines how far back the overlaid run is and how far from that time it may have run
+const (
+	weeklyShift     = 7 * 24 * time.Hour
+	weeklyTolerance = 12 * time.Hour
+)
+
+//weeklyHandler implements an HTTP response returning an HTML page overlaying the alarms of the current run with those of the run a week earlier
+//Alarms raised by both runs at the same time of the week are listed as recurring, pointing at chronic weekly issues such as batch jobs, apart from the new and the resolved ones
+//The earlier run is the stored one closest to a week before the current one, its alarms being shifted a week forward
+func weeklyHandler(outlierReports []analyser.OutlierReport, history *analyser.RunHistory) http.HandlerFunc {
+	return func(res http.ResponseWriter, req *http.Request) {
+		res.Header().Set("Content-Type", "text/html; charset=utf-8")
+		res.Write([]byte("<!DOCTYPE html>\n"))
+		res.Write([]byte("<title>Week Over Week</title>\n"))
+		res.Write([]byte("<h1>Week Over Week</h1>\n"))
+		if history == nil {
+			res.Write([]byte("<p>No run history, storage is not configured.</p>\n"))
+			return
+		}
+
+		runTime := analyser.RunTime(outlierReports)
+		previous, previousRunTime, found, err := history.RunNear(runTime.Add(-weeklyShift), weeklyTolerance)
+		if err != nil {
+			http.Error(res, "500 "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if !found {
+			res.Write([]byte(fmt.Sprintf("<p>No run stored around %s to compare with.</p>\n", runTime.Add(-weeklyShift).Format(time.RFC3339))))
+			return
+		}
+
+		overlay := analyser.OverlayRuns(outlierReports, previous, weeklyShift)
+		res.Write([]byte(fmt.Sprintf("<p>Run of %s compared with the run of %s</p>\n", runTime.Format(time.RFC3339), previousRunTime.Format(time.RFC3339))))
+		writeOverlayEvents(res, "Recurring", overlay.Recurring)
+		writeOverlayEvents(res, "New", overlay.New)
+		writeOverlayEvents(res, "Resolved", overlay.Resolved)
+	}
+}
+
+//writeOverlayEvents writes a section listing the given alarms with their current and earlier periods, if any
+func writeOverlayEvents(res http.ResponseWriter, title string, events []analyser.OverlayEvent) {
+	res.Write([]byte(fmt.Sprintf("<h2>%s (%d)</h2>\n", title, len(events))))
+	if len(events) == 0 {
+		return
+	}
+	period := func(start, end time.Time) string {
+		return fmt.Sprintf("%s to %s", start.Format(time.RFC3339), end.Format(time.RFC3339))
+	}
+	res.Write([]byte("<ul>\n"))
+	for _, event := range events {
+		item := fmt.Sprintf("%s - %s - %s", event.SiteId, event.Metric, event.Attribute)
+		if event.Resolution != "" {
+			item = fmt.Sprintf("%s (%s) - %s - %s", event.SiteId, event.Resolution, event.Metric, event.Attribute)
+		}
+		item = html.EscapeString(item)
+		if !event.PeriodStart.IsZero() {
+			item += " - " + period(event.PeriodStart, event.PeriodEnd)
+		}
+		if event.PreviousPeriodStart != nil {
+			item += " - last week " + period(*event.PreviousPeriodStart, *event.PreviousPeriodEnd)
+		}
+		res.Write([]byte(fmt.Sprintf("<li>%s</li>\n", item)))
+	}
+	res.Write([]byte("</ul>\n"))
+}
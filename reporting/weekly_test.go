@@ -0,0 +1,68 @@
+package reporting
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ftfmtavares/anomalies-detector/analyser"
+	"github.com/ftfmtavares/anomalies-detector/config"
+	"github.com/ftfmtavares/anomalies-detector/utils"
+)
+
+func Test_weeklyHandler(t *testing.T) {
+	defer func(clock utils.Clock) { analyser.Clock = clock }(analyser.Clock)
+	runTime := time.Date(2024, 3, 10, 0, 0, 0, 0, time.UTC)
+	sunday := time.Date(2024, 3, 9, 22, 0, 0, 0, time.UTC)
+	report := func(checkDate, start time.Time, metric string) analyser.OutlierReport {
+		return analyser.OutlierReport{SiteId: "shop", CheckDateStart: checkDate, Result: analyser.OutlierResults{
+			Alarms: []analyser.OutlierEvent{{OutlierPeriodStart: start, OutlierPeriodEnd: start.Add(time.Hour), Metric: metric, Attribute: "Total"}},
+		}}
+	}
+
+	history, err := analyser.NewRunHistory(config.StorageParams{Dir: t.TempDir()})
+	if err != nil {
+		t.Fatalf("NewRunHistory() error = %v", err)
+	}
+	analyser.Clock = utils.FixedClock(runTime.AddDate(0, 0, -7).Add(time.Hour))
+	if err := history.SaveRun(runTime.AddDate(0, 0, -7), []analyser.OutlierReport{report(runTime.AddDate(0, 0, -7), sunday.AddDate(0, 0, -7), "Revenue")}); err != nil {
+		t.Fatalf("SaveRun() error = %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		history  *analyser.RunHistory
+		reports  []analyser.OutlierReport
+		wantBody []string
+	}{
+		{
+			name:     "Recurring and new alarms",
+			history:  history,
+			reports:  []analyser.OutlierReport{report(runTime, sunday, "Revenue"), report(runTime, sunday, "Visits")},
+			wantBody: []string{"Recurring (1)", "shop - Revenue - Total - 2024-03-09T22:00:00Z", "last week 2024-03-02T22:00:00Z", "New (1)", "shop - Visits - Total", "Resolved (0)"},
+		},
+		{
+			name:     "No run a week earlier",
+			history:  history,
+			reports:  []analyser.OutlierReport{report(runTime.AddDate(0, 0, 3), sunday, "Revenue")},
+			wantBody: []string{"No run stored around 2024-03-06T00:00:00Z"},
+		},
+		{
+			name:     "No storage",
+			reports:  []analyser.OutlierReport{report(runTime, sunday, "Revenue")},
+			wantBody: []string{"storage is not configured"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := httptest.NewRecorder()
+			weeklyHandler(tt.reports, tt.history)(res, httptest.NewRequest("GET", "/report/weekly", nil))
+			for _, want := range tt.wantBody {
+				if !strings.Contains(res.Body.String(), want) {
+					t.Errorf("weeklyHandler() body = %s, want it to contain %q", res.Body.String(), want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,105 @@
+package reporting
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/ftfmtavares/anomalies-detector/analyser"
+	"github.com/ftfmtavares/anomalies-detector/config"
+
+	"github.com/wcharczuk/go-chart/v2"
+	"github.com/wcharczuk/go-chart/v2/drawing"
+)
+
+//zscoreLimits returns the warning and alarm limits of the z-score view of a site metric, the 3-sigmas multipliers of its detection settings
+//The outliersMultiplier and strongOutliersMultiplier query strings override them, so thresholds can be tuned against the plotted z-scores
+//It returns an error if an override isn't a positive number
+func zscoreLimits(req *http.Request, datasets []config.Dataset, general config.DetectionMethodsParams, siteId, metric string) (float64, float64, error) {
+	dataConf := config.Dataset{SiteId: siteId, OutliersDetectionMethod: "3-sigmas"}
+	for _, dataSet := range datasets {
+		if dataSet.SiteId == siteId {
+			dataConf = dataSet
+			break
+		}
+	}
+	methodParams := dataConf.MethodParams(general)
+	if resolved, resolvedParams, _, err := analyser.ResolveDetectionProfile(dataConf, methodParams); err == nil {
+		dataConf, methodParams = resolved, resolvedParams
+	}
+	if detection, found := dataConf.MetricDetection[metric]; found {
+		_, _, methodParams = detection.Apply(nil, 0, methodParams)
+	}
+	warning, alarm := methodParams.ThreeSigmas.OutliersMultiplier, methodParams.ThreeSigmas.StrongOutliersMultiplier
+
+	var err error
+	if value := req.URL.Query().Get("outliersMultiplier"); value != "" {
+		if warning, err = strconv.ParseFloat(value, 64); err != nil || warning <= 0 {
+			return 0, 0, errors.New("invalid outliersMultiplier parameter, positive number expected")
+		}
+	}
+	if value := req.URL.Query().Get("strongOutliersMultiplier"); value != "" {
+		if alarm, err = strconv.ParseFloat(value, 64); err != nil || alarm <= 0 {
+			return 0, 0, errors.New("invalid strongOutliersMultiplier parameter, positive number expected")
+		}
+	}
+	return warning, alarm, nil
+}
+
+//addZScoreLimits draws the warning and alarm limits on a z-score chart as horizontal dashed lines on both sides of zero, zero limits being left out
+//The vertical axis is made symmetric around zero so drops show as clearly as spikes, with room for the limits and the furthest z-score
+func addZScoreLimits(graph *chart.Chart, warning, alarm float64) {
+	var from, to time.Time
+	top := math.Max(warning, alarm)
+	for _, series := range graph.Series {
+		timeSeries, isTimeSeries := series.(chart.TimeSeries)
+		if !isTimeSeries || timeSeries.Name == "" {
+			continue
+		}
+		for i, date := range timeSeries.XValues {
+			if from.IsZero() || date.Before(from) {
+				from = date
+			}
+			if date.After(to) {
+				to = date
+			}
+			top = math.Max(top, math.Abs(timeSeries.YValues[i]))
+		}
+	}
+	if from.IsZero() {
+		return
+	}
+
+	limits := []struct {
+		name  string
+		value float64
+		color drawing.Color
+	}{
+		{name: "warning limit", value: warning, color: drawing.Color{R: 255, G: 165, B: 0, A: 255}},
+		{name: "alarm limit", value: alarm, color: drawing.Color{R: 255, G: 0, B: 0, A: 255}},
+	}
+	for _, limit := range limits {
+		if limit.value <= 0 {
+			continue
+		}
+		for _, sign := range []float64{1, -1} {
+			name := ""
+			if sign > 0 {
+				name = fmt.Sprintf("%s (±%g)", limit.name, limit.value)
+			}
+			graph.Series = append(graph.Series, chart.TimeSeries{
+				Name:    name,
+				Style:   chart.Style{StrokeColor: limit.color, StrokeWidth: 1, StrokeDashArray: []float64{5, 5}},
+				XValues: []time.Time{from, to},
+				YValues: []float64{sign * limit.value, sign * limit.value},
+			})
+		}
+	}
+	graph.YAxis.Range = &chart.ContinuousRange{Min: -top * 1.2, Max: top * 1.2}
+
+	//The legend is rebuilt to list the limits, the one set by buildChart holding its own copy of the chart
+	graph.Elements = []chart.Renderable{chart.LegendLeft(graph)}
+}
@@ -0,0 +1,533 @@
+package rules
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+//Expression is a compiled rule condition ready to be evaluated against events
+//The language supports string, number and boolean literals, identifiers resolved from the evaluation environment,
+//comparison operators (== != < <= > >=), logical operators (&& || !), parentheses and the functions startsWith, endsWith, contains and matches
+type Expression struct {
+	source string
+	root   node
+}
+
+//Env maps identifiers to their values on a given evaluation
+//Values must be of type string, float64 or bool
+type Env map[string]interface{}
+
+//Compile parses a rule condition and returns the respective Expression
+//Identifiers are checked against the given list of known variables so that typos are reported before any evaluation
+func Compile(source string, variables []string) (*Expression, error) {
+	tokens, err := tokenize(source)
+	if err != nil {
+		return nil, err
+	}
+
+	p := parser{tokens: tokens, variables: map[string]bool{}}
+	for _, variable := range variables {
+		p.variables[variable] = true
+	}
+
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.peek().kind != tokenEOF {
+		return nil, fmt.Errorf("rules: unexpected \"%s\" at position %d", p.peek().text, p.peek().pos)
+	}
+
+	return &Expression{source: source, root: root}, nil
+}
+
+//Eval evaluates the expression against the given environment and returns its boolean result
+func (expr *Expression) Eval(env Env) (bool, error) {
+	val, err := expr.root.eval(env)
+	if err != nil {
+		return false, err
+	}
+	res, ok := val.(bool)
+	if !ok {
+		return false, fmt.Errorf("rules: \"%s\" does not evaluate to a boolean", expr.source)
+	}
+	return res, nil
+}
+
+//String returns the original source of the expression
+func (expr *Expression) String() string {
+	return expr.source
+}
+
+//tokenKind identifies the type of a lexical token
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenIdent
+	tokenString
+	tokenNumber
+	tokenOperator
+	tokenLParen
+	tokenRParen
+	tokenComma
+)
+
+//token holds a single lexical token and its position on the source
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+//tokenize splits the source into tokens
+func tokenize(source string) ([]token, error) {
+	tokens := []token{}
+	i := 0
+	for i < len(source) {
+		c := source[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '(':
+			tokens = append(tokens, token{kind: tokenLParen, text: "(", pos: i})
+			i++
+		case c == ')':
+			tokens = append(tokens, token{kind: tokenRParen, text: ")", pos: i})
+			i++
+		case c == ',':
+			tokens = append(tokens, token{kind: tokenComma, text: ",", pos: i})
+			i++
+		case c == '"':
+			//String literals support backslash escaping of quotes and backslashes
+			var sb strings.Builder
+			j := i + 1
+			for j < len(source) && source[j] != '"' {
+				if source[j] == '\\' && j+1 < len(source) {
+					j++
+				}
+				sb.WriteByte(source[j])
+				j++
+			}
+			if j == len(source) {
+				return nil, fmt.Errorf("rules: unterminated string at position %d", i)
+			}
+			tokens = append(tokens, token{kind: tokenString, text: sb.String(), pos: i})
+			i = j + 1
+		case (c >= '0' && c <= '9') || c == '.':
+			j := i
+			for j < len(source) && ((source[j] >= '0' && source[j] <= '9') || source[j] == '.') {
+				j++
+			}
+			tokens = append(tokens, token{kind: tokenNumber, text: source[i:j], pos: i})
+			i = j
+		case c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z'):
+			j := i
+			for j < len(source) && (source[j] == '_' || (source[j] >= 'a' && source[j] <= 'z') || (source[j] >= 'A' && source[j] <= 'Z') || (source[j] >= '0' && source[j] <= '9')) {
+				j++
+			}
+			tokens = append(tokens, token{kind: tokenIdent, text: source[i:j], pos: i})
+			i = j
+		default:
+			//Operators are matched with 2 characters first and then with a single character
+			if i+1 < len(source) {
+				switch op := source[i : i+2]; op {
+				case "==", "!=", "<=", ">=", "&&", "||":
+					tokens = append(tokens, token{kind: tokenOperator, text: op, pos: i})
+					i += 2
+					continue
+				}
+			}
+			switch c {
+			case '<', '>', '!', '-':
+				tokens = append(tokens, token{kind: tokenOperator, text: string(c), pos: i})
+				i++
+			default:
+				return nil, fmt.Errorf("rules: unexpected character '%c' at position %d", c, i)
+			}
+		}
+	}
+	tokens = append(tokens, token{kind: tokenEOF, pos: len(source)})
+
+	return tokens, nil
+}
+
+//parser implements a recursive descent parser over the token list
+type parser struct {
+	tokens    []token
+	pos       int
+	variables map[string]bool
+}
+
+//peek returns the current token without consuming it
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+//next consumes and returns the current token
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokenEOF {
+		p.pos++
+	}
+	return t
+}
+
+//parseOr parses a sequence of && expressions joined by ||
+func (p *parser) parseOr() (node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().kind == tokenOperator && p.peek().text == "||" {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = logicalNode{op: "||", left: left, right: right}
+	}
+	return left, nil
+}
+
+//parseAnd parses a sequence of unary expressions joined by &&
+func (p *parser) parseAnd() (node, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().kind == tokenOperator && p.peek().text == "&&" {
+		p.next()
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = logicalNode{op: "&&", left: left, right: right}
+	}
+	return left, nil
+}
+
+//parseUnary parses negated expressions or falls back to comparisons
+func (p *parser) parseUnary() (node, error) {
+	if p.peek().kind == tokenOperator && p.peek().text == "!" {
+		p.next()
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return notNode{operand: operand}, nil
+	}
+	return p.parseComparison()
+}
+
+//parseComparison parses an optional comparison between 2 primary expressions
+func (p *parser) parseComparison() (node, error) {
+	left, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind == tokenOperator {
+		switch t.text {
+		case "==", "!=", "<", "<=", ">", ">=":
+			p.next()
+			right, err := p.parsePrimary()
+			if err != nil {
+				return nil, err
+			}
+			return compareNode{op: t.text, left: left, right: right}, nil
+		}
+	}
+	return left, nil
+}
+
+//parsePrimary parses literals, identifiers, function calls and parenthesized expressions
+func (p *parser) parsePrimary() (node, error) {
+	t := p.next()
+	switch t.kind {
+	case tokenString:
+		return literalNode{value: t.text}, nil
+	case tokenNumber:
+		num, err := strconv.ParseFloat(t.text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("rules: invalid number \"%s\" at position %d", t.text, t.pos)
+		}
+		return literalNode{value: num}, nil
+	case tokenOperator:
+		//A leading minus is only allowed on numbers
+		if t.text == "-" && p.peek().kind == tokenNumber {
+			num, err := strconv.ParseFloat(p.next().text, 64)
+			if err != nil {
+				return nil, fmt.Errorf("rules: invalid number at position %d", t.pos)
+			}
+			return literalNode{value: -num}, nil
+		}
+	case tokenLParen:
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.next().kind != tokenRParen {
+			return nil, fmt.Errorf("rules: missing \")\" for \"(\" at position %d", t.pos)
+		}
+		return inner, nil
+	case tokenIdent:
+		switch t.text {
+		case "true":
+			return literalNode{value: true}, nil
+		case "false":
+			return literalNode{value: false}, nil
+		}
+		if p.peek().kind == tokenLParen {
+			return p.parseCall(t)
+		}
+		if !p.variables[t.text] {
+			return nil, fmt.Errorf("rules: unknown identifier \"%s\" at position %d", t.text, t.pos)
+		}
+		return identNode{name: t.text}, nil
+	}
+
+	if t.kind == tokenEOF {
+		return nil, fmt.Errorf("rules: unexpected end of expression")
+	}
+	return nil, fmt.Errorf("rules: unexpected \"%s\" at position %d", t.text, t.pos)
+}
+
+//parseCall parses the arguments of a function call, checking the function exists and its arity
+func (p *parser) parseCall(name token) (node, error) {
+	function, present := functions[name.text]
+	if !present {
+		return nil, fmt.Errorf("rules: unknown function \"%s\" at position %d", name.text, name.pos)
+	}
+	p.next()
+
+	args := []node{}
+	if p.peek().kind != tokenRParen {
+		for {
+			arg, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, arg)
+			if p.peek().kind != tokenComma {
+				break
+			}
+			p.next()
+		}
+	}
+	if p.next().kind != tokenRParen {
+		return nil, fmt.Errorf("rules: missing \")\" for function \"%s\" at position %d", name.text, name.pos)
+	}
+	if len(args) != 2 {
+		return nil, fmt.Errorf("rules: function \"%s\" expects 2 arguments, got %d", name.text, len(args))
+	}
+
+	//Regular expressions given as literals are compiled once at this stage
+	if name.text == "matches" {
+		if lit, ok := args[1].(literalNode); ok {
+			pattern, ok := lit.value.(string)
+			if !ok {
+				return nil, fmt.Errorf("rules: function \"matches\" expects a string pattern")
+			}
+			re, err := regexp.Compile(pattern)
+			if err != nil {
+				return nil, fmt.Errorf("rules: invalid pattern \"%s\": %s", pattern, err.Error())
+			}
+			return callNode{name: name.text, args: args, function: function, re: re}, nil
+		}
+	}
+
+	return callNode{name: name.text, args: args, function: function}, nil
+}
+
+//node is implemented by every element of the parsed expression tree
+type node interface {
+	eval(env Env) (interface{}, error)
+}
+
+//literalNode holds a constant value
+type literalNode struct {
+	value interface{}
+}
+
+func (n literalNode) eval(env Env) (interface{}, error) {
+	return n.value, nil
+}
+
+//identNode resolves a variable from the environment
+type identNode struct {
+	name string
+}
+
+func (n identNode) eval(env Env) (interface{}, error) {
+	val, present := env[n.name]
+	if !present {
+		return nil, fmt.Errorf("rules: variable \"%s\" not available", n.name)
+	}
+	if i, ok := val.(int); ok {
+		val = float64(i)
+	}
+	return val, nil
+}
+
+//notNode negates a boolean operand
+type notNode struct {
+	operand node
+}
+
+func (n notNode) eval(env Env) (interface{}, error) {
+	val, err := n.operand.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	b, ok := val.(bool)
+	if !ok {
+		return nil, fmt.Errorf("rules: \"!\" expects a boolean operand")
+	}
+	return !b, nil
+}
+
+//logicalNode implements the && and || operators with short-circuit evaluation
+type logicalNode struct {
+	op    string
+	left  node
+	right node
+}
+
+func (n logicalNode) eval(env Env) (interface{}, error) {
+	leftVal, err := n.left.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	left, ok := leftVal.(bool)
+	if !ok {
+		return nil, fmt.Errorf("rules: \"%s\" expects boolean operands", n.op)
+	}
+	if (n.op == "&&" && !left) || (n.op == "||" && left) {
+		return left, nil
+	}
+
+	rightVal, err := n.right.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	right, ok := rightVal.(bool)
+	if !ok {
+		return nil, fmt.Errorf("rules: \"%s\" expects boolean operands", n.op)
+	}
+	return right, nil
+}
+
+//compareNode implements comparison operators between values of the same type
+type compareNode struct {
+	op    string
+	left  node
+	right node
+}
+
+func (n compareNode) eval(env Env) (interface{}, error) {
+	left, err := n.left.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	right, err := n.right.eval(env)
+	if err != nil {
+		return nil, err
+	}
+
+	switch l := left.(type) {
+	case float64:
+		if r, ok := right.(float64); ok {
+			switch n.op {
+			case "==":
+				return l == r, nil
+			case "!=":
+				return l != r, nil
+			case "<":
+				return l < r, nil
+			case "<=":
+				return l <= r, nil
+			case ">":
+				return l > r, nil
+			case ">=":
+				return l >= r, nil
+			}
+		}
+	case string:
+		if r, ok := right.(string); ok {
+			switch n.op {
+			case "==":
+				return l == r, nil
+			case "!=":
+				return l != r, nil
+			case "<":
+				return l < r, nil
+			case "<=":
+				return l <= r, nil
+			case ">":
+				return l > r, nil
+			case ">=":
+				return l >= r, nil
+			}
+		}
+	case bool:
+		if r, ok := right.(bool); ok {
+			switch n.op {
+			case "==":
+				return l == r, nil
+			case "!=":
+				return l != r, nil
+			}
+		}
+	}
+
+	return nil, fmt.Errorf("rules: cannot compare %v %s %v", left, n.op, right)
+}
+
+//callNode implements a call to one of the built-in functions
+type callNode struct {
+	name     string
+	args     []node
+	function func(s, arg string, re *regexp.Regexp) (bool, error)
+	re       *regexp.Regexp
+}
+
+func (n callNode) eval(env Env) (interface{}, error) {
+	strArgs := make([]string, len(n.args))
+	for i, arg := range n.args {
+		val, err := arg.eval(env)
+		if err != nil {
+			return nil, err
+		}
+		s, ok := val.(string)
+		if !ok {
+			return nil, fmt.Errorf("rules: function \"%s\" expects string arguments", n.name)
+		}
+		strArgs[i] = s
+	}
+	return n.function(strArgs[0], strArgs[1], n.re)
+}
+
+//functions maps the built-in function names to their implementations
+//All functions take 2 string arguments, the precompiled regular expression being only used by matches
+var functions = map[string]func(s, arg string, re *regexp.Regexp) (bool, error){
+	"startsWith": func(s, arg string, re *regexp.Regexp) (bool, error) {
+		return strings.HasPrefix(s, arg), nil
+	},
+	"endsWith": func(s, arg string, re *regexp.Regexp) (bool, error) {
+		return strings.HasSuffix(s, arg), nil
+	},
+	"contains": func(s, arg string, re *regexp.Regexp) (bool, error) {
+		return strings.Contains(s, arg), nil
+	},
+	"matches": func(s, arg string, re *regexp.Regexp) (bool, error) {
+		if re == nil {
+			var err error
+			if re, err = regexp.Compile(arg); err != nil {
+				return false, fmt.Errorf("rules: invalid pattern \"%s\": %s", arg, err.Error())
+			}
+		}
+		return re.MatchString(s), nil
+	},
+}
@@ -0,0 +1,75 @@
+package rules
+
+import (
+	"testing"
+)
+
+func TestExpression_Eval(t *testing.T) {
+	variables := []string{"metric", "attribute", "severity", "alarm", "deviationPct"}
+	env := Env{
+		"metric":       "Revenue",
+		"attribute":    "Browser>Chrome>v3",
+		"severity":     2.0,
+		"alarm":        2.0,
+		"deviationPct": -35.5,
+	}
+
+	tests := []struct {
+		name    string
+		source  string
+		want    bool
+		wantErr bool
+	}{
+		{name: "String equality", source: `metric == "Revenue"`, want: true},
+		{name: "String inequality", source: `metric != "Revenue"`, want: false},
+		{name: "Numeric comparison against variable", source: `severity >= alarm`, want: true},
+		{name: "Negative number literal", source: `deviationPct < -20`, want: true},
+		{name: "Combined conditions", source: `metric == "Revenue" && severity >= alarm && deviationPct < -20`, want: true},
+		{name: "Or with parentheses and not", source: `!(metric == "Basket" || metric == "Visits")`, want: true},
+		{name: "Function startsWith", source: `startsWith(attribute, "Browser>")`, want: true},
+		{name: "Function matches", source: `matches(attribute, "v[12]$")`, want: false},
+		{name: "Short-circuit skips type errors", source: `metric == "Basket" && metric > 1`, want: false},
+		{name: "Type mismatch", source: `metric > 1`, wantErr: true},
+		{name: "Non boolean result", source: `metric`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expr, err := Compile(tt.source, variables)
+			if err != nil {
+				t.Fatalf("Compile() error = %v", err)
+			}
+			got, err := expr.Eval(env)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Eval() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Eval() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompile_Errors(t *testing.T) {
+	variables := []string{"metric"}
+
+	tests := []struct {
+		name   string
+		source string
+	}{
+		{name: "Unknown identifier", source: `metrc == "Revenue"`},
+		{name: "Unknown function", source: `hasPrefix(metric, "R")`},
+		{name: "Wrong arity", source: `contains(metric)`},
+		{name: "Unterminated string", source: `metric == "Revenue`},
+		{name: "Missing parenthesis", source: `(metric == "Revenue"`},
+		{name: "Trailing tokens", source: `metric == "Revenue" "Basket"`},
+		{name: "Invalid pattern", source: `matches(metric, "[")`},
+		{name: "Empty expression", source: ``},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Compile(tt.source, variables); err == nil {
+				t.Errorf("Compile(%q) expected an error", tt.source)
+			}
+		})
+	}
+}
@@ -0,0 +1,179 @@
+//go:build soak
+
+package anomaliesdetector
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"runtime/pprof"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ftfmtavares/anomalies-detector/analyser"
+	"github.com/ftfmtavares/anomalies-detector/collector"
+	"github.com/ftfmtavares/anomalies-detector/config"
+	"github.com/ftfmtavares/anomalies-detector/utils"
+)
+
+//Soak test settings, meant to be raised for longer runs, e.g. go test -tags soak -run TestSoak -timeout 1h . -soak.sites 1000 -soak.runs 200
+var (
+	soakSites         = flag.Int("soak.sites", 200, "number of synthetic sites driven by the soak test")
+	soakRuns          = flag.Int("soak.runs", 20, "number of scheduled runs of the soak test, each one advancing the clock by a time step")
+	soakInterval      = flag.Duration("soak.interval", 100*time.Millisecond, "wall time between the scheduled runs of the soak test")
+	soakStreamEvents  = flag.Int("soak.stream-events", 200, "number of raw events streamed to every site between runs")
+	soakHeapMB        = flag.Int("soak.heap-mb", 512, "ceiling of the live heap after every run, in MB")
+	soakGoroutines    = flag.Int("soak.goroutines", 10, "goroutines allowed above the starting count once the soak test is over")
+	soakMinThroughput = flag.Float64("soak.min-throughput", 10000, "minimum raw events buffered per second")
+)
+
+//TestSoak drives the long running paths of the daemon with many synthetic sites at an accelerated schedule:
+//scheduled runs collecting, analysing and notifying every site, the report server answering over the latest run and the stream buffers analysed between runs
+//It fails if the live heap goes over its ceiling after any run, if goroutines are left running once everything is stopped or if streamed events are buffered too slowly
+func TestSoak(t *testing.T) {
+	defer func(clock utils.Clock) { collector.Clock = clock }(collector.Clock)
+	timeRef := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	step := time.Hour
+
+	dataSets := []config.Dataset{}
+	for i := 0; i < *soakSites; i++ {
+		dataSets = append(dataSets, config.Dataset{SiteId: fmt.Sprintf("site%04d", i), TimeAgo: "7d", TimeStep: "1h", OutliersDetectionMethod: "3-sigmas", MetricesList: []string{"all"}})
+	}
+	appConfig := config.ApplicationConfig{
+		Datasets:         dataSets,
+		DetectionMethods: config.DetectionMethodsParams{ThreeSigmas: config.ThreeSigmasParams{OutliersMultiplier: 2, StrongOutliersMultiplier: 3}},
+		ReportServer:     config.ReportServerParams{RateLimit: config.RateLimitParams{RequestsPerMinute: 1e9, Burst: 1e6}},
+		Seed:             1,
+	}
+
+	runtime.GC()
+	startGoroutines := runtime.NumGoroutine()
+
+	detector, err := New(appConfig)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	var notifiedEvents int64
+	detector.Notify = func(report analyser.OutlierReport) {
+		atomic.AddInt64(&notifiedEvents, int64(len(report.Result.Alarms)+len(report.Result.Warnings)))
+	}
+
+	//Serving the latest run as the daemon does, the handler being swapped after every run while clients keep polling
+	var handlerMu sync.RWMutex
+	handler := http.NotFoundHandler()
+	server := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		handlerMu.RLock()
+		served := handler
+		handlerMu.RUnlock()
+		served.ServeHTTP(res, req)
+	}))
+	ctx, cancel := context.WithCancel(context.Background())
+	var clients sync.WaitGroup
+	//Busy chart renders are answered with a 503 status by design, so only other server errors count as failures
+	var requests, failedRequests int64
+	for i := 0; i < 4; i++ {
+		clients.Add(1)
+		go func(client int) {
+			defer clients.Done()
+			paths := []string{"/api/v1/sites", "/api/v1/alarms?limit=50", "/api/catalog", fmt.Sprintf("/report/site%04d/Revenue", client)}
+			for n := 0; ctx.Err() == nil; n++ {
+				resp, err := server.Client().Get(server.URL + paths[n%len(paths)])
+				if err != nil {
+					continue
+				}
+				io.Copy(io.Discard, resp.Body)
+				resp.Body.Close()
+				atomic.AddInt64(&requests, 1)
+				if resp.StatusCode >= http.StatusInternalServerError && resp.StatusCode != http.StatusServiceUnavailable {
+					atomic.AddInt64(&failedRequests, 1)
+				}
+			}
+		}(i)
+	}
+
+	//Every site also streams raw events, buffered over the analysed period and analysed between runs
+	buffers := make([]*collector.StreamBuffer, len(dataSets))
+	for i := range buffers {
+		if buffers[i], err = collector.NewStreamBuffer(config.KafkaParams{Metric: "Revenue", ValueField: "value", Attributes: map[string][]string{"Browser": {"browser"}}}, step, 7*24*time.Hour); err != nil {
+			t.Fatalf("NewStreamBuffer() error = %v", err)
+		}
+	}
+	randGen := rand.New(rand.NewSource(1))
+	browsers := []string{"Chrome", "Edge", "Firefox", "Safari"}
+	var streamedEvents int64
+	var streamDuration time.Duration
+
+	peakHeap := uint64(0)
+	ticker := time.NewTicker(*soakInterval)
+	defer ticker.Stop()
+	for run := 0; run < *soakRuns; run++ {
+		now := timeRef.Add(time.Duration(run) * step)
+		collector.Clock = utils.FixedClock(now)
+		if err := detector.Run(context.Background()); err != nil {
+			t.Fatalf("Run() #%d error = %v", run+1, err)
+		}
+		handlerMu.Lock()
+		handler = detector.Report()
+		handlerMu.Unlock()
+
+		//Streaming the events of the time step to every site and analysing their buffers as the stream workers do
+		started := time.Now()
+		for i, buffer := range buffers {
+			for n := 0; n < *soakStreamEvents; n++ {
+				message := fmt.Sprintf(`{"value": %.2f, "browser": "%s"}`, 100+randGen.NormFloat64()*10, browsers[randGen.Intn(len(browsers))])
+				if err := buffer.Add([]byte(message), now.Add(time.Duration(randGen.Int63n(int64(step))))); err != nil {
+					t.Fatalf("Add() error = %v", err)
+				}
+			}
+			siteData := buffer.Snapshot(dataSets[i], now.Add(step))
+			analyser.GetResults(siteData, dataSets[i], dataSets[i].MethodParams(appConfig.DetectionMethods))
+		}
+		streamDuration += time.Since(started)
+		streamedEvents += int64(len(buffers) * *soakStreamEvents)
+
+		//Measuring the live heap once garbage is collected, which must stay bounded however many runs are served
+		runtime.GC()
+		var mem runtime.MemStats
+		runtime.ReadMemStats(&mem)
+		if mem.HeapAlloc > peakHeap {
+			peakHeap = mem.HeapAlloc
+		}
+		if mem.HeapAlloc > uint64(*soakHeapMB)<<20 {
+			t.Fatalf("run #%d - live heap %d MB over the %d MB ceiling", run+1, mem.HeapAlloc>>20, *soakHeapMB)
+		}
+		<-ticker.C
+	}
+
+	//Stopping the clients and the server, after which only the goroutines running before the test may be left
+	cancel()
+	clients.Wait()
+	server.Close()
+	server.Client().CloseIdleConnections()
+	deadline := time.Now().Add(15 * time.Second)
+	for runtime.NumGoroutine() > startGoroutines+*soakGoroutines && time.Now().Before(deadline) {
+		time.Sleep(50 * time.Millisecond)
+	}
+	if goroutines := runtime.NumGoroutine(); goroutines > startGoroutines+*soakGoroutines {
+		var dump bytes.Buffer
+		pprof.Lookup("goroutine").WriteTo(&dump, 1)
+		t.Errorf("%d goroutines left running, %d at the start\n%s", goroutines, startGoroutines, dump.String())
+	}
+
+	throughput := float64(streamedEvents) / streamDuration.Seconds()
+	if throughput < *soakMinThroughput {
+		t.Errorf("stream throughput %.0f events/s, below the %.0f events/s minimum", throughput, *soakMinThroughput)
+	}
+	if failedRequests > 0 {
+		t.Errorf("%d of %d report server requests failed", failedRequests, requests)
+	}
+	t.Logf("%d sites, %d runs - peak live heap %d MB - %d events notified - %d report server requests - stream throughput %.0f events/s",
+		*soakSites, *soakRuns, peakHeap>>20, notifiedEvents, requests, throughput)
+}
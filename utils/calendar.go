@@ -0,0 +1,104 @@
+package utils
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//Const block defines the time steps moving by calendar days and weeks instead of a fixed duration, and the suffix of those given as calendar ones
+const (
+	calendarDay    = 24 * time.Hour
+	calendarWeek   = 7 * calendarDay
+	calendarSuffix = "-calendar"
+)
+
+//TimeStep provides the structure for a time step moving either by a fixed duration or by calendar days, weeks or months
+//Duration field is the length of the time step, nominal (30 days a month) for monthly ones
+//Months field is the number of months of monthly time steps ("1mo"), 0 for any other
+//Calendar field tells the time steps are aligned to the start of the calendar day, week (Monday) or month in the location of the times, as
+//"1d-calendar", "1w-calendar" and monthly ones are, instead of rolling from the current time
+type TimeStep struct {
+	Duration time.Duration
+	Months   int
+	Calendar bool
+}
+
+//ParseTimeStep reads a time step in the StrToDuration format, either a duration, a calendar one of whole days or weeks (e.g. "1w-calendar") or whole months (e.g. "1mo")
+//It returns an error if the format is invalid or if months are combined with other units
+func ParseTimeStep(value string) (TimeStep, error) {
+	duration, err := StrToDuration(value)
+	if err != nil {
+		return TimeStep{}, err
+	}
+	if strings.Contains(value, "mo") {
+		months, err := strconv.Atoi(strings.TrimSuffix(strings.TrimSuffix(value, calendarSuffix), "mo"))
+		if err != nil || months <= 0 {
+			return TimeStep{}, fmt.Errorf("time: invalid time step \"%s\", months can't be combined with other units", value)
+		}
+		return TimeStep{Duration: duration, Months: months, Calendar: true}, nil
+	}
+	return TimeStep{Duration: duration, Calendar: strings.HasSuffix(value, calendarSuffix)}, nil
+}
+
+//Next returns the time n time steps after t (before it if n is negative), calendar days and months moving in the location of t
+func (step TimeStep) Next(t time.Time, n int) time.Time {
+	switch {
+	case step.Months > 0:
+		return t.AddDate(0, n*step.Months, 0)
+	case step.Calendar:
+		return CalendarStep(t, step.Duration, n)
+	}
+	return t.Add(time.Duration(n) * step.Duration)
+}
+
+//Align returns the start of the calendar day, week or month holding t in its location for calendar time steps, t itself for any other
+func (step TimeStep) Align(t time.Time) time.Time {
+	switch {
+	case step.Months > 0:
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, t.Location())
+	case step.Calendar:
+		return CalendarAlign(t, step.Duration)
+	}
+	return t
+}
+
+//Index returns the number of whole time steps from start to t, i.e. the time step of a period starting at start that holds t
+func (step TimeStep) Index(start, t time.Time) int {
+	n := int(t.Sub(start) / step.Duration)
+	if !step.Calendar {
+		return n
+	}
+
+	//Calendar time steps vary in length, so the nominal estimate is adjusted to the actual boundaries
+	for step.Next(start, n).After(t) {
+		n--
+	}
+	for !step.Next(start, n+1).After(t) {
+		n++
+	}
+	return n
+}
+
+//CalendarStep returns the time n time steps after t (before it if n is negative)
+//Time steps of whole days move by calendar days in the location of t, so daily steps keep their time of day across daylight saving changes
+func CalendarStep(t time.Time, step time.Duration, n int) time.Time {
+	if step > 0 && step%calendarDay == 0 {
+		return t.AddDate(0, 0, n*int(step/calendarDay))
+	}
+	return t.Add(time.Duration(n) * step)
+}
+
+//CalendarAlign returns the start of the calendar period holding t in its location, midnight for time steps of whole days and Monday midnight for those of whole weeks
+//Any other time step leaves t unchanged
+func CalendarAlign(t time.Time, step time.Duration) time.Time {
+	if step <= 0 || step%calendarDay != 0 {
+		return t
+	}
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	if step%calendarWeek == 0 {
+		day = day.AddDate(0, 0, -(int(day.Weekday())+6)%7)
+	}
+	return day
+}
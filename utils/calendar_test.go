@@ -0,0 +1,129 @@
+package utils
+
+import (
+	"testing"
+	"time"
+	_ "time/tzdata"
+)
+
+func TestCalendarStep(t *testing.T) {
+	lisbon, err := time.LoadLocation("Europe/Lisbon")
+	if err != nil {
+		t.Fatal(err)
+	}
+	//Clocks went forward on the 27th of March 2022 in Lisbon, that day lasting 23 hours
+	before := time.Date(2022, 3, 26, 0, 0, 0, 0, lisbon)
+
+	tests := []struct {
+		name string
+		step time.Duration
+		n    int
+		want time.Time
+	}{
+		{name: "Daily step across daylight saving", step: 24 * time.Hour, n: 2, want: time.Date(2022, 3, 28, 0, 0, 0, 0, lisbon)},
+		{name: "Daily step backwards", step: 24 * time.Hour, n: -2, want: time.Date(2022, 3, 24, 0, 0, 0, 0, lisbon)},
+		{name: "Weekly step", step: 7 * 24 * time.Hour, n: 1, want: time.Date(2022, 4, 2, 0, 0, 0, 0, lisbon)},
+		{name: "Hourly step keeps the duration", step: time.Hour, n: 48, want: time.Date(2022, 3, 28, 1, 0, 0, 0, lisbon)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CalendarStep(before, tt.step, tt.n); !got.Equal(tt.want) {
+				t.Errorf("CalendarStep() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCalendarAlign(t *testing.T) {
+	lisbon, err := time.LoadLocation("Europe/Lisbon")
+	if err != nil {
+		t.Fatal(err)
+	}
+	//A Wednesday afternoon
+	value := time.Date(2022, 3, 30, 15, 20, 0, 0, lisbon)
+
+	tests := []struct {
+		name string
+		step time.Duration
+		want time.Time
+	}{
+		{name: "Daily step", step: 24 * time.Hour, want: time.Date(2022, 3, 30, 0, 0, 0, 0, lisbon)},
+		{name: "Weekly step", step: 7 * 24 * time.Hour, want: time.Date(2022, 3, 28, 0, 0, 0, 0, lisbon)},
+		{name: "Hourly step", step: time.Hour, want: value},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CalendarAlign(value, tt.step); !got.Equal(tt.want) {
+				t.Errorf("CalendarAlign() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseTimeStep(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		want    TimeStep
+		wantErr bool
+	}{
+		{name: "Rolling day", value: "1d", want: TimeStep{Duration: 24 * time.Hour}},
+		{name: "Calendar day", value: "1d-calendar", want: TimeStep{Duration: 24 * time.Hour, Calendar: true}},
+		{name: "Calendar week", value: "1w-calendar", want: TimeStep{Duration: 7 * 24 * time.Hour, Calendar: true}},
+		{name: "Month", value: "1mo", want: TimeStep{Duration: 30 * 24 * time.Hour, Months: 1, Calendar: true}},
+		{name: "Quarter", value: "3mo", want: TimeStep{Duration: 90 * 24 * time.Hour, Months: 3, Calendar: true}},
+		{name: "Milliseconds", value: "500ms", want: TimeStep{Duration: 500 * time.Millisecond}},
+		{name: "Calendar hours", value: "6h-calendar", wantErr: true},
+		{name: "Months with days", value: "1mo2d", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseTimeStep(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseTimeStep() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseTimeStep() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTimeStep(t *testing.T) {
+	lisbon, err := time.LoadLocation("Europe/Lisbon")
+	if err != nil {
+		t.Fatal(err)
+	}
+	value := time.Date(2022, 3, 30, 15, 20, 0, 0, lisbon)
+	start := time.Date(2022, 1, 1, 0, 0, 0, 0, lisbon)
+
+	tests := []struct {
+		name      string
+		value     string
+		wantAlign time.Time
+		wantNext  time.Time
+		wantIndex int
+	}{
+		{name: "Rolling day", value: "1d", wantAlign: value, wantNext: value.Add(24 * time.Hour), wantIndex: 88},
+		{name: "Calendar day", value: "1d-calendar", wantAlign: time.Date(2022, 3, 30, 0, 0, 0, 0, lisbon), wantNext: time.Date(2022, 3, 31, 15, 20, 0, 0, lisbon), wantIndex: 88},
+		{name: "Calendar week", value: "1w-calendar", wantAlign: time.Date(2022, 3, 28, 0, 0, 0, 0, lisbon), wantNext: time.Date(2022, 4, 6, 15, 20, 0, 0, lisbon), wantIndex: 12},
+		{name: "Month", value: "1mo", wantAlign: time.Date(2022, 3, 1, 0, 0, 0, 0, lisbon), wantNext: time.Date(2022, 4, 30, 15, 20, 0, 0, lisbon), wantIndex: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			step, err := ParseTimeStep(tt.value)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := step.Align(value); !got.Equal(tt.wantAlign) {
+				t.Errorf("TimeStep.Align() = %v, want %v", got, tt.wantAlign)
+			}
+			if got := step.Next(value, 1); !got.Equal(tt.wantNext) {
+				t.Errorf("TimeStep.Next() = %v, want %v", got, tt.wantNext)
+			}
+			if got := step.Index(start, value); got != tt.wantIndex {
+				t.Errorf("TimeStep.Index() = %d, want %d", got, tt.wantIndex)
+			}
+		})
+	}
+}
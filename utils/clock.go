@@ -0,0 +1,57 @@
+package utils
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+)
+
+//Clock provides the current time to the collector, analyser and reporting packages
+//Tests replace the system clock with a FixedClock so they don't depend on time.Now
+type Clock interface {
+	Now() time.Time
+}
+
+//SystemClock is the Clock reading the system time, used unless replaced
+var SystemClock Clock = systemClock{}
+
+//systemClock implements Clock with time.Now
+type systemClock struct{}
+
+//Now returns the current system time
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+//FixedClock is a Clock always returning the same time
+type FixedClock time.Time
+
+//Now returns the fixed time
+func (clock FixedClock) Now() time.Time {
+	return time.Time(clock)
+}
+
+//lockedSource wraps a rand.Source so it can be shared by several goroutines, as plain sources aren't safe for concurrent use
+type lockedSource struct {
+	mu     sync.Mutex
+	source rand.Source
+}
+
+//NewLockedSource returns a rand.Source drawing from the given one under a lock, so datasets collected in parallel can share it
+func NewLockedSource(source rand.Source) rand.Source {
+	return &lockedSource{source: source}
+}
+
+//Int63 returns the next value of the wrapped source
+func (locked *lockedSource) Int63() int64 {
+	locked.mu.Lock()
+	defer locked.mu.Unlock()
+	return locked.source.Int63()
+}
+
+//Seed reseeds the wrapped source
+func (locked *lockedSource) Seed(seed int64) {
+	locked.mu.Lock()
+	defer locked.mu.Unlock()
+	locked.source.Seed(seed)
+}
@@ -0,0 +1,51 @@
+package utils
+
+import (
+	"math/rand"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestFixedClock(t *testing.T) {
+	now := time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC)
+	var clock Clock = FixedClock(now)
+	if got := clock.Now(); !got.Equal(now) {
+		t.Errorf("FixedClock.Now() = %v, want %v", got, now)
+	}
+}
+
+func TestNewLockedSource(t *testing.T) {
+	expected := map[int64]bool{}
+	reference := rand.NewSource(7)
+	for i := 0; i < 400; i++ {
+		expected[reference.Int63()] = true
+	}
+
+	//Drawing the same values from several goroutines, in any order
+	source := NewLockedSource(rand.NewSource(7))
+	values := make(chan int64, 400)
+	var wg sync.WaitGroup
+	for worker := 0; worker < 4; worker++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				values <- source.Int63()
+			}
+		}()
+	}
+	wg.Wait()
+	close(values)
+	for value := range values {
+		if !expected[value] {
+			t.Fatalf("NewLockedSource() drew %d, not part of the seeded sequence", value)
+		}
+		delete(expected, value)
+	}
+
+	source.Seed(7)
+	if got, want := source.Int63(), rand.NewSource(7).Int63(); got != want {
+		t.Errorf("NewLockedSource() after Seed = %d, want %d", got, want)
+	}
+}
@@ -0,0 +1,118 @@
+package utils
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+//encryptionAlgorithm identifies the algorithm used on EncryptedRecord
+const encryptionAlgorithm = "AES-256-GCM"
+
+//EncryptedRecord provides the structure to store an encrypted JSON value of a given site
+//The site id is kept in clear and authenticated as additional data, so records can be identified without decrypting them
+type EncryptedRecord struct {
+	SiteId     string `json:"siteId"`
+	Algorithm  string `json:"algorithm"`
+	Nonce      string `json:"nonce"`
+	Ciphertext string `json:"ciphertext"`
+}
+
+//LoadEncryptionKey reads a 256 bits key from a reference, either "env:VAR" for an environment variable or "file:path" for a key file
+//Keys must be base64 or hex encoded, which allows KMS managed keys to be injected by the deployment through any of those sources
+func LoadEncryptionKey(ref string) ([]byte, error) {
+	var encoded string
+	switch {
+	case strings.HasPrefix(ref, "env:"):
+		encoded = ResolveSecret(ref)
+		if encoded == "" {
+			return nil, fmt.Errorf("encryption key variable %s not set", strings.TrimPrefix(ref, "env:"))
+		}
+	case strings.HasPrefix(ref, "file:"):
+		content, err := os.ReadFile(strings.TrimPrefix(ref, "file:"))
+		if err != nil {
+			return nil, err
+		}
+		encoded = string(content)
+	default:
+		return nil, errors.New("encryption key must be referenced as \"env:VAR\" or \"file:path\"")
+	}
+	encoded = strings.TrimSpace(encoded)
+
+	key, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(key) != 32 {
+		key, err = hex.DecodeString(encoded)
+	}
+	if err != nil || len(key) != 32 {
+		return nil, errors.New("encryption key must be 32 bytes encoded in base64 or hex")
+	}
+
+	return key, nil
+}
+
+//EncryptRecord marshals any given variable in JSON format and encrypts it with AES-256-GCM
+func EncryptRecord(siteId string, v interface{}, key []byte) (EncryptedRecord, error) {
+	plaintext, err := json.Marshal(v)
+	if err != nil {
+		return EncryptedRecord{}, err
+	}
+
+	gcm, err := newGcm(key)
+	if err != nil {
+		return EncryptedRecord{}, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return EncryptedRecord{}, err
+	}
+
+	return EncryptedRecord{
+		SiteId:     siteId,
+		Algorithm:  encryptionAlgorithm,
+		Nonce:      base64.StdEncoding.EncodeToString(nonce),
+		Ciphertext: base64.StdEncoding.EncodeToString(gcm.Seal(nil, nonce, plaintext, []byte(siteId))),
+	}, nil
+}
+
+//DecryptRecord decrypts an EncryptedRecord and unmarshals its JSON content into the given variable
+//It returns an error if the key is wrong or if the record, including its site id, was tampered with
+func DecryptRecord(record EncryptedRecord, key []byte, v interface{}) error {
+	if record.Algorithm != encryptionAlgorithm {
+		return fmt.Errorf("unsupported encryption algorithm \"%s\"", record.Algorithm)
+	}
+
+	gcm, err := newGcm(key)
+	if err != nil {
+		return err
+	}
+	nonce, err := base64.StdEncoding.DecodeString(record.Nonce)
+	if err != nil || len(nonce) != gcm.NonceSize() {
+		return errors.New("invalid encryption nonce")
+	}
+	ciphertext, err := base64.StdEncoding.DecodeString(record.Ciphertext)
+	if err != nil {
+		return errors.New("invalid ciphertext")
+	}
+
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, []byte(record.SiteId))
+	if err != nil {
+		return errors.New("decryption failed")
+	}
+	return json.Unmarshal(plaintext, v)
+}
+
+//newGcm creates the AES-GCM cipher for a given key
+func newGcm(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
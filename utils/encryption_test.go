@@ -0,0 +1,73 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/base64"
+	"reflect"
+	"testing"
+)
+
+func TestEncryptRecord(t *testing.T) {
+	key := bytes.Repeat([]byte{1}, 32)
+	otherKey := bytes.Repeat([]byte{2}, 32)
+	value := map[string]float64{"Revenue": 100000}
+
+	record, err := EncryptRecord("site", value, key)
+	if err != nil {
+		t.Fatalf("EncryptRecord() error = %v", err)
+	}
+
+	tampered := record
+	tampered.SiteId = "other"
+
+	tests := []struct {
+		name    string
+		record  EncryptedRecord
+		key     []byte
+		wantErr bool
+	}{
+		{name: "Round trip", record: record, key: key},
+		{name: "Wrong key", record: record, key: otherKey, wantErr: true},
+		{name: "Tampered site id", record: tampered, key: key, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := map[string]float64{}
+			err := DecryptRecord(tt.record, tt.key, &got)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("DecryptRecord() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, value) {
+				t.Errorf("DecryptRecord() = %v, want %v", got, value)
+			}
+		})
+	}
+}
+
+func TestLoadEncryptionKey(t *testing.T) {
+	key := bytes.Repeat([]byte{1}, 32)
+	t.Setenv("TEST_KEY_BASE64", base64.StdEncoding.EncodeToString(key))
+	t.Setenv("TEST_KEY_SHORT", base64.StdEncoding.EncodeToString(key[:16]))
+
+	tests := []struct {
+		name    string
+		ref     string
+		wantErr bool
+	}{
+		{name: "Base64 key from environment", ref: "env:TEST_KEY_BASE64"},
+		{name: "Short key", ref: "env:TEST_KEY_SHORT", wantErr: true},
+		{name: "Unset variable", ref: "env:TEST_KEY_UNSET", wantErr: true},
+		{name: "Literal keys are not accepted", ref: base64.StdEncoding.EncodeToString(key), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := LoadEncryptionKey(tt.ref)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("LoadEncryptionKey() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !bytes.Equal(got, key) {
+				t.Errorf("LoadEncryptionKey() = %v, want %v", got, key)
+			}
+		})
+	}
+}
@@ -0,0 +1,198 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+//Files is the policy every output, state and storage file is written with, keeping the process umask and owner by default
+//The application replaces it with the configured one at startup, before writing any file
+var Files = &FilePolicy{uid: -1, gid: -1}
+
+//FilePolicy provides the structure for the permissions, ownership and location of the written files and directories
+//Zero modes leave the modes to the process umask, uid and gid being -1 to keep the owner and group of the process
+type FilePolicy struct {
+	fileMode   os.FileMode
+	dirMode    os.FileMode
+	uid        int
+	gid        int
+	createDirs bool
+	root       string
+}
+
+//NewFilePolicy creates a FilePolicy from octal umask, file and directory modes (empty to leave them unset), the explicit modes overriding the umask ones
+//Owner and group are user and group names or numeric ids, empty to keep those of the process, and root, if not empty, is the only directory files can be written to
+//It returns an error if any mode is invalid or if the owner, group or root can't be found
+func NewFilePolicy(umask, fileMode, dirMode, owner, group string, createDirs bool, root string) (*FilePolicy, error) {
+	policy := &FilePolicy{uid: -1, gid: -1, createDirs: createDirs}
+	if umask != "" {
+		mask, err := ParseFileMode(umask)
+		if err != nil {
+			return nil, fmt.Errorf("files - umask - %s", err.Error())
+		}
+		policy.fileMode, policy.dirMode = 0o666&^mask, 0o777&^mask
+	}
+	var err error
+	if fileMode != "" {
+		if policy.fileMode, err = ParseFileMode(fileMode); err != nil {
+			return nil, fmt.Errorf("files - fileMode - %s", err.Error())
+		}
+	}
+	if dirMode != "" {
+		if policy.dirMode, err = ParseFileMode(dirMode); err != nil {
+			return nil, fmt.Errorf("files - dirMode - %s", err.Error())
+		}
+	}
+
+	if owner != "" {
+		if policy.uid, err = strconv.Atoi(owner); err != nil {
+			account, lookupErr := user.Lookup(owner)
+			if lookupErr != nil {
+				return nil, fmt.Errorf("files - owner - %s", lookupErr.Error())
+			}
+			policy.uid, _ = strconv.Atoi(account.Uid)
+		}
+	}
+	if group != "" {
+		if policy.gid, err = strconv.Atoi(group); err != nil {
+			userGroup, lookupErr := user.LookupGroup(group)
+			if lookupErr != nil {
+				return nil, fmt.Errorf("files - group - %s", lookupErr.Error())
+			}
+			policy.gid, _ = strconv.Atoi(userGroup.Gid)
+		}
+	}
+
+	if root != "" {
+		if policy.root, err = filepath.Abs(root); err != nil {
+			return nil, fmt.Errorf("files - outputRoot - %s", err.Error())
+		}
+		if policy.root, err = filepath.EvalSymlinks(policy.root); err != nil {
+			return nil, fmt.Errorf("files - outputRoot - %s", err.Error())
+		}
+	}
+	return policy, nil
+}
+
+//ParseFileMode parses an octal permission mode, e.g. "0640" or "027"
+func ParseFileMode(mode string) (os.FileMode, error) {
+	value, err := strconv.ParseUint(mode, 8, 32)
+	if err != nil || value > 0o777 {
+		return 0, fmt.Errorf("invalid mode \"%s\", an octal value up to 0777 expected", mode)
+	}
+	return os.FileMode(value), nil
+}
+
+//Create creates or truncates a file for writing, as os.Create does, applying the policy
+//Missing directories are only created if the policy says so
+func (policy *FilePolicy) Create(name string) (*os.File, error) {
+	if err := policy.prepare(name); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(name, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0o666)
+	if err != nil {
+		return nil, err
+	}
+	if err := policy.apply(name, policy.fileMode); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return f, nil
+}
+
+//WriteFile writes a file atomically, through a temporary file renamed over it so readers never see it half written
+//The file mode is perm, restricted to the policy file mode if any, so private files stay private whatever the policy
+func (policy *FilePolicy) WriteFile(name string, data []byte, perm os.FileMode) error {
+	if err := policy.prepare(name); err != nil {
+		return err
+	}
+	if err := os.WriteFile(name+".tmp", data, perm); err != nil {
+		return err
+	}
+	mode := os.FileMode(0)
+	if policy.fileMode != 0 {
+		mode = policy.fileMode & perm
+	}
+	if err := policy.apply(name+".tmp", mode); err != nil {
+		os.Remove(name + ".tmp")
+		return err
+	}
+	return os.Rename(name+".tmp", name)
+}
+
+//MkdirAll creates a directory along with any missing parents, applying the policy to the directory itself
+func (policy *FilePolicy) MkdirAll(dir string) error {
+	if err := policy.allowed(dir); err != nil {
+		return err
+	}
+	mode := policy.dirMode
+	if mode == 0 {
+		mode = 0o755
+	}
+	if err := os.MkdirAll(dir, mode); err != nil {
+		return err
+	}
+	return policy.apply(dir, policy.dirMode)
+}
+
+//prepare checks if a file can be written under the policy root, creating its directory if the policy says so
+func (policy *FilePolicy) prepare(name string) error {
+	if err := policy.allowed(name); err != nil {
+		return err
+	}
+	if policy.createDirs {
+		return policy.MkdirAll(filepath.Dir(name))
+	}
+	return nil
+}
+
+//apply sets the given mode, if not zero, and the policy ownership of a written file or directory
+func (policy *FilePolicy) apply(name string, mode os.FileMode) error {
+	if mode != 0 {
+		if err := os.Chmod(name, mode); err != nil {
+			return err
+		}
+	}
+	if policy.uid != -1 || policy.gid != -1 {
+		return os.Chown(name, policy.uid, policy.gid)
+	}
+	return nil
+}
+
+//allowed checks if a path is within the policy root, if any, following the symbolic links of its existing part so they can't lead outside
+func (policy *FilePolicy) allowed(name string) error {
+	if policy.root == "" {
+		return nil
+	}
+	path, err := filepath.Abs(name)
+	if err != nil {
+		return err
+	}
+
+	//Resolving the longest existing ancestor, the missing part of the path being appended as is
+	existing, missing := path, ""
+	for {
+		if resolved, err := filepath.EvalSymlinks(existing); err == nil {
+			path = filepath.Join(resolved, missing)
+			break
+		} else if !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		parent := filepath.Dir(existing)
+		if parent == existing {
+			break
+		}
+		missing = filepath.Join(filepath.Base(existing), missing)
+		existing = parent
+	}
+
+	if path != policy.root && !strings.HasPrefix(path, policy.root+string(filepath.Separator)) {
+		return fmt.Errorf("\"%s\" is outside the output root \"%s\"", name, policy.root)
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestNewFilePolicyModes(t *testing.T) {
+	tests := []struct {
+		name         string
+		umask        string
+		fileMode     string
+		dirMode      string
+		perm         os.FileMode
+		wantFileMode os.FileMode
+		wantDirMode  os.FileMode
+		//wantCreateMode field is the mode of the files created for writing, which aren't restricted to a given mode
+		wantCreateMode os.FileMode
+	}{
+		{name: "Umask", umask: "027", perm: 0644, wantFileMode: 0640, wantDirMode: 0750, wantCreateMode: 0640},
+		{name: "Explicit modes", umask: "022", fileMode: "0600", dirMode: "0700", perm: 0644, wantFileMode: 0600, wantDirMode: 0700, wantCreateMode: 0600},
+		{name: "Private file kept private", umask: "002", perm: 0600, wantFileMode: 0600, wantDirMode: 0775, wantCreateMode: 0664},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy, err := NewFilePolicy(tt.umask, tt.fileMode, tt.dirMode, "", "", true, "")
+			if err != nil {
+				t.Fatalf("NewFilePolicy() error = %v", err)
+			}
+			dir := filepath.Join(t.TempDir(), "out")
+			name := filepath.Join(dir, "state.json")
+			if err := policy.WriteFile(name, []byte("{}"), tt.perm); err != nil {
+				t.Fatalf("WriteFile() error = %v", err)
+			}
+			if info, _ := os.Stat(name); info.Mode().Perm() != tt.wantFileMode {
+				t.Errorf("WriteFile() mode = %o, want %o", info.Mode().Perm(), tt.wantFileMode)
+			}
+			if info, _ := os.Stat(dir); info.Mode().Perm() != tt.wantDirMode {
+				t.Errorf("WriteFile() directory mode = %o, want %o", info.Mode().Perm(), tt.wantDirMode)
+			}
+			if _, err := os.Stat(name + ".tmp"); !os.IsNotExist(err) {
+				t.Errorf("WriteFile() left its temporary file")
+			}
+
+			created := filepath.Join(dir, "report.json")
+			f, err := policy.Create(created)
+			if err != nil {
+				t.Fatalf("Create() error = %v", err)
+			}
+			f.Close()
+			if info, _ := os.Stat(created); info.Mode().Perm() != tt.wantCreateMode {
+				t.Errorf("Create() mode = %o, want %o", info.Mode().Perm(), tt.wantCreateMode)
+			}
+		})
+	}
+}
+
+func TestFilePolicyOutputRoot(t *testing.T) {
+	root := t.TempDir()
+	outside := t.TempDir()
+	os.Symlink(outside, filepath.Join(root, "escape"))
+	policy, err := NewFilePolicy("", "", "", "", "", true, root)
+	if err != nil {
+		t.Fatalf("NewFilePolicy() error = %v", err)
+	}
+	tests := []struct {
+		name    string
+		file    string
+		wantErr bool
+	}{
+		{name: "Inside the root", file: filepath.Join(root, "report.json")},
+		{name: "Missing directory inside the root", file: filepath.Join(root, "charts", "run", "chart.svg")},
+		{name: "Outside the root", file: filepath.Join(outside, "report.json"), wantErr: true},
+		{name: "Parent traversal", file: filepath.Join(root, "..", "report.json"), wantErr: true},
+		{name: "Symbolic link leading outside", file: filepath.Join(root, "escape", "report.json"), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := policy.WriteFile(tt.file, []byte("{}"), 0644)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("WriteFile() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNewFilePolicyErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		umask    string
+		fileMode string
+		owner    string
+		root     string
+	}{
+		{name: "Umask not octal", umask: "0x22"},
+		{name: "Mode over 0777", fileMode: "1777"},
+		{name: "Unknown owner", owner: "no-such-user-anomalies"},
+		{name: "Missing root", root: filepath.Join(t.TempDir(), "missing")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewFilePolicy(tt.umask, tt.fileMode, "", tt.owner, "", false, tt.root); err == nil {
+				t.Errorf("NewFilePolicy() expected an error")
+			}
+		})
+	}
+}
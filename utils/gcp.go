@@ -0,0 +1,34 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+//GcpMetadataTokenUrl is the compute metadata server address returning access tokens of the default service account
+const GcpMetadataTokenUrl = "http://metadata.google.internal/computeMetadata/v1/instance/service-accounts/default/token"
+
+//GcpMetadataToken requests an access token of the default service account from the compute metadata server at the given address
+//It returns an error if the server is unavailable or doesn't return a token
+func GcpMetadataToken(client *http.Client, tokenUrl string) (string, error) {
+	req, err := http.NewRequest(http.MethodGet, tokenUrl, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Metadata-Flavor", "Google")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("no accessToken and metadata server unavailable - %s", err.Error())
+	}
+	defer resp.Body.Close()
+
+	var tokenResp struct {
+		AccessToken string `json:"access_token"`
+	}
+	if resp.StatusCode != http.StatusOK || json.NewDecoder(resp.Body).Decode(&tokenResp) != nil || tokenResp.AccessToken == "" {
+		return "", fmt.Errorf("metadata server token request failed with status %s", resp.Status)
+	}
+	return tokenResp.AccessToken, nil
+}
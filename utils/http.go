@@ -0,0 +1,87 @@
+package utils
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+//HttpTransport is the transport of every outbound HTTP client, replaced on start up by one with the configured proxy and TLS settings
+var HttpTransport http.RoundTripper = http.DefaultTransport
+
+//TlsConfig is the TLS configuration of the outbound connections not made over HTTP, nil for the system defaults
+var TlsConfig *tls.Config
+
+//TlsFiles provides the structure for the PEM files the outbound TLS configuration is built from
+type TlsFiles struct {
+	CaFile   string
+	CertFile string
+	KeyFile  string
+}
+
+//OutboundTlsFiles holds the files TlsConfig was built from, for the database drivers only reading their TLS settings from files
+var OutboundTlsFiles TlsFiles
+
+//NewHttpClient creates an HTTP client with the given timeout, going through HttpTransport
+func NewHttpClient(timeout time.Duration) *http.Client {
+	return &http.Client{Timeout: timeout, Transport: HttpTransport}
+}
+
+//NewOutboundTransport creates a transport going through the given proxy, except for the noProxy hosts and their subdomains,
+//the HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment variables being used if proxy is empty
+//The CAs of the caFile PEM bundle are trusted besides the system ones and the PEM client certificate and key are presented for mTLS, all of them being optional
+//It returns an error if the proxy URL is invalid or if any file can't be read or holds no valid certificate
+func NewOutboundTransport(proxy string, noProxy []string, caFile, certFile, keyFile string) (*http.Transport, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = http.ProxyFromEnvironment
+	if proxy != "" {
+		proxyUrl, err := url.Parse(proxy)
+		if err != nil || proxyUrl.Host == "" {
+			return nil, fmt.Errorf("outbound - invalid proxy \"%s\"", proxy)
+		}
+		transport.Proxy = func(req *http.Request) (*url.URL, error) {
+			host := strings.ToLower(req.URL.Hostname())
+			for _, direct := range noProxy {
+				direct = strings.ToLower(strings.TrimPrefix(direct, "."))
+				if host == direct || strings.HasSuffix(host, "."+direct) {
+					return nil, nil
+				}
+			}
+			return proxyUrl, nil
+		}
+	}
+
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	if caFile != "" {
+		pem, err := os.ReadFile(caFile)
+		if err != nil {
+			return nil, fmt.Errorf("outbound - caFile - %s", err.Error())
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("outbound - caFile \"%s\" - no valid certificate", caFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	if certFile != "" || keyFile != "" {
+		if certFile == "" || keyFile == "" {
+			return nil, errors.New("outbound - clientCertFile and clientKeyFile are both required for mTLS")
+		}
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("outbound - client certificate - %s", err.Error())
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	transport.TLSClientConfig = tlsConfig
+	return transport, nil
+}
@@ -0,0 +1,94 @@
+package utils
+
+import (
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestNewOutboundTransportProxy(t *testing.T) {
+	transport, err := NewOutboundTransport("http://proxy.internal:3128", []string{"internal.example.com", ".corp"}, "", "", "")
+	if err != nil {
+		t.Fatalf("NewOutboundTransport() error = %v", err)
+	}
+	tests := []struct {
+		name      string
+		url       string
+		wantProxy string
+	}{
+		{name: "Proxied host", url: "https://hooks.slack.com/services/x", wantProxy: "http://proxy.internal:3128"},
+		{name: "Direct host", url: "https://internal.example.com/api", wantProxy: ""},
+		{name: "Direct subdomain", url: "http://prometheus.monitoring.corp:9090/api/v1/query", wantProxy: ""},
+		{name: "Suffix without dot proxied", url: "https://notinternal.example.com", wantProxy: "http://proxy.internal:3128"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reqUrl, _ := url.Parse(tt.url)
+			proxyUrl, err := transport.Proxy(&http.Request{URL: reqUrl})
+			if err != nil {
+				t.Fatalf("Proxy() error = %v", err)
+			}
+			got := ""
+			if proxyUrl != nil {
+				got = proxyUrl.String()
+			}
+			if got != tt.wantProxy {
+				t.Errorf("Proxy() = %q, want %q", got, tt.wantProxy)
+			}
+		})
+	}
+}
+
+func TestNewOutboundTransportCa(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	//The test server certificate is only trusted once its CA bundle is given
+	dir := t.TempDir()
+	caFile := filepath.Join(dir, "ca.pem")
+	os.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}), 0644)
+	transport, err := NewOutboundTransport("", nil, caFile, "", "")
+	if err != nil {
+		t.Fatalf("NewOutboundTransport() error = %v", err)
+	}
+	resp, err := (&http.Client{Transport: transport}).Get(server.URL)
+	if err != nil {
+		t.Fatalf("Get() with private CA error = %v", err)
+	}
+	resp.Body.Close()
+	if _, err := http.Get(server.URL); err == nil {
+		t.Errorf("Get() without private CA expected an error")
+	}
+}
+
+func TestNewOutboundTransportErrors(t *testing.T) {
+	dir := t.TempDir()
+	invalidPem := filepath.Join(dir, "invalid.pem")
+	os.WriteFile(invalidPem, []byte("not a certificate"), 0644)
+	tests := []struct {
+		name     string
+		proxy    string
+		caFile   string
+		certFile string
+		keyFile  string
+	}{
+		{name: "Proxy without scheme", proxy: "proxy.internal:3128"},
+		{name: "Missing CA file", caFile: filepath.Join(dir, "missing.pem")},
+		{name: "CA file without certificates", caFile: invalidPem},
+		{name: "Client certificate without key", certFile: invalidPem},
+		{name: "Invalid client certificate", certFile: invalidPem, keyFile: invalidPem},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewOutboundTransport(tt.proxy, nil, tt.caFile, tt.certFile, tt.keyFile); err == nil {
+				t.Errorf("NewOutboundTransport() expected an error")
+			}
+		})
+	}
+}
@@ -0,0 +1,42 @@
+package utils
+
+import (
+	"fmt"
+	"sort"
+)
+
+//Const block defines the optional integrations, each built into the binary by the build tag of its name or by the "full" tag
+//They are left out of the default build, so binaries only needing the core sources and notifiers don't ship their libraries
+const (
+	IntegrationGa4      = "ga4"
+	IntegrationKafka    = "kafka"
+	IntegrationMysql    = "mysql"
+	IntegrationPostgres = "postgres"
+	IntegrationSqlite   = "sqlite"
+)
+
+//integrations holds the optional integrations built into the binary, registered on init by the files of their build tags
+var integrations = map[string]bool{}
+
+//RegisterIntegration marks an optional integration as built into the binary, being called on init by the files of its build tag
+func RegisterIntegration(name string) {
+	integrations[name] = true
+}
+
+//Integrations returns the optional integrations built into the binary in alphabetical order
+func Integrations() []string {
+	names := []string{}
+	for name := range integrations {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+//CheckIntegration returns an error naming the build tag to use if an optional integration isn't built into the binary
+func CheckIntegration(name string) error {
+	if !integrations[name] {
+		return fmt.Errorf("%s support not built in, rebuild with -tags %s or -tags full", name, name)
+	}
+	return nil
+}
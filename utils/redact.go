@@ -0,0 +1,78 @@
+package utils
+
+import (
+	"reflect"
+	"strings"
+)
+
+//RedactedValue replaces the secrets of a redacted structure
+const RedactedValue = "[redacted]"
+
+//RedactSecrets returns a copy of any given variable whose fields tagged secret:"true" are masked, so it can be logged without leaking credentials
+//Tagged strings, string slices and string map values are replaced by RedactedValue, unless empty or "env:VAR" and "file:path" references
+//The given variable is left untouched, pointers, slices and maps along the way being copied
+func RedactSecrets(v interface{}) interface{} {
+	if v == nil {
+		return nil
+	}
+	return redactValue(reflect.ValueOf(v), false).Interface()
+}
+
+//redactSecret masks a secret value, keeping the references telling where it is read from
+func redactSecret(value string) string {
+	if value == "" || strings.HasPrefix(value, "env:") || strings.HasPrefix(value, "file:") {
+		return value
+	}
+	return RedactedValue
+}
+
+//redactValue returns a copy of a value with its secrets masked, the value itself being a secret if tagged on its parent structure
+func redactValue(value reflect.Value, secret bool) reflect.Value {
+	res := reflect.New(value.Type()).Elem()
+	res.Set(value)
+
+	switch value.Kind() {
+	case reflect.String:
+		if secret {
+			res.SetString(redactSecret(value.String()))
+		}
+
+	case reflect.Ptr, reflect.Interface:
+		if !value.IsNil() {
+			elem := redactValue(value.Elem(), secret)
+			if value.Kind() == reflect.Ptr {
+				copied := reflect.New(elem.Type())
+				copied.Elem().Set(elem)
+				res.Set(copied)
+			} else {
+				res.Set(elem)
+			}
+		}
+
+	case reflect.Struct:
+		for i := 0; i < value.NumField(); i++ {
+			field := value.Type().Field(i)
+			if field.IsExported() {
+				res.Field(i).Set(redactValue(value.Field(i), field.Tag.Get("secret") == "true"))
+			}
+		}
+
+	case reflect.Slice:
+		if !value.IsNil() {
+			res.Set(reflect.MakeSlice(value.Type(), value.Len(), value.Len()))
+			for i := 0; i < value.Len(); i++ {
+				res.Index(i).Set(redactValue(value.Index(i), secret))
+			}
+		}
+
+	case reflect.Map:
+		if !value.IsNil() {
+			res.Set(reflect.MakeMapWithSize(value.Type(), value.Len()))
+			iter := value.MapRange()
+			for iter.Next() {
+				res.SetMapIndex(iter.Key(), redactValue(iter.Value(), secret))
+			}
+		}
+	}
+	return res
+}
@@ -0,0 +1,54 @@
+package utils
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestRedactSecrets(t *testing.T) {
+	type credentials struct {
+		Tokens []string          `json:"tokens" secret:"true"`
+		Users  map[string]string `json:"users" secret:"true"`
+	}
+	type settings struct {
+		Name     string        `json:"name"`
+		Password string        `json:"password" secret:"true"`
+		Key      string        `json:"key" secret:"true"`
+		Empty    string        `json:"empty" secret:"true"`
+		Timeout  time.Duration `json:"timeout"`
+		Auth     *credentials  `json:"auth"`
+		Nested   []credentials `json:"nested"`
+		internal string
+	}
+
+	given := settings{
+		Name:     "shop",
+		Password: "hunter2",
+		Key:      "file:/run/secrets/key",
+		Timeout:  time.Minute,
+		Auth:     &credentials{Tokens: []string{"static-token", "env:TOKEN"}, Users: map[string]string{"analyst": "sha256:5e88"}},
+		Nested:   []credentials{{Tokens: []string{"nested-token"}}},
+		internal: "kept",
+	}
+	want := settings{
+		Name:     "shop",
+		Password: RedactedValue,
+		Key:      "file:/run/secrets/key",
+		Timeout:  time.Minute,
+		Auth:     &credentials{Tokens: []string{RedactedValue, "env:TOKEN"}, Users: map[string]string{"analyst": RedactedValue}},
+		Nested:   []credentials{{Tokens: []string{RedactedValue}}},
+		internal: "kept",
+	}
+
+	got, isSettings := RedactSecrets(given).(settings)
+	if !isSettings || !reflect.DeepEqual(got, want) {
+		t.Errorf("RedactSecrets() = %+v, want %+v", got, want)
+	}
+	if given.Password != "hunter2" || given.Auth.Tokens[0] != "static-token" || given.Auth.Users["analyst"] != "sha256:5e88" || given.Nested[0].Tokens[0] != "nested-token" {
+		t.Errorf("RedactSecrets() changed the given value to %+v", given)
+	}
+	if got := RedactSecrets(nil); got != nil {
+		t.Errorf("RedactSecrets(nil) = %v, want nil", got)
+	}
+}
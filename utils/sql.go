@@ -0,0 +1,45 @@
+package utils
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+//PostgresTlsDsn adds the outbound CA bundle and client certificate files to a PostgreSQL data source name, either a URL or key=value pairs
+//The driver only reads them from files and trusts the CA bundle instead of the system CAs, so settings given on the DSN are kept
+//and connections with sslmode=disable are left as they are
+//It returns an error if a URL data source name can't be parsed
+func PostgresTlsDsn(dsn string) (string, error) {
+	settings := [][2]string{{"sslrootcert", OutboundTlsFiles.CaFile}, {"sslcert", OutboundTlsFiles.CertFile}, {"sslkey", OutboundTlsFiles.KeyFile}}
+
+	if strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://") {
+		dsnUrl, err := url.Parse(dsn)
+		if err != nil {
+			return "", fmt.Errorf("invalid data source name - %s", err.Error())
+		}
+		query := dsnUrl.Query()
+		if query.Get("sslmode") == "disable" {
+			return dsn, nil
+		}
+		for _, setting := range settings {
+			if setting[1] != "" && !query.Has(setting[0]) {
+				query.Set(setting[0], setting[1])
+			}
+		}
+		dsnUrl.RawQuery = query.Encode()
+		return dsnUrl.String(), nil
+	}
+
+	if regexp.MustCompile(`(^|\s)sslmode\s*=\s*'?disable\b`).MatchString(dsn) {
+		return dsn, nil
+	}
+	for _, setting := range settings {
+		if setting[1] != "" && !regexp.MustCompile(`(^|\s)`+setting[0]+`\s*=`).MatchString(dsn) {
+			value := strings.ReplaceAll(strings.ReplaceAll(setting[1], `\`, `\\`), `'`, `\'`)
+			dsn = strings.TrimSpace(fmt.Sprintf("%s %s='%s'", dsn, setting[0], value))
+		}
+	}
+	return dsn, nil
+}
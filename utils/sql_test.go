@@ -0,0 +1,62 @@
+package utils
+
+import "testing"
+
+func TestPostgresTlsDsn(t *testing.T) {
+	tests := []struct {
+		name  string
+		files TlsFiles
+		dsn   string
+		want  string
+	}{
+		{
+			name:  "Key value pairs",
+			files: TlsFiles{CaFile: "/etc/ca.pem", CertFile: "/etc/client.pem", KeyFile: "/etc/client-key.pem"},
+			dsn:   "host=db user=app dbname=metrics",
+			want:  "host=db user=app dbname=metrics sslrootcert='/etc/ca.pem' sslcert='/etc/client.pem' sslkey='/etc/client-key.pem'",
+		},
+		{
+			name:  "Key value pairs with their own CA and quoted paths",
+			files: TlsFiles{CaFile: "/etc/ca.pem", CertFile: `/etc/o'brien.pem`},
+			dsn:   "host=db sslrootcert=/etc/db-ca.pem",
+			want:  `host=db sslrootcert=/etc/db-ca.pem sslcert='/etc/o\'brien.pem'`,
+		},
+		{
+			name:  "URL",
+			files: TlsFiles{CaFile: "/etc/ca.pem"},
+			dsn:   "postgres://app:secret@db:5432/metrics?sslmode=verify-full",
+			want:  "postgres://app:secret@db:5432/metrics?sslmode=verify-full&sslrootcert=%2Fetc%2Fca.pem",
+		},
+		{
+			name:  "TLS disabled",
+			files: TlsFiles{CaFile: "/etc/ca.pem"},
+			dsn:   "host=db sslmode=disable",
+			want:  "host=db sslmode=disable",
+		},
+		{
+			name:  "TLS disabled on a URL",
+			files: TlsFiles{CaFile: "/etc/ca.pem"},
+			dsn:   "postgresql://db/metrics?sslmode=disable",
+			want:  "postgresql://db/metrics?sslmode=disable",
+		},
+		{
+			name: "No outbound TLS files",
+			dsn:  "host=db",
+			want: "host=db",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			OutboundTlsFiles = tt.files
+			defer func() { OutboundTlsFiles = TlsFiles{} }()
+
+			got, err := PostgresTlsDsn(tt.dsn)
+			if err != nil || got != tt.want {
+				t.Errorf("PostgresTlsDsn() = %s, %v, want %s", got, err, tt.want)
+			}
+		})
+	}
+	if _, err := PostgresTlsDsn("postgres://db:port/metrics"); err == nil {
+		t.Errorf("PostgresTlsDsn() error = nil, want an invalid URL error")
+	}
+}
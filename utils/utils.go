@@ -6,11 +6,20 @@ import (
 	"log"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 )
 
-//StrToDuration is similar to time.ParseDuration() but also supports days "d" and weeks "w"
+//StrToDuration is similar to time.ParseDuration() but also supports days "d", weeks "w" and months "mo" (30 days)
+//Calendar time steps of whole days or weeks, e.g. "1d-calendar", are read as their duration
 func StrToDuration(timeStep string) (time.Duration, error) {
+	if strings.HasSuffix(timeStep, calendarSuffix) {
+		res, err := StrToDuration(strings.TrimSuffix(timeStep, calendarSuffix))
+		if err != nil || res <= 0 || res%calendarDay != 0 {
+			return 0, fmt.Errorf("time: invalid calendar duration \"%s\", whole days or weeks expected", timeStep)
+		}
+		return res, nil
+	}
 
 	//Defining duration values for each unit
 	var unitMap = map[string]int64{
@@ -22,6 +31,7 @@ func StrToDuration(timeStep string) (time.Duration, error) {
 		"h":  int64(time.Hour),
 		"d":  int64(time.Hour) * 24,
 		"w":  int64(time.Hour) * 168,
+		"mo": int64(time.Hour) * 720,
 	}
 
 	if len(timeStep) == 0 {
@@ -48,11 +58,15 @@ func StrToDuration(timeStep string) (time.Duration, error) {
 			return 0, fmt.Errorf("time: invalid duration \"%s\"", timeStep)
 		}
 
-		//After the number, it looks for known units of both 1 and 2 characters
-		multiplier, present := unitMap[timeStep[i:i+1]]
-		i++
-		if !present && i < len(timeStep) {
-			multiplier, present = unitMap[timeStep[i-1:i+1]]
+		//After the number, it looks for known units of both 2 and 1 characters, so "mo" and "ms" aren't taken for minutes
+		multiplier, present := int64(0), false
+		if i+2 <= len(timeStep) {
+			multiplier, present = unitMap[timeStep[i:i+2]]
+		}
+		if present {
+			i += 2
+		} else {
+			multiplier, present = unitMap[timeStep[i:i+1]]
 			i++
 		}
 		if !present {
@@ -68,6 +82,18 @@ func StrToDuration(timeStep string) (time.Duration, error) {
 	return time.Duration(res), nil
 }
 
+//ResolveSecret returns the value of the referenced environment variable if the given value has the "env:VAR" format
+//Any other value is returned as it is, allowing secrets to be kept out of the configuration file
+func ResolveSecret(value string) string {
+	if strings.HasPrefix(value, "env:") {
+		return os.Getenv(strings.TrimPrefix(value, "env:"))
+	}
+	return value
+}
+
+//StdStream is the file name standing for the standard input or output, so the application composes into pipelines
+const StdStream = "-"
+
 //PrintJsonStruct simply prints any given variable to the log
 func PrintJsonStruct(v interface{}) {
 	jsonOutput, err := json.MarshalIndent(v, "", "  ")
@@ -77,14 +103,20 @@ func PrintJsonStruct(v interface{}) {
 	log.Println(string(jsonOutput))
 }
 
-//WriteJsonStruct simply stores any given variable to a file
+//WriteJsonStruct simply stores any given variable to a file, written to the standard output for StdStream
 func WriteJsonStruct(v interface{}, filename string) {
 	jsonOutput, err := json.MarshalIndent(v, "", "  ")
 	if err != nil {
 		panic(err)
 	}
+	if filename == StdStream {
+		if _, err = os.Stdout.Write(append(jsonOutput, '\n')); err != nil {
+			panic(err)
+		}
+		return
+	}
 
-	f, err := os.Create(filename)
+	f, err := Files.Create(filename)
 	if err != nil {
 		panic(err)
 	}